@@ -4,6 +4,8 @@ import { useWindowDimensions } from "../../Util";
 import { Route, Routes } from "react-router-dom";
 import { ClusterOverview } from "../features/ClusterOverview/ClusterOverview";
 import { MySQLReplTopo } from "../features/MySQLTopology/MySQLTopology";
+import { MySQLQueryConsole } from "../features/MySQLQueryConsole/MySQLQueryConsole";
+import { PostgresReplTopo } from "../features/PostgresTopology/PostgresTopology";
 import { Pods } from "../features/Pods/Pods";
 import { Deployments } from "../features/Deployments/Deployments";
 import { Statefulsets } from "../features/Statefulsets/Statefulsets";
@@ -17,6 +19,7 @@ import { Nodes } from "../features/Nodes/Nodes";
 import { AccessControl } from "../features/AccessControl/AccessControl";
 import { Reports } from "../features/Reports/Reports";
 import { GeneralSettings } from "../features/GeneralSettings/GeneralSettings";
+import { APITokens } from "../features/APITokens/APITokens";
 import { useGetDynamicAppConfigQuery } from "../../service/khub";
 
 
@@ -37,6 +40,12 @@ export const ContentArea = () => {
         <Route path="/mysql-replication-topology" element={
           <MySQLReplTopo />
         }/>
+        <Route path="/mysql-query-console" element={
+          <MySQLQueryConsole />
+        }/>
+        <Route path="/postgres-replication-topology" element={
+          <PostgresReplTopo />
+        }/>
         <Route path="/pods" element={
           <Pods appConfig={appConfig}/>
         }/>
@@ -76,6 +85,9 @@ export const ContentArea = () => {
         <Route path="/reports" element={
           <Reports />
         }/>
+        <Route path="/api-tokens" element={
+          <APITokens />
+        }/>
       </Routes>
     </div>
   );
@@ -21,7 +21,7 @@ import {
 } from '@carbon/react';
 import { CiMicrochip } from "react-icons/ci";
 
-import { UserAvatar, BareMetalServer, DocumentEpdf, LogoKubernetes, KubernetesPod, KubernetesIpAddress, DocumentMultiple_01, WarningDiamond, ColorPalette, TreeView, CheckboxCheckedFilled, RuleLocked } from '@carbon/icons-react';
+import { UserAvatar, BareMetalServer, DocumentEpdf, LogoKubernetes, KubernetesPod, KubernetesIpAddress, DocumentMultiple_01, WarningDiamond, ColorPalette, TreeView, CheckboxCheckedFilled, RuleLocked, Password } from '@carbon/icons-react';
 import { MdCheckBoxOutlineBlank } from "react-icons/md";
 import { NavLink } from 'react-router-dom';
 import { useSelector } from 'react-redux';
@@ -106,8 +106,15 @@ export const Shell = ({userPreferredTheme, userInfo}: any) => {
                   <SideNavLink as={NavLink} to="/mysql-replication-topology" end>
                     MySQL Replication
                   </SideNavLink>
+                  <SideNavLink as={NavLink} to="/mysql-query-console" end>
+                    MySQL Query Console
+                  </SideNavLink>
+                  <SideNavLink as={NavLink} to="/postgres-replication-topology" end>
+                    Postgres Replication
+                  </SideNavLink>
                 </SideNavMenu>
                 <SideNavLink as={NavLink} to="/reports" end renderIcon={DocumentEpdf} >Reports</SideNavLink>
+                <SideNavLink as={NavLink} to="/api-tokens" end renderIcon={Password} >API Tokens</SideNavLink>
                 {userIsAdmin.isAdmin && <SideNavMenu defaultExpanded renderIcon={RuleLocked} title="Administration">
                   <SideNavLink as={NavLink} to="/general-settings" end>General</SideNavLink>
                   <SideNavLink as={NavLink} to="/access-control" end>Access Control</SideNavLink>
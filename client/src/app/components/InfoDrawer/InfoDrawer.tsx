@@ -1,4 +1,4 @@
-import React, { useEffect, useMemo, useRef } from 'react';
+import React, { useEffect, useMemo, useRef, useState } from 'react';
 import type { CSSProperties } from 'react';
 import './InfoDrawer.scss';
 import { useSelector } from 'react-redux';
@@ -9,9 +9,50 @@ import { Recycle, Report } from "@carbon/icons-react";
 import CodeMirror from '@uiw/react-codemirror';
 import { yaml as yamlint } from '@codemirror/lang-yaml';
 import yaml from 'js-yaml';
-import { useDeletePodMutation, useExecPluginMutation, useRolloutRestartMutation } from '../../../service/khub';
+import { useDeletePodMutation, useExecPluginMutation, useGetExecJobQuery, useRolloutRestartMutation } from '../../../service/khub';
 import { updateNotifications } from '../../../service/notifications';
-import { IPodExecPlugin } from '../../../service/types/AppConfig';
+import { IExecPluginParameter, IPodExecPlugin } from '../../../service/types/AppConfig';
+
+// ExecJobOutput follows the output of an async pod exec plugin job
+const ExecJobOutput = (props: { id: string }) => {
+  const { data: job } = useGetExecJobQuery({ id: props.id });
+  if (!job) {
+    return null;
+  }
+  return (
+    <div id='execJobOutput'>
+      <strong>{job.plugin} ({job.status})</strong>
+      {job.error && <div>{job.error}</div>}
+      {job.artifact && <div>Uploaded {job.artifact} to <a href='/reports'>reports</a></div>}
+      <pre>{job.output}</pre>
+    </div>
+  );
+};
+
+// promptExecPluginArgs asks for the arguments of the plugin parameters. It returns null when cancelled.
+const promptExecPluginArgs = (plugin: IPodExecPlugin): { [key: string]: string } | null => {
+  const args: { [key: string]: string } = {};
+  for (const param of plugin.parameters ?? []) {
+    const hint = describeExecPluginParameter(param);
+    const value = window.prompt(`${plugin.name}: ${param.name}${param.description ? ` - ${param.description}` : ''} ${hint}`, param.default ?? '');
+    if (value === null) {
+      return null;
+    }
+    args[param.name] = value;
+  }
+  return args;
+};
+
+const describeExecPluginParameter = (param: IExecPluginParameter): string => {
+  switch (param.type) {
+  case 'int':
+    return `(integer${param.min !== undefined ? ` >= ${param.min}` : ''}${param.max !== undefined ? ` <= ${param.max}` : ''})`;
+  case 'enum':
+    return `(one of ${param.values?.join(', ')})`;
+  default:
+    return `(matching ${param.pattern})`;
+  }
+};
 
 type InfoDrawerProps = {
   open: boolean;
@@ -142,10 +183,18 @@ export const InfoDrawer: React.FC<InfoDrawerProps> = (props) => {
       .catch((error) => dispatch(updateNotifications({notifications: [{notif: 'Error restarting ' + res.name + ' ' + JSON.stringify(error), status: 'error'}]})));
   };
 
+  const [execJobId, setExecJobId] = useState<string | null>(null);
   const handleExecPlugin = (res: any) => {
     dispatch(updateNotifications({notifications: [{notif: res.name + ' exec plugin initiated ' , status: 'info'}]}));
-    execPlugin({name: res.name, namespace: res.namespace, kind: res.kind, container: res.container, command: res.command, pluginName: res.pluginName}).unwrap()
-      .then((payload) => dispatch(updateNotifications({notifications: [{notif: res.name + ' pod exec success: ' + payload, status: 'success'}]})))
+    execPlugin({name: res.name, namespace: res.namespace, kind: res.kind, container: res.container, command: res.command, pluginName: res.pluginName, pluginArgs: res.pluginArgs, reason: res.reason}).unwrap()
+      .then((payload) => {
+        if (res.async) {
+          setExecJobId(payload.id);
+          dispatch(updateNotifications({notifications: [{notif: res.name + ' pod exec job started: ' + payload.id, status: 'success'}]}));
+          return;
+        }
+        dispatch(updateNotifications({notifications: [{notif: res.name + ' pod exec success: ' + payload, status: 'success'}]}));
+      })
       .catch((error) => dispatch(updateNotifications({notifications: [{notif: 'Error running pod exec plugin ' + res.name + ' ' + JSON.stringify(error), status: 'error'}]})));
   };
   
@@ -224,13 +273,28 @@ export const InfoDrawer: React.FC<InfoDrawerProps> = (props) => {
                       >Delete {resourceDrawer.data?.resourceType}</Button>
                     {getExecPlugins(resourceDrawer.data).map((plugin: any) => {
                         return <Button key={plugin.name} renderIcon={Report} kind="tertiary" style={{marginLeft: '5px'}} onClick={() => {
+                          const pluginArgs = promptExecPluginArgs(plugin);
+                          if (pluginArgs === null) {
+                            return;
+                          }
+                          let reason: string | undefined;
+                          if (plugin.outputFile) {
+                            const value = window.prompt(`${plugin.name}: reason (recorded on the uploaded ${plugin.outputFile.type ?? 'diagnostic'} report)`, '');
+                            if (value === null) {
+                              return;
+                            }
+                            reason = value;
+                          }
                           handleExecPlugin({
                               name: resourceDrawer.data?.resourceData?.metadata?.name, 
                               namespace: resourceDrawer.data?.resourceData?.metadata?.namespace, 
                               kind: resourceDrawer.data.resourceType,
                               container: plugin.container,
                               command: plugin.command,
-                              pluginName: plugin.name
+                              pluginName: plugin.name,
+                              pluginArgs: pluginArgs,
+                              reason: reason,
+                              async: plugin.async
                             });
                         }}>{plugin.name}</Button>;
                       })
@@ -246,6 +310,7 @@ export const InfoDrawer: React.FC<InfoDrawerProps> = (props) => {
                 }
               </TableToolbarContent>
             </TableToolbar>
+            {execJobId && <ExecJobOutput id={execJobId} />}
             <div id='infoDrawerTable'>
               <Table aria-label="sample table">
                 {resourceDrawer.data?.resourceData !== null && (
@@ -1,13 +1,14 @@
 import { Button } from '@carbon/react';
 import React from 'react';
 import { ResourceDataTable } from '../ResourceDataTable/ResourceDataTable';
-import { FaDownload } from "react-icons/fa6";
-import { khubApi } from '../../../service/khub';
+import { FaDownload, FaTrash, FaUpload } from "react-icons/fa6";
+import { khubApi, useCreateReportUploadMutation, useDeleteReportMutation } from '../../../service/khub';
 import { useAppDispatch } from '../../store';
 
 type DocViewerProps = {
   title: string;
   reports: any[];
+  onReportsChanged?: () => void;
 };
 
 
@@ -32,6 +33,49 @@ export const ReportViewer: React.FC<DocViewerProps> = (props) => {
     });
   };
 
+  const [createReportUpload] = useCreateReportUploadMutation();
+  const [deleteReport] = useDeleteReportMutation();
+  const fileInput = React.useRef<HTMLInputElement>(null);
+
+  // Uploads go straight to the report storage, through a link khub signs with the reason and type of the report
+  const handleUploadReport = async (event: React.ChangeEvent<HTMLInputElement>) => {
+    const file = event.target.files?.[0];
+    event.target.value = '';
+    if (file === undefined) {
+      return;
+    }
+    const reason = window.prompt(`${file.name}: reason`, '');
+    if (!reason) {
+      return;
+    }
+    const type = window.prompt(`${file.name}: report type`, 'diagnostic');
+    if (!type) {
+      return;
+    }
+
+    try {
+      const upload = await createReportUpload({name: file.name, reason: reason, type: type}).unwrap();
+      const response = await fetch(upload.url, {method: upload.method, headers: upload.headers, body: file});
+      if (!response.ok) {
+        throw new Error(response.statusText);
+      }
+    } catch (err: any) {
+      window.alert(`Unable to upload ${file.name}: ${err?.data ?? err?.message ?? err}`);
+      return;
+    }
+    dispatch(khubApi.util.invalidateTags(['Reports']));
+    props.onReportsChanged?.();
+  };
+
+  const handleDeleteReport = (report: string) => {
+    if (!window.confirm(`Delete ${report}?`)) {
+      return;
+    }
+    deleteReport({key: report}).unwrap().then(() => props.onReportsChanged?.()).catch((err) => {
+      window.alert(`Unable to delete ${report}: ${err?.data ?? err}`);
+    });
+  };
+
   const headers = [
     {
       header: 'Name',
@@ -57,6 +101,10 @@ export const ReportViewer: React.FC<DocViewerProps> = (props) => {
       header: 'Created Date',
       key: 'created'
     },
+    {
+      header: 'Expires',
+      key: 'expires'
+    },
     {
       header: 'Size',
       key: 'size',
@@ -84,15 +132,19 @@ export const ReportViewer: React.FC<DocViewerProps> = (props) => {
       createdBy: report.user,
       reference: report.reason,
       created: report.created,
+      expires: report.expires || 'Never',
       size: report.size + report.sizeUnits,
       controls: <div style={{float: 'right'}}>
                    <Button onClick={() => handleDownloadReport(report.name)} renderIcon={FaDownload} kind='ghost' hasIconOnly iconDescription='Download'/>
+                   <Button onClick={() => handleDeleteReport(report.name)} renderIcon={FaTrash} kind='ghost' hasIconOnly iconDescription='Delete'/>
                 </div>
     };
   });
 
   return (
     <div id={'ReportViewer'} className="ReportViewer">
+      <input ref={fileInput} type='file' style={{display: 'none'}} onChange={handleUploadReport}/>
+      <Button onClick={() => fileInput.current?.click()} renderIcon={FaUpload} kind='ghost'>Upload report</Button>
       <ResourceDataTable 
         rows={rows} 
         headers={headers} 
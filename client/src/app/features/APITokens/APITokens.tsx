@@ -0,0 +1,136 @@
+import React from "react";
+import { Close } from "@carbon/icons-react";
+import { Button, ButtonSet, ComposedModal, ModalBody, ModalHeader, NumberInput, RadioButton, RadioButtonGroup, TextInput, CodeSnippet } from "@carbon/react";
+import { AdminDataTable } from "../../components/AdminDataTable/AdminDataTable";
+import { useCreateAPITokenMutation, useGetAPITokensQuery, useRevokeAPITokenMutation } from "../../../service/khub";
+import { useAppDispatch } from "../../store";
+import { updateNotifications } from "../../../service/notifications";
+
+export const APITokens = () => {
+
+  const dispatch = useAppDispatch();
+  const {data: tokens = []} = useGetAPITokensQuery({});
+  const [createAPIToken] = useCreateAPITokenMutation();
+  const [revokeAPIToken] = useRevokeAPITokenMutation();
+
+  const [modalOpen, setModalOpen] = React.useState(false);
+  const [tokenName, setTokenName] = React.useState('');
+  const [tokenAccess, setTokenAccess] = React.useState('read');
+  const [tokenExpiresInDays, setTokenExpiresInDays] = React.useState(30);
+  const [createdToken, setCreatedToken] = React.useState('');
+
+  const [tokensFilter, setTokensFilter] = React.useState('');
+  const filterTokens = (args: any) => {
+    setTokensFilter(args.target.value);
+  };
+
+  const resetTokenForm = () => {
+    setModalOpen(false);
+    setTokenName('');
+    setTokenAccess('read');
+    setTokenExpiresInDays(30);
+  };
+
+  const handleTokenFormSubmit = () => {
+    const scopes = tokenAccess === 'write' ? ['read', 'write'] : ['read'];
+    createAPIToken({name: tokenName, scopes: scopes, expiresInDays: tokenExpiresInDays}).unwrap()
+      .then((token: any) => setCreatedToken(token.token))
+      .catch((error) => dispatch(updateNotifications({notifications: [{notif: 'Error creating api token: ' + JSON.stringify(error), status: 'error'}]})));
+    resetTokenForm();
+  };
+
+  const handleRevokeToken = (id: string) => {
+    revokeAPIToken({id: id}).unwrap()
+      .then(() => dispatch(updateNotifications({notifications: [{notif: 'succesfully revoked api token', status: 'success'}]})))
+      .catch((error) => dispatch(updateNotifications({notifications: [{notif: 'Error revoking api token: ' + JSON.stringify(error), status: 'error'}]})));
+  };
+
+  return (
+    <>
+      <AdminDataTable
+        rows={
+          tokens.filter((token: any) => token.name.toLowerCase().includes(tokensFilter.toLowerCase())).map((token: any) => {
+            return {
+              id: token.id,
+              name: token.name,
+              hint: token.hint + '...',
+              scopes: token.scopes.join(', '),
+              expires: new Date(token.expiresAt).toLocaleString(),
+              lastUsed: token.lastUsed ? new Date(token.lastUsed).toLocaleString() : 'never',
+              actions: <Button renderIcon={Close} kind="ghost" size="sm" onClick={() => handleRevokeToken(token.id)}>
+                         Revoke
+                       </Button>
+            };
+          })
+        }
+        headers={[
+          {'header': 'Name', 'key': 'name'},
+          {'header': 'Token', 'key': 'hint'},
+          {'header': 'Scopes', 'key': 'scopes'},
+          {'header': 'Expires', 'key': 'expires'},
+          {'header': 'Last Used', 'key': 'lastUsed'},
+          {'header': 'Actions', 'key': 'actions'}
+        ]}
+        filterFunction={filterTokens}
+        filterPlaceholder="Filter api tokens"
+        filterValue={tokensFilter}
+        title={'API Tokens'}
+        upsertFunction={() => setModalOpen(true)}
+        upsertFunctionTitle={'Create API Token'}
+      />
+
+      <ComposedModal open={modalOpen} onClose={() => {resetTokenForm();}}>
+        <ModalHeader label="API Tokens" title="Create a new api token" />
+        <ModalBody>
+          <p style={{marginBottom: '1rem'}}>
+            API tokens authenticate scripts and pipelines as you using an `Authorization: Bearer` header. A token can never do more than you can.
+          </p>
+          <TextInput
+            data-modal-primary-focus
+            onChange={(e: any) => {setTokenName(e.target.value);}}
+            id="token-name-input"
+            labelText="Token Name"
+            placeholder="e.g. ci-pipeline"
+            style={{marginBottom: '1rem'}}
+            value={tokenName}
+          />
+          <NumberInput
+            id="token-expiry-input"
+            label="Expires in (days)"
+            min={1}
+            max={365}
+            value={tokenExpiresInDays}
+            onChange={(e: any, state: any) => {setTokenExpiresInDays(state.value);}}
+            style={{marginBottom: '1rem'}}
+          />
+          <RadioButtonGroup
+            valueSelected={tokenAccess}
+            onChange={(e: any) => {setTokenAccess(e);}}
+            legendText="Select token access level"
+            name="token-access-selector">
+              <RadioButton labelText="read" value="read" id="token-radio-1"/>
+              <RadioButton labelText="read & write" value="write" id="token-radio-2"/>
+          </RadioButtonGroup>
+          <ButtonSet style={{marginTop: '20px'}}>
+            <Button kind="primary" onClick={() => handleTokenFormSubmit()}>
+              Submit
+            </Button>
+            <Button kind="secondary" onClick={() => {resetTokenForm();}}>
+              Cancel
+            </Button>
+          </ButtonSet>
+        </ModalBody>
+      </ComposedModal>
+
+      <ComposedModal open={createdToken !== ''} onClose={() => {setCreatedToken('');}}>
+        <ModalHeader label="API Tokens" title="Your new api token" />
+        <ModalBody>
+          <p style={{marginBottom: '1rem'}}>
+            Copy this token now. It will not be shown again.
+          </p>
+          <CodeSnippet type="single">{createdToken}</CodeSnippet>
+        </ModalBody>
+      </ComposedModal>
+    </>
+  );
+};
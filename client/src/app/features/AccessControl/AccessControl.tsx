@@ -1,7 +1,7 @@
 import React from "react";
 import { ResourceDataTable } from "../../components/ResourceDataTable/ResourceDataTable";
 import { Identification, Events, GroupSecurity, Misuse, CheckmarkFilled, Edit, Close } from "@carbon/icons-react";
-import { useGetGroupsQuery, useGetPermissionsQuery, useGetUsersQuery, useUpsertGroupMutation, useUpsertPermissionMutation } from "../../../service/khub";
+import { useGetGroupsQuery, useGetPermissionsQuery, useGetUsersQuery, useUpsertGroupMutation, useUpsertPermissionMutation, useRevokeUserSessionsMutation, useDeleteUserMutation, useSetUserDeactivatedMutation, useUpdateUserAdminMutation, useDeleteGroupMutation, useDeletePermissionMutation } from "../../../service/khub";
 import { AdminDataTable } from "../../components/AdminDataTable/AdminDataTable";
 import { useSelector } from "react-redux";
 import { RootState, useAppDispatch } from "../../store";
@@ -23,6 +23,12 @@ export const AccessControl = () => {
   const {data: permissions = []} = useGetPermissionsQuery({});
 
   const [upsertGroup] = useUpsertGroupMutation();
+  const [revokeUserSessions] = useRevokeUserSessionsMutation();
+  const [deleteUser] = useDeleteUserMutation();
+  const [setUserDeactivated] = useSetUserDeactivatedMutation();
+  const [updateUserAdmin] = useUpdateUserAdminMutation();
+  const [deleteGroup] = useDeleteGroupMutation();
+  const [deletePermission] = useDeletePermissionMutation();
   // eslint-disable-next-line @typescript-eslint/no-unused-vars
   const [upsertPermission] = useUpsertPermissionMutation();
 
@@ -88,6 +94,18 @@ export const AccessControl = () => {
     resetSelectedPermission();
   };
 
+  const handleRevokeUserSessions = (id: string, name: string) => {
+    revokeUserSessions({id: id}).unwrap()
+      .then(() => dispatch(updateNotifications({notifications: [{notif: 'succesfully revoked sessions for ' + name, status: 'success'}]})))
+      .catch((error) => dispatch(updateNotifications({notifications: [{notif: 'Error revoking user sessions: ' + JSON.stringify(error), status: 'error'}]})));
+  };
+
+  const handleAdminAction = (action: Promise<any>, notif: string) => {
+    action
+      .then(() => dispatch(updateNotifications({notifications: [{notif: notif, status: 'success'}]})))
+      .catch((error) => dispatch(updateNotifications({notifications: [{notif: 'Error: ' + JSON.stringify(error?.data ?? error), status: 'error'}]})));
+  };
+
   const resetSelectedPermission = () => {
     setSelectedPermissionID(defaultUUID);
     setPermissionsModalOpen(false);
@@ -117,10 +135,25 @@ export const AccessControl = () => {
                     id: user.id,
                     name: user.name, 
                     email: user.email, 
-                    isAdmin: user.isAdmin === true ? <CheckmarkFilled color="green" /> : <Misuse color="coral"/>
+                    isAdmin: user.isAdmin === true ? <CheckmarkFilled color="green" /> : <Misuse color="coral"/>,
+                    status: user.deactivated === true ? <Tag type="red">deactivated</Tag> : <Tag type="green">active</Tag>,
+                    actions: <ButtonSet>
+                               <Button renderIcon={Close} kind="ghost" size="sm" onClick={() => handleRevokeUserSessions(user.id, user.name)}>
+                                 Revoke Sessions
+                               </Button>
+                               <Button kind="ghost" size="sm" disabled={user.isServiceAccount} onClick={() => handleAdminAction(updateUserAdmin({id: user.id, isAdmin: !user.isAdmin}).unwrap(), 'succesfully updated admin access for ' + user.name)}>
+                                 {user.isAdmin ? 'Revoke Admin' : 'Grant Admin'}
+                               </Button>
+                               <Button kind="ghost" size="sm" onClick={() => handleAdminAction(setUserDeactivated({id: user.id, deactivated: !user.deactivated}).unwrap(), 'succesfully updated ' + user.name)}>
+                                 {user.deactivated ? 'Activate' : 'Deactivate'}
+                               </Button>
+                               <Button renderIcon={Close} kind="danger--ghost" size="sm" onClick={() => handleAdminAction(deleteUser({id: user.id}).unwrap(), 'succesfully deleted ' + user.name)}>
+                                 Delete
+                               </Button>
+                             </ButtonSet>
                   };
                 })}
-                headers={[{'header': 'Name', 'key': 'name'}, {'header': 'Email', 'key': 'email'}, {'header': 'Admin', 'key': 'isAdmin'}]} 
+                headers={[{'header': 'Name', 'key': 'name'}, {'header': 'Email', 'key': 'email'}, {'header': 'Admin', 'key': 'isAdmin'}, {'header': 'Status', 'key': 'status'}, {'header': 'Actions', 'key': 'actions'}]} 
                 filterFunction={filterUsers}
                 filterPlaceholder="Filter users"
                 filterValue={usersFilter}
@@ -157,10 +190,10 @@ export const AccessControl = () => {
                               Edit
                             </Button> 
                             <Button 
-                              disabled
                               renderIcon={Close}
                               kind="ghost" 
                               size="sm"
+                              onClick={() => handleAdminAction(deleteGroup({id: group.id}).unwrap(), 'succesfully deleted group ' + group.name)}
                             >
                               Delete
                             </Button>
@@ -206,10 +239,10 @@ export const AccessControl = () => {
                               Edit
                             </Button> 
                             <Button 
-                              disabled
                               renderIcon={Close} 
                               kind="ghost" 
                               size="sm"
+                              onClick={() => handleAdminAction(deletePermission({id: perm.id}).unwrap(), 'succesfully deleted permission ' + perm.name)}
                             >
                               Delete
                             </Button>
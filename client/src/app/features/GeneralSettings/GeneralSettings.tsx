@@ -1,16 +1,17 @@
-import { Accordion, AccordionItem, Button, ButtonSet, ClickableTile, ComposedModal, Heading, ModalBody, ModalHeader, NumberInput, StructuredListBody, StructuredListCell, StructuredListHead, StructuredListRow, StructuredListWrapper, Tag, TextInput, Tile, Toggle, Tooltip } from "@carbon/react";
+import { Accordion, AccordionItem, Button, ButtonSet, ClickableTile, ComposedModal, Heading, ModalBody, ModalHeader, NumberInput, Select, SelectItem, StructuredListBody, StructuredListCell, StructuredListHead, StructuredListRow, StructuredListWrapper, Tag, TextInput, Tile, Toggle, Tooltip } from "@carbon/react";
 import React, { useEffect } from "react";
 import { SiMysql } from "react-icons/si";
 import { TbTrash, TbEdit } from "react-icons/tb";
 import { PiPlusBold } from "react-icons/pi";
 import { SiKubernetes } from "react-icons/si";
-import { useDeleteMySQLDBInfoMutation, useGetMySQLDBCatalogQuery, useUpdateDynamicAppConfigMutation, useUpsertMySQLDBInfoMutation } from "../../../service/khub";
+import { useAcceptMySQLDiscoveryProposalMutation, useDeleteMySQLDBInfoMutation, useGetMySQLDBCatalogQuery, useGetMySQLDiscoveryProposalsQuery, useSetMySQLDiscoveryProposalStatusMutation, useUpdateDynamicAppConfigMutation, useUpsertMySQLDBInfoMutation } from "../../../service/khub";
 import { useAppDispatch } from "../../store";
 import { updateNotifications } from "../../../service/notifications";
 import { CheckmarkFilled, Misuse } from "@carbon/icons-react";
 import { PiCaretCircleUpDownLight } from "react-icons/pi";
 import { CgNametag } from "react-icons/cg";
 import { IAppConfig } from "../../../service/types/AppConfig";
+import { PostgresCatalog } from "./PostgresCatalog";
 
 const DBSettingsTitle = () => {
   return (
@@ -59,9 +60,19 @@ export const GeneralSettings = ({appConfig}: GeneralSettingsProps) => {
   const [selectedMySQLDBPort, setSelectedMySQLDBPort] = React.useState<number>(3306);
   const [selectedMySQLDBUsername, setSelectedMySQLDBUsername] = React.useState<string>('');
   const [selectedMySQLDBIsPrimary, setSelectedMySQLDBIsPrimary] = React.useState<boolean>(false);
+  const [selectedMySQLDBCredential, setSelectedMySQLDBCredential] = React.useState<any>({});
 
   const [mySQLDBInfoModalOpen, setMySQLDBInfoModalOpen] = React.useState(false);
 
+  /* MySQL discovery proposals are accepted through the catalog modal
+  /
+  /
+  */
+  const {data: mysqlDiscoveryProposals = []} = useGetMySQLDiscoveryProposalsQuery({});
+  const [acceptMySQLDiscoveryProposal] = useAcceptMySQLDiscoveryProposalMutation();
+  const [setMySQLDiscoveryProposalStatus] = useSetMySQLDiscoveryProposalStatusMutation();
+  const [acceptingProposal, setAcceptingProposal] = React.useState<boolean>(false);
+
   const resetSelectedMySQLDB = () => {
     setSelectedMySQLDBHost('');
     setSelectedMySQLDBShortname('');
@@ -69,6 +80,8 @@ export const GeneralSettings = ({appConfig}: GeneralSettingsProps) => {
     setSelectedMySQLDBUsername('');
     setMySQLDBInfoModalOpen(false);
     setSelectedMySQLDBIsPrimary(false);
+    setSelectedMySQLDBCredential({});
+    setAcceptingProposal(false);
   };
 
   const handleMySQLInfoUpsertModalOpen = (db: any) => {
@@ -78,10 +91,29 @@ export const GeneralSettings = ({appConfig}: GeneralSettingsProps) => {
     setSelectedMySQLDBPort(db.port);
     setSelectedMySQLDBUsername(db.username);
     setSelectedMySQLDBIsPrimary(db.isPrimary);
+    setSelectedMySQLDBCredential(db.credential ?? {});
+  };
+
+  const handleAcceptProposalModalOpen = (proposal: any) => {
+    handleMySQLInfoUpsertModalOpen({...proposal, username: appConfig?.data?.mySQLDiscovery?.username ?? '', isPrimary: false});
+    setAcceptingProposal(true);
+  };
+
+  const handleIgnoreProposal = (proposal: any) => {
+    setMySQLDiscoveryProposalStatus({host: proposal.host, action: 'ignore'}).unwrap()
+    .then(() => dispatch(updateNotifications({notifications: [{notif: `${proposal.host} will no longer be proposed`, status: 'success'}]})))
+    .catch((error) => dispatch(updateNotifications({notifications: [{notif: 'Error ignoring discovered host: ' + JSON.stringify(error), status: 'error'}]})));
   };
 
   const handleUpsertMySQLDBInfo = () => {
-    upsertMySQLDBInfo({host: selectedMySQLDBHost, shortName: selectedMySQLDBShortname, port: selectedMySQLDBPort, username: selectedMySQLDBUsername, isPrimary: selectedMySQLDBIsPrimary}).unwrap()
+    if (acceptingProposal) {
+      acceptMySQLDiscoveryProposal({host: selectedMySQLDBHost, shortName: selectedMySQLDBShortname, port: selectedMySQLDBPort, username: selectedMySQLDBUsername, isPrimary: selectedMySQLDBIsPrimary, credential: selectedMySQLDBCredential}).unwrap()
+      .then(() => dispatch(updateNotifications({notifications: [{notif: 'succesfully added the discovered host to the catalog', status: 'success'}]})))
+      .catch((error) => dispatch(updateNotifications({notifications: [{notif: 'Error accepting discovered host: ' + JSON.stringify(error), status: 'error'}]})));
+      resetSelectedMySQLDB();
+      return;
+    }
+    upsertMySQLDBInfo({host: selectedMySQLDBHost, shortName: selectedMySQLDBShortname, port: selectedMySQLDBPort, username: selectedMySQLDBUsername, isPrimary: selectedMySQLDBIsPrimary, credential: selectedMySQLDBCredential}).unwrap()
     .then(() => dispatch(updateNotifications({notifications: [{notif: 'succesful mysql db info upsert', status: 'success'}]})))
     .catch((error) => dispatch(updateNotifications({notifications: [{notif: 'Error upserting db info: ' + JSON.stringify(error), status: 'error'}]})));
     
@@ -100,10 +132,19 @@ export const GeneralSettings = ({appConfig}: GeneralSettingsProps) => {
   /
   */
   const [updateAppConfig] = useUpdateDynamicAppConfigMutation();
-  const handleUpdateAppConfig = (config: {id: number, data: any}, keyName: string) => {
+  const handleUpdateAppConfig = (config: IAppConfig, keyName: string) => {
     updateAppConfig(config).unwrap().then(() => {
       dispatch(updateNotifications({notifications: [{notif: `${keyName} updated successfully`, status: 'success'}]}));
     }).catch((error) => {
+      if (error?.status === 400 && error?.data?.errors) {
+        const fieldErrors = error.data.errors.map((e: {field: string, message: string}) => `${e.field} ${e.message}`).join('; ');
+        dispatch(updateNotifications({notifications: [{notif: `Invalid dynamic app config: ${fieldErrors}`, status: 'error'}]}));
+        return;
+      }
+      if (error?.status === 409) {
+        dispatch(updateNotifications({notifications: [{notif: 'The dynamic app config was updated by someone else. The latest version has been loaded, please review it and try again', status: 'error'}]}));
+        return;
+      }
       dispatch(updateNotifications({notifications: [{notif: 'Error updating dynamic app config: ' + JSON.stringify(error), status: 'error'}]}));
     });
   };
@@ -111,14 +152,14 @@ export const GeneralSettings = ({appConfig}: GeneralSettingsProps) => {
   const [enableK8sGlobalReadOnly, setEnableK8sGlobalReadOnly] = React.useState<boolean>(false);
   const handleUpdateEnableK8sGlobalReadOnly = (val: boolean) => {
     setEnableK8sGlobalReadOnly(val);
-    const updatedAppConfig: IAppConfig = {id: appConfig.id, data: {...appConfig.data, enableK8sGlobalReadOnly: val}};
+    const updatedAppConfig: IAppConfig = {id: appConfig.id, version: appConfig.version, data: {...appConfig.data, enableK8sGlobalReadOnly: val}};
     handleUpdateAppConfig(updatedAppConfig, 'enableK8sGlobalReadOnly');
   };
 
   const [defaultReplicaScaleLimit, setDefaultReplicaScaleLimit] = React.useState<number>(0);
   const handleUpdateDefaultReplicaScaleLimit = (val: number) => {
     setDefaultReplicaScaleLimit(val);
-    const updatedAppConfig: IAppConfig = {id: appConfig.id, data: {...appConfig.data, defaultReplicaScaleLimit: val}};
+    const updatedAppConfig: IAppConfig = {id: appConfig.id, version: appConfig.version, data: {...appConfig.data, defaultReplicaScaleLimit: val}};
     handleUpdateAppConfig(updatedAppConfig, 'defaultReplicaScaleLimit');
   };
   
@@ -129,7 +170,7 @@ export const GeneralSettings = ({appConfig}: GeneralSettingsProps) => {
   };
 
   const handleRemovek8sClusterNamespace = (namespace: string) => {
-    const updatedAppConfig: IAppConfig = {id: appConfig.id, data: {
+    const updatedAppConfig: IAppConfig = {id: appConfig.id, version: appConfig.version, data: {
       ...appConfig.data, 
       k8sClusterNamespaces: appConfig.data?.k8sClusterNamespaces.filter((ns: string) => ns !== namespace)
     }};
@@ -144,7 +185,7 @@ export const GeneralSettings = ({appConfig}: GeneralSettingsProps) => {
     if (appConfig.data?.k8sClusterNamespaces !== null) {
       namespaces.push(...appConfig.data?.k8sClusterNamespaces);
     }
-    const updatedAppConfig: IAppConfig = {id: appConfig.id, data: {
+    const updatedAppConfig: IAppConfig = {id: appConfig.id, version: appConfig.version, data: {
       ...appConfig.data, 
       k8sClusterNamespaces: [...namespaces, namespace]
     }};
@@ -165,7 +206,7 @@ export const GeneralSettings = ({appConfig}: GeneralSettingsProps) => {
     }
     delete replicaScaleLimitMap[label];
 
-    const updatedAppConfig: IAppConfig = {id: appConfig.id, data: {
+    const updatedAppConfig: IAppConfig = {id: appConfig.id, version: appConfig.version, data: {
       ...appConfig.data,
       replicaScaleLimits: {...replicaScaleLimitMap}
     }};
@@ -182,7 +223,7 @@ export const GeneralSettings = ({appConfig}: GeneralSettingsProps) => {
     }
     replicaScaleLimitMap[label] = value;
 
-    const updatedAppConfig: IAppConfig = {id: appConfig.id, data: {
+    const updatedAppConfig: IAppConfig = {id: appConfig.id, version: appConfig.version, data: {
       ...appConfig.data,
       replicaScaleLimits: {...replicaScaleLimitMap}
     }};
@@ -209,6 +250,7 @@ export const GeneralSettings = ({appConfig}: GeneralSettingsProps) => {
 
     const updatedAppConfig: IAppConfig = {
       id: appConfig.id,
+      version: appConfig.version,
       data: {
         ...appConfig.data,
         k8sPodExecPlugins: plugins
@@ -230,6 +272,7 @@ export const GeneralSettings = ({appConfig}: GeneralSettingsProps) => {
 
     const updatedAppConfig: IAppConfig = {
       id: appConfig.id,
+      version: appConfig.version,
       data: {
         ...appConfig.data,
         k8sPodExecPlugins: updatedPlugins
@@ -276,7 +319,7 @@ export const GeneralSettings = ({appConfig}: GeneralSettingsProps) => {
                       onChange={(e: any) => {setClusterName(e.target.value);}}
                       onKeyDown={(e: any) => {
                         if (e.key === 'Enter') {
-                          const updatedAppConfig: IAppConfig = {id: appConfig.id, data: {...appConfig.data, k8sClusterName: clusterName}};
+                          const updatedAppConfig: IAppConfig = {id: appConfig.id, version: appConfig.version, data: {...appConfig.data, k8sClusterName: clusterName}};
                           handleUpdateAppConfig(updatedAppConfig, 'clusterName');
                         }
                       }}
@@ -459,7 +502,9 @@ export const GeneralSettings = ({appConfig}: GeneralSettingsProps) => {
                   <StructuredListCell head>Name</StructuredListCell>
                   <StructuredListCell head>Host</StructuredListCell>
                   <StructuredListCell head>Username</StructuredListCell>
+                  <StructuredListCell head>Credential</StructuredListCell>
                   <StructuredListCell head>Primary</StructuredListCell>
+                  <StructuredListCell head>Origin</StructuredListCell>
                   <StructuredListCell head>Actions</StructuredListCell>
                 </StructuredListRow>
               </StructuredListHead>
@@ -469,7 +514,12 @@ export const GeneralSettings = ({appConfig}: GeneralSettingsProps) => {
                     <StructuredListCell noWrap>{db.shortName}</StructuredListCell>
                     <StructuredListCell>{db.host}:{db.port}</StructuredListCell>
                     <StructuredListCell>{db.username}</StructuredListCell>
+                    <StructuredListCell>{db.credential?.source ? db.credential.source : 'default'}</StructuredListCell>
                     <StructuredListCell>{db.isPrimary === true ? <CheckmarkFilled color="green" /> : <Misuse color="coral"/>}</StructuredListCell>
+                    <StructuredListCell>
+                      <Tag type={db.origin === 'manual' ? 'gray' : 'blue'} title={db.originRef}>{db.origin || 'manual'}</Tag>
+                      {db.lastSeenAt && <div style={{fontSize: 11}}>seen {new Date(db.lastSeenAt).toLocaleString()}</div>}
+                    </StructuredListCell>
                     <StructuredListCell>
                       <ButtonSet stacked>
                         <Button tooltipPosition='right' size='sm' onClick={() => handleMySQLInfoUpsertModalOpen(db)} iconDescription="Edit" style={{border: 'none'}} hasIconOnly renderIcon={TbEdit} kind="tertiary"/>
@@ -486,12 +536,44 @@ export const GeneralSettings = ({appConfig}: GeneralSettingsProps) => {
               </Tooltip>
             </ClickableTile>
           </AccordionItem>
+          <AccordionItem title={`Discovered hosts (${mysqlDiscoveryProposals.length})`} className="accordian-border-0">
+            <span className='accordian-subtitle'>MySQL hosts found by the discovery sources which are not in the catalog yet</span>
+            <StructuredListWrapper selection>
+              <StructuredListHead>
+                <StructuredListRow head>
+                  <StructuredListCell head>Name</StructuredListCell>
+                  <StructuredListCell head>Host</StructuredListCell>
+                  <StructuredListCell head>Origin</StructuredListCell>
+                  <StructuredListCell head>Last seen</StructuredListCell>
+                  <StructuredListCell head>Actions</StructuredListCell>
+                </StructuredListRow>
+              </StructuredListHead>
+              <StructuredListBody>
+                {mysqlDiscoveryProposals.map((proposal) => (
+                  <StructuredListRow key={proposal.host}>
+                    <StructuredListCell noWrap>{proposal.shortName}</StructuredListCell>
+                    <StructuredListCell>{proposal.host}:{proposal.port}</StructuredListCell>
+                    <StructuredListCell><Tag type='blue' title={proposal.originRef}>{proposal.origin}</Tag></StructuredListCell>
+                    <StructuredListCell>{new Date(proposal.lastSeenAt).toLocaleString()}</StructuredListCell>
+                    <StructuredListCell>
+                      <ButtonSet stacked>
+                        <Button tooltipPosition='right' size='sm' onClick={() => handleAcceptProposalModalOpen(proposal)} iconDescription="Accept" style={{border: 'none'}} hasIconOnly renderIcon={PiPlusBold} kind="tertiary"/>
+                        <Button tooltipPosition='right' size='sm' onClick={() => handleIgnoreProposal(proposal)} iconDescription="Ignore" style={{border: 'none'}} hasIconOnly renderIcon={Misuse} kind="danger--tertiary"/>
+                      </ButtonSet>
+                    </StructuredListCell>
+                  </StructuredListRow>
+                ))}
+              </StructuredListBody>
+            </StructuredListWrapper>
+          </AccordionItem>
         </Accordion>
       </Tile>
+
+      <PostgresCatalog />
       
       {/* MySQL DB Info Upsert Modal */} 
       <ComposedModal open={mySQLDBInfoModalOpen} onClose={() => {resetSelectedMySQLDB();}}>
-        <ModalHeader label="MySQL DB Catalog" title="Add a new db to the catalog" />
+        <ModalHeader label="MySQL DB Catalog" title={acceptingProposal ? "Add a discovered db to the catalog" : "Add a new db to the catalog"} />
         <ModalBody>
           <p style={{marginBottom: '1rem'}}>
             MySQL DBs in this catalog will be represented in the MySQL replication topology plugin.
@@ -501,6 +583,7 @@ export const GeneralSettings = ({appConfig}: GeneralSettingsProps) => {
             onChange={(e: any) => {setSelectedMySQLDBHost(e.target.value);}}
             id="dbhost" 
             labelText="Host" 
+            readOnly={acceptingProposal}
             placeholder="e.g. db-core-007.platform-databases.staging.smar.cloud" 
             style={{marginBottom: '1rem'}}
             value={selectedMySQLDBHost} 
@@ -523,6 +606,41 @@ export const GeneralSettings = ({appConfig}: GeneralSettingsProps) => {
             style={{marginBottom: '1rem'}}
             value={selectedMySQLDBUsername} 
           />
+          <Select
+            id="dbcredentialsource"
+            labelText="Password source"
+            value={selectedMySQLDBCredential.source ?? ''}
+            onChange={(e: any) => setSelectedMySQLDBCredential({source: e.target.value || undefined})}
+            style={{marginBottom: '1rem'}}
+          >
+            <SelectItem value="" text="Catalog password (default)" />
+            <SelectItem value="secret" text="Kubernetes secret" />
+            <SelectItem value="file" text="File" />
+            <SelectItem value="env" text="Environment variable" />
+          </Select>
+          {selectedMySQLDBCredential.source === 'secret' &&
+            <>
+              <TextInput id="dbsecretnamespace" labelText="Secret namespace" placeholder="e.g. databases" style={{marginBottom: '1rem'}}
+                value={selectedMySQLDBCredential.secretNamespace ?? ''}
+                onChange={(e: any) => setSelectedMySQLDBCredential({...selectedMySQLDBCredential, secretNamespace: e.target.value})} />
+              <TextInput id="dbsecretname" labelText="Secret name" placeholder="e.g. khub-monitor" style={{marginBottom: '1rem'}}
+                value={selectedMySQLDBCredential.secretName ?? ''}
+                onChange={(e: any) => setSelectedMySQLDBCredential({...selectedMySQLDBCredential, secretName: e.target.value})} />
+              <TextInput id="dbsecretkey" labelText="Secret key" placeholder="e.g. password" style={{marginBottom: '1rem'}}
+                value={selectedMySQLDBCredential.secretKey ?? ''}
+                onChange={(e: any) => setSelectedMySQLDBCredential({...selectedMySQLDBCredential, secretKey: e.target.value})} />
+            </>
+          }
+          {selectedMySQLDBCredential.source === 'file' &&
+            <TextInput id="dbcredentialfile" labelText="Password file path" placeholder="e.g. /etc/khub/credentials/db-core-007" style={{marginBottom: '1rem'}}
+              value={selectedMySQLDBCredential.filePath ?? ''}
+              onChange={(e: any) => setSelectedMySQLDBCredential({...selectedMySQLDBCredential, filePath: e.target.value})} />
+          }
+          {selectedMySQLDBCredential.source === 'env' &&
+            <TextInput id="dbcredentialenv" labelText="Password environment variable" placeholder="e.g. KHUB_CREDENTIAL_CORE_PASSWORD" style={{marginBottom: '1rem'}}
+              value={selectedMySQLDBCredential.envVar ?? ''}
+              onChange={(e: any) => setSelectedMySQLDBCredential({...selectedMySQLDBCredential, envVar: e.target.value})} />
+          }
           <NumberInput id="db-port" 
             max={99999} 
             value={selectedMySQLDBPort} 
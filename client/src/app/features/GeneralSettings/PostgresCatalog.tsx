@@ -0,0 +1,188 @@
+import { Accordion, AccordionItem, Button, ButtonSet, ClickableTile, ComposedModal, ModalBody, ModalHeader, NumberInput, Select, SelectItem, StructuredListBody, StructuredListCell, StructuredListHead, StructuredListRow, StructuredListWrapper, TextInput, Tile, Toggle, Tooltip } from "@carbon/react";
+import React from "react";
+import { SiPostgresql } from "react-icons/si";
+import { TbTrash, TbEdit } from "react-icons/tb";
+import { PiPlusBold } from "react-icons/pi";
+import { CheckmarkFilled, Misuse } from "@carbon/icons-react";
+import { useDeletePostgresDBInfoMutation, useGetPostgresDBCatalogQuery, useUpsertPostgresDBInfoMutation } from "../../../service/khub";
+import { IPostgresDBInfo } from "../../../service/types/PostgresDBInfo";
+import { useAppDispatch } from "../../store";
+import { updateNotifications } from "../../../service/notifications";
+
+const PostgresSettingsTitle = () => {
+  return (
+    <>
+    <div style={{ display: 'flex'}} >
+      <SiPostgresql size={50}/>
+      <h4 className='accordian-title'>Postgres Database Catalog</h4>
+    </div>
+    <span className='accordian-subtitle'>Manage Postgres databases represented in the replication topology</span>
+    <br/>
+    </>
+  );
+};
+
+const emptyPostgresDB: IPostgresDBInfo = {host: '', shortName: '', username: '', port: 5432, database: 'postgres', isPrimary: false, credential: {}};
+
+export const PostgresCatalog = () => {
+  const dispatch = useAppDispatch();
+
+  const {data: postgresDBCatalog = []} = useGetPostgresDBCatalogQuery({});
+  const [upsertPostgresDBInfo] = useUpsertPostgresDBInfoMutation();
+  const [deletePostgresDBInfo] = useDeletePostgresDBInfoMutation();
+
+  const [modalOpen, setModalOpen] = React.useState(false);
+  const [selectedDB, setSelectedDB] = React.useState<IPostgresDBInfo>(emptyPostgresDB);
+  const credential = selectedDB.credential ?? {};
+
+  const handleModalOpen = (db: IPostgresDBInfo) => {
+    setSelectedDB({...db, credential: db.credential ?? {}});
+    setModalOpen(true);
+  };
+
+  const handleModalClose = () => {
+    setSelectedDB(emptyPostgresDB);
+    setModalOpen(false);
+  };
+
+  const handleUpsertPostgresDBInfo = () => {
+    upsertPostgresDBInfo(selectedDB).unwrap()
+    .then(() => dispatch(updateNotifications({notifications: [{notif: 'succesful postgres db info upsert', status: 'success'}]})))
+    .catch((error) => dispatch(updateNotifications({notifications: [{notif: 'Error upserting db info: ' + JSON.stringify(error), status: 'error'}]})));
+    handleModalClose();
+  };
+
+  const handleDeletePostgresDBInfo = (db: IPostgresDBInfo) => {
+    deletePostgresDBInfo({host: db.host}).unwrap()
+    .then(() => dispatch(updateNotifications({notifications: [{notif: 'succesful postgres db info delete', status: 'success'}]})))
+    .catch((error) => dispatch(updateNotifications({notifications: [{notif: 'Error deleting db info: ' + JSON.stringify(error), status: 'error'}]})));
+  };
+
+  return (
+    <>
+      <Tile style={{marginTop: '10px'}}>
+        <Accordion size='lg' className='accordian-content-override'>
+          <AccordionItem title={PostgresSettingsTitle()} className="accordian-border-0" open>
+            <StructuredListWrapper selection>
+              <StructuredListHead>
+                <StructuredListRow head>
+                  <StructuredListCell head>Name</StructuredListCell>
+                  <StructuredListCell head>Host</StructuredListCell>
+                  <StructuredListCell head>Database</StructuredListCell>
+                  <StructuredListCell head>Username</StructuredListCell>
+                  <StructuredListCell head>Credential</StructuredListCell>
+                  <StructuredListCell head>Primary</StructuredListCell>
+                  <StructuredListCell head>Actions</StructuredListCell>
+                </StructuredListRow>
+              </StructuredListHead>
+              <StructuredListBody>
+                {postgresDBCatalog.map((db) => (
+                  <StructuredListRow key={db.host}>
+                    <StructuredListCell noWrap>{db.shortName}</StructuredListCell>
+                    <StructuredListCell>{db.host}:{db.port}</StructuredListCell>
+                    <StructuredListCell>{db.database}</StructuredListCell>
+                    <StructuredListCell>{db.username}</StructuredListCell>
+                    <StructuredListCell>{db.credential?.source ? db.credential.source : 'default'}</StructuredListCell>
+                    <StructuredListCell>{db.isPrimary === true ? <CheckmarkFilled color="green" /> : <Misuse color="coral"/>}</StructuredListCell>
+                    <StructuredListCell>
+                      <ButtonSet stacked>
+                        <Button tooltipPosition='right' size='sm' onClick={() => handleModalOpen(db)} iconDescription="Edit" style={{border: 'none'}} hasIconOnly renderIcon={TbEdit} kind="tertiary"/>
+                        <Button tooltipPosition='right' size='sm' onClick={() => handleDeletePostgresDBInfo(db)} iconDescription="Remove" style={{border: 'none'}} hasIconOnly renderIcon={TbTrash} kind="danger--tertiary"/>
+                      </ButtonSet>
+                    </StructuredListCell>
+                  </StructuredListRow>
+                ))}
+              </StructuredListBody>
+            </StructuredListWrapper>
+            <ClickableTile style={{display: 'flex', justifyContent: 'center'}} onClick={() => handleModalOpen(emptyPostgresDB)}>
+              <Tooltip label='add host'>
+                <PiPlusBold size={30}/>
+              </Tooltip>
+            </ClickableTile>
+          </AccordionItem>
+        </Accordion>
+      </Tile>
+
+      {/* Postgres DB Info Upsert Modal */}
+      <ComposedModal open={modalOpen} onClose={() => handleModalClose()}>
+        <ModalHeader label="Postgres DB Catalog" title="Add a new db to the catalog" />
+        <ModalBody>
+          <p style={{marginBottom: '1rem'}}>
+            Postgres DBs in this catalog will be represented in the Postgres replication topology plugin.
+          </p>
+          <TextInput id="pghost" labelText="Host" placeholder="e.g. pg-core-001.platform-databases.staging.smar.cloud" style={{marginBottom: '1rem'}}
+            value={selectedDB.host}
+            onChange={(e: any) => setSelectedDB({...selectedDB, host: e.target.value})} />
+          <TextInput id="pgshortname" labelText="Shortname" placeholder="e.g. pg-core-001" style={{marginBottom: '1rem'}}
+            value={selectedDB.shortName}
+            onChange={(e: any) => setSelectedDB({...selectedDB, shortName: e.target.value})} />
+          <TextInput id="pgdatabase" labelText="Database" helperText="Subscriptions are read from this database" placeholder="postgres" style={{marginBottom: '1rem'}}
+            value={selectedDB.database ?? ''}
+            onChange={(e: any) => setSelectedDB({...selectedDB, database: e.target.value})} />
+          <TextInput id="pgusername" labelText="Username" placeholder="e.g. khub" style={{marginBottom: '1rem'}}
+            value={selectedDB.username}
+            onChange={(e: any) => setSelectedDB({...selectedDB, username: e.target.value})} />
+          <Select
+            id="pgcredentialsource"
+            labelText="Password source"
+            value={credential.source ?? ''}
+            onChange={(e: any) => setSelectedDB({...selectedDB, credential: {source: e.target.value || undefined}})}
+            style={{marginBottom: '1rem'}}
+          >
+            <SelectItem value="" text="Catalog password (default)" />
+            <SelectItem value="secret" text="Kubernetes secret" />
+            <SelectItem value="file" text="File" />
+            <SelectItem value="env" text="Environment variable" />
+          </Select>
+          {credential.source === 'secret' &&
+            <>
+              <TextInput id="pgsecretnamespace" labelText="Secret namespace" placeholder="e.g. databases" style={{marginBottom: '1rem'}}
+                value={credential.secretNamespace ?? ''}
+                onChange={(e: any) => setSelectedDB({...selectedDB, credential: {...credential, secretNamespace: e.target.value}})} />
+              <TextInput id="pgsecretname" labelText="Secret name" placeholder="e.g. khub-monitor" style={{marginBottom: '1rem'}}
+                value={credential.secretName ?? ''}
+                onChange={(e: any) => setSelectedDB({...selectedDB, credential: {...credential, secretName: e.target.value}})} />
+              <TextInput id="pgsecretkey" labelText="Secret key" placeholder="e.g. password" style={{marginBottom: '1rem'}}
+                value={credential.secretKey ?? ''}
+                onChange={(e: any) => setSelectedDB({...selectedDB, credential: {...credential, secretKey: e.target.value}})} />
+            </>
+          }
+          {credential.source === 'file' &&
+            <TextInput id="pgcredentialfile" labelText="Password file path" placeholder="e.g. /etc/khub/credentials/pg-core-001" style={{marginBottom: '1rem'}}
+              value={credential.filePath ?? ''}
+              onChange={(e: any) => setSelectedDB({...selectedDB, credential: {...credential, filePath: e.target.value}})} />
+          }
+          {credential.source === 'env' &&
+            <TextInput id="pgcredentialenv" labelText="Password environment variable" placeholder="e.g. KHUB_CREDENTIAL_PG_CORE_PASSWORD" style={{marginBottom: '1rem'}}
+              value={credential.envVar ?? ''}
+              onChange={(e: any) => setSelectedDB({...selectedDB, credential: {...credential, envVar: e.target.value}})} />
+          }
+          <NumberInput id="pg-port"
+            min={1}
+            max={65535}
+            value={selectedDB.port ?? 5432}
+            label="DB Port"
+            invalidText="Port must be between 1 and 65535"
+            onChange={(e: any, data: any) => setSelectedDB({...selectedDB, port: data.value})}
+            hideSteppers
+            />
+          <Toggle
+            labelText="Primary database"
+            labelA="no"
+            labelB="yes"
+            id="pg-primary-toggle"
+            toggled={selectedDB.isPrimary}
+            onToggle={(val: any) => setSelectedDB({...selectedDB, isPrimary: val})}/>
+          <ButtonSet style={{marginTop: '20px'}}>
+            <Button kind="primary" onClick={() => handleUpsertPostgresDBInfo()}>
+              Submit
+            </Button>
+            <Button kind="secondary" onClick={() => handleModalClose()}>
+              Cancel
+            </Button>
+          </ButtonSet>
+        </ModalBody>
+      </ComposedModal>
+    </>
+  );
+};
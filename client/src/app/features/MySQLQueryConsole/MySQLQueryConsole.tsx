@@ -0,0 +1,80 @@
+import React, { useMemo, useState } from 'react';
+import { Button, InlineNotification, Select, SelectItem, TextInput, Tile } from '@carbon/react';
+import { useGetMySQLQueryPluginsQuery, useGetMySQLReplicationTopologyGraphQuery, useRunMySQLQueryPluginMutation } from '../../../service/khub';
+import { ResourceDataTable } from '../../components/ResourceDataTable/ResourceDataTable';
+
+// MySQLQueryConsole runs the read only query plugins the user is permitted to run against a catalog host
+export const MySQLQueryConsole = () => {
+  const {data: plugins} = useGetMySQLQueryPluginsQuery({});
+  // Catalog hosts are listed from the topology, which every user can read
+  const {data: topologyGraph} = useGetMySQLReplicationTopologyGraphQuery({});
+  const [runQuery, {data: result, error, isLoading, reset}] = useRunMySQLQueryPluginMutation();
+
+  const [pluginName, setPluginName] = useState('');
+  const [host, setHost] = useState('');
+  const [args, setArgs] = useState<Record<string, string>>({});
+  const [filter, setFilter] = useState('');
+
+  const plugin = (plugins ?? []).find((p) => p.name === pluginName);
+  const hosts: string[] = (topologyGraph?.nodes ?? [])
+    .filter((n: any) => !n.data.consumerType)
+    .map((n: any) => n.id)
+    .filter((h: string) => !plugin?.hosts?.length || plugin.hosts.includes(h));
+
+  const headers = useMemo(() => (result?.columns ?? []).map((c, i) => ({key: `c${i}`, header: c})), [result]);
+  const rows = useMemo(() => (result?.rows ?? [])
+    .map((r, i) => {
+      const row: any = {id: `${i}`};
+      r.forEach((v, j) => { row[`c${j}`] = v === null ? 'NULL' : v; });
+      return row;
+    })
+    .filter((row) => filter === '' || Object.values(row).some((v: any) => `${v}`.toLowerCase().includes(filter.toLowerCase()))), [result, filter]);
+
+  return (
+    <div style={{height: '100%'}}>
+      <Tile style={{marginBottom: '16px'}}>
+        <Select id="mysql-query-plugin" labelText="Query" value={pluginName} onChange={(e: any) => { setPluginName(e.target.value); setArgs({}); reset(); }}>
+          <SelectItem value="" text="Choose a query" />
+          {(plugins ?? []).map((p) => <SelectItem key={p.name} value={p.name} text={p.name} />)}
+        </Select>
+        {plugin?.description && <p style={{marginTop: '8px'}}>{plugin.description}</p>}
+        {plugin && <pre style={{marginTop: '8px', whiteSpace: 'pre-wrap', fontSize: '12px'}}>{plugin.query}</pre>}
+        <Select id="mysql-query-host" labelText="Host" value={host} onChange={(e: any) => setHost(e.target.value)} style={{marginTop: '8px'}}>
+          <SelectItem value="" text="Choose a host" />
+          {hosts.map((h) => <SelectItem key={h} value={h} text={h} />)}
+        </Select>
+        {(plugin?.parameters ?? []).map((param) => (
+          param.type === 'enum' ?
+            <Select key={param.name} id={`mysql-query-arg-${param.name}`} labelText={param.name} helperText={param.description}
+              value={args[param.name] ?? param.default ?? ''} onChange={(e: any) => setArgs({...args, [param.name]: e.target.value})}>
+              <SelectItem value="" text="Choose a value" />
+              {(param.values ?? []).map((v) => <SelectItem key={v} value={v} text={v} />)}
+            </Select> :
+            <TextInput key={param.name} id={`mysql-query-arg-${param.name}`} labelText={param.name} helperText={param.description}
+              placeholder={param.default} value={args[param.name] ?? ''} onChange={(e: any) => setArgs({...args, [param.name]: e.target.value})}/>
+        ))}
+        <Button style={{marginTop: '16px'}} disabled={!plugin || !host || isLoading}
+          onClick={() => runQuery({host: host, name: pluginName, args: args})}>
+          {isLoading ? 'Running...' : 'Run query'}
+        </Button>
+      </Tile>
+      {error &&
+        <InlineNotification kind="error" title="Query failed" subtitle={`${(error as any).data ?? ''}`} hideCloseButton/>
+      }
+      {result?.truncated &&
+        <InlineNotification kind="warning" title="Truncated" subtitle={`Only the first ${result.rows.length} rows are shown`} hideCloseButton/>
+      }
+      {result &&
+        <ResourceDataTable
+          rows={rows}
+          headers={headers}
+          filterFunction={(e: any) => setFilter(e.target.value)}
+          filterPlaceholder={'Filter rows'}
+          filterValue={filter}
+          title={`${result.plugin} on ${result.host} (${result.rows.length} rows, ${result.durationMs}ms)`}
+          batchActions={[]}
+        />
+      }
+    </div>
+  );
+};
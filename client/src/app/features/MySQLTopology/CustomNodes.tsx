@@ -1,10 +1,63 @@
 import { Tile } from '@carbon/react';
 import React from 'react';
 import { Handle, Position } from 'reactflow';
-import { TbDatabaseStar, TbDatabase, TbDatabaseHeart } from "react-icons/tb";
+import { TbDatabaseStar, TbDatabase, TbDatabaseHeart, TbTransferOut, TbPlugConnected } from "react-icons/tb";
 
+// formatBytes formats a replication lag in bytes of WAL
+export const formatBytes = (bytes: number) => {
+  const units = ['B', 'KiB', 'MiB', 'GiB', 'TiB'];
+  let value = bytes;
+  let unit = 0;
+  while (value >= 1024 && unit < units.length - 1) {
+    value /= 1024;
+    unit++;
+  }
+  return `${unit === 0 ? value : value.toFixed(1)} ${units[unit]}`;
+};
+
+// channelsHealthy reports whether every replication channel of a database is running without errors
+const channelsHealthy = (channels: any[] | null) => {
+  return (channels ?? []).every((c: any) => c.ioState === 'ON' && c.sqlState === 'ON' && !c.lastIOError && !c.lastSQLError);
+};
+
+// consumerColors are the colors of the binlog consumer types
+export const consumerColors: {[consumerType: string]: string} = {
+  dms: '#8a3ffc',
+  cdc: '#1192e8',
+};
+
+// ConsumerNode is a DMS task or CDC connector reading the binlog of a catalog host
+export const ConsumerNode = ({data}: any) => {
+  const color = consumerColors[data.consumerType] ?? 'gray';
+  return (
+    <>
+      <Handle style={{visibility: 'hidden'}} type="target" position={Position.Top} id="left"/>
+      <Handle style={{visibility: 'hidden'}} type="target" position={Position.Top} id="top"/>
+      <Tile style={{
+        borderRadius: '25px',
+        border: '1px dashed ' + color,
+        maxWidth: '300px',
+        minWidth: '200px',
+        height: '90px',
+        padding: '10px',
+        fontSize: '10px',
+        outline: data.changed ? '2px solid #f1c21b' : undefined,
+      }}>
+        {data.consumerType === 'cdc' ?
+          <TbPlugConnected color={color} size={40} style={{marginTop: '5px', marginBottom: '5px'}}/> :
+          <TbTransferOut color={color} size={40} style={{marginTop: '5px', marginBottom: '5px'}}/>
+        }
+        <strong style={{marginLeft: '10px', fontSize: '16px'}}>
+          {data.shortName}
+        </strong> <br/>
+        <span style={{marginLeft: '10px'}}>{data.consumerType === 'cdc' ? 'CDC connector' : 'DMS task'} reading {data.source}</span>
+      </Tile>
+    </>
+  );
+};
 
 export const ReplTopoNode = ({data}: any) => {
+  const healthy = channelsHealthy(data.channels);
   const innerData = () => {
     return (
       <div>
@@ -24,6 +77,18 @@ export const ReplTopoNode = ({data}: any) => {
         <strong style={{marginLeft: '10px', fontSize: '16px'}}>
             {data.shortName}
         </strong> <br/>
+        {data.secondsBehindSource !== null && data.secondsBehindSource !== undefined &&
+          <span style={{marginLeft: '10px'}}>{data.secondsBehindSource}s behind source</span>
+        }
+        {data.bytesBehindSource !== null && data.bytesBehindSource !== undefined &&
+          <span style={{marginLeft: '10px'}}>{formatBytes(data.bytesBehindSource)} of WAL behind</span>
+        }
+        {!healthy &&
+          <span style={{marginLeft: '10px', color: '#da1e28'}}>replication stopped or erroring</span>
+        }
+        {data.probeError &&
+          <span style={{marginLeft: '10px', color: '#8d8d8d'}} title={data.probeError}>unreachable: {data.probeError}</span>
+        }
       </div>
     );
   };
@@ -36,6 +101,14 @@ export const ReplTopoNode = ({data}: any) => {
   } else {
     borderColor = '#ff832b';
   }
+  if (!healthy) {
+    borderColor = '#da1e28';
+  }
+  if (data.probeError) {
+    borderColor = '#8d8d8d';
+  }
+  // Nodes that changed in the displayed capture are outlined
+  const outline = data.changed ? '2px solid #f1c21b' : undefined;
 
   return (
     <>
@@ -51,6 +124,7 @@ export const ReplTopoNode = ({data}: any) => {
           height: '115px',
           padding: '10px',
           fontSize: '10px',
+          outline: outline,
         }}>
         {innerData()}
         </Tile>
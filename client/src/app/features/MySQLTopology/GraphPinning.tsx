@@ -0,0 +1,46 @@
+import React, { useCallback } from "react";
+import { useSelector } from "react-redux";
+import { Button } from '@carbon/react';
+import { Panel } from 'reactflow';
+
+import { RootState, useAppDispatch } from "../../store";
+import { useGetGraphNodePositionsQuery, usePinGraphNodePositionMutation, useUnpinGraphNodePositionMutation } from "../../../service/khub";
+import { GraphName } from "../../../service/types/ReplTopology";
+import { updateNotifications } from "../../../service/notifications";
+
+// useGraphNodePinning pins the nodes admins drag at their new position, so the server positions them there for everyone
+export const useGraphNodePinning = (graph: GraphName) => {
+  const dispatch = useAppDispatch();
+  const userIsAdmin = useSelector((state: RootState) => state.userIsAdminState);
+  const {data: pinned} = useGetGraphNodePositionsQuery({graph}, {skip: !userIsAdmin.isAdmin});
+  const [pinGraphNodePosition] = usePinGraphNodePositionMutation();
+  const [unpinGraphNodePosition] = useUnpinGraphNodePositionMutation();
+
+  const onNodeDragStop = useCallback((_event: any, node: any) => {
+    if (!userIsAdmin.isAdmin) {
+      return;
+    }
+    pinGraphNodePosition({graph, node: node.id, x: Math.round(node.position.x), y: Math.round(node.position.y)}).unwrap()
+      .catch((error) => dispatch(updateNotifications({notifications: [{notif: 'Error pinning ' + node.id + ' ' + JSON.stringify(error), status: 'error'}]})));
+  }, [graph, userIsAdmin, pinGraphNodePosition, dispatch]);
+
+  const unpinAll = useCallback(() => {
+    Promise.all((pinned ?? []).map((p) => unpinGraphNodePosition({graph, node: p.nodeId}).unwrap()))
+      .catch((error) => dispatch(updateNotifications({notifications: [{notif: 'Error unpinning nodes ' + JSON.stringify(error), status: 'error'}]})));
+  }, [graph, pinned, unpinGraphNodePosition, dispatch]);
+
+  return {canPin: userIsAdmin.isAdmin, pinned: pinned ?? [], onNodeDragStop, unpinAll};
+};
+
+// PinnedNodesPanel lets admins return the nodes they pinned to the server side layout
+export const PinnedNodesPanel = ({pinned, unpinAll}: {pinned: any[], unpinAll: () => void}) => {
+  if (pinned.length === 0) {
+    return null;
+  }
+  return (
+    <Panel className='cds--tile' position="bottom-center">
+      <p style={{fontSize: '12px'}}>{pinned.length} pinned node{pinned.length === 1 ? '' : 's'}</p>
+      <Button kind="ghost" size="sm" onClick={unpinAll}>Reset layout</Button>
+    </Panel>
+  );
+};
@@ -1,6 +1,8 @@
 
 import React, { useCallback, useEffect, useMemo, useState } from "react";
-import { useGetMySQLReplicationTopologyGraphQuery } from "../../../service/khub";
+import { useGetMySQLReplicationTopologyGraphQuery, useGetMySQLReplicationTopologyHistoryQuery } from "../../../service/khub";
+import { IReplTopoChange } from "../../../service/types/ReplTopology";
+import { Select, SelectItem } from '@carbon/react';
 
 import 'reactflow/dist/style.css';
 
@@ -18,92 +20,138 @@ import ReactFlow, {
   Panel
 } from 'reactflow';
 
-import { stratify, tree } from 'd3';
-
-import { ReplTopoNode } from "./CustomNodes";
+import { ConsumerNode, consumerColors, formatBytes, ReplTopoNode } from "./CustomNodes";
+import { PinnedNodesPanel, useGraphNodePinning } from "./GraphPinning";
 import { BsArrows } from "react-icons/bs";
 import { TbArrowWaveRightDown } from "react-icons/tb";
 
-import { TbDatabase, TbDatabaseHeart, TbDatabaseStar } from "react-icons/tb";
-
-const nodeWidth = 285;
-const nodeHeight = 260;
+import { TbDatabase, TbDatabaseHeart, TbDatabaseStar, TbPlugConnected, TbTransferOut } from "react-icons/tb";
 
-// eslint-disable-next-line @typescript-eslint/no-unused-vars
-const getLayoutedElements = (nodes: any[], edges: any[], direction = 'TB') => {
-  // Create a hierarchy from the nodes and edges
-  const root = stratify()
-    .id((d: any) => d.id)
-    .parentId((d: any) => {
-      const edge = edges.find(edge => edge.target === d.id);
-      return edge ? edge.source : null;
-    })(nodes);
+const unhealthyColor = '#da1e28';
+const changedColor = '#f1c21b';
 
-  // Create a tree layout
-  const treeLayout = tree().nodeSize([nodeWidth, nodeHeight]);
+// markChanges flags the nodes and edges that changed in the displayed capture, so they can be highlighted
+const markChanges = (nodes: any[], edges: any[], changes: IReplTopoChange[] | null | undefined) => {
+  const changedNodes = new Set((changes ?? []).filter((c) => c.node).map((c) => c.node));
+  const changedEdges = new Set((changes ?? []).filter((c) => c.edge).map((c) => c.edge));
+  return {
+    nodes: nodes.map((node) => ({...node, data: {...node.data, changed: changedNodes.has(node.id)}})),
+    edges: edges.map((edge) => changedEdges.has(edge.id) ? {
+      ...edge,
+      style: {...edge.style, strokeWidth: 3, stroke: edge.health === 'unhealthy' ? unhealthyColor : changedColor},
+    } : edge),
+  };
+};
 
-  // Apply the layout to the hierarchy
-  const treeData = treeLayout(root);
+// edgeDetails labels an edge with the lag and the last error of its replication channels, and colors unhealthy links.
+// Postgres links are labelled with their kind, and logical subscriptions are dotted.
+const edgeDetails = (edge: any) => {
+  const details: any = {};
+  const label = [];
+  if (edge.kind) {
+    label.push(edge.kind);
+  }
+  if (edge.secondsBehindSource !== null && edge.secondsBehindSource !== undefined) {
+    label.push(`${edge.secondsBehindSource}s behind`);
+  }
+  if (edge.bytesBehindSource !== null && edge.bytesBehindSource !== undefined) {
+    label.push(`${formatBytes(edge.bytesBehindSource)} behind`);
+  }
+  if (edge.lastError) {
+    label.push(`error ${edge.lastError.number}: ${edge.lastError.message}`);
+  }
+  if (label.length > 0) {
+    details.label = label.join(' | ');
+    details.labelStyle = {fill: edge.health === 'unhealthy' ? unhealthyColor : '#161616', fontSize: 11};
+  }
+  if (edge.kind === 'logical') {
+    details.style = {...edge.style, strokeDasharray: '2 4'};
+  }
+  if (edge.health === 'unhealthy') {
+    details.style = {strokeWidth: 2, stroke: unhealthyColor, strokeDasharray: '6 4'};
+    details.markerEnd = {type: MarkerType.ArrowClosed, width: 20, height: 20, color: unhealthyColor};
+  }
+  return details;
+};
 
-  // Map the calculated positions back to the nodes
-  const updatedNodes = nodes.map(node => {
-    const treeNode = treeData.descendants().find(d => d.id === node.id);
+// styleEdge sets the handles and style of an edge from its replication type. Binlog consumers are linked by dashed
+// edges of the color of their type.
+const styleEdge = (edge: any) => {
+  if (consumerColors[edge.edgeType]) {
     return {
-      ...node,
-      type: 'replTopoNode',
-      position: {
-        x: treeNode ? treeNode.x - nodeWidth / 2 : 0,
-        y: treeNode ? treeNode.y - nodeHeight / 2 : 0
+      ...edge,
+      sourceHandle: 'right',
+      targetHandle: 'left',
+      type: ConnectionLineType.SimpleBezier,
+      animated: false,
+      style: {
+        strokeWidth: 1,
+        stroke: consumerColors[edge.edgeType],
+        strokeDasharray: '8 4',
+      },
+      markerEnd: {
+        type: MarkerType.Arrow,
+        width: 25,
+        height: 25,
+        color: consumerColors[edge.edgeType]
       }
     };
-  });
+  } else if (edge.edgeType === 'unidirectional') {
+    return { 
+      ...edge, 
+      sourceHandle: 'right',
+      targetHandle: 'left',
+      type: ConnectionLineType.SimpleBezier, 
+      animated: false,
+      style: {
+        strokeWidth: 1,
+        stroke: '#fafafa',
+      },
+      markerEnd: {
+        type: MarkerType.Arrow,
+        width: 25,
+        height: 25,
+        color: '#fafafa'
+      }
+    };
+  } else if (edge.edgeType === 'bidirectional') {
+    return { 
+      ...edge,
+      sourceHandle: 'bottom',
+      targetHandle: 'top',
+      type: ConnectionLineType.SimpleBezier, 
+      animated: true, 
+      style: {
+        strokeWidth: 2,
+        stroke: '#FF0072',
+      },
+      markerEnd: {
+        type: MarkerType.ArrowClosed, 
+        width: 20,
+        height: 20,
+        color: '#FF0072'
+      }, 
+      markerStart:{
+        type: MarkerType.ArrowClosed, 
+        width: 20,
+        height: 20,
+        color: '#FF0072'
+      }
+    };
+  }
+  return edge;
+};
+
+// getLayoutedElements types the nodes of a replication graph and styles its edges. Nodes keep the position the server
+// laid them out at, or the position an admin pinned them at.
+export const getLayoutedElements = (nodes: any[], edges: any[]) => {
+  const updatedNodes = nodes.map(node => ({
+    ...node,
+    type: node.data?.consumerType ? 'consumerNode' : 'replTopoNode',
+  }));
 
   // Update edges with styles and handles
-  const updatedEdges = edges.map(edge => {
-    if (edge.edgeType === 'unidirectional' || edge.edgeType === 'dms') {
-      return { 
-        ...edge, 
-        sourceHandle: 'right',
-        targetHandle: 'left',
-        type: ConnectionLineType.SimpleBezier, 
-        animated: false,
-        style: {
-          strokeWidth: 1,
-          stroke: '#fafafa',
-        },
-        markerEnd: {
-          type: MarkerType.Arrow,
-          width: 25,
-          height: 25,
-          color: '#fafafa'
-        }
-      };
-    } else if (edge.edgeType === 'bidirectional') {
-      return { 
-        ...edge,
-        sourceHandle: 'bottom',
-        targetHandle: 'top',
-        type: ConnectionLineType.SimpleBezier, 
-        animated: true, 
-        style: {
-          strokeWidth: 2,
-          stroke: '#FF0072',
-        },
-        markerEnd: {
-          type: MarkerType.ArrowClosed, 
-          width: 20,
-          height: 20,
-          color: '#FF0072'
-        }, 
-        markerStart:{
-          type: MarkerType.ArrowClosed, 
-          width: 20,
-          height: 20,
-          color: '#FF0072'
-        }
-      };
-    }
-  });
+  const updatedEdges = edges.map(edge => styleEdge(edge)).map(edge => ({...edge, ...edgeDetails(edge)}));
 
   return {
     nodes: updatedNodes,
@@ -111,8 +159,13 @@ const getLayoutedElements = (nodes: any[], edges: any[], direction = 'TB') => {
   };
 };
 
+
 export const MySQLReplTopo = () => {
-  const {data: topologyGraph} = useGetMySQLReplicationTopologyGraphQuery({});
+  // at is the capturedAt of the capture being shown, the latest capture is shown when it is empty
+  const [at, setAt] = useState('');
+  const {data: topologyGraph} = useGetMySQLReplicationTopologyGraphQuery(at ? {at} : {});
+  const {data: history} = useGetMySQLReplicationTopologyHistoryQuery({limit: 100});
+  const {pinned, onNodeDragStop, unpinAll} = useGraphNodePinning('mysql-replication');
 
   // eslint-disable-next-line @typescript-eslint/no-unused-vars
   const [nodes, setNodes, onNodesChange] = useNodesState([]);
@@ -120,9 +173,10 @@ export const MySQLReplTopo = () => {
   
   useEffect(() => {
     if (topologyGraph) {
-      const { nodes: layoutedNodes, edges: layoutedEdges } = getLayoutedElements(topologyGraph!.nodes, topologyGraph!.edges, 'LR');
-      setNodes(layoutedNodes);
-      setEdges(layoutedEdges);
+      const { nodes: layoutedNodes, edges: layoutedEdges } = getLayoutedElements(topologyGraph!.nodes, topologyGraph!.edges);
+      const marked = markChanges(layoutedNodes, layoutedEdges, topologyGraph!.changes);
+      setNodes(marked.nodes);
+      setEdges(marked.edges);
     }
   }, [topologyGraph, setNodes, setEdges]);
  
@@ -134,7 +188,7 @@ export const MySQLReplTopo = () => {
     [setEdges]
   );
 
-  const nodeTypes = useMemo(() => ({ replTopoNode: ReplTopoNode }), []);
+  const nodeTypes = useMemo(() => ({ replTopoNode: ReplTopoNode, consumerNode: ConsumerNode }), []);
 
   const getAllIncomers = (node: any, nodes: any[], edges: any[], prevIncomers: any[] = []) => {
     const incomers = getIncomers(node, nodes, edges);
@@ -300,7 +354,7 @@ export const MySQLReplTopo = () => {
         if (isEdge(elem)) {
           // eslint-disable-next-line @typescript-eslint/ban-ts-comment
           {/* @ts-ignore */}
-          if (elem.edgeType === 'unidirectional' || elem.edgeType === 'dms') {
+          if (elem.edgeType === 'unidirectional' || consumerColors[elem.edgeType]) {
             elem.style = {
               ...elem.style,
               strokeWidth: 1.5,
@@ -343,6 +397,7 @@ export const MySQLReplTopo = () => {
         preventScrolling
         onNodeMouseEnter={(_event, node) => !selectedNode && highlightPath(node, nodes, edges, true)}
         onNodeMouseLeave={() => !selectedNode && resetNodeStyles()}
+        onNodeDragStop={onNodeDragStop}
       >
         <Background color="#aaa" gap={16} />
         <MiniMap />
@@ -361,7 +416,44 @@ export const MySQLReplTopo = () => {
           <br/>
           <br/>
           <TbDatabase color='#ff832b' size={23}/> Standard DB replica
+          <br/>
+          <br/>
+          <TbTransferOut color={consumerColors.dms} size={23}/> DMS task
+          <br/>
+          <br/>
+          <TbPlugConnected color={consumerColors.cdc} size={23}/> CDC connector
+          <br/>
+          <br/>
+          <TbArrowWaveRightDown color={unhealthyColor} size={25}/> Unhealthy replication (stopped or erroring)
+          <br/>
+          <br/>
+          <TbArrowWaveRightDown color={changedColor} size={25}/> Changed since the previous capture
+        </Panel>
+        <Panel className='cds--tile' position="top-left" style={{maxWidth: '420px'}}>
+          <Select
+            id="repl-topo-capture"
+            labelText="Capture"
+            size="sm"
+            value={at}
+            onChange={(e: any) => setAt(e.target.value)}
+          >
+            <SelectItem value="" text="Latest" />
+            {(history ?? []).map((snapshot) => (
+              <SelectItem
+                key={snapshot.id}
+                value={snapshot.capturedAt}
+                text={`${new Date(snapshot.capturedAt).toLocaleString()} (${snapshot.changes?.length ?? 0} changes)`}
+              />
+            ))}
+          </Select>
+          {topologyGraph?.capturedAt &&
+            <p style={{marginTop: '10px', fontSize: '12px'}}>Captured {new Date(topologyGraph.capturedAt).toLocaleString()}</p>
+          }
+          {(topologyGraph?.changes ?? []).map((change: IReplTopoChange, i: number) => (
+            <p key={i} style={{fontSize: '12px', color: change.kind === 'link_broken' ? unhealthyColor : undefined}}>{change.detail}</p>
+          ))}
         </Panel>
+        <PinnedNodesPanel pinned={pinned} unpinAll={unpinAll}/>
       </ReactFlow>
     </div>
   );
@@ -0,0 +1,84 @@
+import React, { useEffect, useMemo } from "react";
+import { useGetPostgresReplicationTopologyGraphQuery } from "../../../service/khub";
+
+import 'reactflow/dist/style.css';
+
+import ReactFlow, {
+  ConnectionLineType,
+  useNodesState,
+  useEdgesState,
+  Background,
+  MiniMap,
+  Controls,
+  Panel
+} from 'reactflow';
+
+import { ReplTopoNode } from "../MySQLTopology/CustomNodes";
+import { getLayoutedElements } from "../MySQLTopology/MySQLTopology";
+import { PinnedNodesPanel, useGraphNodePinning } from "../MySQLTopology/GraphPinning";
+import { BsArrows } from "react-icons/bs";
+import { TbArrowWaveRightDown, TbDatabase, TbDatabaseHeart, TbDatabaseStar } from "react-icons/tb";
+
+export const PostgresReplTopo = () => {
+  const {data: topologyGraph} = useGetPostgresReplicationTopologyGraphQuery({});
+  const {pinned, onNodeDragStop, unpinAll} = useGraphNodePinning('postgres-replication');
+
+  const [nodes, setNodes, onNodesChange] = useNodesState([]);
+  const [edges, setEdges, onEdgesChange] = useEdgesState([]);
+
+  useEffect(() => {
+    if (topologyGraph?.nodes && topologyGraph?.edges) {
+      const { nodes: layoutedNodes, edges: layoutedEdges } = getLayoutedElements(topologyGraph.nodes, topologyGraph.edges);
+      setNodes(layoutedNodes);
+      setEdges(layoutedEdges);
+    }
+  }, [topologyGraph, setNodes, setEdges]);
+
+  const nodeTypes = useMemo(() => ({ replTopoNode: ReplTopoNode }), []);
+
+  return (
+    <div style={{ height: 1050, backgroundColor: '#555555' }}>
+      <ReactFlow
+        nodeTypes={nodeTypes}
+        nodes={nodes}
+        edges={edges}
+        onNodesChange={onNodesChange}
+        onEdgesChange={onEdgesChange}
+        connectionLineType={ConnectionLineType.SmoothStep}
+        fitView
+        minZoom={0.2}
+        maxZoom={4}
+        preventScrolling
+        onNodeDragStop={onNodeDragStop}
+      >
+        <Background color="#aaa" gap={16} />
+        <MiniMap />
+        <Controls />
+        <Panel className='cds--tile' position="top-right">
+          <TbArrowWaveRightDown color='#fafafa' size={25}/> Streaming replication
+          <br/>
+          <br/>
+          <TbArrowWaveRightDown color='#fafafa' size={25} style={{opacity: 0.6}}/> Logical subscription (dotted)
+          <br/>
+          <br/>
+          <BsArrows size={25} color="rgb(255 0 114)"/> Bidirectional logical replication
+          <br/>
+          <br/>
+          <TbDatabaseHeart color='#b44e4e' size={23}/> DB Primary source
+          <br/>
+          <br/>
+          <TbDatabaseStar color='#24a148' size={23}/> DB replication source
+          <br/>
+          <br/>
+          <TbDatabase color='#ff832b' size={23}/> Standard DB replica
+        </Panel>
+        {topologyGraph?.capturedAt &&
+          <Panel className='cds--tile' position="top-left">
+            <p style={{fontSize: '12px'}}>Captured {new Date(topologyGraph.capturedAt).toLocaleString()}</p>
+          </Panel>
+        }
+        <PinnedNodesPanel pinned={pinned} unpinAll={unpinAll}/>
+      </ReactFlow>
+    </div>
+  );
+};
@@ -1,11 +1,22 @@
-import React from 'react';
+import React, { useEffect, useState } from 'react';
 import { ReportViewer } from '../../components/ReportViewer/ReportViewer';
 import { useGetReportsQuery } from '../../../service/khub';
-import { Loading } from '@carbon/react';
+import { Button, Loading } from '@carbon/react';
+import { IReport } from '../../../service/types/Reports';
 
 export const Reports = () => {
 
-  const {data: reports = [], isLoading} = useGetReportsQuery({});
+  const [cursor, setCursor] = useState<string | undefined>(undefined);
+  const [reports, setReports] = useState<IReport[]>([]);
+  const {data: page, isLoading, isFetching} = useGetReportsQuery({cursor: cursor});
+
+  useEffect(() => {
+    if (page === undefined) {
+      return;
+    }
+    // The first page replaces the list, so refetches after an upload do not duplicate reports
+    setReports((loaded) => cursor === undefined ? page.reports : [...loaded, ...page.reports]);
+  }, [page]);
 
   return (
     <div style={{height: '100%'}}>
@@ -14,7 +25,10 @@ export const Reports = () => {
             <Loading withOverlay={true}/>
           </div>
         }
-        <ReportViewer title='Pods' reports={reports}/>
+        <ReportViewer title='Pods' reports={reports} onReportsChanged={() => setCursor(undefined)}/>
+        {page?.nextCursor &&
+          <Button kind='ghost' disabled={isFetching} onClick={() => setCursor(page.nextCursor)}>Load more reports</Button>
+        }
     </div>
   );
-};
\ No newline at end of file
+};
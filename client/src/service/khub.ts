@@ -1,6 +1,11 @@
 import { createApi, fetchBaseQuery } from '@reduxjs/toolkit/query/react';
 import { wsConnect } from './websocketConnector';
-import { IAppConfig } from './types/AppConfig';
+import { IAppConfig, IAppConfigDiff, IAppConfigVersion, IExecJob } from './types/AppConfig';
+import { IReportFilter, IReportPage, IReportUpload, IReportUploadRequest } from './types/Reports';
+import { GraphName, IGraphNodePosition, IReplTopoHistoryFilter, IReplTopoSnapshot } from './types/ReplTopology';
+import { IMySQLQueryPlugin, IMySQLQueryResult } from './types/MySQLQuery';
+import { IMySQLDiscoveryAcceptRequest, IMySQLDiscoveryProposal } from './types/MySQLDiscovery';
+import { IPostgresDBInfo } from './types/PostgresDBInfo';
 
 
 const baseURL = 
@@ -18,7 +23,7 @@ const baseQuery = fetchBaseQuery({ baseUrl: baseURL, credentials: 'include', mod
 export const khubApi = createApi({
   reducerPath: 'khubApi',
   baseQuery: baseQuery,
-  tagTypes: ['Groups', 'Permissions', 'Reports', 'MySQLDBCatalog', 'DynamicAppConfig', 'ClusterName'],
+  tagTypes: ['Groups', 'Permissions', 'Reports', 'MySQLDBCatalog', 'DynamicAppConfig', 'ClusterName', 'APITokens', 'ServiceAccounts', 'UserSessions', 'Users', 'AuditEvents', 'DynamicAppConfigVersions', 'MySQLDiscoveryProposals', 'PostgresDBCatalog', 'GraphNodePositions'],
   endpoints: (builder) => ({
     userInfo: builder.query<any, any>({
       query: () => ({
@@ -46,7 +51,36 @@ export const khubApi = createApi({
         method: 'PUT',
         body: arg
       }),
-      invalidatesTags: ['DynamicAppConfig', 'ClusterName']
+      // A version conflict means the cached config is outdated, so it is refetched either way
+      invalidatesTags: ['DynamicAppConfig', 'ClusterName', 'DynamicAppConfigVersions']
+    }),
+    getDynamicAppConfigSchema: builder.query<any, any>({
+      query: () => ({
+        url: `/appconfig/schema`,
+        method: 'GET',
+      }),
+    }),
+    getDynamicAppConfigVersions: builder.query<IAppConfigVersion[], any>({
+      query: () => ({
+        url: `/appconfig/versions`,
+        method: 'GET',
+      }),
+      providesTags: ['DynamicAppConfigVersions']
+    }),
+    diffDynamicAppConfigVersions: builder.query<IAppConfigDiff, { from: number, to: number }>({
+      query: (arg) => ({
+        url: `/appconfig/diff?from=${arg.from}&to=${arg.to}`,
+        method: 'GET',
+      }),
+      providesTags: ['DynamicAppConfigVersions']
+    }),
+    rollbackDynamicAppConfig: builder.mutation<IAppConfig, { version: number, currentVersion: number }>({
+      query: (arg) => ({
+        url: `/appconfig/rollback`,
+        method: 'POST',
+        body: arg
+      }),
+      invalidatesTags: ['DynamicAppConfig', 'ClusterName', 'DynamicAppConfigVersions', 'AuditEvents']
     }),
     getPods: builder.query<any, any>({
       query: () => ({
@@ -222,7 +256,7 @@ export const khubApi = createApi({
         }
       })
     }),
-    execPlugin: builder.mutation<any, { kind: string, name: string, namespace: string, container: string, command: string, pluginName: string }>({
+    execPlugin: builder.mutation<any, { kind: string, name: string, namespace: string, container: string, command: string, pluginName: string, pluginArgs?: { [key: string]: string }, reason?: string }>({
       query: (arg) => ({
         url: `/k8s/exec`,
         method: 'POST',
@@ -234,15 +268,70 @@ export const khubApi = createApi({
             name: arg.pluginName,
             container: arg.container,
             command: arg.command
-          }
+          },
+          pluginArgs: arg.pluginArgs,
+          reason: arg.reason
         }
-      })
+      }),
+      invalidatesTags: ['Reports']
+    }),
+    getExecJob: builder.query<IExecJob, { id: string }>({
+      query: (arg) => ({
+        url: `/k8s/exec/jobs/${arg.id}`,
+        method: 'GET',
+      }),
+      async onCacheEntryAdded(
+        arg,
+        { updateCachedData, cacheDataLoaded, cacheEntryRemoved }
+      ) {
+        // The initial query returns the output written so far. The stream continues from there,
+        // and each message carries the output written since the previous one.
+        const { data } = await cacheDataLoaded;
+        if (data.status !== 'running') {
+          return;
+        }
+        const offset = new TextEncoder().encode(data.output ?? '').length;
+        const ws = new WebSocket(`${wsBaseUrl}/k8s/exec/jobs/${arg.id}?offset=${offset}`);
+        ws.addEventListener('message', (event: MessageEvent) => {
+          const job: IExecJob = JSON.parse(event.data);
+          updateCachedData((draft) => {
+            return { ...job, output: (draft.output ?? '') + (job.output ?? '') };
+          });
+        });
+        await cacheEntryRemoved;
+        ws.close();
+      }
     }),
     getUsers: builder.query<any, any>({
       query: () => ({
         url: `/users`,
         method: 'GET',
-      })
+      }),
+      providesTags: ['Users']
+    }),
+    deleteUser: builder.mutation<any, { id: string }>({
+      query: (arg) => ({
+        url: `/users/${arg.id}`,
+        method: 'DELETE',
+      }),
+      invalidatesTags: ['Users', 'UserSessions', 'AuditEvents']
+    }),
+    setUserDeactivated: builder.mutation<any, { id: string, deactivated: boolean }>({
+      query: (arg) => ({
+        url: `/users/${arg.id}/${arg.deactivated ? 'deactivate' : 'activate'}`,
+        method: 'POST',
+      }),
+      invalidatesTags: ['Users', 'UserSessions', 'AuditEvents']
+    }),
+    updateUserAdmin: builder.mutation<any, { id: string, isAdmin: boolean }>({
+      query: (arg) => ({
+        url: `/users/${arg.id}/admin`,
+        method: 'PUT',
+        body: {
+          isAdmin: arg.isAdmin
+        }
+      }),
+      invalidatesTags: ['Users', 'AuditEvents', 'DynamicAppConfigVersions']
     }),
     updateUserTheme: builder.mutation<any, {name: string, darkMode: boolean}>({
       query: (arg) => ({
@@ -270,6 +359,13 @@ export const khubApi = createApi({
       }),
       invalidatesTags: ['Groups']
     }),
+    deleteGroup: builder.mutation<any, { id: string }>({
+      query: (arg) => ({
+        url: `/groups/${arg.id}`,
+        method: 'DELETE',
+      }),
+      invalidatesTags: ['Groups', 'AuditEvents']
+    }),
     getPermissions: builder.query<any, any>({
       query: () => ({
         url: `/permissions`,
@@ -289,6 +385,99 @@ export const khubApi = createApi({
       }),
       invalidatesTags: ['Permissions']
     }),
+    deletePermission: builder.mutation<any, { id: string }>({
+      query: (arg) => ({
+        url: `/permissions/${arg.id}`,
+        method: 'DELETE',
+      }),
+      invalidatesTags: ['Permissions', 'Groups', 'AuditEvents']
+    }),
+    simulatePermissions: builder.query<any, { user: string, resource: string, namespace?: string, name?: string }>({
+      query: (arg) => ({
+        url: `/permissions/simulate`,
+        method: 'GET',
+        params: arg
+      }),
+      providesTags: ['Permissions', 'Groups']
+    }),
+    getResourceWriters: builder.query<any, { resource: string, namespace: string, name: string }>({
+      query: (arg) => ({
+        url: `/permissions/writers`,
+        method: 'GET',
+        params: arg
+      }),
+      providesTags: ['Permissions', 'Groups']
+    }),
+    getAuditEvents: builder.query<any, { resourceType?: string }>({
+      query: (arg) => ({
+        url: `/audit${arg.resourceType ? `?resourceType=${arg.resourceType}` : ''}`,
+        method: 'GET',
+      }),
+      providesTags: ['AuditEvents']
+    }),
+    getUserSessions: builder.query<any, { id: string }>({
+      query: (arg) => ({
+        url: `/users/${arg.id}/sessions`,
+        method: 'GET',
+      }),
+      providesTags: ['UserSessions']
+    }),
+    revokeUserSessions: builder.mutation<any, { id: string }>({
+      query: (arg) => ({
+        url: `/users/${arg.id}/sessions`,
+        method: 'DELETE',
+      }),
+      invalidatesTags: ['UserSessions']
+    }),
+    getAPITokens: builder.query<any, any>({
+      query: () => ({
+        url: `/tokens`,
+        method: 'GET',
+      }),
+      providesTags: ['APITokens']
+    }),
+    createAPIToken: builder.mutation<any, { name: string, scopes: string[], expiresInDays: number }>({
+      query: (arg) => ({
+        url: `/tokens`,
+        method: 'POST',
+        body: arg
+      }),
+      invalidatesTags: ['APITokens']
+    }),
+    revokeAPIToken: builder.mutation<any, { id: string }>({
+      query: (arg) => ({
+        url: `/tokens/${arg.id}`,
+        method: 'DELETE',
+      }),
+      invalidatesTags: ['APITokens']
+    }),
+    getServiceAccounts: builder.query<any, any>({
+      query: () => ({
+        url: `/serviceaccounts`,
+        method: 'GET',
+      }),
+      providesTags: ['ServiceAccounts']
+    }),
+    createServiceAccount: builder.mutation<any, { name: string, groupIds: string[] }>({
+      query: (arg) => ({
+        url: `/serviceaccounts`,
+        method: 'POST',
+        body: arg
+      }),
+      invalidatesTags: ['ServiceAccounts']
+    }),
+    createServiceAccountToken: builder.mutation<any, { id: string, name: string, scopes: string[], expiresInDays: number }>({
+      query: (arg) => ({
+        url: `/serviceaccounts/${arg.id}/tokens`,
+        method: 'POST',
+        body: {
+          name: arg.name,
+          scopes: arg.scopes,
+          expiresInDays: arg.expiresInDays
+        }
+      }),
+      invalidatesTags: ['APITokens']
+    }),
     getMySQLDBCatalog: builder.query<any, any>({
       query: () => ({
         url: `/infra/mysql`,
@@ -296,7 +485,7 @@ export const khubApi = createApi({
       }),
       providesTags: ['MySQLDBCatalog']
     }),
-    upsertMySQLDBInfo: builder.mutation<any, { shortName: string, host: string, username: string, port: number, isPrimary: boolean }>({
+    upsertMySQLDBInfo: builder.mutation<any, { shortName: string, host: string, username: string, port: number, isPrimary: boolean, credential?: any }>({
       query: (arg) => ({
         url: `/infra/mysql`,
         method: 'PUT',
@@ -305,11 +494,35 @@ export const khubApi = createApi({
           host: arg.host,
           username: arg.username,
           port: arg.port,
-          isPrimary: arg.isPrimary
+          isPrimary: arg.isPrimary,
+          credential: arg.credential
         }
       }),
       invalidatesTags: ['MySQLDBCatalog']
     }),
+    getMySQLDiscoveryProposals: builder.query<IMySQLDiscoveryProposal[], {status?: string}>({
+      query: (arg) => ({
+        url: `/infra/mysql/discovery`,
+        method: 'GET',
+        params: arg.status ? {status: arg.status} : undefined,
+      }),
+      providesTags: ['MySQLDiscoveryProposals']
+    }),
+    acceptMySQLDiscoveryProposal: builder.mutation<any, IMySQLDiscoveryAcceptRequest & {host: string}>({
+      query: ({host, ...request}) => ({
+        url: `/infra/mysql/discovery/${encodeURIComponent(host)}/accept`,
+        method: 'POST',
+        body: request,
+      }),
+      invalidatesTags: ['MySQLDiscoveryProposals', 'MySQLDBCatalog']
+    }),
+    setMySQLDiscoveryProposalStatus: builder.mutation<any, {host: string, action: 'ignore' | 'restore'}>({
+      query: (arg) => ({
+        url: `/infra/mysql/discovery/${encodeURIComponent(arg.host)}/${arg.action}`,
+        method: 'POST',
+      }),
+      invalidatesTags: ['MySQLDiscoveryProposals']
+    }),
     deleteMySQLDBInfo: builder.mutation<any, {host: string}>({
       query: (arg) => ({
         url: `/infra/mysql?dbHost=${arg.host}`,
@@ -317,16 +530,90 @@ export const khubApi = createApi({
       }),
       invalidatesTags: ['MySQLDBCatalog']
     }),
-    getMySQLReplicationTopologyGraph: builder.query<any, any>({
-      query: () => ({
+    getMySQLReplicationTopologyGraph: builder.query<any, {at?: string}>({
+      query: (arg) => ({
         url: `/infra/mysql/topology`,
         method: 'GET',
+        params: arg,
+      }),
+      providesTags: ['GraphNodePositions']
+    }),
+    getMySQLReplicationTopologyHistory: builder.query<IReplTopoSnapshot[], IReplTopoHistoryFilter>({
+      query: (arg) => ({
+        url: `/infra/mysql/topology/history`,
+        method: 'GET',
+        params: arg,
+      }),
+    }),
+    getPostgresDBCatalog: builder.query<IPostgresDBInfo[], any>({
+      query: () => ({
+        url: `/infra/postgres`,
+        method: 'GET',
       }),
+      providesTags: ['PostgresDBCatalog']
     }),
-    getReports: builder.query<any, any>({
+    upsertPostgresDBInfo: builder.mutation<IPostgresDBInfo, IPostgresDBInfo>({
+      query: (arg) => ({
+        url: `/infra/postgres`,
+        method: 'PUT',
+        body: arg,
+      }),
+      invalidatesTags: ['PostgresDBCatalog']
+    }),
+    deletePostgresDBInfo: builder.mutation<any, {host: string}>({
+      query: (arg) => ({
+        url: `/infra/postgres?dbHost=${encodeURIComponent(arg.host)}`,
+        method: 'DELETE',
+      }),
+      invalidatesTags: ['PostgresDBCatalog']
+    }),
+    getPostgresReplicationTopologyGraph: builder.query<any, any>({
       query: () => ({
+        url: `/infra/postgres/topology`,
+        method: 'GET',
+      }),
+      providesTags: ['GraphNodePositions']
+    }),
+    getGraphNodePositions: builder.query<IGraphNodePosition[], {graph: GraphName}>({
+      query: (arg) => ({
+        url: `/graphs/${arg.graph}/positions`,
+        method: 'GET',
+      }),
+      providesTags: ['GraphNodePositions']
+    }),
+    pinGraphNodePosition: builder.mutation<IGraphNodePosition, {graph: GraphName, node: string, x: number, y: number}>({
+      query: (arg) => ({
+        url: `/graphs/${arg.graph}/positions?node=${encodeURIComponent(arg.node)}`,
+        method: 'PUT',
+        body: {x: arg.x, y: arg.y},
+      }),
+      invalidatesTags: ['GraphNodePositions']
+    }),
+    unpinGraphNodePosition: builder.mutation<any, {graph: GraphName, node: string}>({
+      query: (arg) => ({
+        url: `/graphs/${arg.graph}/positions?node=${encodeURIComponent(arg.node)}`,
+        method: 'DELETE',
+      }),
+      invalidatesTags: ['GraphNodePositions']
+    }),
+    getMySQLQueryPlugins: builder.query<IMySQLQueryPlugin[], any>({
+      query: () => ({
+        url: `/infra/mysql/query`,
+        method: 'GET',
+      }),
+    }),
+    runMySQLQueryPlugin: builder.mutation<IMySQLQueryResult, {host: string, name: string, args: Record<string, string>}>({
+      query: (arg) => ({
+        url: `/infra/mysql/${encodeURIComponent(arg.host)}/query/${encodeURIComponent(arg.name)}`,
+        method: 'POST',
+        body: {args: arg.args},
+      }),
+    }),
+    getReports: builder.query<IReportPage, IReportFilter>({
+      query: (arg) => ({
         url: `/reports`,
         method: 'GET',
+        params: arg,
       }),
       providesTags: ['Reports']
     }),
@@ -336,6 +623,20 @@ export const khubApi = createApi({
         method: 'GET',
       }),
     }),
+    createReportUpload: builder.mutation<IReportUpload, IReportUploadRequest>({
+      query: (arg) => ({
+        url: `/reports/uploads`,
+        method: 'POST',
+        body: arg
+      }),
+    }),
+    deleteReport: builder.mutation<any, {key: string}>({
+      query: (arg) => ({
+        url: `/reports/${arg.key}`,
+        method: 'DELETE',
+      }),
+      invalidatesTags: ['Reports', 'AuditEvents']
+    }),
   })
 });
 
@@ -346,6 +647,10 @@ export const {
   useGetClusterNameQuery,
   useGetDynamicAppConfigQuery,
   useUpdateDynamicAppConfigMutation,
+  useGetDynamicAppConfigSchemaQuery,
+  useGetDynamicAppConfigVersionsQuery,
+  useDiffDynamicAppConfigVersionsQuery,
+  useRollbackDynamicAppConfigMutation,
   useGetPodsQuery,
   useDeletePodMutation,
   useGetDeploymentsQuery,
@@ -362,18 +667,50 @@ export const {
   useGetClusterEventsQuery,
   useRolloutRestartMutation,
   useExecPluginMutation,
+  useGetExecJobQuery,
   useGetUsersQuery,
   useUpdateUserThemeMutation,
+  useDeleteUserMutation,
+  useSetUserDeactivatedMutation,
+  useUpdateUserAdminMutation,
   useGetGroupsQuery,
   useUpsertGroupMutation,
+  useDeleteGroupMutation,
   useGetPermissionsQuery,
   useUpsertPermissionMutation,
+  useDeletePermissionMutation,
+  useSimulatePermissionsQuery,
+  useGetResourceWritersQuery,
+  useGetAuditEventsQuery,
+  useGetAPITokensQuery,
+  useCreateAPITokenMutation,
+  useRevokeAPITokenMutation,
+  useGetUserSessionsQuery,
+  useRevokeUserSessionsMutation,
+  useGetServiceAccountsQuery,
+  useCreateServiceAccountMutation,
+  useCreateServiceAccountTokenMutation,
   useGetReportsQuery,
   useGetReportDownloadURLQuery,
+  useCreateReportUploadMutation,
+  useDeleteReportMutation,
   useGetMySQLDBCatalogQuery,
   useUpsertMySQLDBInfoMutation,
   useDeleteMySQLDBInfoMutation,
-  useGetMySQLReplicationTopologyGraphQuery
+  useGetMySQLReplicationTopologyGraphQuery,
+  useGetMySQLReplicationTopologyHistoryQuery,
+  useGetMySQLQueryPluginsQuery,
+  useRunMySQLQueryPluginMutation,
+  useGetMySQLDiscoveryProposalsQuery,
+  useAcceptMySQLDiscoveryProposalMutation,
+  useSetMySQLDiscoveryProposalStatusMutation,
+  useGetPostgresDBCatalogQuery,
+  useUpsertPostgresDBInfoMutation,
+  useDeletePostgresDBInfoMutation,
+  useGetPostgresReplicationTopologyGraphQuery,
+  useGetGraphNodePositionsQuery,
+  usePinGraphNodePositionMutation,
+  useUnpinGraphNodePositionMutation
 } = khubApi;
 
 
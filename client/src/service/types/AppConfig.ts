@@ -1,7 +1,32 @@
 /*eslint-disable */
+import { IMySQLQueryPlugin } from './MySQLQuery';
+
 export interface IAppConfig {
   id: number;
+  version: number;
   data: IAppConfigData;
+  updatedBy?: string;
+  updatedAt?: string;
+}
+
+export interface IAppConfigVersion {
+  version: number;
+  data?: IAppConfigData;
+  author: string;
+  rollbackOf?: number;
+  createdAt: string;
+}
+
+export interface IAppConfigChange {
+  path: string;
+  from: any;
+  to: any;
+}
+
+export interface IAppConfigDiff {
+  from: number;
+  to: number;
+  changes: IAppConfigChange[];
 }
 
 export interface IAppConfigData {
@@ -11,6 +36,45 @@ export interface IAppConfigData {
   k8sClusterName: string;
   k8sClusterNamespaces: string[];
   k8sPodExecPlugins: IPodExecPlugin[];
+  mySQLQueryPlugins?: IMySQLQueryPlugin[];
+  mySQLDiscovery?: IMySQLDiscoveryConfig;
+  mySQLConsumerRules?: IMySQLConsumerRule[];
+  replicationAlerting?: IReplAlertingConfig;
+}
+
+export interface IMySQLDiscoveryConfig {
+  labels?: { [key: string]: string };
+  rds?: boolean;
+  username?: string;
+}
+
+export interface IMySQLConsumerRule {
+  name: string;
+  user?: string;
+  host?: string;
+  sources?: string[];
+  type: 'replica' | 'dms' | 'cdc' | 'ignore';
+}
+
+export interface IReplAlertingConfig {
+  rules?: IReplAlertRule[];
+  receivers?: IReplAlertReceiver[];
+}
+
+export interface IReplAlertRule {
+  name: string;
+  kind: 'replication_stopped' | 'lag' | 'source_changed' | 'host_unreachable';
+  topology?: '' | 'mysql' | 'postgres';
+  hosts?: string[];
+  lagSeconds?: number;
+  lagBytes?: number;
+  receivers?: string[];
+}
+
+export interface IReplAlertReceiver {
+  name: string;
+  type: 'slack' | 'webhook';
+  url: string;
 }
 
 export interface IPodExecPlugin {
@@ -18,4 +82,41 @@ export interface IPodExecPlugin {
   container: string
   command: string;
   labelFilter: string;
+  parameters?: IExecPluginParameter[];
+  timeoutSeconds?: number;
+  async?: boolean;
+  outputFile?: IExecPluginOutputFile;
+}
+
+export interface IExecPluginOutputFile {
+  path: string;
+  type?: string;
+}
+
+export interface IExecPluginParameter {
+  name: string;
+  type: 'int' | 'enum' | 'string';
+  description?: string;
+  default?: string;
+  min?: number;
+  max?: number;
+  values?: string[];
+  pattern?: string;
+}
+
+export interface IExecJob {
+  id: string;
+  plugin: string;
+  namespace: string;
+  pod: string;
+  container: string;
+  command: string[];
+  status: 'running' | 'succeeded' | 'failed' | 'timed_out';
+  error?: string;
+  output?: string;
+  startedBy: string;
+  startedAt: string;
+  finishedAt?: string;
+  outputFile?: string;
+  artifact?: string;
 }
\ No newline at end of file
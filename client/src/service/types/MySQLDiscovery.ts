@@ -0,0 +1,18 @@
+export interface IMySQLDiscoveryProposal {
+  host: string;
+  shortName: string;
+  port: number;
+  origin: 'kubernetes' | 'rds';
+  originRef: string;
+  status: 'pending' | 'ignored';
+  createdAt: string;
+  lastSeenAt: string;
+}
+
+export interface IMySQLDiscoveryAcceptRequest {
+  shortName?: string;
+  username?: string;
+  port?: number;
+  isPrimary?: boolean;
+  credential?: any;
+}
@@ -0,0 +1,26 @@
+export interface IMySQLQueryParameter {
+  name: string;
+  type: string;
+  description?: string;
+  default?: string;
+  values?: string[];
+}
+
+export interface IMySQLQueryPlugin {
+  name: string;
+  description?: string;
+  query: string;
+  parameters?: IMySQLQueryParameter[];
+  hosts?: string[];
+  maxRows?: number;
+  timeoutSeconds?: number;
+}
+
+export interface IMySQLQueryResult {
+  plugin: string;
+  host: string;
+  columns: string[];
+  rows: (string | null)[][];
+  truncated: boolean;
+  durationMs: number;
+}
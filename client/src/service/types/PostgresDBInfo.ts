@@ -0,0 +1,11 @@
+export interface IPostgresDBInfo {
+  host: string;
+  shortName: string;
+  username: string;
+  port?: number;
+  database?: string;
+  isPrimary: boolean;
+  credential?: any;
+  createdAt?: string;
+  updatedAt?: string;
+}
@@ -0,0 +1,32 @@
+export interface IReplTopoChange {
+  kind: string;
+  node?: string;
+  edge?: string;
+  from?: string;
+  to?: string;
+  detail: string;
+}
+
+export interface IReplTopoSnapshot {
+  id: number;
+  capturedAt: string;
+  changes: IReplTopoChange[] | null;
+}
+
+export interface IReplTopoHistoryFilter {
+  since?: string;
+  until?: string;
+  limit?: number;
+}
+
+// GraphName is a graph whose nodes admins can pin
+export type GraphName = 'mysql-replication' | 'postgres-replication';
+
+export interface IGraphNodePosition {
+  graph: GraphName;
+  nodeId: string;
+  x: number;
+  y: number;
+  updatedBy: string;
+  updatedAt: string;
+}
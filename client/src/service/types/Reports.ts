@@ -0,0 +1,41 @@
+export interface IReport {
+  name: string;
+  lastModified: string;
+  created: string;
+  expires: string;
+  bucket: string;
+  size: number;
+  sizeUnits: string;
+  user: string;
+  reason: string;
+  type: string;
+}
+
+export interface IReportPage {
+  reports: IReport[];
+  nextCursor?: string;
+}
+
+export interface IReportFilter {
+  user?: string;
+  type?: string;
+  reason?: string;
+  since?: string;
+  until?: string;
+  cursor?: string;
+  limit?: number;
+}
+
+export interface IReportUploadRequest {
+  name: string;
+  reason: string;
+  type: string;
+}
+
+export interface IReportUpload {
+  name: string;
+  url: string;
+  method: string;
+  headers: Record<string, string>;
+  expiresAt: string;
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/spf13/cobra"
+	"github.com/sullivtr/k8s_platform/internal/devidp"
+)
+
+func devIDPCmd() *cobra.Command {
+	var (
+		port      int
+		issuer    string
+		clientID  string
+		usersFile string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "dev-idp",
+		Short: "start a local mock OIDC identity provider with fake users (development only)",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			users := devidp.DefaultUsers()
+			if usersFile != "" {
+				var err error
+				if users, err = devidp.LoadUsers(usersFile); err != nil {
+					return err
+				}
+			}
+
+			if issuer == "" {
+				issuer = fmt.Sprintf("http://localhost:%d", port)
+			}
+
+			provider, err := devidp.New(devidp.Config{Issuer: issuer, ClientID: clientID, Users: users})
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("Starting khub dev idp at %s\n", provider.Issuer())
+			fmt.Println("Configure khub with:")
+			fmt.Printf("  KHUB_OIDC_ISSUER=%s/\n", provider.Issuer())
+			fmt.Printf("  KHUB_OIDC_CLIENT_ID=%s\n", provider.ClientID())
+			fmt.Println("Users:")
+			for _, u := range users {
+				fmt.Printf("  %s (subject: %s)\n", u.Email, u.Subject)
+			}
+
+			return http.ListenAndServe(fmt.Sprintf(":%d", port), provider)
+		},
+	}
+
+	cmd.Flags().IntVar(&port, "port", 9000, "port to listen on")
+	cmd.Flags().StringVar(&issuer, "issuer", "", "externally reachable issuer url (defaults to http://localhost:<port>)")
+	cmd.Flags().StringVar(&clientID, "client-id", devidp.DefaultClientID, "oauth client id accepted by the dev idp")
+	cmd.Flags().StringVar(&usersFile, "users", "", "yaml file listing the fake users (subject, email, preferredUsername, name, claims)")
+	return cmd
+}
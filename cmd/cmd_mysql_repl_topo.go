@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/spf13/cobra"
@@ -24,23 +25,13 @@ func mySQLReplTopoCmd(version string) *cobra.Command {
 			prvds.InitStorageProvider()
 			prvds.InitCacheProvider()
 
-			catalog, err := prvds.StorageProvider.GetMySQLCatalog()
-			if err != nil {
-				fmt.Println("Error fetching mysql catalog")
-			}
-
-			prvds.MySQLTopoProvider.Session.SDK.Databases = catalog
-
-			nodes, edges, err := prvds.MySQLTopoProvider.CaptureReplicationTopology()
+			snapshot, err := prvds.CaptureMySQLTopology(context.Background())
 			if err != nil {
 				fmt.Printf("Error capturing mysql replication topology: %s\n", err.Error())
+				return
 			}
-
-			if err := prvds.CacheProvider.Put("mysql-repl-topo-nodes", nodes); err != nil {
-				fmt.Printf("Error caching mysql replication topology nodes: %s\n", err.Error())
-			}
-			if err := prvds.CacheProvider.Put("mysql-repl-topo-edges", edges); err != nil {
-				fmt.Printf("Error caching mysql replication topology edges: %s\n", err.Error())
+			for _, change := range snapshot.Changes {
+				fmt.Println(change.Detail)
 			}
 
 			fmt.Println("Capture of mysql replication topology from sources complete")
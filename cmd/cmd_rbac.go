@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/sullivtr/k8s_platform/internal/config"
+	"github.com/sullivtr/k8s_platform/internal/providers"
+	"github.com/sullivtr/k8s_platform/internal/rbac"
+)
+
+func rbacCmd(version string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rbac",
+		Short: "export and apply the khub access model (permissions, groups, memberships and dynamic app config) as yaml",
+	}
+	cmd.AddCommand(rbacExportCmd(version), rbacApplyCmd(version))
+	return cmd
+}
+
+func rbacExportCmd(version string) *cobra.Command {
+	var output string
+
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "export the khub access model as a yaml rbac document",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			storageProvider, err := rbacStorageProvider(version)
+			if err != nil {
+				return err
+			}
+
+			doc, err := rbac.Export(storageProvider)
+			if err != nil {
+				return fmt.Errorf("unable to export rbac document: %s", err.Error())
+			}
+
+			b, err := rbac.Marshal(doc)
+			if err != nil {
+				return err
+			}
+
+			if output == "" || output == "-" {
+				_, err = cmd.OutOrStdout().Write(b)
+				return err
+			}
+			return os.WriteFile(output, b, 0o644)
+		},
+	}
+
+	cmd.Flags().StringVarP(&output, "output", "o", "", "file to write the rbac document to (defaults to stdout)")
+	return cmd
+}
+
+func rbacApplyCmd(version string) *cobra.Command {
+	var (
+		file   string
+		dryRun bool
+		prune  bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "apply",
+		Short: "reconcile the khub access model with a yaml rbac document",
+		Long: `Reconcile the khub access model with a yaml rbac document.
+
+Groups listed in the document are authoritative for their permissions and members.
+Permissions and groups that are not listed are left untouched unless --prune is set.
+The built-in admin permission and group are never pruned.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if file == "" {
+				return errors.New("an rbac document must be provided with -f")
+			}
+
+			doc, err := rbac.Load(file)
+			if err != nil {
+				return err
+			}
+
+			storageProvider, err := rbacStorageProvider(version)
+			if err != nil {
+				return err
+			}
+
+			plan, err := rbac.NewPlan(storageProvider, doc, prune)
+			if err != nil {
+				return fmt.Errorf("unable to plan rbac changes: %s", err.Error())
+			}
+
+			fmt.Fprint(cmd.OutOrStdout(), plan.String())
+			if dryRun || plan.Empty() {
+				return nil
+			}
+
+			if err := plan.Apply(storageProvider, "khub rbac apply"); err != nil {
+				return err
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), "Apply complete.")
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&file, "file", "f", "", "yaml rbac document to apply")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "print the changes without applying them")
+	cmd.Flags().BoolVar(&prune, "prune", false, "delete permissions and groups that are not listed in the document")
+	return cmd
+}
+
+// rbacStorageProvider initializes the storage provider from the khub configuration.
+// Dynamic app config updates are announced to the running khub processes so that they take effect immediately.
+func rbacStorageProvider(version string) (*providers.StorageProvider, error) {
+	prvds := &providers.ModuleProviders{
+		Config: config.Load(version, ""),
+	}
+	if err := prvds.InitStorageProvider(); err != nil {
+		return nil, err
+	}
+	if err := prvds.InitCacheProvider(); err != nil {
+		return nil, err
+	}
+	prvds.PublishDynamicAppConfigChanges()
+	return prvds.StorageProvider, nil
+}
@@ -24,6 +24,8 @@ func rootCmd() *cobra.Command {
 		serverCmd(version),
 		dataSinkCmd(version),
 		mySQLReplTopoCmd(version),
+		rbacCmd(version),
+		devIDPCmd(),
 	)
 	return cmd
 }
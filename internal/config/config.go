@@ -1,6 +1,7 @@
 package config
 
 import (
+	"fmt"
 	"strings"
 
 	"github.com/spf13/viper"
@@ -17,6 +18,9 @@ type Config struct {
 
 	// DB Settings
 	RedisAddress string `json:"-" mapstructure:"redis_address"`
+	// AppConfigCacheTTLSeconds is how long the dynamic app config is cached in process for. Updates invalidate the cache
+	// of every khub process immediately, so the ttl only bounds staleness when an update announcement is missed.
+	AppConfigCacheTTLSeconds int `json:"-" mapstructure:"app_config_cache_ttl_seconds"`
 	// DB Settings
 	DBUserName    string `json:"-" mapstructure:"db_username"`
 	DBPassword    string `json:"-" mapstructure:"db_password"`
@@ -27,6 +31,38 @@ type Config struct {
 	// MySQLCatalog Settings
 	// This should always be set in a secret
 	MySQLCatalogDBPassword string `json:"-" mapstructure:"mysql_catalog_db_password"`
+	// MySQLTopologyCaptureIntervalSeconds is how often the data sink captures the replication topology. 0 disables it.
+	MySQLTopologyCaptureIntervalSeconds int `json:"-" mapstructure:"mysql_topology_capture_interval_seconds"`
+	// MySQLTopologyCaptureWorkers bounds how many hosts are probed at once
+	MySQLTopologyCaptureWorkers int `json:"-" mapstructure:"mysql_topology_capture_workers"`
+	// MySQLTopologyConnectTimeoutSeconds and MySQLTopologyQueryTimeoutSeconds bound connecting to a host and its queries
+	MySQLTopologyConnectTimeoutSeconds int `json:"-" mapstructure:"mysql_topology_connect_timeout_seconds"`
+	MySQLTopologyQueryTimeoutSeconds   int `json:"-" mapstructure:"mysql_topology_query_timeout_seconds"`
+	// MySQLTopologyCaptureTimeoutSeconds bounds a whole capture, hosts not probed by then are captured as unreachable
+	MySQLTopologyCaptureTimeoutSeconds int `json:"-" mapstructure:"mysql_topology_capture_timeout_seconds"`
+	// MySQLTopologyHistoryDays is how long captures of the topology are kept for. 0 keeps them forever.
+	MySQLTopologyHistoryDays int `json:"-" mapstructure:"mysql_topology_history_days"`
+	// MySQLDiscoveryIntervalSeconds is how often the data sink runs the MySQL discovery sources. 0 disables discovery.
+	MySQLDiscoveryIntervalSeconds int `json:"-" mapstructure:"mysql_discovery_interval_seconds"`
+
+	// PostgresCatalog Settings
+	// This should always be set in a secret
+	PostgresCatalogDBPassword string `json:"-" mapstructure:"postgres_catalog_db_password"`
+	// PostgresTopologyCaptureIntervalSeconds is how often the data sink captures the Postgres replication topology. 0
+	// disables it.
+	PostgresTopologyCaptureIntervalSeconds int `json:"-" mapstructure:"postgres_topology_capture_interval_seconds"`
+	// PostgresTopologyCaptureWorkers bounds how many Postgres hosts are probed at once
+	PostgresTopologyCaptureWorkers int `json:"-" mapstructure:"postgres_topology_capture_workers"`
+	// PostgresTopologyConnectTimeoutSeconds and PostgresTopologyQueryTimeoutSeconds bound connecting to a Postgres host
+	// and its queries
+	PostgresTopologyConnectTimeoutSeconds int `json:"-" mapstructure:"postgres_topology_connect_timeout_seconds"`
+	PostgresTopologyQueryTimeoutSeconds   int `json:"-" mapstructure:"postgres_topology_query_timeout_seconds"`
+	// PostgresTopologyCaptureTimeoutSeconds bounds a whole Postgres capture, hosts not probed by then are captured as
+	// unreachable
+	PostgresTopologyCaptureTimeoutSeconds int `json:"-" mapstructure:"postgres_topology_capture_timeout_seconds"`
+
+	// CredentialFilesDir is the directory the file credentials of catalog hosts must be within. Empty disables them.
+	CredentialFilesDir string `json:"-" mapstructure:"credential_files_dir"`
 
 	// General Auth
 	AuthSessionHandlerKey string `json:"-" mapstructure:"auth_session_handler_key"`
@@ -40,6 +76,17 @@ type Config struct {
 	OIDCCLientTLSVerify bool   `json:"-" mapstructure:"oidc_client_tls_verify"`
 	OIDCAudience        string `json:"-" mapstructure:"oidc_audience"`
 
+	// OIDCProviders configures additional identity providers, selectable at /login?idp=<name>.
+	// When empty, the single provider described by the OIDC* settings above is used.
+	OIDCProviders []OIDCProvider `json:"-" mapstructure:"oidc_providers"`
+	// OIDCMetadataCacheTTLSeconds is how long OIDC discovery documents and JWKS are cached for
+	OIDCMetadataCacheTTLSeconds int `json:"-" mapstructure:"oidc_metadata_cache_ttl_seconds"`
+
+	// Dev IDP settings. The in-process dev identity provider is only served outside of production.
+	DevIDPEnabled   bool   `json:"-" mapstructure:"dev_idp_enabled"`
+	DevIDPIssuer    string `json:"-" mapstructure:"dev_idp_issuer"`
+	DevIDPUsersFile string `json:"-" mapstructure:"dev_idp_users_file"`
+
 	// Kubernetes settings
 	K8sInCluster               bool `json:"-" mapstructure:"k8s_in_cluster"`
 	K8sDataSinkIntervalSeconds int  `json:"-" mapstructure:"k8s_data_sink_interval_seconds"`
@@ -47,6 +94,73 @@ type Config struct {
 	// AWS settings
 	AWSRegion     string `json:"-" mapstructure:"aws_region"`
 	ReportsBucket string `json:"-" mapstructure:"reports_bucket"`
+
+	// Report storage settings. ReportsStorage selects the backend reports are stored in, s3 (default) or local.
+	// ReportsS3Endpoint and ReportsS3ForcePathStyle point the s3 backend at an s3 compatible service, such as MinIO.
+	// The local backend stores reports in ReportsLocalDir and serves upload and download links signed with ReportsSigningKey.
+	ReportsStorage          string `json:"-" mapstructure:"reports_storage"`
+	ReportsS3Endpoint       string `json:"-" mapstructure:"reports_s3_endpoint"`
+	ReportsS3ForcePathStyle bool   `json:"-" mapstructure:"reports_s3_force_path_style"`
+	ReportsLocalDir         string `json:"-" mapstructure:"reports_local_dir"`
+	ReportsSigningKey       string `json:"-" mapstructure:"reports_signing_key"`
+	// ReportsMaxUploadSizeMB is the largest report the local backend accepts through an upload link
+	ReportsMaxUploadSizeMB int `json:"-" mapstructure:"reports_max_upload_size_mb"`
+	// ReportsLocalRetentionDays is how long the local backend keeps reports for. 0 keeps them forever.
+	ReportsLocalRetentionDays int `json:"-" mapstructure:"reports_local_retention_days"`
+	// ReportsRefreshIntervalSeconds is how often the report storage is refreshed in the background. The s3 backend re-indexes
+	// object tags and reloads the bucket lifecycle, the local backend deletes expired reports. 0 disables the refresh.
+	ReportsRefreshIntervalSeconds int `json:"-" mapstructure:"reports_refresh_interval_seconds"`
+}
+
+// Report storage backends
+const (
+	ReportsStorageS3    = "s3"
+	ReportsStorageLocal = "local"
+)
+
+// OIDCProvider represents the settings of a single OIDC identity provider
+type OIDCProvider struct {
+	Name            string `json:"name" mapstructure:"name"`
+	Issuer          string `json:"-" mapstructure:"issuer"`
+	RedirectURI     string `json:"-" mapstructure:"redirect_uri"`
+	ClientID        string `json:"-" mapstructure:"client_id"`
+	ClientSecret    string `json:"-" mapstructure:"client_secret"`
+	ClientTLSVerify bool   `json:"-" mapstructure:"client_tls_verify"`
+}
+
+// GetOIDCProviders returns the configured identity providers. The first provider is the default.
+func (c Config) GetOIDCProviders() []OIDCProvider {
+	if len(c.OIDCProviders) > 0 {
+		return c.OIDCProviders
+	}
+
+	name := c.AuthIDP
+	if name == "" {
+		name = "default"
+	}
+	return []OIDCProvider{{
+		Name:            name,
+		Issuer:          c.OIDCIssuer,
+		RedirectURI:     c.OIDCRedirectURI,
+		ClientID:        c.OIDCClientID,
+		ClientSecret:    c.OIDCClientSecret,
+		ClientTLSVerify: c.OIDCCLientTLSVerify,
+	}}
+}
+
+// GetOIDCProvider returns the identity provider with the given name, or the default provider when name is empty
+func (c Config) GetOIDCProvider(name string) (OIDCProvider, error) {
+	providers := c.GetOIDCProviders()
+	if name == "" {
+		return providers[0], nil
+	}
+
+	for _, p := range providers {
+		if strings.EqualFold(p.Name, name) {
+			return p, nil
+		}
+	}
+	return OIDCProvider{}, fmt.Errorf("unknown identity provider: %s", name)
 }
 
 func (c Config) IsProduction() bool {
@@ -56,27 +170,50 @@ func (c Config) IsProduction() bool {
 func Load(version string, cfgFile string) *Config {
 	// SET CONFIG DEFAULTS
 	c := &Config{
-		Environment:                "Development",
-		Version:                    version,
-		ListenPort:                 8080,
-		Timeout:                    2000,
-		BaseURL:                    "http://localhost:3000",
-		AuthSessionHandlerKey:      "auth-session",
-		OIDCIssuer:                 "",
-		OIDCRedirectURI:            "http://localhost:8080/authorization-code/callback",
-		OIDCClientID:               "",
-		OIDCClientSecret:           "",
-		OIDCCLientTLSVerify:        false, // Zitadel cloud's self-signed cert is not trusted by default, for example
-		OIDCAudience:               "",
-		K8sInCluster:               true,
-		RedisAddress:               "redis-master.redis:6379",
-		DBUserName:                 "postgres",
-		DBPassword:                 "postgres1011",
-		DBHost:                     "postgres-postgresql.default.svc.cluster.local",
-		DBName:                     "khub",
-		DBAutoMigrate:              true,
-		K8sDataSinkIntervalSeconds: 5,
-		MySQLCatalogDBPassword:     "khub1011",
+		Environment:                         "Development",
+		Version:                             version,
+		ListenPort:                          8080,
+		Timeout:                             2000,
+		BaseURL:                             "http://localhost:3000",
+		AuthSessionHandlerKey:               "auth-session",
+		OIDCIssuer:                          "",
+		OIDCRedirectURI:                     "http://localhost:8080/authorization-code/callback",
+		OIDCClientID:                        "",
+		OIDCClientSecret:                    "",
+		OIDCCLientTLSVerify:                 false, // Zitadel cloud's self-signed cert is not trusted by default, for example
+		OIDCAudience:                        "",
+		K8sInCluster:                        true,
+		RedisAddress:                        "redis-master.redis:6379",
+		DBUserName:                          "postgres",
+		DBPassword:                          "postgres1011",
+		DBHost:                              "postgres-postgresql.default.svc.cluster.local",
+		DBName:                              "khub",
+		DBAutoMigrate:                       true,
+		K8sDataSinkIntervalSeconds:          5,
+		MySQLCatalogDBPassword:              "khub1011",
+		MySQLTopologyCaptureIntervalSeconds: 300,
+		MySQLTopologyCaptureWorkers:         8,
+		MySQLTopologyConnectTimeoutSeconds:  5,
+		MySQLTopologyQueryTimeoutSeconds:    10,
+		MySQLTopologyCaptureTimeoutSeconds:  60,
+		MySQLTopologyHistoryDays:            30,
+		MySQLDiscoveryIntervalSeconds:       600,
+		OIDCMetadataCacheTTLSeconds:         3600,
+		AppConfigCacheTTLSeconds:            300,
+		ReportsStorage:                      ReportsStorageS3,
+		ReportsLocalDir:                     "./reports",
+		ReportsLocalRetentionDays:           90,
+		ReportsMaxUploadSizeMB:              2048,
+		ReportsRefreshIntervalSeconds:       60,
+
+		PostgresCatalogDBPassword:              "khub1011",
+		PostgresTopologyCaptureIntervalSeconds: 300,
+		PostgresTopologyCaptureWorkers:         8,
+		PostgresTopologyConnectTimeoutSeconds:  5,
+		PostgresTopologyQueryTimeoutSeconds:    10,
+		PostgresTopologyCaptureTimeoutSeconds:  60,
+
+		CredentialFilesDir: "/etc/khub/credentials",
 	}
 
 	if cfgFile != "" {
@@ -101,7 +238,12 @@ func Load(version string, cfgFile string) *Config {
 	_ = viper.BindEnv("OIDC_CLIENT_SECRET")
 	_ = viper.BindEnv("OIDC_CLIENT_TLS_VERIFY")
 	_ = viper.BindEnv("OIDC_AUDIENCE")
+	_ = viper.BindEnv("OIDC_METADATA_CACHE_TTL_SECONDS")
+	_ = viper.BindEnv("DEV_IDP_ENABLED")
+	_ = viper.BindEnv("DEV_IDP_ISSUER")
+	_ = viper.BindEnv("DEV_IDP_USERS_FILE")
 	_ = viper.BindEnv("REDIS_ADDRESS")
+	_ = viper.BindEnv("APP_CONFIG_CACHE_TTL_SECONDS")
 	_ = viper.BindEnv("DB_USERNAME")
 	_ = viper.BindEnv("DB_PASSWORD")
 	_ = viper.BindEnv("DB_HOST")
@@ -111,7 +253,29 @@ func Load(version string, cfgFile string) *Config {
 	_ = viper.BindEnv("K8S_DATA_SYNC_INTERVAL_SECONDS")
 	_ = viper.BindEnv("AWS_REGION")
 	_ = viper.BindEnv("REPORTS_BUCKET")
+	_ = viper.BindEnv("REPORTS_STORAGE")
+	_ = viper.BindEnv("REPORTS_S3_ENDPOINT")
+	_ = viper.BindEnv("REPORTS_S3_FORCE_PATH_STYLE")
+	_ = viper.BindEnv("REPORTS_LOCAL_DIR")
+	_ = viper.BindEnv("REPORTS_SIGNING_KEY")
+	_ = viper.BindEnv("REPORTS_LOCAL_RETENTION_DAYS")
+	_ = viper.BindEnv("REPORTS_MAX_UPLOAD_SIZE_MB")
+	_ = viper.BindEnv("REPORTS_REFRESH_INTERVAL_SECONDS")
 	_ = viper.BindEnv("MYSQL_CATALOG_DB_PASSWORD")
+	_ = viper.BindEnv("MYSQL_TOPOLOGY_CAPTURE_INTERVAL_SECONDS")
+	_ = viper.BindEnv("MYSQL_TOPOLOGY_CAPTURE_WORKERS")
+	_ = viper.BindEnv("MYSQL_TOPOLOGY_CONNECT_TIMEOUT_SECONDS")
+	_ = viper.BindEnv("MYSQL_TOPOLOGY_QUERY_TIMEOUT_SECONDS")
+	_ = viper.BindEnv("MYSQL_TOPOLOGY_CAPTURE_TIMEOUT_SECONDS")
+	_ = viper.BindEnv("MYSQL_TOPOLOGY_HISTORY_DAYS")
+	_ = viper.BindEnv("MYSQL_DISCOVERY_INTERVAL_SECONDS")
+	_ = viper.BindEnv("POSTGRES_CATALOG_DB_PASSWORD")
+	_ = viper.BindEnv("POSTGRES_TOPOLOGY_CAPTURE_INTERVAL_SECONDS")
+	_ = viper.BindEnv("POSTGRES_TOPOLOGY_CAPTURE_WORKERS")
+	_ = viper.BindEnv("POSTGRES_TOPOLOGY_CONNECT_TIMEOUT_SECONDS")
+	_ = viper.BindEnv("POSTGRES_TOPOLOGY_QUERY_TIMEOUT_SECONDS")
+	_ = viper.BindEnv("POSTGRES_TOPOLOGY_CAPTURE_TIMEOUT_SECONDS")
+	_ = viper.BindEnv("CREDENTIAL_FILES_DIR")
 
 	_ = viper.ReadInConfig()
 	viper.AutomaticEnv()
@@ -106,6 +106,48 @@ func (suite *ConfigSuite) TestConfigFileErr() {
 	suite.Equal("1.2.3", c.Version, "Version should be '1.2.3'")
 }
 
+func (suite *ConfigSuite) TestGetOIDCProvider() {
+	legacy := Config{AuthIDP: "okta", OIDCIssuer: "https://corp.okta.com/", OIDCClientID: "corp"}
+	idp, err := legacy.GetOIDCProvider("")
+	suite.NoError(err)
+	suite.Equal("okta", idp.Name)
+	suite.Equal("https://corp.okta.com/", idp.Issuer)
+
+	multi := Config{OIDCProviders: []OIDCProvider{
+		{Name: "okta", Issuer: "https://corp.okta.com/"},
+		{Name: "zitadel", Issuer: "https://contractors.zitadel.cloud/"},
+	}}
+	idp, err = multi.GetOIDCProvider("")
+	suite.NoError(err)
+	suite.Equal("okta", idp.Name)
+
+	idp, err = multi.GetOIDCProvider("Zitadel")
+	suite.NoError(err)
+	suite.Equal("https://contractors.zitadel.cloud/", idp.Issuer)
+
+	_, err = multi.GetOIDCProvider("github")
+	suite.EqualError(err, "unknown identity provider: github")
+}
+
+func (suite *ConfigSuite) TestOIDCProvidersConfigFile() {
+	cfgFile := "./test-config-oidc-providers.yml"
+	content := []byte(`oidc_providers:
+  - name: okta
+    issuer: https://corp.okta.com/
+    client_id: corp
+  - name: zitadel
+    issuer: https://contractors.zitadel.cloud/
+    client_id: contractors
+`)
+	suite.NoError(os.WriteFile(cfgFile, content, 0644))
+	defer os.RemoveAll(cfgFile)
+
+	c := Load("1.2.3", cfgFile)
+	suite.Len(c.GetOIDCProviders(), 2)
+	suite.Equal("contractors", c.GetOIDCProviders()[1].ClientID)
+	suite.Equal(3600, c.OIDCMetadataCacheTTLSeconds)
+}
+
 func TestConfigSuite(t *testing.T) {
 	suite.Run(t, new(ConfigSuite))
 }
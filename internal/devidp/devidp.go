@@ -0,0 +1,425 @@
+// Package devidp provides a minimal, in-memory OIDC identity provider for local development and tests.
+// It supports the PKCE authorization code flow used by khub, with a fixed set of fake users.
+// It must never be used in production: any user can sign in as any configured identity without a password.
+package devidp
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// DefaultClientID is the client id accepted by the dev identity provider when none is configured
+	DefaultClientID = "khub-dev"
+
+	authCodeTTL    = 2 * time.Minute
+	accessTokenTTL = time.Hour
+)
+
+// User represents a fake user that can sign in to the dev identity provider
+type User struct {
+	Subject           string         `json:"subject"`
+	Email             string         `json:"email"`
+	PreferredUsername string         `json:"preferredUsername"`
+	Name              string         `json:"name"`
+	Claims            map[string]any `json:"claims"`
+}
+
+// Config represents the settings of the dev identity provider
+type Config struct {
+	// Issuer is the externally reachable base URL of the provider, e.g. http://localhost:9000
+	Issuer   string `json:"issuer"`
+	ClientID string `json:"clientId"`
+	Users    []User `json:"users"`
+}
+
+// DefaultUsers returns the users available when no users are configured
+func DefaultUsers() []User {
+	return []User{
+		{Subject: "dev-admin", Email: "admin@khub.dev", PreferredUsername: "admin@khub.dev", Name: "Dev Admin"},
+		{Subject: "dev-user", Email: "jane.doe@khub.dev", PreferredUsername: "jane.doe@khub.dev", Name: "Jane Doe"},
+	}
+}
+
+// LoadUsers reads fake users from a yaml or json file
+func LoadUsers(path string) ([]User, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read dev idp users file: %s", err.Error())
+	}
+
+	users := []User{}
+	if err := yaml.Unmarshal(b, &users); err != nil {
+		return nil, fmt.Errorf("unable to parse dev idp users file: %s", err.Error())
+	}
+	return users, nil
+}
+
+type authRequest struct {
+	clientID      string
+	redirectURI   string
+	codeChallenge string
+	nonce         string
+	user          User
+	expiresAt     time.Time
+}
+
+type accessToken struct {
+	user      User
+	expiresAt time.Time
+}
+
+// Provider is an http.Handler serving the OIDC endpoints of the dev identity provider
+type Provider struct {
+	config     Config
+	signingKey jwk.Key
+	publicKeys jwk.Set
+
+	mu           sync.Mutex
+	authRequests map[string]authRequest
+	accessTokens map[string]accessToken
+}
+
+// New creates a dev identity provider with a freshly generated signing key
+func New(config Config) (*Provider, error) {
+	if config.Issuer == "" {
+		return nil, errors.New("dev idp issuer is required")
+	}
+	config.Issuer = strings.TrimSuffix(config.Issuer, "/")
+
+	if config.ClientID == "" {
+		config.ClientID = DefaultClientID
+	}
+	if len(config.Users) == 0 {
+		config.Users = DefaultUsers()
+	}
+	for _, u := range config.Users {
+		if u.Subject == "" || u.Email == "" {
+			return nil, fmt.Errorf("dev idp user %q must have a subject and an email", u.Name)
+		}
+	}
+
+	rawKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, fmt.Errorf("unable to generate dev idp signing key: %s", err.Error())
+	}
+
+	signingKey, err := jwk.FromRaw(rawKey)
+	if err != nil {
+		return nil, err
+	}
+	_ = signingKey.Set(jwk.KeyIDKey, "khub-dev-idp")
+	_ = signingKey.Set(jwk.AlgorithmKey, jwa.RS256)
+
+	publicKey, err := jwk.PublicKeyOf(signingKey)
+	if err != nil {
+		return nil, err
+	}
+	_ = publicKey.Set(jwk.KeyUsageKey, jwk.ForSignature)
+	publicKeys := jwk.NewSet()
+	if err := publicKeys.AddKey(publicKey); err != nil {
+		return nil, err
+	}
+
+	return &Provider{
+		config:       config,
+		signingKey:   signingKey,
+		publicKeys:   publicKeys,
+		authRequests: map[string]authRequest{},
+		accessTokens: map[string]accessToken{},
+	}, nil
+}
+
+// Issuer returns the issuer of the tokens minted by the provider
+func (p *Provider) Issuer() string {
+	return p.config.Issuer
+}
+
+// ClientID returns the client id accepted by the provider
+func (p *Provider) ClientID() string {
+	return p.config.ClientID
+}
+
+// ServeHTTP routes requests to the OIDC endpoints of the provider
+func (p *Provider) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch strings.TrimSuffix(r.URL.Path, "/") {
+	case "/.well-known/openid-configuration":
+		p.discovery(w, r)
+	case "/keys":
+		writeJSON(w, http.StatusOK, p.publicKeys)
+	case "/authorize":
+		p.authorize(w, r)
+	case "/token":
+		p.token(w, r)
+	case "/userinfo":
+		p.userInfo(w, r)
+	case "/end_session":
+		p.endSession(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (p *Provider) discovery(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"issuer":                                p.config.Issuer,
+		"authorization_endpoint":                p.config.Issuer + "/authorize",
+		"token_endpoint":                        p.config.Issuer + "/token",
+		"userinfo_endpoint":                     p.config.Issuer + "/userinfo",
+		"end_session_endpoint":                  p.config.Issuer + "/end_session",
+		"jwks_uri":                              p.config.Issuer + "/keys",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+		"scopes_supported":                      []string{"openid", "profile", "email"},
+	})
+}
+
+var userSelectionPage = template.Must(template.New("users").Parse(`<!DOCTYPE html>
+<html>
+<head><title>khub dev idp</title></head>
+<body style="font-family: sans-serif">
+<h2>khub dev idp</h2>
+<p>Sign in as:</p>
+<ul>
+{{range .}}<li><a href="{{.URL}}">{{.Name}} ({{.Email}})</a></li>
+{{end}}</ul>
+</body>
+</html>`))
+
+func (p *Provider) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("client_id") != p.config.ClientID {
+		http.Error(w, "unknown client_id", http.StatusBadRequest)
+		return
+	}
+	if q.Get("response_type") != "code" {
+		http.Error(w, "unsupported response_type", http.StatusBadRequest)
+		return
+	}
+	if q.Get("redirect_uri") == "" {
+		http.Error(w, "redirect_uri is required", http.StatusBadRequest)
+		return
+	}
+	if method := q.Get("code_challenge_method"); q.Get("code_challenge") != "" && method != "S256" {
+		http.Error(w, "unsupported code_challenge_method", http.StatusBadRequest)
+		return
+	}
+
+	user, ok := p.findUser(q.Get("login_hint"))
+	if !ok {
+		// Let the developer pick a user, each choice re-enters the authorize endpoint with a login hint.
+		// The links are relative so they keep working when the provider is served under a path prefix.
+		choices := []map[string]string{}
+		for _, u := range p.config.Users {
+			choiceQuery := r.URL.Query()
+			choiceQuery.Set("login_hint", u.Subject)
+			choices = append(choices, map[string]string{
+				"Name":  u.Name,
+				"Email": u.Email,
+				"URL":   fmt.Sprintf("authorize?%s", choiceQuery.Encode()),
+			})
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_ = userSelectionPage.Execute(w, choices)
+		return
+	}
+
+	code := randomString()
+	p.mu.Lock()
+	p.authRequests[code] = authRequest{
+		clientID:      q.Get("client_id"),
+		redirectURI:   q.Get("redirect_uri"),
+		codeChallenge: q.Get("code_challenge"),
+		nonce:         q.Get("nonce"),
+		user:          user,
+		expiresAt:     time.Now().Add(authCodeTTL),
+	}
+	p.mu.Unlock()
+
+	redirect, err := url.Parse(q.Get("redirect_uri"))
+	if err != nil {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+	rq := redirect.Query()
+	rq.Set("code", code)
+	if state := q.Get("state"); state != "" {
+		rq.Set("state", state)
+	}
+	redirect.RawQuery = rq.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+// findUser resolves a login hint to a user. When only one user is configured, it is always selected.
+func (p *Provider) findUser(loginHint string) (User, bool) {
+	if loginHint == "" {
+		if len(p.config.Users) == 1 {
+			return p.config.Users[0], true
+		}
+		return User{}, false
+	}
+
+	for _, u := range p.config.Users {
+		if strings.EqualFold(u.Subject, loginHint) || strings.EqualFold(u.Email, loginHint) || strings.EqualFold(u.PreferredUsername, loginHint) {
+			return u, true
+		}
+	}
+	return User{}, false
+}
+
+func (p *Provider) token(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		writeTokenError(w, "invalid_request", err.Error())
+		return
+	}
+	if r.PostForm.Get("grant_type") != "authorization_code" {
+		writeTokenError(w, "unsupported_grant_type", "only authorization_code is supported")
+		return
+	}
+
+	code := r.PostForm.Get("code")
+	p.mu.Lock()
+	req, ok := p.authRequests[code]
+	delete(p.authRequests, code) // codes are single use
+	p.mu.Unlock()
+
+	if !ok || time.Now().After(req.expiresAt) {
+		writeTokenError(w, "invalid_grant", "unknown or expired authorization code")
+		return
+	}
+	if r.PostForm.Get("client_id") != req.clientID {
+		writeTokenError(w, "invalid_client", "client_id does not match the authorization request")
+		return
+	}
+	if r.PostForm.Get("redirect_uri") != req.redirectURI {
+		writeTokenError(w, "invalid_grant", "redirect_uri does not match the authorization request")
+		return
+	}
+	if req.codeChallenge != "" && s256(r.PostForm.Get("code_verifier")) != req.codeChallenge {
+		writeTokenError(w, "invalid_grant", "code_verifier does not match the code_challenge")
+		return
+	}
+
+	idToken, err := p.signIDToken(req)
+	if err != nil {
+		writeTokenError(w, "server_error", err.Error())
+		return
+	}
+
+	token := randomString()
+	p.mu.Lock()
+	p.accessTokens[token] = accessToken{user: req.user, expiresAt: time.Now().Add(accessTokenTTL)}
+	p.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token": token,
+		"token_type":   "Bearer",
+		"expires_in":   int(accessTokenTTL.Seconds()),
+		"scope":        "openid profile email",
+		"id_token":     idToken,
+	})
+}
+
+func (p *Provider) signIDToken(req authRequest) (string, error) {
+	now := time.Now()
+	t := jwt.New()
+	for k, v := range req.user.Claims {
+		_ = t.Set(k, v)
+	}
+	_ = t.Set(jwt.IssuerKey, p.config.Issuer)
+	_ = t.Set(jwt.SubjectKey, req.user.Subject)
+	_ = t.Set(jwt.AudienceKey, req.clientID)
+	_ = t.Set(jwt.IssuedAtKey, now)
+	_ = t.Set(jwt.ExpirationKey, now.Add(accessTokenTTL))
+	_ = t.Set("email", req.user.Email)
+	_ = t.Set("preferred_username", req.user.PreferredUsername)
+	_ = t.Set("name", req.user.Name)
+	if req.nonce != "" {
+		_ = t.Set("nonce", req.nonce)
+	}
+
+	signed, err := jwt.Sign(t, jwt.WithKey(jwa.RS256, p.signingKey))
+	if err != nil {
+		return "", fmt.Errorf("unable to sign id_token: %s", err.Error())
+	}
+	return string(signed), nil
+}
+
+func (p *Provider) userInfo(w http.ResponseWriter, r *http.Request) {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+	p.mu.Lock()
+	at, ok := p.accessTokens[token]
+	p.mu.Unlock()
+
+	if !ok || time.Now().After(at.expiresAt) {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_token"})
+		return
+	}
+
+	claims := map[string]any{"email_verified": true}
+	for k, v := range at.user.Claims {
+		claims[k] = v
+	}
+	claims["sub"] = at.user.Subject
+	claims["email"] = at.user.Email
+	claims["name"] = at.user.Name
+	if at.user.PreferredUsername != "" {
+		claims["preferred_username"] = at.user.PreferredUsername
+	}
+	writeJSON(w, http.StatusOK, claims)
+}
+
+func (p *Provider) endSession(w http.ResponseWriter, r *http.Request) {
+	if redirect := r.URL.Query().Get("post_logout_redirect_uri"); redirect != "" {
+		http.Redirect(w, r, redirect, http.StatusFound)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = w.Write([]byte("signed out of khub dev idp"))
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func writeTokenError(w http.ResponseWriter, code, description string) {
+	writeJSON(w, http.StatusBadRequest, map[string]string{"error": code, "error_description": description})
+}
+
+func s256(verifier string) string {
+	h := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(h[:])
+}
+
+func randomString() string {
+	b := make([]byte, 24)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,115 @@
+package devidp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func authorize(t *testing.T, p *Provider, query url.Values) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/authorize?"+query.Encode(), nil)
+	rec := httptest.NewRecorder()
+	p.ServeHTTP(rec, req)
+	return rec
+}
+
+func exchange(t *testing.T, p *Provider, form url.Values) (int, map[string]any) {
+	req := httptest.NewRequest(http.MethodPost, "/token", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	p.ServeHTTP(rec, req)
+
+	body := map[string]any{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	return rec.Code, body
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	p, err := New(Config{Issuer: "http://localhost:9000/"})
+	require.NoError(t, err)
+	assert.Equal(t, "http://localhost:9000", p.Issuer())
+
+	verifier := "a-code-verifier-that-is-long-enough-for-pkce-1234"
+	query := url.Values{
+		"client_id":             {DefaultClientID},
+		"response_type":         {"code"},
+		"redirect_uri":          {"http://localhost:8080/authorization-code/callback"},
+		"state":                 {"xyz"},
+		"code_challenge_method": {"S256"},
+		"code_challenge":        {s256(verifier)},
+	}
+
+	// Without a login hint the developer is asked to pick one of the users
+	rec := authorize(t, p, query)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "jane.doe@khub.dev")
+
+	query.Set("login_hint", "jane.doe@khub.dev")
+	rec = authorize(t, p, query)
+	require.Equal(t, http.StatusFound, rec.Code)
+	redirect, err := url.Parse(rec.Header().Get("Location"))
+	require.NoError(t, err)
+	assert.Equal(t, "xyz", redirect.Query().Get("state"))
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {redirect.Query().Get("code")},
+		"client_id":     {DefaultClientID},
+		"redirect_uri":  {"http://localhost:8080/authorization-code/callback"},
+		"code_verifier": {verifier},
+	}
+	status, body := exchange(t, p, form)
+	require.Equal(t, http.StatusOK, status, body)
+	assert.NotEmpty(t, body["id_token"])
+
+	req := httptest.NewRequest(http.MethodGet, "/userinfo", nil)
+	req.Header.Set("Authorization", "Bearer "+body["access_token"].(string))
+	userInfo := httptest.NewRecorder()
+	p.ServeHTTP(userInfo, req)
+	assert.Equal(t, http.StatusOK, userInfo.Code)
+	assert.Contains(t, userInfo.Body.String(), `"sub":"dev-user"`)
+
+	// Codes are single use
+	status, body = exchange(t, p, form)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, "invalid_grant", body["error"])
+}
+
+func TestTokenRejectsWrongCodeVerifier(t *testing.T) {
+	p, err := New(Config{Issuer: "http://localhost:9000", Users: []User{{Subject: "only", Email: "only@khub.dev"}}})
+	require.NoError(t, err)
+
+	rec := authorize(t, p, url.Values{
+		"client_id":             {DefaultClientID},
+		"response_type":         {"code"},
+		"redirect_uri":          {"http://localhost:8080/cb"},
+		"code_challenge_method": {"S256"},
+		"code_challenge":        {s256("right")},
+	})
+	require.Equal(t, http.StatusFound, rec.Code)
+	redirect, _ := url.Parse(rec.Header().Get("Location"))
+
+	status, body := exchange(t, p, url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {redirect.Query().Get("code")},
+		"client_id":     {DefaultClientID},
+		"redirect_uri":  {"http://localhost:8080/cb"},
+		"code_verifier": {"wrong"},
+	})
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, "invalid_grant", body["error"])
+}
+
+func TestAuthorizeRejectsUnknownClient(t *testing.T) {
+	p, err := New(Config{Issuer: "http://localhost:9000"})
+	require.NoError(t, err)
+
+	rec := authorize(t, p, url.Values{"client_id": {"other"}, "response_type": {"code"}, "redirect_uri": {"http://localhost/cb"}})
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/sullivtr/k8s_platform/internal/providers"
+	"github.com/sullivtr/k8s_platform/internal/types"
+)
+
+type APITokensHandler struct {
+	provider *providers.ModuleProviders
+}
+
+// GetAPITokens godoc
+// @Summary Get API Tokens
+// @Description get the api tokens owned by the current user. Admins may list the tokens of another user.
+// @Tags APITokens
+// @Accept  json
+// @Produce  json
+// @Param userId query string false "User ID (admin only)"
+// @Success 200 {object} []types.APIToken
+// @Router /api/tokens [get]
+func (c APITokensHandler) GetAPITokens(ctx echo.Context) error {
+	user, status, err := GetUserContext(ctx, c.provider.StorageProvider)
+	if err != nil {
+		return ctx.JSON(status, err.Error())
+	}
+
+	userID := *user.ID
+	if ctx.QueryParam("userId") != "" {
+		if !user.IsAdmin {
+			return ctx.JSON(http.StatusForbidden, "user must be an admin to view api tokens of other users")
+		}
+		userID, err = uuid.Parse(ctx.QueryParam("userId"))
+		if err != nil {
+			return ctx.JSON(http.StatusBadRequest, fmt.Sprintf("invalid userId: %s", err.Error()))
+		}
+	}
+
+	tokens, err := c.provider.StorageProvider.GetAPITokensByUser(userID)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, err.Error())
+	}
+	return ctx.JSON(http.StatusOK, tokens)
+}
+
+// CreateAPIToken godoc
+// @Summary Create API Token
+// @Description mint a new scoped, expiring api token for the current user. The raw token is only returned once.
+// @Tags APITokens
+// @Accept  json
+// @Produce  json
+// @Success 201 {object} types.APIToken
+// @Router /api/tokens [post]
+func (c APITokensHandler) CreateAPIToken(ctx echo.Context) error {
+	if _, ok := ctx.Get("apiToken").(types.APIToken); ok {
+		return ctx.JSON(http.StatusForbidden, "api tokens cannot be used to mint new api tokens")
+	}
+
+	user, status, err := GetUserContext(ctx, c.provider.StorageProvider)
+	if err != nil {
+		return ctx.JSON(status, err.Error())
+	}
+
+	return c.createAPIToken(ctx, *user.ID)
+}
+
+// RevokeAPIToken godoc
+// @Summary Revoke API Token
+// @Description revoke an api token. Tokens can be revoked by their owner or an admin.
+// @Tags APITokens
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Token ID"
+// @NoContent 204 {object} string
+// @Router /api/tokens/{id} [delete]
+func (c APITokensHandler) RevokeAPIToken(ctx echo.Context) error {
+	user, status, err := GetUserContext(ctx, c.provider.StorageProvider)
+	if err != nil {
+		return ctx.JSON(status, err.Error())
+	}
+
+	tokenID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, fmt.Sprintf("invalid token id: %s", err.Error()))
+	}
+
+	token, err := c.provider.StorageProvider.GetAPIToken(tokenID)
+	if err != nil {
+		return ctx.JSON(http.StatusNotFound, err.Error())
+	}
+
+	if token.UserID != *user.ID && !user.IsAdmin {
+		return ctx.JSON(http.StatusForbidden, "unable to revoke api token. You do not own this token.")
+	}
+
+	if err := c.provider.StorageProvider.RevokeAPIToken(tokenID); err != nil {
+		return ctx.JSON(http.StatusInternalServerError, err.Error())
+	}
+	recordAuditEvent(ctx, c.provider, types.AuditActionRevoke, types.AuditResourceAPIToken, tokenID.String(), token.Name, fmt.Sprintf("revoked api token of user %s", token.UserID.String()))
+	return ctx.NoContent(http.StatusNoContent)
+}
+
+// GetServiceAccounts godoc
+// @Summary Get Service Accounts
+// @Description get service accounts
+// @Tags APITokens
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} []types.User
+// @Router /api/serviceaccounts [get]
+func (c APITokensHandler) GetServiceAccounts(ctx echo.Context) error {
+	user, status, err := GetUserContext(ctx, c.provider.StorageProvider)
+	if err != nil {
+		return ctx.JSON(status, err.Error())
+	}
+
+	if !user.IsAdmin {
+		return ctx.JSON(http.StatusForbidden, "user must be an admin to view service accounts")
+	}
+
+	serviceAccounts, err := c.provider.StorageProvider.GetServiceAccounts()
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, err.Error())
+	}
+	return ctx.JSON(http.StatusOK, serviceAccounts)
+}
+
+// CreateServiceAccount godoc
+// @Summary Create Service Account
+// @Description create a non-human service account bound to the given groups
+// @Tags APITokens
+// @Accept  json
+// @Produce  json
+// @Success 201 {object} types.User
+// @Router /api/serviceaccounts [post]
+func (c APITokensHandler) CreateServiceAccount(ctx echo.Context) error {
+	user, status, err := GetUserContext(ctx, c.provider.StorageProvider)
+	if err != nil {
+		return ctx.JSON(status, err.Error())
+	}
+
+	if !user.IsAdmin {
+		return ctx.JSON(http.StatusForbidden, "user must be an admin to create service accounts")
+	}
+
+	var req types.ServiceAccountRequest
+	if err := json.NewDecoder(ctx.Request().Body).Decode(&req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, fmt.Sprintf("unable to decode service account json body %s", err.Error()))
+	}
+
+	serviceAccount, err := c.provider.StorageProvider.CreateServiceAccount(req.Name, req.GroupIDs)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, err.Error())
+	}
+	recordAuditEvent(ctx, c.provider, types.AuditActionCreate, types.AuditResourceServiceAccount, serviceAccount.ID.String(), serviceAccount.Name, fmt.Sprintf("bound to %d groups", len(req.GroupIDs)))
+	return ctx.JSON(http.StatusCreated, serviceAccount)
+}
+
+// CreateServiceAccountToken godoc
+// @Summary Create Service Account API Token
+// @Description mint a new scoped, expiring api token for a service account. The raw token is only returned once.
+// @Tags APITokens
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Service Account ID"
+// @Success 201 {object} types.APIToken
+// @Router /api/serviceaccounts/{id}/tokens [post]
+func (c APITokensHandler) CreateServiceAccountToken(ctx echo.Context) error {
+	if _, ok := ctx.Get("apiToken").(types.APIToken); ok {
+		return ctx.JSON(http.StatusForbidden, "api tokens cannot be used to mint new api tokens")
+	}
+
+	user, status, err := GetUserContext(ctx, c.provider.StorageProvider)
+	if err != nil {
+		return ctx.JSON(status, err.Error())
+	}
+
+	if !user.IsAdmin {
+		return ctx.JSON(http.StatusForbidden, "user must be an admin to create service account tokens")
+	}
+
+	serviceAccountID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, fmt.Sprintf("invalid service account id: %s", err.Error()))
+	}
+
+	serviceAccount, err := c.provider.StorageProvider.GetUserByID(serviceAccountID)
+	if err != nil {
+		return ctx.JSON(http.StatusNotFound, fmt.Sprintf("unable to find service account: %s", err.Error()))
+	}
+
+	if !serviceAccount.IsServiceAccount {
+		return ctx.JSON(http.StatusBadRequest, fmt.Sprintf("%s is not a service account", serviceAccount.Name))
+	}
+
+	return c.createAPIToken(ctx, serviceAccountID)
+}
+
+func (c APITokensHandler) createAPIToken(ctx echo.Context, userID uuid.UUID) error {
+	var req types.APITokenRequest
+	if err := json.NewDecoder(ctx.Request().Body).Decode(&req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, fmt.Sprintf("unable to decode api token json body %s", err.Error()))
+	}
+
+	if valid, errMsg := req.IsValid(); !valid {
+		return ctx.JSON(http.StatusBadRequest, fmt.Sprintf("validation error: %s", errMsg))
+	}
+
+	token, err := c.provider.StorageProvider.CreateAPIToken(userID, req)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, err.Error())
+	}
+	recordAuditEvent(ctx, c.provider, types.AuditActionCreate, types.AuditResourceAPIToken, token.ID.String(), token.Name, fmt.Sprintf("minted api token for user %s with scopes %v", userID.String(), token.Scopes))
+	return ctx.JSON(http.StatusCreated, token)
+}
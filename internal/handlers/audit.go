@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sullivtr/k8s_platform/internal/providers"
+	"github.com/sullivtr/k8s_platform/internal/types"
+)
+
+// defaultAuditEventLimit is the number of audit events returned when no limit is requested
+const defaultAuditEventLimit = 100
+
+type AuditHandler struct {
+	provider *providers.ModuleProviders
+}
+
+// GetAuditEvents godoc
+// @Summary Get Audit Events
+// @Description get the most recent administrative audit events (admin only)
+// @Tags Audit
+// @Accept  json
+// @Produce  json
+// @Param resourceType query string false "Resource Type"
+// @Param limit query int false "Limit"
+// @Success 200 {object} []types.AuditEvent
+// @Router /api/audit [get]
+func (c AuditHandler) GetAuditEvents(ctx echo.Context) error {
+	if _, status, err := requireAdmin(ctx, c.provider.StorageProvider, "view audit events"); err != nil {
+		return ctx.JSON(status, err.Error())
+	}
+
+	limit := defaultAuditEventLimit
+	if ctx.QueryParam("limit") != "" {
+		l, err := strconv.Atoi(ctx.QueryParam("limit"))
+		if err != nil || l <= 0 {
+			return ctx.JSON(http.StatusBadRequest, fmt.Sprintf("invalid limit: %s", ctx.QueryParam("limit")))
+		}
+		limit = l
+	}
+
+	events, err := c.provider.StorageProvider.GetAuditEvents(ctx.QueryParam("resourceType"), limit)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, err.Error())
+	}
+	return ctx.JSON(http.StatusOK, events)
+}
+
+// recordAuditEvent records an administrative operation performed by the user of the request context
+func recordAuditEvent(ctx echo.Context, provider *providers.ModuleProviders, action, resourceType, resourceID, resourceName, detail string) {
+	actor, _ := ctx.Get("username").(string)
+	provider.StorageProvider.RecordAuditEvent(types.AuditEvent{
+		Actor:        actor,
+		Action:       action,
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		ResourceName: resourceName,
+		Detail:       detail,
+	})
+}
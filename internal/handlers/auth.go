@@ -52,6 +52,7 @@ func generateNonce() (string, error) {
 // @Tags auth
 // @Accept  json
 // @Produce  json
+// @Param idp query string false "Identity provider name (defaults to the first configured provider)"
 // @Success 200 {string} string "OK"
 // @Failure 400 {object} string "Bad Request"
 // @Failure 401 {object} string "unauthorized"
@@ -60,12 +61,18 @@ func generateNonce() (string, error) {
 func (c *AuthSessionHandler) Login(ctx echo.Context) error {
 	ctx.Response().Header().Add("Cache-Control", "no-cache") // See https://github.com/okta/samples-golang/issues/20
 
+	idp, err := c.provider.Config.GetOIDCProvider(ctx.QueryParam("idp"))
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, err.Error())
+	}
+
 	// Create a session and generate a new nonce for each login attempt
 	sess, err := session.Get("khub-login-session-store", ctx)
 	if err != nil {
 		return ctx.JSON(http.StatusInternalServerError, err.Error())
 	}
 
+	sess.Values["idp"] = idp.Name // Store the selected identity provider in the session
 	state := generateState()
 	sess.Values["state"] = state // Store the state in the session
 
@@ -83,24 +90,16 @@ func (c *AuthSessionHandler) Login(ctx echo.Context) error {
 		return ctx.JSON(http.StatusInternalServerError, err.Error())
 	}
 
-	oAuthClient := &oauthClient{
-		ClientID:            c.provider.Config.OIDCClientID,
-		ClientSecret:        c.provider.Config.OIDCClientSecret,
-		OIDCCLientTLSVerify: c.provider.Config.OIDCCLientTLSVerify,
-		IDP:                 c.provider.Config.AuthIDP,
-		Client:              &http.Client{},
-		IssuerURL:           c.provider.Config.OIDCIssuer,
-		OIDCRedirectURI:     c.provider.Config.OIDCRedirectURI,
-		State:               state,
-		CodeChallenge:       codeChallenge,
-	}
+	oAuthClient := newOAuthClient(idp, c.provider.Config.OIDCMetadataCacheTTLSeconds)
+	oAuthClient.State = state
+	oAuthClient.CodeChallenge = codeChallenge
 
-	authorizationEndpoint, _, _, err := oAuthClient.getDiscoveryEndpoints()
+	discovery, err := oAuthClient.getDiscovery()
 	if err != nil {
 		return ctx.JSON(http.StatusInternalServerError, fmt.Sprintf("failure during OIDC discovery: %s", err.Error()))
 	}
 
-	redirectPath := oAuthClient.getAuthorizeRedirect(ctx.Request(), authorizationEndpoint)
+	redirectPath := oAuthClient.getAuthorizeRedirect(ctx.Request(), discovery.AuthorizationEndpoint)
 
 	// Make sure the session state can be read and is available
 	sessionState, ok := sess.Values["state"].(string)
@@ -160,24 +159,22 @@ func (c *AuthSessionHandler) AuthCodeCallback(ctx echo.Context) error {
 		return ctx.JSON(http.StatusInternalServerError, "Code verifier was not returned or is invalid.")
 	}
 
-	oAuthClient := &oauthClient{
-		ClientID:            c.provider.Config.OIDCClientID,
-		ClientSecret:        c.provider.Config.OIDCClientSecret,
-		OIDCCLientTLSVerify: c.provider.Config.OIDCCLientTLSVerify,
-		IDP:                 c.provider.Config.AuthIDP,
-		Client:              &http.Client{},
-		IssuerURL:           c.provider.Config.OIDCIssuer,
-		OIDCRedirectURI:     c.provider.Config.OIDCRedirectURI,
-		CodeVerifier:        codeVerifier,
-		AuthCode:            ctx.Request().URL.Query().Get("code"),
+	idpName, _ := sess.Values["idp"].(string)
+	idp, err := c.provider.Config.GetOIDCProvider(idpName)
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, err.Error())
 	}
 
-	_, tokenEndpoint, userInfoEndpoint, err := oAuthClient.getDiscoveryEndpoints()
+	oAuthClient := newOAuthClient(idp, c.provider.Config.OIDCMetadataCacheTTLSeconds)
+	oAuthClient.CodeVerifier = codeVerifier
+	oAuthClient.AuthCode = ctx.Request().URL.Query().Get("code")
+
+	discovery, err := oAuthClient.getDiscovery()
 	if err != nil {
 		return ctx.JSON(http.StatusInternalServerError, fmt.Sprintf("failure during OIDC discovery: %s", err.Error()))
 	}
 
-	exchange, err := oAuthClient.exchangeAuthCodeForToken(tokenEndpoint)
+	exchange, err := oAuthClient.exchangeAuthCodeForToken(discovery.TokenEndpoint)
 	if err != nil {
 		return ctx.JSON(http.StatusInternalServerError, fmt.Sprintf("failure during auth code exchange: %s", err.Error()))
 	}
@@ -186,41 +183,124 @@ func (c *AuthSessionHandler) AuthCodeCallback(ctx echo.Context) error {
 		return ctx.JSON(http.StatusInternalServerError, fmt.Sprintf("failure during auth code exchange: %s, %s", exchange.Error, exchange.ErrorDescription))
 	}
 
-	email, err := oAuthClient.getUserEmailFromIDP(exchange.AccessToken, userInfoEndpoint)
+	idToken, err := oAuthClient.verifyIDToken(exchange.IdToken, discovery)
+	if err != nil {
+		return ctx.JSON(http.StatusUnauthorized, fmt.Sprintf("failure during id token verification: %s", err.Error()))
+	}
+
+	userInfo, err := oAuthClient.getUserInfoFromIDP(exchange.AccessToken, discovery.UserInfoEndpoint)
 	if err != nil {
 		return ctx.JSON(http.StatusInternalServerError, fmt.Sprintf("failure during user info retrieval: %s", err.Error()))
 	}
 
+	email := userInfo.Username
 	if email == "" {
 		return ctx.JSON(http.StatusInternalServerError, "no email found in user info response")
 	}
 
+	// Users are keyed by the issuer and subject of their identity, so the same email at two IDPs never collides.
+	// Users created before identities were recorded signed in with the default IDP.
+	defaultIDP, _ := c.provider.Config.GetOIDCProvider("")
+	user, err := c.provider.StorageProvider.ResolveUserIdentity(types.UserIdentity{
+		IDP:           idp.Name,
+		Issuer:        idToken.Issuer(),
+		Subject:       idToken.Subject(),
+		Email:         email,
+		VerifiedEmail: userInfo.verifiedEmail(),
+		DefaultIDP:    strings.EqualFold(idp.Name, defaultIDP.Name),
+	})
+	if errors.Is(err, providers.ErrUserDeactivated) || errors.Is(err, providers.ErrUserDeleted) {
+		return ctx.JSON(http.StatusForbidden, fmt.Sprintf("forbidden. Unable to sign in: %s", err.Error()))
+	}
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, fmt.Sprintf("failure during user resolution: %s", err.Error()))
+	}
+
 	sess.Values["id_token"] = exchange.IdToken
 	sess.Values["access_token"] = exchange.AccessToken
 	sess.Values["preferred_username"] = email
+	sess.Values["khub_username"] = user.Name
 	if err := sess.Save(ctx.Request(), ctx.Response()); err != nil {
 		return ctx.JSON(http.StatusInternalServerError, err.Error())
 	}
 
+	// Track the session so that it can be listed and revoked by an admin. Only server side sessions have an ID.
+	if sess.ID != "" {
+		if err := c.provider.CacheProvider.TrackUserSession(types.UserSession{
+			Email:      strings.ToLower(email),
+			IPAddress:  ctx.RealIP(),
+			UserAgent:  ctx.Request().UserAgent(),
+			CreatedAt:  time.Now(),
+			ExpiresAt:  time.Now().Add(time.Duration(sess.Options.MaxAge) * time.Second),
+			SessionKey: sess.ID,
+		}); err != nil {
+			log.Warn().Msgf("unable to track user session for %s: %s", email, err.Error())
+		}
+	}
+
 	return ctx.Redirect(http.StatusFound, c.provider.Config.BaseURL)
 }
 
+// Logout godoc
+// @Summary Ends the user's session.
+// @Description Ends the user's session and, when the IDP supports it, performs an OIDC RP-initiated logout.
+// @Tags auth
+// @Success 302 {string} string "Found"
+// @Failure 500 {object} string "internal server error"
+// @Router /logout [get]
 func (c *AuthSessionHandler) Logout(ctx echo.Context) error {
-	// Create a session and generate a new nonce for each login attempt
 	sess, err := session.Get("khub-login-session-store", ctx)
 	if err != nil {
 		return ctx.JSON(http.StatusInternalServerError, err.Error())
 	}
 
+	idToken, _ := sess.Values["id_token"].(string)
+	idpName, _ := sess.Values["idp"].(string)
+	if email, ok := sess.Values["preferred_username"].(string); ok && sess.ID != "" {
+		if err := c.provider.CacheProvider.UntrackUserSession(strings.ToLower(email), sess.ID); err != nil {
+			log.Warn().Msgf("unable to untrack user session for %s: %s", email, err.Error())
+		}
+	}
+
 	delete(sess.Values, "id_token")
 	delete(sess.Values, "access_token")
-	delete(sess.Values, "username")
-	delete(sess.Values, "email")
+	delete(sess.Values, "preferred_username")
+	delete(sess.Values, "khub_username")
+	delete(sess.Values, "idp")
+	delete(sess.Values, "nonce")
+	delete(sess.Values, "code_verifier")
+	delete(sess.Values, "code_challenge")
 	sess.Options.MaxAge = -1
+	if err := sess.Save(ctx.Request(), ctx.Response()); err != nil {
+		log.Warn().Msgf("unable to clear login session: %s", err.Error())
+	}
 
-	sess.Save(ctx.Request(), ctx.Response())
+	// Drop the cached permissions as well so a new login always resolves fresh permissions
+	if permissionsSess, err := session.Get("user-permissions", ctx); err == nil {
+		permissionsSess.Options.MaxAge = -1
+		if err := permissionsSess.Save(ctx.Request(), ctx.Response()); err != nil {
+			log.Warn().Msgf("unable to clear user permissions session: %s", err.Error())
+		}
+	}
 
-	return ctx.Redirect(http.StatusFound, c.provider.Config.BaseURL)
+	idp, err := c.provider.Config.GetOIDCProvider(idpName)
+	if err != nil {
+		log.Warn().Msgf("unable to perform RP-initiated logout, falling back to local logout: %s", err.Error())
+		return ctx.Redirect(http.StatusFound, c.provider.Config.BaseURL)
+	}
+
+	oAuthClient := newOAuthClient(idp, c.provider.Config.OIDCMetadataCacheTTLSeconds)
+	discovery, err := oAuthClient.getDiscovery()
+	if err != nil {
+		log.Warn().Msgf("unable to perform RP-initiated logout, falling back to local logout: %s", err.Error())
+		return ctx.Redirect(http.StatusFound, c.provider.Config.BaseURL)
+	}
+
+	if discovery.EndSessionEndpoint == "" {
+		return ctx.Redirect(http.StatusFound, c.provider.Config.BaseURL)
+	}
+
+	return ctx.Redirect(http.StatusFound, oAuthClient.getEndSessionRedirect(discovery.EndSessionEndpoint, idToken, c.provider.Config.BaseURL))
 }
 
 // UserInfo godoc
@@ -309,12 +389,14 @@ type oauthClient struct {
 	CodeVerifier        string
 	AuthCode            string
 	OIDCCLientTLSVerify bool
+	MetadataCacheTTL    time.Duration
 }
 
 func (c *oauthClient) getAuthorizeRedirect(r *http.Request, authorizationEndpoint string) string {
 	var redirectPath string
 
 	q := r.URL.Query()
+	q.Del("idp")
 	q.Add("client_id", c.ClientID)
 	q.Add("response_type", "code")
 	q.Add("scope", "openid profile email")
@@ -372,34 +454,41 @@ func (c *oauthClient) constructOauth2TokenRequest(tokenEndpoint string) *http.Re
 	return req
 }
 
-func (c *oauthClient) getDiscoveryEndpoints() (string, string, string, error) {
-	client := &http.Client{
-		Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: c.OIDCCLientTLSVerify},
-		},
-	}
-	req, err := http.NewRequest("GET", c.IssuerURL+".well-known/openid-configuration", nil)
-	if err != nil {
-		log.Error().Err(err).Msg("failed to create request)")
+// getEndSessionRedirect builds the RP-initiated logout redirect as described in
+// https://openid.net/specs/openid-connect-rpinitiated-1_0.html#RPLogout
+func (c *oauthClient) getEndSessionRedirect(endSessionEndpoint, idToken, postLogoutRedirectURI string) string {
+	q := url.Values{}
+	if idToken != "" {
+		q.Add("id_token_hint", idToken)
 	}
+	q.Add("client_id", c.ClientID)
+	q.Add("post_logout_redirect_uri", postLogoutRedirectURI)
 
-	respData := map[string]interface{}{}
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", "", "", fmt.Errorf("failed to get OIDC dicovery data: %v", err)
+	separator := "?"
+	if strings.Contains(endSessionEndpoint, "?") {
+		separator = "&"
 	}
+	return fmt.Sprintf("%s%s%s", endSessionEndpoint, separator, q.Encode())
+}
 
-	body, _ := io.ReadAll(resp.Body)
-	defer resp.Body.Close()
-	if err := json.Unmarshal(body, &respData); err != nil {
-		return "", "", "", fmt.Errorf("failed to unmarshal response body: %v", err)
-	}
+// idpUserInfo represents the claims of an IDP user info response khub relies on
+type idpUserInfo struct {
+	// Username is the preferred_username claim, or the email claim when the IDP does not send one
+	Username string
+	Email    string
+	// EmailVerified is whether the IDP asserted that the user owns Email. It does not vouch for preferred_username.
+	EmailVerified bool
+}
 
-	return respData["authorization_endpoint"].(string), respData["token_endpoint"].(string), respData["userinfo_endpoint"].(string), nil
+// verifiedEmail returns the email claim when the IDP asserted that the user owns it
+func (u idpUserInfo) verifiedEmail() string {
+	if !u.EmailVerified {
+		return ""
+	}
+	return u.Email
 }
 
-func (c *oauthClient) getUserEmailFromIDP(accessToken, userInfoEndpoint string) (string, error) {
+func (c *oauthClient) getUserInfoFromIDP(accessToken, userInfoEndpoint string) (idpUserInfo, error) {
 	client := &http.Client{
 		Transport: &http.Transport{
 			TLSClientConfig: &tls.Config{InsecureSkipVerify: c.OIDCCLientTLSVerify},
@@ -407,29 +496,36 @@ func (c *oauthClient) getUserEmailFromIDP(accessToken, userInfoEndpoint string)
 	}
 	req, err := http.NewRequest("GET", userInfoEndpoint, nil)
 	if err != nil {
-		return "", fmt.Errorf("unable to create request to get user info: %s", err.Error())
+		return idpUserInfo{}, fmt.Errorf("unable to create request to get user info: %s", err.Error())
 	}
 	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", accessToken))
 	resp, err := client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("unable to get user info from IDP: %s", err.Error())
+		return idpUserInfo{}, fmt.Errorf("unable to get user info from IDP: %s", err.Error())
 	}
 	defer resp.Body.Close()
 
 	respData := map[string]any{}
 	body, _ := io.ReadAll(resp.Body)
 	if err := json.Unmarshal(body, &respData); err != nil {
-		return "", fmt.Errorf("unable to unmarshal user info response: %s", err.Error())
+		return idpUserInfo{}, fmt.Errorf("unable to unmarshal user info response: %s", err.Error())
 	}
 
-	preferredUsername, ok := respData["preferred_username"]
-	if !ok {
-		email, ok := respData["email"]
-		if !ok {
-			return "", errors.New("no username found in user info response")
+	userInfo := idpUserInfo{
+		// Some IDPs send the email_verified claim as a string
+		EmailVerified: respData["email_verified"] == true || respData["email_verified"] == "true",
+	}
+	userInfo.Email, _ = respData["email"].(string)
+
+	preferredUsername, ok := respData["preferred_username"].(string)
+	if !ok || preferredUsername == "" {
+		if userInfo.Email == "" {
+			return idpUserInfo{}, errors.New("no username found in user info response")
 		}
-		return email.(string), nil
+		userInfo.Username = userInfo.Email
+		return userInfo, nil
 	}
 
-	return preferredUsername.(string), nil
+	userInfo.Username = preferredUsername
+	return userInfo, nil
 }
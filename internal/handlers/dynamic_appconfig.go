@@ -2,21 +2,44 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/labstack/echo/v4"
 	"github.com/sullivtr/k8s_platform/internal/providers"
 	"github.com/sullivtr/k8s_platform/internal/types"
+	"gorm.io/gorm"
 )
 
 type DynamicAppConfigHandler struct {
 	provider *providers.ModuleProviders
 }
 
+// DynamicAppConfigConflict is returned when an update is based on an outdated version of the dynamic app config
+type DynamicAppConfigConflict struct {
+	Message string                 `json:"message"`
+	Current types.DynamicAppConfig `json:"current"`
+}
+
+// DynamicAppConfigValidationError is returned when an update would store an invalid dynamic app config
+type DynamicAppConfigValidationError struct {
+	Message string                   `json:"message"`
+	Errors  []types.ConfigFieldError `json:"errors"`
+}
+
+// DynamicAppConfigDiff lists the changes between two versions of the dynamic app config
+type DynamicAppConfigDiff struct {
+	From    int                            `json:"from"`
+	To      int                            `json:"to"`
+	Changes []types.DynamicAppConfigChange `json:"changes"`
+}
+
 // GetDynamicAppConfig godoc
 // @Summary Get DynamicAppConfig
-// @Description get DynamicAppConfig
+// @Description get DynamicAppConfig. The urls of the replication alert receivers are only returned to admins.
 // @Tags DynamicAppConfig
 // @Accept  json
 // @Produce  json
@@ -27,37 +50,248 @@ func (c DynamicAppConfigHandler) GetDynamicAppConfig(ctx echo.Context) error {
 	if err != nil {
 		return ctx.JSON(http.StatusInternalServerError, err.Error())
 	}
+
+	if user, _, err := GetUserContext(ctx, c.provider.StorageProvider); err != nil || !user.IsAdmin {
+		dac.Data.ReplicationAlerting = dac.Data.ReplicationAlerting.Redacted()
+	}
 	return ctx.JSON(http.StatusOK, dac)
 }
 
+// GetDynamicAppConfigSchema godoc
+// @Summary Get DynamicAppConfig Schema
+// @Description get the json schema of the dynamic app config data
+// @Tags DynamicAppConfig
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} map[string]any
+// @Router /api/appconfig/schema [get]
+func (c DynamicAppConfigHandler) GetDynamicAppConfigSchema(ctx echo.Context) error {
+	return ctx.JSON(http.StatusOK, types.DynamicAppConfigSchema())
+}
+
 // UpdateDynamicAppConfig godoc
 // @Summary Update DynamicAppConfig
-// @Description update DynamicAppConfig
+// @Description update DynamicAppConfig (admin only). The version must be the current version of the config.
+// @Description When the config was updated in the meantime a 409 is returned along with the current config.
 // @Tags DynamicAppConfig
 // @Accept  json
 // @Produce  json
 // @Success 200 {object} types.DynamicAppConfig
+// @Failure 400 {object} DynamicAppConfigValidationError
+// @Failure 409 {object} DynamicAppConfigConflict
 // @Router /api/appconfig [put]
 func (c DynamicAppConfigHandler) UpdateDynamicAppConfig(ctx echo.Context) error {
-	user, _, err := GetUserContext(ctx, c.provider.StorageProvider)
+	user, status, err := requireAdmin(ctx, c.provider.StorageProvider, "edit dynamic app config")
 	if err != nil {
-		return ctx.JSON(http.StatusForbidden, err.Error())
-	}
-
-	if !user.IsAdmin {
-		return ctx.JSON(http.StatusForbidden, "user must be an admin to edit dynamic app config")
+		return ctx.JSON(status, err.Error())
 	}
 
 	var dac types.DynamicAppConfig
-	err = json.NewDecoder(ctx.Request().Body).Decode(&dac)
+	decoder := json.NewDecoder(ctx.Request().Body)
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(&dac)
 	if err != nil {
 		return ctx.JSON(http.StatusBadRequest, fmt.Sprintf("unable to decode dynamic app config json body %s", err.Error()))
 	}
 
-	_, err = c.provider.StorageProvider.UpdateDynamicAppConfig(dac)
+	if dac.Version == 0 {
+		return ctx.JSON(http.StatusBadRequest, "the version of the dynamic app config being updated is required")
+	}
+
+	previous, err := c.provider.StorageProvider.GetDynamicAppConfig()
 	if err != nil {
 		return ctx.JSON(http.StatusInternalServerError, err.Error())
 	}
 
-	return ctx.JSON(http.StatusOK, dac)
+	dac.UpdatedBy = user.Name
+	updated, err := c.provider.StorageProvider.UpdateDynamicAppConfig(dac)
+	if errors.Is(err, providers.ErrAppConfigVersionConflict) {
+		return c.conflict(ctx, err)
+	}
+	if errors.Is(err, providers.ErrInvalidAppConfig) {
+		return ctx.JSON(http.StatusBadRequest, DynamicAppConfigValidationError{Message: err.Error(), Errors: dac.Data.Validate()})
+	}
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	recordAuditEvent(ctx, c.provider, types.AuditActionUpdate, types.AuditResourceAppConfig, strconv.Itoa(updated.Version), "dynamic app config",
+		changeSummary(previous.Data, updated.Data))
+	return ctx.JSON(http.StatusOK, updated)
+}
+
+// GetDynamicAppConfigVersions godoc
+// @Summary Get DynamicAppConfig Versions
+// @Description get the version history of the dynamic app config, newest first (admin only)
+// @Tags DynamicAppConfig
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} []types.DynamicAppConfigVersion
+// @Router /api/appconfig/versions [get]
+func (c DynamicAppConfigHandler) GetDynamicAppConfigVersions(ctx echo.Context) error {
+	if _, status, err := requireAdmin(ctx, c.provider.StorageProvider, "view dynamic app config versions"); err != nil {
+		return ctx.JSON(status, err.Error())
+	}
+
+	versions, err := c.provider.StorageProvider.GetDynamicAppConfigVersions()
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, err.Error())
+	}
+	return ctx.JSON(http.StatusOK, versions)
+}
+
+// GetDynamicAppConfigVersion godoc
+// @Summary Get DynamicAppConfig Version
+// @Description get a single version of the dynamic app config (admin only)
+// @Tags DynamicAppConfig
+// @Accept  json
+// @Produce  json
+// @Param version path int true "Version"
+// @Success 200 {object} types.DynamicAppConfigVersion
+// @Router /api/appconfig/versions/{version} [get]
+func (c DynamicAppConfigHandler) GetDynamicAppConfigVersion(ctx echo.Context) error {
+	if _, status, err := requireAdmin(ctx, c.provider.StorageProvider, "view dynamic app config versions"); err != nil {
+		return ctx.JSON(status, err.Error())
+	}
+
+	version, err := strconv.Atoi(ctx.Param("version"))
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, fmt.Sprintf("invalid version: %s", ctx.Param("version")))
+	}
+
+	v, status, err := c.getVersion(version)
+	if err != nil {
+		return ctx.JSON(status, err.Error())
+	}
+	return ctx.JSON(http.StatusOK, v)
+}
+
+// DiffDynamicAppConfigVersions godoc
+// @Summary Diff DynamicAppConfig Versions
+// @Description list the changes between two versions of the dynamic app config (admin only)
+// @Tags DynamicAppConfig
+// @Accept  json
+// @Produce  json
+// @Param from query int true "From Version"
+// @Param to query int true "To Version"
+// @Success 200 {object} DynamicAppConfigDiff
+// @Router /api/appconfig/diff [get]
+func (c DynamicAppConfigHandler) DiffDynamicAppConfigVersions(ctx echo.Context) error {
+	if _, status, err := requireAdmin(ctx, c.provider.StorageProvider, "view dynamic app config versions"); err != nil {
+		return ctx.JSON(status, err.Error())
+	}
+
+	from, err := strconv.Atoi(ctx.QueryParam("from"))
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, fmt.Sprintf("invalid from version: %s", ctx.QueryParam("from")))
+	}
+	to, err := strconv.Atoi(ctx.QueryParam("to"))
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, fmt.Sprintf("invalid to version: %s", ctx.QueryParam("to")))
+	}
+
+	fromVersion, status, err := c.getVersion(from)
+	if err != nil {
+		return ctx.JSON(status, err.Error())
+	}
+	toVersion, status, err := c.getVersion(to)
+	if err != nil {
+		return ctx.JSON(status, err.Error())
+	}
+
+	changes, err := types.DiffDynamicAppConfig(fromVersion.Data, toVersion.Data)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, err.Error())
+	}
+	return ctx.JSON(http.StatusOK, DynamicAppConfigDiff{From: from, To: to, Changes: changes})
+}
+
+// RollbackDynamicAppConfig godoc
+// @Summary Rollback DynamicAppConfig
+// @Description restore a previous version of the dynamic app config as a new version (admin only).
+// @Description The currentVersion must be the current version of the config, otherwise a 409 is returned along with the current config.
+// @Tags DynamicAppConfig
+// @Accept  json
+// @Produce  json
+// @Param request body types.DynamicAppConfigRollbackRequest true "Rollback Request"
+// @Success 200 {object} types.DynamicAppConfig
+// @Failure 400 {object} DynamicAppConfigValidationError
+// @Failure 409 {object} DynamicAppConfigConflict
+// @Router /api/appconfig/rollback [post]
+func (c DynamicAppConfigHandler) RollbackDynamicAppConfig(ctx echo.Context) error {
+	user, status, err := requireAdmin(ctx, c.provider.StorageProvider, "roll back dynamic app config")
+	if err != nil {
+		return ctx.JSON(status, err.Error())
+	}
+
+	var req types.DynamicAppConfigRollbackRequest
+	if err := json.NewDecoder(ctx.Request().Body).Decode(&req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, fmt.Sprintf("unable to decode rollback request json body %s", err.Error()))
+	}
+	if req.Version == 0 || req.CurrentVersion == 0 {
+		return ctx.JSON(http.StatusBadRequest, "version and currentVersion are required")
+	}
+
+	target, status, err := c.getVersion(req.Version)
+	if err != nil {
+		return ctx.JSON(status, err.Error())
+	}
+
+	previous, err := c.provider.StorageProvider.GetDynamicAppConfig()
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	updated, err := c.provider.StorageProvider.RollbackDynamicAppConfig(req.Version, req.CurrentVersion, user.Name)
+	if errors.Is(err, providers.ErrAppConfigVersionConflict) {
+		return c.conflict(ctx, err)
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ctx.JSON(http.StatusNotFound, fmt.Sprintf("dynamic app config version %d not found", req.Version))
+	}
+	// Versions stored before validation was introduced may be invalid and cannot be restored
+	if errors.Is(err, providers.ErrInvalidAppConfig) {
+		return ctx.JSON(http.StatusBadRequest, DynamicAppConfigValidationError{Message: fmt.Sprintf("version %d is not a valid dynamic app config", req.Version), Errors: target.Data.Validate()})
+	}
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	recordAuditEvent(ctx, c.provider, types.AuditActionRollback, types.AuditResourceAppConfig, strconv.Itoa(updated.Version), "dynamic app config",
+		fmt.Sprintf("rolled back to version %d; %s", req.Version, changeSummary(previous.Data, updated.Data)))
+	return ctx.JSON(http.StatusOK, updated)
+}
+
+// getVersion fetches a version of the dynamic app config, returning a 404 when it does not exist
+func (c DynamicAppConfigHandler) getVersion(version int) (types.DynamicAppConfigVersion, int, error) {
+	v, err := c.provider.StorageProvider.GetDynamicAppConfigVersion(version)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return types.DynamicAppConfigVersion{}, http.StatusNotFound, fmt.Errorf("dynamic app config version %d not found", version)
+	}
+	if err != nil {
+		return types.DynamicAppConfigVersion{}, http.StatusInternalServerError, err
+	}
+	return v, http.StatusOK, nil
+}
+
+// conflict responds with the current dynamic app config so that the client can reload it before retrying
+func (c DynamicAppConfigHandler) conflict(ctx echo.Context, conflictErr error) error {
+	current, err := c.provider.StorageProvider.GetDynamicAppConfig()
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, err.Error())
+	}
+	return ctx.JSON(http.StatusConflict, DynamicAppConfigConflict{Message: conflictErr.Error(), Current: current})
+}
+
+// changeSummary describes the paths changed between two dynamic app configs for the audit log
+func changeSummary(from, to types.DynamicConfigJSONB) string {
+	changes, err := types.DiffDynamicAppConfig(from, to)
+	if err != nil || len(changes) == 0 {
+		return "no changes"
+	}
+	paths := make([]string, 0, len(changes))
+	for _, c := range changes {
+		paths = append(paths, c.Path)
+	}
+	return fmt.Sprintf("changed %s", strings.Join(paths, ", "))
 }
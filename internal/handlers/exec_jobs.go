@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+	"github.com/sullivtr/k8s_platform/internal/providers"
+	"github.com/sullivtr/k8s_platform/internal/types"
+)
+
+// ExecJobsPath is the path exec jobs are read and followed at
+const ExecJobsPath = "/api/k8s/exec/jobs/"
+
+// execJobPollInterval is how often the output of a followed exec job is checked for updates
+const execJobPollInterval = 500 * time.Millisecond
+
+// GetExecJob godoc
+// @Summary Get Exec Job
+// @Description get an async pod exec plugin job and its output, starting at the given byte offset.
+// @Description Websocket requests stream the job output as it is written, until the job finishes.
+// @Description Jobs can only be read by the user who started them and by admins.
+// @Tags K8s
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Job ID"
+// @Param offset query int false "Output Offset"
+// @Success 200 {object} types.ExecJob
+// @Router /api/k8s/exec/jobs/{id} [get]
+func (c K8sSessionHandler) GetExecJob(ctx echo.Context) error {
+	user, status, err := GetUserContext(ctx, c.provider.StorageProvider)
+	if err != nil {
+		return ctx.JSON(status, err.Error())
+	}
+
+	var offset int64
+	if ctx.QueryParam("offset") != "" {
+		offset, err = strconv.ParseInt(ctx.QueryParam("offset"), 10, 64)
+		if err != nil || offset < 0 {
+			return ctx.JSON(http.StatusBadRequest, fmt.Sprintf("invalid offset: %s", ctx.QueryParam("offset")))
+		}
+	}
+
+	job, status, err := c.getExecJob(ctx.Param("id"), offset, user)
+	if err != nil {
+		return ctx.JSON(status, err.Error())
+	}
+
+	if ctx.Request().Header.Get("Upgrade") != "websocket" {
+		return ctx.JSON(http.StatusOK, job)
+	}
+
+	ws, err := upgrader.Upgrade(ctx.Response(), ctx.Request(), nil)
+	if err != nil {
+		return err
+	}
+	defer ws.Close()
+
+	// The client never sends messages, reading only detects when it goes away
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := ws.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(execJobPollInterval)
+	defer ticker.Stop()
+	for {
+		// Each message carries the output written since the previous message
+		if job.Output != "" || job.Done() {
+			if err := ws.WriteJSON(job); err != nil {
+				return nil
+			}
+			offset += int64(len(job.Output))
+		}
+		if job.Done() {
+			_ = ws.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, job.Status))
+			return nil
+		}
+
+		select {
+		case <-closed:
+			log.Debug().Msgf("client stopped following exec job %s", job.ID)
+			return nil
+		case <-ticker.C:
+		}
+
+		job, status, err = c.getExecJob(job.ID, offset, user)
+		if err != nil {
+			_ = ws.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseInternalServerErr, err.Error()))
+			return nil
+		}
+	}
+}
+
+// getExecJob fetches an exec job the user is allowed to read
+func (c K8sSessionHandler) getExecJob(jobID string, offset int64, user types.User) (types.ExecJob, int, error) {
+	job, err := c.provider.CacheProvider.GetExecJob(jobID, offset)
+	if errors.Is(err, providers.ErrExecJobNotFound) {
+		return types.ExecJob{}, http.StatusNotFound, err
+	}
+	if err != nil {
+		return types.ExecJob{}, http.StatusInternalServerError, err
+	}
+
+	// Jobs owned by someone else are reported as missing rather than revealing that they exist
+	if job.StartedBy != user.Name && !user.IsAdmin {
+		return types.ExecJob{}, http.StatusNotFound, providers.ErrExecJobNotFound
+	}
+	return job, http.StatusOK, nil
+}
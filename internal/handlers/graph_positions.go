@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sullivtr/k8s_platform/internal/providers"
+	"github.com/sullivtr/k8s_platform/internal/types"
+)
+
+type GraphPositionsHandler struct {
+	provider *providers.ModuleProviders
+}
+
+// GetGraphNodePositions godoc
+// @Summary Get Graph Node Positions
+// @Description get the positions admins pinned the nodes of a graph at
+// @Tags Graphs
+// @Accept  json
+// @Produce  json
+// @Param graph path string true "Graph (mysql-replication or postgres-replication)"
+// @Success 200 {object} []types.GraphNodePosition
+// @Failure 400 {object} string "invalid graph"
+// @Router /api/graphs/{graph}/positions [get]
+func (c GraphPositionsHandler) GetGraphNodePositions(ctx echo.Context) error {
+	graph := ctx.Param("graph")
+	if !types.IsGraph(graph) {
+		return ctx.JSON(http.StatusBadRequest, fmt.Sprintf("invalid graph: %s", graph))
+	}
+
+	positions, err := c.provider.StorageProvider.GetGraphNodePositions(graph)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, err.Error())
+	}
+	return ctx.JSON(http.StatusOK, positions)
+}
+
+// PinGraphNodePosition godoc
+// @Summary Pin Graph Node Position
+// @Description pin a node of a graph at a position, which overrides the server side layout (admin only)
+// @Tags Graphs
+// @Accept  json
+// @Produce  json
+// @Param graph path string true "Graph (mysql-replication or postgres-replication)"
+// @Param node query string true "Node ID"
+// @Param position body types.GraphNodePositionRequest true "Position"
+// @Success 200 {object} types.GraphNodePosition
+// @Failure 400 {object} string "invalid graph or node"
+// @Failure 403 {object} string "user must be an admin"
+// @Router /api/graphs/{graph}/positions [put]
+func (c GraphPositionsHandler) PinGraphNodePosition(ctx echo.Context) error {
+	user, status, err := requireAdmin(ctx, c.provider.StorageProvider, "pin graph nodes")
+	if err != nil {
+		return ctx.JSON(status, err.Error())
+	}
+
+	graph, nodeID := ctx.Param("graph"), ctx.QueryParam("node")
+	if !types.IsGraph(graph) {
+		return ctx.JSON(http.StatusBadRequest, fmt.Sprintf("invalid graph: %s", graph))
+	}
+	if nodeID == "" {
+		return ctx.JSON(http.StatusBadRequest, "node query param is required")
+	}
+
+	req := types.GraphNodePositionRequest{}
+	if err := json.NewDecoder(ctx.Request().Body).Decode(&req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, fmt.Sprintf("invalid request body: %s", err.Error()))
+	}
+
+	pinned, err := c.provider.StorageProvider.PinGraphNodePosition(types.GraphNodePosition{
+		Graph:     graph,
+		NodeID:    nodeID,
+		X:         req.X,
+		Y:         req.Y,
+		UpdatedBy: user.Email,
+	})
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	recordAuditEvent(ctx, c.provider, types.AuditActionUpdate, types.AuditResourceGraphNode, graph+"/"+nodeID, nodeID,
+		fmt.Sprintf("pinned %s node at (%d, %d)", graph, pinned.X, pinned.Y))
+	return ctx.JSON(http.StatusOK, pinned)
+}
+
+// UnpinGraphNodePosition godoc
+// @Summary Unpin Graph Node Position
+// @Description remove the pinned position of a node of a graph, so the server side layout positions it again (admin only)
+// @Tags Graphs
+// @Accept  json
+// @Produce  json
+// @Param graph path string true "Graph (mysql-replication or postgres-replication)"
+// @Param node query string true "Node ID"
+// @Success 204
+// @Failure 400 {object} string "invalid graph or node"
+// @Failure 403 {object} string "user must be an admin"
+// @Router /api/graphs/{graph}/positions [delete]
+func (c GraphPositionsHandler) UnpinGraphNodePosition(ctx echo.Context) error {
+	if _, status, err := requireAdmin(ctx, c.provider.StorageProvider, "unpin graph nodes"); err != nil {
+		return ctx.JSON(status, err.Error())
+	}
+
+	graph, nodeID := ctx.Param("graph"), ctx.QueryParam("node")
+	if !types.IsGraph(graph) {
+		return ctx.JSON(http.StatusBadRequest, fmt.Sprintf("invalid graph: %s", graph))
+	}
+	if nodeID == "" {
+		return ctx.JSON(http.StatusBadRequest, "node query param is required")
+	}
+
+	if err := c.provider.StorageProvider.UnpinGraphNodePosition(graph, nodeID); err != nil {
+		return ctx.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	recordAuditEvent(ctx, c.provider, types.AuditActionDelete, types.AuditResourceGraphNode, graph+"/"+nodeID, nodeID,
+		fmt.Sprintf("unpinned %s node", graph))
+	return ctx.NoContent(http.StatusNoContent)
+}
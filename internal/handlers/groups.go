@@ -52,9 +52,11 @@ func (c GroupsHandler) UpsertGroup(ctx echo.Context) error {
 		return ctx.JSON(http.StatusBadRequest, fmt.Sprintf("unable to decode group json body %s", err.Error()))
 	}
 
+	action := types.AuditActionUpdate
 	if group.ID != nil && *group.ID == uuid.Nil {
 		gid := uuid.New()
 		group.ID = &gid
+		action = types.AuditActionCreate
 	} else {
 		groupFound := false
 		for _, g := range groups {
@@ -72,5 +74,95 @@ func (c GroupsHandler) UpsertGroup(ctx echo.Context) error {
 	if err != nil {
 		return ctx.JSON(http.StatusInternalServerError, fmt.Sprintf("unable to upsert group %s", err.Error()))
 	}
+	recordAuditEvent(ctx, c.provider, action, types.AuditResourceGroup, g.ID.String(), g.Name, fmt.Sprintf("%d permissions, %d users", len(g.Permissions), len(g.Users)))
 	return ctx.JSON(http.StatusOK, g)
 }
+
+// GetDeletedGroups godoc
+// @Summary Get Deleted Groups
+// @Description get soft deleted groups that can be restored (admin only)
+// @Tags Groups
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} []types.Group
+// @Router /api/groups/deleted [get]
+func (c GroupsHandler) GetDeletedGroups(ctx echo.Context) error {
+	if _, status, err := requireAdmin(ctx, c.provider.StorageProvider, "view deleted groups"); err != nil {
+		return ctx.JSON(status, err.Error())
+	}
+
+	groups, err := c.provider.StorageProvider.GetDeletedGroups()
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, err.Error())
+	}
+	return ctx.JSON(http.StatusOK, groups)
+}
+
+// DeleteGroup godoc
+// @Summary Delete Group
+// @Description soft delete a group (admin only). The built-in admin group cannot be deleted.
+// @Tags Groups
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Group ID"
+// @NoContent 204 {object} string
+// @Router /api/groups/{id} [delete]
+func (c GroupsHandler) DeleteGroup(ctx echo.Context) error {
+	if _, status, err := requireAdmin(ctx, c.provider.StorageProvider, "delete groups"); err != nil {
+		return ctx.JSON(status, err.Error())
+	}
+
+	groupID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, fmt.Sprintf("invalid group id: %s", err.Error()))
+	}
+
+	if groupID.String() == types.BuiltinAdminID {
+		return ctx.JSON(http.StatusBadRequest, "the built-in admin group cannot be deleted")
+	}
+
+	groups, err := c.provider.StorageProvider.GetGroupsByIDs([]uuid.UUID{groupID})
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, err.Error())
+	}
+	if len(groups) == 0 {
+		return ctx.JSON(http.StatusNotFound, fmt.Sprintf("unable to find group %s", groupID.String()))
+	}
+
+	if err := c.provider.StorageProvider.DeleteGroup(groupID); err != nil {
+		return ctx.JSON(http.StatusInternalServerError, err.Error())
+	}
+	recordAuditEvent(ctx, c.provider, types.AuditActionDelete, types.AuditResourceGroup, groupID.String(), groups[0].Name, "deleted group")
+	return ctx.NoContent(http.StatusNoContent)
+}
+
+// RestoreGroup godoc
+// @Summary Restore Group
+// @Description restore a soft deleted group (admin only)
+// @Tags Groups
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Group ID"
+// @Success 200 {object} types.Group
+// @Router /api/groups/{id}/restore [post]
+func (c GroupsHandler) RestoreGroup(ctx echo.Context) error {
+	if _, status, err := requireAdmin(ctx, c.provider.StorageProvider, "restore groups"); err != nil {
+		return ctx.JSON(status, err.Error())
+	}
+
+	groupID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, fmt.Sprintf("invalid group id: %s", err.Error()))
+	}
+
+	if err := c.provider.StorageProvider.RestoreGroup(groupID); err != nil {
+		return ctx.JSON(http.StatusNotFound, err.Error())
+	}
+
+	groups, err := c.provider.StorageProvider.GetGroupsByIDs([]uuid.UUID{groupID})
+	if err != nil || len(groups) == 0 {
+		return ctx.JSON(http.StatusInternalServerError, fmt.Sprintf("unable to fetch restored group %s", groupID.String()))
+	}
+	recordAuditEvent(ctx, c.provider, types.AuditActionRestore, types.AuditResourceGroup, groupID.String(), groups[0].Name, "restored group")
+	return ctx.JSON(http.StatusOK, groups[0])
+}
@@ -5,9 +5,9 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 
 	"github.com/gorilla/websocket"
-	"github.com/labstack/echo-contrib/session"
 	"github.com/labstack/echo/v4"
 	"github.com/rs/zerolog/log"
 	"github.com/sullivtr/k8s_platform/internal/providers"
@@ -71,16 +71,11 @@ func (c K8sSessionHandler) DeletePod(ctx echo.Context) error {
 	}
 
 	// Fetch user's permissions from session data
-	sess, err := session.Get("user-permissions", ctx)
+	userPermissions, err := getUserPermissionTags(ctx)
 	if err != nil {
 		return ctx.JSON(http.StatusForbidden, fmt.Sprintf("forbidden. Unable to get auth info: %s", err.Error()))
 	}
 
-	userPermissions := []string{}
-	if sess.Values["permissions"] != nil {
-		userPermissions = sess.Values["permissions"].([]string)
-	}
-
 	if !c.hasWritePermissions(userPermissions, p.Labels) {
 		return ctx.JSON(http.StatusForbidden, "forbidden. You do not have write permissions for this resource")
 	}
@@ -116,16 +111,11 @@ func (c K8sSessionHandler) GetDeployments(ctx echo.Context) error {
 // @Failure 500 {object} string "unable to scale deployment"
 // @Router /api/k8s/deployments/scale [post]
 func (c K8sSessionHandler) ScaleDeployment(ctx echo.Context) error {
-	sess, err := session.Get("user-permissions", ctx)
+	userPermissions, err := getUserPermissionTags(ctx)
 	if err != nil {
 		return ctx.JSON(http.StatusForbidden, fmt.Sprintf("forbidden. Unable to get auth info: %s", err.Error()))
 	}
 
-	userPermissions := []string{}
-	if sess.Values["permissions"] != nil {
-		userPermissions = sess.Values["permissions"].([]string)
-	}
-
 	scaleInfoData, err := io.ReadAll(ctx.Request().Body)
 	if err != nil {
 		return ctx.JSON(http.StatusBadRequest, fmt.Sprintf("unable to read deploy scale info from json body %s", err.Error()))
@@ -312,16 +302,11 @@ func (c K8sSessionHandler) GetClusterEvents(ctx echo.Context) error {
 // @Failure 400 {object} string "Bad Request"
 // @Router /api/k8s/rolloutrestart [post]
 func (c K8sSessionHandler) RolloutRestart(ctx echo.Context) error {
-	sess, err := session.Get("user-permissions", ctx)
+	userPermissions, err := getUserPermissionTags(ctx)
 	if err != nil {
 		return ctx.JSON(http.StatusForbidden, fmt.Sprintf("forbidden. Unable to get auth info: %s", err.Error()))
 	}
 
-	userPermissions := []string{}
-	if sess.Values["permissions"] != nil {
-		userPermissions = sess.Values["permissions"].([]string)
-	}
-
 	resourceInfoData, err := io.ReadAll(ctx.Request().Body)
 	if err != nil {
 		return ctx.JSON(http.StatusBadRequest, fmt.Sprintf("unable to read resource info from json body %s", err.Error()))
@@ -362,11 +347,14 @@ func (c K8sSessionHandler) RolloutRestart(ctx echo.Context) error {
 
 // RunPodExecPlugin godoc
 // @Summary Executes a pod exec plugin on a specific pod
-// @Description Executes a pod exec plugin on a specific pod
+// @Description Executes a pod exec plugin on a specific pod. Plugin parameters are passed as pluginArgs.
+// @Description Async plugins start a background job, which is returned with a 202. Its output is available at /api/k8s/exec/jobs/{id}.
+// @Description Plugins with an output file upload it to the reports bucket, tagged with the user, the request reason and the plugin output type.
 // @Tags K8s
 // @Accept  json
 // @Produce  json
 // @Success 200 {string} string "Successfully initiated pod exec plugin"
+// @Success 202 {object} types.ExecJob
 // @Failure 400 {object} string "Bad Request"
 // @Failure 403 {object} string "Forbidden"
 // @Failure 500 {object} string "unable to run pod exec plugin"
@@ -383,16 +371,11 @@ func (c K8sSessionHandler) RunPodExecPlugin(ctx echo.Context) error {
 		return ctx.JSON(http.StatusBadRequest, fmt.Sprintf("unable to unmarshal resource info from json body for pod exec plugin request %s", err.Error()))
 	}
 
-	sess, err := session.Get("user-permissions", ctx)
+	userPermissions, err := getUserPermissionTags(ctx)
 	if err != nil {
 		return ctx.JSON(http.StatusForbidden, fmt.Sprintf("forbidden. Unable to get auth info: %s", err.Error()))
 	}
 
-	userPermissions := []string{}
-	if sess.Values["permissions"] != nil {
-		userPermissions = sess.Values["permissions"].([]string)
-	}
-
 	if !c.hasWritePermissions(userPermissions, resourceInfo.Labels) {
 		return ctx.JSON(http.StatusForbidden, "forbidden. You do not have write permissions for this resource")
 	}
@@ -421,26 +404,69 @@ func (c K8sSessionHandler) RunPodExecPlugin(ctx echo.Context) error {
 		return ctx.JSON(http.StatusBadRequest, fmt.Sprintf("plugin command does not match the expected command for %s", resourceInfo.Plugin.Name))
 	}
 
-	stdout, stderr, err := c.provider.K8sProvider.ExecutePodExecPlugin(resourceInfo.Namespace, resourceInfo.Name, plugin)
+	command, err := plugin.BuildCommand(resourceInfo.PluginArgs)
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, fmt.Sprintf("invalid arguments for plugin %s: %s", plugin.Name, err.Error()))
+	}
+
+	user, status, err := GetUserContext(ctx, c.provider.StorageProvider)
+	if err != nil {
+		return ctx.JSON(status, err.Error())
+	}
+
+	outputFile := ""
+	var artifactTags *types.ReportTags
+	if plugin.OutputFile != nil {
+		outputFile, err = plugin.BuildOutputPath(resourceInfo.PluginArgs)
+		if err != nil {
+			return ctx.JSON(http.StatusBadRequest, fmt.Sprintf("invalid arguments for plugin %s: %s", plugin.Name, err.Error()))
+		}
+
+		reason := strings.TrimSpace(resourceInfo.Reason)
+		if reason == "" {
+			reason = fmt.Sprintf("%s exec plugin", plugin.Name)
+		}
+		artifactTags = &types.ReportTags{User: user.Email, Reason: reason, Type: plugin.OutputFile.ReportType()}
+	}
+
+	if plugin.Async {
+		job, err := c.provider.StartExecJob(types.ExecJob{
+			Namespace:    resourceInfo.Namespace,
+			Pod:          resourceInfo.Name,
+			Command:      command,
+			StartedBy:    user.Name,
+			OutputFile:   outputFile,
+			ArtifactTags: artifactTags,
+		}, plugin)
+		if err != nil {
+			return ctx.JSON(http.StatusInternalServerError, fmt.Sprintf("unable to start pod exec plugin job: %v", err))
+		}
+		return ctx.JSON(http.StatusAccepted, job)
+	}
+
+	stdout, stderr, err := c.provider.K8sProvider.ExecutePodExecPlugin(resourceInfo.Namespace, resourceInfo.Name, plugin, command)
 	if err != nil {
 		return ctx.JSON(http.StatusInternalServerError, fmt.Sprintf("unable to run pod exec plugin: %v", err))
 	}
 	outStr := fmt.Sprintf("stdout: %s\nstderr: %s", stdout, stderr)
+
+	if artifactTags != nil {
+		artifact, err := c.provider.UploadExecPluginOutputFile(resourceInfo.Namespace, resourceInfo.Name, plugin, outputFile, *artifactTags)
+		if err != nil {
+			return ctx.JSON(http.StatusInternalServerError, fmt.Sprintf("%s\n%v", outStr, err))
+		}
+		outStr = fmt.Sprintf("%s\nartifact: %s", outStr, artifact)
+	}
 	return ctx.JSON(http.StatusOK, outStr)
 }
 
 func (c K8sSessionHandler) k8sDataHandler(ctx echo.Context, resource string) error {
 
-	sess, err := session.Get("user-permissions", ctx)
+	userPermissions, err := getUserPermissionTags(ctx)
 	if err != nil {
 		return ctx.JSON(http.StatusForbidden, fmt.Sprintf("forbidden. Unable to get auth info: %s", err.Error()))
 	}
 
-	userPermissions := []string{}
-	if sess.Values["permissions"] != nil {
-		userPermissions = sess.Values["permissions"].([]string)
-	}
-
 	upgradeHeader := ctx.Request().Header.Get("Upgrade")
 	if upgradeHeader != "websocket" {
 		data, err := c.GetK8sData(ctx, userPermissions, resource)
@@ -483,91 +509,16 @@ func (c K8sSessionHandler) k8sDataHandler(ctx echo.Context, resource string) err
 }
 
 func (c K8sSessionHandler) GetK8sData(ctx echo.Context, userPermissions []string, resource string) ([]types.K8sResourceWrapper, error) {
-	dac, ok := ctx.Get("dynamicAppConfig").(types.DynamicAppConfig)
-	if !ok {
-		log.Warn().Msg("dynamic config format unknown")
-	}
-	if dac.Data.K8sClusterName == "" {
-		dac.Data.K8sClusterName = "default"
-	}
-
-	data, err := c.provider.CacheProvider.Get(fmt.Sprintf("%s_%s", dac.Data.K8sClusterName, resource))
+	rd, err := getCachedK8sResources(ctx, c.provider, resource)
 	if err != nil {
-		return nil, fmt.Errorf("unable to get %s: %v", resource, err)
-	}
-
-	rd, ok := data.([]any)
-	if !ok {
-		return nil, fmt.Errorf("unexpected data type for %s: %T", resource, data)
+		return nil, err
 	}
 
-	resp := []types.K8sResourceWrapper{}
-
+	permissionMap := make(map[string]bool)
 	for _, p := range userPermissions {
-		if p == "*" || resource == "clusterevents" {
-			for _, r := range rd {
-				resp = append(resp, types.K8sResourceWrapper{
-					Data:  r,
-					Write: true,
-				})
-			}
-			return resp, nil
-		}
-	}
-
-	if resource != "clusterevents" {
-		permissionMap := make(map[string]bool)
-		for _, p := range userPermissions {
-			permissionMap[p] = true
-		}
-
-		for _, r := range rd {
-			d, ok := r.(map[string]interface{})
-			if !ok {
-				continue
-			}
-
-			md, ok := d["metadata"].(map[string]interface{})
-			if !ok {
-				continue
-			}
-
-			labelMap := map[string]interface{}{}
-			if mdl, ok := md["labels"]; ok {
-				labelMap, ok = mdl.(map[string]interface{})
-				if !ok {
-					continue
-				}
-			}
-
-			writeAdded := false
-			readAdded := false
-			for _, v := range labelMap {
-				writePermission := fmt.Sprintf("%s_write", v)
-				readPermission := fmt.Sprintf("%s_read", v)
-
-				if !writeAdded && permissionMap[writePermission] {
-					resp = append(resp, types.K8sResourceWrapper{
-						Data:  r,
-						Write: true,
-					})
-					writeAdded = true
-				} else if !readAdded && (permissionMap["global_read_only"] || permissionMap[readPermission]) {
-					resp = append(resp, types.K8sResourceWrapper{
-						Data:  r,
-						Write: false,
-					})
-					readAdded = true
-				}
-
-				if writeAdded && readAdded {
-					break
-				}
-			}
-		}
+		permissionMap[p] = true
 	}
-
-	return resp, nil
+	return filterK8sResources(permissionMap, resource, rd), nil
 }
 
 func (c K8sSessionHandler) hasWritePermissions(userPermissions []string, labels map[string]string) bool {
@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+	"github.com/sullivtr/k8s_platform/internal/providers"
+	"github.com/sullivtr/k8s_platform/internal/types"
+)
+
+// k8sResourceTypes are the k8s resource types cached by the data sink
+var k8sResourceTypes = []string{
+	"pods", "deployments", "replicasets", "daemonsets", "statefulsets", "jobs",
+	"cronjobs", "services", "ingresses", "configmaps", "nodes", "clusterevents",
+}
+
+// k8sResourceMetadata is the subset of a cached k8s object's metadata used for authorization
+type k8sResourceMetadata struct {
+	name      string
+	namespace string
+	labels    map[string]interface{}
+}
+
+// getCachedK8sResources returns the cached objects of a k8s resource type for the cluster of the request context
+func getCachedK8sResources(ctx echo.Context, provider *providers.ModuleProviders, resource string) ([]any, error) {
+	dac, ok := ctx.Get("dynamicAppConfig").(types.DynamicAppConfig)
+	if !ok {
+		log.Warn().Msg("dynamic config format unknown")
+	}
+	if dac.Data.K8sClusterName == "" {
+		dac.Data.K8sClusterName = "default"
+	}
+
+	data, err := provider.CacheProvider.Get(fmt.Sprintf("%s_%s", dac.Data.K8sClusterName, resource))
+	if err != nil {
+		return nil, fmt.Errorf("unable to get %s: %v", resource, err)
+	}
+
+	rd, ok := data.([]any)
+	if !ok {
+		return nil, fmt.Errorf("unexpected data type for %s: %T", resource, data)
+	}
+	return rd, nil
+}
+
+// getResourceMetadata reads the name, namespace and labels of a cached k8s object
+func getResourceMetadata(r any) (k8sResourceMetadata, bool) {
+	d, ok := r.(map[string]interface{})
+	if !ok {
+		return k8sResourceMetadata{}, false
+	}
+
+	md, ok := d["metadata"].(map[string]interface{})
+	if !ok {
+		return k8sResourceMetadata{}, false
+	}
+
+	labels := map[string]interface{}{}
+	if mdl, ok := md["labels"]; ok {
+		labels, ok = mdl.(map[string]interface{})
+		if !ok {
+			return k8sResourceMetadata{}, false
+		}
+	}
+
+	name, _ := md["name"].(string)
+	namespace, _ := md["namespace"].(string)
+	return k8sResourceMetadata{name: name, namespace: namespace, labels: labels}, true
+}
+
+// filterK8sResources returns the cached objects of a k8s resource type the permission tags of a user grant access to.
+// The * tag grants write access to every object, including objects whose metadata cannot be read. Cluster events are
+// visible with write access to users holding any permission tag. Other objects are evaluated with evaluateResourceAccess.
+func filterK8sResources(permissionMap map[string]bool, resource string, rd []any) []types.K8sResourceWrapper {
+	resp := []types.K8sResourceWrapper{}
+	if permissionMap["*"] || (resource == "clusterevents" && len(permissionMap) > 0) {
+		for _, r := range rd {
+			resp = append(resp, types.K8sResourceWrapper{Data: r, Write: true})
+		}
+		return resp
+	}
+	if resource == "clusterevents" {
+		return resp
+	}
+
+	for _, r := range rd {
+		md, ok := getResourceMetadata(r)
+		if !ok {
+			continue
+		}
+
+		write, read, _ := evaluateResourceAccess(permissionMap, md.labels)
+		if write || read {
+			resp = append(resp, types.K8sResourceWrapper{Data: r, Write: write})
+		}
+	}
+	return resp
+}
+
+// evaluateResourceAccess evaluates the permission tags of a user against the labels of a k8s object.
+// A label value grants write access through the <value>_write tag and read access through the <value>_read tag,
+// the * tag grants write access to everything and the global_read_only tag grants read access to every object with
+// at least one label. The returned tags are the ones granting the highest access level, in sorted order.
+func evaluateResourceAccess(permissionMap map[string]bool, labels map[string]interface{}) (bool, bool, []string) {
+	writeTags := []string{}
+	readTags := []string{}
+	if permissionMap["*"] {
+		writeTags = append(writeTags, "*")
+	}
+	if permissionMap["global_read_only"] && len(labels) > 0 {
+		readTags = append(readTags, "global_read_only")
+	}
+
+	for _, v := range labels {
+		writePermission := fmt.Sprintf("%s_write", v)
+		readPermission := fmt.Sprintf("%s_read", v)
+		if permissionMap[writePermission] {
+			writeTags = append(writeTags, writePermission)
+		}
+		if permissionMap[readPermission] {
+			readTags = append(readTags, readPermission)
+		}
+	}
+
+	if len(writeTags) > 0 {
+		return true, true, uniqueSorted(writeTags)
+	}
+	if len(readTags) > 0 {
+		return false, true, uniqueSorted(readTags)
+	}
+	return false, false, nil
+}
+
+// resolvePermissionGrants resolves the permission grants of a user the same way the user access context middleware does.
+// Admins hold every permission, other users hold the permissions of their groups plus global read only when it is enabled.
+// Deactivated users and users without groups (when global read only is disabled) hold no permissions.
+func resolvePermissionGrants(user types.User, groups []types.Group, globalReadOnly bool) []types.PermissionGrant {
+	if user.Deactivated || user.ID == nil {
+		return nil
+	}
+
+	if user.IsAdmin {
+		return []types.PermissionGrant{{AppTag: "*", Permission: "admin"}}
+	}
+
+	grants := []types.PermissionGrant{}
+	memberOfGroup := false
+	for _, g := range groups {
+		member := false
+		for _, u := range g.Users {
+			if u.ID != nil && *u.ID == *user.ID {
+				member = true
+				break
+			}
+		}
+		if !member {
+			continue
+		}
+
+		memberOfGroup = true
+		for _, p := range g.Permissions {
+			grants = append(grants, types.PermissionGrant{AppTag: p.AppTag, Permission: p.Name, Group: g.Name})
+		}
+	}
+
+	if !memberOfGroup && !globalReadOnly {
+		return nil
+	}
+	if globalReadOnly {
+		grants = append(grants, types.PermissionGrant{AppTag: "global_read_only", Permission: "global_read_only"})
+	}
+	return grants
+}
+
+// permissionMapFromGrants returns the set of permission tags held through the given grants
+func permissionMapFromGrants(grants []types.PermissionGrant) map[string]bool {
+	permissionMap := make(map[string]bool)
+	for _, g := range grants {
+		permissionMap[g.AppTag] = true
+	}
+	return permissionMap
+}
+
+// grantsForTags returns the grants that hold any of the given permission tags
+func grantsForTags(grants []types.PermissionGrant, tags []string) []types.PermissionGrant {
+	matched := []types.PermissionGrant{}
+	for _, g := range grants {
+		for _, t := range tags {
+			if g.AppTag == t {
+				matched = append(matched, g)
+				break
+			}
+		}
+	}
+	return matched
+}
+
+func uniqueSorted(values []string) []string {
+	sort.Strings(values)
+	unique := values[:0]
+	for i, v := range values {
+		if i == 0 || v != values[i-1] {
+			unique = append(unique, v)
+		}
+	}
+	return unique
+}
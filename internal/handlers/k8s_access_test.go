@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/sullivtr/k8s_platform/internal/types"
+)
+
+func TestEvaluateResourceAccess(t *testing.T) {
+	labels := map[string]interface{}{"app": "checkout", "team": "payments"}
+
+	tests := []struct {
+		name        string
+		permissions []string
+		write       bool
+		read        bool
+		tags        []string
+	}{
+		{name: "no permissions", permissions: []string{}},
+		{name: "unrelated label", permissions: []string{"search_write"}},
+		{name: "read", permissions: []string{"checkout_read"}, read: true, tags: []string{"checkout_read"}},
+		{name: "global read only", permissions: []string{"global_read_only"}, read: true, tags: []string{"global_read_only"}},
+		{name: "write wins over read", permissions: []string{"checkout_read", "payments_write", "global_read_only"}, write: true, read: true, tags: []string{"payments_write"}},
+		{name: "multiple write labels", permissions: []string{"checkout_write", "payments_write"}, write: true, read: true, tags: []string{"checkout_write", "payments_write"}},
+		{name: "all admin", permissions: []string{"*"}, write: true, read: true, tags: []string{"*"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			permissionMap := map[string]bool{}
+			for _, p := range tt.permissions {
+				permissionMap[p] = true
+			}
+			write, read, tags := evaluateResourceAccess(permissionMap, labels)
+			assert.Equal(t, tt.write, write)
+			assert.Equal(t, tt.read, read)
+			if tt.tags == nil {
+				assert.Empty(t, tags)
+			} else {
+				assert.Equal(t, tt.tags, tags)
+			}
+		})
+	}
+}
+
+func TestEvaluateResourceAccessUnlabeled(t *testing.T) {
+	// Global read only does not grant access to objects without labels
+	write, read, tags := evaluateResourceAccess(map[string]bool{"global_read_only": true}, map[string]interface{}{})
+	assert.False(t, write)
+	assert.False(t, read)
+	assert.Empty(t, tags)
+
+	write, read, tags = evaluateResourceAccess(map[string]bool{"*": true}, map[string]interface{}{})
+	assert.True(t, write)
+	assert.True(t, read)
+	assert.Equal(t, []string{"*"}, tags)
+}
+
+func TestFilterK8sResources(t *testing.T) {
+	labeled := map[string]interface{}{"metadata": map[string]interface{}{"name": "checkout", "labels": map[string]interface{}{"app": "checkout"}}}
+	unlabeled := map[string]interface{}{"metadata": map[string]interface{}{"name": "kube-proxy"}}
+	unparseable := "not an object"
+	rd := []any{labeled, unlabeled, unparseable}
+
+	tests := []struct {
+		name        string
+		resource    string
+		permissions []string
+		expected    []types.K8sResourceWrapper
+	}{
+		{name: "no permissions", resource: "pods", permissions: []string{}, expected: []types.K8sResourceWrapper{}},
+		{
+			name:        "all admin sees objects that cannot be read",
+			resource:    "pods",
+			permissions: []string{"*"},
+			expected:    []types.K8sResourceWrapper{{Data: labeled, Write: true}, {Data: unlabeled, Write: true}, {Data: unparseable, Write: true}},
+		},
+		{
+			name:        "global read only does not see unlabeled objects",
+			resource:    "pods",
+			permissions: []string{"global_read_only"},
+			expected:    []types.K8sResourceWrapper{{Data: labeled, Write: false}},
+		},
+		{
+			name:        "label write",
+			resource:    "pods",
+			permissions: []string{"checkout_write", "global_read_only"},
+			expected:    []types.K8sResourceWrapper{{Data: labeled, Write: true}},
+		},
+		{name: "cluster events without permissions", resource: "clusterevents", permissions: []string{}, expected: []types.K8sResourceWrapper{}},
+		{
+			name:        "cluster events with any permission",
+			resource:    "clusterevents",
+			permissions: []string{"search_read"},
+			expected:    []types.K8sResourceWrapper{{Data: labeled, Write: true}, {Data: unlabeled, Write: true}, {Data: unparseable, Write: true}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			permissionMap := map[string]bool{}
+			for _, p := range tt.permissions {
+				permissionMap[p] = true
+			}
+			assert.Equal(t, tt.expected, filterK8sResources(permissionMap, tt.resource, rd))
+		})
+	}
+}
+
+func TestResolvePermissionGrants(t *testing.T) {
+	uid := uuid.New()
+	otherID := uuid.New()
+	user := types.User{ID: &uid, Name: "jdoe"}
+	groups := []types.Group{
+		{
+			Name:        "payments",
+			Users:       []*types.User{{ID: &uid}},
+			Permissions: []*types.Permission{{Name: "paymentswrite", AppTag: "payments_write"}},
+		},
+		{
+			Name:        "search",
+			Users:       []*types.User{{ID: &otherID}},
+			Permissions: []*types.Permission{{Name: "searchwrite", AppTag: "search_write"}},
+		},
+	}
+
+	grants := resolvePermissionGrants(user, groups, false)
+	assert.Equal(t, []types.PermissionGrant{{AppTag: "payments_write", Permission: "paymentswrite", Group: "payments"}}, grants)
+
+	grants = resolvePermissionGrants(user, groups, true)
+	assert.Len(t, grants, 2)
+	assert.Equal(t, "global_read_only", grants[1].AppTag)
+
+	outsider := types.User{ID: &otherID, Name: "outsider"}
+	assert.Len(t, resolvePermissionGrants(types.User{ID: &otherID}, groups[:1], false), 0)
+	assert.Equal(t, []types.PermissionGrant{{AppTag: "global_read_only", Permission: "global_read_only"}}, resolvePermissionGrants(outsider, groups[:1], true))
+
+	admin := types.User{ID: &otherID, IsAdmin: true}
+	assert.Equal(t, []types.PermissionGrant{{AppTag: "*", Permission: "admin"}}, resolvePermissionGrants(admin, nil, false))
+
+	deactivated := types.User{ID: &uid, Deactivated: true}
+	assert.Empty(t, resolvePermissionGrants(deactivated, groups, true))
+}
+
+func TestGrantsForTags(t *testing.T) {
+	grants := []types.PermissionGrant{
+		{AppTag: "payments_write", Group: "payments"},
+		{AppTag: "payments_write", Group: "oncall"},
+		{AppTag: "global_read_only"},
+	}
+	assert.Equal(t, grants[:2], grantsForTags(grants, []string{"payments_write"}))
+	assert.Empty(t, grantsForTags(grants, []string{"search_write"}))
+}
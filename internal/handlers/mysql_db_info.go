@@ -2,12 +2,16 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/sullivtr/k8s_platform/internal/providers"
 	"github.com/sullivtr/k8s_platform/internal/types"
+	"gorm.io/gorm"
 )
 
 type MySQLDBInfoHandler struct {
@@ -65,6 +69,9 @@ func (c MySQLDBInfoHandler) UpsertMySQLDBInfo(ctx echo.Context) error {
 	}
 
 	dbInfo, err := c.provider.StorageProvider.UpsertMySQLDBInfo(mysqlDBInfo)
+	if errors.Is(err, providers.ErrInvalidMySQLDBInfo) {
+		return ctx.JSON(http.StatusBadRequest, err.Error())
+	}
 	if err != nil {
 		return ctx.JSON(http.StatusInternalServerError, fmt.Sprintf("unable to upsert mysql db info: %s", err.Error()))
 	}
@@ -106,19 +113,46 @@ func (c MySQLDBInfoHandler) DeleteMySQLDBInfo(ctx echo.Context) error {
 
 // GetReplicationTopology godoc
 // @Summary Get MySQL Replication Topology
-// @Description get mysql replication topology
+// @Description get mysql replication topology, as of the latest capture or of the capture current at a past time. Nodes
+// @Description are positioned by the server side layout, or at the position an admin pinned them at.
 // @Tags MySQLDBInfo
 // @Accept  json
 // @Produce  json
+// @Param at query string false "Show the topology as it was at this time (RFC 3339)"
 // @Success 200 {object} string
+// @Failure 400 {object} string "invalid at"
+// @Failure 404 {object} string "the topology was not captured yet at that time"
 // @Router /api/infra/mysql/topology [get]
 func (c MySQLDBInfoHandler) GetReplicationTopology(ctx echo.Context) error {
-	nodes, err := c.provider.CacheProvider.Get("mysql-repl-topo-nodes")
+	if at := ctx.QueryParam("at"); at != "" {
+		atTime, err := time.Parse(time.RFC3339, at)
+		if err != nil {
+			return ctx.JSON(http.StatusBadRequest, fmt.Sprintf("invalid at, %s", err.Error()))
+		}
+		snapshot, err := c.provider.StorageProvider.GetReplTopoSnapshot(atTime)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ctx.JSON(http.StatusNotFound, "the mysql replication topology was not captured yet at that time")
+		}
+		if err != nil {
+			return ctx.JSON(http.StatusInternalServerError, err.Error())
+		}
+		if err := c.provider.PinReplTopoNodes(types.GraphMySQLReplication, snapshot.Nodes); err != nil {
+			return ctx.JSON(http.StatusInternalServerError, err.Error())
+		}
+		return ctx.JSON(http.StatusOK, map[string]interface{}{
+			"nodes":      snapshot.Nodes,
+			"edges":      snapshot.Edges,
+			"changes":    snapshot.Changes,
+			"capturedAt": snapshot.CapturedAt,
+		})
+	}
+
+	nodes, err := c.provider.GetPinnedReplTopoNodes(types.GraphMySQLReplication, providers.MySQLReplTopoNodesCacheKey)
 	if err != nil {
 		return ctx.JSON(http.StatusInternalServerError, fmt.Sprintf("unable to get mysql replication topology nodes: %s", err.Error()))
 	}
 
-	edges, err := c.provider.CacheProvider.Get("mysql-repl-topo-edges")
+	edges, err := c.provider.CacheProvider.Get(providers.MySQLReplTopoEdgesCacheKey)
 	if err != nil {
 		return ctx.JSON(http.StatusInternalServerError, fmt.Sprintf("unable to get mysql replication topology edges: %s", err.Error()))
 	}
@@ -128,5 +162,157 @@ func (c MySQLDBInfoHandler) GetReplicationTopology(ctx echo.Context) error {
 		"edges": edges,
 	}
 
+	// The changes are only cached once the data sink captured the topology
+	if latest, err := c.provider.CacheProvider.Get(providers.MySQLReplTopoChangesCacheKey); err == nil {
+		if latest, ok := latest.(map[string]interface{}); ok {
+			topoGraph["changes"] = latest["changes"]
+			topoGraph["capturedAt"] = latest["capturedAt"]
+		}
+	}
+
 	return ctx.JSON(http.StatusOK, topoGraph)
 }
+
+// GetReplicationTopologyHistory godoc
+// @Summary Get MySQL Replication Topology History
+// @Description get the captures of the mysql replication topology taken in a time range, newest first, with what changed in each
+// @Tags MySQLDBInfo
+// @Accept  json
+// @Produce  json
+// @Param since query string false "Only captures taken at or after this time (RFC 3339, default 24 hours ago)"
+// @Param until query string false "Only captures taken at or before this time (RFC 3339, default now)"
+// @Param limit query int false "Maximum number of captures (default 100, max 1000)"
+// @Success 200 {object} []types.ReplTopoSnapshot
+// @Failure 400 {object} string "invalid range"
+// @Router /api/infra/mysql/topology/history [get]
+func (c MySQLDBInfoHandler) GetReplicationTopologyHistory(ctx echo.Context) error {
+	until := time.Now().UTC()
+	since := until.Add(-24 * time.Hour)
+	limit := 100
+
+	var err error
+	if v := ctx.QueryParam("until"); v != "" {
+		if until, err = time.Parse(time.RFC3339, v); err != nil {
+			return ctx.JSON(http.StatusBadRequest, fmt.Sprintf("invalid until, %s", err.Error()))
+		}
+	}
+	if v := ctx.QueryParam("since"); v != "" {
+		if since, err = time.Parse(time.RFC3339, v); err != nil {
+			return ctx.JSON(http.StatusBadRequest, fmt.Sprintf("invalid since, %s", err.Error()))
+		}
+	}
+	if v := ctx.QueryParam("limit"); v != "" {
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit <= 0 || limit > 1000 {
+			return ctx.JSON(http.StatusBadRequest, "limit must be an integer between 1 and 1000")
+		}
+	}
+
+	history, err := c.provider.StorageProvider.GetReplTopoSnapshotHistory(since, until, limit)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	return ctx.JSON(http.StatusOK, history)
+}
+
+// GetMySQLQueryPlugins godoc
+// @Summary Get MySQL Query Plugins
+// @Description get the query plugins the user may run against the hosts of the mysql catalog
+// @Tags MySQLDBInfo
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} []types.MySQLQueryPlugin
+// @Router /api/infra/mysql/query [get]
+func (c MySQLDBInfoHandler) GetMySQLQueryPlugins(ctx echo.Context) error {
+	userPermissions, err := getUserPermissionTags(ctx)
+	if err != nil {
+		return ctx.JSON(http.StatusForbidden, fmt.Sprintf("forbidden. Unable to get auth info: %s", err.Error()))
+	}
+
+	appConfig, err := c.provider.StorageProvider.GetDynamicAppConfig()
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, fmt.Sprintf("unable to load query plugin configurations: %v", err))
+	}
+
+	plugins := []types.MySQLQueryPlugin{}
+	for _, p := range appConfig.Data.MySQLQueryPlugins {
+		if p.Permitted(userPermissions) {
+			plugins = append(plugins, p)
+		}
+	}
+	return ctx.JSON(http.StatusOK, plugins)
+}
+
+// RunMySQLQueryPlugin godoc
+// @Summary Run MySQL Query Plugin
+// @Description Runs a read only query plugin against a host of the mysql catalog and returns its result as a table.
+// @Description Users must hold the mysql_query or mysql_query_<name> permission tag. Plugin parameters are passed as args.
+// @Tags MySQLDBInfo
+// @Accept  json
+// @Produce  json
+// @Param host path string true "The shortname of the catalog host"
+// @Param name path string true "The name of the query plugin"
+// @Param request body types.MySQLQueryRequest false "The plugin arguments"
+// @Success 200 {object} types.MySQLQueryResult
+// @Failure 400 {object} string "invalid arguments"
+// @Failure 403 {object} string "Forbidden"
+// @Failure 404 {object} string "unknown host or plugin"
+// @Failure 500 {object} string "unable to run query plugin"
+// @Router /api/infra/mysql/{host}/query/{name} [post]
+func (c MySQLDBInfoHandler) RunMySQLQueryPlugin(ctx echo.Context) error {
+	request := types.MySQLQueryRequest{}
+	if ctx.Request().ContentLength != 0 {
+		if err := json.NewDecoder(ctx.Request().Body).Decode(&request); err != nil {
+			return ctx.JSON(http.StatusBadRequest, fmt.Sprintf("unable to unmarshal query plugin request: %s", err.Error()))
+		}
+	}
+
+	userPermissions, err := getUserPermissionTags(ctx)
+	if err != nil {
+		return ctx.JSON(http.StatusForbidden, fmt.Sprintf("forbidden. Unable to get auth info: %s", err.Error()))
+	}
+
+	appConfig, err := c.provider.StorageProvider.GetDynamicAppConfig()
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, fmt.Sprintf("unable to load query plugin configurations: %v", err))
+	}
+
+	name := ctx.Param("name")
+	plugin, ok := types.MySQLQueryPlugin{}, false
+	for _, p := range appConfig.Data.MySQLQueryPlugins {
+		if p.Name == name {
+			plugin, ok = p, true
+			break
+		}
+	}
+	if !ok {
+		return ctx.JSON(http.StatusNotFound, fmt.Sprintf("query plugin with name, %s, does not exist", name))
+	}
+	if !plugin.Permitted(userPermissions) {
+		return ctx.JSON(http.StatusForbidden, fmt.Sprintf("forbidden. You do not have permission to run query plugin %s", name))
+	}
+
+	db, err := c.provider.GetMySQLCatalogHost(ctx.Param("host"))
+	if errors.Is(err, providers.ErrMySQLHostNotFound) {
+		return ctx.JSON(http.StatusNotFound, fmt.Sprintf("host %s is not in the mysql catalog", ctx.Param("host")))
+	}
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, err.Error())
+	}
+	if !plugin.AllowsHost(db.Shortname) {
+		return ctx.JSON(http.StatusForbidden, fmt.Sprintf("forbidden. Query plugin %s may not run against %s", name, db.Shortname))
+	}
+	if _, _, err := plugin.BuildQuery(request.Args); err != nil {
+		return ctx.JSON(http.StatusBadRequest, fmt.Sprintf("invalid arguments for query plugin %s: %s", name, err.Error()))
+	}
+
+	recordAuditEvent(ctx, c.provider, types.AuditActionExecute, types.AuditResourceMySQLHost, db.Host, db.Shortname,
+		fmt.Sprintf("ran query plugin %s with args %v", name, request.Args))
+	result, err := c.provider.MySQLTopoProvider.RunQueryPlugin(ctx.Request().Context(), db, plugin, request.Args)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	return ctx.JSON(http.StatusOK, result)
+}
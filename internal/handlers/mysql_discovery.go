@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sullivtr/k8s_platform/internal/providers"
+	"github.com/sullivtr/k8s_platform/internal/types"
+	"gorm.io/gorm"
+)
+
+// GetMySQLDiscoveryProposals godoc
+// @Summary Get MySQL Discovery Proposals
+// @Description get the mysql hosts found by discovery sources which are not in the catalog
+// @Tags MySQLDBInfo
+// @Accept  json
+// @Produce  json
+// @Param status query string false "pending (default), ignored or all"
+// @Success 200 {object} []types.MySQLDiscoveryProposal
+// @Failure 400 {object} string "invalid status"
+// @Router /api/infra/mysql/discovery [get]
+func (c MySQLDBInfoHandler) GetMySQLDiscoveryProposals(ctx echo.Context) error {
+	userDetail, _, err := GetUserContext(ctx, c.provider.StorageProvider)
+	if err != nil {
+		return ctx.JSON(http.StatusForbidden, "user context unavailable")
+	}
+
+	if !userDetail.IsAdmin {
+		return ctx.JSON(http.StatusForbidden, "user must be a global admin to review mysql discovery proposals")
+	}
+
+	status := ctx.QueryParam("status")
+	switch status {
+	case "":
+		status = types.MySQLProposalPending
+	case "all":
+		status = ""
+	case types.MySQLProposalPending, types.MySQLProposalIgnored:
+	default:
+		return ctx.JSON(http.StatusBadRequest, fmt.Sprintf("status must be %s, %s or all", types.MySQLProposalPending, types.MySQLProposalIgnored))
+	}
+
+	proposals, err := c.provider.StorageProvider.GetMySQLDiscoveryProposals(status)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	return ctx.JSON(http.StatusOK, proposals)
+}
+
+// AcceptMySQLDiscoveryProposal godoc
+// @Summary Accept MySQL Discovery Proposal
+// @Description add a discovered mysql host to the catalog. The shortname and port default to the discovered ones, and the username to the discovery config.
+// @Tags MySQLDBInfo
+// @Accept  json
+// @Produce  json
+// @Param host path string true "the discovered host"
+// @Param request body types.MySQLDiscoveryAcceptRequest true "the catalog settings of the host"
+// @Success 200 {object} types.MySQLDBInfo
+// @Failure 400 {object} string "the catalog entry is not valid"
+// @Failure 404 {object} string "no proposal for the host"
+// @Router /api/infra/mysql/discovery/{host}/accept [post]
+func (c MySQLDBInfoHandler) AcceptMySQLDiscoveryProposal(ctx echo.Context) error {
+	userDetail, _, err := GetUserContext(ctx, c.provider.StorageProvider)
+	if err != nil {
+		return ctx.JSON(http.StatusForbidden, "user context unavailable")
+	}
+
+	if !userDetail.IsAdmin {
+		return ctx.JSON(http.StatusForbidden, "user must be a global admin to accept mysql discovery proposals")
+	}
+
+	var request types.MySQLDiscoveryAcceptRequest
+	if err := json.NewDecoder(ctx.Request().Body).Decode(&request); err != nil {
+		return ctx.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	dac, err := c.provider.StorageProvider.GetDynamicAppConfig()
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	host := ctx.Param("host")
+	dbInfo, err := c.provider.StorageProvider.AcceptMySQLDiscoveryProposal(host, request, dac.Data.MySQLDiscovery.Username)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ctx.JSON(http.StatusNotFound, fmt.Sprintf("no discovery proposal for %s", host))
+	}
+	if errors.Is(err, providers.ErrInvalidMySQLDBInfo) {
+		return ctx.JSON(http.StatusBadRequest, err.Error())
+	}
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	recordAuditEvent(ctx, c.provider, types.AuditActionCreate, types.AuditResourceMySQLHost, dbInfo.Host, dbInfo.Shortname,
+		fmt.Sprintf("accepted the %s discovery proposal %s", dbInfo.Origin, dbInfo.OriginRef))
+	return ctx.JSON(http.StatusOK, dbInfo)
+}
+
+// IgnoreMySQLDiscoveryProposal godoc
+// @Summary Ignore MySQL Discovery Proposal
+// @Description ignore a discovered mysql host, so it is no longer proposed. Ignored hosts can be proposed again with restore.
+// @Tags MySQLDBInfo
+// @Accept  json
+// @Produce  json
+// @Param host path string true "the discovered host"
+// @NoContent 204 {object} string
+// @Failure 404 {object} string "no proposal for the host"
+// @Router /api/infra/mysql/discovery/{host}/ignore [post]
+func (c MySQLDBInfoHandler) IgnoreMySQLDiscoveryProposal(ctx echo.Context) error {
+	return c.setMySQLDiscoveryProposalStatus(ctx, types.MySQLProposalIgnored)
+}
+
+// RestoreMySQLDiscoveryProposal godoc
+// @Summary Restore MySQL Discovery Proposal
+// @Description propose an ignored mysql host again
+// @Tags MySQLDBInfo
+// @Accept  json
+// @Produce  json
+// @Param host path string true "the discovered host"
+// @NoContent 204 {object} string
+// @Failure 404 {object} string "no proposal for the host"
+// @Router /api/infra/mysql/discovery/{host}/restore [post]
+func (c MySQLDBInfoHandler) RestoreMySQLDiscoveryProposal(ctx echo.Context) error {
+	return c.setMySQLDiscoveryProposalStatus(ctx, types.MySQLProposalPending)
+}
+
+func (c MySQLDBInfoHandler) setMySQLDiscoveryProposalStatus(ctx echo.Context, status string) error {
+	userDetail, _, err := GetUserContext(ctx, c.provider.StorageProvider)
+	if err != nil {
+		return ctx.JSON(http.StatusForbidden, "user context unavailable")
+	}
+
+	if !userDetail.IsAdmin {
+		return ctx.JSON(http.StatusForbidden, "user must be a global admin to review mysql discovery proposals")
+	}
+
+	host := ctx.Param("host")
+	err = c.provider.StorageProvider.SetMySQLDiscoveryProposalStatus(host, status)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ctx.JSON(http.StatusNotFound, fmt.Sprintf("no discovery proposal for %s", host))
+	}
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	recordAuditEvent(ctx, c.provider, types.AuditActionUpdate, types.AuditResourceMySQLHost, host, host,
+		fmt.Sprintf("set the discovery proposal status to %s", status))
+	return ctx.NoContent(http.StatusNoContent)
+}
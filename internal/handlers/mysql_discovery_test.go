@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/sullivtr/k8s_platform/internal/modules"
+	"github.com/sullivtr/k8s_platform/internal/providers"
+	"github.com/sullivtr/k8s_platform/internal/types"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func TestAcceptInvalidMySQLDiscoveryProposal(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{Conn: db, PreferSimpleProtocol: true}), &gorm.Config{})
+	require.NoError(t, err)
+	handler := MySQLDBInfoHandler{provider: &providers.ModuleProviders{
+		StorageProvider: &providers.StorageProvider{Session: providers.StorageSession{SDK: modules.NewPGSDK(gormDB)}},
+	}}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "users" WHERE name = $1`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "email", "is_admin"}).AddRow(uuid.New(), "admin", "admin@khub.dev", true))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "group_users"`)).WillReturnRows(sqlmock.NewRows([]string{"user_id", "group_id"}))
+	// No discovery username is configured, so the accepted host has no username
+	data, _ := types.DynamicConfigJSONB{K8sClusterName: "prod"}.Value()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "dynamic_app_configs"`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "version", "data"}).AddRow(1, 1, data))
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "my_sql_discovery_proposals" WHERE host = $1`)).
+		WillReturnRows(sqlmock.NewRows([]string{"host", "shortname", "port"}).AddRow("orders.rds.amazonaws.com", "orders", 3306))
+	mock.ExpectRollback()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/infra/mysql/discovery/orders.rds.amazonaws.com/accept", strings.NewReader(`{}`))
+	rec := httptest.NewRecorder()
+	ctx := echo.New().NewContext(req, rec)
+	ctx.Set("username", "admin")
+	ctx.Set("email", "admin@khub.dev")
+	ctx.SetParamNames("host")
+	ctx.SetParamValues("orders.rds.amazonaws.com")
+
+	require.NoError(t, handler.AcceptMySQLDiscoveryProposal(ctx))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Username is invalid")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
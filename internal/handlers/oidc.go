@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jws"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"github.com/sullivtr/k8s_platform/internal/config"
+)
+
+// oidcDiscovery represents the parts of an OIDC discovery document used by khub
+// See https://openid.net/specs/openid-connect-discovery-1_0.html#ProviderMetadata
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserInfoEndpoint      string `json:"userinfo_endpoint"`
+	EndSessionEndpoint    string `json:"end_session_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// validate reports the first required endpoint missing from the discovery document
+func (d oidcDiscovery) validate() error {
+	required := []struct {
+		name  string
+		value string
+	}{
+		{name: "issuer", value: d.Issuer},
+		{name: "authorization_endpoint", value: d.AuthorizationEndpoint},
+		{name: "token_endpoint", value: d.TokenEndpoint},
+		{name: "userinfo_endpoint", value: d.UserInfoEndpoint},
+		{name: "jwks_uri", value: d.JWKSURI},
+	}
+	for _, r := range required {
+		if r.value == "" {
+			return fmt.Errorf("OIDC discovery document is missing %s", r.name)
+		}
+	}
+	return nil
+}
+
+type cachedOIDCDiscovery struct {
+	discovery oidcDiscovery
+	expiresAt time.Time
+}
+
+type cachedJWKS struct {
+	keySet    jwk.Set
+	expiresAt time.Time
+}
+
+// oidcMetadataCache caches discovery documents and JWKS by URL so that they are not fetched on every login
+type oidcMetadataCache struct {
+	mu        sync.RWMutex
+	discovery map[string]cachedOIDCDiscovery
+	jwks      map[string]cachedJWKS
+}
+
+var oidcCache = &oidcMetadataCache{
+	discovery: map[string]cachedOIDCDiscovery{},
+	jwks:      map[string]cachedJWKS{},
+}
+
+func (c *oidcMetadataCache) getDiscovery(url string) (oidcDiscovery, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	cached, ok := c.discovery[url]
+	if !ok || time.Now().After(cached.expiresAt) {
+		return oidcDiscovery{}, false
+	}
+	return cached.discovery, true
+}
+
+func (c *oidcMetadataCache) putDiscovery(url string, discovery oidcDiscovery, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.discovery[url] = cachedOIDCDiscovery{discovery: discovery, expiresAt: time.Now().Add(ttl)}
+}
+
+func (c *oidcMetadataCache) getJWKS(url string) (jwk.Set, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	cached, ok := c.jwks[url]
+	if !ok || time.Now().After(cached.expiresAt) {
+		return nil, false
+	}
+	return cached.keySet, true
+}
+
+func (c *oidcMetadataCache) putJWKS(url string, keySet jwk.Set, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.jwks[url] = cachedJWKS{keySet: keySet, expiresAt: time.Now().Add(ttl)}
+}
+
+// newOAuthClient returns an oauth client for the given identity provider
+func newOAuthClient(idp config.OIDCProvider, metadataCacheTTLSeconds int) *oauthClient {
+	return &oauthClient{
+		ClientID:            idp.ClientID,
+		ClientSecret:        idp.ClientSecret,
+		OIDCCLientTLSVerify: idp.ClientTLSVerify,
+		IDP:                 idp.Name,
+		Client:              &http.Client{},
+		IssuerURL:           idp.Issuer,
+		OIDCRedirectURI:     idp.RedirectURI,
+		MetadataCacheTTL:    time.Duration(metadataCacheTTLSeconds) * time.Second,
+	}
+}
+
+func (c *oauthClient) httpClient() *http.Client {
+	return &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: c.OIDCCLientTLSVerify},
+		},
+	}
+}
+
+// getDiscovery returns the OIDC discovery document of the issuer, served from cache when possible
+func (c *oauthClient) getDiscovery() (oidcDiscovery, error) {
+	discoveryURL := strings.TrimSuffix(c.IssuerURL, "/") + "/.well-known/openid-configuration"
+	if discovery, ok := oidcCache.getDiscovery(discoveryURL); ok {
+		return discovery, nil
+	}
+
+	resp, err := c.httpClient().Get(discoveryURL)
+	if err != nil {
+		return oidcDiscovery{}, fmt.Errorf("failed to get OIDC dicovery data: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return oidcDiscovery{}, fmt.Errorf("failed to get OIDC dicovery data: unexpected status %d", resp.StatusCode)
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+	discovery := oidcDiscovery{}
+	if err := json.Unmarshal(body, &discovery); err != nil {
+		return oidcDiscovery{}, fmt.Errorf("failed to unmarshal response body: %v", err)
+	}
+
+	if err := discovery.validate(); err != nil {
+		return oidcDiscovery{}, err
+	}
+
+	oidcCache.putDiscovery(discoveryURL, discovery, c.MetadataCacheTTL)
+	return discovery, nil
+}
+
+// getJWKS returns the signing keys of the issuer, served from cache unless a refresh is forced
+func (c *oauthClient) getJWKS(jwksURI string, forceRefresh bool) (jwk.Set, error) {
+	if !forceRefresh {
+		if keySet, ok := oidcCache.getJWKS(jwksURI); ok {
+			return keySet, nil
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	keySet, err := jwk.Fetch(ctx, jwksURI, jwk.WithHTTPClient(c.httpClient()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get OIDC JWKS: %v", err)
+	}
+
+	oidcCache.putJWKS(jwksURI, keySet, c.MetadataCacheTTL)
+	return keySet, nil
+}
+
+// verifyIDToken validates the signature, issuer, audience and expiry of an id_token.
+// The JWKS is refreshed once when the token is signed by an unknown key, in case the IDP rotated its keys.
+func (c *oauthClient) verifyIDToken(idToken string, discovery oidcDiscovery) (jwt.Token, error) {
+	if idToken == "" {
+		return nil, errors.New("no id_token returned by the IDP")
+	}
+
+	var token jwt.Token
+	var err error
+	for _, forceRefresh := range []bool{false, true} {
+		var keySet jwk.Set
+		keySet, err = c.getJWKS(discovery.JWKSURI, forceRefresh)
+		if err != nil {
+			return nil, err
+		}
+
+		token, err = jwt.Parse([]byte(idToken),
+			jwt.WithKeySet(keySet, jws.WithInferAlgorithmFromKey(true)),
+			jwt.WithValidate(true),
+			jwt.WithIssuer(discovery.Issuer),
+			jwt.WithAudience(c.ClientID),
+		)
+		if err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid id_token: %v", err)
+	}
+
+	if token.Subject() == "" {
+		return nil, errors.New("invalid id_token: no subject")
+	}
+	return token, nil
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/sullivtr/k8s_platform/internal/config"
+)
+
+func newDiscoveryServer(t *testing.T, hits *int32, omit string) *httptest.Server {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/.well-known/openid-configuration" {
+			http.NotFound(w, r)
+			return
+		}
+		atomic.AddInt32(hits, 1)
+		doc := map[string]string{
+			"issuer":                 server.URL,
+			"authorization_endpoint": server.URL + "/authorize",
+			"token_endpoint":         server.URL + "/token",
+			"userinfo_endpoint":      server.URL + "/userinfo",
+			"jwks_uri":               server.URL + "/keys",
+		}
+		delete(doc, omit)
+		assert.NoError(t, json.NewEncoder(w).Encode(doc))
+	}))
+	return server
+}
+
+func TestGetDiscoveryIsCached(t *testing.T) {
+	var hits int32
+	server := newDiscoveryServer(t, &hits, "")
+	defer server.Close()
+
+	client := newOAuthClient(config.OIDCProvider{Name: "test", Issuer: server.URL + "/"}, 60)
+	for i := 0; i < 3; i++ {
+		discovery, err := client.getDiscovery()
+		assert.NoError(t, err)
+		assert.Equal(t, server.URL+"/token", discovery.TokenEndpoint)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&hits))
+}
+
+func TestGetDiscoveryCacheExpires(t *testing.T) {
+	var hits int32
+	server := newDiscoveryServer(t, &hits, "")
+	defer server.Close()
+
+	client := newOAuthClient(config.OIDCProvider{Name: "test", Issuer: server.URL}, 0)
+	for i := 0; i < 2; i++ {
+		_, err := client.getDiscovery()
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, int32(2), atomic.LoadInt32(&hits))
+}
+
+func TestGetDiscoveryMissingEndpoint(t *testing.T) {
+	var hits int32
+	server := newDiscoveryServer(t, &hits, "token_endpoint")
+	defer server.Close()
+
+	client := newOAuthClient(config.OIDCProvider{Name: "test", Issuer: server.URL}, 60)
+	_, err := client.getDiscovery()
+	assert.EqualError(t, err, "OIDC discovery document is missing token_endpoint")
+}
+
+func TestGetDiscoveryUnavailable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	client := newOAuthClient(config.OIDCProvider{Name: "test", Issuer: server.URL}, 60)
+	_, err := client.getDiscovery()
+	assert.EqualError(t, err, "failed to get OIDC dicovery data: unexpected status 404")
+}
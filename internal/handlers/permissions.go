@@ -46,14 +46,304 @@ func (c PermissionsHandler) UpsertPermission(ctx echo.Context) error {
 		return ctx.JSON(http.StatusBadRequest, fmt.Sprintf("unable to decode permission json body %s", err.Error()))
 	}
 
+	action := types.AuditActionUpdate
 	if permission.ID != nil && *permission.ID == uuid.Nil {
 		pid := uuid.New()
 		permission.ID = &pid
+		action = types.AuditActionCreate
 	}
 
 	a, err := c.provider.StorageProvider.UpsertPermission(permission)
 	if err != nil {
 		return ctx.JSON(http.StatusInternalServerError, fmt.Sprintf("unable to upsert permission %s", err.Error()))
 	}
+	recordAuditEvent(ctx, c.provider, action, types.AuditResourcePermission, a.ID.String(), a.Name, fmt.Sprintf("app tag %s", a.AppTag))
 	return ctx.JSON(http.StatusOK, a)
 }
+
+// GetDeletedPermissions godoc
+// @Summary Get Deleted Permissions
+// @Description get soft deleted permissions that can be restored (admin only)
+// @Tags Permissions
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} []types.Permission
+// @Router /api/permissions/deleted [get]
+func (c PermissionsHandler) GetDeletedPermissions(ctx echo.Context) error {
+	if _, status, err := requireAdmin(ctx, c.provider.StorageProvider, "view deleted permissions"); err != nil {
+		return ctx.JSON(status, err.Error())
+	}
+
+	permissions, err := c.provider.StorageProvider.GetDeletedPermissions()
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, err.Error())
+	}
+	return ctx.JSON(http.StatusOK, permissions)
+}
+
+// DeletePermission godoc
+// @Summary Delete Permission
+// @Description soft delete a permission (admin only). The built-in admin permission cannot be deleted.
+// @Tags Permissions
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Permission ID"
+// @NoContent 204 {object} string
+// @Router /api/permissions/{id} [delete]
+func (c PermissionsHandler) DeletePermission(ctx echo.Context) error {
+	if _, status, err := requireAdmin(ctx, c.provider.StorageProvider, "delete permissions"); err != nil {
+		return ctx.JSON(status, err.Error())
+	}
+
+	permissionID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, fmt.Sprintf("invalid permission id: %s", err.Error()))
+	}
+
+	if permissionID.String() == types.BuiltinAdminID {
+		return ctx.JSON(http.StatusBadRequest, "the built-in admin permission cannot be deleted")
+	}
+
+	permissions, err := c.provider.StorageProvider.GetPermissionsByIDs([]uuid.UUID{permissionID})
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, err.Error())
+	}
+	if len(permissions) == 0 {
+		return ctx.JSON(http.StatusNotFound, fmt.Sprintf("unable to find permission %s", permissionID.String()))
+	}
+
+	if err := c.provider.StorageProvider.DeletePermission(permissionID); err != nil {
+		return ctx.JSON(http.StatusInternalServerError, err.Error())
+	}
+	recordAuditEvent(ctx, c.provider, types.AuditActionDelete, types.AuditResourcePermission, permissionID.String(), permissions[0].Name, "deleted permission")
+	return ctx.NoContent(http.StatusNoContent)
+}
+
+// RestorePermission godoc
+// @Summary Restore Permission
+// @Description restore a soft deleted permission (admin only)
+// @Tags Permissions
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Permission ID"
+// @Success 200 {object} types.Permission
+// @Router /api/permissions/{id}/restore [post]
+func (c PermissionsHandler) RestorePermission(ctx echo.Context) error {
+	if _, status, err := requireAdmin(ctx, c.provider.StorageProvider, "restore permissions"); err != nil {
+		return ctx.JSON(status, err.Error())
+	}
+
+	permissionID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, fmt.Sprintf("invalid permission id: %s", err.Error()))
+	}
+
+	if err := c.provider.StorageProvider.RestorePermission(permissionID); err != nil {
+		return ctx.JSON(http.StatusNotFound, err.Error())
+	}
+
+	permissions, err := c.provider.StorageProvider.GetPermissionsByIDs([]uuid.UUID{permissionID})
+	if err != nil || len(permissions) == 0 {
+		return ctx.JSON(http.StatusInternalServerError, fmt.Sprintf("unable to fetch restored permission %s", permissionID.String()))
+	}
+	recordAuditEvent(ctx, c.provider, types.AuditActionRestore, types.AuditResourcePermission, permissionID.String(), permissions[0].Name, "restored permission")
+	return ctx.JSON(http.StatusOK, permissions[0])
+}
+
+// SimulatePermissions godoc
+// @Summary Simulate Permissions
+// @Description evaluate the full authorization path of a user against the cached k8s resources of a type (admin only).
+// @Description Returns the objects the user can read or write and the permissions and groups granting the access.
+// @Tags Permissions
+// @Accept  json
+// @Produce  json
+// @Param user query string true "User Name"
+// @Param resource query string true "K8s resource type (e.g. deployments)"
+// @Param namespace query string false "Namespace"
+// @Param name query string false "Object Name"
+// @Success 200 {object} types.PermissionSimulation
+// @Router /api/permissions/simulate [get]
+func (c PermissionsHandler) SimulatePermissions(ctx echo.Context) error {
+	if _, status, err := requireAdmin(ctx, c.provider.StorageProvider, "simulate permissions"); err != nil {
+		return ctx.JSON(status, err.Error())
+	}
+
+	resource, err := getK8sResourceParam(ctx)
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	username := ctx.QueryParam("user")
+	if username == "" {
+		return ctx.JSON(http.StatusBadRequest, "user cannot be empty")
+	}
+
+	user, err := c.provider.StorageProvider.GetUser(username)
+	if err != nil {
+		return ctx.JSON(http.StatusNotFound, fmt.Sprintf("unable to find user %s: %s", username, err.Error()))
+	}
+
+	groups, err := c.provider.StorageProvider.GetGroups()
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	rd, err := getCachedK8sResources(ctx, c.provider, resource)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	globalReadOnly := c.globalReadOnly(ctx)
+	grants := resolvePermissionGrants(user, groups, globalReadOnly)
+	permissionMap := permissionMapFromGrants(grants)
+
+	simulation := types.PermissionSimulation{
+		User:           user.Name,
+		IsAdmin:        user.IsAdmin,
+		Deactivated:    user.Deactivated,
+		GlobalReadOnly: globalReadOnly,
+		Resource:       resource,
+		Grants:         grants,
+		Resources:      []types.SimulatedResourceAccess{},
+	}
+	if len(grants) == 0 {
+		return ctx.JSON(http.StatusOK, simulation)
+	}
+
+	for _, r := range rd {
+		md, ok := getResourceMetadata(r)
+		if !ok || !matchesObjectFilter(ctx, md) {
+			continue
+		}
+
+		// Cluster events are visible to every user that holds any permission
+		if resource == "clusterevents" {
+			simulation.Resources = append(simulation.Resources, types.SimulatedResourceAccess{
+				Namespace: md.namespace,
+				Name:      md.name,
+				Write:     true,
+				GrantedBy: []types.PermissionGrant{},
+			})
+			continue
+		}
+
+		write, read, tags := evaluateResourceAccess(permissionMap, md.labels)
+		if !write && !read {
+			continue
+		}
+		simulation.Resources = append(simulation.Resources, types.SimulatedResourceAccess{
+			Namespace: md.namespace,
+			Name:      md.name,
+			Write:     write,
+			GrantedBy: grantsForTags(grants, tags),
+		})
+	}
+	return ctx.JSON(http.StatusOK, simulation)
+}
+
+// GetResourceWriters godoc
+// @Summary Get Resource Writers
+// @Description list every user that can write a cached k8s object and the permissions and groups granting the access (admin only)
+// @Tags Permissions
+// @Accept  json
+// @Produce  json
+// @Param resource query string true "K8s resource type (e.g. deployments)"
+// @Param namespace query string true "Namespace"
+// @Param name query string true "Object Name"
+// @Success 200 {object} types.ResourceWriters
+// @Router /api/permissions/writers [get]
+func (c PermissionsHandler) GetResourceWriters(ctx echo.Context) error {
+	if _, status, err := requireAdmin(ctx, c.provider.StorageProvider, "query resource writers"); err != nil {
+		return ctx.JSON(status, err.Error())
+	}
+
+	resource, err := getK8sResourceParam(ctx)
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	if ctx.QueryParam("name") == "" {
+		return ctx.JSON(http.StatusBadRequest, "name cannot be empty")
+	}
+
+	rd, err := getCachedK8sResources(ctx, c.provider, resource)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	var target *k8sResourceMetadata
+	for _, r := range rd {
+		if md, ok := getResourceMetadata(r); ok && matchesObjectFilter(ctx, md) {
+			target = &md
+			break
+		}
+	}
+	if target == nil {
+		return ctx.JSON(http.StatusNotFound, fmt.Sprintf("unable to find %s %s/%s", resource, ctx.QueryParam("namespace"), ctx.QueryParam("name")))
+	}
+
+	users, err := c.provider.StorageProvider.GetUsers()
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	groups, err := c.provider.StorageProvider.GetGroups()
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	globalReadOnly := c.globalReadOnly(ctx)
+	writers := types.ResourceWriters{
+		Resource:  resource,
+		Namespace: target.namespace,
+		Name:      target.name,
+		Writers:   []types.ResourceWriter{},
+	}
+	for _, u := range users {
+		grants := resolvePermissionGrants(u, groups, globalReadOnly)
+		if len(grants) == 0 {
+			continue
+		}
+
+		writer := types.ResourceWriter{User: u.Name, Email: u.Email}
+		if resource == "clusterevents" {
+			writer.GrantedBy = []types.PermissionGrant{}
+			writers.Writers = append(writers.Writers, writer)
+			continue
+		}
+
+		if write, _, tags := evaluateResourceAccess(permissionMapFromGrants(grants), target.labels); write {
+			writer.GrantedBy = grantsForTags(grants, tags)
+			writers.Writers = append(writers.Writers, writer)
+		}
+	}
+	return ctx.JSON(http.StatusOK, writers)
+}
+
+// globalReadOnly reports whether global read only access is enabled for the request's dynamic app config
+func (c PermissionsHandler) globalReadOnly(ctx echo.Context) bool {
+	dac, _ := ctx.Get("dynamicAppConfig").(types.DynamicAppConfig)
+	return dac.Data.EnableK8sGlobalReadOnly
+}
+
+// getK8sResourceParam reads and validates the resource query param
+func getK8sResourceParam(ctx echo.Context) (string, error) {
+	resource := ctx.QueryParam("resource")
+	for _, r := range k8sResourceTypes {
+		if r == resource {
+			return resource, nil
+		}
+	}
+	return "", fmt.Errorf("invalid resource %q. Must be one of %v", resource, k8sResourceTypes)
+}
+
+// matchesObjectFilter reports whether an object matches the optional namespace and name query params
+func matchesObjectFilter(ctx echo.Context, md k8sResourceMetadata) bool {
+	if ns := ctx.QueryParam("namespace"); ns != "" && ns != md.namespace {
+		return false
+	}
+	if name := ctx.QueryParam("name"); name != "" && name != md.name {
+		return false
+	}
+	return true
+}
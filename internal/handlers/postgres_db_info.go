@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sullivtr/k8s_platform/internal/providers"
+	"github.com/sullivtr/k8s_platform/internal/types"
+)
+
+type PostgresDBInfoHandler struct {
+	provider *providers.ModuleProviders
+}
+
+// GetPostgresDBCatalog godoc
+// @Summary Get Postgres DB Info Catalog
+// @Description get postgres db info catalog
+// @Tags PostgresDBInfo
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} []types.PostgresDBInfo
+// @Router /api/infra/postgres [get]
+func (c PostgresDBInfoHandler) GetPostgresDBCatalog(ctx echo.Context) error {
+	userDetail, _, err := GetUserContext(ctx, c.provider.StorageProvider)
+	if err != nil {
+		return ctx.JSON(http.StatusForbidden, "user context unavailable")
+	}
+
+	if !userDetail.IsAdmin {
+		return ctx.JSON(http.StatusForbidden, "user must be a global admin to access this postgres database catalog")
+	}
+
+	catalog, err := c.provider.StorageProvider.GetPostgresCatalog()
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	return ctx.JSON(http.StatusOK, catalog)
+}
+
+// UpsertPostgresDBInfo godoc
+// @Summary Upsert Postgres DB Info
+// @Description upsert postgres db info. The port defaults to 5432 and the database to postgres.
+// @Tags PostgresDBInfo
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} types.PostgresDBInfo
+// @Router /api/infra/postgres [put]
+func (c PostgresDBInfoHandler) UpsertPostgresDBInfo(ctx echo.Context) error {
+	userDetail, _, err := GetUserContext(ctx, c.provider.StorageProvider)
+	if err != nil {
+		return ctx.JSON(http.StatusForbidden, "user context unavailable")
+	}
+
+	if !userDetail.IsAdmin {
+		return ctx.JSON(http.StatusForbidden, "user must be a global admin to update postgres database catalog items")
+	}
+
+	var postgresDBInfo types.PostgresDBInfo
+	err = json.NewDecoder(ctx.Request().Body).Decode(&postgresDBInfo)
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	dbInfo, err := c.provider.StorageProvider.UpsertPostgresDBInfo(postgresDBInfo)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, fmt.Sprintf("unable to upsert postgres db info: %s", err.Error()))
+	}
+
+	return ctx.JSON(http.StatusOK, dbInfo)
+}
+
+// DeletePostgresDBInfo godoc
+// @Summary Delete Postgres DB Info
+// @Description delete postgres db info
+// @Tags PostgresDBInfo
+// @Accept  json
+// @Produce  json
+// @NoContent 204 {object} string
+// @Param dbHost query string true "dbHost"
+// @Router /api/infra/postgres [delete]
+func (c PostgresDBInfoHandler) DeletePostgresDBInfo(ctx echo.Context) error {
+	userDetail, _, err := GetUserContext(ctx, c.provider.StorageProvider)
+	if err != nil {
+		return ctx.JSON(http.StatusForbidden, "user context unavailable")
+	}
+
+	if !userDetail.IsAdmin {
+		return ctx.JSON(http.StatusForbidden, "user must be a global admin to delete postgres database catalog items")
+	}
+
+	dbHost := ctx.QueryParam("dbHost")
+	if dbHost == "" {
+		return ctx.JSON(http.StatusBadRequest, "dbHost query param is required")
+	}
+
+	err = c.provider.StorageProvider.DeletePostgresDBInfo(dbHost)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, fmt.Sprintf("unable to delete postgres db info: %s", err.Error()))
+	}
+
+	return ctx.NoContent(http.StatusNoContent)
+}
+
+// GetPostgresReplicationTopology godoc
+// @Summary Get Postgres Replication Topology
+// @Description get the postgres replication topology of the latest capture. Streaming replicas and logical subscribers
+// @Description are edges from their source, with the kind of their channels and their lag in bytes and seconds. Nodes
+// @Description are positioned by the server side layout, or at the position an admin pinned them at.
+// @Tags PostgresDBInfo
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} string
+// @Router /api/infra/postgres/topology [get]
+func (c PostgresDBInfoHandler) GetPostgresReplicationTopology(ctx echo.Context) error {
+	nodes, err := c.provider.GetPinnedReplTopoNodes(types.GraphPostgresReplication, providers.PostgresReplTopoNodesCacheKey)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, fmt.Sprintf("unable to get postgres replication topology nodes: %s", err.Error()))
+	}
+
+	edges, err := c.provider.CacheProvider.Get(providers.PostgresReplTopoEdgesCacheKey)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, fmt.Sprintf("unable to get postgres replication topology edges: %s", err.Error()))
+	}
+
+	topoGraph := map[string]interface{}{
+		"nodes": nodes,
+		"edges": edges,
+	}
+
+	// The capture time is only cached once the data sink captured the topology
+	if capturedAt, err := c.provider.CacheProvider.Get(providers.PostgresReplTopoCapturedAtCacheKey); err == nil && capturedAt != nil {
+		topoGraph["capturedAt"] = capturedAt
+	}
+
+	return ctx.JSON(http.StatusOK, topoGraph)
+}
@@ -1,32 +1,91 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/sullivtr/k8s_platform/internal/providers"
+	"github.com/sullivtr/k8s_platform/internal/types"
 )
 
+// ReportFilesPath is the path reports of the local report storage are uploaded to and downloaded from. Requests are
+// authorized by the signature of the link rather than the user session.
+const ReportFilesPath = "/api/reports/files/"
+
 type ReportsHandler struct {
 	provider *providers.ModuleProviders
 }
 
 // Get Reports godoc
 // @Summary Get Reports
-// @Description Get Reports
+// @Description Get a page of the reports visible to the user, newest first. Pass the nextCursor of a page as cursor to fetch the following page.
+// @Description Users see the reports they created, and reports of the types they hold the reports_<type>_read or reports_<type>_write permission for.
 // @Tags Reports
 // @Accept  json
 // @Produce  json
-// @Success 200 {object} []types.Report
+// @Param user query string false "Filter by the user that created the report"
+// @Param type query string false "Filter by report type"
+// @Param reason query string false "Filter by reports whose reason contains this value"
+// @Param since query string false "Only reports created at or after this time (RFC 3339)"
+// @Param until query string false "Only reports created before this time (RFC 3339)"
+// @Param cursor query string false "The nextCursor of the previous page"
+// @Param limit query int false "Page size (default 100, max 1000)"
+// @Success 200 {object} types.ReportPage
+// @Failure 400 {object} string "invalid filter"
 // @Router /api/reports [get]
 func (c ReportsHandler) GetReports(ctx echo.Context) error {
-	reports, err := c.provider.AWSProvider.GetS3Reports()
+	filter := types.ReportFilter{
+		User:   ctx.QueryParam("user"),
+		Type:   ctx.QueryParam("type"),
+		Reason: ctx.QueryParam("reason"),
+		Cursor: ctx.QueryParam("cursor"),
+	}
+
+	var err error
+	if filter.Since, err = parseReportTime(ctx.QueryParam("since")); err != nil {
+		return ctx.JSON(http.StatusBadRequest, fmt.Sprintf("invalid since, %s", err.Error()))
+	}
+	if filter.Until, err = parseReportTime(ctx.QueryParam("until")); err != nil {
+		return ctx.JSON(http.StatusBadRequest, fmt.Sprintf("invalid until, %s", err.Error()))
+	}
+	if limit := ctx.QueryParam("limit"); limit != "" {
+		filter.Limit, err = strconv.Atoi(limit)
+		if err != nil || filter.Limit <= 0 {
+			return ctx.JSON(http.StatusBadRequest, "limit must be a positive integer")
+		}
+	}
+
+	access, status, err := c.reportAccess(ctx)
+	if err != nil {
+		return ctx.JSON(status, err.Error())
+	}
+	filter.Access = &access
+
+	page, err := c.provider.AWSProvider.GetReports(filter)
+	if errors.Is(err, types.ErrInvalidReportCursor) {
+		return ctx.JSON(http.StatusBadRequest, err.Error())
+	}
 	if err != nil {
 		return ctx.JSON(http.StatusInternalServerError, fmt.Sprintf("unable to fetch reports, %s", err.Error()))
 	}
 
-	return ctx.JSON(http.StatusOK, reports)
+	return ctx.JSON(http.StatusOK, page)
+}
+
+func parseReportTime(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
 }
 
 // Get Reports Download godoc
@@ -44,6 +103,10 @@ func (c ReportsHandler) GetReportDownloadURL(ctx echo.Context) error {
 		return ctx.JSON(http.StatusBadRequest, "key is cannot be empty")
 	}
 
+	if _, status, err := c.readableReport(ctx, key); err != nil {
+		return ctx.JSON(status, err.Error())
+	}
+
 	url, err := c.provider.AWSProvider.GetReportDownloadURL(key)
 	if err != nil {
 		return ctx.JSON(http.StatusInternalServerError, fmt.Sprintf("unable to fetch report download url, %s", err.Error()))
@@ -51,3 +114,202 @@ func (c ReportsHandler) GetReportDownloadURL(ctx echo.Context) error {
 
 	return ctx.JSON(http.StatusOK, url)
 }
+
+// Create Report Upload godoc
+// @Summary Create a report upload link
+// @Description Returns a link to upload a report, valid for 15 minutes. Send the report as the body of a request with the returned method and headers.
+// @Description Uploading requires the reports_<type>_write permission. The report is tagged with the user, reason and type.
+// @Tags Reports
+// @Accept  json
+// @Produce  json
+// @Param upload body types.ReportUploadRequest true "Report upload"
+// @Success 201 {object} types.ReportUpload
+// @Failure 400 {object} string "validation error"
+// @Failure 403 {object} string "forbidden"
+// @Failure 409 {object} string "a report with this name already exists"
+// @Router /api/reports/uploads [post]
+func (c ReportsHandler) CreateReportUpload(ctx echo.Context) error {
+	var req types.ReportUploadRequest
+	if err := json.NewDecoder(ctx.Request().Body).Decode(&req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, fmt.Sprintf("unable to decode report upload json body %s", err.Error()))
+	}
+
+	if valid, errMsg := req.IsValid(); !valid {
+		return ctx.JSON(http.StatusBadRequest, fmt.Sprintf("validation error: %s", errMsg))
+	}
+
+	access, status, err := c.reportAccess(ctx)
+	if err != nil {
+		return ctx.JSON(status, err.Error())
+	}
+	if !access.CanUpload(req.Type) {
+		_, write := types.ReportPermissionTags(req.Type)
+		return ctx.JSON(http.StatusForbidden, fmt.Sprintf("forbidden. Uploading %s reports requires the %s permission", req.Type, write))
+	}
+
+	_, err = c.provider.AWSProvider.GetReport(req.Name)
+	if err == nil {
+		return ctx.JSON(http.StatusConflict, fmt.Sprintf("a report named %s already exists", req.Name))
+	}
+	if !errors.Is(err, providers.ErrReportNotFound) {
+		return ctx.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	upload, err := c.provider.AWSProvider.GetReportUploadURL(req.Name, types.ReportTags{User: access.Email, Reason: req.Reason, Type: req.Type})
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, fmt.Sprintf("unable to create report upload url, %s", err.Error()))
+	}
+	return ctx.JSON(http.StatusCreated, upload)
+}
+
+// Delete Report godoc
+// @Summary Delete a report
+// @Description Deletes a report. Only the user that created the report or an admin can delete it.
+// @Tags Reports
+// @Produce  json
+// @Param key path string true "Report Name"
+// @Success 200 {object} string
+// @Failure 403 {object} string "forbidden"
+// @Failure 404 {object} string "report not found"
+// @Router /api/reports/{key} [delete]
+func (c ReportsHandler) DeleteReport(ctx echo.Context) error {
+	user, status, err := GetUserContext(ctx, c.provider.StorageProvider)
+	if err != nil {
+		return ctx.JSON(status, err.Error())
+	}
+
+	report, err := c.provider.AWSProvider.GetReport(ctx.Param("key"))
+	if errors.Is(err, providers.ErrReportNotFound) {
+		return ctx.JSON(http.StatusNotFound, err.Error())
+	}
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	if !user.IsAdmin && !report.OwnedBy(user.Email) {
+		return ctx.JSON(http.StatusForbidden, "forbidden. Only the user that created a report or an admin can delete it")
+	}
+
+	if err := c.provider.AWSProvider.DeleteReport(report.Name); err != nil {
+		return ctx.JSON(http.StatusInternalServerError, fmt.Sprintf("unable to delete report, %s", err.Error()))
+	}
+	recordAuditEvent(ctx, c.provider, types.AuditActionDelete, types.AuditResourceReport, report.Name, report.Name, fmt.Sprintf("deleted %s report created by %s", report.Type, report.User))
+	return ctx.JSON(http.StatusOK, fmt.Sprintf("deleted report %s", report.Name))
+}
+
+// reportAccess returns the identity reports are listed and uploaded for
+func (c ReportsHandler) reportAccess(ctx echo.Context) (types.ReportAccess, int, error) {
+	user, status, err := GetUserContext(ctx, c.provider.StorageProvider)
+	if err != nil {
+		return types.ReportAccess{}, status, err
+	}
+	permissions, err := getUserPermissionTags(ctx)
+	if err != nil {
+		return types.ReportAccess{}, http.StatusForbidden, fmt.Errorf("forbidden. Unable to get auth info: %s", err.Error())
+	}
+	return types.ReportAccess{Email: user.Email, Permissions: permissions}, http.StatusOK, nil
+}
+
+// readableReport fetches a report the user has read access to
+func (c ReportsHandler) readableReport(ctx echo.Context, key string) (types.Report, int, error) {
+	access, status, err := c.reportAccess(ctx)
+	if err != nil {
+		return types.Report{}, status, err
+	}
+
+	report, err := c.provider.AWSProvider.GetReport(key)
+	if errors.Is(err, providers.ErrReportNotFound) {
+		return types.Report{}, http.StatusNotFound, err
+	}
+	if err != nil {
+		return types.Report{}, http.StatusInternalServerError, err
+	}
+	if !access.CanRead(report) {
+		read, write := types.ReportPermissionTags(report.Type)
+		return types.Report{}, http.StatusForbidden, fmt.Errorf("forbidden. Downloading %s reports requires the %s or %s permission", report.Type, read, write)
+	}
+	return report, http.StatusOK, nil
+}
+
+// Download Report File godoc
+// @Summary Download a report
+// @Description Serves a report from the local report storage, for a download link returned by /api/reports/download/{key}.
+// @Description The link is authorized by its signature, so it does not require a session.
+// @Tags Reports
+// @Produce  octet-stream
+// @Param key path string true "Report Name"
+// @Param expires query int true "Link expiry (unix seconds)"
+// @Param signature query string true "Link signature"
+// @Success 200 {file} file
+// @Failure 403 {object} string "invalid or expired report link"
+// @Failure 404 {object} string "report not found"
+// @Router /api/reports/files/{key} [get]
+func (c ReportsHandler) DownloadReportFile(ctx echo.Context) error {
+	expires, err := strconv.ParseInt(ctx.QueryParam("expires"), 10, 64)
+	if err != nil {
+		return ctx.JSON(http.StatusForbidden, providers.ErrReportLinkInvalid.Error())
+	}
+
+	file, err := c.provider.AWSProvider.OpenSignedReport(ctx.Param("key"), expires, ctx.QueryParam("signature"))
+	if errors.Is(err, providers.ErrReportLinkInvalid) {
+		return ctx.JSON(http.StatusForbidden, err.Error())
+	}
+	if errors.Is(err, providers.ErrReportNotFound) {
+		return ctx.JSON(http.StatusNotFound, err.Error())
+	}
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, fmt.Sprintf("unable to download report, %s", err.Error()))
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, fmt.Sprintf("unable to download report, %s", err.Error()))
+	}
+
+	ctx.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", info.Name()))
+	http.ServeContent(ctx.Response(), ctx.Request(), info.Name(), info.ModTime(), file)
+	return nil
+}
+
+// Upload Report File godoc
+// @Summary Upload a report
+// @Description Stores a report in the local report storage, for an upload link returned by /api/reports/uploads.
+// @Description The link is authorized by its signature, so it does not require a session.
+// @Tags Reports
+// @Accept  octet-stream
+// @Produce  json
+// @Param key path string true "Report Name"
+// @Param expires query int true "Link expiry (unix seconds)"
+// @Param signature query string true "Link signature"
+// @Success 201 {object} string
+// @Failure 403 {object} string "invalid or expired report link"
+// @Router /api/reports/files/{key} [put]
+func (c ReportsHandler) UploadReportFile(ctx echo.Context) error {
+	expires, err := strconv.ParseInt(ctx.QueryParam("expires"), 10, 64)
+	if err != nil {
+		return ctx.JSON(http.StatusForbidden, providers.ErrReportLinkInvalid.Error())
+	}
+
+	tags := types.ReportTags{User: ctx.QueryParam("user"), Reason: ctx.QueryParam("reason"), Type: ctx.QueryParam("type")}
+	// This route is only protected by the link signature, so the size of the body is bounded
+	maxBytes := int64(c.provider.Config.ReportsMaxUploadSizeMB) << 20
+	body := http.MaxBytesReader(ctx.Response(), ctx.Request().Body, maxBytes)
+	err = c.provider.AWSProvider.ReceiveSignedReport(ctx.Param("key"), expires, ctx.QueryParam("signature"), tags, body)
+	if errors.Is(err, providers.ErrReportLinkInvalid) {
+		return ctx.JSON(http.StatusForbidden, err.Error())
+	}
+	if errors.Is(err, providers.ErrReportExists) {
+		return ctx.JSON(http.StatusConflict, err.Error())
+	}
+	if errors.Is(err, providers.ErrReportTooLarge) {
+		return ctx.JSON(http.StatusRequestEntityTooLarge, err.Error())
+	}
+	if errors.Is(err, providers.ErrReportNotFound) {
+		return ctx.JSON(http.StatusNotFound, err.Error())
+	}
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, fmt.Sprintf("unable to upload report, %s", err.Error()))
+	}
+	return ctx.JSON(http.StatusCreated, fmt.Sprintf("uploaded report %s", ctx.Param("key")))
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/sullivtr/k8s_platform/internal/config"
+	"github.com/sullivtr/k8s_platform/internal/modules"
+	"github.com/sullivtr/k8s_platform/internal/providers"
+	"github.com/sullivtr/k8s_platform/internal/types"
+)
+
+func TestUploadReportFile(t *testing.T) {
+	sdk, err := modules.NewLocalReportSDK(t.TempDir(), "http://localhost:3000/", "signing-key", 90)
+	require.NoError(t, err)
+	handler := ReportsHandler{provider: &providers.ModuleProviders{
+		Config:      &config.Config{ReportsMaxUploadSizeMB: 1},
+		AWSProvider: &providers.AWSProvider{Session: providers.AWSSession{SDK: sdk}},
+	}}
+
+	upload := func(name, body string) int {
+		link, err := sdk.GetReportUploadURL(name, types.ReportTags{User: "jane.doe@gmail.com", Type: "heapdump"})
+		require.NoError(t, err)
+		u, err := url.Parse(link.URL)
+		require.NoError(t, err)
+
+		req := httptest.NewRequest(http.MethodPut, u.RequestURI(), strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		ctx := echo.New().NewContext(req, rec)
+		ctx.SetParamNames("key")
+		ctx.SetParamValues(name)
+		require.NoError(t, handler.UploadReportFile(ctx))
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusCreated, upload("heap.hprof", "heap"))
+	assert.Equal(t, http.StatusConflict, upload("heap.hprof", "overwritten"))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, upload("large.hprof", strings.Repeat("x", 1<<20+1)))
+
+	_, err = sdk.GetReport(context.Background(), "large.hprof")
+	assert.ErrorIs(t, err, modules.ErrReportNotFound)
+}
@@ -48,6 +48,7 @@ func registerK8sResources(e *echo.Echo, prv *providers.ModuleProviders) error {
 	e.GET("/api/k8s/clusterevents", k8sHandler.GetClusterEvents)
 	e.POST("/api/k8s/rolloutrestart", k8sHandler.RolloutRestart)
 	e.POST("/api/k8s/exec", k8sHandler.RunPodExecPlugin)
+	e.GET(ExecJobsPath+":id", k8sHandler.GetExecJob)
 	return nil
 }
 
@@ -55,28 +56,83 @@ func registerAdministrationRoutes(e *echo.Echo, prv *providers.ModuleProviders)
 	usersHandler := &UsersHandler{provider: prv}
 	e.GET("/api/users", usersHandler.GetUsers)
 	e.PUT("/api/users/theme/:name", usersHandler.UpdateUserThemePreference)
+	e.GET("/api/users/deleted", usersHandler.GetDeletedUsers)
+	e.DELETE("/api/users/:id", usersHandler.DeleteUser)
+	e.POST("/api/users/:id/restore", usersHandler.RestoreUser)
+	e.POST("/api/users/:id/deactivate", usersHandler.DeactivateUser)
+	e.POST("/api/users/:id/activate", usersHandler.ActivateUser)
+	e.PUT("/api/users/:id/admin", usersHandler.UpdateUserAdmin)
+	e.GET("/api/users/:id/sessions", usersHandler.GetUserSessions)
+	e.DELETE("/api/users/:id/sessions", usersHandler.RevokeUserSessions)
+	e.DELETE("/api/users/:id/sessions/:sessionId", usersHandler.RevokeUserSession)
 
 	groupsHanlder := &GroupsHandler{provider: prv}
 	e.GET("/api/groups", groupsHanlder.GetGroups)
 	e.PUT("/api/groups", groupsHanlder.UpsertGroup)
+	e.GET("/api/groups/deleted", groupsHanlder.GetDeletedGroups)
+	e.DELETE("/api/groups/:id", groupsHanlder.DeleteGroup)
+	e.POST("/api/groups/:id/restore", groupsHanlder.RestoreGroup)
 
 	permissionsHandler := &PermissionsHandler{provider: prv}
 	e.GET("/api/permissions", permissionsHandler.GetPermissions)
 	e.PUT("/api/permissions", permissionsHandler.UpsertPermission)
+	e.GET("/api/permissions/deleted", permissionsHandler.GetDeletedPermissions)
+	e.GET("/api/permissions/simulate", permissionsHandler.SimulatePermissions)
+	e.GET("/api/permissions/writers", permissionsHandler.GetResourceWriters)
+	e.DELETE("/api/permissions/:id", permissionsHandler.DeletePermission)
+	e.POST("/api/permissions/:id/restore", permissionsHandler.RestorePermission)
+
+	apiTokensHandler := &APITokensHandler{provider: prv}
+	e.GET("/api/tokens", apiTokensHandler.GetAPITokens)
+	e.POST("/api/tokens", apiTokensHandler.CreateAPIToken)
+	e.DELETE("/api/tokens/:id", apiTokensHandler.RevokeAPIToken)
+	e.GET("/api/serviceaccounts", apiTokensHandler.GetServiceAccounts)
+	e.POST("/api/serviceaccounts", apiTokensHandler.CreateServiceAccount)
+	e.POST("/api/serviceaccounts/:id/tokens", apiTokensHandler.CreateServiceAccountToken)
 
 	reportsHandler := &ReportsHandler{provider: prv}
 	e.GET("/api/reports", reportsHandler.GetReports)
 	e.GET("/api/reports/download/:key", reportsHandler.GetReportDownloadURL)
+	e.POST("/api/reports/uploads", reportsHandler.CreateReportUpload)
+	e.DELETE("/api/reports/:key", reportsHandler.DeleteReport)
+	e.GET(ReportFilesPath+":key", reportsHandler.DownloadReportFile)
+	e.PUT(ReportFilesPath+":key", reportsHandler.UploadReportFile)
 
 	mySQLDBInfoHandler := &MySQLDBInfoHandler{provider: prv}
 	e.GET("/api/infra/mysql", mySQLDBInfoHandler.GetMySQLDBCatalog)
 	e.PUT("/api/infra/mysql", mySQLDBInfoHandler.UpsertMySQLDBInfo)
 	e.DELETE("/api/infra/mysql", mySQLDBInfoHandler.DeleteMySQLDBInfo)
 	e.GET("/api/infra/mysql/topology", mySQLDBInfoHandler.GetReplicationTopology)
+	e.GET("/api/infra/mysql/topology/history", mySQLDBInfoHandler.GetReplicationTopologyHistory)
+	e.GET("/api/infra/mysql/discovery", mySQLDBInfoHandler.GetMySQLDiscoveryProposals)
+	e.POST("/api/infra/mysql/discovery/:host/accept", mySQLDBInfoHandler.AcceptMySQLDiscoveryProposal)
+	e.POST("/api/infra/mysql/discovery/:host/ignore", mySQLDBInfoHandler.IgnoreMySQLDiscoveryProposal)
+	e.POST("/api/infra/mysql/discovery/:host/restore", mySQLDBInfoHandler.RestoreMySQLDiscoveryProposal)
+	e.GET("/api/infra/mysql/query", mySQLDBInfoHandler.GetMySQLQueryPlugins)
+	e.POST("/api/infra/mysql/:host/query/:name", mySQLDBInfoHandler.RunMySQLQueryPlugin)
+
+	postgresDBInfoHandler := &PostgresDBInfoHandler{provider: prv}
+	e.GET("/api/infra/postgres", postgresDBInfoHandler.GetPostgresDBCatalog)
+	e.PUT("/api/infra/postgres", postgresDBInfoHandler.UpsertPostgresDBInfo)
+	e.DELETE("/api/infra/postgres", postgresDBInfoHandler.DeletePostgresDBInfo)
+	e.GET("/api/infra/postgres/topology", postgresDBInfoHandler.GetPostgresReplicationTopology)
+
+	graphPositionsHandler := &GraphPositionsHandler{provider: prv}
+	e.GET("/api/graphs/:graph/positions", graphPositionsHandler.GetGraphNodePositions)
+	e.PUT("/api/graphs/:graph/positions", graphPositionsHandler.PinGraphNodePosition)
+	e.DELETE("/api/graphs/:graph/positions", graphPositionsHandler.UnpinGraphNodePosition)
+
+	auditHandler := &AuditHandler{provider: prv}
+	e.GET("/api/audit", auditHandler.GetAuditEvents)
 
 	dynamicAppConfigHandler := &DynamicAppConfigHandler{provider: prv}
 	e.GET("/api/appconfig", dynamicAppConfigHandler.GetDynamicAppConfig)
 	e.PUT("/api/appconfig", dynamicAppConfigHandler.UpdateDynamicAppConfig)
+	e.GET("/api/appconfig/schema", dynamicAppConfigHandler.GetDynamicAppConfigSchema)
+	e.GET("/api/appconfig/versions", dynamicAppConfigHandler.GetDynamicAppConfigVersions)
+	e.GET("/api/appconfig/versions/:version", dynamicAppConfigHandler.GetDynamicAppConfigVersion)
+	e.GET("/api/appconfig/diff", dynamicAppConfigHandler.DiffDynamicAppConfigVersions)
+	e.POST("/api/appconfig/rollback", dynamicAppConfigHandler.RollbackDynamicAppConfig)
 
 	return nil
 }
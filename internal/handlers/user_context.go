@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/labstack/echo-contrib/session"
 	"github.com/labstack/echo/v4"
 	"github.com/rs/zerolog/log"
 	"github.com/sullivtr/k8s_platform/internal/providers"
@@ -17,8 +18,8 @@ import (
 // If the user does not exist, the user is automatically created
 // Otherwise, the user's lastUsed timestamp is updated
 func GetUserContextUpsert(ctx echo.Context, storageProvider *providers.StorageProvider) types.User {
-	username := ctx.Get("username").(string)
-	userEmail := ctx.Get("email").(string)
+	username, _ := ctx.Get("username").(string)
+	userEmail, _ := ctx.Get("email").(string)
 
 	user := types.User{}
 	var err error
@@ -47,15 +48,48 @@ func GetUserContextUpsert(ctx echo.Context, storageProvider *providers.StoragePr
 }
 
 // GetUserContext will fetch the user indicated by the request context
+// Requests the identity middleware did not identify are unauthenticated.
 func GetUserContext(ctx echo.Context, storageProvider *providers.StorageProvider) (types.User, int, error) {
-	username := ctx.Get("username").(string)
-	userEmail := ctx.Get("email").(string)
+	username, _ := ctx.Get("username").(string)
+	userEmail, _ := ctx.Get("email").(string)
 
 	if username != "" && userEmail != "" {
 		user, err := storageProvider.GetUser(username)
 		return user, http.StatusOK, err
 	}
-	return types.User{}, http.StatusForbidden, errors.New("unable to read user context details from request (unauthenticated)")
+	return types.User{}, http.StatusUnauthorized, errors.New("unable to read user context details from request (unauthenticated)")
+}
+
+// requireAdmin fetches the user indicated by the request context and verifies that they are an admin
+func requireAdmin(ctx echo.Context, storageProvider *providers.StorageProvider, action string) (types.User, int, error) {
+	user, status, err := GetUserContext(ctx, storageProvider)
+	if err != nil {
+		return types.User{}, status, err
+	}
+
+	if !user.IsAdmin {
+		return types.User{}, http.StatusForbidden, fmt.Errorf("user must be an admin to %s", action)
+	}
+	return user, http.StatusOK, nil
+}
+
+// getUserPermissionTags returns the permission tags resolved for the request by the user access context middleware.
+// Api token requests carry their permission tags on the request context, browser sessions carry them in the session store.
+func getUserPermissionTags(ctx echo.Context) ([]string, error) {
+	if permissions, ok := ctx.Get("permissions").([]string); ok {
+		return permissions, nil
+	}
+
+	sess, err := session.Get("user-permissions", ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	userPermissions := []string{}
+	if sess.Values["permissions"] != nil {
+		userPermissions = sess.Values["permissions"].([]string)
+	}
+	return userPermissions, nil
 }
 
 func GetUserPermissions(ctx echo.Context, storageProvider *providers.StorageProvider, user *types.User, enableGlobalReadOnly bool) ([]types.Permission, error) {
@@ -0,0 +1,21 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetUserContextUnauthenticated(t *testing.T) {
+	ctx := echo.New().NewContext(httptest.NewRequest(http.MethodGet, ExecJobsPath+"job-1", nil), httptest.NewRecorder())
+
+	// Requests the identity middleware skipped carry no user
+	_, status, err := GetUserContext(ctx, nil)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusUnauthorized, status)
+
+	assert.Nil(t, GetUserContextUpsert(ctx, nil).ID)
+}
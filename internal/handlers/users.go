@@ -1,11 +1,16 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 
+	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
 	"github.com/sullivtr/k8s_platform/internal/providers"
+	"github.com/sullivtr/k8s_platform/internal/types"
 )
 
 type UsersHandler struct {
@@ -66,3 +71,296 @@ func (c UsersHandler) UpdateUserThemePreference(ctx echo.Context) error {
 
 	return ctx.JSON(http.StatusOK, user)
 }
+
+// GetUserSessions godoc
+// @Summary Get User Sessions
+// @Description get the active login sessions of a user (admin only)
+// @Tags Users
+// @Accept  json
+// @Produce  json
+// @Param id path string true "User ID"
+// @Success 200 {object} []types.UserSession
+// @Router /api/users/{id}/sessions [get]
+func (c UsersHandler) GetUserSessions(ctx echo.Context) error {
+	user, status, err := c.getAdminTargetUser(ctx, "manage user sessions")
+	if err != nil {
+		return ctx.JSON(status, err.Error())
+	}
+
+	userSessions, err := c.provider.CacheProvider.GetUserSessions(user.Email)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, err.Error())
+	}
+	return ctx.JSON(http.StatusOK, userSessions)
+}
+
+// RevokeUserSessions godoc
+// @Summary Revoke User Sessions
+// @Description revoke every active login session of a user (admin only). The user is logged out immediately.
+// @Tags Users
+// @Accept  json
+// @Produce  json
+// @Param id path string true "User ID"
+// @NoContent 204 {object} string
+// @Router /api/users/{id}/sessions [delete]
+func (c UsersHandler) RevokeUserSessions(ctx echo.Context) error {
+	user, status, err := c.getAdminTargetUser(ctx, "manage user sessions")
+	if err != nil {
+		return ctx.JSON(status, err.Error())
+	}
+
+	if err := c.provider.CacheProvider.RevokeUserSessions(user.Email); err != nil {
+		return ctx.JSON(http.StatusInternalServerError, err.Error())
+	}
+	recordAuditEvent(ctx, c.provider, types.AuditActionRevoke, types.AuditResourceUserSession, user.ID.String(), user.Name, "revoked all login sessions")
+	return ctx.NoContent(http.StatusNoContent)
+}
+
+// RevokeUserSession godoc
+// @Summary Revoke User Session
+// @Description revoke a single login session of a user (admin only)
+// @Tags Users
+// @Accept  json
+// @Produce  json
+// @Param id path string true "User ID"
+// @Param sessionId path string true "Session ID"
+// @NoContent 204 {object} string
+// @Router /api/users/{id}/sessions/{sessionId} [delete]
+func (c UsersHandler) RevokeUserSession(ctx echo.Context) error {
+	user, status, err := c.getAdminTargetUser(ctx, "manage user sessions")
+	if err != nil {
+		return ctx.JSON(status, err.Error())
+	}
+
+	if err := c.provider.CacheProvider.RevokeUserSession(user.Email, ctx.Param("sessionId")); err != nil {
+		return ctx.JSON(http.StatusNotFound, err.Error())
+	}
+	recordAuditEvent(ctx, c.provider, types.AuditActionRevoke, types.AuditResourceUserSession, user.ID.String(), user.Name, fmt.Sprintf("revoked login session %s", ctx.Param("sessionId")))
+	return ctx.NoContent(http.StatusNoContent)
+}
+
+// GetDeletedUsers godoc
+// @Summary Get Deleted Users
+// @Description get soft deleted users that can be restored (admin only)
+// @Tags Users
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} []types.User
+// @Router /api/users/deleted [get]
+func (c UsersHandler) GetDeletedUsers(ctx echo.Context) error {
+	if _, status, err := requireAdmin(ctx, c.provider.StorageProvider, "view deleted users"); err != nil {
+		return ctx.JSON(status, err.Error())
+	}
+
+	users, err := c.provider.StorageProvider.GetDeletedUsers()
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, err.Error())
+	}
+	return ctx.JSON(http.StatusOK, users)
+}
+
+// DeleteUser godoc
+// @Summary Delete User
+// @Description soft delete a user and revoke their login sessions (admin only). The last admin cannot be deleted.
+// @Tags Users
+// @Accept  json
+// @Produce  json
+// @Param id path string true "User ID"
+// @NoContent 204 {object} string
+// @Router /api/users/{id} [delete]
+func (c UsersHandler) DeleteUser(ctx echo.Context) error {
+	user, status, err := c.getAdminTargetUser(ctx, "delete users")
+	if err != nil {
+		return ctx.JSON(status, err.Error())
+	}
+
+	if status, err := c.checkAdminRemoval(ctx, user); err != nil {
+		return ctx.JSON(status, err.Error())
+	}
+
+	if err := c.provider.StorageProvider.DeleteUser(*user.ID); err != nil {
+		return ctx.JSON(http.StatusInternalServerError, err.Error())
+	}
+	c.revokeSessions(user)
+	recordAuditEvent(ctx, c.provider, types.AuditActionDelete, types.AuditResourceUser, user.ID.String(), user.Name, "deleted user")
+	return ctx.NoContent(http.StatusNoContent)
+}
+
+// RestoreUser godoc
+// @Summary Restore User
+// @Description restore a soft deleted user (admin only)
+// @Tags Users
+// @Accept  json
+// @Produce  json
+// @Param id path string true "User ID"
+// @Success 200 {object} types.User
+// @Router /api/users/{id}/restore [post]
+func (c UsersHandler) RestoreUser(ctx echo.Context) error {
+	if _, status, err := requireAdmin(ctx, c.provider.StorageProvider, "restore users"); err != nil {
+		return ctx.JSON(status, err.Error())
+	}
+
+	userID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, fmt.Sprintf("invalid user id: %s", err.Error()))
+	}
+
+	if err := c.provider.StorageProvider.RestoreUser(userID); err != nil {
+		return ctx.JSON(http.StatusNotFound, err.Error())
+	}
+
+	user, err := c.provider.StorageProvider.GetUserByID(userID)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, err.Error())
+	}
+	recordAuditEvent(ctx, c.provider, types.AuditActionRestore, types.AuditResourceUser, user.ID.String(), user.Name, "restored user")
+	return ctx.JSON(http.StatusOK, user)
+}
+
+// DeactivateUser godoc
+// @Summary Deactivate User
+// @Description deactivate a user (admin only). Their login sessions are revoked and they can no longer sign in or use their api tokens.
+// @Tags Users
+// @Accept  json
+// @Produce  json
+// @Param id path string true "User ID"
+// @Success 200 {object} types.User
+// @Router /api/users/{id}/deactivate [post]
+func (c UsersHandler) DeactivateUser(ctx echo.Context) error {
+	user, status, err := c.getAdminTargetUser(ctx, "deactivate users")
+	if err != nil {
+		return ctx.JSON(status, err.Error())
+	}
+
+	if status, err := c.checkAdminRemoval(ctx, user); err != nil {
+		return ctx.JSON(status, err.Error())
+	}
+
+	user.Deactivated = true
+	user, err = c.provider.StorageProvider.UpsertUser(user)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, err.Error())
+	}
+	c.revokeSessions(user)
+	recordAuditEvent(ctx, c.provider, types.AuditActionDeactivate, types.AuditResourceUser, user.ID.String(), user.Name, "deactivated user and revoked their login sessions")
+	return ctx.JSON(http.StatusOK, user)
+}
+
+// ActivateUser godoc
+// @Summary Activate User
+// @Description re-activate a deactivated user (admin only)
+// @Tags Users
+// @Accept  json
+// @Produce  json
+// @Param id path string true "User ID"
+// @Success 200 {object} types.User
+// @Router /api/users/{id}/activate [post]
+func (c UsersHandler) ActivateUser(ctx echo.Context) error {
+	user, status, err := c.getAdminTargetUser(ctx, "activate users")
+	if err != nil {
+		return ctx.JSON(status, err.Error())
+	}
+
+	user.Deactivated = false
+	user, err = c.provider.StorageProvider.UpsertUser(user)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, err.Error())
+	}
+	recordAuditEvent(ctx, c.provider, types.AuditActionActivate, types.AuditResourceUser, user.ID.String(), user.Name, "activated user")
+	return ctx.JSON(http.StatusOK, user)
+}
+
+// UpdateUserAdmin godoc
+// @Summary Update User Admin
+// @Description grant or revoke admin access for a user (admin only). Admin access cannot be revoked from the last admin.
+// @Tags Users
+// @Accept  json
+// @Produce  json
+// @Param id path string true "User ID"
+// @Param body body types.UserAdminRequest true "Admin access"
+// @Success 200 {object} types.User
+// @Router /api/users/{id}/admin [put]
+func (c UsersHandler) UpdateUserAdmin(ctx echo.Context) error {
+	user, status, err := c.getAdminTargetUser(ctx, "manage admin access")
+	if err != nil {
+		return ctx.JSON(status, err.Error())
+	}
+
+	var req types.UserAdminRequest
+	if err := json.NewDecoder(ctx.Request().Body).Decode(&req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, fmt.Sprintf("unable to decode user admin json body %s", err.Error()))
+	}
+
+	if req.IsAdmin == user.IsAdmin {
+		return ctx.JSON(http.StatusOK, user)
+	}
+
+	action := types.AuditActionGrantAdmin
+	if req.IsAdmin {
+		if user.IsServiceAccount || user.Deactivated {
+			return ctx.JSON(http.StatusBadRequest, fmt.Sprintf("%s cannot be granted admin access", user.Name))
+		}
+	} else {
+		action = types.AuditActionRevokeAdmin
+		if status, err := c.checkLastAdmin(user); err != nil {
+			return ctx.JSON(status, err.Error())
+		}
+	}
+
+	user.IsAdmin = req.IsAdmin
+	user, err = c.provider.StorageProvider.UpsertUser(user)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, err.Error())
+	}
+	recordAuditEvent(ctx, c.provider, action, types.AuditResourceUser, user.ID.String(), user.Name, fmt.Sprintf("set admin access to %t", req.IsAdmin))
+	return ctx.JSON(http.StatusOK, user)
+}
+
+// checkAdminRemoval prevents an admin from removing themselves and ensures the last active admin is never removed
+func (c UsersHandler) checkAdminRemoval(ctx echo.Context, user types.User) (int, error) {
+	if currentUser, ok := ctx.Get("username").(string); ok && currentUser == user.Name {
+		return http.StatusBadRequest, errors.New("you cannot remove your own user")
+	}
+	return c.checkLastAdmin(user)
+}
+
+// checkLastAdmin ensures that the last active admin does not lose admin access
+func (c UsersHandler) checkLastAdmin(user types.User) (int, error) {
+	if !user.IsAdmin || user.Deactivated {
+		return http.StatusOK, nil
+	}
+
+	admins, err := c.provider.StorageProvider.CountAdmins()
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	if admins <= 1 {
+		return http.StatusConflict, fmt.Errorf("%s is the last admin", user.Name)
+	}
+	return http.StatusOK, nil
+}
+
+// revokeSessions revokes every login session of a user that has been removed or deactivated
+func (c UsersHandler) revokeSessions(user types.User) {
+	if err := c.provider.CacheProvider.RevokeUserSessions(user.Email); err != nil {
+		log.Warn().Msgf("unable to revoke login sessions of user %s: %s", user.Name, err.Error())
+	}
+}
+
+// getAdminTargetUser verifies the current user is an admin and returns the user referenced by the id path param
+func (c UsersHandler) getAdminTargetUser(ctx echo.Context, action string) (types.User, int, error) {
+	if _, status, err := requireAdmin(ctx, c.provider.StorageProvider, action); err != nil {
+		return types.User{}, status, err
+	}
+
+	userID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		return types.User{}, http.StatusBadRequest, fmt.Errorf("invalid user id: %s", err.Error())
+	}
+
+	user, err := c.provider.StorageProvider.GetUserByID(userID)
+	if err != nil {
+		return types.User{}, http.StatusNotFound, fmt.Errorf("unable to find user: %s", err.Error())
+	}
+	return user, http.StatusOK, nil
+}
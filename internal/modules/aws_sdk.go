@@ -1,40 +1,62 @@
 package modules
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	"net/url"
 	"sort"
 	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 	"github.com/sullivtr/k8s_platform/internal/types"
 )
 
 type AWSSDK struct {
 	Session       *session.Session
 	Region        string
-	S3Client      *s3.S3
+	S3Client      s3iface.S3API
 	ReportsBucket string
+	tagIndex      *reportTagIndex
+	lifecycle     *reportLifecycle
 }
 
-func NewAWSSDK(session *session.Session, region string, reportsBucket string) *AWSSDK {
+// NewAWSSDK creates the s3 report storage backend. When endpoint is set, the client is pointed at an s3 compatible service
+// such as MinIO instead of AWS. Most of these services require path style addressing.
+func NewAWSSDK(session *session.Session, region string, reportsBucket string, endpoint string, forcePathStyle bool) *AWSSDK {
+	cfg := aws.NewConfig().WithRegion(region).WithS3ForcePathStyle(forcePathStyle)
+	if endpoint != "" {
+		cfg = cfg.WithEndpoint(endpoint)
+	}
+
 	return &AWSSDK{
 		Session:       session,
 		Region:        region,
-		S3Client:      s3.New(session, aws.NewConfig().WithRegion(region)),
+		S3Client:      s3.New(session, cfg),
 		ReportsBucket: reportsBucket,
+		tagIndex:      newReportTagIndex(),
+		lifecycle:     &reportLifecycle{},
 	}
 }
 
+// GetReports lists every report of the bucket. Tags are read from the tag index, so only objects that were not indexed
+// yet are tagged per request.
 func (sdk *AWSSDK) GetReports() ([]types.Report, error) {
-	bucketObjs, err := sdk.S3Client.ListObjects(&s3.ListObjectsInput{Bucket: &sdk.ReportsBucket})
+	objects, err := sdk.listObjects(context.Background())
 	if err != nil {
 		return nil, fmt.Errorf("unable to fetch s3 reports data : %v", err)
 	}
+	sdk.ensureLifecycle(context.Background())
 
-	reports, err := sdk.toReportContract(bucketObjs.Contents)
+	reports, err := sdk.toReportContract(objects)
 	if err != nil {
 		return nil, fmt.Errorf("unable to process s3 reports data : %v", err)
 	}
@@ -46,6 +68,29 @@ func (sdk *AWSSDK) GetReports() ([]types.Report, error) {
 	return reports, nil
 }
 
+// GetReport fetches a single report. ErrReportNotFound is returned when the object does not exist.
+func (sdk *AWSSDK) GetReport(ctx context.Context, reportName string) (types.Report, error) {
+	head, err := sdk.S3Client.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(sdk.ReportsBucket),
+		Key:    aws.String(reportName),
+	})
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) && (awsErr.Code() == "NotFound" || awsErr.Code() == s3.ErrCodeNoSuchKey) {
+		return types.Report{}, ErrReportNotFound
+	}
+	if err != nil {
+		return types.Report{}, fmt.Errorf("unable to fetch report %s : %v", reportName, err)
+	}
+	sdk.ensureLifecycle(ctx)
+
+	return sdk.toReport(ctx, &s3.Object{
+		Key:          aws.String(reportName),
+		ETag:         head.ETag,
+		Size:         head.ContentLength,
+		LastModified: head.LastModified,
+	})
+}
+
 func (sdk *AWSSDK) GetReportDownloadURL(reportName string) (string, error) {
 	req, _ := sdk.S3Client.GetObjectRequest(&s3.GetObjectInput{
 		Bucket: aws.String(sdk.ReportsBucket),
@@ -59,57 +104,144 @@ func (sdk *AWSSDK) GetReportDownloadURL(reportName string) (string, error) {
 	return urlStr, nil
 }
 
+// GetReportUploadURL returns a presigned PUT link to upload a report. The tags are part of the signature, so the uploader
+// cannot change them.
+func (sdk *AWSSDK) GetReportUploadURL(reportName string, tags types.ReportTags) (types.ReportUpload, error) {
+	req, _ := sdk.S3Client.PutObjectRequest(&s3.PutObjectInput{
+		Bucket:  aws.String(sdk.ReportsBucket),
+		Key:     aws.String(reportName),
+		Tagging: aws.String(reportTagging(tags)),
+	})
+	urlStr, signedHeaders, err := req.PresignRequest(reportUploadURLTTL)
+	if err != nil {
+		return types.ReportUpload{}, fmt.Errorf("unable to generate presigned upload url for %s : %v", reportName, err)
+	}
+
+	headers := map[string]string{}
+	for key, values := range signedHeaders {
+		// Browsers set the host header themselves
+		if len(values) > 0 && !strings.EqualFold(key, "Host") {
+			headers[key] = values[0]
+		}
+	}
+	return types.ReportUpload{
+		Name:      reportName,
+		URL:       urlStr,
+		Method:    http.MethodPut,
+		Headers:   headers,
+		ExpiresAt: time.Now().Add(reportUploadURLTTL),
+	}, nil
+}
+
+// DeleteReport deletes a report from the bucket
+func (sdk *AWSSDK) DeleteReport(ctx context.Context, reportName string) error {
+	_, err := sdk.S3Client.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(sdk.ReportsBucket),
+		Key:    aws.String(reportName),
+	})
+	if err != nil {
+		return fmt.Errorf("unable to delete report %s : %v", reportName, err)
+	}
+	sdk.tagIndex.delete(reportName)
+	return nil
+}
+
+// Refresh re-indexes the tags of the bucket objects and reloads the bucket lifecycle configuration
+func (sdk *AWSSDK) Refresh(ctx context.Context) error {
+	sdk.refreshLifecycle(ctx)
+	return sdk.RefreshTagIndex(ctx)
+}
+
+// UploadReport streams a report to the reports bucket, tagged with the user that created it, the reason and the report type.
+// Large reports are uploaded in parts, so the size of the report does not need to be known up front.
+func (sdk *AWSSDK) UploadReport(ctx context.Context, reportName string, body io.Reader, tags types.ReportTags) error {
+	tags = sanitizeTags(tags)
+	uploader := s3manager.NewUploaderWithClient(sdk.S3Client)
+	output, err := uploader.UploadWithContext(ctx, &s3manager.UploadInput{
+		Bucket:  aws.String(sdk.ReportsBucket),
+		Key:     aws.String(reportName),
+		Body:    body,
+		Tagging: aws.String(reportTagging(tags)),
+	})
+	if err != nil {
+		return fmt.Errorf("unable to upload report %s : %v", reportName, err)
+	}
+	if output.ETag != nil {
+		sdk.tagIndex.set(reportName, *output.ETag, reportTagSet(tags))
+	}
+	return nil
+}
+
+// reportTagging encodes the tags of a report as the url query s3 expects in the x-amz-tagging header
+func reportTagging(tags types.ReportTags) string {
+	tagging := url.Values{}
+	for k, v := range reportTagSet(tags) {
+		tagging.Set(k, v)
+	}
+	return tagging.Encode()
+}
+
+// reportTagSet returns the object tag set a report is stored with
+func reportTagSet(tags types.ReportTags) map[string]string {
+	tags = sanitizeTags(tags)
+	return map[string]string{"User": tags.User, "Reason": tags.Reason, "Type": tags.Type}
+}
+
+func sanitizeTags(tags types.ReportTags) types.ReportTags {
+	return types.ReportTags{User: sanitizeTagValue(tags.User), Reason: sanitizeTagValue(tags.Reason), Type: sanitizeTagValue(tags.Type)}
+}
+
+// sanitizeTagValue replaces the characters s3 does not allow in tag values, and truncates values to the maximum tag length
+func sanitizeTagValue(value string) string {
+	sanitized := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		case strings.ContainsRune(" +-=._:/@", r):
+			return r
+		}
+		return '_'
+	}, value)
+	if len(sanitized) > 256 {
+		sanitized = sanitized[:256]
+	}
+	return sanitized
+}
+
 // toReportContract transforms the list of s3.Objects into the user friendly Report model
 func (sdk *AWSSDK) toReportContract(objects []*s3.Object) ([]types.Report, error) {
-	dumpFileObjects := make([]types.Report, len(objects))
+	dumpFileObjects := make([]types.Report, 0, len(objects))
 	for _, obj := range objects {
 		if obj.Key != nil && *obj.Key != "" {
-			expiresDate := obj.LastModified.Add(time.Hour * 24 * 90) // Objects expire in 90 days from create date
-			var objSize int64
-			var sizeUnits string
-
-			if *obj.Size > 1000000 {
-				objSize = *obj.Size / 1000000
-				sizeUnits = "MB"
-			} else {
-				objSize = *obj.Size / 1000
-				sizeUnits = "KB"
-			}
-
-			objTagOutput, err := sdk.S3Client.GetObjectTagging(&s3.GetObjectTaggingInput{
-				Bucket: aws.String(sdk.ReportsBucket),
-				Key:    obj.Key,
-			})
+			report, err := sdk.toReport(context.Background(), obj)
 			if err != nil {
-				return dumpFileObjects, fmt.Errorf("unable to fetch object tags for %s, error: %v", *obj.Key, err)
+				return dumpFileObjects, err
 			}
-
-			userTag := getBucketTagByKey("User", objTagOutput)
-			reasonTag := getBucketTagByKey("Reason", objTagOutput)
-			typeTag := getBucketTagByKey("Type", objTagOutput)
-
-			dumpFileObjects = append(dumpFileObjects, types.Report{
-				Name:         *obj.Key,
-				LastModified: *obj.LastModified,
-				Created:      obj.LastModified.Format(time.UnixDate),
-				Expires:      expiresDate.Format(time.UnixDate),
-				Bucket:       sdk.ReportsBucket,
-				Size:         objSize,
-				SizeUnits:    sizeUnits,
-				User:         strings.ReplaceAll(userTag, "@gmail.com", ""),
-				Reason:       reasonTag,
-				Type:         typeTag,
-			})
+			dumpFileObjects = append(dumpFileObjects, report)
 		}
 	}
 	return dumpFileObjects, nil
 }
 
-func getBucketTagByKey(key string, objTagOutput *s3.GetObjectTaggingOutput) string {
-	for _, t := range objTagOutput.TagSet {
-		if strings.EqualFold(*t.Key, key) {
-			return *t.Value
-		}
+// toReport transforms an s3.Object into the Report model. Objects expire according to the bucket lifecycle configuration.
+func (sdk *AWSSDK) toReport(ctx context.Context, obj *s3.Object) (types.Report, error) {
+	objSize, sizeUnits := reportSize(*obj.Size)
+	tagSet, err := sdk.objectTags(ctx, obj)
+	if err != nil {
+		return types.Report{}, err
 	}
-	return "unspecified"
+	tags := reportTagsFromSet(tagSet)
+
+	return types.Report{
+		Name:         *obj.Key,
+		LastModified: *obj.LastModified,
+		Created:      obj.LastModified.Format(time.UnixDate),
+		Expires:      formatReportExpiry(sdk.expiresAt(obj, tagSet)),
+		Bucket:       sdk.ReportsBucket,
+		Size:         objSize,
+		SizeUnits:    sizeUnits,
+		User:         strings.ReplaceAll(tags.User, "@gmail.com", ""),
+		Reason:       tags.Reason,
+		Type:         tags.Type,
+	}, nil
 }
@@ -0,0 +1,135 @@
+package modules
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/rs/zerolog/log"
+)
+
+// reportLifecycle caches the lifecycle rules of the reports bucket, which determine when reports expire
+type reportLifecycle struct {
+	mu     sync.RWMutex
+	rules  []*s3.LifecycleRule
+	loaded bool
+}
+
+// refreshLifecycle reads the lifecycle configuration of the reports bucket. Buckets without one never expire reports.
+// When the configuration cannot be read, e.g. because khub may not read it, the previous rules are kept and the expiry
+// of reports is unknown, so reports are still served. It is read again at the next refresh.
+func (sdk *AWSSDK) refreshLifecycle(ctx context.Context) {
+	output, err := sdk.S3Client.GetBucketLifecycleConfigurationWithContext(ctx, &s3.GetBucketLifecycleConfigurationInput{
+		Bucket: aws.String(sdk.ReportsBucket),
+	})
+
+	sdk.lifecycle.mu.Lock()
+	defer sdk.lifecycle.mu.Unlock()
+	sdk.lifecycle.loaded = true
+
+	var awsErr awserr.Error
+	switch {
+	case errors.As(err, &awsErr) && awsErr.Code() == "NoSuchLifecycleConfiguration":
+		sdk.lifecycle.rules = []*s3.LifecycleRule{}
+	case err != nil:
+		log.Warn().Msgf("unable to fetch the lifecycle configuration of %s, report expiry is unknown: %v", sdk.ReportsBucket, err)
+	default:
+		sdk.lifecycle.rules = output.Rules
+	}
+}
+
+// ensureLifecycle loads the lifecycle configuration if it was never loaded
+func (sdk *AWSSDK) ensureLifecycle(ctx context.Context) {
+	sdk.lifecycle.mu.RLock()
+	loaded := sdk.lifecycle.loaded
+	sdk.lifecycle.mu.RUnlock()
+	if !loaded {
+		sdk.refreshLifecycle(ctx)
+	}
+}
+
+// expiresAt returns when the bucket lifecycle expires an object with the given tag set, or nil when no enabled rule
+// expires it. When several rules apply, the earliest expiration wins, as it does in s3.
+func (sdk *AWSSDK) expiresAt(obj *s3.Object, objectTags map[string]string) *time.Time {
+	sdk.lifecycle.mu.RLock()
+	defer sdk.lifecycle.mu.RUnlock()
+
+	var earliest *time.Time
+	for _, rule := range sdk.lifecycle.rules {
+		if aws.StringValue(rule.Status) != s3.ExpirationStatusEnabled || rule.Expiration == nil || !lifecycleRuleMatches(rule, obj, objectTags) {
+			continue
+		}
+
+		var expiry time.Time
+		switch {
+		case rule.Expiration.Days != nil && *rule.Expiration.Days > 0:
+			expiry = lifecycleExpiry(*obj.LastModified, int(*rule.Expiration.Days))
+		case rule.Expiration.Date != nil:
+			expiry = *rule.Expiration.Date
+		default:
+			continue
+		}
+		if earliest == nil || expiry.Before(*earliest) {
+			earliest = &expiry
+		}
+	}
+	return earliest
+}
+
+// lifecycleExpiry adds the days of an expiration rule to the creation time of an object, rounded up to the next midnight
+// UTC like s3 does
+func lifecycleExpiry(lastModified time.Time, days int) time.Time {
+	expiry := lastModified.UTC().AddDate(0, 0, days)
+	midnight := expiry.Truncate(24 * time.Hour)
+	if midnight.Equal(expiry) {
+		return expiry
+	}
+	return midnight.Add(24 * time.Hour)
+}
+
+// lifecycleRuleMatches reports whether the filter of a lifecycle rule selects the object. Tag filters only match tags
+// the object has, like in s3.
+func lifecycleRuleMatches(rule *s3.LifecycleRule, obj *s3.Object, tags map[string]string) bool {
+	key := aws.StringValue(obj.Key)
+	size := aws.Int64Value(obj.Size)
+
+	if rule.Prefix != nil && !strings.HasPrefix(key, *rule.Prefix) {
+		return false
+	}
+
+	filter := rule.Filter
+	if filter == nil {
+		return true
+	}
+	matches := func(prefix *string, objectTags []*s3.Tag, greaterThan, lessThan *int64) bool {
+		if prefix != nil && !strings.HasPrefix(key, *prefix) {
+			return false
+		}
+		for _, t := range objectTags {
+			if value, ok := tags[aws.StringValue(t.Key)]; !ok || value != aws.StringValue(t.Value) {
+				return false
+			}
+		}
+		if greaterThan != nil && size <= *greaterThan {
+			return false
+		}
+		if lessThan != nil && size >= *lessThan {
+			return false
+		}
+		return true
+	}
+
+	if filter.And != nil {
+		return matches(filter.And.Prefix, filter.And.Tags, filter.And.ObjectSizeGreaterThan, filter.And.ObjectSizeLessThan)
+	}
+	objectTags := []*s3.Tag{}
+	if filter.Tag != nil {
+		objectTags = append(objectTags, filter.Tag)
+	}
+	return matches(filter.Prefix, objectTags, filter.ObjectSizeGreaterThan, filter.ObjectSizeLessThan)
+}
@@ -0,0 +1,87 @@
+package modules
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLifecycleExpiry(t *testing.T) {
+	created := time.Date(2024, 3, 1, 13, 45, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2024, 3, 9, 0, 0, 0, 0, time.UTC), lifecycleExpiry(created, 7))
+
+	midnight := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2024, 3, 8, 0, 0, 0, 0, time.UTC), lifecycleExpiry(midnight, 7))
+}
+
+func TestExpiresAtUsesEarliestMatchingRule(t *testing.T) {
+	sdk := &AWSSDK{lifecycle: &reportLifecycle{loaded: true, rules: []*s3.LifecycleRule{
+		{
+			Status:     aws.String(s3.ExpirationStatusEnabled),
+			Expiration: &s3.LifecycleExpiration{Days: aws.Int64(90)},
+			Filter:     &s3.LifecycleRuleFilter{Prefix: aws.String("")},
+		},
+		{
+			Status:     aws.String(s3.ExpirationStatusEnabled),
+			Expiration: &s3.LifecycleExpiration{Days: aws.Int64(7)},
+			Filter:     &s3.LifecycleRuleFilter{Tag: &s3.Tag{Key: aws.String("Type"), Value: aws.String("heapdump")}},
+		},
+		{
+			Status:     aws.String(s3.ExpirationStatusDisabled),
+			Expiration: &s3.LifecycleExpiration{Days: aws.Int64(1)},
+		},
+		{
+			Status:     aws.String(s3.ExpirationStatusEnabled),
+			Expiration: &s3.LifecycleExpiration{Days: aws.Int64(2)},
+			Filter: &s3.LifecycleRuleFilter{And: &s3.LifecycleRuleAndOperator{
+				Prefix: aws.String("tmp-"),
+				Tags:   []*s3.Tag{{Key: aws.String("Type"), Value: aws.String("heapdump")}},
+			}},
+		},
+	}}}
+	created := time.Date(2024, 3, 1, 13, 45, 0, 0, time.UTC)
+	obj := &s3.Object{Key: aws.String("heap.hprof"), Size: aws.Int64(10), LastModified: aws.Time(created)}
+
+	expiresAt := sdk.expiresAt(obj, map[string]string{"Type": "heapdump"})
+	require.NotNil(t, expiresAt)
+	assert.Equal(t, time.Date(2024, 3, 9, 0, 0, 0, 0, time.UTC), *expiresAt)
+
+	expiresAt = sdk.expiresAt(obj, map[string]string{"Type": "threaddump"})
+	require.NotNil(t, expiresAt)
+	assert.Equal(t, time.Date(2024, 5, 31, 0, 0, 0, 0, time.UTC), *expiresAt)
+
+	obj.Key = aws.String("tmp-heap.hprof")
+	expiresAt = sdk.expiresAt(obj, map[string]string{"Type": "heapdump"})
+	require.NotNil(t, expiresAt)
+	assert.Equal(t, time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC), *expiresAt)
+
+	assert.Nil(t, (&AWSSDK{lifecycle: &reportLifecycle{loaded: true}}).expiresAt(obj, map[string]string{}))
+}
+
+func TestExpiresAtUntaggedObject(t *testing.T) {
+	sdk := &AWSSDK{lifecycle: &reportLifecycle{loaded: true, rules: []*s3.LifecycleRule{
+		{
+			Status:     aws.String(s3.ExpirationStatusEnabled),
+			Expiration: &s3.LifecycleExpiration{Days: aws.Int64(7)},
+			Filter:     &s3.LifecycleRuleFilter{Tag: &s3.Tag{Key: aws.String("Type"), Value: aws.String("unspecified")}},
+		},
+		{
+			Status:     aws.String(s3.ExpirationStatusEnabled),
+			Expiration: &s3.LifecycleExpiration{Days: aws.Int64(1)},
+			Filter:     &s3.LifecycleRuleFilter{Tag: &s3.Tag{Key: aws.String("Type"), Value: aws.String("")}},
+		},
+	}}}
+	obj := &s3.Object{Key: aws.String("heap.hprof"), Size: aws.Int64(10), LastModified: aws.Time(time.Date(2024, 3, 1, 13, 45, 0, 0, time.UTC))}
+
+	// Untagged objects are listed as unspecified, but s3 never expires them with a tag filter
+	assert.Equal(t, "unspecified", reportTagsFromSet(map[string]string{}).Type)
+	assert.Nil(t, sdk.expiresAt(obj, map[string]string{}))
+
+	expiresAt := sdk.expiresAt(obj, map[string]string{"Type": ""})
+	require.NotNil(t, expiresAt)
+	assert.Equal(t, time.Date(2024, 3, 3, 0, 0, 0, 0, time.UTC), *expiresAt)
+}
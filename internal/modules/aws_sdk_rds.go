@@ -0,0 +1,49 @@
+package modules
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/aws/aws-sdk-go/service/rds/rdsiface"
+	"github.com/sullivtr/k8s_platform/internal/types"
+)
+
+// rdsMySQLEngines are the RDS engines discovered as MySQL hosts
+var rdsMySQLEngines = []string{"mysql", "aurora-mysql"}
+
+// RDSSDK discovers the MySQL instances of the AWS account
+type RDSSDK struct {
+	Client rdsiface.RDSAPI
+}
+
+// NewRDSSDK creates the RDS client of the given session
+func NewRDSSDK(session *session.Session, region string) *RDSSDK {
+	return &RDSSDK{Client: rds.New(session, aws.NewConfig().WithRegion(region))}
+}
+
+// DiscoverMySQLInstances proposes the available MySQL and Aurora MySQL instances, addressed by their endpoint.
+// Instances without an endpoint yet, such as instances being created, are skipped.
+func (sdk *RDSSDK) DiscoverMySQLInstances(ctx context.Context) ([]types.MySQLDiscoveryProposal, error) {
+	proposals := []types.MySQLDiscoveryProposal{}
+	input := &rds.DescribeDBInstancesInput{
+		Filters: []*rds.Filter{{Name: aws.String("engine"), Values: aws.StringSlice(rdsMySQLEngines)}},
+	}
+	err := sdk.Client.DescribeDBInstancesPagesWithContext(ctx, input, func(page *rds.DescribeDBInstancesOutput, _ bool) bool {
+		for _, instance := range page.DBInstances {
+			if instance.Endpoint == nil || aws.StringValue(instance.Endpoint.Address) == "" {
+				continue
+			}
+			proposals = append(proposals, types.MySQLDiscoveryProposal{
+				Host:      aws.StringValue(instance.Endpoint.Address),
+				Shortname: aws.StringValue(instance.DBInstanceIdentifier),
+				Port:      int(aws.Int64Value(instance.Endpoint.Port)),
+				Origin:    types.MySQLOriginRDS,
+				OriginRef: aws.StringValue(instance.DBInstanceArn),
+			})
+		}
+		return true
+	})
+	return proposals, err
+}
@@ -0,0 +1,136 @@
+package modules
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/sullivtr/k8s_platform/internal/types"
+)
+
+// reportTagIndex caches the tag sets of the reports bucket objects, keyed by object key.
+// Entries remember the ETag they were read for, so overwritten objects are tagged again.
+type reportTagIndex struct {
+	mu      sync.RWMutex
+	entries map[string]reportTagEntry
+}
+
+type reportTagEntry struct {
+	etag string
+	tags map[string]string
+}
+
+func newReportTagIndex() *reportTagIndex {
+	return &reportTagIndex{entries: map[string]reportTagEntry{}}
+}
+
+func (i *reportTagIndex) get(key, etag string) (map[string]string, bool) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	entry, ok := i.entries[key]
+	if !ok || entry.etag != etag {
+		return nil, false
+	}
+	return entry.tags, true
+}
+
+func (i *reportTagIndex) set(key, etag string, tags map[string]string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.entries[key] = reportTagEntry{etag: etag, tags: tags}
+}
+
+func (i *reportTagIndex) delete(key string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	delete(i.entries, key)
+}
+
+// retain drops the entries of objects that no longer exist
+func (i *reportTagIndex) retain(keys map[string]bool) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	for key := range i.entries {
+		if !keys[key] {
+			delete(i.entries, key)
+		}
+	}
+}
+
+// RefreshTagIndex reads the tags of every object that is new or changed since the last refresh, and drops the tags of
+// deleted objects. It is run in the background so that listing reports rarely needs to tag objects itself.
+func (sdk *AWSSDK) RefreshTagIndex(ctx context.Context) error {
+	objects, err := sdk.listObjects(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to fetch s3 reports data : %v", err)
+	}
+
+	keys := map[string]bool{}
+	for _, obj := range objects {
+		if obj.Key == nil || *obj.Key == "" {
+			continue
+		}
+		keys[*obj.Key] = true
+		if _, err := sdk.objectTags(ctx, obj); err != nil {
+			return err
+		}
+	}
+	sdk.tagIndex.retain(keys)
+	return nil
+}
+
+// listObjects lists every object of the reports bucket, following ListObjectsV2 continuation tokens past the 1000 keys
+// returned per call
+func (sdk *AWSSDK) listObjects(ctx context.Context) ([]*s3.Object, error) {
+	objects := []*s3.Object{}
+	err := sdk.S3Client.ListObjectsV2PagesWithContext(ctx, &s3.ListObjectsV2Input{Bucket: aws.String(sdk.ReportsBucket)},
+		func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+			objects = append(objects, page.Contents...)
+			return true
+		})
+	return objects, err
+}
+
+// objectTags returns the tag set of an object, reading it from s3 when it is not indexed
+func (sdk *AWSSDK) objectTags(ctx context.Context, obj *s3.Object) (map[string]string, error) {
+	etag := aws.StringValue(obj.ETag)
+	if tags, ok := sdk.tagIndex.get(*obj.Key, etag); ok {
+		return tags, nil
+	}
+
+	objTagOutput, err := sdk.S3Client.GetObjectTaggingWithContext(ctx, &s3.GetObjectTaggingInput{
+		Bucket: aws.String(sdk.ReportsBucket),
+		Key:    obj.Key,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch object tags for %s, error: %v", *obj.Key, err)
+	}
+
+	tags := make(map[string]string, len(objTagOutput.TagSet))
+	for _, t := range objTagOutput.TagSet {
+		tags[aws.StringValue(t.Key)] = aws.StringValue(t.Value)
+	}
+	sdk.tagIndex.set(*obj.Key, etag, tags)
+	return tags, nil
+}
+
+// reportTagsFromSet returns the User, Reason and Type tags of an object tag set. Missing tags are unspecified.
+func reportTagsFromSet(tags map[string]string) types.ReportTags {
+	return types.ReportTags{
+		User:   reportTagValue(tags, "User"),
+		Reason: reportTagValue(tags, "Reason"),
+		Type:   reportTagValue(tags, "Type"),
+	}
+}
+
+func reportTagValue(tags map[string]string, key string) string {
+	for k, v := range tags {
+		if strings.EqualFold(k, key) {
+			return v
+		}
+	}
+	return "unspecified"
+}
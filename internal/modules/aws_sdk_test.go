@@ -0,0 +1,110 @@
+package modules
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeS3 serves a bucket listing split into pages of pageSize objects, and counts tagging calls
+type fakeS3 struct {
+	s3iface.S3API
+	objects      []*s3.Object
+	pageSize     int
+	taggingCalls int
+	// lifecycleErr is returned when the lifecycle configuration is read, the bucket has none by default
+	lifecycleErr error
+}
+
+func (f *fakeS3) ListObjectsV2PagesWithContext(_ aws.Context, _ *s3.ListObjectsV2Input, fn func(*s3.ListObjectsV2Output, bool) bool, _ ...request.Option) error {
+	for start := 0; start < len(f.objects); start += f.pageSize {
+		end := min(start+f.pageSize, len(f.objects))
+		if !fn(&s3.ListObjectsV2Output{Contents: f.objects[start:end]}, end == len(f.objects)) {
+			break
+		}
+	}
+	return nil
+}
+
+func (f *fakeS3) GetObjectTaggingWithContext(_ aws.Context, in *s3.GetObjectTaggingInput, _ ...request.Option) (*s3.GetObjectTaggingOutput, error) {
+	f.taggingCalls++
+	return &s3.GetObjectTaggingOutput{TagSet: []*s3.Tag{
+		{Key: aws.String("User"), Value: aws.String("jane.doe@gmail.com")},
+		{Key: aws.String("Type"), Value: aws.String("heapdump")},
+	}}, nil
+}
+
+func (f *fakeS3) GetBucketLifecycleConfigurationWithContext(_ aws.Context, _ *s3.GetBucketLifecycleConfigurationInput, _ ...request.Option) (*s3.GetBucketLifecycleConfigurationOutput, error) {
+	if f.lifecycleErr != nil {
+		return nil, f.lifecycleErr
+	}
+	return nil, awserr.New("NoSuchLifecycleConfiguration", "the lifecycle configuration does not exist", nil)
+}
+
+func newFakeS3(count int) *fakeS3 {
+	f := &fakeS3{pageSize: 1000}
+	for i := 0; i < count; i++ {
+		f.objects = append(f.objects, &s3.Object{
+			Key:          aws.String(fmt.Sprintf("report-%04d.hprof", i)),
+			ETag:         aws.String(fmt.Sprintf("etag-%d", i)),
+			Size:         aws.Int64(2000),
+			LastModified: aws.Time(time.Unix(int64(1700000000+i), 0)),
+		})
+	}
+	return f
+}
+
+func TestGetReportsPaginatesListing(t *testing.T) {
+	fake := newFakeS3(2500)
+	sdk := &AWSSDK{S3Client: fake, ReportsBucket: "reports", tagIndex: newReportTagIndex(), lifecycle: &reportLifecycle{}}
+
+	reports, err := sdk.GetReports()
+	require.NoError(t, err)
+	require.Len(t, reports, 2500)
+	assert.Equal(t, "report-2499.hprof", reports[0].Name)
+	assert.Equal(t, "jane.doe", reports[0].User)
+	assert.Equal(t, "unspecified", reports[0].Reason)
+	assert.Equal(t, 2500, fake.taggingCalls)
+
+	// Tags are served from the index on the following requests
+	_, err = sdk.GetReports()
+	require.NoError(t, err)
+	assert.Equal(t, 2500, fake.taggingCalls)
+}
+
+func TestRefreshTagIndex(t *testing.T) {
+	fake := newFakeS3(3)
+	sdk := &AWSSDK{S3Client: fake, ReportsBucket: "reports", tagIndex: newReportTagIndex(), lifecycle: &reportLifecycle{}}
+
+	require.NoError(t, sdk.RefreshTagIndex(context.Background()))
+	assert.Equal(t, 3, fake.taggingCalls)
+
+	// Overwritten objects are tagged again and deleted objects are dropped from the index
+	fake.objects[0].ETag = aws.String("etag-changed")
+	fake.objects = fake.objects[:2]
+	require.NoError(t, sdk.RefreshTagIndex(context.Background()))
+	assert.Equal(t, 4, fake.taggingCalls)
+	assert.Len(t, sdk.tagIndex.entries, 2)
+}
+
+func TestGetReportsWithoutLifecycleAccess(t *testing.T) {
+	fake := newFakeS3(2)
+	fake.lifecycleErr = awserr.New("AccessDenied", "access denied", nil)
+	sdk := &AWSSDK{S3Client: fake, ReportsBucket: "reports", tagIndex: newReportTagIndex(), lifecycle: &reportLifecycle{}}
+
+	// Reports are served without their expiry
+	reports, err := sdk.GetReports()
+	require.NoError(t, err)
+	require.Len(t, reports, 2)
+	assert.Empty(t, reports[0].Expires)
+	require.NoError(t, sdk.Refresh(context.Background()))
+}
@@ -0,0 +1,69 @@
+package modules
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/redis/go-redis/v9"
+	"github.com/sullivtr/k8s_platform/internal/types"
+)
+
+const (
+	// execJobKeyPrefix is the key prefix of exec jobs. The job output is stored under the job key with an :output suffix.
+	execJobKeyPrefix = "exec-job:"
+	// ExecJobTTL is how long exec jobs and their output are kept for
+	ExecJobTTL = 24 * time.Hour
+)
+
+func execJobKey(jobID string) string {
+	return fmt.Sprintf("%s%s", execJobKeyPrefix, jobID)
+}
+
+func execJobOutputKey(jobID string) string {
+	return fmt.Sprintf("%s%s:output", execJobKeyPrefix, jobID)
+}
+
+// SaveExecJob stores an exec job. The job output is stored separately by AppendExecJobOutput.
+func (sdk *RedisStorageSDK) SaveExecJob(ctx context.Context, job types.ExecJob) error {
+	json := jsoniter.ConfigCompatibleWithStandardLibrary
+	job.Output = ""
+	b, err := json.Marshal(&job)
+	if err != nil {
+		return err
+	}
+	return sdk.Client.Set(ctx, execJobKey(job.ID), b, ExecJobTTL).Err()
+}
+
+// GetExecJob fetches an exec job without its output. redis.Nil is returned when the job does not exist.
+func (sdk *RedisStorageSDK) GetExecJob(ctx context.Context, jobID string) (types.ExecJob, error) {
+	b, err := sdk.Client.Get(ctx, execJobKey(jobID)).Bytes()
+	if err != nil {
+		return types.ExecJob{}, err
+	}
+
+	json := jsoniter.ConfigCompatibleWithStandardLibrary
+	job := types.ExecJob{}
+	err = json.Unmarshal(b, &job)
+	return job, err
+}
+
+// AppendExecJobOutput appends a chunk of output to an exec job
+func (sdk *RedisStorageSDK) AppendExecJobOutput(ctx context.Context, jobID string, chunk []byte) error {
+	key := execJobOutputKey(jobID)
+	pipe := sdk.Client.TxPipeline()
+	pipe.Append(ctx, key, string(chunk))
+	pipe.Expire(ctx, key, ExecJobTTL)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// GetExecJobOutput fetches the output of an exec job starting at the given byte offset
+func (sdk *RedisStorageSDK) GetExecJobOutput(ctx context.Context, jobID string, offset int64) (string, error) {
+	output, err := sdk.Client.GetRange(ctx, execJobOutputKey(jobID), offset, -1).Result()
+	if err == redis.Nil {
+		return "", nil
+	}
+	return output, err
+}
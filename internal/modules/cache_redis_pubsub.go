@@ -0,0 +1,42 @@
+package modules
+
+import (
+	"context"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog/log"
+)
+
+// Publish publishes a message on a redis pub/sub channel
+func (sdk *RedisStorageSDK) Publish(ctx context.Context, channel, message string) error {
+	return sdk.Client.Publish(ctx, channel, message).Err()
+}
+
+// Subscribe calls onMessage for every message published on a redis pub/sub channel until the context is cancelled.
+// Messages published while the subscription is disconnected are lost, so onSubscribe is called every time the
+// subscription is established, including after reconnects.
+func (sdk *RedisStorageSDK) Subscribe(ctx context.Context, channel string, onSubscribe func(), onMessage func(payload string)) {
+	pubsub := sdk.Client.Subscribe(ctx, channel)
+	defer pubsub.Close()
+
+	messages := pubsub.ChannelWithSubscriptions()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case m, ok := <-messages:
+			if !ok {
+				return
+			}
+			switch msg := m.(type) {
+			case *redis.Subscription:
+				if msg.Kind == "subscribe" {
+					log.Debug().Msgf("subscribed to %s", channel)
+					onSubscribe()
+				}
+			case *redis.Message:
+				onMessage(msg.Payload)
+			}
+		}
+	}
+}
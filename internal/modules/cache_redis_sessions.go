@@ -0,0 +1,129 @@
+package modules
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/sullivtr/k8s_platform/internal/types"
+)
+
+const (
+	// authSessionKeyPrefix is the key prefix used by the redis auth session store
+	authSessionKeyPrefix = "session:"
+	// userSessionsKeyPrefix is the key prefix of the per-user index of login sessions
+	userSessionsKeyPrefix = "user-sessions:"
+)
+
+// UserSessionID returns the opaque identifier used to reference a login session without exposing its key
+func UserSessionID(sessionKey string) string {
+	h := sha256.Sum256([]byte(sessionKey))
+	return hex.EncodeToString(h[:])[:16]
+}
+
+// trackedUserSession is the value stored in the user session index. The session key is kept
+// alongside the session details since it is not serialized as part of types.UserSession.
+type trackedUserSession struct {
+	Session    types.UserSession `json:"session"`
+	SessionKey string            `json:"sessionKey"`
+}
+
+func userSessionsKey(email string) string {
+	return fmt.Sprintf("%s%s", userSessionsKeyPrefix, strings.ToLower(email))
+}
+
+// TrackUserSession records a login session in the user's session index so it can later be listed and revoked
+func (sdk *RedisStorageSDK) TrackUserSession(ctx context.Context, userSession types.UserSession) error {
+	json := jsoniter.ConfigCompatibleWithStandardLibrary
+	userSession.ID = UserSessionID(userSession.SessionKey)
+	b, err := json.Marshal(&trackedUserSession{Session: userSession, SessionKey: userSession.SessionKey})
+	if err != nil {
+		return err
+	}
+
+	key := userSessionsKey(userSession.Email)
+	if err := sdk.Client.HSet(ctx, key, userSession.ID, b).Err(); err != nil {
+		return err
+	}
+
+	// The index never needs to outlive the longest session it references
+	ttl := sdk.Client.TTL(ctx, key).Val()
+	if expiresIn := time.Until(userSession.ExpiresAt); expiresIn > ttl {
+		return sdk.Client.Expire(ctx, key, expiresIn).Err()
+	}
+	return nil
+}
+
+// GetUserSessions returns the active login sessions of a user. Sessions that have expired
+// from the session store are pruned from the index.
+func (sdk *RedisStorageSDK) GetUserSessions(ctx context.Context, email string) ([]types.UserSession, error) {
+	key := userSessionsKey(email)
+	entries, err := sdk.Client.HGetAll(ctx, key).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	json := jsoniter.ConfigCompatibleWithStandardLibrary
+	userSessions := []types.UserSession{}
+	for id, entry := range entries {
+		var s trackedUserSession
+		if err := json.Unmarshal([]byte(entry), &s); err != nil {
+			return nil, err
+		}
+
+		exists, err := sdk.Client.Exists(ctx, authSessionKeyPrefix+s.SessionKey).Result()
+		if err != nil {
+			return nil, err
+		}
+		if exists == 0 {
+			sdk.Client.HDel(ctx, key, id)
+			continue
+		}
+
+		s.Session.SessionKey = s.SessionKey
+		userSessions = append(userSessions, s.Session)
+	}
+	return userSessions, nil
+}
+
+// RevokeUserSession deletes a single login session of a user from the session store
+func (sdk *RedisStorageSDK) RevokeUserSession(ctx context.Context, email, sessionID string) error {
+	userSessions, err := sdk.GetUserSessions(ctx, email)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range userSessions {
+		if s.ID == sessionID {
+			if err := sdk.Client.Del(ctx, authSessionKeyPrefix+s.SessionKey).Err(); err != nil {
+				return err
+			}
+			return sdk.Client.HDel(ctx, userSessionsKey(email), s.ID).Err()
+		}
+	}
+	return fmt.Errorf("session %s not found", sessionID)
+}
+
+// RevokeUserSessions deletes every login session of a user from the session store
+func (sdk *RedisStorageSDK) RevokeUserSessions(ctx context.Context, email string) error {
+	userSessions, err := sdk.GetUserSessions(ctx, email)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range userSessions {
+		if err := sdk.Client.Del(ctx, authSessionKeyPrefix+s.SessionKey).Err(); err != nil {
+			return err
+		}
+	}
+	return sdk.Client.Del(ctx, userSessionsKey(email)).Err()
+}
+
+// UntrackUserSession removes a login session from the user's session index
+func (sdk *RedisStorageSDK) UntrackUserSession(ctx context.Context, email, sessionKey string) error {
+	return sdk.Client.HDel(ctx, userSessionsKey(email), UserSessionID(sessionKey)).Err()
+}
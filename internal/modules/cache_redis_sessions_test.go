@@ -0,0 +1,32 @@
+package modules
+
+import "testing"
+
+func TestUserSessionID(t *testing.T) {
+	tests := []struct {
+		name       string
+		sessionKey string
+		other      string
+	}{
+		{name: "distinct keys", sessionKey: "ABCDEF123", other: "ABCDEF124"},
+		{name: "empty key", sessionKey: "", other: "A"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id := UserSessionID(tt.sessionKey)
+			if len(id) != 16 {
+				t.Errorf("expected session id of length 16, got %d", len(id))
+			}
+			if id != UserSessionID(tt.sessionKey) {
+				t.Errorf("expected session id to be stable for the same session key")
+			}
+			if id == UserSessionID(tt.other) {
+				t.Errorf("expected distinct session ids for %s and %s", tt.sessionKey, tt.other)
+			}
+			if id == tt.sessionKey {
+				t.Errorf("expected session id to not expose the session key")
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package modules
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sullivtr/k8s_platform/internal/types"
+)
+
+// SecretReader reads the values of kubernetes secrets. It is implemented by K8sSDK.
+type SecretReader interface {
+	GetSecretValue(ctx context.Context, namespace, name, key string) (string, error)
+}
+
+// resolvePassword reads the password of a database from its credential reference. Databases without a reference use
+// the catalog password. Trailing newlines are dropped, since secret files usually end with one.
+func (sdk *MySQLTopoSDK) resolvePassword(ctx context.Context, db *types.MySQLDBInfo) (string, error) {
+	return resolveCredential(ctx, sdk.Secrets, sdk.CredentialFilesDir, db.Credential, sdk.MySQLDBPassword, db.Shortname)
+}
+
+// resolveCredential reads a password from a credential reference, or returns the catalog password when the reference
+// has no source. Secret references are read with secrets, and file references must be within filesDir. References
+// stored before they were restricted are checked again, so they cannot read khub's own environment or files.
+func resolveCredential(ctx context.Context, secrets SecretReader, filesDir string, ref types.CredentialRef, catalogPassword, shortname string) (string, error) {
+	var password string
+	switch ref.Source {
+	case "":
+		return catalogPassword, nil
+	case types.CredentialSourceSecret:
+		if secrets == nil {
+			return "", errors.New("kubernetes secrets are not available to the topology capture")
+		}
+		value, err := secrets.GetSecretValue(ctx, ref.SecretNamespace, ref.SecretName, ref.SecretKey)
+		if err != nil {
+			return "", fmt.Errorf("unable to read secret %s/%s : %v", ref.SecretNamespace, ref.SecretName, err)
+		}
+		password = value
+	case types.CredentialSourceFile:
+		path, err := credentialFilePath(filesDir, ref.FilePath)
+		if err != nil {
+			return "", err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("unable to read credential file : %v", err)
+		}
+		password = string(data)
+	case types.CredentialSourceEnv:
+		if !strings.HasPrefix(ref.EnvVar, types.CredentialEnvVarPrefix) {
+			return "", fmt.Errorf("environment variable %s does not start with %s", ref.EnvVar, types.CredentialEnvVarPrefix)
+		}
+		value, ok := os.LookupEnv(ref.EnvVar)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", ref.EnvVar)
+		}
+		password = value
+	default:
+		return "", fmt.Errorf("unknown credential source %s", ref.Source)
+	}
+
+	password = strings.TrimRight(password, "\r\n")
+	if password == "" {
+		return "", fmt.Errorf("the %s credential of %s is empty", ref.Source, shortname)
+	}
+	return password, nil
+}
+
+// credentialFilePath resolves the symlinks of a credential file, such as those of mounted secrets, and checks that it
+// is within the credential files directory
+func credentialFilePath(filesDir, path string) (string, error) {
+	if filesDir == "" {
+		return "", errors.New("file credentials are disabled, the credential files directory is not set")
+	}
+	dir, err := filepath.EvalSymlinks(filesDir)
+	if err != nil {
+		return "", fmt.Errorf("unable to read the credential files directory : %v", err)
+	}
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", fmt.Errorf("unable to read credential file : %v", err)
+	}
+	if rel, err := filepath.Rel(dir, resolved); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("credential file %s is outside of %s", path, filesDir)
+	}
+	return resolved, nil
+}
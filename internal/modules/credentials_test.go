@@ -0,0 +1,85 @@
+package modules
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/sullivtr/k8s_platform/internal/types"
+)
+
+type fakeSecretReader map[string]string
+
+func (f fakeSecretReader) GetSecretValue(_ context.Context, namespace, name, key string) (string, error) {
+	value, ok := f[namespace+"/"+name+"/"+key]
+	if !ok {
+		return "", errors.New("secret not found")
+	}
+	return value, nil
+}
+
+func TestResolvePassword(t *testing.T) {
+	filesDir := t.TempDir()
+	passwordFile := filepath.Join(filesDir, "password")
+	require.NoError(t, os.WriteFile(passwordFile, []byte("from-file\n"), 0o600))
+	// Mounted secrets link their keys to a timestamped directory
+	require.NoError(t, os.Mkdir(filepath.Join(filesDir, "..data"), 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(filesDir, "..data", "linked"), []byte("from-link"), 0o600))
+	require.NoError(t, os.Symlink(filepath.Join("..data", "linked"), filepath.Join(filesDir, "linked")))
+	outsideFile := filepath.Join(t.TempDir(), "password")
+	require.NoError(t, os.WriteFile(outsideFile, []byte("outside"), 0o600))
+	require.NoError(t, os.Symlink(outsideFile, filepath.Join(filesDir, "escape")))
+	t.Setenv("KHUB_CREDENTIAL_TEST_PASSWORD", "from-env")
+	t.Setenv("KHUB_TEST_DB_PASSWORD", "khub-password")
+
+	sdk := &MySQLTopoSDK{
+		MySQLDBPassword:    "catalog-password",
+		Secrets:            fakeSecretReader{"databases/monitor/password": "from-secret"},
+		CredentialFilesDir: filesDir,
+	}
+
+	for name, tc := range map[string]struct {
+		ref      types.CredentialRef
+		password string
+	}{
+		"catalog password": {ref: types.CredentialRef{}, password: "catalog-password"},
+		"secret": {
+			ref:      types.CredentialRef{Source: types.CredentialSourceSecret, SecretNamespace: "databases", SecretName: "monitor", SecretKey: "password"},
+			password: "from-secret",
+		},
+		"file":        {ref: types.CredentialRef{Source: types.CredentialSourceFile, FilePath: passwordFile}, password: "from-file"},
+		"linked file": {ref: types.CredentialRef{Source: types.CredentialSourceFile, FilePath: filepath.Join(filesDir, "linked")}, password: "from-link"},
+		"env":         {ref: types.CredentialRef{Source: types.CredentialSourceEnv, EnvVar: "KHUB_CREDENTIAL_TEST_PASSWORD"}, password: "from-env"},
+	} {
+		password, err := sdk.resolvePassword(context.Background(), &types.MySQLDBInfo{Shortname: "db-1", Credential: tc.ref})
+		require.NoError(t, err, name)
+		assert.Equal(t, tc.password, password, name)
+	}
+
+	for name, ref := range map[string]types.CredentialRef{
+		"missing secret": {Source: types.CredentialSourceSecret, SecretNamespace: "databases", SecretName: "other", SecretKey: "password"},
+		"missing file":   {Source: types.CredentialSourceFile, FilePath: filepath.Join(filesDir, "missing")},
+		"outside file":   {Source: types.CredentialSourceFile, FilePath: outsideFile},
+		"escaping link":  {Source: types.CredentialSourceFile, FilePath: filepath.Join(filesDir, "escape")},
+		"unset env":      {Source: types.CredentialSourceEnv, EnvVar: "KHUB_CREDENTIAL_TEST_UNSET"},
+		"khub env":       {Source: types.CredentialSourceEnv, EnvVar: "KHUB_TEST_DB_PASSWORD"},
+		"unknown source": {Source: "vault"},
+	} {
+		_, err := sdk.resolvePassword(context.Background(), &types.MySQLDBInfo{Shortname: "db-1", Credential: ref})
+		assert.Error(t, err, name)
+	}
+
+	_, err := (&MySQLTopoSDK{}).resolvePassword(context.Background(), &types.MySQLDBInfo{
+		Credential: types.CredentialRef{Source: types.CredentialSourceSecret, SecretNamespace: "databases", SecretName: "monitor", SecretKey: "password"},
+	})
+	assert.ErrorContains(t, err, "secrets are not available")
+
+	_, err = (&MySQLTopoSDK{}).resolvePassword(context.Background(), &types.MySQLDBInfo{
+		Credential: types.CredentialRef{Source: types.CredentialSourceFile, FilePath: passwordFile},
+	})
+	assert.ErrorContains(t, err, "file credentials are disabled")
+}
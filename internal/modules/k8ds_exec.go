@@ -1,32 +1,79 @@
 package modules
 
 import (
+	"archive/tar"
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"strings"
-	"time"
 
-	"github.com/sullivtr/k8s_platform/internal/types"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/tools/remotecommand"
 )
 
-func (sdk *K8sSDK) ExecutePodExecPlugin(namespace, podName string, plugin types.K8sPodExecPlugin) (string, string, error) {
-	cmd := strings.Split(plugin.Command, " ")
+// ExecutePodCommand runs a command in a pod container, writing its output to stdout and stderr until the command exits
+// or the context is done. The command is passed as an argv array and never interpreted by a shell.
+func (sdk *K8sSDK) ExecutePodCommand(ctx context.Context, namespace, podName, container string, command []string, stdout, stderr io.Writer) error {
+	return sdk.streamPodCommand(ctx, namespace, podName, container, command, true, stdout, stderr)
+}
+
+// CopyFileFromPod writes the content of a file in a pod container to w. The file is streamed out of the container as a
+// tar archive (the equivalent of kubectl cp), so the container image must provide tar.
+func (sdk *K8sSDK) CopyFileFromPod(ctx context.Context, namespace, podName, container, filePath string, w io.Writer) error {
+	reader, writer := io.Pipe()
+	stderr := &bytes.Buffer{}
+
+	execErr := make(chan error, 1)
+	go func() {
+		// A tty would translate line endings, so the archive is streamed without one
+		err := sdk.streamPodCommand(ctx, namespace, podName, container, []string{"tar", "cf", "-", filePath}, false, writer, stderr)
+		writer.CloseWithError(err)
+		execErr <- err
+	}()
+
+	copyErr := copyFirstTarFile(reader, w)
+	// Drain the end of the archive, so the command is not blocked writing it
+	_, _ = io.Copy(io.Discard, reader)
+	if err := <-execErr; err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	if copyErr != nil {
+		return fmt.Errorf("unable to read %s from %v/%v: %w", filePath, namespace, podName, copyErr)
+	}
+	return nil
+}
+
+// copyFirstTarFile writes the content of the first regular file of a tar archive to w
+func copyFirstTarFile(r io.Reader, w io.Writer) error {
+	archive := tar.NewReader(r)
+	for {
+		header, err := archive.Next()
+		if errors.Is(err, io.EOF) {
+			return errors.New("the archive does not contain a regular file")
+		}
+		if err != nil {
+			return err
+		}
+		if header.Typeflag == tar.TypeReg {
+			_, err = io.Copy(w, archive)
+			return err
+		}
+	}
+}
+
+func (sdk *K8sSDK) streamPodCommand(ctx context.Context, namespace, podName, container string, command []string, tty bool, stdout, stderr io.Writer) error {
 	execOptions := &v1.PodExecOptions{
-		Command:   cmd,
-		Container: plugin.Container,
+		Command:   command,
+		Container: container,
 		Stdin:     false,
 		Stdout:    true,
 		Stderr:    true,
-		TTY:       true,
+		TTY:       tty,
 	}
 
-	buf := &bytes.Buffer{}
-	errBuf := &bytes.Buffer{}
-
 	execReq := sdk.client.CoreV1().RESTClient().
 		Post().
 		Namespace(namespace).
@@ -37,18 +84,15 @@ func (sdk *K8sSDK) ExecutePodExecPlugin(namespace, podName string, plugin types.
 
 	exec, err := remotecommand.NewSPDYExecutor(sdk.restClientConfig, "POST", execReq.URL())
 	if err != nil {
-		return "", "", fmt.Errorf("%w Failed initialize remote executor for (%s: %s) on %v/%v", err, plugin.Container, plugin.Command, namespace, podName)
+		return fmt.Errorf("%w Failed initialize remote executor for (%s: %s) on %v/%v", err, container, strings.Join(command, " "), namespace, podName)
 	}
 
-	ctxWithTimeout, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-	err = exec.StreamWithContext(ctxWithTimeout, remotecommand.StreamOptions{
-		Stdout: buf,
-		Stderr: errBuf,
+	err = exec.StreamWithContext(ctx, remotecommand.StreamOptions{
+		Stdout: stdout,
+		Stderr: stderr,
 	})
 	if err != nil {
-		cancel()
-		return "", "", fmt.Errorf("%w Failed executing command (%s) on %v/%v", err, plugin.Command, namespace, podName)
+		return fmt.Errorf("%w Failed executing command (%s) on %v/%v", err, strings.Join(command, " "), namespace, podName)
 	}
-	return buf.String(), errBuf.String(), nil
+	return nil
 }
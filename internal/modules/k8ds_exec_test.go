@@ -0,0 +1,29 @@
+package modules
+
+import (
+	"archive/tar"
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCopyFirstTarFile(t *testing.T) {
+	archive := &bytes.Buffer{}
+	tw := tar.NewWriter(archive)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "tmp/", Typeflag: tar.TypeDir, Mode: 0755}))
+	content := []byte("heap\r\ndump\x00")
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "tmp/heap.hprof", Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(content))}))
+	_, err := tw.Write(content)
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+
+	out := &bytes.Buffer{}
+	require.NoError(t, copyFirstTarFile(archive, out))
+	assert.Equal(t, content, out.Bytes())
+
+	empty := &bytes.Buffer{}
+	require.NoError(t, tar.NewWriter(empty).Close())
+	assert.EqualError(t, copyFirstTarFile(empty, out), "the archive does not contain a regular file")
+}
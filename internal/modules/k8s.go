@@ -444,3 +444,20 @@ func (sdk *K8sSDK) GetClusterEvents() ([]types.K8sEventWrapper, error) {
 	}
 	return wrappedEvents, nil
 }
+
+/*
+/    Secrets
+*/
+
+// GetSecretValue returns the value of a single key of a kubernetes secret
+func (sdk *K8sSDK) GetSecretValue(ctx context.Context, namespace, name, key string) (string, error) {
+	secret, err := sdk.client.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+	value, ok := secret.Data[key]
+	if !ok {
+		return "", fmt.Errorf("secret %s/%s has no key %s", namespace, name, key)
+	}
+	return string(value), nil
+}
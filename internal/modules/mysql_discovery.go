@@ -0,0 +1,87 @@
+package modules
+
+import (
+	"fmt"
+
+	"github.com/sullivtr/k8s_platform/internal/types"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+)
+
+// mysqlPortName is the name of the port MySQL is exposed on by Services and containers
+const mysqlPortName = "mysql"
+
+// DiscoverKubernetesMySQLHosts proposes the MySQL hosts of the Services and StatefulSets carrying the configured labels.
+// Each pod of a StatefulSet is a host, addressed through the governing Service of the StatefulSet, which is not proposed
+// on its own. Hosts are addressed as <name>.<namespace>.svc.
+func DiscoverKubernetesMySQLHosts(services []v1.Service, statefulsets []appsv1.StatefulSet, cfg types.MySQLDiscoveryConfig) []types.MySQLDiscoveryProposal {
+	proposals := []types.MySQLDiscoveryProposal{}
+	governing := map[string]bool{}
+
+	for _, sts := range statefulsets {
+		if !cfg.Matches(sts.Labels) || sts.Spec.ServiceName == "" {
+			continue
+		}
+		governing[sts.Namespace+"/"+sts.Spec.ServiceName] = true
+
+		replicas := int32(1)
+		if sts.Spec.Replicas != nil {
+			replicas = *sts.Spec.Replicas
+		}
+		port := containerMySQLPort(sts.Spec.Template.Spec.Containers)
+		for i := int32(0); i < replicas; i++ {
+			pod := fmt.Sprintf("%s-%d", sts.Name, i)
+			proposals = append(proposals, types.MySQLDiscoveryProposal{
+				Host:      fmt.Sprintf("%s.%s.%s.svc", pod, sts.Spec.ServiceName, sts.Namespace),
+				Shortname: pod,
+				Port:      port,
+				Origin:    types.MySQLOriginKubernetes,
+				OriginRef: fmt.Sprintf("statefulset/%s/%s", sts.Namespace, sts.Name),
+			})
+		}
+	}
+
+	for _, svc := range services {
+		if !cfg.Matches(svc.Labels) || governing[svc.Namespace+"/"+svc.Name] {
+			continue
+		}
+		proposals = append(proposals, types.MySQLDiscoveryProposal{
+			Host:      fmt.Sprintf("%s.%s.svc", svc.Name, svc.Namespace),
+			Shortname: svc.Name,
+			Port:      serviceMySQLPort(svc.Spec.Ports),
+			Origin:    types.MySQLOriginKubernetes,
+			OriginRef: fmt.Sprintf("service/%s/%s", svc.Namespace, svc.Name),
+		})
+	}
+	return proposals
+}
+
+// serviceMySQLPort returns the port named mysql, else the default port when it is exposed, else the first port
+func serviceMySQLPort(ports []v1.ServicePort) int {
+	for _, p := range ports {
+		if p.Name == mysqlPortName {
+			return int(p.Port)
+		}
+	}
+	for _, p := range ports {
+		if p.Port == types.DefaultMySQLDiscoveryPort {
+			return int(p.Port)
+		}
+	}
+	if len(ports) > 0 {
+		return int(ports[0].Port)
+	}
+	return types.DefaultMySQLDiscoveryPort
+}
+
+// containerMySQLPort returns the container port named mysql, else the default port
+func containerMySQLPort(containers []v1.Container) int {
+	for _, c := range containers {
+		for _, p := range c.Ports {
+			if p.Name == mysqlPortName {
+				return int(p.ContainerPort)
+			}
+		}
+	}
+	return types.DefaultMySQLDiscoveryPort
+}
@@ -0,0 +1,102 @@
+package modules
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/aws/aws-sdk-go/service/rds/rdsiface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/sullivtr/k8s_platform/internal/types"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDiscoverKubernetesMySQLHosts(t *testing.T) {
+	mysqlLabels := map[string]string{"app.kubernetes.io/name": "mysql", "team": "core"}
+	replicas := int32(2)
+	statefulsets := []appsv1.StatefulSet{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "databases", Labels: mysqlLabels},
+			Spec: appsv1.StatefulSetSpec{
+				ServiceName: "orders-headless",
+				Replicas:    &replicas,
+				Template: v1.PodTemplateSpec{Spec: v1.PodSpec{Containers: []v1.Container{
+					{Name: "mysql", Ports: []v1.ContainerPort{{Name: "mysql", ContainerPort: 3307}}},
+				}}},
+			},
+		},
+		{ObjectMeta: metav1.ObjectMeta{Name: "redis", Namespace: "databases", Labels: map[string]string{"app.kubernetes.io/name": "redis"}}},
+	}
+	services := []v1.Service{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "orders-headless", Namespace: "databases", Labels: mysqlLabels},
+			Spec:       v1.ServiceSpec{Ports: []v1.ServicePort{{Name: "mysql", Port: 3307}}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "legacy", Namespace: "billing", Labels: mysqlLabels},
+			Spec:       v1.ServiceSpec{Ports: []v1.ServicePort{{Name: "metrics", Port: 9104}, {Name: "db", Port: 3306}}},
+		},
+		{
+			// Only part of the labels match
+			ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "billing", Labels: map[string]string{"app.kubernetes.io/name": "mysql"}},
+		},
+	}
+
+	proposals := DiscoverKubernetesMySQLHosts(services, statefulsets, types.MySQLDiscoveryConfig{Labels: mysqlLabels})
+	require.Len(t, proposals, 3)
+	assert.Equal(t, types.MySQLDiscoveryProposal{
+		Host: "orders-0.orders-headless.databases.svc", Shortname: "orders-0", Port: 3307,
+		Origin: types.MySQLOriginKubernetes, OriginRef: "statefulset/databases/orders",
+	}, proposals[0])
+	assert.Equal(t, "orders-1.orders-headless.databases.svc", proposals[1].Host)
+	assert.Equal(t, types.MySQLDiscoveryProposal{
+		Host: "legacy.billing.svc", Shortname: "legacy", Port: 3306,
+		Origin: types.MySQLOriginKubernetes, OriginRef: "service/billing/legacy",
+	}, proposals[2])
+
+	// Kubernetes discovery is disabled without labels
+	assert.Empty(t, DiscoverKubernetesMySQLHosts(services, statefulsets, types.MySQLDiscoveryConfig{RDS: true}))
+}
+
+// fakeRDS serves DB instances in pages of one instance
+type fakeRDS struct {
+	rdsiface.RDSAPI
+	instances []*rds.DBInstance
+	input     *rds.DescribeDBInstancesInput
+}
+
+func (f *fakeRDS) DescribeDBInstancesPagesWithContext(_ aws.Context, in *rds.DescribeDBInstancesInput, fn func(*rds.DescribeDBInstancesOutput, bool) bool, _ ...request.Option) error {
+	f.input = in
+	for i, instance := range f.instances {
+		if !fn(&rds.DescribeDBInstancesOutput{DBInstances: []*rds.DBInstance{instance}}, i == len(f.instances)-1) {
+			break
+		}
+	}
+	return nil
+}
+
+func TestDiscoverMySQLInstances(t *testing.T) {
+	client := &fakeRDS{instances: []*rds.DBInstance{
+		{
+			DBInstanceIdentifier: aws.String("payments-1"),
+			DBInstanceArn:        aws.String("arn:aws:rds:us-east-1:123456789012:db:payments-1"),
+			Endpoint:             &rds.Endpoint{Address: aws.String("payments-1.abc.us-east-1.rds.amazonaws.com"), Port: aws.Int64(3306)},
+		},
+		// Instances being created have no endpoint yet
+		{DBInstanceIdentifier: aws.String("payments-2")},
+	}}
+	sdk := &RDSSDK{Client: client}
+
+	proposals, err := sdk.DiscoverMySQLInstances(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []types.MySQLDiscoveryProposal{{
+		Host: "payments-1.abc.us-east-1.rds.amazonaws.com", Shortname: "payments-1", Port: 3306,
+		Origin: types.MySQLOriginRDS, OriginRef: "arn:aws:rds:us-east-1:123456789012:db:payments-1",
+	}}, proposals)
+	assert.Equal(t, []string{"mysql", "aurora-mysql"}, aws.StringValueSlice(client.input.Filters[0].Values))
+}
@@ -0,0 +1,104 @@
+package modules
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/sullivtr/k8s_platform/internal/types"
+)
+
+// RunQuery runs a read only query against a catalog host and returns up to maxRows rows of its result.
+// The query runs in a read only transaction, which is rolled back, over a single connection that is closed once the
+// rows are read. Its run time is bounded by the context, and by max_execution_time on the server for SELECT statements.
+// Arguments are interpolated by the driver, so SHOW statements, which cannot be prepared, may take arguments too.
+func (sdk *MySQLTopoSDK) RunQuery(ctx context.Context, db *types.MySQLDBInfo, query string, args []any, maxRows int) (types.MySQLQueryResult, error) {
+	result := types.MySQLQueryResult{Host: db.Shortname}
+
+	password, err := sdk.resolvePassword(ctx, db)
+	if err != nil {
+		return result, err
+	}
+
+	timeout := defaultTopoQueryTimeout
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = time.Until(deadline)
+	}
+	connectTimeout := sdk.ConnectTimeout
+	if connectTimeout <= 0 {
+		connectTimeout = defaultTopoConnectTimeout
+	}
+	dsnConfig := mysqlConfig(*db, password, connectTimeout, timeout)
+	dsnConfig.InterpolateParams = true
+	dsnConfig.Params = map[string]string{"max_execution_time": strconv.FormatInt(timeout.Milliseconds(), 10)}
+	pool, err := sql.Open("mysql", dsnConfig.FormatDSN())
+	if err != nil {
+		return result, fmt.Errorf("unable to open connection: %v", err)
+	}
+	defer pool.Close()
+
+	conn, err := pool.Conn(ctx)
+	if err != nil {
+		return result, fmt.Errorf("unable to connect: %v", err)
+	}
+	defer conn.Close()
+
+	return readOnlyQuery(ctx, conn, db.Shortname, query, args, maxRows)
+}
+
+// txBeginner starts transactions on a MySQL host, through a *sql.Conn or a *sql.DB
+type txBeginner interface {
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+// readOnlyQuery runs a query in a read only transaction, which is rolled back, and reads up to maxRows rows of its result
+func readOnlyQuery(ctx context.Context, connection txBeginner, host, query string, args []any, maxRows int) (types.MySQLQueryResult, error) {
+	result := types.MySQLQueryResult{Host: host, Columns: []string{}, Rows: [][]*string{}}
+
+	tx, err := connection.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return result, fmt.Errorf("unable to start a read only transaction: %v", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	start := time.Now()
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return result, err
+	}
+	defer rows.Close()
+
+	result.Columns, err = rows.Columns()
+	if err != nil {
+		return result, err
+	}
+	for rows.Next() {
+		if len(result.Rows) == maxRows {
+			result.Truncated = true
+			break
+		}
+		values := make([]sql.NullString, len(result.Columns))
+		dest := make([]any, len(values))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return result, err
+		}
+		row := make([]*string, len(values))
+		for i, v := range values {
+			if v.Valid {
+				value := v.String
+				row[i] = &value
+			}
+		}
+		result.Rows = append(result.Rows, row)
+	}
+	if err := rows.Err(); err != nil {
+		return result, err
+	}
+	result.DurationMs = time.Since(start).Milliseconds()
+	return result, nil
+}
@@ -0,0 +1,53 @@
+package modules
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadOnlyQuery(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	query := "SELECT ID, USER, INFO FROM information_schema.processlist WHERE TIME > ?"
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(query)).
+		WithArgs("30").
+		WillReturnRows(sqlmock.NewRows([]string{"ID", "USER", "INFO"}).
+			AddRow(7, "app", "select 1").
+			AddRow(8, "app", nil).
+			AddRow(9, "report", "select 2"))
+	mock.ExpectRollback()
+
+	result, err := readOnlyQuery(context.Background(), db, "db-core-001", query, []any{"30"}, 2)
+	require.NoError(t, err)
+	assert.Equal(t, "db-core-001", result.Host)
+	assert.Equal(t, []string{"ID", "USER", "INFO"}, result.Columns)
+	require.Len(t, result.Rows, 2)
+	assert.Equal(t, "7", *result.Rows[0][0])
+	assert.Equal(t, "select 1", *result.Rows[0][2])
+	assert.Nil(t, result.Rows[1][2])
+	assert.True(t, result.Truncated)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReadOnlyQueryError(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SHOW ENGINE INNODB STATUS").WillReturnError(errors.New("Access denied; you need the PROCESS privilege"))
+	mock.ExpectRollback()
+
+	_, err = readOnlyQuery(context.Background(), db, "db-core-001", "SHOW ENGINE INNODB STATUS", nil, 10)
+	assert.ErrorContains(t, err, "PROCESS privilege")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -1,140 +1,207 @@
 package modules
 
 import (
-	"database/sql"
+	"context"
 	"fmt"
+	"net"
 	"strings"
 	"time"
 
 	"github.com/rs/zerolog/log"
 	"github.com/sullivtr/k8s_platform/internal/types"
-
-	_ "github.com/go-sql-driver/mysql"
 )
 
-const (
-	SHOW_SOURCE_QUERY         = "select HOST from performance_schema.replication_connection_configuration"
-	REPLICATION_RUNNING_QUERY = "SELECT SERVICE_STATE FROM performance_schema.replication_connection_status"
-	SHOW_USERS_QUERY          = "select DISTINCT USER from information_schema.processlist where USER not in ('system user', 'root', 'dbadmin', 'rdsadmin', 'event_scheduler')"
-	SHOW_DMS_REPLICAS         = "select distinct USER from information_schema.processlist where COMMAND='Binlog Dump' AND USER not in ('repl')"
-)
+// SHOW_BINLOG_CONSUMERS reads the distinct users and client hosts of the connections reading the binlog of a host
+const SHOW_BINLOG_CONSUMERS = "select distinct USER, SUBSTRING_INDEX(COALESCE(HOST, ''), ':', 1) from information_schema.processlist where COMMAND in ('Binlog Dump', 'Binlog Dump GTID')"
 
-// MySQLTopo represents a MySQL database topology module SDK
+// MySQLTopo represents a MySQL database topology module SDK.
+// MySQLDBPassword is used by databases without a credential reference, Secrets resolves secret references, and
+// CredentialFilesDir holds the files credentials may be read from.
+// Prober reads the replication status of a host, and connects to it over SQL when it is not set.
+// Consumers classifies the connections reading the binlog of the hosts. Resolver resolves the catalog hosts, so that
+// consumers connecting from one of them are classified as replicas, and net.DefaultResolver is used when it is not set.
+// Workers bounds how many hosts are probed at once. ConnectTimeout bounds connecting to a host, QueryTimeout bounds
+// the queries run on it, and CaptureTimeout bounds the whole capture.
 type MySQLTopoSDK struct {
-	MySQLDBPassword string
-	Databases       []*types.MySQLDBInfo
+	MySQLDBPassword    string
+	Secrets            SecretReader
+	CredentialFilesDir string
+	Databases          []*types.MySQLDBInfo
+	Prober             MySQLHostProber
+	Consumers          *types.MySQLConsumerClassifier
+	Resolver           HostResolver
+	Workers            int
+	ConnectTimeout     time.Duration
+	QueryTimeout       time.Duration
+	CaptureTimeout     time.Duration
+}
+
+// HostResolver looks up the addresses of a host, as net.Resolver does
+type HostResolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
 }
 
 // CaptureReplicationTopo captures the replication topology for the databases in the MySQLTopoSDK.
-// It performs the following steps:
-// 1. Initializes a map to store the short names and corresponding host:port pairs of the databases.
-// 2. Iterates over the databases to populate the map.
-// 3. For each database, it attempts to connect using the appropriate credentials.
-// 4. Checks for the replication source and updates the source if found.
-// 5. Checks if replication is running and updates the status accordingly.
-// 6. Checks for DMS connected users as replicas and adds them to the database's replica list.
-func (sdk *MySQLTopoSDK) CaptureReplicationTopo() {
+// Hosts are probed concurrently, by at most Workers probes, within CaptureTimeout. Each probe:
+// 1. Resolves the credential reference of the database and connects to it.
+// 2. Reads the replication channels, with their source, lag, last errors and GTID sets, and the executed GTID set.
+// 3. Derives the replication source and whether replication is running from the channels.
+// 4. Reads the connections reading the binlog of the database, which are classified by the Consumers classifier. DMS tasks
+// and CDC connectors are captured as consumers of the database, replicas and ignored consumers are not.
+//
+// The databases of the MySQLTopoSDK are not modified. Databases that could not be probed in time, or at all, are
+// captured with a ProbeError.
+func (sdk *MySQLTopoSDK) CaptureReplicationTopo(ctx context.Context) ReplTopoCapture {
+	captureTimeout := sdk.CaptureTimeout
+	if captureTimeout <= 0 {
+		captureTimeout = defaultTopoCaptureTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, captureTimeout)
+	defer cancel()
+
 	dbShortNameMap := make(map[string]string)
-	for _, db := range sdk.Databases {
+	databases := make([]*types.ReplDBInfo, len(sdk.Databases))
+	for i, db := range sdk.Databases {
 		dbShortNameMap[db.Shortname] = strings.Split(db.Host, ".")[0] + ":" + fmt.Sprintf("%d", db.Port)
+		databases[i] = db.TopologyNode()
 	}
 
-	for _, db := range sdk.Databases {
-		// TODO: Find a nicer way to get the password for differing DBs
-		log.Info().Msgf("checking replication topology for %s", db.Shortname)
-		dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/", db.Username, sdk.MySQLDBPassword, db.Host, db.Port)
-		connection, err := sql.Open("mysql", dsn)
-		if err != nil {
-			log.Warn().Msgf("Error connecting to database, %s: %v", db.Shortname, err)
-			continue
-		}
-		defer connection.Close()
+	// Each probe only writes its own index, so the results need no lock
+	consumers := make([][]types.MySQLBinlogConsumer, len(databases))
+	probed := probeConcurrently(ctx, len(databases), sdk.Workers, func(i int) {
+		consumers[i] = sdk.probeHost(ctx, sdk.Databases[i], databases[i], dbShortNameMap)
+	})
 
-		connection.SetConnMaxLifetime(time.Minute * 3)
-		connection.SetMaxOpenConns(10)
-		connection.SetMaxIdleConns(10)
-
-		// Check for replication source if there is one
-		var sourceHost sql.NullString
-		err = connection.QueryRow(SHOW_SOURCE_QUERY).Scan(&sourceHost)
-		if err != nil && err != sql.ErrNoRows {
-			log.Warn().Msgf("Error executing SHOW_SOURCE_QUERY on %s: %v", db.Shortname, err)
+	capture := ReplTopoCapture{Databases: databases, Consumers: []*types.ReplDBInfo{}}
+	catalogHosts := sdk.newCatalogHostSet(databases)
+	for i, db := range databases {
+		if !probed[i] {
+			db.ProbeError = fmt.Sprintf("not probed before the capture timed out: %v", ctx.Err())
+			log.Warn().Msgf("Error capturing the replication topology of %s: %s", db.Shortname, db.ProbeError)
 			continue
 		}
-		if sourceHost.Valid {
-			sourceHostName := strings.Split(sourceHost.String, ".")[0]
-			for k := range dbShortNameMap {
-				if strings.Contains(sourceHostName, k) {
-					log.Info().Msgf("source found for %s: %s", db.Shortname, sourceHostName)
-					db.SetSource(k)
-				}
+		uniqueConsumerNodes := make(map[string]bool)
+		for _, consumer := range consumers[i] {
+			consumer.CatalogHost = catalogHosts.contains(ctx, consumer.Host)
+			consumerType := sdk.Consumers.Classify(db.Shortname, consumer)
+			if consumerType == types.MySQLConsumerReplica || consumerType == types.MySQLConsumerIgnore || uniqueConsumerNodes[consumer.User] {
+				continue
 			}
+			uniqueConsumerNodes[consumer.User] = true
+			capture.Consumers = append(capture.Consumers, &types.ReplDBInfo{
+				Host:         consumer.User + "-" + consumerType,
+				Shortname:    consumer.User,
+				Source:       db.Shortname,
+				ConsumerType: consumerType,
+			})
 		}
+	}
+	capture.setReplicas()
 
-		// Check if replication is running
-		var replState sql.NullString
-		err = connection.QueryRow(REPLICATION_RUNNING_QUERY).Scan(&replState)
-		if err != nil && err != sql.ErrNoRows {
-			log.Warn().Msgf("Error executing REPLICATION_RUNNING_QUERY on %s: %v", db.Shortname, err)
-			continue
-		}
-		if replState.Valid {
-			log.Info().Msgf("replication running for %s: %s", db.Shortname, replState.String)
-			if replState.String == "OFF" {
-				db.SetReplicationRunning(false)
-			} else {
-				db.SetReplicationRunning(true)
-			}
-		}
+	return capture
+}
 
-		// Check for DMS connected users as replicas
-		rows, err := connection.Query(SHOW_DMS_REPLICAS)
-		if err != nil {
-			log.Warn().Msgf("Error executing SHOW_DMS_REPLICAS on %s: %v", db.Shortname, err)
-			continue
-		}
-		defer rows.Close()
+// catalogHostSet tells whether the client host of a consumer is a catalog host, by name or by address. The catalog
+// hosts are resolved concurrently, once a client host does not match them by name.
+type catalogHostSet struct {
+	resolver     HostResolver
+	queryTimeout time.Duration
+	workers      int
+	hosts        []string
+	names        map[string]bool
+	addrs        map[string]bool
+}
 
-		uniqueDMSReplicaNodes := make(map[string]bool)
-		for rows.Next() {
-			var replicaUser string
-			if err := rows.Scan(&replicaUser); err != nil {
-				log.Warn().Msgf("Error scanning row: %v", err)
-				continue
+// newCatalogHostSet returns the catalog host set of the databases, matching their hosts, the first label of their hosts
+// and their shortnames
+func (sdk *MySQLTopoSDK) newCatalogHostSet(databases []*types.ReplDBInfo) *catalogHostSet {
+	set := &catalogHostSet{resolver: sdk.Resolver, queryTimeout: sdk.QueryTimeout, workers: sdk.Workers, names: map[string]bool{}}
+	if set.resolver == nil {
+		set.resolver = net.DefaultResolver
+	}
+	if set.queryTimeout <= 0 {
+		set.queryTimeout = defaultTopoQueryTimeout
+	}
+	for _, db := range databases {
+		host := strings.ToLower(db.Host)
+		set.hosts = append(set.hosts, db.Host)
+		set.names[host] = true
+		set.names[strings.Split(host, ".")[0]] = true
+		set.names[strings.ToLower(db.Shortname)] = true
+	}
+	return set
+}
+
+// contains reports whether a client host is a catalog host. Catalog hosts that cannot be resolved only match by name.
+func (s *catalogHostSet) contains(ctx context.Context, host string) bool {
+	host = strings.ToLower(host)
+	if host == "" {
+		return false
+	}
+	if s.names[host] {
+		return true
+	}
+	if s.addrs == nil {
+		s.addrs = map[string]bool{}
+		resolved := make([][]string, len(s.hosts))
+		probeConcurrently(ctx, len(s.hosts), s.workers, func(i int) {
+			lookupCtx, cancel := context.WithTimeout(ctx, s.queryTimeout)
+			defer cancel()
+			addrs, err := s.resolver.LookupHost(lookupCtx, s.hosts[i])
+			if err != nil {
+				log.Warn().Msgf("Error resolving catalog host %s: %v", s.hosts[i], err)
 			}
-			if _, ok := uniqueDMSReplicaNodes[replicaUser]; !ok {
-				sdk.Databases = append(sdk.Databases, &types.MySQLDBInfo{
-					Host:      replicaUser + "-dms",
-					Shortname: replicaUser,
-					Source:    db.Shortname,
-				})
-				uniqueDMSReplicaNodes[replicaUser] = true
+			resolved[i] = addrs
+		})
+		for _, addrs := range resolved {
+			for _, addr := range addrs {
+				s.addrs[addr] = true
 			}
-
-			db.AddReplica(replicaUser)
-		}
-		if err := rows.Err(); err != nil {
-			log.Warn().Msgf("Error iterating rows: %v", err)
 		}
 	}
+	return s.addrs[host]
 }
 
-// SetDBReplicas sets the replicas for each database in the MySQLTopoSDK.
-// It performs the following steps:
-//  1. Iterates over each database in the sdk.Databases slice.
-//  2. For each database, it iterates over the other databases to find those that have the current database
-//     as their source and are hosted on different hosts.
-//  3. Adds the short name of the source database to the replica list of the current database.
-//  4. Logs the replicas for each database.
-//
-// This function helps in identifying and setting up the replication relationships between the databases.
-func (sdk *MySQLTopoSDK) SetDBReplicas() {
-	for _, db := range sdk.Databases {
-		for _, sdb := range sdk.Databases {
-			if sdb.Host != db.Host && sdb.Source == db.Shortname {
-				db.AddReplica(sdb.Shortname)
-			}
+// probeHost reads the replication status of a database into its node, and returns the connections reading its binlog.
+// Problems are logged and recorded as the ProbeError of the node.
+func (sdk *MySQLTopoSDK) probeHost(ctx context.Context, db *types.MySQLDBInfo, node *types.ReplDBInfo, dbShortNameMap map[string]string) []types.MySQLBinlogConsumer {
+	log.Info().Msgf("checking replication topology for %s", db.Shortname)
+	password, err := sdk.resolvePassword(ctx, db)
+	if err != nil {
+		node.ProbeError = err.Error()
+		log.Warn().Msgf("Error resolving the credential of %s: %v", db.Shortname, err)
+		return nil
+	}
+
+	probe, err := sdk.prober().Probe(ctx, *db, password)
+	if err != nil {
+		node.ProbeError = err.Error()
+		log.Warn().Msgf("Error reading the replication status of %s: %v", db.Shortname, err)
+		return nil
+	}
+
+	for i := range probe.Channels {
+		probe.Channels[i].Source = resolveSource(dbShortNameMap, probe.Channels[i].SourceHost)
+		if probe.Channels[i].Source != "" {
+			log.Info().Msgf("source found for %s: %s (channel %q, io %s, sql %s)", db.Shortname, probe.Channels[i].Source, probe.Channels[i].Name, probe.Channels[i].IOState, probe.Channels[i].SQLState)
 		}
-		log.Info().Msgf("Replicas for %s: %v", db.Shortname, db.Replicas)
 	}
+	node.SetReplicationChannels(probe.Channels)
+	node.ExecutedGTIDSet = probe.ExecutedGTIDSet
+	return probe.BinlogConsumers
+}
+
+// prober returns the prober of the SDK, or an SQL prober using its timeouts
+func (sdk *MySQLTopoSDK) prober() MySQLHostProber {
+	if sdk.Prober != nil {
+		return sdk.Prober
+	}
+	prober := sqlHostProber{connectTimeout: sdk.ConnectTimeout, queryTimeout: sdk.QueryTimeout}
+	if prober.connectTimeout <= 0 {
+		prober.connectTimeout = defaultTopoConnectTimeout
+	}
+	if prober.queryTimeout <= 0 {
+		prober.queryTimeout = defaultTopoQueryTimeout
+	}
+	return prober
 }
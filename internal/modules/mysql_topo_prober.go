@@ -0,0 +1,110 @@
+package modules
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/rs/zerolog/log"
+	"github.com/sullivtr/k8s_platform/internal/types"
+)
+
+// MySQLHostProbe is the replication status read from a MySQL host
+type MySQLHostProbe struct {
+	// Channels are the replication channels of the host, their Source is not resolved to a catalog shortname yet
+	Channels        []types.ReplChannel
+	ExecutedGTIDSet string
+	// BinlogConsumers are the connections reading the binlog of the host, replicas included
+	BinlogConsumers []types.MySQLBinlogConsumer
+}
+
+// MySQLHostProber reads the replication status of a MySQL host. Probe must return once the context is done.
+type MySQLHostProber interface {
+	Probe(ctx context.Context, db types.MySQLDBInfo, password string) (MySQLHostProbe, error)
+}
+
+// sqlHostProber probes MySQL hosts over a single connection, which is released as soon as the probe is done
+type sqlHostProber struct {
+	connectTimeout time.Duration
+	queryTimeout   time.Duration
+}
+
+// Probe connects to the host and reads its replication channels, executed GTID set and binlog consumers. Failing to read
+// the executed GTID set or the binlog consumers is logged, the channels are still returned.
+func (p sqlHostProber) Probe(ctx context.Context, db types.MySQLDBInfo, password string) (MySQLHostProbe, error) {
+	probe := MySQLHostProbe{}
+
+	dsnConfig := mysqlConfig(db, password, p.connectTimeout, p.queryTimeout)
+	// Replication timestamps are read in UTC
+	dsnConfig.ParseTime = true
+	dsnConfig.Loc = time.UTC
+	dsnConfig.Params = map[string]string{"time_zone": "'+00:00'"}
+	pool, err := sql.Open("mysql", dsnConfig.FormatDSN())
+	if err != nil {
+		return probe, fmt.Errorf("unable to open connection: %v", err)
+	}
+	defer pool.Close()
+
+	connectCtx, cancel := context.WithTimeout(ctx, p.connectTimeout)
+	defer cancel()
+	conn, err := pool.Conn(connectCtx)
+	if err != nil {
+		return probe, fmt.Errorf("unable to connect: %v", err)
+	}
+	defer conn.Close()
+
+	queryCtx, cancel := context.WithTimeout(ctx, p.queryTimeout)
+	defer cancel()
+
+	// Read the replication channels, their source, lag, errors and GTID sets
+	probe.Channels, err = readReplicationChannels(queryCtx, conn)
+	if err != nil {
+		return probe, err
+	}
+
+	probe.ExecutedGTIDSet, err = readExecutedGTIDSet(queryCtx, conn)
+	if err != nil {
+		log.Warn().Msgf("Error reading the executed gtid set of %s: %v", db.Shortname, err)
+	}
+
+	probe.BinlogConsumers, err = readBinlogConsumers(queryCtx, conn)
+	if err != nil {
+		log.Warn().Msgf("Error executing SHOW_BINLOG_CONSUMERS on %s: %v", db.Shortname, err)
+	}
+
+	return probe, nil
+}
+
+// mysqlConfig returns the connection settings of a catalog host.
+// FormatDSN escapes the password, which may hold any character once it comes from a secret.
+func mysqlConfig(db types.MySQLDBInfo, password string, connectTimeout, readTimeout time.Duration) *mysql.Config {
+	dsnConfig := mysql.NewConfig()
+	dsnConfig.User = db.Username
+	dsnConfig.Passwd = password
+	dsnConfig.Net = "tcp"
+	dsnConfig.Addr = fmt.Sprintf("%s:%d", db.Host, db.Port)
+	dsnConfig.Timeout = connectTimeout
+	dsnConfig.ReadTimeout = readTimeout
+	return dsnConfig
+}
+
+// readBinlogConsumers reads the distinct users and client hosts of the connections reading the binlog of a host
+func readBinlogConsumers(ctx context.Context, connection queryer) ([]types.MySQLBinlogConsumer, error) {
+	rows, err := connection.QueryContext(ctx, SHOW_BINLOG_CONSUMERS)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	consumers := []types.MySQLBinlogConsumer{}
+	for rows.Next() {
+		var consumer types.MySQLBinlogConsumer
+		if err := rows.Scan(&consumer.User, &consumer.Host); err != nil {
+			return consumers, err
+		}
+		consumers = append(consumers, consumer)
+	}
+	return consumers, rows.Err()
+}
@@ -0,0 +1,175 @@
+package modules
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/sullivtr/k8s_platform/internal/types"
+)
+
+const (
+	// REPLICATION_CONNECTION_STATUS_QUERY reads the receiver (IO) thread of every replication channel
+	REPLICATION_CONNECTION_STATUS_QUERY = `SELECT cc.CHANNEL_NAME, cc.HOST, cs.SERVICE_STATE, cs.RECEIVED_TRANSACTION_SET, cs.LAST_ERROR_NUMBER, cs.LAST_ERROR_MESSAGE,
+IF(cs.LAST_ERROR_NUMBER = 0, NULL, cs.LAST_ERROR_TIMESTAMP)
+FROM performance_schema.replication_connection_configuration cc
+LEFT JOIN performance_schema.replication_connection_status cs ON cs.CHANNEL_NAME = cc.CHANNEL_NAME
+ORDER BY cc.CHANNEL_NAME`
+	// REPLICATION_APPLIER_STATUS_QUERY reads the applier (SQL) workers of every replication channel. The lag of a worker
+	// is how long ago the transaction it applies was committed on the original source, 0 when it is idle.
+	REPLICATION_APPLIER_STATUS_QUERY = `SELECT CHANNEL_NAME, SERVICE_STATE, LAST_ERROR_NUMBER, LAST_ERROR_MESSAGE,
+IF(LAST_ERROR_NUMBER = 0, NULL, LAST_ERROR_TIMESTAMP),
+IF(APPLYING_TRANSACTION = '', 0, TIMESTAMPDIFF(SECOND, APPLYING_TRANSACTION_ORIGINAL_COMMIT_TIMESTAMP, NOW(6)))
+FROM performance_schema.replication_applier_status_by_worker`
+	// REPLICATION_APPLIER_STATUS_57_QUERY reads the applier workers of MySQL 5.7 hosts, whose workers do not record the
+	// transaction they apply, so their lag is unknown
+	REPLICATION_APPLIER_STATUS_57_QUERY = `SELECT CHANNEL_NAME, SERVICE_STATE, LAST_ERROR_NUMBER, LAST_ERROR_MESSAGE,
+IF(LAST_ERROR_NUMBER = 0, NULL, LAST_ERROR_TIMESTAMP), NULL
+FROM performance_schema.replication_applier_status_by_worker`
+	EXECUTED_GTID_SET_QUERY = "SELECT @@GLOBAL.gtid_executed"
+)
+
+// mysqlErrBadField is the error number of queries selecting an unknown column
+const mysqlErrBadField = 1054
+
+// queryer runs queries on a MySQL host, through a *sql.Conn or a *sql.DB
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// readReplicationChannels reads the status of the replication channels of a host. Hosts that do not replicate have none.
+func readReplicationChannels(ctx context.Context, connection queryer) ([]types.ReplChannel, error) {
+	rows, err := connection.QueryContext(ctx, REPLICATION_CONNECTION_STATUS_QUERY)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the replication connection status : %v", err)
+	}
+	defer rows.Close()
+
+	channels := []types.ReplChannel{}
+	for rows.Next() {
+		var (
+			channel          types.ReplChannel
+			ioState, gtidSet sql.NullString
+			errNumber        sql.NullInt64
+			errMessage       sql.NullString
+			errTimestamp     sql.NullTime
+		)
+		if err := rows.Scan(&channel.Name, &channel.SourceHost, &ioState, &gtidSet, &errNumber, &errMessage, &errTimestamp); err != nil {
+			return nil, fmt.Errorf("unable to read the replication connection status : %v", err)
+		}
+		channel.IOState = ioState.String
+		if !ioState.Valid {
+			channel.IOState = "OFF"
+		}
+		channel.ReceivedGTIDSet = strings.ReplaceAll(gtidSet.String, "\n", "")
+		channel.LastIOError = replicationError(errNumber, errMessage, errTimestamp)
+		channels = append(channels, channel)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("unable to read the replication connection status : %v", err)
+	}
+	if len(channels) == 0 {
+		return channels, nil
+	}
+
+	workers, err := queryApplierStatus(ctx, connection)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the replication applier status : %v", err)
+	}
+	defer workers.Close()
+
+	byName := map[string]*types.ReplChannel{}
+	for i := range channels {
+		byName[channels[i].Name] = &channels[i]
+	}
+	for workers.Next() {
+		var (
+			name, state  string
+			errNumber    sql.NullInt64
+			errMessage   sql.NullString
+			errTimestamp sql.NullTime
+			lag          sql.NullInt64
+		)
+		if err := workers.Scan(&name, &state, &errNumber, &errMessage, &errTimestamp, &lag); err != nil {
+			return nil, fmt.Errorf("unable to read the replication applier status : %v", err)
+		}
+		channel, ok := byName[name]
+		if !ok {
+			continue
+		}
+
+		// A channel applies transactions when every worker does
+		if channel.SQLState == "" || channel.SQLState == "ON" {
+			channel.SQLState = state
+		}
+		if workerErr := replicationError(errNumber, errMessage, errTimestamp); workerErr != nil &&
+			(channel.LastSQLError == nil || workerErr.Timestamp.After(channel.LastSQLError.Timestamp)) {
+			channel.LastSQLError = workerErr
+		}
+		if lag.Valid && (channel.SecondsBehindSource == nil || lag.Int64 > *channel.SecondsBehindSource) {
+			workerLag := max(lag.Int64, 0)
+			channel.SecondsBehindSource = &workerLag
+		}
+	}
+	if err := workers.Err(); err != nil {
+		return nil, fmt.Errorf("unable to read the replication applier status : %v", err)
+	}
+
+	for i := range channels {
+		if channels[i].SQLState == "" {
+			channels[i].SQLState = "OFF"
+		}
+		if !channels[i].Running() {
+			channels[i].SecondsBehindSource = nil
+		}
+	}
+	return channels, nil
+}
+
+// queryApplierStatus queries the applier workers of a host. Hosts older than MySQL 8.0 are queried without the lag of
+// their workers, which they lack the columns for.
+func queryApplierStatus(ctx context.Context, connection queryer) (*sql.Rows, error) {
+	workers, err := connection.QueryContext(ctx, REPLICATION_APPLIER_STATUS_QUERY)
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlErrBadField {
+		return connection.QueryContext(ctx, REPLICATION_APPLIER_STATUS_57_QUERY)
+	}
+	return workers, err
+}
+
+// readExecutedGTIDSet reads the GTID set executed by a host
+func readExecutedGTIDSet(ctx context.Context, connection queryer) (string, error) {
+	var gtidSet sql.NullString
+	if err := connection.QueryRowContext(ctx, EXECUTED_GTID_SET_QUERY).Scan(&gtidSet); err != nil {
+		return "", fmt.Errorf("unable to read the executed gtid set : %v", err)
+	}
+	return strings.ReplaceAll(gtidSet.String, "\n", ""), nil
+}
+
+func replicationError(number sql.NullInt64, message sql.NullString, timestamp sql.NullTime) *types.ReplChannelError {
+	if !number.Valid || number.Int64 == 0 {
+		return nil
+	}
+	replErr := &types.ReplChannelError{Number: int(number.Int64), Message: message.String}
+	if timestamp.Valid {
+		replErr.Timestamp = timestamp.Time.UTC()
+	}
+	return replErr
+}
+
+// resolveSource returns the catalog shortname of a replication source host. Hosts are matched on their first dns label,
+// the longest matching shortname wins.
+func resolveSource(shortnames map[string]string, sourceHost string) string {
+	sourceHostName := strings.Split(sourceHost, ".")[0]
+	source := ""
+	for shortname := range shortnames {
+		if strings.Contains(sourceHostName, shortname) && len(shortname) > len(source) {
+			source = shortname
+		}
+	}
+	return source
+}
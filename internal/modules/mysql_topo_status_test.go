@@ -0,0 +1,91 @@
+package modules
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadReplicationChannels(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	ioErrorAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(regexp.QuoteMeta(REPLICATION_CONNECTION_STATUS_QUERY)).
+		WillReturnRows(sqlmock.NewRows([]string{"CHANNEL_NAME", "HOST", "SERVICE_STATE", "RECEIVED_TRANSACTION_SET", "LAST_ERROR_NUMBER", "LAST_ERROR_MESSAGE", "LAST_ERROR_TIMESTAMP"}).
+			AddRow("", "db-core-001.example.com", "ON", "3e11fa47-71ca-11e1-9e33-c80aa9429562:1-5,\n4f22fb58:1-2", 0, "", nil).
+			AddRow("analytics", "db-analytics-001.example.com", "CONNECTING", "", 2003, "error connecting to source", ioErrorAt))
+	mock.ExpectQuery(regexp.QuoteMeta(REPLICATION_APPLIER_STATUS_QUERY)).
+		WillReturnRows(sqlmock.NewRows([]string{"CHANNEL_NAME", "SERVICE_STATE", "LAST_ERROR_NUMBER", "LAST_ERROR_MESSAGE", "LAST_ERROR_TIMESTAMP", "LAG"}).
+			AddRow("", "ON", 0, "", nil, 3).
+			AddRow("", "ON", 0, "", nil, 12).
+			AddRow("analytics", "ON", 0, "", nil, 0))
+
+	channels, err := readReplicationChannels(context.Background(), db)
+	require.NoError(t, err)
+	require.Len(t, channels, 2)
+
+	assert.Equal(t, "db-core-001.example.com", channels[0].SourceHost)
+	assert.True(t, channels[0].Healthy())
+	require.NotNil(t, channels[0].SecondsBehindSource)
+	assert.Equal(t, int64(12), *channels[0].SecondsBehindSource)
+	assert.Equal(t, "3e11fa47-71ca-11e1-9e33-c80aa9429562:1-5,4f22fb58:1-2", channels[0].ReceivedGTIDSet)
+
+	assert.Equal(t, "analytics", channels[1].Name)
+	assert.False(t, channels[1].Healthy())
+	assert.Nil(t, channels[1].SecondsBehindSource, "lag is unknown while the receiver is not running")
+	require.NotNil(t, channels[1].LastIOError)
+	assert.Equal(t, 2003, channels[1].LastIOError.Number)
+	assert.Equal(t, ioErrorAt, channels[1].LastIOError.Timestamp)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReadReplicationChannelsMySQL57(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta(REPLICATION_CONNECTION_STATUS_QUERY)).
+		WillReturnRows(sqlmock.NewRows([]string{"CHANNEL_NAME", "HOST", "SERVICE_STATE", "RECEIVED_TRANSACTION_SET", "LAST_ERROR_NUMBER", "LAST_ERROR_MESSAGE", "LAST_ERROR_TIMESTAMP"}).
+			AddRow("", "db-core-001.example.com", "ON", "", 0, "", nil))
+	mock.ExpectQuery(regexp.QuoteMeta(REPLICATION_APPLIER_STATUS_QUERY)).
+		WillReturnError(&mysql.MySQLError{Number: 1054, Message: "Unknown column 'APPLYING_TRANSACTION' in 'field list'"})
+	mock.ExpectQuery(regexp.QuoteMeta(REPLICATION_APPLIER_STATUS_57_QUERY)).
+		WillReturnRows(sqlmock.NewRows([]string{"CHANNEL_NAME", "SERVICE_STATE", "LAST_ERROR_NUMBER", "LAST_ERROR_MESSAGE", "LAST_ERROR_TIMESTAMP", "LAG"}).
+			AddRow("", "ON", 0, "", nil, nil))
+
+	channels, err := readReplicationChannels(context.Background(), db)
+	require.NoError(t, err)
+	require.Len(t, channels, 1)
+	assert.True(t, channels[0].Healthy())
+	assert.Nil(t, channels[0].SecondsBehindSource, "5.7 workers have no lag")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReadReplicationChannelsOfSource(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta(REPLICATION_CONNECTION_STATUS_QUERY)).
+		WillReturnRows(sqlmock.NewRows([]string{"CHANNEL_NAME", "HOST", "SERVICE_STATE", "RECEIVED_TRANSACTION_SET", "LAST_ERROR_NUMBER", "LAST_ERROR_MESSAGE", "LAST_ERROR_TIMESTAMP"}))
+
+	channels, err := readReplicationChannels(context.Background(), db)
+	require.NoError(t, err)
+	assert.Empty(t, channels)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestResolveSource(t *testing.T) {
+	shortnames := map[string]string{"db-core-1": "db-core-1:3306", "db-core-10": "db-core-10:3306"}
+	assert.Equal(t, "db-core-10", resolveSource(shortnames, "db-core-10.example.com"))
+	assert.Equal(t, "db-core-1", resolveSource(shortnames, "db-core-1.example.com"))
+	assert.Equal(t, "", resolveSource(shortnames, "db-other.example.com"))
+}
@@ -0,0 +1,175 @@
+package modules
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/sullivtr/k8s_platform/internal/types"
+)
+
+// fakeHostProber answers probes from a map of host probes. Hosts listed in hang block until the probe context is done.
+// When concurrent is set, probes block until that many probes were in flight at once.
+type fakeHostProber struct {
+	probes     map[string]MySQLHostProbe
+	hang       map[string]bool
+	concurrent int32
+	inFlight   atomic.Int32
+	maxMu      sync.Mutex
+	max        int32
+	reached    chan struct{}
+	reachOnce  sync.Once
+}
+
+func (f *fakeHostProber) Probe(ctx context.Context, db types.MySQLDBInfo, _ string) (MySQLHostProbe, error) {
+	n := f.inFlight.Add(1)
+	defer f.inFlight.Add(-1)
+	f.maxMu.Lock()
+	if n > f.max {
+		f.max = n
+	}
+	f.maxMu.Unlock()
+
+	if f.hang[db.Host] {
+		<-ctx.Done()
+		return MySQLHostProbe{}, ctx.Err()
+	}
+	if f.concurrent > 0 {
+		if n >= f.concurrent {
+			f.reachOnce.Do(func() { close(f.reached) })
+		}
+		select {
+		case <-f.reached:
+		case <-ctx.Done():
+			return MySQLHostProbe{}, ctx.Err()
+		}
+	}
+	return f.probes[db.Host], nil
+}
+
+// fakeResolver resolves hosts from a map of addresses
+type fakeResolver map[string][]string
+
+func (f fakeResolver) LookupHost(_ context.Context, host string) ([]string, error) {
+	if addrs, ok := f[host]; ok {
+		return addrs, nil
+	}
+	return nil, fmt.Errorf("no such host: %s", host)
+}
+
+func channelFrom(host string) []types.ReplChannel {
+	return []types.ReplChannel{{SourceHost: host, IOState: "ON", SQLState: "ON"}}
+}
+
+func TestCaptureReplicationTopoManyHosts(t *testing.T) {
+	const hosts = 300
+	prober := &fakeHostProber{probes: map[string]MySQLHostProbe{}, concurrent: 50, reached: make(chan struct{})}
+	databases := []*types.MySQLDBInfo{}
+	for i := 0; i < hosts; i++ {
+		host := fmt.Sprintf("db-%03d.example.com", i)
+		databases = append(databases, &types.MySQLDBInfo{Host: host, Shortname: fmt.Sprintf("db-%03d", i), Port: 3306})
+		if i > 0 {
+			prober.probes[host] = MySQLHostProbe{Channels: channelFrom("db-000")}
+		}
+	}
+	prober.probes["db-000.example.com"] = MySQLHostProbe{BinlogConsumers: []types.MySQLBinlogConsumer{
+		{User: "cdc", Host: "10.0.0.1"}, {User: "cdc", Host: "10.0.0.2"}, {User: "analytics", Host: "10.0.1.1"},
+		{User: "repl", Host: "10.0.2.1"}, {User: "fivetran", Host: "35.1.1.1"}, {User: "rdsrepladmin", Host: "10.0.2.2"},
+		{User: "gtid_repl", Host: "10.0.3.7"}, {User: "gtid_repl", Host: "DB-005"},
+	}}
+
+	rules := []types.MySQLConsumerRule{
+		{Name: "debezium", User: "cdc", Host: `10\.0\.0\.\d+`, Type: types.MySQLConsumerCDC},
+		{Name: "fivetran", User: "fivetran", Type: types.MySQLConsumerIgnore},
+	}
+	sdk := &MySQLTopoSDK{MySQLDBPassword: "password", Databases: databases, Prober: prober, Workers: 50,
+		Consumers: types.NewMySQLConsumerClassifier(rules), Resolver: fakeResolver{"db-007.example.com": {"10.0.3.7"}}}
+	capture := sdk.CaptureReplicationTopo(context.Background())
+
+	// Probes only return once 50 of them run at once, so every host is probed only when the workers run concurrently
+	assert.Equal(t, int32(50), prober.max)
+	require.Len(t, capture.Databases, hosts)
+	for _, db := range capture.Databases {
+		require.Empty(t, db.ProbeError, db.Shortname)
+	}
+
+	primary := capture.Databases[0]
+	assert.Len(t, primary.Replicas, hosts-1+2)
+	assert.Equal(t, "db-000", capture.Databases[7].Source)
+	assert.True(t, capture.Databases[7].ReplicationRunning)
+
+	// Consumers are captured separately, once per user, and the catalog databases are left untouched. Replicas, which
+	// includes consumers connecting from catalog hosts, and ignored consumers are not captured.
+	require.Len(t, capture.Consumers, 2)
+	assert.Equal(t, "cdc", capture.Consumers[0].Shortname)
+	assert.Equal(t, "cdc-cdc", capture.Consumers[0].Host)
+	assert.Equal(t, types.MySQLConsumerCDC, capture.Consumers[0].ConsumerType)
+	assert.Equal(t, "db-000", capture.Consumers[0].Source)
+	assert.Equal(t, types.MySQLConsumerDMS, capture.Consumers[1].ConsumerType)
+	assert.Len(t, sdk.Databases, hosts)
+}
+
+func TestCaptureReplicationTopoSlowHost(t *testing.T) {
+	databases := func() []*types.MySQLDBInfo {
+		return []*types.MySQLDBInfo{
+			{Host: "db-1.example.com", Shortname: "db-1", Port: 3306},
+			{Host: "db-2.example.com", Shortname: "db-2", Port: 3306},
+			{Host: "db-3.example.com", Shortname: "db-3", Port: 3306},
+			{Host: "db-4.example.com", Shortname: "db-4", Port: 3306},
+		}
+	}
+	sdk := &MySQLTopoSDK{
+		MySQLDBPassword: "password",
+		Databases:       databases(),
+		Prober: &fakeHostProber{
+			probes: map[string]MySQLHostProbe{"db-2.example.com": {Channels: channelFrom("db-1")}},
+			hang:   map[string]bool{"db-3.example.com": true},
+		},
+		Workers:        4,
+		CaptureTimeout: 100 * time.Millisecond,
+	}
+
+	// The hanging host only returns once the capture times out, and does not hold back the others
+	capture := sdk.CaptureReplicationTopo(context.Background())
+	assert.Empty(t, capture.Databases[0].ProbeError)
+	assert.Equal(t, "db-1", capture.Databases[1].Source)
+	assert.Empty(t, capture.Databases[3].ProbeError)
+	assert.Contains(t, capture.Databases[2].ProbeError, "deadline exceeded")
+	assert.Equal(t, []string{"db-2"}, capture.Databases[0].Replicas)
+
+	// Hosts still queued when the capture times out are not probed
+	sdk.Databases = databases()
+	sdk.Workers = 1
+	sdk.Prober = &fakeHostProber{hang: map[string]bool{
+		"db-1.example.com": true, "db-2.example.com": true, "db-3.example.com": true, "db-4.example.com": true,
+	}}
+	capture = sdk.CaptureReplicationTopo(context.Background())
+	notProbed := 0
+	for _, db := range capture.Databases {
+		require.NotEmpty(t, db.ProbeError)
+		if strings.HasPrefix(db.ProbeError, "not probed before the capture timed out") {
+			notProbed++
+		}
+	}
+	assert.Equal(t, 3, notProbed)
+}
+
+func TestCaptureReplicationTopoCredentialError(t *testing.T) {
+	sdk := &MySQLTopoSDK{
+		Databases: []*types.MySQLDBInfo{{
+			Host: "db-1.example.com", Shortname: "db-1", Port: 3306,
+			Credential: types.CredentialRef{Source: types.CredentialSourceEnv, EnvVar: "KHUB_TEST_UNSET_PASSWORD"},
+		}},
+		Prober: &fakeHostProber{},
+	}
+
+	capture := sdk.CaptureReplicationTopo(context.Background())
+	assert.NotEmpty(t, capture.Databases[0].ProbeError)
+	assert.Empty(t, capture.Consumers)
+}
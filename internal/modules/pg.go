@@ -30,6 +30,35 @@ func InitPGDB(autoMigrate bool, host, username, password, dbName, environment st
 	return PGSDK{db: db}
 }
 
+// NewPGSDK creates a PGSDK from an existing gorm connection
+func NewPGSDK(db *gorm.DB) PGSDK {
+	return PGSDK{db: db}
+}
+
+// softDelete will soft delete the record of the given model with the given ID
+func softDelete(db *gorm.DB, model any, id uuid.UUID) error {
+	results := db.Where("id = ?", id).Delete(model)
+	if results.Error != nil {
+		return results.Error
+	}
+	if results.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// restore will clear the soft delete marker of the record of the given model with the given ID
+func restore(db *gorm.DB, model any, id uuid.UUID) error {
+	results := db.Unscoped().Model(model).Where("id = ? AND deleted_at IS NOT NULL", id).Update("deleted_at", nil)
+	if results.Error != nil {
+		return results.Error
+	}
+	if results.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
 func migrate(db *gorm.DB, environment string) {
 	if err := db.AutoMigrate(
 		&types.Group{},
@@ -38,7 +67,14 @@ func migrate(db *gorm.DB, environment string) {
 		&types.GroupPermissions{},
 		&types.GroupUsers{},
 		&types.MySQLDBInfo{},
-		&types.DynamicAppConfig{}); err != nil {
+		&types.MySQLDiscoveryProposal{},
+		&types.PostgresDBInfo{},
+		&types.ReplTopoSnapshot{},
+		&types.GraphNodePosition{},
+		&types.DynamicAppConfig{},
+		&types.DynamicAppConfigVersion{},
+		&types.APIToken{},
+		&types.AuditEvent{}); err != nil {
 		log.Fatalln(err)
 	}
 
@@ -104,6 +140,20 @@ func migrate(db *gorm.DB, environment string) {
 		}
 	}
 
+	// Seed the version history with the current dynamic app config
+	if db.Migrator().HasTable(&types.DynamicAppConfigVersion{}) {
+		if err := db.First(&types.DynamicAppConfigVersion{}).Error; errors.Is(err, gorm.ErrRecordNotFound) {
+			current := types.DynamicAppConfig{}
+			if err := db.Where("id = 1").First(&current).Error; err != nil {
+				log.Fatalln(err)
+			}
+			result := db.Create(&types.DynamicAppConfigVersion{Version: current.Version, Data: current.Data, Author: "system"})
+			if result.Error != nil {
+				log.Fatalln(result.Error)
+			}
+		}
+	}
+
 	// create the hostdash tester user if the environment is development
 	if environment == "Development" {
 		uid, err := uuid.Parse("1b434611-5fe8-4ed0-b0b4-1307f9945b34")
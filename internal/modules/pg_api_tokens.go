@@ -0,0 +1,47 @@
+package modules
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sullivtr/k8s_platform/internal/types"
+)
+
+// GetAPITokensByUser will fetch all active (non-revoked) api tokens owned by a user
+func (sdk *PGSDK) GetAPITokensByUser(userID uuid.UUID) ([]types.APIToken, error) {
+	tokens := []types.APIToken{}
+	results := sdk.db.Where("user_id = ?", userID).Order("created_at desc").Find(&tokens)
+	return tokens, results.Error
+}
+
+// GetAPITokenByHash will fetch a single active (non-revoked) api token by its hash
+func (sdk *PGSDK) GetAPITokenByHash(tokenHash string) (types.APIToken, error) {
+	token := types.APIToken{}
+	results := sdk.db.Where("token_hash = ?", tokenHash).First(&token)
+	return token, results.Error
+}
+
+// GetAPIToken will fetch a single active (non-revoked) api token by its ID
+func (sdk *PGSDK) GetAPIToken(tokenID uuid.UUID) (types.APIToken, error) {
+	token := types.APIToken{}
+	results := sdk.db.Where("id = ?", tokenID).First(&token)
+	return token, results.Error
+}
+
+// CreateAPIToken will create a new api token
+func (sdk *PGSDK) CreateAPIToken(token types.APIToken) (*types.APIToken, error) {
+	if err := sdk.db.Create(&token).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// RevokeAPIToken will revoke an api token. The token is soft deleted so it remains available for auditing.
+func (sdk *PGSDK) RevokeAPIToken(tokenID uuid.UUID) error {
+	return sdk.db.Where("id = ?", tokenID).Delete(&types.APIToken{}).Error
+}
+
+// TouchAPIToken will update the last used time of an api token
+func (sdk *PGSDK) TouchAPIToken(tokenID uuid.UUID) error {
+	return sdk.db.Model(&types.APIToken{}).Where("id = ?", tokenID).Update("last_used", time.Now()).Error
+}
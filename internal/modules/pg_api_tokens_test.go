@@ -0,0 +1,66 @@
+package modules
+
+import (
+	"regexp"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+)
+
+func (s *PGSuite) TestGetAPITokensByUser() {
+	sdk := PGSDK{db: s.DB}
+	tid := uuid.New()
+	uid := uuid.New()
+	expiresAt := time.Now().Add(24 * time.Hour)
+
+	s.mock.ExpectQuery(regexp.QuoteMeta(
+		`SELECT * FROM "api_tokens" WHERE user_id = $1 AND "api_tokens"."deleted_at" IS NULL ORDER BY created_at desc`)).
+		WithArgs(uid).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "user_id", "token_hash", "hint", "scopes", "expires_at"}).
+			AddRow(tid, "ci", uid, "hash", "khub_abcd", `["read"]`, expiresAt))
+
+	resp, err := sdk.GetAPITokensByUser(uid)
+	s.NoError(err, "unexpected error while fetching api tokens")
+
+	s.Equal(*resp[0].ID, tid)
+	s.Equal(resp[0].Name, "ci")
+	s.Equal(resp[0].UserID, uid)
+	s.Equal(resp[0].Scopes, []string{"read"})
+	s.True(resp[0].HasScope("read"))
+	s.False(resp[0].HasScope("write"))
+}
+
+func (s *PGSuite) TestGetAPITokenByHash() {
+	sdk := PGSDK{db: s.DB}
+	tid := uuid.New()
+	uid := uuid.New()
+
+	s.mock.ExpectQuery(regexp.QuoteMeta(
+		`SELECT * FROM "api_tokens" WHERE token_hash = $1 AND "api_tokens"."deleted_at" IS NULL ORDER BY "api_tokens"."id" LIMIT $2`)).
+		WithArgs("hash", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "user_id", "token_hash", "scopes", "expires_at"}).
+			AddRow(tid, "ci", uid, "hash", `["read","write"]`, time.Now().Add(-time.Hour)))
+
+	resp, err := sdk.GetAPITokenByHash("hash")
+	s.NoError(err, "unexpected error while fetching api token by hash")
+
+	s.Equal(*resp.ID, tid)
+	s.True(resp.HasScope("write"))
+	s.True(resp.IsExpired())
+}
+
+func (s *PGSuite) TestRevokeAPIToken() {
+	sdk := PGSDK{db: s.DB}
+	tid := uuid.New()
+
+	s.mock.ExpectBegin()
+	s.mock.ExpectExec(regexp.QuoteMeta(
+		`UPDATE "api_tokens" SET "deleted_at"=$1 WHERE id = $2 AND "api_tokens"."deleted_at" IS NULL`)).
+		WithArgs(sqlmock.AnyArg(), tid).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	s.mock.ExpectCommit()
+
+	err := sdk.RevokeAPIToken(tid)
+	s.NoError(err, "unexpected error while revoking api token")
+}
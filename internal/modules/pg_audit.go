@@ -0,0 +1,21 @@
+package modules
+
+import (
+	"github.com/sullivtr/k8s_platform/internal/types"
+)
+
+// CreateAuditEvent will record an audit event
+func (sdk *PGSDK) CreateAuditEvent(event types.AuditEvent) error {
+	return sdk.db.Create(&event).Error
+}
+
+// GetAuditEvents will fetch the most recent audit events, optionally filtered by resource type
+func (sdk *PGSDK) GetAuditEvents(resourceType string, limit int) ([]types.AuditEvent, error) {
+	events := []types.AuditEvent{}
+	query := sdk.db.Order("created_at desc").Limit(limit)
+	if resourceType != "" {
+		query = query.Where("resource_type = ?", resourceType)
+	}
+	results := query.Find(&events)
+	return events, results.Error
+}
@@ -0,0 +1,47 @@
+package modules
+
+import (
+	"regexp"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/sullivtr/k8s_platform/internal/types"
+)
+
+func (s *PGSuite) TestCreateAuditEvent() {
+	sdk := PGSDK{db: s.DB}
+	eid := uuid.New()
+
+	s.mock.ExpectBegin()
+	s.mock.ExpectQuery(regexp.QuoteMeta(
+		`INSERT INTO "audit_events" ("actor","action","resource_type","resource_id","resource_name","detail","created_at") VALUES ($1,$2,$3,$4,$5,$6,$7) RETURNING "id"`)).
+		WithArgs("admin", types.AuditActionDelete, types.AuditResourceUser, eid.String(), "jdoe", "deleted user", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(uuid.New()))
+	s.mock.ExpectCommit()
+
+	err := sdk.CreateAuditEvent(types.AuditEvent{
+		Actor:        "admin",
+		Action:       types.AuditActionDelete,
+		ResourceType: types.AuditResourceUser,
+		ResourceID:   eid.String(),
+		ResourceName: "jdoe",
+		Detail:       "deleted user",
+	})
+	s.NoError(err, "unexpected error while creating audit event")
+}
+
+func (s *PGSuite) TestGetAuditEvents() {
+	sdk := PGSDK{db: s.DB}
+	eid := uuid.New()
+
+	s.mock.ExpectQuery(regexp.QuoteMeta(
+		`SELECT * FROM "audit_events" WHERE resource_type = $1 ORDER BY created_at desc LIMIT $2`)).
+		WithArgs(types.AuditResourceGroup, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "actor", "action", "resource_type"}).
+			AddRow(eid, "admin", types.AuditActionCreate, types.AuditResourceGroup))
+
+	resp, err := sdk.GetAuditEvents(types.AuditResourceGroup, 10)
+	s.NoError(err, "unexpected error while fetching audit events")
+	s.Equal(eid, *resp[0].ID)
+	s.Equal("admin", resp[0].Actor)
+}
@@ -1,11 +1,21 @@
 package modules
 
 import (
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/sullivtr/k8s_platform/internal/types"
+	"gorm.io/gorm"
 )
 
+// ErrDynamicAppConfigVersionConflict is returned when the dynamic app config was updated since the version an update is based on
+var ErrDynamicAppConfigVersionConflict = errors.New("dynamic app config version conflict")
+
+// ErrInvalidDynamicAppConfig is returned when the dynamic app config being saved is not valid
+var ErrInvalidDynamicAppConfig = errors.New("invalid dynamic app config")
+
+// GetDynamicAppConfig will fetch the dynamic app config
 func (sdk *PGSDK) GetDynamicAppConfig() (types.DynamicAppConfig, error) {
 	config := types.DynamicAppConfig{}
 	// There will only ever be one record for dynamic app config
@@ -15,16 +25,93 @@ func (sdk *PGSDK) GetDynamicAppConfig() (types.DynamicAppConfig, error) {
 	return config, nil
 }
 
-// GetDynamicAppConfig will fetch the dynamic app config
+// UpdateDynamicAppConfig will update the dynamic app config and store the result as a new version.
+// The config version must be the current version, otherwise ErrDynamicAppConfigVersionConflict is returned. Invalid
+// configs are rejected with ErrInvalidDynamicAppConfig.
 func (sdk *PGSDK) UpdateDynamicAppConfig(config types.DynamicAppConfig) (types.DynamicAppConfig, error) {
 	// There will only ever be one record for dynamic app config
 	// If an attempt is made to update the wrong record, return an error
 	if config.ID != 1 {
 		return types.DynamicAppConfig{}, fmt.Errorf("invalid dynamic app config ID: %v", config.ID)
 	}
+	if valid, errMsg := config.Data.IsValid(); !valid {
+		return types.DynamicAppConfig{}, fmt.Errorf("%w: %s", ErrInvalidDynamicAppConfig, errMsg)
+	}
+
+	err := sdk.db.Transaction(func(tx *gorm.DB) error {
+		return saveDynamicAppConfigVersion(tx, &config, 0)
+	})
+	if err != nil {
+		return types.DynamicAppConfig{}, err
+	}
+	return config, nil
+}
+
+// GetDynamicAppConfigVersions will fetch the version history of the dynamic app config, newest first.
+// The config data is not included.
+func (sdk *PGSDK) GetDynamicAppConfigVersions() ([]types.DynamicAppConfigVersion, error) {
+	versions := []types.DynamicAppConfigVersion{}
+	results := sdk.db.Select("version", "author", "rollback_of", "created_at").Order("version desc").Find(&versions)
+	return versions, results.Error
+}
+
+// GetDynamicAppConfigVersion will fetch a single version of the dynamic app config
+func (sdk *PGSDK) GetDynamicAppConfigVersion(version int) (types.DynamicAppConfigVersion, error) {
+	v := types.DynamicAppConfigVersion{}
+	if err := sdk.db.Where("version = ?", version).First(&v).Error; err != nil {
+		return types.DynamicAppConfigVersion{}, err
+	}
+	return v, nil
+}
 
-	if err := sdk.db.Save(&config).Error; err != nil {
+// RollbackDynamicAppConfig will restore the data of a previous version of the dynamic app config as a new version.
+// currentVersion must be the current version, otherwise ErrDynamicAppConfigVersionConflict is returned. Versions that
+// are not valid are rejected with ErrInvalidDynamicAppConfig.
+func (sdk *PGSDK) RollbackDynamicAppConfig(version, currentVersion int, author string) (types.DynamicAppConfig, error) {
+	config := types.DynamicAppConfig{ID: 1, Version: currentVersion, UpdatedBy: author}
+	err := sdk.db.Transaction(func(tx *gorm.DB) error {
+		target := types.DynamicAppConfigVersion{}
+		if err := tx.Where("version = ?", version).First(&target).Error; err != nil {
+			return err
+		}
+		// Versions stored before validation was introduced may be invalid and cannot be restored
+		if valid, errMsg := target.Data.IsValid(); !valid {
+			return fmt.Errorf("%w: version %d: %s", ErrInvalidDynamicAppConfig, version, errMsg)
+		}
+		config.Data = target.Data
+		return saveDynamicAppConfigVersion(tx, &config, version)
+	})
+	if err != nil {
 		return types.DynamicAppConfig{}, err
 	}
 	return config, nil
 }
+
+// saveDynamicAppConfigVersion updates the dynamic app config if it is still at config.Version, then records the new version
+func saveDynamicAppConfigVersion(tx *gorm.DB, config *types.DynamicAppConfig, rollbackOf int) error {
+	now := time.Now()
+	results := tx.Model(&types.DynamicAppConfig{}).
+		Where("id = ? AND version = ?", config.ID, config.Version).
+		Updates(map[string]any{
+			"data":       config.Data,
+			"version":    gorm.Expr("version + 1"),
+			"updated_by": config.UpdatedBy,
+			"updated_at": now,
+		})
+	if results.Error != nil {
+		return results.Error
+	}
+	if results.RowsAffected == 0 {
+		return ErrDynamicAppConfigVersionConflict
+	}
+
+	config.Version++
+	config.UpdatedAt = now
+	return tx.Create(&types.DynamicAppConfigVersion{
+		Version:    config.Version,
+		Data:       config.Data,
+		Author:     config.UpdatedBy,
+		RollbackOf: rollbackOf,
+		CreatedAt:  now,
+	}).Error
+}
@@ -0,0 +1,107 @@
+package modules
+
+import (
+	"regexp"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/sullivtr/k8s_platform/internal/types"
+)
+
+func (s *PGSuite) TestUpdateDynamicAppConfig() {
+	sdk := PGSDK{db: s.DB}
+	config := types.DynamicAppConfig{
+		ID:        1,
+		Version:   4,
+		UpdatedBy: "admin",
+		Data:      types.DynamicConfigJSONB{K8sClusterName: "prod"},
+	}
+
+	s.mock.ExpectBegin()
+	s.mock.ExpectExec(regexp.QuoteMeta(
+		`UPDATE "dynamic_app_configs" SET "data"=$1,"updated_at"=$2,"updated_by"=$3,"version"=version + 1 WHERE id = $4 AND version = $5`)).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), "admin", 1, 4).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	s.mock.ExpectExec(regexp.QuoteMeta(
+		`INSERT INTO "dynamic_app_config_versions" ("version","data","author","rollback_of","created_at") VALUES ($1,$2,$3,$4,$5)`)).
+		WithArgs(5, sqlmock.AnyArg(), "admin", 0, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	s.mock.ExpectCommit()
+
+	updated, err := sdk.UpdateDynamicAppConfig(config)
+	s.NoError(err, "unexpected error while updating dynamic app config")
+	s.Equal(5, updated.Version)
+	s.NoError(s.mock.ExpectationsWereMet())
+}
+
+func (s *PGSuite) TestUpdateDynamicAppConfigVersionConflict() {
+	sdk := PGSDK{db: s.DB}
+
+	s.mock.ExpectBegin()
+	s.mock.ExpectExec(regexp.QuoteMeta(`UPDATE "dynamic_app_configs"`)).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), "admin", 1, 2).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	s.mock.ExpectRollback()
+
+	_, err := sdk.UpdateDynamicAppConfig(types.DynamicAppConfig{ID: 1, Version: 2, UpdatedBy: "admin", Data: types.DynamicConfigJSONB{K8sClusterName: "prod"}})
+	s.ErrorIs(err, ErrDynamicAppConfigVersionConflict)
+	s.NoError(s.mock.ExpectationsWereMet())
+}
+
+func (s *PGSuite) TestRollbackDynamicAppConfig() {
+	sdk := PGSDK{db: s.DB}
+	data, err := types.DynamicConfigJSONB{K8sClusterName: "old"}.Value()
+	s.NoError(err)
+
+	s.mock.ExpectBegin()
+	s.mock.ExpectQuery(regexp.QuoteMeta(
+		`SELECT * FROM "dynamic_app_config_versions" WHERE version = $1 ORDER BY "dynamic_app_config_versions"."version" LIMIT $2`)).
+		WithArgs(2, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"version", "data", "author"}).AddRow(2, data, "jdoe"))
+	s.mock.ExpectExec(regexp.QuoteMeta(`UPDATE "dynamic_app_configs"`)).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), "admin", 1, 7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	s.mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "dynamic_app_config_versions"`)).
+		WithArgs(8, sqlmock.AnyArg(), "admin", 2, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	s.mock.ExpectCommit()
+
+	updated, err := sdk.RollbackDynamicAppConfig(2, 7, "admin")
+	s.NoError(err, "unexpected error while rolling back dynamic app config")
+	s.Equal(8, updated.Version)
+	s.Equal("old", updated.Data.K8sClusterName)
+	s.NoError(s.mock.ExpectationsWereMet())
+}
+
+func (s *PGSuite) TestDynamicAppConfigWithDefaultContainer() {
+	sdk := PGSDK{db: s.DB}
+	// Plugins without a container run in the default container of the pod
+	config := types.DynamicConfigJSONB{
+		K8sClusterName:    "prod",
+		K8sPodExecPlugins: []types.K8sPodExecPlugin{{Name: "thread-dump", Command: "jcmd 1 Thread.print", LabelFilter: "checkout"}},
+	}
+
+	s.mock.ExpectBegin()
+	s.mock.ExpectExec(regexp.QuoteMeta(`UPDATE "dynamic_app_configs"`)).WillReturnResult(sqlmock.NewResult(0, 1))
+	s.mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "dynamic_app_config_versions"`)).WillReturnResult(sqlmock.NewResult(0, 1))
+	s.mock.ExpectCommit()
+
+	updated, err := sdk.UpdateDynamicAppConfig(types.DynamicAppConfig{ID: 1, Version: 4, UpdatedBy: "admin", Data: config})
+	s.NoError(err, "unexpected error while updating dynamic app config")
+	s.Equal(5, updated.Version)
+
+	data, err := config.Value()
+	s.NoError(err)
+	s.mock.ExpectBegin()
+	s.mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "dynamic_app_config_versions"`)).
+		WithArgs(5, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"version", "data", "author"}).AddRow(5, data, "admin"))
+	s.mock.ExpectExec(regexp.QuoteMeta(`UPDATE "dynamic_app_configs"`)).WillReturnResult(sqlmock.NewResult(0, 1))
+	s.mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "dynamic_app_config_versions"`)).WillReturnResult(sqlmock.NewResult(0, 1))
+	s.mock.ExpectCommit()
+
+	restored, err := sdk.RollbackDynamicAppConfig(5, 6, "admin")
+	s.NoError(err, "unexpected error while rolling back dynamic app config")
+	s.Equal(7, restored.Version)
+	s.Equal("", restored.Data.K8sPodExecPlugins[0].Container)
+	s.NoError(s.mock.ExpectationsWereMet())
+}
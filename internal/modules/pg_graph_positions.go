@@ -0,0 +1,34 @@
+package modules
+
+import (
+	"fmt"
+
+	"github.com/sullivtr/k8s_platform/internal/types"
+)
+
+// GetGraphNodePositions will fetch the positions admins pinned the nodes of a graph at
+func (sdk *PGSDK) GetGraphNodePositions(graph string) ([]types.GraphNodePosition, error) {
+	positions := []types.GraphNodePosition{}
+	results := sdk.db.Where("graph = ?", graph).Order("node_id").Find(&positions)
+	return positions, results.Error
+}
+
+// PinGraphNodePosition will create or update the pinned position of a node
+func (sdk *PGSDK) PinGraphNodePosition(position types.GraphNodePosition) (*types.GraphNodePosition, error) {
+	if valid, errMsg := position.IsValid(); !valid {
+		return nil, fmt.Errorf("validation error: %s", errMsg)
+	}
+
+	if err := sdk.db.Save(&position).Error; err != nil {
+		return nil, err
+	}
+	return &position, nil
+}
+
+// UnpinGraphNodePosition will delete the pinned position of a node, which is laid out again
+func (sdk *PGSDK) UnpinGraphNodePosition(graph, nodeID string) error {
+	if graph == "" || nodeID == "" {
+		return fmt.Errorf("invalid graph node: %s/%s", graph, nodeID)
+	}
+	return sdk.db.Where("graph = ? AND node_id = ?", graph, nodeID).Delete(&types.GraphNodePosition{}).Error
+}
@@ -0,0 +1,63 @@
+package modules
+
+import (
+	"regexp"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/sullivtr/k8s_platform/internal/types"
+)
+
+func (s *PGSuite) TestGetGraphNodePositions() {
+	sdk := PGSDK{db: s.DB}
+	s.mock.ExpectQuery(regexp.QuoteMeta(
+		`SELECT * FROM "graph_node_positions" WHERE graph = $1 ORDER BY node_id`)).
+		WithArgs(types.GraphMySQLReplication).
+		WillReturnRows(sqlmock.NewRows([]string{"graph", "node_id", "x", "y", "updated_by"}).
+			AddRow(types.GraphMySQLReplication, "db-core-007", 640, 260, "admin@example.com"))
+
+	resp, err := sdk.GetGraphNodePositions(types.GraphMySQLReplication)
+	s.NoError(err, "unexpected error while fetching graph node positions")
+	s.Len(resp, 1)
+	s.Equal("db-core-007", resp[0].NodeID)
+	s.Equal(640, resp[0].X)
+}
+
+func (s *PGSuite) TestPinGraphNodePosition() {
+	sdk := PGSDK{db: s.DB}
+	position := types.GraphNodePosition{Graph: types.GraphMySQLReplication, NodeID: "db-core-007", X: 640, Y: 260, UpdatedBy: "admin@example.com"}
+	s.mock.MatchExpectationsInOrder(false)
+
+	s.mock.ExpectBegin()
+
+	s.mock.ExpectExec(regexp.QuoteMeta(
+		`UPDATE "graph_node_positions" SET "x"=$1,"y"=$2,"updated_by"=$3,"updated_at"=$4 WHERE "graph" = $5 AND "node_id" = $6`)).
+		WithArgs(640, 260, "admin@example.com", sqlmock.AnyArg(), types.GraphMySQLReplication, "db-core-007").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	s.mock.ExpectCommit()
+
+	resp, err := sdk.PinGraphNodePosition(position)
+	s.NoError(err, "unexpected error while pinning graph node position")
+	s.Equal(260, resp.Y)
+
+	position.Graph = "oracle"
+	_, err = sdk.PinGraphNodePosition(position)
+	s.ErrorContains(err, "Graph is invalid")
+}
+
+func (s *PGSuite) TestUnpinGraphNodePosition() {
+	sdk := PGSDK{db: s.DB}
+	s.mock.MatchExpectationsInOrder(false)
+
+	s.mock.ExpectBegin()
+
+	s.mock.ExpectExec(regexp.QuoteMeta(
+		`DELETE FROM "graph_node_positions" WHERE graph = $1 AND node_id = $2`)).
+		WithArgs(types.GraphMySQLReplication, "db-core-007").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	s.mock.ExpectCommit()
+
+	err := sdk.UnpinGraphNodePosition(types.GraphMySQLReplication, "db-core-007")
+	s.NoError(err, "unexpected error while unpinning graph node position")
+}
@@ -48,7 +48,7 @@ func (sdk *PGSDK) UpsertGroup(group types.Group) (*types.Group, error) {
 		userAssociationsToRemove := identifyGroupUsersToRemove(existingGroup.Users, group.Users)
 
 		for _, a := range permissionAssociationsToRemove {
-			sdk.db.Unscoped().Delete(&types.Permission{}, "group_id = ? AND permission_id = ?", existingGroup.ID, a)
+			sdk.db.Unscoped().Delete(&types.GroupPermissions{}, "group_id = ? AND permission_id = ?", existingGroup.ID, a)
 		}
 
 		for _, u := range userAssociationsToRemove {
@@ -62,6 +62,23 @@ func (sdk *PGSDK) UpsertGroup(group types.Group) (*types.Group, error) {
 	}
 }
 
+// GetDeletedGroups will fetch all soft deleted groups
+func (sdk *PGSDK) GetDeletedGroups() ([]types.Group, error) {
+	groups := []types.Group{}
+	results := sdk.db.Unscoped().Where("deleted_at IS NOT NULL").Find(&groups)
+	return groups, results.Error
+}
+
+// DeleteGroup will soft delete a group. Memberships are kept so that a restored group regains its users and permissions.
+func (sdk *PGSDK) DeleteGroup(groupID uuid.UUID) error {
+	return softDelete(sdk.db, &types.Group{}, groupID)
+}
+
+// RestoreGroup will restore a soft deleted group
+func (sdk *PGSDK) RestoreGroup(groupID uuid.UUID) error {
+	return restore(sdk.db, &types.Group{}, groupID)
+}
+
 func identifyGroupPermissionsToRemove(a, b []*types.Permission) []*uuid.UUID {
 	bMap := make(map[uuid.UUID]bool)
 	for _, item := range b {
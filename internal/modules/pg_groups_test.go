@@ -8,6 +8,7 @@ import (
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/google/uuid"
 	"github.com/sullivtr/k8s_platform/internal/types"
+	"gorm.io/gorm"
 )
 
 func (s *PGSuite) TestGetGroups() {
@@ -203,3 +204,18 @@ func (s *PGSuite) TestUpsertGroupUpdate() {
 	}
 
 }
+
+func (s *PGSuite) TestDeleteGroupNotFound() {
+	sdk := PGSDK{db: s.DB}
+	gid := uuid.New()
+
+	s.mock.ExpectBegin()
+	s.mock.ExpectExec(regexp.QuoteMeta(
+		`UPDATE "groups" SET "deleted_at"=$1 WHERE id = $2 AND "groups"."deleted_at" IS NULL`)).
+		WithArgs(sqlmock.AnyArg(), gid).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	s.mock.ExpectCommit()
+
+	err := sdk.DeleteGroup(gid)
+	s.ErrorIs(err, gorm.ErrRecordNotFound)
+}
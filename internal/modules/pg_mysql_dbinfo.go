@@ -1,11 +1,15 @@
 package modules
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/sullivtr/k8s_platform/internal/types"
 )
 
+// ErrInvalidMySQLDBInfo is returned when a MySQL catalog entry fails validation
+var ErrInvalidMySQLDBInfo = errors.New("validation error")
+
 // GetMySQLCatalog will fetch MYSQL databases from the catalog
 func (sdk *PGSDK) GetMySQLCatalog() ([]*types.MySQLDBInfo, error) {
 	dbCatalog := []*types.MySQLDBInfo{}
@@ -13,14 +17,19 @@ func (sdk *PGSDK) GetMySQLCatalog() ([]*types.MySQLDBInfo, error) {
 	return dbCatalog, results.Error
 }
 
-// UpsertMySQLDBInfo will create or update a MySQL database in the catalog
+// UpsertMySQLDBInfo will create or update a MySQL database in the catalog. The origin of existing hosts is kept unless
+// one is given, and new hosts without one are recorded as manual. The last seen time is only set by discovery.
 func (sdk *PGSDK) UpsertMySQLDBInfo(dbInfo types.MySQLDBInfo) (*types.MySQLDBInfo, error) {
 	mysqlDBInfoIsValid, errMsg := dbInfo.IsValid()
 	if !mysqlDBInfoIsValid {
-		return nil, fmt.Errorf("validation error: %s", errMsg)
+		return nil, fmt.Errorf("%w: %s", ErrInvalidMySQLDBInfo, errMsg)
 	}
 
-	if err := sdk.db.Save(&dbInfo).Error; err != nil {
+	omit := []string{"last_seen_at"}
+	if dbInfo.Origin == "" {
+		omit = append(omit, "origin", "origin_ref")
+	}
+	if err := sdk.db.Omit(omit...).Save(&dbInfo).Error; err != nil {
 		return nil, err
 	}
 	return &dbInfo, nil
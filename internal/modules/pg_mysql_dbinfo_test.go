@@ -32,20 +32,30 @@ func (s *PGSuite) TestUpsertMySQLDBInfo() {
 		Username:  "khub",
 		Port:      3306,
 		IsPrimary: false,
+		Credential: types.CredentialRef{
+			Source:          types.CredentialSourceSecret,
+			SecretNamespace: "databases",
+			SecretName:      "khub-monitor",
+			SecretKey:       "password",
+		},
 	}
 	s.mock.MatchExpectationsInOrder(false)
 
 	s.mock.ExpectBegin()
 
 	s.mock.ExpectExec(regexp.QuoteMeta(
-		`UPDATE "my_sqldb_infos" SET "shortname"=$1,"username"=$2,"port"=$3,"is_primary"=$4,"created_at"=$5,"updated_at"=$6,"deleted_at"=$7 WHERE "my_sqldb_infos"."deleted_at" IS NULL AND "host" = $8`)).
-		WithArgs(dbInfo.Shortname, dbInfo.Username, dbInfo.Port, dbInfo.IsPrimary, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), dbInfo.Host).
+		`UPDATE "my_sqldb_infos" SET "shortname"=$1,"username"=$2,"port"=$3,"is_primary"=$4,"credential_source"=$5,"credential_secret_namespace"=$6,"credential_secret_name"=$7,"credential_secret_key"=$8,"credential_file_path"=$9,"credential_env_var"=$10,"created_at"=$11,"updated_at"=$12,"deleted_at"=$13 WHERE "my_sqldb_infos"."deleted_at" IS NULL AND "host" = $14`)).
+		WithArgs(dbInfo.Shortname, dbInfo.Username, dbInfo.Port, dbInfo.IsPrimary, "secret", "databases", "khub-monitor", "password", "", "", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), dbInfo.Host).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
 	s.mock.ExpectCommit()
 
 	_, err := sdk.UpsertMySQLDBInfo(*dbInfo)
 	s.NoError(err, "unexpected error while upserting mysql db info")
+
+	dbInfo.Credential = types.CredentialRef{Source: types.CredentialSourceFile, FilePath: "relative/password"}
+	_, err = sdk.UpsertMySQLDBInfo(*dbInfo)
+	s.ErrorContains(err, "filePath is invalid")
 }
 
 func (s *PGSuite) TestDeleteMySQLDBInfo() {
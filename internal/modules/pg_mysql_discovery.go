@@ -0,0 +1,83 @@
+package modules
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sullivtr/k8s_platform/internal/types"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GetMySQLDiscoveryProposals will fetch the discovery proposals with the given status, or every proposal when it is empty
+func (sdk *PGSDK) GetMySQLDiscoveryProposals(status string) ([]types.MySQLDiscoveryProposal, error) {
+	proposals := []types.MySQLDiscoveryProposal{}
+	query := sdk.db.Order("host")
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	err := query.Find(&proposals).Error
+	return proposals, err
+}
+
+// SaveMySQLDiscoveryProposals will create or refresh discovery proposals. The status and first seen time of existing
+// proposals are kept, so ignored hosts stay ignored.
+func (sdk *PGSDK) SaveMySQLDiscoveryProposals(proposals []types.MySQLDiscoveryProposal) error {
+	if len(proposals) == 0 {
+		return nil
+	}
+	for i := range proposals {
+		proposals[i].Status = types.MySQLProposalPending
+	}
+	return sdk.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "host"}},
+		DoUpdates: clause.AssignmentColumns([]string{"shortname", "port", "origin", "origin_ref", "last_seen_at"}),
+	}).Create(&proposals).Error
+}
+
+// SetMySQLDiscoveryProposalStatus will set the status of a discovery proposal.
+// gorm.ErrRecordNotFound is returned when there is no proposal for the host.
+func (sdk *PGSDK) SetMySQLDiscoveryProposalStatus(host, status string) error {
+	result := sdk.db.Model(&types.MySQLDiscoveryProposal{}).Where("host = ?", host).Update("status", status)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// AcceptMySQLDiscoveryProposal will add the host of a discovery proposal to the catalog and delete the proposal.
+// gorm.ErrRecordNotFound is returned when there is no proposal for the host, and ErrInvalidMySQLDBInfo when the
+// catalog entry is not valid.
+func (sdk *PGSDK) AcceptMySQLDiscoveryProposal(host string, request types.MySQLDiscoveryAcceptRequest, defaultUsername string) (*types.MySQLDBInfo, error) {
+	var dbInfo types.MySQLDBInfo
+	err := sdk.db.Transaction(func(tx *gorm.DB) error {
+		proposal := types.MySQLDiscoveryProposal{}
+		if err := tx.Where("host = ?", host).First(&proposal).Error; err != nil {
+			return err
+		}
+
+		dbInfo = proposal.CatalogEntry(request, defaultUsername)
+		if valid, errMsg := dbInfo.IsValid(); !valid {
+			return fmt.Errorf("%w: %s", ErrInvalidMySQLDBInfo, errMsg)
+		}
+		if err := tx.Create(&dbInfo).Error; err != nil {
+			return err
+		}
+		return tx.Where("host = ?", host).Delete(&types.MySQLDiscoveryProposal{}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &dbInfo, nil
+}
+
+// MarkMySQLHostsSeen will record that discovery sources found the given catalog hosts
+func (sdk *PGSDK) MarkMySQLHostsSeen(hosts []string, at time.Time) error {
+	if len(hosts) == 0 {
+		return nil
+	}
+	return sdk.db.Model(&types.MySQLDBInfo{}).Where("host IN ?", hosts).UpdateColumn("last_seen_at", at).Error
+}
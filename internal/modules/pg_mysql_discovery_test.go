@@ -0,0 +1,60 @@
+package modules
+
+import (
+	"regexp"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/sullivtr/k8s_platform/internal/types"
+	"gorm.io/gorm"
+)
+
+func (s *PGSuite) TestSaveMySQLDiscoveryProposals() {
+	sdk := PGSDK{db: s.DB}
+	seenAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	s.mock.ExpectBegin()
+	s.mock.ExpectExec(regexp.QuoteMeta(
+		`INSERT INTO "my_sql_discovery_proposals" ("host","shortname","port","origin","origin_ref","status","created_at","last_seen_at") VALUES ($1,$2,$3,$4,$5,$6,$7,$8) ON CONFLICT ("host") DO UPDATE SET "shortname"="excluded"."shortname","port"="excluded"."port","origin"="excluded"."origin","origin_ref"="excluded"."origin_ref","last_seen_at"="excluded"."last_seen_at"`)).
+		WithArgs("legacy.billing.svc", "legacy", 3306, types.MySQLOriginKubernetes, "service/billing/legacy", types.MySQLProposalPending, sqlmock.AnyArg(), seenAt).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	s.mock.ExpectCommit()
+
+	err := sdk.SaveMySQLDiscoveryProposals([]types.MySQLDiscoveryProposal{{
+		Host: "legacy.billing.svc", Shortname: "legacy", Port: 3306,
+		Origin: types.MySQLOriginKubernetes, OriginRef: "service/billing/legacy", LastSeenAt: seenAt,
+	}})
+	s.NoError(err, "unexpected error while saving mysql discovery proposals")
+	s.NoError(s.mock.ExpectationsWereMet())
+}
+
+func (s *PGSuite) TestSetMySQLDiscoveryProposalStatusNotFound() {
+	sdk := PGSDK{db: s.DB}
+
+	s.mock.ExpectBegin()
+	s.mock.ExpectExec(regexp.QuoteMeta(
+		`UPDATE "my_sql_discovery_proposals" SET "status"=$1 WHERE host = $2`)).
+		WithArgs(types.MySQLProposalIgnored, "unknown.svc").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	s.mock.ExpectCommit()
+
+	err := sdk.SetMySQLDiscoveryProposalStatus("unknown.svc", types.MySQLProposalIgnored)
+	s.ErrorIs(err, gorm.ErrRecordNotFound)
+	s.NoError(s.mock.ExpectationsWereMet())
+}
+
+func (s *PGSuite) TestMarkMySQLHostsSeen() {
+	sdk := PGSDK{db: s.DB}
+	seenAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	s.mock.ExpectBegin()
+	s.mock.ExpectExec(regexp.QuoteMeta(
+		`UPDATE "my_sqldb_infos" SET "last_seen_at"=$1 WHERE host IN ($2,$3) AND "my_sqldb_infos"."deleted_at" IS NULL`)).
+		WithArgs(seenAt, "db-1.example.com", "db-2.example.com").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	s.mock.ExpectCommit()
+
+	err := sdk.MarkMySQLHostsSeen([]string{"db-1.example.com", "db-2.example.com"}, seenAt)
+	s.NoError(err, "unexpected error while marking mysql hosts seen")
+	s.NoError(s.mock.ExpectationsWereMet())
+}
@@ -0,0 +1,38 @@
+package modules
+
+import (
+	"time"
+
+	"github.com/sullivtr/k8s_platform/internal/types"
+)
+
+// CreateReplTopoSnapshot will store a capture of the replication topology
+func (sdk *PGSDK) CreateReplTopoSnapshot(snapshot types.ReplTopoSnapshot) (types.ReplTopoSnapshot, error) {
+	err := sdk.db.Create(&snapshot).Error
+	return snapshot, err
+}
+
+// GetReplTopoSnapshot will fetch the latest capture of the replication topology taken at or before the given time.
+// gorm.ErrRecordNotFound is returned when there is none.
+func (sdk *PGSDK) GetReplTopoSnapshot(at time.Time) (types.ReplTopoSnapshot, error) {
+	snapshot := types.ReplTopoSnapshot{}
+	err := sdk.db.Where("captured_at <= ?", at).Order("captured_at desc").First(&snapshot).Error
+	return snapshot, err
+}
+
+// GetReplTopoSnapshotHistory will fetch the captures of the replication topology taken in a time range, newest first,
+// without their nodes and edges
+func (sdk *PGSDK) GetReplTopoSnapshotHistory(since, until time.Time, limit int) ([]types.ReplTopoSnapshot, error) {
+	snapshots := []types.ReplTopoSnapshot{}
+	err := sdk.db.Select("id", "captured_at", "changes").
+		Where("captured_at >= ? AND captured_at <= ?", since, until).
+		Order("captured_at desc").Limit(limit).
+		Find(&snapshots).Error
+	return snapshots, err
+}
+
+// DeleteReplTopoSnapshotsBefore will delete the captures of the replication topology taken before the given time
+func (sdk *PGSDK) DeleteReplTopoSnapshotsBefore(before time.Time) (int64, error) {
+	result := sdk.db.Where("captured_at < ?", before).Delete(&types.ReplTopoSnapshot{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,63 @@
+package modules
+
+import (
+	"regexp"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/sullivtr/k8s_platform/internal/types"
+)
+
+func (s *PGSuite) TestCreateReplTopoSnapshot() {
+	sdk := PGSDK{db: s.DB}
+	capturedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	s.mock.ExpectBegin()
+	s.mock.ExpectQuery(regexp.QuoteMeta(
+		`INSERT INTO "repl_topo_snapshots" ("captured_at","nodes","edges","changes") VALUES ($1,$2,$3,$4) RETURNING "id"`)).
+		WithArgs(capturedAt, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+	s.mock.ExpectCommit()
+
+	snapshot, err := sdk.CreateReplTopoSnapshot(types.ReplTopoSnapshot{
+		CapturedAt: capturedAt,
+		Nodes:      []types.ReplTopoTreeNode{{ID: "db-1"}},
+	})
+	s.NoError(err, "unexpected error while creating replication topology snapshot")
+	s.Equal(uint(7), snapshot.ID)
+}
+
+func (s *PGSuite) TestGetReplTopoSnapshot() {
+	sdk := PGSDK{db: s.DB}
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	s.mock.ExpectQuery(regexp.QuoteMeta(
+		`SELECT * FROM "repl_topo_snapshots" WHERE captured_at <= $1 ORDER BY captured_at desc,"repl_topo_snapshots"."id" LIMIT $2`)).
+		WithArgs(at, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "captured_at", "nodes", "edges", "changes"}).
+			AddRow(3, at.Add(-time.Minute), `[{"id":"db-1"}]`, `[]`, `[{"kind":"node_added","node":"db-1","detail":"db-1 was added"}]`))
+
+	snapshot, err := sdk.GetReplTopoSnapshot(at)
+	s.NoError(err, "unexpected error while fetching replication topology snapshot")
+	s.Equal(uint(3), snapshot.ID)
+	s.Equal("db-1", snapshot.Nodes[0].ID)
+	s.Equal(types.ReplTopoChangeNodeAdded, snapshot.Changes[0].Kind)
+}
+
+func (s *PGSuite) TestGetReplTopoSnapshotHistory() {
+	sdk := PGSDK{db: s.DB}
+	until := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	since := until.Add(-24 * time.Hour)
+
+	s.mock.ExpectQuery(regexp.QuoteMeta(
+		`SELECT "id","captured_at","changes" FROM "repl_topo_snapshots" WHERE captured_at >= $1 AND captured_at <= $2 ORDER BY captured_at desc LIMIT $3`)).
+		WithArgs(since, until, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "captured_at", "changes"}).
+			AddRow(2, until, `[]`).
+			AddRow(1, since, `[]`))
+
+	history, err := sdk.GetReplTopoSnapshotHistory(since, until, 10)
+	s.NoError(err, "unexpected error while fetching replication topology history")
+	s.Len(history, 2)
+	s.Nil(history[0].Nodes)
+}
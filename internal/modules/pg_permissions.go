@@ -43,3 +43,20 @@ func (sdk *PGSDK) UpsertPermission(permission types.Permission) (*types.Permissi
 
 	return &permission, nil
 }
+
+// GetDeletedPermissions will fetch all soft deleted permissions
+func (sdk *PGSDK) GetDeletedPermissions() ([]types.Permission, error) {
+	permissions := []types.Permission{}
+	results := sdk.db.Unscoped().Where("deleted_at IS NOT NULL").Find(&permissions)
+	return permissions, results.Error
+}
+
+// DeletePermission will soft delete a permission
+func (sdk *PGSDK) DeletePermission(permissionID uuid.UUID) error {
+	return softDelete(sdk.db, &types.Permission{}, permissionID)
+}
+
+// RestorePermission will restore a soft deleted permission
+func (sdk *PGSDK) RestorePermission(permissionID uuid.UUID) error {
+	return restore(sdk.db, &types.Permission{}, permissionID)
+}
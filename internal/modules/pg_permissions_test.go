@@ -141,3 +141,17 @@ func (s *PGSuite) TestUpsertPermissionUpdate() {
 	}
 
 }
+
+func (s *PGSuite) TestGetDeletedPermissions() {
+	sdk := PGSDK{db: s.DB}
+	pid := uuid.New()
+
+	s.mock.ExpectQuery(regexp.QuoteMeta(
+		`SELECT * FROM "permissions" WHERE deleted_at IS NOT NULL`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "app_tag"}).
+			AddRow(pid, "gridbatch", "grid_batch"))
+
+	resp, err := sdk.GetDeletedPermissions()
+	s.NoError(err, "unexpected error while fetching deleted permissions")
+	s.Equal(pid, *resp[0].ID)
+}
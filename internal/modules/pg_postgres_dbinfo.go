@@ -0,0 +1,45 @@
+package modules
+
+import (
+	"fmt"
+
+	"github.com/sullivtr/k8s_platform/internal/types"
+)
+
+// GetPostgresCatalog will fetch Postgres databases from the catalog
+func (sdk *PGSDK) GetPostgresCatalog() ([]*types.PostgresDBInfo, error) {
+	dbCatalog := []*types.PostgresDBInfo{}
+	results := sdk.db.Model(&types.PostgresDBInfo{}).Find(&dbCatalog)
+	return dbCatalog, results.Error
+}
+
+// UpsertPostgresDBInfo will create or update a Postgres database in the catalog. The port and database default to the
+// Postgres ones.
+func (sdk *PGSDK) UpsertPostgresDBInfo(dbInfo types.PostgresDBInfo) (*types.PostgresDBInfo, error) {
+	if dbInfo.Port == 0 {
+		dbInfo.Port = types.DefaultPostgresPort
+	}
+	dbInfo.Database = dbInfo.DatabaseName()
+
+	postgresDBInfoIsValid, errMsg := dbInfo.IsValid()
+	if !postgresDBInfoIsValid {
+		return nil, fmt.Errorf("validation error: %s", errMsg)
+	}
+
+	if err := sdk.db.Save(&dbInfo).Error; err != nil {
+		return nil, err
+	}
+	return &dbInfo, nil
+}
+
+// DeletePostgresDBInfo will delete a Postgres database from the catalog
+func (sdk *PGSDK) DeletePostgresDBInfo(dbHost string) error {
+	if dbHost == "" {
+		return fmt.Errorf("invalid PostgresDBInfo: %v", dbHost)
+	}
+
+	if err := sdk.db.Unscoped().Where("host = ?", dbHost).Delete(&types.PostgresDBInfo{}).Error; err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package modules
+
+import (
+	"regexp"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/sullivtr/k8s_platform/internal/types"
+)
+
+func (s *PGSuite) TestGetPostgresDBCatalog() {
+	sdk := PGSDK{db: s.DB}
+	host := "pg-core-001.cloud.com"
+	s.mock.ExpectQuery(regexp.QuoteMeta(
+		`SELECT * FROM "postgres_db_infos"`)).
+		WillReturnRows(sqlmock.NewRows([]string{"host", "shortname", "username", "port", "database"}).
+			AddRow(host, "pg-core-001", "khub", 5432, "app"))
+
+	resp, err := sdk.GetPostgresCatalog()
+	s.NoError(err, "unexpected error while fetching postgres db info")
+
+	s.Equal(host, resp[0].Host)
+	s.Equal("pg-core-001", resp[0].Shortname)
+	s.Equal(5432, resp[0].Port)
+	s.Equal("app", resp[0].Database)
+}
+
+func (s *PGSuite) TestUpsertPostgresDBInfo() {
+	sdk := PGSDK{db: s.DB}
+	dbInfo := types.PostgresDBInfo{
+		Host:      "pg-core-001.cloud.com",
+		Shortname: "pg-core-001",
+		Username:  "khub",
+	}
+	s.mock.MatchExpectationsInOrder(false)
+
+	s.mock.ExpectBegin()
+
+	s.mock.ExpectExec(regexp.QuoteMeta(
+		`UPDATE "postgres_db_infos" SET "shortname"=$1,"username"=$2,"port"=$3,"database"=$4,"is_primary"=$5,"credential_source"=$6,"credential_secret_namespace"=$7,"credential_secret_name"=$8,"credential_secret_key"=$9,"credential_file_path"=$10,"credential_env_var"=$11,"created_at"=$12,"updated_at"=$13,"deleted_at"=$14 WHERE "postgres_db_infos"."deleted_at" IS NULL AND "host" = $15`)).
+		WithArgs(dbInfo.Shortname, dbInfo.Username, types.DefaultPostgresPort, types.DefaultPostgresDatabase, false, "", "", "", "", "", "", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), dbInfo.Host).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	s.mock.ExpectCommit()
+
+	resp, err := sdk.UpsertPostgresDBInfo(dbInfo)
+	s.NoError(err, "unexpected error while upserting postgres db info")
+	s.Equal(types.DefaultPostgresPort, resp.Port)
+
+	dbInfo.Port = 70000
+	_, err = sdk.UpsertPostgresDBInfo(dbInfo)
+	s.ErrorContains(err, "Port is invalid")
+}
+
+func (s *PGSuite) TestDeletePostgresDBInfo() {
+	sdk := PGSDK{db: s.DB}
+	s.mock.MatchExpectationsInOrder(false)
+
+	s.mock.ExpectBegin()
+
+	s.mock.ExpectExec(regexp.QuoteMeta(
+		`DELETE FROM "postgres_db_infos" WHERE host = $1`)).
+		WithArgs("pg-core-001.cloud.com").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	s.mock.ExpectCommit()
+
+	err := sdk.DeletePostgresDBInfo("pg-core-001.cloud.com")
+	s.NoError(err, "unexpected error while deleting postgres db info")
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/sullivtr/k8s_platform/internal/types"
+	"gorm.io/gorm"
 )
 
 // GetUsers will fetch all users
@@ -21,10 +22,67 @@ func (sdk *PGSDK) GetUser(username string) (types.User, error) {
 	return user, results.Error
 }
 
+// GetUserIncludingDeleted will fetch a single user, including soft deleted users
+func (sdk *PGSDK) GetUserIncludingDeleted(username string) (types.User, error) {
+	user := types.User{}
+	results := sdk.db.Unscoped().Where("name = ?", username).First(&user)
+	return user, results.Error
+}
+
+// GetUserByID will fetch a single user by ID
+func (sdk *PGSDK) GetUserByID(userID uuid.UUID) (types.User, error) {
+	user := types.User{}
+	results := sdk.db.Preload("Groups").Where("id = ?", userID).First(&user)
+	return user, results.Error
+}
+
+// GetUserByIdentity will fetch a single user by the issuer and subject of their identity provider.
+// Soft deleted users are included so that a deleted user is not recreated on their next login.
+func (sdk *PGSDK) GetUserByIdentity(issuer, subject string) (types.User, error) {
+	user := types.User{}
+	results := sdk.db.Unscoped().Preload("Groups").Where("issuer = ? AND subject = ?", issuer, subject).First(&user)
+	return user, results.Error
+}
+
+// GetServiceAccounts will fetch all service account users
+func (sdk *PGSDK) GetServiceAccounts() ([]types.User, error) {
+	users := []types.User{}
+	results := sdk.db.Preload("Groups").Where("is_service_account = ?", true).Find(&users)
+	return users, results.Error
+}
+
+// CreateServiceAccount will create a service account user and bind it to the provided groups
+func (sdk *PGSDK) CreateServiceAccount(user types.User, groupIDs []uuid.UUID) (*types.User, error) {
+	userIsValid, errMsg := user.IsValid()
+	if !userIsValid {
+		return nil, fmt.Errorf("validation error: %s", errMsg)
+	}
+
+	err := sdk.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+		for _, gid := range groupIDs {
+			if err := tx.Create(&types.GroupUsers{GroupID: gid, UserID: *user.ID}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
 // GetUserAccessDetails will fetch a list of accounts and groups that a user can access (for authorization)
 func (sdk *PGSDK) GetUserAccessDetails(userID uuid.UUID) (types.UserAccessDetails, error) {
 	groupIDs := []uuid.UUID{}
-	groupIDResults := sdk.db.Raw("SELECT group_id FROM group_users WHERE user_id = ?", userID).Scan(&groupIDs)
+	groupIDResults := sdk.db.Raw(
+		"SELECT group_users.group_id FROM group_users JOIN groups ON groups.id = group_users.group_id WHERE group_users.user_id = ? AND groups.deleted_at IS NULL",
+		userID,
+	).Scan(&groupIDs)
 	if groupIDResults.Error != nil {
 		return types.UserAccessDetails{}, groupIDResults.Error
 	}
@@ -70,3 +128,27 @@ func (sdk *PGSDK) UpsertUser(user types.User) (*types.User, error) {
 
 	return &user, nil
 }
+
+// GetDeletedUsers will fetch all soft deleted users
+func (sdk *PGSDK) GetDeletedUsers() ([]types.User, error) {
+	users := []types.User{}
+	results := sdk.db.Unscoped().Where("deleted_at IS NOT NULL").Find(&users)
+	return users, results.Error
+}
+
+// CountAdmins will count the active admins
+func (sdk *PGSDK) CountAdmins() (int64, error) {
+	var count int64
+	results := sdk.db.Model(&types.User{}).Where("is_admin = ? AND deactivated = ?", true, false).Count(&count)
+	return count, results.Error
+}
+
+// DeleteUser will soft delete a user
+func (sdk *PGSDK) DeleteUser(userID uuid.UUID) error {
+	return softDelete(sdk.db, &types.User{}, userID)
+}
+
+// RestoreUser will restore a soft deleted user
+func (sdk *PGSDK) RestoreUser(userID uuid.UUID) error {
+	return restore(sdk.db, &types.User{}, userID)
+}
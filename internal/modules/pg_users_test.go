@@ -8,6 +8,7 @@ import (
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/google/uuid"
 	"github.com/sullivtr/k8s_platform/internal/types"
+	"gorm.io/gorm"
 )
 
 func (s *PGSuite) TestGetUsers() {
@@ -71,7 +72,7 @@ func (s *PGSuite) TestGetUserPermissionAccess() {
 
 	s.mock.MatchExpectationsInOrder(false)
 	s.mock.ExpectQuery(regexp.QuoteMeta(
-		`SELECT group_id FROM group_users WHERE user_id = $1`)).
+		`SELECT group_users.group_id FROM group_users JOIN groups ON groups.id = group_users.group_id WHERE group_users.user_id = $1 AND groups.deleted_at IS NULL`)).
 		WithArgs(uid).
 		WillReturnRows(sqlmock.NewRows([]string{"group_id"}).
 			AddRow(groupID))
@@ -117,11 +118,11 @@ func (s *PGSuite) TestUpsertUserCreate() {
 	s.mock.ExpectBegin()
 
 	// Expecting a create query.
-	s.mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "users" ("name","email","is_admin","last_used","dark_mode","created_at","updated_at","deleted_at") VALUES ($1,$2,$3,$4,$5,$6,$7,$8) RETURNING "id"`)).
-		WithArgs(user.Name, user.Email, user.IsAdmin, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+	s.mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "users" ("name","email","is_admin","last_used","dark_mode","is_service_account","issuer","subject","deactivated","created_at","updated_at","deleted_at") VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12) RETURNING "id"`)).
+		WithArgs(user.Name, user.Email, user.IsAdmin, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
 		WillReturnRows(
-			sqlmock.NewRows([]string{"id", "name", "email", "is_admin", "last_used", "dark_mode", "created_at", "updated_at", "deleted_at"}).
-				AddRow(&uid, user.Name, user.Email, user.IsAdmin, user.LastUsed, true, time.Now(), time.Now(), sql.NullTime{}))
+			sqlmock.NewRows([]string{"id", "name", "email", "is_admin", "last_used", "dark_mode", "is_service_account", "issuer", "subject", "deactivated", "created_at", "updated_at", "deleted_at"}).
+				AddRow(&uid, user.Name, user.Email, user.IsAdmin, user.LastUsed, true, false, "", "", false, time.Now(), time.Now(), sql.NullTime{}))
 
 	s.mock.ExpectCommit()
 
@@ -161,8 +162,8 @@ func (s *PGSuite) TestUpsertUserUpdate() {
 		IsAdmin:  false,
 	}
 
-	rows := sqlmock.NewRows([]string{"id", "name", "email", "is_admin", "last_used", "dark_mode", "created_at", "updated_at", "deleted_at"}).
-		AddRow(user.ID, user.Name, user.Email, user.IsAdmin, user.LastUsed, true, time.Now(), time.Now(), sql.NullTime{})
+	rows := sqlmock.NewRows([]string{"id", "name", "email", "is_admin", "last_used", "dark_mode", "is_service_account", "issuer", "subject", "deactivated", "created_at", "updated_at", "deleted_at"}).
+		AddRow(user.ID, user.Name, user.Email, user.IsAdmin, user.LastUsed, true, false, "", "", false, time.Now(), time.Now(), sql.NullTime{})
 
 	s.mock.MatchExpectationsInOrder(false)
 
@@ -174,8 +175,8 @@ func (s *PGSuite) TestUpsertUserUpdate() {
 		WillReturnRows(rows)
 
 	// Expecting a update query.
-	s.mock.ExpectExec(regexp.QuoteMeta(`UPDATE "users" SET "name"=$1,"email"=$2,"is_admin"=$3,"last_used"=$4,"dark_mode"=$5,"created_at"=$6,"updated_at"=$7,"deleted_at"=$8 WHERE "users"."deleted_at" IS NULL AND "id" = $9`)).
-		WithArgs(userUpdated.Name, userUpdated.Email, userUpdated.IsAdmin, userUpdated.LastUsed, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), uid).
+	s.mock.ExpectExec(regexp.QuoteMeta(`UPDATE "users" SET "name"=$1,"email"=$2,"is_admin"=$3,"last_used"=$4,"dark_mode"=$5,"is_service_account"=$6,"issuer"=$7,"subject"=$8,"deactivated"=$9,"created_at"=$10,"updated_at"=$11,"deleted_at"=$12 WHERE "users"."deleted_at" IS NULL AND "id" = $13`)).
+		WithArgs(userUpdated.Name, userUpdated.Email, userUpdated.IsAdmin, userUpdated.LastUsed, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), uid).
 		WillReturnResult(sqlmock.NewResult(0, 1))
 
 	s.mock.ExpectCommit()
@@ -196,3 +197,61 @@ func (s *PGSuite) TestUpsertUserUpdate() {
 	}
 
 }
+
+func (s *PGSuite) TestDeleteUser() {
+	sdk := PGSDK{db: s.DB}
+	uid := uuid.New()
+
+	s.mock.ExpectBegin()
+	s.mock.ExpectExec(regexp.QuoteMeta(
+		`UPDATE "users" SET "deleted_at"=$1 WHERE id = $2 AND "users"."deleted_at" IS NULL`)).
+		WithArgs(sqlmock.AnyArg(), uid).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	s.mock.ExpectCommit()
+
+	err := sdk.DeleteUser(uid)
+	s.NoError(err, "unexpected error while deleting user")
+}
+
+func (s *PGSuite) TestRestoreUser() {
+	sdk := PGSDK{db: s.DB}
+	uid := uuid.New()
+
+	s.mock.ExpectBegin()
+	s.mock.ExpectExec(regexp.QuoteMeta(
+		`UPDATE "users" SET "deleted_at"=$1,"updated_at"=$2 WHERE id = $3 AND deleted_at IS NOT NULL`)).
+		WithArgs(nil, sqlmock.AnyArg(), uid).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	s.mock.ExpectCommit()
+
+	err := sdk.RestoreUser(uid)
+	s.NoError(err, "unexpected error while restoring user")
+}
+
+func (s *PGSuite) TestRestoreUserNotDeleted() {
+	sdk := PGSDK{db: s.DB}
+	uid := uuid.New()
+
+	s.mock.ExpectBegin()
+	s.mock.ExpectExec(regexp.QuoteMeta(
+		`UPDATE "users" SET "deleted_at"=$1,"updated_at"=$2 WHERE id = $3 AND deleted_at IS NOT NULL`)).
+		WithArgs(nil, sqlmock.AnyArg(), uid).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	s.mock.ExpectCommit()
+
+	err := sdk.RestoreUser(uid)
+	s.ErrorIs(err, gorm.ErrRecordNotFound)
+}
+
+func (s *PGSuite) TestCountAdmins() {
+	sdk := PGSDK{db: s.DB}
+
+	s.mock.ExpectQuery(regexp.QuoteMeta(
+		`SELECT count(*) FROM "users" WHERE (is_admin = $1 AND deactivated = $2) AND "users"."deleted_at" IS NULL`)).
+		WithArgs(true, false).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+
+	count, err := sdk.CountAdmins()
+	s.NoError(err, "unexpected error while counting admins")
+	s.Equal(int64(2), count)
+}
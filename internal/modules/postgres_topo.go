@@ -0,0 +1,130 @@
+package modules
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/sullivtr/k8s_platform/internal/types"
+)
+
+// PostgresTopoSDK represents a Postgres database topology module SDK. It captures into a ReplTopoCapture, like the
+// MySQLTopoSDK. PostgresDBPassword is used by databases without a credential reference, Secrets resolves secret
+// references, CredentialFilesDir holds the files credentials may be read from, and Prober reads the replication status
+// of a host, over SQL when it is not set. Workers bounds how many hosts are probed at once. ConnectTimeout, QueryTimeout
+// and CaptureTimeout bound connecting to a host, the queries run on it and the whole capture.
+type PostgresTopoSDK struct {
+	PostgresDBPassword string
+	Secrets            SecretReader
+	CredentialFilesDir string
+	Databases          []*types.PostgresDBInfo
+	Prober             PostgresHostProber
+	Workers            int
+	ConnectTimeout     time.Duration
+	QueryTimeout       time.Duration
+	CaptureTimeout     time.Duration
+}
+
+// CaptureReplicationTopo captures the replication topology for the databases in the PostgresTopoSDK.
+// Hosts are probed concurrently, by at most Workers probes, within CaptureTimeout. Each probe:
+// 1. Resolves the credential reference of the database and connects to it.
+// 2. Reads the WAL receiver of the host, which is its streaming channel, and its logical subscriptions.
+// 3. Reads the WAL senders and replication slots of the host.
+//
+// Once every host is probed, the source of each channel is resolved to a catalog shortname, and its lag is read from
+// the WAL sender or slot of the source. Channels of sources outside the catalog keep the lag read on the replica.
+//
+// The databases of the PostgresTopoSDK are not modified. Databases that could not be probed in time, or at all, are
+// captured with a ProbeError. Postgres captures have no consumers.
+func (sdk *PostgresTopoSDK) CaptureReplicationTopo(ctx context.Context) ReplTopoCapture {
+	captureTimeout := sdk.CaptureTimeout
+	if captureTimeout <= 0 {
+		captureTimeout = defaultTopoCaptureTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, captureTimeout)
+	defer cancel()
+
+	dbShortNameMap := make(map[string]string)
+	databases := make([]*types.ReplDBInfo, len(sdk.Databases))
+	for i, db := range sdk.Databases {
+		dbShortNameMap[db.Shortname] = strings.Split(db.Host, ".")[0] + ":" + fmt.Sprintf("%d", db.Port)
+		databases[i] = db.TopologyNode()
+	}
+
+	// Lags are only resolved once every host is probed, since the lag of a channel is read on its source
+	probes := make([]*PostgresHostProbe, len(databases))
+	probed := probeConcurrently(ctx, len(databases), sdk.Workers, func(i int) {
+		probes[i] = sdk.probeHost(ctx, sdk.Databases[i], databases[i])
+	})
+
+	probeByShortname := make(map[string]*PostgresHostProbe)
+	for i, db := range databases {
+		if !probed[i] {
+			db.ProbeError = fmt.Sprintf("not probed before the capture timed out: %v", ctx.Err())
+			log.Warn().Msgf("Error capturing the replication topology of %s: %s", db.Shortname, db.ProbeError)
+			continue
+		}
+		if probes[i] != nil {
+			probeByShortname[db.Shortname] = probes[i]
+		}
+	}
+
+	for i, db := range databases {
+		if probes[i] == nil {
+			continue
+		}
+		channels := []types.ReplChannel{}
+		for _, channel := range probes[i].Channels {
+			channel.Channel.Source = resolveSource(dbShortNameMap, channel.Channel.SourceHost)
+			if source, ok := probeByShortname[channel.Channel.Source]; ok {
+				channel.setLagFromSource(*source)
+			}
+			if channel.Channel.Source != "" {
+				log.Info().Msgf("source found for %s: %s (%s channel %q, receiver %s, apply %s)", db.Shortname, channel.Channel.Source, channel.Channel.Kind, channel.Channel.Name, channel.Channel.IOState, channel.Channel.SQLState)
+			}
+			channels = append(channels, channel.Channel)
+		}
+		db.SetReplicationChannels(channels)
+	}
+
+	capture := ReplTopoCapture{Databases: databases, Consumers: []*types.ReplDBInfo{}}
+	capture.setReplicas()
+	return capture
+}
+
+// probeHost reads the replication status of a database. Problems are logged and recorded as the ProbeError of its
+// node, and nil is returned.
+func (sdk *PostgresTopoSDK) probeHost(ctx context.Context, db *types.PostgresDBInfo, node *types.ReplDBInfo) *PostgresHostProbe {
+	log.Info().Msgf("checking replication topology for %s", db.Shortname)
+	password, err := resolveCredential(ctx, sdk.Secrets, sdk.CredentialFilesDir, db.Credential, sdk.PostgresDBPassword, db.Shortname)
+	if err != nil {
+		node.ProbeError = err.Error()
+		log.Warn().Msgf("Error resolving the credential of %s: %v", db.Shortname, err)
+		return nil
+	}
+
+	probe, err := sdk.prober().Probe(ctx, *db, password)
+	if err != nil {
+		node.ProbeError = err.Error()
+		log.Warn().Msgf("Error reading the replication status of %s: %v", db.Shortname, err)
+		return nil
+	}
+	return &probe
+}
+
+// prober returns the prober of the SDK, or an SQL prober using its timeouts
+func (sdk *PostgresTopoSDK) prober() PostgresHostProber {
+	if sdk.Prober != nil {
+		return sdk.Prober
+	}
+	prober := sqlPostgresHostProber{connectTimeout: sdk.ConnectTimeout, queryTimeout: sdk.QueryTimeout}
+	if prober.connectTimeout <= 0 {
+		prober.connectTimeout = defaultTopoConnectTimeout
+	}
+	if prober.queryTimeout <= 0 {
+		prober.queryTimeout = defaultTopoQueryTimeout
+	}
+	return prober
+}
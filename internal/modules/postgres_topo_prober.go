@@ -0,0 +1,106 @@
+package modules
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"time"
+
+	// Registers the pgx database/sql driver
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/rs/zerolog/log"
+	"github.com/sullivtr/k8s_platform/internal/types"
+)
+
+// PostgresHostProbe is the replication status read from a Postgres host
+type PostgresHostProbe struct {
+	// Channels are the streaming channel and the logical subscriptions of the host, their Source is not resolved to a
+	// catalog shortname yet
+	Channels []PostgresReplicationChannel
+	// Senders and Slots are the WAL senders and the lag of the replication slots of the host, which give the lag of its
+	// replicas and subscribers
+	Senders []PostgresWALSender
+	Slots   map[string]int64
+}
+
+// PostgresHostProber reads the replication status of a Postgres host. Probe must return once the context is done.
+type PostgresHostProber interface {
+	Probe(ctx context.Context, db types.PostgresDBInfo, password string) (PostgresHostProbe, error)
+}
+
+// sqlPostgresHostProber probes Postgres hosts over a single connection, which is released as soon as the probe is done
+type sqlPostgresHostProber struct {
+	connectTimeout time.Duration
+	queryTimeout   time.Duration
+}
+
+// Probe connects to the host and reads its replication channels, WAL senders and replication slots. Failing to read the
+// subscriptions, WAL senders or slots is logged, the streaming channel is still returned.
+func (p sqlPostgresHostProber) Probe(ctx context.Context, db types.PostgresDBInfo, password string) (PostgresHostProbe, error) {
+	probe := PostgresHostProbe{Channels: []PostgresReplicationChannel{}, Senders: []PostgresWALSender{}, Slots: map[string]int64{}}
+
+	pool, err := sql.Open("pgx", postgresDSN(db, password, p.connectTimeout))
+	if err != nil {
+		return probe, fmt.Errorf("unable to open connection: %v", err)
+	}
+	defer pool.Close()
+
+	connectCtx, cancel := context.WithTimeout(ctx, p.connectTimeout)
+	defer cancel()
+	conn, err := pool.Conn(connectCtx)
+	if err != nil {
+		return probe, fmt.Errorf("unable to connect: %v", err)
+	}
+	defer conn.Close()
+
+	queryCtx, cancel := context.WithTimeout(ctx, p.queryTimeout)
+	defer cancel()
+
+	streaming, err := readStreamingChannel(queryCtx, conn)
+	if err != nil {
+		return probe, err
+	}
+	if streaming != nil {
+		probe.Channels = append(probe.Channels, *streaming)
+	}
+
+	subscriptions, err := readSubscriptions(queryCtx, conn)
+	if err != nil {
+		log.Warn().Msgf("Error reading the subscriptions of %s: %v", db.Shortname, err)
+	}
+	probe.Channels = append(probe.Channels, subscriptions...)
+
+	if senders, err := readWALSenders(queryCtx, conn); err != nil {
+		log.Warn().Msgf("Error reading the wal senders of %s: %v", db.Shortname, err)
+	} else {
+		probe.Senders = senders
+	}
+
+	if slots, err := readReplicationSlots(queryCtx, conn); err != nil {
+		log.Warn().Msgf("Error reading the replication slots of %s: %v", db.Shortname, err)
+	} else {
+		probe.Slots = slots
+	}
+
+	return probe, nil
+}
+
+// postgresDSN returns the connection URL of a catalog host. Connections are named khub, so they can be told apart in
+// pg_stat_activity.
+func postgresDSN(db types.PostgresDBInfo, password string, connectTimeout time.Duration) string {
+	query := url.Values{}
+	query.Set("application_name", "khub")
+	// connect_timeout is in whole seconds, and 0 waits forever
+	query.Set("connect_timeout", strconv.Itoa(max(int(connectTimeout.Seconds()), 1)))
+	dsn := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(db.Username, password),
+		Host:     net.JoinHostPort(db.Host, strconv.Itoa(db.Port)),
+		Path:     "/" + db.DatabaseName(),
+		RawQuery: query.Encode(),
+	}
+	return dsn.String()
+}
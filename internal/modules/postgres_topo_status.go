@@ -0,0 +1,337 @@
+package modules
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/sullivtr/k8s_platform/internal/types"
+)
+
+const (
+	// POSTGRES_WAL_RECEIVER_QUERY reads the WAL receiver of a streaming replica. Its lag is the WAL it received but did not
+	// replay yet, and how long ago the last transaction it replayed was committed on the primary.
+	POSTGRES_WAL_RECEIVER_QUERY = `SELECT status, COALESCE(sender_host, ''), COALESCE(slot_name, ''), COALESCE(conninfo, ''),
+pg_is_wal_replay_paused(),
+pg_wal_lsn_diff(pg_last_wal_receive_lsn(), pg_last_wal_replay_lsn())::bigint,
+EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp())::bigint
+FROM pg_stat_wal_receiver`
+	// POSTGRES_RECOVERY_QUERY reads the primary a host in recovery is configured to stream from, for replicas whose WAL
+	// receiver is not running. Reading the settings needs the pg_read_all_settings role.
+	POSTGRES_RECOVERY_QUERY = `SELECT pg_is_in_recovery(), COALESCE(current_setting('primary_conninfo', true), ''),
+COALESCE(current_setting('primary_slot_name', true), '')`
+	// POSTGRES_SUBSCRIPTIONS_QUERY reads the logical subscriptions of the database. The apply worker of a subscription is
+	// the row of pg_stat_subscription without a relid. Reading subconninfo needs a superuser or a grant on the column.
+	POSTGRES_SUBSCRIPTIONS_QUERY = `SELECT s.subname, s.subenabled, COALESCE(s.subslotname, ''), s.subconninfo, st.pid IS NOT NULL
+FROM pg_subscription s
+LEFT JOIN pg_stat_subscription st ON st.subid = s.oid AND st.relid IS NULL
+WHERE s.subdbid = (SELECT oid FROM pg_database WHERE datname = current_database())
+ORDER BY s.subname`
+	// POSTGRES_WAL_SENDERS_QUERY reads the WAL senders of a host, to streaming replicas and logical subscribers, with how far
+	// behind the WAL of the host their peer replayed. Hosts in recovery compare to the WAL they received.
+	POSTGRES_WAL_SENDERS_QUERY = `SELECT r.application_name, COALESCE(s.slot_name, ''), r.state,
+pg_wal_lsn_diff(CASE WHEN pg_is_in_recovery() THEN pg_last_wal_receive_lsn() ELSE pg_current_wal_lsn() END, r.replay_lsn)::bigint,
+EXTRACT(EPOCH FROM r.replay_lag)::bigint
+FROM pg_stat_replication r
+LEFT JOIN pg_replication_slots s ON s.active_pid = r.pid`
+	// POSTGRES_REPLICATION_SLOTS_QUERY reads how far behind the WAL of the host each replication slot is, which is the lag
+	// of the replicas and subscribers that are not connected
+	POSTGRES_REPLICATION_SLOTS_QUERY = `SELECT slot_name,
+pg_wal_lsn_diff(CASE WHEN pg_is_in_recovery() THEN pg_last_wal_receive_lsn() ELSE pg_current_wal_lsn() END, COALESCE(confirmed_flush_lsn, restart_lsn))::bigint
+FROM pg_replication_slots`
+)
+
+// defaultWALReceiverName is the application name of WAL receivers which do not set one, without a cluster_name
+const defaultWALReceiverName = "walreceiver"
+
+// PostgresReplicationChannel is a replication channel of a Postgres host. SlotName and ApplicationName identify the
+// channel on its source, where its lag is read from.
+type PostgresReplicationChannel struct {
+	Channel         types.ReplChannel
+	SlotName        string
+	ApplicationName string
+}
+
+// PostgresWALSender is a WAL sender of a Postgres host, to a streaming replica or a logical subscriber
+type PostgresWALSender struct {
+	ApplicationName string
+	SlotName        string
+	State           string
+	// BytesBehind is how much WAL of the host the peer did not replay, ReplayLagSeconds how long ago the WAL it replayed
+	// last was flushed. ReplayLagSeconds is nil when the peer caught up and was idle for a while.
+	BytesBehind      *int64
+	ReplayLagSeconds *int64
+}
+
+// readStreamingChannel reads the channel a host streams WAL from. Hosts which are not in recovery have none.
+func readStreamingChannel(ctx context.Context, connection queryer) (*PostgresReplicationChannel, error) {
+	var (
+		status, senderHost, slotName, conninfo string
+		paused                                 bool
+		lagBytes, lagSeconds                   sql.NullInt64
+	)
+	err := connection.QueryRowContext(ctx, POSTGRES_WAL_RECEIVER_QUERY).
+		Scan(&status, &senderHost, &slotName, &conninfo, &paused, &lagBytes, &lagSeconds)
+	if errors.Is(err, sql.ErrNoRows) {
+		return readRecoveryChannel(ctx, connection)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the wal receiver status : %v", err)
+	}
+
+	channel := newStreamingChannel(senderHost, slotName, conninfo)
+	channel.Channel.IOState = walReceiverState(status)
+	channel.Channel.SQLState = "ON"
+	if paused {
+		channel.Channel.SQLState = "OFF"
+	}
+	if lagBytes.Valid {
+		bytes := max(lagBytes.Int64, 0)
+		channel.Channel.BytesBehindSource = &bytes
+	}
+	if lagSeconds.Valid && channel.Channel.Running() {
+		seconds := max(lagSeconds.Int64, 0)
+		channel.Channel.SecondsBehindSource = &seconds
+	}
+	return channel, nil
+}
+
+// readRecoveryChannel reads the channel of a host in recovery whose WAL receiver is not running
+func readRecoveryChannel(ctx context.Context, connection queryer) (*PostgresReplicationChannel, error) {
+	var (
+		inRecovery         bool
+		conninfo, slotName string
+	)
+	if err := connection.QueryRowContext(ctx, POSTGRES_RECOVERY_QUERY).Scan(&inRecovery, &conninfo, &slotName); err != nil {
+		return nil, fmt.Errorf("unable to read the recovery status : %v", err)
+	}
+	if !inRecovery || conninfo == "" {
+		return nil, nil
+	}
+	channel := newStreamingChannel("", slotName, conninfo)
+	channel.Channel.IOState = "OFF"
+	channel.Channel.SQLState = "OFF"
+	return channel, nil
+}
+
+// newStreamingChannel returns the channel of a WAL receiver. It is named after its slot, or its application name.
+func newStreamingChannel(senderHost, slotName, conninfo string) *PostgresReplicationChannel {
+	if senderHost == "" {
+		senderHost = conninfoValue(conninfo, "host")
+	}
+	applicationName := conninfoValue(conninfo, "application_name")
+	if applicationName == "" {
+		applicationName = defaultWALReceiverName
+	}
+	name := slotName
+	if name == "" {
+		name = applicationName
+	}
+	return &PostgresReplicationChannel{
+		Channel: types.ReplChannel{
+			Name:       name,
+			Kind:       types.ReplicationKindStreaming,
+			SourceHost: senderHost,
+		},
+		SlotName:        slotName,
+		ApplicationName: applicationName,
+	}
+}
+
+// walReceiverState maps the status of a WAL receiver to the state of a replication receiver thread
+func walReceiverState(status string) string {
+	switch status {
+	case "streaming":
+		return "ON"
+	case "starting", "waiting", "restarting":
+		return "CONNECTING"
+	default:
+		return "OFF"
+	}
+}
+
+// readSubscriptions reads the logical subscriptions of the database khub is connected to. Their lag is only known on
+// the publisher.
+func readSubscriptions(ctx context.Context, connection queryer) ([]PostgresReplicationChannel, error) {
+	rows, err := connection.QueryContext(ctx, POSTGRES_SUBSCRIPTIONS_QUERY)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the subscriptions : %v", err)
+	}
+	defer rows.Close()
+
+	channels := []PostgresReplicationChannel{}
+	for rows.Next() {
+		var (
+			name, slotName, conninfo string
+			enabled, running         bool
+		)
+		if err := rows.Scan(&name, &enabled, &slotName, &conninfo, &running); err != nil {
+			return nil, fmt.Errorf("unable to read the subscriptions : %v", err)
+		}
+		if slotName == "" {
+			slotName = name
+		}
+		channel := PostgresReplicationChannel{
+			Channel: types.ReplChannel{
+				Name:       name,
+				Kind:       types.ReplicationKindLogical,
+				SourceHost: conninfoValue(conninfo, "host"),
+				IOState:    "OFF",
+				SQLState:   "OFF",
+			},
+			SlotName:        slotName,
+			ApplicationName: name,
+		}
+		if running {
+			channel.Channel.IOState = "ON"
+		}
+		if enabled {
+			channel.Channel.SQLState = "ON"
+		}
+		channels = append(channels, channel)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("unable to read the subscriptions : %v", err)
+	}
+	return channels, nil
+}
+
+// readWALSenders reads the WAL senders of a host
+func readWALSenders(ctx context.Context, connection queryer) ([]PostgresWALSender, error) {
+	rows, err := connection.QueryContext(ctx, POSTGRES_WAL_SENDERS_QUERY)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the wal senders : %v", err)
+	}
+	defer rows.Close()
+
+	senders := []PostgresWALSender{}
+	for rows.Next() {
+		var (
+			sender               PostgresWALSender
+			lagBytes, lagSeconds sql.NullInt64
+		)
+		if err := rows.Scan(&sender.ApplicationName, &sender.SlotName, &sender.State, &lagBytes, &lagSeconds); err != nil {
+			return nil, fmt.Errorf("unable to read the wal senders : %v", err)
+		}
+		if lagBytes.Valid {
+			bytes := max(lagBytes.Int64, 0)
+			sender.BytesBehind = &bytes
+		}
+		if lagSeconds.Valid {
+			seconds := max(lagSeconds.Int64, 0)
+			sender.ReplayLagSeconds = &seconds
+		}
+		senders = append(senders, sender)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("unable to read the wal senders : %v", err)
+	}
+	return senders, nil
+}
+
+// readReplicationSlots reads how many bytes of WAL each replication slot of a host is behind
+func readReplicationSlots(ctx context.Context, connection queryer) (map[string]int64, error) {
+	rows, err := connection.QueryContext(ctx, POSTGRES_REPLICATION_SLOTS_QUERY)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the replication slots : %v", err)
+	}
+	defer rows.Close()
+
+	slots := map[string]int64{}
+	for rows.Next() {
+		var (
+			name     string
+			lagBytes sql.NullInt64
+		)
+		if err := rows.Scan(&name, &lagBytes); err != nil {
+			return nil, fmt.Errorf("unable to read the replication slots : %v", err)
+		}
+		if lagBytes.Valid {
+			slots[name] = max(lagBytes.Int64, 0)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("unable to read the replication slots : %v", err)
+	}
+	return slots, nil
+}
+
+// setLagFromSource sets the lag of a channel from the WAL sender of its source, matched on its slot or application
+// name, or else from its slot on the source. Channels without a slot only match senders without one. The lag read on
+// the replica is kept when the source has neither.
+func (c *PostgresReplicationChannel) setLagFromSource(source PostgresHostProbe) {
+	for _, sender := range source.Senders {
+		if (c.SlotName != "" && sender.SlotName == c.SlotName) || (c.SlotName == "" && sender.SlotName == "" && sender.ApplicationName == c.ApplicationName) {
+			if sender.BytesBehind != nil {
+				c.Channel.BytesBehindSource = sender.BytesBehind
+			}
+			if sender.ReplayLagSeconds != nil {
+				c.Channel.SecondsBehindSource = sender.ReplayLagSeconds
+			} else if sender.State == "streaming" {
+				caughtUp := int64(0)
+				c.Channel.SecondsBehindSource = &caughtUp
+			}
+			break
+		}
+	}
+	if bytes, ok := source.Slots[c.SlotName]; ok && c.SlotName != "" && c.Channel.BytesBehindSource == nil {
+		c.Channel.BytesBehindSource = &bytes
+	}
+	if !c.Channel.Running() {
+		c.Channel.SecondsBehindSource = nil
+	}
+}
+
+// conninfoValue returns the value of a key of a libpq connection string, in either the key=value or the URI format
+func conninfoValue(conninfo, key string) string {
+	if strings.HasPrefix(conninfo, "postgres://") || strings.HasPrefix(conninfo, "postgresql://") {
+		u, err := url.Parse(conninfo)
+		if err != nil {
+			return ""
+		}
+		if key == "host" {
+			return (&url.URL{Host: strings.Split(u.Host, ",")[0]}).Hostname()
+		}
+		return u.Query().Get(key)
+	}
+
+	for rest := strings.TrimSpace(conninfo); rest != ""; rest = strings.TrimSpace(rest) {
+		eq := strings.IndexByte(rest, '=')
+		if eq < 0 {
+			return ""
+		}
+		k := strings.TrimSpace(rest[:eq])
+		rest = strings.TrimLeft(rest[eq+1:], " ")
+
+		value := strings.Builder{}
+		if strings.HasPrefix(rest, "'") {
+			// Quoted values escape quotes and backslashes with a backslash
+			i := 1
+			for ; i < len(rest) && rest[i] != '\''; i++ {
+				if rest[i] == '\\' && i+1 < len(rest) {
+					i++
+				}
+				value.WriteByte(rest[i])
+			}
+			rest = rest[min(i+1, len(rest)):]
+		} else {
+			end := strings.IndexAny(rest, " \t\n")
+			if end < 0 {
+				end = len(rest)
+			}
+			value.WriteString(rest[:end])
+			rest = rest[end:]
+		}
+		if k == key {
+			if k == "host" {
+				// Multiple hosts are tried in order, the first one is the intended source
+				return strings.Split(value.String(), ",")[0]
+			}
+			return value.String()
+		}
+	}
+	return ""
+}
@@ -0,0 +1,129 @@
+package modules
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/sullivtr/k8s_platform/internal/types"
+)
+
+func TestReadStreamingChannel(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta(POSTGRES_WAL_RECEIVER_QUERY)).
+		WillReturnRows(sqlmock.NewRows([]string{"status", "sender_host", "slot_name", "conninfo", "paused", "lag_bytes", "lag_seconds"}).
+			AddRow("streaming", "pg-core-001.example.com", "", "user=repl host=pg-core-001.example.com application_name='pg core 002'", false, 4096, 3))
+
+	channel, err := readStreamingChannel(context.Background(), db)
+	require.NoError(t, err)
+	require.NotNil(t, channel)
+	assert.Equal(t, "pg core 002", channel.Channel.Name)
+	assert.Equal(t, types.ReplicationKindStreaming, channel.Channel.Kind)
+	assert.Equal(t, "pg-core-001.example.com", channel.Channel.SourceHost)
+	assert.True(t, channel.Channel.Healthy())
+	assert.Equal(t, int64(4096), *channel.Channel.BytesBehindSource)
+	assert.Equal(t, int64(3), *channel.Channel.SecondsBehindSource)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReadStreamingChannelWithoutReceiver(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	receiverColumns := []string{"status", "sender_host", "slot_name", "conninfo", "paused", "lag_bytes", "lag_seconds"}
+	mock.ExpectQuery(regexp.QuoteMeta(POSTGRES_WAL_RECEIVER_QUERY)).WillReturnRows(sqlmock.NewRows(receiverColumns))
+	mock.ExpectQuery(regexp.QuoteMeta(POSTGRES_RECOVERY_QUERY)).
+		WillReturnRows(sqlmock.NewRows([]string{"in_recovery", "primary_conninfo", "primary_slot_name"}).
+			AddRow(true, "postgresql://repl@pg-core-001.example.com:5432/postgres", "pg_core_002"))
+
+	channel, err := readStreamingChannel(context.Background(), db)
+	require.NoError(t, err)
+	require.NotNil(t, channel)
+	assert.Equal(t, "pg_core_002", channel.Channel.Name)
+	assert.Equal(t, "pg-core-001.example.com", channel.Channel.SourceHost)
+	assert.False(t, channel.Channel.Running())
+
+	// Primaries have no streaming channel
+	mock.ExpectQuery(regexp.QuoteMeta(POSTGRES_WAL_RECEIVER_QUERY)).WillReturnRows(sqlmock.NewRows(receiverColumns))
+	mock.ExpectQuery(regexp.QuoteMeta(POSTGRES_RECOVERY_QUERY)).
+		WillReturnRows(sqlmock.NewRows([]string{"in_recovery", "primary_conninfo", "primary_slot_name"}).AddRow(false, "", ""))
+
+	channel, err = readStreamingChannel(context.Background(), db)
+	require.NoError(t, err)
+	assert.Nil(t, channel)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReadSubscriptions(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta(POSTGRES_SUBSCRIPTIONS_QUERY)).
+		WillReturnRows(sqlmock.NewRows([]string{"subname", "subenabled", "subslotname", "subconninfo", "running"}).
+			AddRow("orders_sub", true, "", "host=pg-orders-001.example.com,pg-orders-002.example.com dbname=orders", true).
+			AddRow("billing_sub", false, "billing_slot", "host=pg-billing-001.example.com", false))
+
+	channels, err := readSubscriptions(context.Background(), db)
+	require.NoError(t, err)
+	require.Len(t, channels, 2)
+
+	assert.Equal(t, "orders_sub", channels[0].SlotName, "the slot defaults to the subscription name")
+	assert.Equal(t, "pg-orders-001.example.com", channels[0].Channel.SourceHost)
+	assert.Equal(t, types.ReplicationKindLogical, channels[0].Channel.Kind)
+	assert.True(t, channels[0].Channel.Running())
+
+	assert.Equal(t, "billing_slot", channels[1].SlotName)
+	assert.False(t, channels[1].Channel.Running())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSetLagFromSource(t *testing.T) {
+	lag := int64(2)
+	source := PostgresHostProbe{
+		Senders: []PostgresWALSender{
+			{ApplicationName: "walreceiver", SlotName: "pg_core_002", State: "streaming", BytesBehind: ptr(int64(8192)), ReplayLagSeconds: &lag},
+			{ApplicationName: "walreceiver", State: "streaming", BytesBehind: ptr(int64(0))},
+		},
+		Slots: map[string]int64{"pg_core_002": 8192, "orders_sub": 1 << 20},
+	}
+
+	streaming := PostgresReplicationChannel{
+		Channel:         types.ReplChannel{IOState: "ON", SQLState: "ON", BytesBehindSource: ptr(int64(10))},
+		SlotName:        "pg_core_002",
+		ApplicationName: "walreceiver",
+	}
+	streaming.setLagFromSource(source)
+	assert.Equal(t, int64(8192), *streaming.Channel.BytesBehindSource)
+	assert.Equal(t, int64(2), *streaming.Channel.SecondsBehindSource)
+
+	// Idle senders have no replay lag, their replica caught up
+	slotless := PostgresReplicationChannel{Channel: types.ReplChannel{IOState: "ON", SQLState: "ON"}, ApplicationName: "walreceiver"}
+	slotless.setLagFromSource(source)
+	assert.Equal(t, int64(0), *slotless.Channel.SecondsBehindSource)
+
+	// Stopped subscriptions only have the lag of their slot
+	logical := PostgresReplicationChannel{Channel: types.ReplChannel{IOState: "OFF", SQLState: "ON"}, SlotName: "orders_sub", ApplicationName: "orders_sub"}
+	logical.setLagFromSource(source)
+	assert.Equal(t, int64(1<<20), *logical.Channel.BytesBehindSource)
+	assert.Nil(t, logical.Channel.SecondsBehindSource)
+}
+
+func TestConninfoValue(t *testing.T) {
+	assert.Equal(t, "pg-core-001", conninfoValue("host=pg-core-001 port=5432", "host"))
+	assert.Equal(t, "it's khub", conninfoValue(`user=repl application_name='it\'s khub' host=pg`, "application_name"))
+	assert.Equal(t, "pg-core-001", conninfoValue("postgres://repl@pg-core-001:5432,pg-core-002:5432/app?application_name=khub", "host"))
+	assert.Equal(t, "khub", conninfoValue("postgresql://repl@pg-core-001/app?application_name=khub", "application_name"))
+	assert.Equal(t, "", conninfoValue("user=repl", "host"))
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,79 @@
+package modules
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/sullivtr/k8s_platform/internal/types"
+)
+
+// fakePostgresProber answers probes from a map of host probes. Hosts without a probe fail.
+type fakePostgresProber struct {
+	probes map[string]PostgresHostProbe
+}
+
+func (f fakePostgresProber) Probe(_ context.Context, db types.PostgresDBInfo, _ string) (PostgresHostProbe, error) {
+	probe, ok := f.probes[db.Host]
+	if !ok {
+		return PostgresHostProbe{}, errors.New("connection refused")
+	}
+	return probe, nil
+}
+
+func TestCapturePostgresReplicationTopo(t *testing.T) {
+	replayLag := int64(4)
+	prober := fakePostgresProber{probes: map[string]PostgresHostProbe{
+		"pg-core-001.example.com": {
+			Senders: []PostgresWALSender{
+				{ApplicationName: "walreceiver", SlotName: "pg_core_002", State: "streaming", BytesBehind: ptr(int64(16384)), ReplayLagSeconds: &replayLag},
+				{ApplicationName: "orders_sub", SlotName: "orders_sub", State: "streaming", BytesBehind: ptr(int64(512))},
+			},
+			Slots: map[string]int64{"pg_core_002": 16384, "orders_sub": 512},
+		},
+		"pg-core-002.example.com": {
+			Channels: []PostgresReplicationChannel{{
+				Channel:         types.ReplChannel{Name: "pg_core_002", Kind: types.ReplicationKindStreaming, SourceHost: "pg-core-001.example.com", IOState: "ON", SQLState: "ON"},
+				SlotName:        "pg_core_002",
+				ApplicationName: "walreceiver",
+			}},
+		},
+		"pg-orders-001.example.com": {
+			Channels: []PostgresReplicationChannel{{
+				Channel:         types.ReplChannel{Name: "orders_sub", Kind: types.ReplicationKindLogical, SourceHost: "pg-core-001.example.com", IOState: "ON", SQLState: "ON"},
+				SlotName:        "orders_sub",
+				ApplicationName: "orders_sub",
+			}},
+		},
+	}}
+	databases := []*types.PostgresDBInfo{
+		{Host: "pg-core-001.example.com", Shortname: "pg-core-001", Port: 5432, IsPrimary: true},
+		{Host: "pg-core-002.example.com", Shortname: "pg-core-002", Port: 5432},
+		{Host: "pg-orders-001.example.com", Shortname: "pg-orders-001", Port: 5432},
+		{Host: "pg-down-001.example.com", Shortname: "pg-down-001", Port: 5432},
+	}
+	sdk := PostgresTopoSDK{Databases: databases, Prober: prober}
+
+	capture := sdk.CaptureReplicationTopo(context.Background())
+	require.Len(t, capture.Databases, 4)
+	assert.Empty(t, capture.Consumers)
+
+	primary := capture.Databases[0]
+	assert.ElementsMatch(t, []string{"pg-core-002", "pg-orders-001"}, primary.Replicas)
+
+	replica := capture.Databases[1]
+	assert.Equal(t, "pg-core-001", replica.Source)
+	assert.True(t, replica.ReplicationRunning)
+	assert.Equal(t, int64(16384), *replica.BytesBehindSource)
+	assert.Equal(t, int64(4), *replica.SecondsBehindSource)
+
+	subscriber := capture.Databases[2]
+	assert.Equal(t, "pg-core-001", subscriber.Source)
+	assert.Equal(t, types.ReplicationKindLogical, subscriber.Channels[0].Kind)
+	assert.Equal(t, int64(512), *subscriber.BytesBehindSource)
+	assert.Equal(t, int64(0), *subscriber.SecondsBehindSource, "idle senders have caught up")
+
+	assert.Equal(t, "connection refused", capture.Databases[3].ProbeError)
+}
@@ -0,0 +1,74 @@
+package modules
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sullivtr/k8s_platform/internal/types"
+)
+
+// defaultReplAlertNotifyTimeout bounds a single webhook call of a ReplAlertNotifier without a client
+const defaultReplAlertNotifyTimeout = 10 * time.Second
+
+// ReplAlertNotifier posts replication alerts to their receivers. A nil Client uses a client with a 10 second timeout.
+type ReplAlertNotifier struct {
+	Client *http.Client
+}
+
+// slackMessage is the body of a Slack compatible webhook call
+type slackMessage struct {
+	Text string `json:"text"`
+}
+
+// Notify posts an alert to a receiver. Slack receivers are sent a text message, webhook receivers the alert as json.
+// Responses other than 2xx are returned as errors.
+func (n ReplAlertNotifier) Notify(ctx context.Context, receiver types.ReplAlertReceiver, alert types.ReplAlert) error {
+	var body any = alert
+	if receiver.Type == types.ReplAlertReceiverSlack {
+		body = slackMessage{Text: ReplAlertText(alert)}
+	}
+	b, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("unable to encode alert: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, receiver.URL, bytes.NewReader(b))
+	if err != nil {
+		return fmt.Errorf("unable to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := n.Client
+	if client == nil {
+		client = &http.Client{Timeout: defaultReplAlertNotifyTimeout}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("unable to notify %s: %v", receiver.Name, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("unable to notify %s: %s %s", receiver.Name, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// ReplAlertText returns the text message of an alert
+func ReplAlertText(alert types.ReplAlert) string {
+	switch {
+	case alert.Kind == types.ReplAlertSourceChanged:
+		return fmt.Sprintf(":large_blue_circle: [%s] %s", alert.Topology, alert.Summary)
+	case alert.Status == types.ReplAlertResolved:
+		return fmt.Sprintf(":large_green_circle: [%s] RESOLVED %s: %s", alert.Topology, alert.Rule, alert.Summary)
+	default:
+		return fmt.Sprintf(":red_circle: [%s] FIRING %s: %s", alert.Topology, alert.Rule, alert.Summary)
+	}
+}
@@ -0,0 +1,56 @@
+package modules
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/sullivtr/k8s_platform/internal/types"
+)
+
+func TestReplAlertNotifierNotify(t *testing.T) {
+	bodies := []map[string]any{}
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		body := map[string]any{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		bodies = append(bodies, body)
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte("invalid_token"))
+	}))
+	defer server.Close()
+
+	alert := types.ReplAlert{
+		Fingerprint: "mysql/stopped/db-core-008", Status: types.ReplAlertFiring, Rule: "stopped", Kind: types.ReplAlertReplicationStopped,
+		Topology: types.ReplTopologyMySQL, Host: "db-core-008", Summary: "replication of db-core-008 from db-core-007 stopped",
+		StartsAt: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+	}
+	notifier := ReplAlertNotifier{}
+
+	require.NoError(t, notifier.Notify(context.Background(), types.ReplAlertReceiver{Name: "dba", Type: types.ReplAlertReceiverSlack, URL: server.URL}, alert))
+	require.NoError(t, notifier.Notify(context.Background(), types.ReplAlertReceiver{Name: "pager", Type: types.ReplAlertReceiverWebhook, URL: server.URL}, alert))
+	require.Len(t, bodies, 2)
+	assert.Equal(t, map[string]any{"text": ":red_circle: [mysql] FIRING stopped: replication of db-core-008 from db-core-007 stopped"}, bodies[0])
+	assert.Equal(t, "mysql/stopped/db-core-008", bodies[1]["fingerprint"])
+	assert.Equal(t, types.ReplAlertFiring, bodies[1]["status"])
+
+	status = http.StatusForbidden
+	err := notifier.Notify(context.Background(), types.ReplAlertReceiver{Name: "dba", Type: types.ReplAlertReceiverSlack, URL: server.URL}, alert)
+	assert.EqualError(t, err, "unable to notify dba: 403 Forbidden invalid_token")
+}
+
+func TestReplAlertText(t *testing.T) {
+	endsAt := time.Date(2024, 5, 1, 12, 5, 0, 0, time.UTC)
+	resolved := types.ReplAlert{Status: types.ReplAlertResolved, Rule: "lag", Topology: types.ReplTopologyPostgres, Summary: "pg-core-002 is 120s behind pg-core-001", EndsAt: &endsAt}
+	assert.Equal(t, ":large_green_circle: [postgres] RESOLVED lag: pg-core-002 is 120s behind pg-core-001", ReplAlertText(resolved))
+
+	changed := types.ReplAlert{Status: types.ReplAlertFiring, Kind: types.ReplAlertSourceChanged, Topology: types.ReplTopologyMySQL, Summary: "the source of db-core-008 changed from db-core-007 to db-core-009"}
+	assert.Equal(t, ":large_blue_circle: [mysql] the source of db-core-008 changed from db-core-007 to db-core-009", ReplAlertText(changed))
+}
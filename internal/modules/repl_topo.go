@@ -0,0 +1,84 @@
+package modules
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/sullivtr/k8s_platform/internal/types"
+)
+
+// Defaults of the topology capture, used when the settings of a topology SDK are not set
+const (
+	defaultTopoCaptureWorkers = 8
+	defaultTopoConnectTimeout = 5 * time.Second
+	defaultTopoQueryTimeout   = 10 * time.Second
+	defaultTopoCaptureTimeout = 60 * time.Second
+)
+
+// ReplTopoCapture is a capture of the replication topology of a MySQL or Postgres catalog. Databases are the nodes of
+// the catalog databases, with their replication status and replicas. Consumers are the DMS tasks and CDC connectors
+// reading the binlog of MySQL databases, with their ConsumerType.
+type ReplTopoCapture struct {
+	Databases []*types.ReplDBInfo
+	Consumers []*types.ReplDBInfo
+}
+
+// All returns the databases and the consumers of the capture
+func (c ReplTopoCapture) All() []*types.ReplDBInfo {
+	all := make([]*types.ReplDBInfo, 0, len(c.Databases)+len(c.Consumers))
+	all = append(all, c.Databases...)
+	return append(all, c.Consumers...)
+}
+
+// probeConcurrently runs probe for each of n hosts, by at most workers probes at once, until the context is done.
+// It returns which hosts were probed, hosts still waiting for a worker once the context is done are not.
+func probeConcurrently(ctx context.Context, n, workers int, probe func(i int)) []bool {
+	if workers <= 0 {
+		workers = defaultTopoCaptureWorkers
+	}
+	probed := make([]bool, n)
+	sem := make(chan struct{}, workers)
+	wg := sync.WaitGroup{}
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				return
+			}
+			if ctx.Err() != nil {
+				return
+			}
+			probed[i] = true
+			probe(i)
+		}(i)
+	}
+	wg.Wait()
+	return probed
+}
+
+// setReplicas sets the replicas for each database of the capture.
+// It performs the following steps:
+//  1. Iterates over each database and consumer of the capture.
+//  2. For each database, it iterates over the others to find those that have the current database
+//     as their source and are hosted on different hosts.
+//  3. Adds the short name of the replica to the replica list of the current database.
+//  4. Logs the replicas for each database.
+//
+// This function helps in identifying and setting up the replication relationships between the databases.
+func (c *ReplTopoCapture) setReplicas() {
+	all := c.All()
+	for _, db := range all {
+		for _, sdb := range all {
+			if sdb.Host != db.Host && sdb.Source == db.Shortname {
+				db.AddReplica(sdb.Shortname)
+			}
+		}
+		log.Info().Msgf("Replicas for %s: %v", db.Shortname, db.Replicas)
+	}
+}
@@ -0,0 +1,341 @@
+package modules
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sullivtr/k8s_platform/internal/types"
+)
+
+var (
+	// ErrInvalidReportSignature is returned when a report link is not signed by khub, or has expired
+	ErrInvalidReportSignature = errors.New("invalid or expired report link")
+	// ErrReportExists is returned when a report is sent to an upload link of a report that is already stored
+	ErrReportExists = errors.New("report already exists")
+)
+
+// reportTagsDir is the directory, relative to the reports directory, that holds the tags of each report
+const reportTagsDir = ".tags"
+
+// reportDownloadURLTTL is how long report download links are valid for
+const reportDownloadURLTTL = 5 * time.Minute
+
+// LocalReportSDK stores reports in a local directory. Reports are uploaded to and downloaded from khub itself, through
+// links signed with an HMAC of the request, so that the links behave like s3 presigned urls.
+// Reports are deleted RetentionDays after they are created, they are kept forever when it is 0.
+type LocalReportSDK struct {
+	Dir           string
+	BaseURL       string
+	RetentionDays int
+	signingKey    []byte
+	now           func() time.Time
+}
+
+// NewLocalReportSDK creates the local report storage backend, creating the reports directory if needed.
+// baseURL is the url khub is served at, links point to /api/reports/files on it.
+func NewLocalReportSDK(dir, baseURL, signingKey string, retentionDays int) (*LocalReportSDK, error) {
+	if signingKey == "" {
+		return nil, errors.New("a signing key is required to serve report links")
+	}
+	if err := os.MkdirAll(filepath.Join(dir, reportTagsDir), 0o750); err != nil {
+		return nil, fmt.Errorf("unable to create the reports directory %s : %v", dir, err)
+	}
+
+	return &LocalReportSDK{
+		Dir:           dir,
+		BaseURL:       strings.TrimSuffix(baseURL, "/"),
+		RetentionDays: retentionDays,
+		signingKey:    []byte(signingKey),
+		now:           time.Now,
+	}, nil
+}
+
+func (sdk *LocalReportSDK) GetReports() ([]types.Report, error) {
+	entries, err := os.ReadDir(sdk.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the reports directory : %v", err)
+	}
+
+	reports := []types.Report{}
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return nil, fmt.Errorf("unable to read report %s : %v", entry.Name(), err)
+		}
+		reports = append(reports, sdk.toReport(info))
+	}
+
+	sort.Slice(reports, func(i, j int) bool {
+		return reports[i].LastModified.After(reports[j].LastModified)
+	})
+	return reports, nil
+}
+
+// GetReport fetches a single report. ErrReportNotFound is returned when the report does not exist.
+func (sdk *LocalReportSDK) GetReport(ctx context.Context, reportName string) (types.Report, error) {
+	if !validReportName(reportName) {
+		return types.Report{}, ErrReportNotFound
+	}
+	info, err := os.Stat(filepath.Join(sdk.Dir, reportName))
+	if errors.Is(err, fs.ErrNotExist) {
+		return types.Report{}, ErrReportNotFound
+	}
+	if err != nil {
+		return types.Report{}, fmt.Errorf("unable to read report %s : %v", reportName, err)
+	}
+	return sdk.toReport(info), nil
+}
+
+// GetReportDownloadURL returns a link to the report that is valid for 5 minutes
+func (sdk *LocalReportSDK) GetReportDownloadURL(reportName string) (string, error) {
+	if !validReportName(reportName) {
+		return "", fmt.Errorf("invalid report name %s", reportName)
+	}
+	if _, err := os.Stat(filepath.Join(sdk.Dir, reportName)); err != nil {
+		return "", fmt.Errorf("unable to find report %s : %w", reportName, err)
+	}
+
+	expires := sdk.now().Add(reportDownloadURLTTL).Unix()
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(expires, 10))
+	query.Set("signature", sdk.sign(http.MethodGet, reportName, expires, types.ReportTags{}))
+	return sdk.fileURL(reportName, query), nil
+}
+
+// GetReportUploadURL returns a link to upload a report, valid for 15 minutes. The tags are part of the signature, so the
+// uploader cannot change them.
+func (sdk *LocalReportSDK) GetReportUploadURL(reportName string, tags types.ReportTags) (types.ReportUpload, error) {
+	if !validReportName(reportName) {
+		return types.ReportUpload{}, fmt.Errorf("invalid report name %s", reportName)
+	}
+
+	expiresAt := sdk.now().Add(reportUploadURLTTL)
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(expiresAt.Unix(), 10))
+	query.Set("user", tags.User)
+	query.Set("reason", tags.Reason)
+	query.Set("type", tags.Type)
+	query.Set("signature", sdk.sign(http.MethodPut, reportName, expiresAt.Unix(), tags))
+	return types.ReportUpload{
+		Name:      reportName,
+		URL:       sdk.fileURL(reportName, query),
+		Method:    http.MethodPut,
+		Headers:   map[string]string{},
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// OpenReport opens a report for a signed download link. ErrInvalidReportSignature is returned when the signature does
+// not match or the link has expired.
+func (sdk *LocalReportSDK) OpenReport(reportName string, expires int64, signature string) (*os.File, error) {
+	if err := sdk.verify(http.MethodGet, reportName, expires, types.ReportTags{}, signature); err != nil {
+		return nil, err
+	}
+	return os.Open(filepath.Join(sdk.Dir, reportName))
+}
+
+// ReceiveReport stores a report sent to a signed upload link. ErrInvalidReportSignature is returned when the signature
+// does not match the report name and tags, or the link has expired. Upload links can be replayed until they expire, so
+// ErrReportExists is returned instead of overwriting a report that is already stored.
+func (sdk *LocalReportSDK) ReceiveReport(ctx context.Context, reportName string, expires int64, signature string, tags types.ReportTags, body io.Reader) error {
+	if err := sdk.verify(http.MethodPut, reportName, expires, tags, signature); err != nil {
+		return err
+	}
+	if _, err := os.Stat(filepath.Join(sdk.Dir, reportName)); err == nil {
+		return ErrReportExists
+	}
+	return sdk.writeReport(ctx, reportName, body, tags, false)
+}
+
+// UploadReport writes a report to the reports directory. The report is written to a temporary file first, so that
+// partially uploaded reports are never listed.
+func (sdk *LocalReportSDK) UploadReport(ctx context.Context, reportName string, body io.Reader, tags types.ReportTags) error {
+	return sdk.writeReport(ctx, reportName, body, tags, true)
+}
+
+// writeReport writes a report through a temporary file. Without overwrite, the report is linked into place so that a
+// concurrent upload of the same report fails with ErrReportExists.
+func (sdk *LocalReportSDK) writeReport(ctx context.Context, reportName string, body io.Reader, tags types.ReportTags, overwrite bool) error {
+	if !validReportName(reportName) {
+		return fmt.Errorf("invalid report name %s", reportName)
+	}
+
+	tmp, err := os.CreateTemp(sdk.Dir, ".upload-*")
+	if err != nil {
+		return fmt.Errorf("unable to upload report %s : %v", reportName, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = io.Copy(tmp, contextReader{ctx: ctx, r: body})
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("unable to upload report %s : %w", reportName, err)
+	}
+
+	tagData, err := json.Marshal(tags)
+	if err != nil {
+		return fmt.Errorf("unable to upload report %s : %v", reportName, err)
+	}
+
+	path := filepath.Join(sdk.Dir, reportName)
+	if !overwrite {
+		if err := os.Link(tmp.Name(), path); errors.Is(err, fs.ErrExist) {
+			return ErrReportExists
+		} else if err != nil {
+			return fmt.Errorf("unable to upload report %s : %v", reportName, err)
+		}
+		if err := os.WriteFile(sdk.tagsPath(reportName), tagData, 0o640); err != nil {
+			_ = os.Remove(path)
+			return fmt.Errorf("unable to write the tags of report %s : %v", reportName, err)
+		}
+		return nil
+	}
+
+	if err := os.WriteFile(sdk.tagsPath(reportName), tagData, 0o640); err != nil {
+		return fmt.Errorf("unable to write the tags of report %s : %v", reportName, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("unable to upload report %s : %v", reportName, err)
+	}
+	return nil
+}
+
+// DeleteReport deletes a report and its tags
+func (sdk *LocalReportSDK) DeleteReport(ctx context.Context, reportName string) error {
+	if !validReportName(reportName) {
+		return ErrReportNotFound
+	}
+	if err := os.Remove(filepath.Join(sdk.Dir, reportName)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("unable to delete report %s : %v", reportName, err)
+	}
+	if err := os.Remove(sdk.tagsPath(reportName)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("unable to delete the tags of report %s : %v", reportName, err)
+	}
+	return nil
+}
+
+// Refresh deletes the reports that are past their retention
+func (sdk *LocalReportSDK) Refresh(ctx context.Context) error {
+	if sdk.RetentionDays <= 0 {
+		return nil
+	}
+
+	reports, err := sdk.GetReports()
+	if err != nil {
+		return err
+	}
+	for _, r := range reports {
+		if expiresAt := sdk.expiresAt(r.LastModified); expiresAt != nil && !sdk.now().Before(*expiresAt) {
+			if err := sdk.DeleteReport(ctx, r.Name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (sdk *LocalReportSDK) toReport(info fs.FileInfo) types.Report {
+	tags := sdk.readTags(info.Name())
+	size, sizeUnits := reportSize(info.Size())
+
+	return types.Report{
+		Name:         info.Name(),
+		LastModified: info.ModTime(),
+		Created:      info.ModTime().Format(time.UnixDate),
+		Expires:      formatReportExpiry(sdk.expiresAt(info.ModTime())),
+		Bucket:       sdk.Dir,
+		Size:         size,
+		SizeUnits:    sizeUnits,
+		User:         strings.ReplaceAll(tags.User, "@gmail.com", ""),
+		Reason:       tags.Reason,
+		Type:         tags.Type,
+	}
+}
+
+func (sdk *LocalReportSDK) expiresAt(createdAt time.Time) *time.Time {
+	if sdk.RetentionDays <= 0 {
+		return nil
+	}
+	expiresAt := createdAt.AddDate(0, 0, sdk.RetentionDays)
+	return &expiresAt
+}
+
+// readTags reads the tags of a report. Missing tags are reported as unspecified, like untagged s3 objects.
+func (sdk *LocalReportSDK) readTags(reportName string) types.ReportTags {
+	tags := types.ReportTags{}
+	if data, err := os.ReadFile(sdk.tagsPath(reportName)); err == nil {
+		_ = json.Unmarshal(data, &tags)
+	}
+
+	for _, tag := range []*string{&tags.User, &tags.Reason, &tags.Type} {
+		if *tag == "" {
+			*tag = "unspecified"
+		}
+	}
+	return tags
+}
+
+func (sdk *LocalReportSDK) tagsPath(reportName string) string {
+	return filepath.Join(sdk.Dir, reportTagsDir, reportName+".json")
+}
+
+func (sdk *LocalReportSDK) fileURL(reportName string, query url.Values) string {
+	return fmt.Sprintf("%s/api/reports/files/%s?%s", sdk.BaseURL, url.PathEscape(reportName), query.Encode())
+}
+
+// sign returns the signature of a link. It covers the method, so download links cannot be used to upload, and the tags
+// of uploads.
+func (sdk *LocalReportSDK) sign(method, reportName string, expires int64, tags types.ReportTags) string {
+	mac := hmac.New(sha256.New, sdk.signingKey)
+	mac.Write([]byte(strings.Join([]string{method, reportName, strconv.FormatInt(expires, 10), tags.User, tags.Reason, tags.Type}, "\n")))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (sdk *LocalReportSDK) verify(method, reportName string, expires int64, tags types.ReportTags, signature string) error {
+	if !validReportName(reportName) || !hmac.Equal([]byte(signature), []byte(sdk.sign(method, reportName, expires, tags))) {
+		return ErrInvalidReportSignature
+	}
+	if sdk.now().Unix() > expires {
+		return ErrInvalidReportSignature
+	}
+	return nil
+}
+
+// validReportName reports whether a report name is a single, visible file name, so it cannot escape the reports directory
+func validReportName(reportName string) bool {
+	return reportName != "" && !strings.HasPrefix(reportName, ".") && !strings.ContainsAny(reportName, `/\`)
+}
+
+// contextReader stops reading once the context is done
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
@@ -0,0 +1,151 @@
+package modules
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/sullivtr/k8s_platform/internal/types"
+)
+
+func newTestLocalReportSDK(t *testing.T) *LocalReportSDK {
+	sdk, err := NewLocalReportSDK(t.TempDir(), "http://localhost:3000/", "signing-key", 90)
+	require.NoError(t, err)
+	return sdk
+}
+
+func TestLocalReportUploadAndList(t *testing.T) {
+	sdk := newTestLocalReportSDK(t)
+
+	tags := types.ReportTags{User: "jane.doe@gmail.com", Reason: "latency spike", Type: "heapdump"}
+	require.NoError(t, sdk.UploadReport(context.Background(), "checkout-1-heap.hprof", strings.NewReader("heap"), tags))
+	require.NoError(t, os.WriteFile(filepath.Join(sdk.Dir, "untagged.txt"), []byte("report"), 0o640))
+
+	reports, err := sdk.GetReports()
+	require.NoError(t, err)
+	require.Len(t, reports, 2)
+
+	byName := map[string]types.Report{}
+	for _, r := range reports {
+		byName[r.Name] = r
+	}
+	assert.Equal(t, "jane.doe", byName["checkout-1-heap.hprof"].User)
+	assert.Equal(t, "latency spike", byName["checkout-1-heap.hprof"].Reason)
+	assert.Equal(t, "heapdump", byName["checkout-1-heap.hprof"].Type)
+	assert.Equal(t, "unspecified", byName["untagged.txt"].Type)
+
+	assert.Error(t, sdk.UploadReport(context.Background(), "../escape", strings.NewReader("x"), tags))
+	assert.Error(t, sdk.UploadReport(context.Background(), ".hidden", strings.NewReader("x"), tags))
+}
+
+func TestLocalReportSignedDownload(t *testing.T) {
+	sdk := newTestLocalReportSDK(t)
+	now := time.Unix(1700000000, 0)
+	sdk.now = func() time.Time { return now }
+	require.NoError(t, sdk.UploadReport(context.Background(), "heap.hprof", strings.NewReader("heap"), types.ReportTags{}))
+
+	link, err := sdk.GetReportDownloadURL("heap.hprof")
+	require.NoError(t, err)
+	u, err := url.Parse(link)
+	require.NoError(t, err)
+	assert.Equal(t, "http://localhost:3000/api/reports/files/heap.hprof", u.Scheme+"://"+u.Host+u.Path)
+
+	expires, err := strconv.ParseInt(u.Query().Get("expires"), 10, 64)
+	require.NoError(t, err)
+	signature := u.Query().Get("signature")
+
+	file, err := sdk.OpenReport("heap.hprof", expires, signature)
+	require.NoError(t, err)
+	file.Close()
+
+	_, err = sdk.OpenReport("heap.hprof", expires+60, signature)
+	assert.ErrorIs(t, err, ErrInvalidReportSignature)
+
+	_, err = sdk.OpenReport("other.hprof", expires, signature)
+	assert.ErrorIs(t, err, ErrInvalidReportSignature)
+
+	now = now.Add(reportDownloadURLTTL + time.Second)
+	_, err = sdk.OpenReport("heap.hprof", expires, signature)
+	assert.ErrorIs(t, err, ErrInvalidReportSignature)
+
+	_, err = sdk.GetReportDownloadURL("missing.hprof")
+	assert.Error(t, err)
+}
+
+func TestLocalReportSignedUpload(t *testing.T) {
+	sdk := newTestLocalReportSDK(t)
+	tags := types.ReportTags{User: "jane.doe@gmail.com", Reason: "incident 42", Type: "heapdump"}
+
+	upload, err := sdk.GetReportUploadURL("heap.hprof", tags)
+	require.NoError(t, err)
+	assert.Equal(t, "PUT", upload.Method)
+	u, err := url.Parse(upload.URL)
+	require.NoError(t, err)
+	expires, err := strconv.ParseInt(u.Query().Get("expires"), 10, 64)
+	require.NoError(t, err)
+	signature := u.Query().Get("signature")
+
+	tampered := tags
+	tampered.Type = "audit"
+	err = sdk.ReceiveReport(context.Background(), "heap.hprof", expires, signature, tampered, strings.NewReader("heap"))
+	assert.ErrorIs(t, err, ErrInvalidReportSignature)
+
+	// Download links cannot be used to upload
+	err = sdk.ReceiveReport(context.Background(), "heap.hprof", expires, sdk.sign("GET", "heap.hprof", expires, types.ReportTags{}), tags, strings.NewReader("heap"))
+	assert.ErrorIs(t, err, ErrInvalidReportSignature)
+
+	require.NoError(t, sdk.ReceiveReport(context.Background(), "heap.hprof", expires, signature, tags, strings.NewReader("heap")))
+	report, err := sdk.GetReport(context.Background(), "heap.hprof")
+	require.NoError(t, err)
+	assert.Equal(t, "incident 42", report.Reason)
+	assert.Equal(t, "heapdump", report.Type)
+	assert.NotEmpty(t, report.Expires)
+
+	// The link cannot be replayed to overwrite the report
+	err = sdk.ReceiveReport(context.Background(), "heap.hprof", expires, signature, tags, strings.NewReader("overwritten"))
+	assert.ErrorIs(t, err, ErrReportExists)
+	data, err := os.ReadFile(filepath.Join(sdk.Dir, "heap.hprof"))
+	require.NoError(t, err)
+	assert.Equal(t, "heap", string(data))
+}
+
+func TestLocalReportDelete(t *testing.T) {
+	sdk := newTestLocalReportSDK(t)
+	require.NoError(t, sdk.UploadReport(context.Background(), "heap.hprof", strings.NewReader("heap"), types.ReportTags{Type: "heapdump"}))
+
+	require.NoError(t, sdk.DeleteReport(context.Background(), "heap.hprof"))
+	_, err := sdk.GetReport(context.Background(), "heap.hprof")
+	assert.ErrorIs(t, err, ErrReportNotFound)
+	_, err = os.Stat(sdk.tagsPath("heap.hprof"))
+	assert.True(t, os.IsNotExist(err))
+
+	assert.ErrorIs(t, sdk.DeleteReport(context.Background(), "../escape"), ErrReportNotFound)
+}
+
+func TestLocalReportRetention(t *testing.T) {
+	sdk := newTestLocalReportSDK(t)
+	sdk.RetentionDays = 30
+	require.NoError(t, sdk.UploadReport(context.Background(), "old.hprof", strings.NewReader("old"), types.ReportTags{}))
+	require.NoError(t, sdk.UploadReport(context.Background(), "new.hprof", strings.NewReader("new"), types.ReportTags{}))
+
+	now := time.Now()
+	require.NoError(t, os.Chtimes(filepath.Join(sdk.Dir, "old.hprof"), now.AddDate(0, 0, -31), now.AddDate(0, 0, -31)))
+
+	require.NoError(t, sdk.Refresh(context.Background()))
+	reports, err := sdk.GetReports()
+	require.NoError(t, err)
+	require.Len(t, reports, 1)
+	assert.Equal(t, "new.hprof", reports[0].Name)
+
+	sdk.RetentionDays = 0
+	report, err := sdk.GetReport(context.Background(), "new.hprof")
+	require.NoError(t, err)
+	assert.Empty(t, report.Expires)
+}
@@ -0,0 +1,50 @@
+package modules
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/sullivtr/k8s_platform/internal/types"
+)
+
+// ErrReportNotFound is returned when a report does not exist
+var ErrReportNotFound = errors.New("report not found")
+
+// reportUploadURLTTL is how long report upload links are valid for
+const reportUploadURLTTL = 15 * time.Minute
+
+// ReportStorage is a backend storing khub reports, such as heap dumps and diagnostics
+type ReportStorage interface {
+	GetReports() ([]types.Report, error)
+	GetReport(ctx context.Context, reportName string) (types.Report, error)
+	GetReportDownloadURL(reportName string) (string, error)
+	GetReportUploadURL(reportName string, tags types.ReportTags) (types.ReportUpload, error)
+	UploadReport(ctx context.Context, reportName string, body io.Reader, tags types.ReportTags) error
+	DeleteReport(ctx context.Context, reportName string) error
+	// Refresh runs the periodic maintenance of the backend, such as refreshing caches and deleting expired reports
+	Refresh(ctx context.Context) error
+}
+
+// Compile time proof of implementation
+var (
+	_ ReportStorage = (*AWSSDK)(nil)
+	_ ReportStorage = (*LocalReportSDK)(nil)
+)
+
+// reportSize returns the size of a report in the units it is displayed in
+func reportSize(size int64) (int64, string) {
+	if size > 1000000 {
+		return size / 1000000, "MB"
+	}
+	return size / 1000, "KB"
+}
+
+// formatReportExpiry returns the displayed expiry of a report, which is empty when the report does not expire
+func formatReportExpiry(expiresAt *time.Time) string {
+	if expiresAt == nil {
+		return ""
+	}
+	return expiresAt.Format(time.UnixDate)
+}
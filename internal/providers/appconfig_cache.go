@@ -0,0 +1,125 @@
+package providers
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/sullivtr/k8s_platform/internal/types"
+)
+
+// dynamicAppConfigChannel is the redis pub/sub channel used to announce dynamic app config updates.
+// The message is the new version of the config.
+const dynamicAppConfigChannel = "khub:dynamic_app_config"
+
+// appConfigCache holds the dynamic app config in process.
+// Entries are invalidated when an update is announced, and expire after the ttl in case an announcement is missed.
+type appConfigCache struct {
+	mu       sync.RWMutex
+	config   *types.DynamicAppConfig
+	loadedAt time.Time
+	ttl      time.Duration
+	// minVersion is the highest version announced, older configs loaded concurrently with the announcement are not cached
+	minVersion int
+}
+
+func newAppConfigCache(ttl time.Duration) *appConfigCache {
+	return &appConfigCache{ttl: ttl}
+}
+
+// get returns the cached config, if it has not expired. A nil cache never holds a config.
+func (c *appConfigCache) get() (types.DynamicAppConfig, bool) {
+	if c == nil {
+		return types.DynamicAppConfig{}, false
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.config == nil || time.Since(c.loadedAt) > c.ttl {
+		return types.DynamicAppConfig{}, false
+	}
+	return *c.config, true
+}
+
+// set caches a config, unless a newer version is already cached or was announced
+func (c *appConfigCache) set(config types.DynamicAppConfig) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if (c.config != nil && c.config.Version > config.Version) || config.Version < c.minVersion {
+		return
+	}
+	c.config = &config
+	c.loadedAt = time.Now()
+}
+
+// invalidate drops the cached config if it is older than version. A version of 0 always drops it.
+func (c *appConfigCache) invalidate(version int) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.minVersion = max(c.minVersion, version)
+	if version > 0 && c.config != nil && c.config.Version >= version {
+		return
+	}
+	c.config = nil
+}
+
+// InvalidateDynamicAppConfig drops the in-process copy of the dynamic app config if it is older than version
+func (p *StorageProvider) InvalidateDynamicAppConfig(version int) {
+	p.appConfigCache.invalidate(version)
+}
+
+// dynamicAppConfigChanged caches an updated config and announces it to other khub processes
+func (p *StorageProvider) dynamicAppConfigChanged(config types.DynamicAppConfig) {
+	p.appConfigCache.set(config)
+	if p.appConfigPublisher != nil {
+		p.appConfigPublisher(config.Version)
+	}
+}
+
+// PublishDynamicAppConfigChanges announces dynamic app config updates made through the storage provider to the other
+// khub processes, so that they reload it. It requires the cache provider.
+func (p *ModuleProviders) PublishDynamicAppConfigChanges() {
+	p.StorageProvider.appConfigPublisher = func(version int) {
+		if err := p.CacheProvider.PublishDynamicAppConfigChange(version); err != nil {
+			log.Error().Msgf("unable to announce dynamic app config version %d: %s", version, err.Error())
+		}
+	}
+}
+
+// WatchDynamicAppConfig caches the dynamic app config in process instead of reading it from postgres on every use.
+// The cache is invalidated whenever any khub process updates the config. It requires the storage and cache providers,
+// and must be called before they are used concurrently.
+func (p *ModuleProviders) WatchDynamicAppConfig(ctx context.Context) {
+	ttl := time.Duration(p.Config.AppConfigCacheTTLSeconds) * time.Second
+	p.StorageProvider.appConfigCache = newAppConfigCache(ttl)
+	p.PublishDynamicAppConfigChanges()
+	go p.CacheProvider.SubscribeDynamicAppConfigChanges(ctx, p.StorageProvider.InvalidateDynamicAppConfig)
+}
+
+// PublishDynamicAppConfigChange announces a new version of the dynamic app config
+func (p *CacheProvider) PublishDynamicAppConfigChange(version int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return p.Session.SDK.Publish(ctx, dynamicAppConfigChannel, strconv.Itoa(version))
+}
+
+// SubscribeDynamicAppConfigChanges calls onChange with the version of every announced dynamic app config update
+// until the context is cancelled. onChange is called with version 0 whenever the subscription is (re)established,
+// since updates may have been missed while disconnected.
+func (p *CacheProvider) SubscribeDynamicAppConfigChanges(ctx context.Context, onChange func(version int)) {
+	p.Session.SDK.Subscribe(ctx, dynamicAppConfigChannel, func() { onChange(0) }, func(payload string) {
+		version, err := strconv.Atoi(payload)
+		if err != nil {
+			log.Warn().Msgf("ignoring invalid dynamic app config announcement: %s", payload)
+			return
+		}
+		onChange(version)
+	})
+}
@@ -0,0 +1,142 @@
+package providers
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/sullivtr/k8s_platform/internal/modules"
+	"github.com/sullivtr/k8s_platform/internal/types"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func newMockStorageProvider(t *testing.T) (*StorageProvider, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{Conn: db, PreferSimpleProtocol: true}), &gorm.Config{})
+	require.NoError(t, err)
+	return &StorageProvider{Session: StorageSession{SDK: modules.NewPGSDK(gormDB)}}, mock
+}
+
+func expectAppConfigQuery(mock sqlmock.Sqlmock, version int, clusterName string) {
+	data, _ := types.DynamicConfigJSONB{K8sClusterName: clusterName}.Value()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "dynamic_app_configs" WHERE id = 1`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "version", "data"}).AddRow(1, version, data))
+}
+
+func TestGetDynamicAppConfigCache(t *testing.T) {
+	p, mock := newMockStorageProvider(t)
+	p.appConfigCache = newAppConfigCache(time.Minute)
+
+	expectAppConfigQuery(mock, 1, "prod")
+	for i := 0; i < 3; i++ {
+		dac, err := p.GetDynamicAppConfig()
+		require.NoError(t, err)
+		assert.Equal(t, "prod", dac.Data.K8sClusterName)
+	}
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	// The announcement of a version that is already cached is ignored
+	p.InvalidateDynamicAppConfig(1)
+	_, err := p.GetDynamicAppConfig()
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	// A newer version is reloaded
+	p.InvalidateDynamicAppConfig(2)
+	expectAppConfigQuery(mock, 2, "prod-2")
+	dac, err := p.GetDynamicAppConfig()
+	require.NoError(t, err)
+	assert.Equal(t, "prod-2", dac.Data.K8sClusterName)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAppConfigCacheDropsConfigsOlderThanAnnounced(t *testing.T) {
+	c := newAppConfigCache(time.Minute)
+
+	// A reader loads version 1 while version 2 is announced to the empty cache, and caches what it read afterwards
+	c.invalidate(2)
+	c.set(types.DynamicAppConfig{Version: 1})
+	_, ok := c.get()
+	assert.False(t, ok)
+
+	c.set(types.DynamicAppConfig{Version: 2})
+	dac, ok := c.get()
+	require.True(t, ok)
+	assert.Equal(t, 2, dac.Version)
+}
+
+func TestGetDynamicAppConfigCacheExpires(t *testing.T) {
+	p, mock := newMockStorageProvider(t)
+	p.appConfigCache = newAppConfigCache(0)
+
+	expectAppConfigQuery(mock, 1, "prod")
+	expectAppConfigQuery(mock, 1, "prod")
+	for i := 0; i < 2; i++ {
+		_, err := p.GetDynamicAppConfig()
+		require.NoError(t, err)
+	}
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateDynamicAppConfigAnnouncesVersion(t *testing.T) {
+	p, mock := newMockStorageProvider(t)
+	p.appConfigCache = newAppConfigCache(time.Minute)
+	announced := []int{}
+	p.appConfigPublisher = func(version int) { announced = append(announced, version) }
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "dynamic_app_configs"`)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "dynamic_app_config_versions"`)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	_, err := p.UpdateDynamicAppConfig(types.DynamicAppConfig{ID: 1, Version: 4, Data: types.DynamicConfigJSONB{K8sClusterName: "prod"}})
+	require.NoError(t, err)
+	assert.Equal(t, []int{5}, announced)
+
+	// The updating process serves the new version without reading it back
+	dac, err := p.GetDynamicAppConfig()
+	require.NoError(t, err)
+	assert.Equal(t, 5, dac.Version)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateDynamicAppConfigRejectsInvalidConfig(t *testing.T) {
+	p, mock := newMockStorageProvider(t)
+	p.appConfigPublisher = func(version int) { t.Fatalf("version %d of an invalid config was announced", version) }
+
+	_, err := p.UpdateDynamicAppConfig(types.DynamicAppConfig{ID: 1, Version: 4, Data: types.DynamicConfigJSONB{K8sClusterName: "prod", DefaultReplicaScaleLimit: -1}})
+	assert.ErrorIs(t, err, ErrInvalidAppConfig)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRollbackDynamicAppConfigRejectsInvalidVersion(t *testing.T) {
+	p, mock := newMockStorageProvider(t)
+
+	data, _ := types.DynamicConfigJSONB{K8sClusterName: "prod", DefaultReplicaScaleLimit: -1}.Value()
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "dynamic_app_config_versions"`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "version", "data"}).AddRow(1, 2, data))
+	mock.ExpectRollback()
+
+	_, err := p.RollbackDynamicAppConfig(2, 4, "admin")
+	assert.ErrorIs(t, err, ErrInvalidAppConfig)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStorageProviderWithoutAppConfigCache(t *testing.T) {
+	p, mock := newMockStorageProvider(t)
+
+	expectAppConfigQuery(mock, 1, "prod")
+	expectAppConfigQuery(mock, 1, "prod")
+	for i := 0; i < 2; i++ {
+		_, err := p.GetDynamicAppConfig()
+		require.NoError(t, err)
+	}
+	p.InvalidateDynamicAppConfig(0)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
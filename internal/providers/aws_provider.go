@@ -1,13 +1,37 @@
 package providers
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"time"
+
 	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/rs/zerolog/log"
+	"github.com/sullivtr/k8s_platform/internal/config"
 	"github.com/sullivtr/k8s_platform/internal/modules"
 	"github.com/sullivtr/k8s_platform/internal/types"
 )
 
-// AWSProvider is a port for the aws client.
-// It provides an api for interacting with aws services for the needs of khub.
+var (
+	// ErrReportLinkInvalid is returned when a report link is not signed by khub, or has expired
+	ErrReportLinkInvalid = errors.New("invalid or expired report link")
+	// ErrReportNotFound is returned when a report does not exist
+	ErrReportNotFound = errors.New("report not found")
+	// ErrReportExists is returned when a report is sent to the upload link of a report that is already stored
+	ErrReportExists = errors.New("report already exists")
+	// ErrReportTooLarge is returned when an uploaded report is larger than the maximum upload size
+	ErrReportTooLarge = errors.New("report is larger than the maximum upload size")
+	// ErrRDSUnavailable is returned when RDS discovery is used without an AWS session
+	ErrRDSUnavailable = errors.New("no aws session is available for rds discovery")
+)
+
+// AWSProvider is a port for the report storage.
+// It provides an api for storing and downloading khub reports, in s3 (or an s3 compatible service) or a local directory.
 type AWSProvider struct {
 	Session AWSSession
 }
@@ -15,30 +39,179 @@ type AWSProvider struct {
 // Compile time proof of implementation
 var _ IAWSProvider = (*AWSProvider)(nil)
 
-// AWS session represents the AWS session, and the account it belongs to
+// AWS session represents the report storage backend, and the RDS client used to discover MySQL hosts
 type AWSSession struct {
-	SDK *modules.AWSSDK
+	SDK modules.ReportStorage
+	RDS *modules.RDSSDK
 }
 
-// InitAWSProvider will initialize the AWSProvider implementation.
+// InitAWSProvider will initialize the AWSProvider implementation, using the configured report storage backend.
+// RDS discovery shares the AWS session of the s3 report storage, and gets its own session otherwise.
 func (p *ModuleProviders) InitAWSProvider() {
-	sess := session.Must(session.NewSession())
+	var storage modules.ReportStorage
+	var sess *session.Session
+	switch p.Config.ReportsStorage {
+	case config.ReportsStorageLocal:
+		signingKey := p.Config.ReportsSigningKey
+		if signingKey == "" {
+			signingKey = p.Config.AuthSessionHandlerKey
+		}
 
-	awsSDK := modules.NewAWSSDK(sess, p.Config.AWSRegion, p.Config.ReportsBucket)
+		localSDK, err := modules.NewLocalReportSDK(p.Config.ReportsLocalDir, p.Config.BaseURL, signingKey, p.Config.ReportsLocalRetentionDays)
+		if err != nil {
+			log.Fatal().Msgf("unable to initialize the local report storage: %s", err.Error())
+		}
+		storage = localSDK
+	case config.ReportsStorageS3, "":
+		sess = session.Must(session.NewSession())
+		storage = modules.NewAWSSDK(sess, p.Config.AWSRegion, p.Config.ReportsBucket, p.Config.ReportsS3Endpoint, p.Config.ReportsS3ForcePathStyle)
+	default:
+		log.Fatal().Msgf("unknown report storage %s, expected %s or %s", p.Config.ReportsStorage, config.ReportsStorageS3, config.ReportsStorageLocal)
+	}
+
+	var rdsSDK *modules.RDSSDK
+	if sess == nil {
+		var err error
+		if sess, err = session.NewSession(); err != nil {
+			log.Warn().Msgf("unable to create an aws session, rds discovery is unavailable: %s", err.Error())
+		}
+	}
+	if sess != nil {
+		rdsSDK = modules.NewRDSSDK(sess, p.Config.AWSRegion)
+	}
 
 	p.AWSProvider = &AWSProvider{
 		Session: AWSSession{
-			SDK: awsSDK,
+			SDK: storage,
+			RDS: rdsSDK,
 		},
 	}
 }
 
-// GetS3Reports will fetch the list of reports from the configured S3 bucket
-func (p *AWSProvider) GetS3Reports() ([]types.Report, error) {
-	return p.Session.SDK.GetReports()
+// GetReports will fetch a page of the reports matching the filter from the configured report storage
+func (p *AWSProvider) GetReports(filter types.ReportFilter) (types.ReportPage, error) {
+	reports, err := p.Session.SDK.GetReports()
+	if err != nil {
+		return types.ReportPage{}, err
+	}
+	return types.PaginateReports(reports, filter)
+}
+
+// RefreshReportStorage refreshes the report storage in the background, until the context is done
+func (p *ModuleProviders) RefreshReportStorage(ctx context.Context) {
+	interval := time.Duration(p.Config.ReportsRefreshIntervalSeconds) * time.Second
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if err := p.AWSProvider.Session.SDK.Refresh(ctx); err != nil && ctx.Err() == nil {
+				log.Error().Msgf("unable to refresh the report storage: %s", err.Error())
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// GetReport will fetch a single report
+func (p *AWSProvider) GetReport(reportName string) (types.Report, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	report, err := p.Session.SDK.GetReport(ctx, reportName)
+	if errors.Is(err, modules.ErrReportNotFound) {
+		return types.Report{}, ErrReportNotFound
+	}
+	if err != nil {
+		return types.Report{}, fmt.Errorf("unable to fetch report: %s", err.Error())
+	}
+	return report, nil
+}
+
+// GetReportUploadURL will generate a link to upload a report with the given tags
+func (p *AWSProvider) GetReportUploadURL(reportName string, tags types.ReportTags) (types.ReportUpload, error) {
+	return p.Session.SDK.GetReportUploadURL(reportName, tags)
+}
+
+// DeleteReport will delete a report
+func (p *AWSProvider) DeleteReport(reportName string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := p.Session.SDK.DeleteReport(ctx, reportName)
+	if errors.Is(err, modules.ErrReportNotFound) {
+		return ErrReportNotFound
+	}
+	return err
 }
 
 // GetReportDownloadURL will generate a presigned URL for the given report
 func (p *AWSProvider) GetReportDownloadURL(reportName string) (string, error) {
 	return p.Session.SDK.GetReportDownloadURL(reportName)
 }
+
+// OpenSignedReport opens a report for a download link signed by khub. Only the local report storage serves reports
+// through khub, s3 links point to the bucket directly.
+func (p *AWSProvider) OpenSignedReport(reportName string, expires int64, signature string) (*os.File, error) {
+	localSDK, ok := p.Session.SDK.(*modules.LocalReportSDK)
+	if !ok {
+		return nil, ErrReportNotFound
+	}
+
+	file, err := localSDK.OpenReport(reportName, expires, signature)
+	if errors.Is(err, modules.ErrInvalidReportSignature) {
+		return nil, ErrReportLinkInvalid
+	}
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrReportNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to open report %s: %s", reportName, err.Error())
+	}
+	return file, nil
+}
+
+// ReceiveSignedReport stores a report sent to an upload link signed by khub. Only the local report storage receives
+// reports through khub.
+func (p *AWSProvider) ReceiveSignedReport(reportName string, expires int64, signature string, tags types.ReportTags, body io.Reader) error {
+	localSDK, ok := p.Session.SDK.(*modules.LocalReportSDK)
+	if !ok {
+		return ErrReportNotFound
+	}
+
+	err := localSDK.ReceiveReport(context.Background(), reportName, expires, signature, tags, body)
+	if errors.Is(err, modules.ErrInvalidReportSignature) {
+		return ErrReportLinkInvalid
+	}
+	if errors.Is(err, modules.ErrReportExists) {
+		return ErrReportExists
+	}
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return ErrReportTooLarge
+	}
+	if err != nil {
+		return fmt.Errorf("unable to store report %s: %s", reportName, err.Error())
+	}
+	return nil
+}
+
+// DiscoverMySQLInstances will propose the MySQL instances of the AWS account
+func (p *AWSProvider) DiscoverMySQLInstances(ctx context.Context) ([]types.MySQLDiscoveryProposal, error) {
+	if p.Session.RDS == nil {
+		return nil, ErrRDSUnavailable
+	}
+	proposals, err := p.Session.RDS.DiscoverMySQLInstances(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to describe rds instances: %s", err.Error())
+	}
+	return proposals, nil
+}
@@ -2,11 +2,13 @@ package providers
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"github.com/rbcervilla/redisstore/v9"
 	"github.com/rs/zerolog/log"
 	"github.com/sullivtr/k8s_platform/internal/modules"
+	"github.com/sullivtr/k8s_platform/internal/types"
 )
 
 // CacheProvider is a port for the applications underlying storage/persistence layer
@@ -59,3 +61,48 @@ func (p *CacheProvider) InitAuthSessionStore() *redisstore.RedisStore {
 
 	return sess
 }
+
+// TrackUserSession records a login session so it can later be listed and revoked by an admin
+func (p *CacheProvider) TrackUserSession(userSession types.UserSession) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return p.Session.SDK.TrackUserSession(ctx, userSession)
+}
+
+// UntrackUserSession removes a login session from the user's session index
+func (p *CacheProvider) UntrackUserSession(email, sessionKey string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return p.Session.SDK.UntrackUserSession(ctx, email, sessionKey)
+}
+
+// GetUserSessions returns the active login sessions of a user
+func (p *CacheProvider) GetUserSessions(email string) ([]types.UserSession, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	userSessions, err := p.Session.SDK.GetUserSessions(ctx, email)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get user sessions: %s", err.Error())
+	}
+	return userSessions, nil
+}
+
+// RevokeUserSession revokes a single login session of a user
+func (p *CacheProvider) RevokeUserSession(email, sessionID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := p.Session.SDK.RevokeUserSession(ctx, email, sessionID); err != nil {
+		return fmt.Errorf("unable to revoke user session: %s", err.Error())
+	}
+	return nil
+}
+
+// RevokeUserSessions revokes every login session of a user
+func (p *CacheProvider) RevokeUserSessions(email string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := p.Session.SDK.RevokeUserSessions(ctx, email); err != nil {
+		return fmt.Errorf("unable to revoke user sessions: %s", err.Error())
+	}
+	return nil
+}
@@ -20,6 +20,10 @@ import (
 // After starting the collection for all resources, the method waits for 10 seconds before starting the collection
 // for the resource tree map. This is to ensure that the latest data for all resources is available when building the resource tree map.
 //
+// The MySQL replication topology is captured every MySQLTopologyCaptureIntervalSeconds, MySQL hosts are discovered
+// every MySQLDiscoveryIntervalSeconds, and the Postgres replication topology is captured every
+// PostgresTopologyCaptureIntervalSeconds, unless they are set to 0.
+//
 // Note: This method runs indefinitely until the provided context is cancelled. It should typically be run in a separate goroutine.
 func (p *ModuleProviders) StartDataSink(ctx context.Context, intervaSecond int) {
 	// Todo: Use a bounded pool for goroutines for more control over the aysnc processes.
@@ -38,6 +42,23 @@ func (p *ModuleProviders) StartDataSink(ctx context.Context, intervaSecond int)
 	modules.Poll(ctx, fastInterval, p.collectConfigMaps)
 	modules.Poll(ctx, slowInterval, p.collectNodes)
 	modules.Poll(ctx, fastInterval, p.collectClusterEvents)
+
+	// The MySQL topology is captured on its own interval. Poll runs each capture to completion before the next one.
+	if p.Config.MySQLTopologyCaptureIntervalSeconds > 0 {
+		p.InitMySQLTopoProvider()
+		topoInterval := time.Duration(p.Config.MySQLTopologyCaptureIntervalSeconds) * time.Second
+		modules.Poll(ctx, topoInterval, func() { p.collectMySQLTopology(ctx) })
+	}
+	if p.Config.MySQLDiscoveryIntervalSeconds > 0 {
+		p.InitAWSProvider()
+		discoveryInterval := time.Duration(p.Config.MySQLDiscoveryIntervalSeconds) * time.Second
+		modules.Poll(ctx, discoveryInterval, func() { p.collectMySQLDiscovery(ctx) })
+	}
+	if p.Config.PostgresTopologyCaptureIntervalSeconds > 0 {
+		p.InitPostgresTopoProvider()
+		postgresTopoInterval := time.Duration(p.Config.PostgresTopologyCaptureIntervalSeconds) * time.Second
+		modules.Poll(ctx, postgresTopoInterval, func() { p.collectPostgresTopology(ctx) })
+	}
 	time.Sleep(10 * time.Second)
 }
 
@@ -0,0 +1,50 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"time"
+
+	"github.com/sullivtr/k8s_platform/internal/types"
+)
+
+// UploadExecPluginOutputFile copies the output file of an exec plugin out of the pod container and uploads it to the report
+// storage, for at most the plugin timeout. The file is streamed from the pod to the report storage without being buffered in memory.
+// It returns the name of the uploaded report.
+func (p *ModuleProviders) UploadExecPluginOutputFile(namespace, podName string, plugin types.K8sPodExecPlugin, filePath string, tags types.ReportTags) (string, error) {
+	if p.AWSProvider == nil {
+		return "", errors.New("unable to upload the plugin output file: the report storage is not configured")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), plugin.Timeout())
+	defer cancel()
+
+	reportName := execArtifactName(podName, filePath, time.Now())
+	reader, writer := io.Pipe()
+	copyErr := make(chan error, 1)
+	go func() {
+		err := p.K8sProvider.Session.SDK.CopyFileFromPod(ctx, namespace, podName, plugin.Container, filePath, writer)
+		writer.CloseWithError(err)
+		copyErr <- err
+	}()
+
+	uploadErr := p.AWSProvider.Session.SDK.UploadReport(ctx, reportName, reader, tags)
+	// Unblock the copy if the upload stopped reading
+	reader.CloseWithError(io.ErrClosedPipe)
+	if err := <-copyErr; err != nil && !errors.Is(err, io.ErrClosedPipe) {
+		return "", fmt.Errorf("unable to copy the plugin output file %s: %s", filePath, err.Error())
+	}
+	if uploadErr != nil {
+		return "", fmt.Errorf("unable to upload the plugin output file %s: %s", filePath, uploadErr.Error())
+	}
+	return reportName, nil
+}
+
+// execArtifactName returns the report name of an exec plugin output file.
+// Report names are used as a single path segment of the download route, so they never contain '/'.
+func execArtifactName(podName, filePath string, createdAt time.Time) string {
+	return fmt.Sprintf("%s-%s-%s", podName, createdAt.UTC().Format("20060102T150405Z"), path.Base(filePath))
+}
@@ -0,0 +1,139 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog/log"
+	"github.com/sullivtr/k8s_platform/internal/types"
+)
+
+// maxExecJobOutputBytes bounds the output stored for a single exec job. Output past the limit is dropped.
+const maxExecJobOutputBytes = 4 << 20
+
+// ErrExecJobNotFound is returned when an exec job does not exist or has expired
+var ErrExecJobNotFound = errors.New("exec job not found")
+
+// StartExecJob runs a plugin command in a pod in the background, for at most the plugin timeout.
+// Jobs with an OutputFile upload it to the reports bucket once the command succeeds. The job and its output are stored in the cache so that they can be followed from any khub instance.
+func (p *ModuleProviders) StartExecJob(job types.ExecJob, plugin types.K8sPodExecPlugin) (types.ExecJob, error) {
+	job.ID = uuid.NewString()
+	job.Plugin = plugin.Name
+	job.Container = plugin.Container
+	job.Status = types.ExecJobRunning
+	job.StartedAt = time.Now()
+	if err := p.CacheProvider.SaveExecJob(job); err != nil {
+		return types.ExecJob{}, err
+	}
+
+	go p.runExecJob(job, plugin)
+	return job, nil
+}
+
+func (p *ModuleProviders) runExecJob(job types.ExecJob, plugin types.K8sPodExecPlugin) {
+	timeout := plugin.Timeout()
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	output := &execJobOutput{cache: p.CacheProvider, jobID: job.ID}
+	err := p.K8sProvider.Session.SDK.ExecutePodCommand(ctx, job.Namespace, job.Pod, job.Container, job.Command, output, output)
+
+	finishedAt := time.Now()
+	job.FinishedAt = &finishedAt
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		job.Status = types.ExecJobTimedOut
+		job.Error = fmt.Sprintf("the command did not finish within %s", timeout)
+	case err != nil:
+		job.Status = types.ExecJobFailed
+		job.Error = err.Error()
+	default:
+		job.Status = types.ExecJobSucceeded
+	}
+
+	if job.Status == types.ExecJobSucceeded && job.OutputFile != "" && job.ArtifactTags != nil {
+		artifact, err := p.UploadExecPluginOutputFile(job.Namespace, job.Pod, plugin, job.OutputFile, *job.ArtifactTags)
+		if err != nil {
+			job.Status = types.ExecJobFailed
+			job.Error = err.Error()
+		}
+		job.Artifact = artifact
+		finishedAt = time.Now()
+	}
+
+	if err := p.CacheProvider.SaveExecJob(job); err != nil {
+		log.Error().Msgf("unable to save the result of exec job %s: %s", job.ID, err.Error())
+	}
+	log.Info().Msgf("exec job %s (%s on %s/%s) started by %s finished: %s", job.ID, job.Plugin, job.Namespace, job.Pod, job.StartedBy, job.Status)
+}
+
+// execJobOutput is an io.Writer appending the output of a running exec job to the cache.
+// Failing to store output never interrupts the command.
+type execJobOutput struct {
+	mu      sync.Mutex
+	cache   *CacheProvider
+	jobID   string
+	written int
+}
+
+func (w *execJobOutput) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	chunk := b
+	if remaining := maxExecJobOutputBytes - w.written; len(chunk) > remaining {
+		chunk = chunk[:max(remaining, 0)]
+	}
+	if len(chunk) == 0 {
+		return len(b), nil
+	}
+
+	if err := w.cache.AppendExecJobOutput(w.jobID, chunk); err != nil {
+		log.Error().Msgf("unable to store the output of exec job %s: %s", w.jobID, err.Error())
+		return len(b), nil
+	}
+	w.written += len(chunk)
+	return len(b), nil
+}
+
+// SaveExecJob stores an exec job
+func (p *CacheProvider) SaveExecJob(job types.ExecJob) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := p.Session.SDK.SaveExecJob(ctx, job); err != nil {
+		return fmt.Errorf("unable to save exec job: %s", err.Error())
+	}
+	return nil
+}
+
+// GetExecJob fetches an exec job along with its output starting at the given byte offset
+func (p *CacheProvider) GetExecJob(jobID string, offset int64) (types.ExecJob, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	job, err := p.Session.SDK.GetExecJob(ctx, jobID)
+	if errors.Is(err, redis.Nil) {
+		return types.ExecJob{}, ErrExecJobNotFound
+	}
+	if err != nil {
+		return types.ExecJob{}, fmt.Errorf("unable to fetch exec job: %s", err.Error())
+	}
+
+	job.Output, err = p.Session.SDK.GetExecJobOutput(ctx, jobID, offset)
+	if err != nil {
+		return types.ExecJob{}, fmt.Errorf("unable to fetch exec job output: %s", err.Error())
+	}
+	return job, nil
+}
+
+// AppendExecJobOutput appends a chunk of output to an exec job
+func (p *CacheProvider) AppendExecJobOutput(jobID string, chunk []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return p.Session.SDK.AppendExecJobOutput(ctx, jobID, chunk)
+}
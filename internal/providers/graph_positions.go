@@ -0,0 +1,39 @@
+package providers
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/sullivtr/k8s_platform/internal/types"
+)
+
+// GetPinnedReplTopoNodes returns the cached nodes of a replication graph, moved to the positions admins pinned them at.
+// It returns no nodes when the graph was not cached yet.
+func (p *ModuleProviders) GetPinnedReplTopoNodes(graph, cacheKey string) ([]types.ReplTopoTreeNode, error) {
+	data, err := p.CacheProvider.GetNoUnmarshal(cacheKey)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %s nodes: %s", graph, err.Error())
+	}
+	if len(data) == 0 || string(data) == "null" {
+		return nil, nil
+	}
+
+	nodes := []types.ReplTopoTreeNode{}
+	if err := json.Unmarshal(data, &nodes); err != nil {
+		return nil, fmt.Errorf("unable to decode %s nodes: %s", graph, err.Error())
+	}
+	if err := p.PinReplTopoNodes(graph, nodes); err != nil {
+		return nil, err
+	}
+	return nodes, nil
+}
+
+// PinReplTopoNodes moves the nodes of a replication graph to the positions admins pinned them at
+func (p *ModuleProviders) PinReplTopoNodes(graph string, nodes []types.ReplTopoTreeNode) error {
+	pinned, err := p.StorageProvider.GetGraphNodePositions(graph)
+	if err != nil {
+		return err
+	}
+	types.PinReplTopoTreeNodes(nodes, pinned)
+	return nil
+}
@@ -1,6 +1,7 @@
 package providers
 
 import (
+	"bytes"
 	"context"
 	"path/filepath"
 
@@ -145,6 +146,16 @@ func (p *K8sApiProvider) RolloutRestartStatefulSet(namespace, name string) error
 	return p.Session.SDK.RolloutRestartStatefulSet(context.Background(), name, namespace)
 }
 
-func (p *K8sApiProvider) ExecutePodExecPlugin(namespace, podName string, plugin types.K8sPodExecPlugin) (string, string, error) {
-	return p.Session.SDK.ExecutePodExecPlugin(namespace, podName, plugin)
+// ExecutePodExecPlugin runs a plugin command in a pod and waits for it to exit, for at most the plugin timeout.
+// It returns the stdout and stderr of the command.
+func (p *K8sApiProvider) ExecutePodExecPlugin(namespace, podName string, plugin types.K8sPodExecPlugin, command []string) (string, string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), plugin.Timeout())
+	defer cancel()
+
+	buf := &bytes.Buffer{}
+	errBuf := &bytes.Buffer{}
+	if err := p.Session.SDK.ExecutePodCommand(ctx, namespace, podName, plugin.Container, command, buf, errBuf); err != nil {
+		return "", "", err
+	}
+	return buf.String(), errBuf.String(), nil
 }
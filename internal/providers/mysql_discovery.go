@@ -0,0 +1,98 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/sullivtr/k8s_platform/internal/modules"
+	"github.com/sullivtr/k8s_platform/internal/types"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+)
+
+// DiscoverMySQLHosts runs the discovery sources of the dynamic app config. Catalog hosts that are found get their last
+// seen time updated, and the other hosts are proposed to admins. Kubernetes resources are read from the data sink cache.
+// A failing source is logged and does not prevent the others from proposing hosts.
+func (p *ModuleProviders) DiscoverMySQLHosts(ctx context.Context) ([]types.MySQLDiscoveryProposal, error) {
+	dac, err := p.StorageProvider.GetDynamicAppConfig()
+	if err != nil {
+		return nil, err
+	}
+	cfg := dac.Data.MySQLDiscovery
+	if !cfg.Enabled() {
+		return []types.MySQLDiscoveryProposal{}, nil
+	}
+
+	discovered := []types.MySQLDiscoveryProposal{}
+	if len(cfg.Labels) > 0 {
+		hosts, err := p.discoverKubernetesMySQLHosts(dac.Data.K8sClusterName, cfg)
+		if err != nil {
+			log.Error().Msgf("unable to discover mysql hosts from kubernetes: %s", err.Error())
+		}
+		discovered = append(discovered, hosts...)
+	}
+	if cfg.RDS && p.AWSProvider != nil {
+		hosts, err := p.AWSProvider.DiscoverMySQLInstances(ctx)
+		if err != nil {
+			log.Error().Msgf("unable to discover mysql hosts from rds: %s", err.Error())
+		}
+		discovered = append(discovered, hosts...)
+	}
+
+	catalog, err := p.StorageProvider.GetMySQLCatalog()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	seen, proposals := types.ReconcileMySQLDiscovery(catalog, discovered, now)
+	if err := p.StorageProvider.MarkMySQLHostsSeen(seen, now); err != nil {
+		return nil, err
+	}
+	if err := p.StorageProvider.SaveMySQLDiscoveryProposals(proposals); err != nil {
+		return nil, err
+	}
+	return proposals, nil
+}
+
+// discoverKubernetesMySQLHosts proposes the hosts of the cached Services and StatefulSets of the cluster
+func (p *ModuleProviders) discoverKubernetesMySQLHosts(clusterName string, cfg types.MySQLDiscoveryConfig) ([]types.MySQLDiscoveryProposal, error) {
+	services := []v1.Service{}
+	if err := p.getCachedK8sResource(clusterName, "services", &services); err != nil {
+		return nil, err
+	}
+	statefulsets := []appsv1.StatefulSet{}
+	if err := p.getCachedK8sResource(clusterName, "statefulsets", &statefulsets); err != nil {
+		return nil, err
+	}
+	return modules.DiscoverKubernetesMySQLHosts(services, statefulsets, cfg), nil
+}
+
+// getCachedK8sResource decodes the resources the data sink cached for the cluster. Resources that were not collected
+// yet are left empty.
+func (p *ModuleProviders) getCachedK8sResource(clusterName, resource string, into any) error {
+	data, err := p.CacheProvider.GetNoUnmarshal(fmt.Sprintf("%s_%s", clusterName, resource))
+	if err != nil {
+		return fmt.Errorf("unable to read cached %s: %s", resource, err.Error())
+	}
+	if len(data) == 0 || string(data) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(data, into); err != nil {
+		return fmt.Errorf("unable to decode cached %s: %s", resource, err.Error())
+	}
+	return nil
+}
+
+// collectMySQLDiscovery runs the discovery sources for the data sink
+func (p *ModuleProviders) collectMySQLDiscovery(ctx context.Context) {
+	log.Info().Msg("discovering mysql hosts")
+	proposals, err := p.DiscoverMySQLHosts(ctx)
+	if err != nil {
+		log.Error().Msgf("unable to discover mysql hosts: %s", err.Error())
+		return
+	}
+	log.Info().Msgf("discovered %d mysql hosts which are not in the catalog", len(proposals))
+}
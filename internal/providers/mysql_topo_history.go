@@ -0,0 +1,97 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/sullivtr/k8s_platform/internal/types"
+	"gorm.io/gorm"
+)
+
+// Cache keys of the latest capture of the MySQL replication topology
+const (
+	MySQLReplTopoNodesCacheKey   = "mysql-repl-topo-nodes"
+	MySQLReplTopoEdgesCacheKey   = "mysql-repl-topo-edges"
+	MySQLReplTopoChangesCacheKey = "mysql-repl-topo-changes"
+)
+
+// CaptureMySQLTopology captures the replication topology of the MySQL catalog, caches it for the API server and stores
+// it as a snapshot, along with the changes since the previous snapshot. The first snapshot has no changes. Binlog
+// consumers are classified by the consumer rules of the dynamic app config. Snapshots older than MySQLTopologyHistoryDays are pruned. ErrNoMySQLDatabases is
+// returned when the catalog is empty.
+func (p *ModuleProviders) CaptureMySQLTopology(ctx context.Context) (types.ReplTopoSnapshot, error) {
+	catalog, err := p.StorageProvider.GetMySQLCatalog()
+	if err != nil {
+		return types.ReplTopoSnapshot{}, err
+	}
+	p.SetMySQLTopoDatabases(catalog)
+
+	dac, err := p.StorageProvider.GetDynamicAppConfig()
+	if err != nil {
+		return types.ReplTopoSnapshot{}, err
+	}
+	p.MySQLTopoProvider.Session.SDK.Consumers = types.NewMySQLConsumerClassifier(dac.Data.MySQLConsumerRules)
+
+	nodes, edges, err := p.MySQLTopoProvider.CaptureReplicationTopology(ctx)
+	if err != nil {
+		return types.ReplTopoSnapshot{}, err
+	}
+
+	capturedAt := time.Now().UTC()
+	var previous *types.ReplTopoSnapshot
+	if last, err := p.StorageProvider.GetReplTopoSnapshot(capturedAt); err == nil {
+		previous = &last
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return types.ReplTopoSnapshot{}, err
+	}
+	next := types.ReplTopoSnapshot{CapturedAt: capturedAt, Nodes: nodes, Edges: edges}
+	next.Changes = next.ChangesSince(previous)
+	snapshot, err := p.StorageProvider.SaveReplTopoSnapshot(next)
+	if err != nil {
+		return types.ReplTopoSnapshot{}, err
+	}
+
+	if err := p.CacheProvider.Put(MySQLReplTopoNodesCacheKey, nodes); err != nil {
+		return snapshot, fmt.Errorf("unable to cache mysql replication topology nodes: %s", err.Error())
+	}
+	if err := p.CacheProvider.Put(MySQLReplTopoEdgesCacheKey, edges); err != nil {
+		return snapshot, fmt.Errorf("unable to cache mysql replication topology edges: %s", err.Error())
+	}
+	if err := p.CacheProvider.Put(MySQLReplTopoChangesCacheKey, types.ReplTopoSnapshot{ID: snapshot.ID, CapturedAt: capturedAt, Changes: snapshot.Changes}); err != nil {
+		return snapshot, fmt.Errorf("unable to cache mysql replication topology changes: %s", err.Error())
+	}
+
+	if p.Config.MySQLTopologyHistoryDays > 0 {
+		before := capturedAt.AddDate(0, 0, -p.Config.MySQLTopologyHistoryDays)
+		pruned, err := p.StorageProvider.PruneReplTopoSnapshots(before)
+		if err != nil {
+			log.Error().Msg(err.Error())
+		} else if pruned > 0 {
+			log.Info().Msgf("pruned %d mysql replication topology snapshots", pruned)
+		}
+	}
+
+	return snapshot, nil
+}
+
+// collectMySQLTopology captures the MySQL replication topology from the data sink, and evaluates its alerts
+func (p *ModuleProviders) collectMySQLTopology(ctx context.Context) {
+	log.Info().Msg("collecting mysql replication topology")
+	snapshot, err := p.CaptureMySQLTopology(ctx)
+	if errors.Is(err, ErrNoMySQLDatabases) {
+		log.Debug().Msg("the mysql catalog is empty, skipping the replication topology capture")
+		return
+	}
+	if err != nil {
+		log.Error().Msgf("unable to capture mysql replication topology: %s", err.Error())
+		return
+	}
+	log.Info().Msgf("captured mysql replication topology with %d nodes and %d changes", len(snapshot.Nodes), len(snapshot.Changes))
+
+	if err := p.EvaluateReplAlerts(ctx, types.ReplTopologyMySQL, snapshot.Nodes); err != nil {
+		log.Error().Msgf("unable to evaluate mysql replication alerts: %s", err.Error())
+	}
+}
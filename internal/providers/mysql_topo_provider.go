@@ -1,12 +1,22 @@
 package providers
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"time"
 
 	"github.com/sullivtr/k8s_platform/internal/modules"
 	"github.com/sullivtr/k8s_platform/internal/types"
 )
 
+var (
+	// ErrNoMySQLDatabases is returned when the topology is captured without databases in the MySQL catalog
+	ErrNoMySQLDatabases = errors.New("No databases found")
+	// ErrMySQLHostNotFound is returned when a host is not in the MySQL catalog
+	ErrMySQLHostNotFound = errors.New("host not found in the mysql catalog")
+)
+
 // IMySQLTopoProvider represents the interface for the MySQL topology capture provider
 type MySQLTopoProvider struct {
 	Session MySQLTopoSession
@@ -20,49 +30,88 @@ type MySQLTopoSession struct {
 	SDK modules.MySQLTopoSDK
 }
 
+// InitMySQLTopoProvider initializes the MySQL topology provider. Secret credential references are read through the
+// kubernetes provider when it is initialized first.
 func (p *ModuleProviders) InitMySQLTopoProvider() {
 	p.MySQLTopoProvider = &MySQLTopoProvider{
 		Session: MySQLTopoSession{
 			SDK: modules.MySQLTopoSDK{
-				MySQLDBPassword: p.Config.MySQLCatalogDBPassword,
+				MySQLDBPassword:    p.Config.MySQLCatalogDBPassword,
+				CredentialFilesDir: p.Config.CredentialFilesDir,
+				Workers:            p.Config.MySQLTopologyCaptureWorkers,
+				ConnectTimeout:     time.Duration(p.Config.MySQLTopologyConnectTimeoutSeconds) * time.Second,
+				QueryTimeout:       time.Duration(p.Config.MySQLTopologyQueryTimeoutSeconds) * time.Second,
+				CaptureTimeout:     time.Duration(p.Config.MySQLTopologyCaptureTimeoutSeconds) * time.Second,
 			},
 		},
 	}
+	if p.K8sProvider != nil {
+		p.MySQLTopoProvider.Session.SDK.Secrets = &p.K8sProvider.Session.SDK
+	}
+}
+
+// SetMySQLTopoDatabases sets the databases the topology is captured for. The kubernetes provider is initialized when a
+// database reads its password from a kubernetes secret and it is not already available.
+func (p *ModuleProviders) SetMySQLTopoDatabases(databases []*types.MySQLDBInfo) {
+	for _, db := range databases {
+		if db.Credential.Source != types.CredentialSourceSecret {
+			continue
+		}
+		if p.K8sProvider == nil {
+			p.InitK8sProvider()
+		}
+		p.MySQLTopoProvider.Session.SDK.Secrets = &p.K8sProvider.Session.SDK
+		break
+	}
+	p.MySQLTopoProvider.Session.SDK.Databases = databases
 }
 
-func (p *MySQLTopoProvider) CaptureReplicationTopology() ([]types.ReplTopoTreeNode, []types.ReplTopoTreeEdge, error) {
+func (p *MySQLTopoProvider) CaptureReplicationTopology(ctx context.Context) ([]types.ReplTopoTreeNode, []types.ReplTopoTreeEdge, error) {
 	if len(p.Session.SDK.Databases) == 0 {
-		return nil, nil, errors.New("No databases found")
+		return nil, nil, ErrNoMySQLDatabases
 	}
-	p.Session.SDK.CaptureReplicationTopo()
-	p.Session.SDK.SetDBReplicas()
+	capture := p.Session.SDK.CaptureReplicationTopo(ctx)
+	nodes, edges := buildReplTopoTree(capture.All())
+	return nodes, edges, nil
+}
 
+// buildReplTopoTree returns the nodes and edges of the replication graph of captured databases, laid out with sources
+// above their replicas. Databases replicating from each other are linked by a single bidirectional edge, and binlog
+// consumers by an edge of their consumer type.
+func buildReplTopoTree(databases []*types.ReplDBInfo) ([]types.ReplTopoTreeNode, []types.ReplTopoTreeEdge) {
 	nodeMap := make(map[string]bool)
 	edgeMap := make(map[string]bool)
 
 	nodes := []types.ReplTopoTreeNode{}
 	edges := []types.ReplTopoTreeEdge{}
 
-	for _, db := range p.Session.SDK.Databases {
+	dbByShortname := make(map[string]*types.ReplDBInfo)
+	for _, db := range databases {
+		if _, ok := dbByShortname[db.Shortname]; !ok {
+			dbByShortname[db.Shortname] = db
+		}
+	}
+	// linkChannels returns the channels a replica replicates from a source through
+	linkChannels := func(source, replica string) []types.ReplChannel {
+		replicaDB, ok := dbByShortname[replica]
+		if !ok {
+			return nil
+		}
+		channel, ok := replicaDB.ChannelFrom(source)
+		if !ok {
+			return nil
+		}
+		return []types.ReplChannel{channel}
+	}
+
+	for _, db := range databases {
 		if _, ok := nodeMap[db.Shortname]; !ok {
 			nodes = append(nodes, types.ReplTopoTreeNode{
-				ID:   db.Shortname,
-				Data: *db,
-				Position: types.ReplTopoTreeNodePosition{
-					X: 0,
-					Y: 0,
-				},
+				ID:     db.Shortname,
+				Data:   *db,
+				Health: types.ReplicationHealth(db.Channels...),
 			})
 
-			// // If the database is a DMS database, add an edge to the DMS node from its source
-			// if strings.Contains(db.Host, "-dms") {
-			// 	edges = append(edges, types.ReplTopoTreeEdge{
-			// 		ID:       db.Source + "-" + db.Shortname,
-			// 		Source:   db.Source,
-			// 		Target:   db.Shortname,
-			// 		EdgeType: "dms",
-			// 	})
-			// }
 			nodeMap[db.Shortname] = true
 		}
 
@@ -75,21 +124,28 @@ func (p *MySQLTopoProvider) CaptureReplicationTopology() ([]types.ReplTopoTreeNo
 						continue
 					}
 
-					edges = append(edges, types.ReplTopoTreeEdge{
+					edge := types.ReplTopoTreeEdge{
 						ID:       db.Shortname + "-" + replica,
 						Source:   db.Shortname,
 						Target:   replica,
 						EdgeType: "bidirectional",
 						Animated: false,
-					})
+					}
+					edge.SetChannels(append(linkChannels(db.Shortname, replica), linkChannels(replica, db.Shortname)...)...)
+					edges = append(edges, edge)
 				} else {
-					edges = append(edges, types.ReplTopoTreeEdge{
+					edge := types.ReplTopoTreeEdge{
 						ID:       db.Shortname + "-" + replica,
 						Source:   db.Shortname,
 						Target:   replica,
 						EdgeType: "unidirectional",
 						Animated: false,
-					})
+					}
+					if replicaDB, ok := dbByShortname[replica]; ok && replicaDB.ConsumerType != "" {
+						edge.EdgeType = replicaDB.ConsumerType
+					}
+					edge.SetChannels(linkChannels(db.Shortname, replica)...)
+					edges = append(edges, edge)
 				}
 				edgeMap[db.Shortname+"-"+replica] = true
 			}
@@ -97,5 +153,38 @@ func (p *MySQLTopoProvider) CaptureReplicationTopology() ([]types.ReplTopoTreeNo
 		}
 	}
 
-	return nodes, edges, nil
+	types.LayoutReplTopoTree(nodes, edges)
+	return nodes, edges
+}
+
+// RunQueryPlugin runs a query plugin against a catalog database, within the plugin timeout and row limit
+func (p *MySQLTopoProvider) RunQueryPlugin(ctx context.Context, db *types.MySQLDBInfo, plugin types.MySQLQueryPlugin, args map[string]string) (types.MySQLQueryResult, error) {
+	query, queryArgs, err := plugin.BuildQuery(args)
+	if err != nil {
+		return types.MySQLQueryResult{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, plugin.Timeout())
+	defer cancel()
+	result, err := p.Session.SDK.RunQuery(ctx, db, query, queryArgs, plugin.RowLimit())
+	if err != nil {
+		return types.MySQLQueryResult{}, fmt.Errorf("unable to run query plugin %s on %s: %s", plugin.Name, db.Shortname, err.Error())
+	}
+	result.Plugin = plugin.Name
+	return result, nil
+}
+
+// GetMySQLCatalogHost returns the catalog database with the given shortname or host.
+// ErrMySQLHostNotFound is returned when there is none.
+func (p *ModuleProviders) GetMySQLCatalogHost(host string) (*types.MySQLDBInfo, error) {
+	catalog, err := p.StorageProvider.GetMySQLCatalog()
+	if err != nil {
+		return nil, err
+	}
+	for _, db := range catalog {
+		if db.Shortname == host || db.Host == host {
+			return db, nil
+		}
+	}
+	return nil, ErrMySQLHostNotFound
 }
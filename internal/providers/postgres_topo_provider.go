@@ -0,0 +1,130 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/sullivtr/k8s_platform/internal/modules"
+	"github.com/sullivtr/k8s_platform/internal/types"
+)
+
+// ErrNoPostgresDatabases is returned when the topology is captured without databases in the Postgres catalog
+var ErrNoPostgresDatabases = errors.New("No postgres databases found")
+
+// Cache keys of the latest capture of the Postgres replication topology
+const (
+	PostgresReplTopoNodesCacheKey      = "postgres-repl-topo-nodes"
+	PostgresReplTopoEdgesCacheKey      = "postgres-repl-topo-edges"
+	PostgresReplTopoCapturedAtCacheKey = "postgres-repl-topo-captured-at"
+)
+
+// PostgresTopoProvider captures the Postgres replication topology
+type PostgresTopoProvider struct {
+	Session PostgresTopoSession
+}
+
+// Compile time proof of implementation
+var _ IPostgresTopoProvider = (*PostgresTopoProvider)(nil)
+
+// PostgresTopoSession represents the PostgresTopo SDK session
+type PostgresTopoSession struct {
+	SDK modules.PostgresTopoSDK
+}
+
+// InitPostgresTopoProvider initializes the Postgres topology provider. Secret credential references are read through the
+// kubernetes provider when it is initialized first.
+func (p *ModuleProviders) InitPostgresTopoProvider() {
+	p.PostgresTopoProvider = &PostgresTopoProvider{
+		Session: PostgresTopoSession{
+			SDK: modules.PostgresTopoSDK{
+				PostgresDBPassword: p.Config.PostgresCatalogDBPassword,
+				CredentialFilesDir: p.Config.CredentialFilesDir,
+				Workers:            p.Config.PostgresTopologyCaptureWorkers,
+				ConnectTimeout:     time.Duration(p.Config.PostgresTopologyConnectTimeoutSeconds) * time.Second,
+				QueryTimeout:       time.Duration(p.Config.PostgresTopologyQueryTimeoutSeconds) * time.Second,
+				CaptureTimeout:     time.Duration(p.Config.PostgresTopologyCaptureTimeoutSeconds) * time.Second,
+			},
+		},
+	}
+	if p.K8sProvider != nil {
+		p.PostgresTopoProvider.Session.SDK.Secrets = &p.K8sProvider.Session.SDK
+	}
+}
+
+// setPostgresTopoDatabases sets the databases the topology is captured for. The kubernetes provider is initialized when
+// a database reads its password from a kubernetes secret and it is not already available.
+func (p *ModuleProviders) setPostgresTopoDatabases(databases []*types.PostgresDBInfo) {
+	for _, db := range databases {
+		if db.Credential.Source != types.CredentialSourceSecret {
+			continue
+		}
+		if p.K8sProvider == nil {
+			p.InitK8sProvider()
+		}
+		p.PostgresTopoProvider.Session.SDK.Secrets = &p.K8sProvider.Session.SDK
+		break
+	}
+	p.PostgresTopoProvider.Session.SDK.Databases = databases
+}
+
+// CaptureReplicationTopology captures the replication graph of the Postgres databases. Streaming replicas and logical
+// subscribers are both edges from their source, the kind of an edge tells them apart.
+func (p *PostgresTopoProvider) CaptureReplicationTopology(ctx context.Context) ([]types.ReplTopoTreeNode, []types.ReplTopoTreeEdge, error) {
+	if len(p.Session.SDK.Databases) == 0 {
+		return nil, nil, ErrNoPostgresDatabases
+	}
+	capture := p.Session.SDK.CaptureReplicationTopo(ctx)
+	nodes, edges := buildReplTopoTree(capture.All())
+	return nodes, edges, nil
+}
+
+// CapturePostgresTopology captures the replication topology of the Postgres catalog and caches it for the API server.
+// ErrNoPostgresDatabases is returned when the catalog is empty.
+func (p *ModuleProviders) CapturePostgresTopology(ctx context.Context) ([]types.ReplTopoTreeNode, []types.ReplTopoTreeEdge, error) {
+	catalog, err := p.StorageProvider.GetPostgresCatalog()
+	if err != nil {
+		return nil, nil, err
+	}
+	if p.PostgresTopoProvider == nil {
+		p.InitPostgresTopoProvider()
+	}
+	p.setPostgresTopoDatabases(catalog)
+
+	nodes, edges, err := p.PostgresTopoProvider.CaptureReplicationTopology(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := p.CacheProvider.Put(PostgresReplTopoNodesCacheKey, nodes); err != nil {
+		return nodes, edges, fmt.Errorf("unable to cache postgres replication topology nodes: %s", err.Error())
+	}
+	if err := p.CacheProvider.Put(PostgresReplTopoEdgesCacheKey, edges); err != nil {
+		return nodes, edges, fmt.Errorf("unable to cache postgres replication topology edges: %s", err.Error())
+	}
+	if err := p.CacheProvider.Put(PostgresReplTopoCapturedAtCacheKey, time.Now().UTC()); err != nil {
+		return nodes, edges, fmt.Errorf("unable to cache postgres replication topology capture time: %s", err.Error())
+	}
+	return nodes, edges, nil
+}
+
+// collectPostgresTopology captures the Postgres replication topology from the data sink, and evaluates its alerts
+func (p *ModuleProviders) collectPostgresTopology(ctx context.Context) {
+	log.Info().Msg("collecting postgres replication topology")
+	nodes, edges, err := p.CapturePostgresTopology(ctx)
+	if errors.Is(err, ErrNoPostgresDatabases) {
+		log.Debug().Msg("the postgres catalog is empty, skipping the replication topology capture")
+		return
+	}
+	if err != nil {
+		log.Error().Msgf("unable to capture postgres replication topology: %s", err.Error())
+		return
+	}
+	log.Info().Msgf("captured postgres replication topology with %d nodes and %d edges", len(nodes), len(edges))
+
+	if err := p.EvaluateReplAlerts(ctx, types.ReplTopologyPostgres, nodes); err != nil {
+		log.Error().Msgf("unable to evaluate postgres replication alerts: %s", err.Error())
+	}
+}
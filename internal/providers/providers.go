@@ -2,6 +2,9 @@ package providers
 
 import (
 	"context"
+	"io"
+	"os"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/rbcervilla/redisstore/v9"
@@ -23,13 +26,27 @@ type IModuleProviders interface {
 	InitStorageProvider() error
 	InitAWSProvider()
 	InitMySQLTopoProvider()
+	CaptureMySQLTopology(ctx context.Context) (types.ReplTopoSnapshot, error)
+	DiscoverMySQLHosts(ctx context.Context) ([]types.MySQLDiscoveryProposal, error)
+	InitPostgresTopoProvider()
+	CapturePostgresTopology(ctx context.Context) ([]types.ReplTopoTreeNode, []types.ReplTopoTreeEdge, error)
 	StartDataSink(ctx context.Context, intervalSeconds int)
+	PublishDynamicAppConfigChanges()
+	WatchDynamicAppConfig(ctx context.Context)
+	StartExecJob(job types.ExecJob, plugin types.K8sPodExecPlugin) (types.ExecJob, error)
+	RefreshReportStorage(ctx context.Context)
 }
 
-// IAWSProvider is an interface representing functionality for an AWS provider
+// IAWSProvider is an interface representing functionality for a report storage provider
 type IAWSProvider interface {
-	GetS3Reports() ([]types.Report, error)
+	GetReports(filter types.ReportFilter) (types.ReportPage, error)
+	GetReport(reportName string) (types.Report, error)
 	GetReportDownloadURL(reportName string) (string, error)
+	GetReportUploadURL(reportName string, tags types.ReportTags) (types.ReportUpload, error)
+	DeleteReport(reportName string) error
+	OpenSignedReport(reportName string, expires int64, signature string) (*os.File, error)
+	ReceiveSignedReport(reportName string, expires int64, signature string, tags types.ReportTags, body io.Reader) error
+	DiscoverMySQLInstances(ctx context.Context) ([]types.MySQLDiscoveryProposal, error)
 }
 
 // IK8sProvider is an interface representing functionality for a kubernetes provider
@@ -52,6 +69,7 @@ type IK8sProvider interface {
 	RolloutRestartDeployment(string, string) error
 	RolloutRestartDaemonSet(string, string) error
 	RolloutRestartStatefulSet(string, string) error
+	ExecutePodExecPlugin(namespace, podName string, plugin types.K8sPodExecPlugin, command []string) (string, string, error)
 }
 
 // IStorageProvider is an interface representing functionality for a storage/persistence provider
@@ -66,11 +84,50 @@ type IStorageProvider interface {
 	GetUser(name string) (types.User, error)
 	GetUserAccessDetails(userID uuid.UUID) (types.UserAccessDetails, error)
 	UpsertUser(user types.User) (types.User, error)
+	GetUserByID(userID uuid.UUID) (types.User, error)
+	ResolveUserIdentity(identity types.UserIdentity) (types.User, error)
+	GetServiceAccounts() ([]types.User, error)
+	CreateServiceAccount(name string, groupIDs []uuid.UUID) (types.User, error)
+	GetAPITokensByUser(userID uuid.UUID) ([]types.APIToken, error)
+	GetAPIToken(tokenID uuid.UUID) (types.APIToken, error)
+	CreateAPIToken(userID uuid.UUID, req types.APITokenRequest) (types.APIToken, error)
+	RevokeAPIToken(tokenID uuid.UUID) error
+	AuthenticateAPIToken(rawToken string) (types.User, types.APIToken, error)
+	GetDeletedUsers() ([]types.User, error)
+	CountAdmins() (int64, error)
+	DeleteUser(userID uuid.UUID) error
+	RestoreUser(userID uuid.UUID) error
+	GetDeletedGroups() ([]types.Group, error)
+	DeleteGroup(groupID uuid.UUID) error
+	RestoreGroup(groupID uuid.UUID) error
+	GetDeletedPermissions() ([]types.Permission, error)
+	DeletePermission(permissionID uuid.UUID) error
+	RestorePermission(permissionID uuid.UUID) error
+	RecordAuditEvent(event types.AuditEvent)
+	GetAuditEvents(resourceType string, limit int) ([]types.AuditEvent, error)
 	GetMySQLCatalog() ([]*types.MySQLDBInfo, error)
 	UpsertMySQLDBInfo(dbInfo types.MySQLDBInfo) (*types.MySQLDBInfo, error)
 	DeleteMySQLDBInfo(dbHost string) error
+	GetMySQLDiscoveryProposals(status string) ([]types.MySQLDiscoveryProposal, error)
+	SaveMySQLDiscoveryProposals(proposals []types.MySQLDiscoveryProposal) error
+	SetMySQLDiscoveryProposalStatus(host, status string) error
+	AcceptMySQLDiscoveryProposal(host string, request types.MySQLDiscoveryAcceptRequest, defaultUsername string) (*types.MySQLDBInfo, error)
+	MarkMySQLHostsSeen(hosts []string, at time.Time) error
+	GetPostgresCatalog() ([]*types.PostgresDBInfo, error)
+	UpsertPostgresDBInfo(dbInfo types.PostgresDBInfo) (*types.PostgresDBInfo, error)
+	DeletePostgresDBInfo(dbHost string) error
+	GetGraphNodePositions(graph string) ([]types.GraphNodePosition, error)
+	PinGraphNodePosition(position types.GraphNodePosition) (*types.GraphNodePosition, error)
+	UnpinGraphNodePosition(graph, nodeID string) error
+	SaveReplTopoSnapshot(snapshot types.ReplTopoSnapshot) (types.ReplTopoSnapshot, error)
+	GetReplTopoSnapshot(at time.Time) (types.ReplTopoSnapshot, error)
+	GetReplTopoSnapshotHistory(since, until time.Time, limit int) ([]types.ReplTopoSnapshot, error)
+	PruneReplTopoSnapshots(before time.Time) (int64, error)
 	GetDynamicAppConfig() (types.DynamicAppConfig, error)
 	UpdateDynamicAppConfig(config types.DynamicAppConfig) (types.DynamicAppConfig, error)
+	GetDynamicAppConfigVersions() ([]types.DynamicAppConfigVersion, error)
+	GetDynamicAppConfigVersion(version int) (types.DynamicAppConfigVersion, error)
+	RollbackDynamicAppConfig(version, currentVersion int, author string) (types.DynamicAppConfig, error)
 }
 
 // ICacheProvider is an interface representing functionality for a storage/persistence provider
@@ -78,11 +135,27 @@ type ICacheProvider interface {
 	Get(key string) (any, error)
 	Put(key string, value any) error
 	InitAuthSessionStore() *redisstore.RedisStore
+	TrackUserSession(userSession types.UserSession) error
+	UntrackUserSession(email, sessionKey string) error
+	GetUserSessions(email string) ([]types.UserSession, error)
+	RevokeUserSession(email, sessionID string) error
+	RevokeUserSessions(email string) error
+	PublishDynamicAppConfigChange(version int) error
+	SubscribeDynamicAppConfigChanges(ctx context.Context, onChange func(version int))
+	SaveExecJob(job types.ExecJob) error
+	GetExecJob(jobID string, offset int64) (types.ExecJob, error)
+	AppendExecJobOutput(jobID string, chunk []byte) error
 }
 
 // IMySQLTopoProvider is an interface representing functionality for a MySQL topology provider
 type IMySQLTopoProvider interface {
-	CaptureReplicationTopology() ([]types.ReplTopoTreeNode, []types.ReplTopoTreeEdge, error)
+	CaptureReplicationTopology(ctx context.Context) ([]types.ReplTopoTreeNode, []types.ReplTopoTreeEdge, error)
+	RunQueryPlugin(ctx context.Context, db *types.MySQLDBInfo, plugin types.MySQLQueryPlugin, args map[string]string) (types.MySQLQueryResult, error)
+}
+
+// IPostgresTopoProvider is an interface representing functionality for a Postgres topology provider
+type IPostgresTopoProvider interface {
+	CaptureReplicationTopology(ctx context.Context) ([]types.ReplTopoTreeNode, []types.ReplTopoTreeEdge, error)
 }
 
 // ModuleProviders is a struct containing the collection of known providers to
@@ -94,4 +167,6 @@ type ModuleProviders struct {
 	StorageProvider   *StorageProvider
 	MySQLTopoProvider *MySQLTopoProvider
 	AWSProvider       *AWSProvider
+	// PostgresTopoProvider captures the Postgres replication topology into the same graph as MySQL
+	PostgresTopoProvider *PostgresTopoProvider
 }
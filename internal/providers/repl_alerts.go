@@ -0,0 +1,70 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/sullivtr/k8s_platform/internal/modules"
+	"github.com/sullivtr/k8s_platform/internal/types"
+)
+
+// replAlertsCacheKey returns the cache key of the state of the replication alerts of a topology
+func replAlertsCacheKey(topology string) string {
+	return fmt.Sprintf("%s-repl-alerts", topology)
+}
+
+// EvaluateReplAlerts evaluates the replication alert rules of the dynamic app config against a capture of a topology,
+// and notifies receivers of the alerts that started firing or resolved. The state of the alerts is kept in the cache,
+// so restarting the data sink does not notify active alerts again. Notifications that could not be delivered are sent
+// again after the next capture, only to the receivers that did not get them. Alerts of removed rules resolve without
+// being notified.
+func (p *ModuleProviders) EvaluateReplAlerts(ctx context.Context, topology string, nodes []types.ReplTopoTreeNode) error {
+	dac, err := p.StorageProvider.GetDynamicAppConfig()
+	if err != nil {
+		return err
+	}
+	cfg := dac.Data.ReplicationAlerting
+
+	state := types.ReplAlertState{}
+	data, err := p.CacheProvider.GetNoUnmarshal(replAlertsCacheKey(topology))
+	if err != nil {
+		return fmt.Errorf("unable to read %s replication alerts: %s", topology, err.Error())
+	}
+	if len(data) > 0 && string(data) != "null" {
+		if err := json.Unmarshal(data, &state); err != nil {
+			return fmt.Errorf("unable to decode %s replication alerts: %s", topology, err.Error())
+		}
+	}
+
+	now := time.Now().UTC()
+	firing := types.EvaluateReplAlertRules(topology, cfg.Rules, nodes, state.Sources, now)
+	notify := state.Reconcile(firing, nodes, now)
+
+	receivers := map[string]types.ReplAlertReceiver{}
+	for _, r := range cfg.Receivers {
+		receivers[r.Name] = r
+	}
+	notifier := modules.ReplAlertNotifier{}
+	for _, delivery := range state.Deliveries(notify, cfg) {
+		alert := delivery.Alert
+		failed := []string{}
+		for _, name := range delivery.Receivers {
+			if err := notifier.Notify(ctx, receivers[name], alert); err != nil {
+				log.Error().Msgf("unable to send %s replication alert %s to %s: %s", topology, alert.Fingerprint, name, err.Error())
+				failed = append(failed, name)
+			}
+		}
+		if len(failed) > 0 {
+			state.Undelivered(alert, failed)
+		}
+		log.Info().Msgf("%s replication alert %s %s: %s", topology, alert.Fingerprint, alert.Status, alert.Summary)
+	}
+
+	if err := p.CacheProvider.Put(replAlertsCacheKey(topology), state); err != nil {
+		return fmt.Errorf("unable to cache %s replication alerts: %s", topology, err.Error())
+	}
+	return nil
+}
@@ -1,18 +1,42 @@
 package providers
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
 	"github.com/sullivtr/k8s_platform/internal/modules"
 	"github.com/sullivtr/k8s_platform/internal/types"
 	"gorm.io/gorm"
 )
 
+var (
+	// ErrUserDeactivated is returned when a deactivated user attempts to authenticate
+	ErrUserDeactivated = errors.New("user has been deactivated")
+	// ErrUserDeleted is returned when a deleted user attempts to authenticate
+	ErrUserDeleted = errors.New("user has been deleted")
+	// ErrAppConfigVersionConflict is returned when the dynamic app config was updated since the version an update is based on
+	ErrAppConfigVersionConflict = errors.New("the dynamic app config was updated by someone else. Reload it and try again")
+	// ErrInvalidAppConfig is returned when the dynamic app config being saved is not valid
+	ErrInvalidAppConfig = errors.New("invalid dynamic app config")
+	// ErrInvalidMySQLDBInfo is returned when a MySQL catalog entry is not valid
+	ErrInvalidMySQLDBInfo = errors.New("invalid MySQL DB info")
+)
+
 // StorageProvider is a port for the applications underlying storage/persistence layer
 type StorageProvider struct {
 	Session StorageSession
+
+	// appConfigCache holds the dynamic app config in process when enabled by WatchDynamicAppConfig
+	appConfigCache *appConfigCache
+	// appConfigPublisher announces dynamic app config updates to other khub processes
+	appConfigPublisher func(version int)
 }
 
 // Compile time proof of implementation
@@ -143,6 +167,9 @@ func (p *StorageProvider) GetMySQLCatalog() ([]*types.MySQLDBInfo, error) {
 
 func (p *StorageProvider) UpsertMySQLDBInfo(dbInfo types.MySQLDBInfo) (*types.MySQLDBInfo, error) {
 	dbi, err := p.Session.SDK.UpsertMySQLDBInfo(dbInfo)
+	if errors.Is(err, modules.ErrInvalidMySQLDBInfo) {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidMySQLDBInfo, err.Error())
+	}
 	if err != nil {
 		return nil, fmt.Errorf("unable to upsert MySQL DB info: %s", err.Error())
 	}
@@ -160,14 +187,525 @@ func (p *StorageProvider) DeleteMySQLDBInfo(dbHost string) error {
 	return nil
 }
 
+func (p *StorageProvider) GetPostgresCatalog() ([]*types.PostgresDBInfo, error) {
+	dbInfo, err := p.Session.SDK.GetPostgresCatalog()
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch Postgres DB catalog: %s", err.Error())
+	}
+	return dbInfo, nil
+}
+
+func (p *StorageProvider) UpsertPostgresDBInfo(dbInfo types.PostgresDBInfo) (*types.PostgresDBInfo, error) {
+	dbi, err := p.Session.SDK.UpsertPostgresDBInfo(dbInfo)
+	if err != nil {
+		return nil, fmt.Errorf("unable to upsert Postgres DB info: %s", err.Error())
+	}
+	if dbi != nil {
+		return dbi, nil
+	}
+	return nil, fmt.Errorf("Postgres DB info upsert failed for unknown reason")
+}
+
+func (p *StorageProvider) DeletePostgresDBInfo(dbHost string) error {
+	err := p.Session.SDK.DeletePostgresDBInfo(dbHost)
+	if err != nil {
+		return fmt.Errorf("unable to delete Postgres DB info: %s", err.Error())
+	}
+	return nil
+}
+
+// GetGraphNodePositions returns the positions admins pinned the nodes of a graph at
+func (p *StorageProvider) GetGraphNodePositions(graph string) ([]types.GraphNodePosition, error) {
+	positions, err := p.Session.SDK.GetGraphNodePositions(graph)
+	if err != nil {
+		return []types.GraphNodePosition{}, fmt.Errorf("unable to fetch %s node positions: %s", graph, err.Error())
+	}
+	return positions, nil
+}
+
+// PinGraphNodePosition pins a node of a graph at a position
+func (p *StorageProvider) PinGraphNodePosition(position types.GraphNodePosition) (*types.GraphNodePosition, error) {
+	pinned, err := p.Session.SDK.PinGraphNodePosition(position)
+	if err != nil {
+		return nil, fmt.Errorf("unable to pin %s node position: %s", position.Graph, err.Error())
+	}
+	return pinned, nil
+}
+
+// UnpinGraphNodePosition removes the pinned position of a node of a graph
+func (p *StorageProvider) UnpinGraphNodePosition(graph, nodeID string) error {
+	if err := p.Session.SDK.UnpinGraphNodePosition(graph, nodeID); err != nil {
+		return fmt.Errorf("unable to unpin %s node position: %s", graph, err.Error())
+	}
+	return nil
+}
+
+// GetMySQLDiscoveryProposals returns the discovery proposals with the given status, or every proposal when it is empty
+func (p *StorageProvider) GetMySQLDiscoveryProposals(status string) ([]types.MySQLDiscoveryProposal, error) {
+	proposals, err := p.Session.SDK.GetMySQLDiscoveryProposals(status)
+	if err != nil {
+		return []types.MySQLDiscoveryProposal{}, fmt.Errorf("unable to fetch MySQL discovery proposals: %s", err.Error())
+	}
+	return proposals, nil
+}
+
+// SaveMySQLDiscoveryProposals creates or refreshes discovery proposals, keeping the status of existing proposals
+func (p *StorageProvider) SaveMySQLDiscoveryProposals(proposals []types.MySQLDiscoveryProposal) error {
+	if err := p.Session.SDK.SaveMySQLDiscoveryProposals(proposals); err != nil {
+		return fmt.Errorf("unable to save MySQL discovery proposals: %s", err.Error())
+	}
+	return nil
+}
+
+// SetMySQLDiscoveryProposalStatus sets the status of a discovery proposal.
+// gorm.ErrRecordNotFound is returned when there is no proposal for the host.
+func (p *StorageProvider) SetMySQLDiscoveryProposalStatus(host, status string) error {
+	err := p.Session.SDK.SetMySQLDiscoveryProposalStatus(host, status)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("unable to update MySQL discovery proposal: %s", err.Error())
+	}
+	return nil
+}
+
+// AcceptMySQLDiscoveryProposal adds the host of a discovery proposal to the catalog.
+// gorm.ErrRecordNotFound is returned when there is no proposal for the host, and ErrInvalidMySQLDBInfo when the
+// catalog entry is not valid.
+func (p *StorageProvider) AcceptMySQLDiscoveryProposal(host string, request types.MySQLDiscoveryAcceptRequest, defaultUsername string) (*types.MySQLDBInfo, error) {
+	dbInfo, err := p.Session.SDK.AcceptMySQLDiscoveryProposal(host, request, defaultUsername)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if errors.Is(err, modules.ErrInvalidMySQLDBInfo) {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidMySQLDBInfo, err.Error())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to accept MySQL discovery proposal: %s", err.Error())
+	}
+	return dbInfo, nil
+}
+
+// MarkMySQLHostsSeen records that discovery sources found the given catalog hosts
+func (p *StorageProvider) MarkMySQLHostsSeen(hosts []string, at time.Time) error {
+	if err := p.Session.SDK.MarkMySQLHostsSeen(hosts, at); err != nil {
+		return fmt.Errorf("unable to update the last seen time of MySQL hosts: %s", err.Error())
+	}
+	return nil
+}
+
+// SaveReplTopoSnapshot stores a capture of the replication topology
+func (p *StorageProvider) SaveReplTopoSnapshot(snapshot types.ReplTopoSnapshot) (types.ReplTopoSnapshot, error) {
+	s, err := p.Session.SDK.CreateReplTopoSnapshot(snapshot)
+	if err != nil {
+		return types.ReplTopoSnapshot{}, fmt.Errorf("unable to store replication topology snapshot: %s", err.Error())
+	}
+	return s, nil
+}
+
+// GetReplTopoSnapshot returns the capture of the replication topology that was current at the given time.
+// gorm.ErrRecordNotFound is returned when the topology was not captured yet at that time.
+func (p *StorageProvider) GetReplTopoSnapshot(at time.Time) (types.ReplTopoSnapshot, error) {
+	s, err := p.Session.SDK.GetReplTopoSnapshot(at)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return types.ReplTopoSnapshot{}, err
+	}
+	if err != nil {
+		return types.ReplTopoSnapshot{}, fmt.Errorf("unable to fetch replication topology snapshot: %s", err.Error())
+	}
+	return s, nil
+}
+
+// GetReplTopoSnapshotHistory returns the captures of the replication topology taken in a time range, with their changes
+func (p *StorageProvider) GetReplTopoSnapshotHistory(since, until time.Time, limit int) ([]types.ReplTopoSnapshot, error) {
+	snapshots, err := p.Session.SDK.GetReplTopoSnapshotHistory(since, until, limit)
+	if err != nil {
+		return []types.ReplTopoSnapshot{}, fmt.Errorf("unable to fetch replication topology history: %s", err.Error())
+	}
+	return snapshots, nil
+}
+
+// PruneReplTopoSnapshots deletes the captures of the replication topology taken before the given time
+func (p *StorageProvider) PruneReplTopoSnapshots(before time.Time) (int64, error) {
+	deleted, err := p.Session.SDK.DeleteReplTopoSnapshotsBefore(before)
+	if err != nil {
+		return 0, fmt.Errorf("unable to prune replication topology snapshots: %s", err.Error())
+	}
+	return deleted, nil
+}
+
+// GetDynamicAppConfig returns the dynamic app config, from the in-process cache when it is enabled
 func (p *StorageProvider) GetDynamicAppConfig() (types.DynamicAppConfig, error) {
+	if dac, ok := p.appConfigCache.get(); ok {
+		return dac, nil
+	}
+
 	dac, err := p.Session.SDK.GetDynamicAppConfig()
 	if err != nil {
 		return types.DynamicAppConfig{}, fmt.Errorf("unable to fetch dynamic app config: %s", err.Error())
 	}
+	p.appConfigCache.set(dac)
 	return dac, nil
 }
 
+// UpdateDynamicAppConfig validates and saves the dynamic app config. ErrInvalidAppConfig is returned when it is not
+// valid, and ErrAppConfigVersionConflict when it was updated since the version it is based on.
 func (p *StorageProvider) UpdateDynamicAppConfig(config types.DynamicAppConfig) (types.DynamicAppConfig, error) {
-	return p.Session.SDK.UpdateDynamicAppConfig(config)
+	dac, err := p.Session.SDK.UpdateDynamicAppConfig(config)
+	if errors.Is(err, modules.ErrDynamicAppConfigVersionConflict) {
+		return types.DynamicAppConfig{}, ErrAppConfigVersionConflict
+	}
+	if errors.Is(err, modules.ErrInvalidDynamicAppConfig) {
+		return types.DynamicAppConfig{}, ErrInvalidAppConfig
+	}
+	if err != nil {
+		return types.DynamicAppConfig{}, fmt.Errorf("unable to update dynamic app config: %s", err.Error())
+	}
+	p.dynamicAppConfigChanged(dac)
+	return dac, nil
+}
+
+func (p *StorageProvider) GetDynamicAppConfigVersions() ([]types.DynamicAppConfigVersion, error) {
+	versions, err := p.Session.SDK.GetDynamicAppConfigVersions()
+	if err != nil {
+		return []types.DynamicAppConfigVersion{}, fmt.Errorf("unable to fetch dynamic app config versions: %s", err.Error())
+	}
+	return versions, nil
+}
+
+func (p *StorageProvider) GetDynamicAppConfigVersion(version int) (types.DynamicAppConfigVersion, error) {
+	v, err := p.Session.SDK.GetDynamicAppConfigVersion(version)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return types.DynamicAppConfigVersion{}, err
+	}
+	if err != nil {
+		return types.DynamicAppConfigVersion{}, fmt.Errorf("unable to fetch dynamic app config version %d: %s", version, err.Error())
+	}
+	return v, nil
+}
+
+// RollbackDynamicAppConfig restores a previous version of the dynamic app config as a new version
+func (p *StorageProvider) RollbackDynamicAppConfig(version, currentVersion int, author string) (types.DynamicAppConfig, error) {
+	dac, err := p.Session.SDK.RollbackDynamicAppConfig(version, currentVersion, author)
+	if errors.Is(err, modules.ErrDynamicAppConfigVersionConflict) {
+		return types.DynamicAppConfig{}, ErrAppConfigVersionConflict
+	}
+	if errors.Is(err, modules.ErrInvalidDynamicAppConfig) {
+		return types.DynamicAppConfig{}, ErrInvalidAppConfig
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return types.DynamicAppConfig{}, err
+	}
+	if err != nil {
+		return types.DynamicAppConfig{}, fmt.Errorf("unable to roll back dynamic app config: %s", err.Error())
+	}
+	p.dynamicAppConfigChanged(dac)
+	return dac, nil
+}
+
+func (p *StorageProvider) GetUserByID(userID uuid.UUID) (types.User, error) {
+	user, err := p.Session.SDK.GetUserByID(userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return types.User{}, err
+	}
+
+	if err != nil {
+		return types.User{}, fmt.Errorf("unable to fetch user with id, %s: %s", userID.String(), err.Error())
+	}
+	return user, nil
+}
+
+// ResolveUserIdentity returns the user bound to an identity provider's issuer and subject.
+// Existing users that have not been bound to an identity yet are linked by username and email, when their email is
+// the verified email claim asserted by the default identity provider. Otherwise a new user is created. When the derived username is
+// already taken, the identity provider name, and then a number, are appended to keep usernames unique.
+func (p *StorageProvider) ResolveUserIdentity(identity types.UserIdentity) (types.User, error) {
+	user, err := p.Session.SDK.GetUserByIdentity(identity.Issuer, identity.Subject)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return types.User{}, fmt.Errorf("unable to fetch user by identity: %s", err.Error())
+	}
+
+	if err == nil {
+		if user.DeletedAt.Valid {
+			return types.User{}, ErrUserDeleted
+		}
+		if user.Deactivated {
+			return types.User{}, ErrUserDeactivated
+		}
+		user.Email = strings.ToLower(identity.Email)
+		user.LastUsed = time.Now()
+		return p.UpsertUser(user)
+	}
+
+	// Deleted users still hold their username, so they are included when checking for a legacy user or a collision
+	name := types.UsernameFromEmail(identity.Email)
+	user, err = p.Session.SDK.GetUserIncludingDeleted(name)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return types.User{}, fmt.Errorf("unable to fetch user with name, %s: %s", name, err.Error())
+	}
+
+	if err == nil && user.Subject == "" && !user.IsServiceAccount && identity.VerifiedEmail != "" &&
+		strings.EqualFold(user.Email, identity.VerifiedEmail) && identity.DefaultIDP {
+		if user.DeletedAt.Valid {
+			return types.User{}, ErrUserDeleted
+		}
+		if user.Deactivated {
+			return types.User{}, ErrUserDeactivated
+		}
+		user.Issuer = identity.Issuer
+		user.Subject = identity.Subject
+		user.LastUsed = time.Now()
+		return p.UpsertUser(user)
+	}
+
+	if err == nil {
+		name, err = p.availableUsername(fmt.Sprintf("%s-%s", name, identity.IDP))
+		if err != nil {
+			return types.User{}, err
+		}
+	}
+
+	return p.UpsertUser(types.User{
+		Name:     name,
+		Email:    strings.ToLower(identity.Email),
+		Issuer:   identity.Issuer,
+		Subject:  identity.Subject,
+		LastUsed: time.Now(),
+		DarkMode: true,
+	})
+}
+
+// availableUsername returns the name, or the name with the first number suffix no user holds
+func (p *StorageProvider) availableUsername(name string) (string, error) {
+	candidate := name
+	for i := 2; ; i++ {
+		_, err := p.Session.SDK.GetUserIncludingDeleted(candidate)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return candidate, nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("unable to fetch user with name, %s: %s", candidate, err.Error())
+		}
+		candidate = fmt.Sprintf("%s-%d", name, i)
+	}
+}
+
+func (p *StorageProvider) GetServiceAccounts() ([]types.User, error) {
+	users, err := p.Session.SDK.GetServiceAccounts()
+	if err != nil {
+		return []types.User{}, fmt.Errorf("unable to fetch service accounts: %s", err.Error())
+	}
+	return users, nil
+}
+
+func (p *StorageProvider) CreateServiceAccount(name string, groupIDs []uuid.UUID) (types.User, error) {
+	u, err := p.Session.SDK.CreateServiceAccount(types.NewServiceAccount(name), groupIDs)
+	if err != nil {
+		return types.User{}, fmt.Errorf("unable to create service account: %s", err.Error())
+	}
+	if u != nil {
+		return *u, nil
+	}
+	return types.User{}, fmt.Errorf("service account creation failed for unknown reason")
+}
+
+func (p *StorageProvider) GetAPITokensByUser(userID uuid.UUID) ([]types.APIToken, error) {
+	tokens, err := p.Session.SDK.GetAPITokensByUser(userID)
+	if err != nil {
+		return []types.APIToken{}, fmt.Errorf("unable to fetch api tokens: %s", err.Error())
+	}
+	return tokens, nil
+}
+
+func (p *StorageProvider) GetAPIToken(tokenID uuid.UUID) (types.APIToken, error) {
+	token, err := p.Session.SDK.GetAPIToken(tokenID)
+	if err != nil {
+		return types.APIToken{}, fmt.Errorf("unable to fetch api token: %s", err.Error())
+	}
+	return token, nil
+}
+
+// CreateAPIToken mints a new api token for the given user. The returned token is the only
+// time the raw token value (PlainToken) is available, only its hash is persisted.
+func (p *StorageProvider) CreateAPIToken(userID uuid.UUID, req types.APITokenRequest) (types.APIToken, error) {
+	valid, errMsg := req.IsValid()
+	if !valid {
+		return types.APIToken{}, fmt.Errorf("validation error: %s", errMsg)
+	}
+
+	rawToken, err := generateAPIToken()
+	if err != nil {
+		return types.APIToken{}, err
+	}
+
+	t, err := p.Session.SDK.CreateAPIToken(types.APIToken{
+		Name:      req.Name,
+		UserID:    userID,
+		TokenHash: hashAPIToken(rawToken),
+		Hint:      rawToken[:len(types.APITokenPrefix)+4],
+		Scopes:    req.Scopes,
+		ExpiresAt: time.Now().Add(time.Duration(req.ExpiresInDays) * 24 * time.Hour),
+	})
+	if err != nil {
+		return types.APIToken{}, fmt.Errorf("unable to create api token: %s", err.Error())
+	}
+	if t == nil {
+		return types.APIToken{}, fmt.Errorf("api token creation failed for unknown reason")
+	}
+
+	t.PlainToken = rawToken
+	return *t, nil
+}
+
+func (p *StorageProvider) RevokeAPIToken(tokenID uuid.UUID) error {
+	if err := p.Session.SDK.RevokeAPIToken(tokenID); err != nil {
+		return fmt.Errorf("unable to revoke api token: %s", err.Error())
+	}
+	return nil
+}
+
+// AuthenticateAPIToken resolves a raw api token to the user that owns it.
+// An error is returned if the token is unknown, revoked, expired, or its owner no longer exists or has been deactivated.
+func (p *StorageProvider) AuthenticateAPIToken(rawToken string) (types.User, types.APIToken, error) {
+	if !strings.HasPrefix(rawToken, types.APITokenPrefix) {
+		return types.User{}, types.APIToken{}, errors.New("malformed api token")
+	}
+
+	token, err := p.Session.SDK.GetAPITokenByHash(hashAPIToken(rawToken))
+	if err != nil {
+		return types.User{}, types.APIToken{}, errors.New("invalid or revoked api token")
+	}
+
+	if token.IsExpired() {
+		return types.User{}, types.APIToken{}, errors.New("api token has expired")
+	}
+
+	user, err := p.GetUserByID(token.UserID)
+	if err != nil {
+		return types.User{}, types.APIToken{}, errors.New("api token owner no longer exists")
+	}
+
+	if user.Deactivated {
+		return types.User{}, types.APIToken{}, errors.New("api token owner has been deactivated")
+	}
+
+	if err := p.Session.SDK.TouchAPIToken(*token.ID); err != nil {
+		log.Warn().Msgf("unable to update api token last used time: %s", err.Error())
+	}
+
+	return user, token, nil
+}
+
+func (p *StorageProvider) GetDeletedUsers() ([]types.User, error) {
+	users, err := p.Session.SDK.GetDeletedUsers()
+	if err != nil {
+		return []types.User{}, fmt.Errorf("unable to fetch deleted users: %s", err.Error())
+	}
+	return users, nil
+}
+
+func (p *StorageProvider) CountAdmins() (int64, error) {
+	count, err := p.Session.SDK.CountAdmins()
+	if err != nil {
+		return 0, fmt.Errorf("unable to count admins: %s", err.Error())
+	}
+	return count, nil
+}
+
+func (p *StorageProvider) DeleteUser(userID uuid.UUID) error {
+	if err := p.Session.SDK.DeleteUser(userID); err != nil {
+		return fmt.Errorf("unable to delete user: %s", err.Error())
+	}
+	return nil
+}
+
+func (p *StorageProvider) RestoreUser(userID uuid.UUID) error {
+	if err := p.Session.SDK.RestoreUser(userID); err != nil {
+		return fmt.Errorf("unable to restore user: %s", err.Error())
+	}
+	return nil
+}
+
+func (p *StorageProvider) GetDeletedGroups() ([]types.Group, error) {
+	groups, err := p.Session.SDK.GetDeletedGroups()
+	if err != nil {
+		return []types.Group{}, fmt.Errorf("unable to fetch deleted groups: %s", err.Error())
+	}
+	return groups, nil
+}
+
+func (p *StorageProvider) DeleteGroup(groupID uuid.UUID) error {
+	if err := p.Session.SDK.DeleteGroup(groupID); err != nil {
+		return fmt.Errorf("unable to delete group: %s", err.Error())
+	}
+	return nil
+}
+
+func (p *StorageProvider) RestoreGroup(groupID uuid.UUID) error {
+	if err := p.Session.SDK.RestoreGroup(groupID); err != nil {
+		return fmt.Errorf("unable to restore group: %s", err.Error())
+	}
+	return nil
+}
+
+func (p *StorageProvider) GetDeletedPermissions() ([]types.Permission, error) {
+	permissions, err := p.Session.SDK.GetDeletedPermissions()
+	if err != nil {
+		return []types.Permission{}, fmt.Errorf("unable to fetch deleted permissions: %s", err.Error())
+	}
+	return permissions, nil
+}
+
+func (p *StorageProvider) DeletePermission(permissionID uuid.UUID) error {
+	if err := p.Session.SDK.DeletePermission(permissionID); err != nil {
+		return fmt.Errorf("unable to delete permission: %s", err.Error())
+	}
+	return nil
+}
+
+func (p *StorageProvider) RestorePermission(permissionID uuid.UUID) error {
+	if err := p.Session.SDK.RestorePermission(permissionID); err != nil {
+		return fmt.Errorf("unable to restore permission: %s", err.Error())
+	}
+	return nil
+}
+
+// RecordAuditEvent persists an audit event and writes it to the application log.
+// Failing to persist an audit event does not fail the audited operation.
+func (p *StorageProvider) RecordAuditEvent(event types.AuditEvent) {
+	log.Info().
+		Str("actor", event.Actor).
+		Str("action", event.Action).
+		Str("resourceType", event.ResourceType).
+		Str("resourceId", event.ResourceID).
+		Str("resourceName", event.ResourceName).
+		Msg(event.Detail)
+
+	if err := p.Session.SDK.CreateAuditEvent(event); err != nil {
+		log.Warn().Msgf("unable to persist audit event: %s", err.Error())
+	}
+}
+
+func (p *StorageProvider) GetAuditEvents(resourceType string, limit int) ([]types.AuditEvent, error) {
+	events, err := p.Session.SDK.GetAuditEvents(resourceType, limit)
+	if err != nil {
+		return []types.AuditEvent{}, fmt.Errorf("unable to fetch audit events: %s", err.Error())
+	}
+	return events, nil
+}
+
+// generateAPIToken generates a random api token with the khub token prefix
+func generateAPIToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("unable to generate api token: %s", err.Error())
+	}
+	return types.APITokenPrefix + hex.EncodeToString(b), nil
+}
+
+// hashAPIToken returns the hex encoded sha256 hash of a raw api token
+func hashAPIToken(rawToken string) string {
+	h := sha256.Sum256([]byte(rawToken))
+	return hex.EncodeToString(h[:])
 }
@@ -0,0 +1,403 @@
+package rbac
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/sullivtr/k8s_platform/internal/types"
+)
+
+// Change actions
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+)
+
+// Change kinds
+const (
+	KindPermission = "permission"
+	KindGroup      = "group"
+	KindAppConfig  = "appconfig"
+)
+
+// Change is a single change required to reconcile the store with a document
+type Change struct {
+	Action  string   `json:"action"`
+	Kind    string   `json:"kind"`
+	Name    string   `json:"name"`
+	Details []string `json:"details,omitempty"`
+
+	resourceID string
+	apply      func(store Store, actor string) error
+}
+
+// Plan is the ordered list of changes required to reconcile the store with a document.
+// Permissions are created before the groups referencing them, and deletions run last.
+type Plan struct {
+	Changes []Change `json:"changes"`
+}
+
+// NewPlan compares a document with the current state of the store.
+// Groups listed in the document are authoritative for their permissions and members.
+// Permissions and groups missing from the document are only deleted when prune is set.
+// The built-in admin permission and group are never deleted.
+func NewPlan(store Store, doc Document, prune bool) (Plan, error) {
+	permissions, err := store.GetPermissions()
+	if err != nil {
+		return Plan{}, err
+	}
+
+	groups, err := store.GetGroups()
+	if err != nil {
+		return Plan{}, err
+	}
+
+	users, err := store.GetUsers()
+	if err != nil {
+		return Plan{}, err
+	}
+
+	usersByName := map[string]types.User{}
+	for _, u := range users {
+		usersByName[u.Name] = u
+	}
+
+	existingPermissions := map[string]types.Permission{}
+	for _, p := range permissions {
+		existingPermissions[p.Name] = p
+	}
+
+	plan := Plan{Changes: []Change{}}
+	deletions := []Change{}
+
+	// desiredPermissions resolves the permission names a group may reference
+	desiredPermissions := map[string]types.Permission{}
+	declaredPermissions := map[string]bool{}
+	for _, spec := range doc.Permissions {
+		declaredPermissions[spec.Name] = true
+		existing, ok := existingPermissions[spec.Name]
+		if !ok {
+			pid := uuid.New()
+			permission := types.Permission{ID: &pid, Name: spec.Name, AppTag: spec.AppTag}
+			desiredPermissions[spec.Name] = permission
+			plan.Changes = append(plan.Changes, Change{
+				Action:     ActionCreate,
+				Kind:       KindPermission,
+				Name:       spec.Name,
+				Details:    []string{fmt.Sprintf("appTag: %s", spec.AppTag)},
+				resourceID: pid.String(),
+				apply:      upsertPermission(permission),
+			})
+			continue
+		}
+
+		if existing.AppTag != spec.AppTag {
+			if existing.ID.String() == types.BuiltinAdminID {
+				return Plan{}, ErrBuiltinAdmin
+			}
+			plan.Changes = append(plan.Changes, Change{
+				Action:     ActionUpdate,
+				Kind:       KindPermission,
+				Name:       spec.Name,
+				Details:    []string{fmt.Sprintf("appTag: %s -> %s", existing.AppTag, spec.AppTag)},
+				resourceID: existing.ID.String(),
+				apply:      upsertPermission(types.Permission{ID: existing.ID, Name: spec.Name, AppTag: spec.AppTag}),
+			})
+		}
+		existing.AppTag = spec.AppTag
+		desiredPermissions[spec.Name] = existing
+	}
+
+	for _, p := range permissions {
+		if declaredPermissions[p.Name] {
+			continue
+		}
+		if !prune || p.ID.String() == types.BuiltinAdminID {
+			desiredPermissions[p.Name] = p
+			continue
+		}
+		deletions = append(deletions, Change{
+			Action:     ActionDelete,
+			Kind:       KindPermission,
+			Name:       p.Name,
+			resourceID: p.ID.String(),
+			apply:      deletePermission(*p.ID),
+		})
+	}
+
+	existingGroups := map[string]types.Group{}
+	for _, g := range groups {
+		existingGroups[g.Name] = g
+	}
+
+	declaredGroups := map[string]bool{}
+	for _, spec := range doc.Groups {
+		declaredGroups[spec.Name] = true
+
+		group := types.Group{Name: spec.Name, Permissions: []*types.Permission{}, Users: []*types.User{}}
+		for _, name := range spec.Permissions {
+			p, ok := desiredPermissions[name]
+			if !ok {
+				return Plan{}, fmt.Errorf("group %q references unknown permission %q", spec.Name, name)
+			}
+			group.Permissions = append(group.Permissions, &p)
+		}
+		for _, name := range spec.Members {
+			u, ok := usersByName[name]
+			if !ok {
+				return Plan{}, fmt.Errorf("group %q references unknown user %q. Users are created on their first login", spec.Name, name)
+			}
+			group.Users = append(group.Users, &u)
+		}
+
+		existing, ok := existingGroups[spec.Name]
+		if !ok {
+			gid := uuid.New()
+			group.ID = &gid
+			plan.Changes = append(plan.Changes, Change{
+				Action:     ActionCreate,
+				Kind:       KindGroup,
+				Name:       spec.Name,
+				Details:    groupDetails(types.Group{}, group),
+				resourceID: gid.String(),
+				apply:      upsertGroup(group),
+			})
+			continue
+		}
+
+		group.ID = existing.ID
+		if existing.ID.String() == types.BuiltinAdminID && !samePermissions(existing, group) {
+			return Plan{}, ErrBuiltinAdmin
+		}
+		if details := groupDetails(existing, group); len(details) > 0 {
+			plan.Changes = append(plan.Changes, Change{
+				Action:     ActionUpdate,
+				Kind:       KindGroup,
+				Name:       spec.Name,
+				Details:    details,
+				resourceID: existing.ID.String(),
+				apply:      upsertGroup(group),
+			})
+		}
+	}
+
+	if prune {
+		for _, g := range groups {
+			if declaredGroups[g.Name] || g.ID.String() == types.BuiltinAdminID {
+				continue
+			}
+			deletions = append(deletions, Change{
+				Action:     ActionDelete,
+				Kind:       KindGroup,
+				Name:       g.Name,
+				resourceID: g.ID.String(),
+				apply:      deleteGroup(*g.ID),
+			})
+		}
+	}
+
+	if doc.AppConfig != nil {
+		dac, err := store.GetDynamicAppConfig()
+		if err != nil {
+			return Plan{}, err
+		}
+
+		details, err := appConfigDetails(dac.Data, *doc.AppConfig)
+		if err != nil {
+			return Plan{}, err
+		}
+		if len(details) > 0 {
+			plan.Changes = append(plan.Changes, Change{
+				Action:     ActionUpdate,
+				Kind:       KindAppConfig,
+				Name:       "dynamic app config",
+				Details:    details,
+				resourceID: fmt.Sprint(dac.ID),
+				apply:      updateAppConfig(types.DynamicAppConfig{ID: dac.ID, Version: dac.Version, Data: *doc.AppConfig}),
+			})
+		}
+	}
+
+	// Groups are deleted before the permissions they may reference
+	sort.SliceStable(deletions, func(i, j int) bool { return deletions[i].Kind == KindGroup && deletions[j].Kind != KindGroup })
+	plan.Changes = append(plan.Changes, deletions...)
+	return plan, nil
+}
+
+// Empty reports whether the store already matches the document
+func (p Plan) Empty() bool {
+	return len(p.Changes) == 0
+}
+
+// String renders the plan as a human readable diff
+func (p Plan) String() string {
+	if p.Empty() {
+		return "No changes. The access model matches the document.\n"
+	}
+
+	out := strings.Builder{}
+	counts := map[string]int{}
+	for _, c := range p.Changes {
+		counts[c.Action]++
+		symbol := map[string]string{ActionCreate: "+", ActionUpdate: "~", ActionDelete: "-"}[c.Action]
+		out.WriteString(fmt.Sprintf("%s %s %s\n", symbol, c.Kind, c.Name))
+		for _, d := range c.Details {
+			out.WriteString(fmt.Sprintf("    %s\n", d))
+		}
+	}
+	out.WriteString(fmt.Sprintf("\nPlan: %d to create, %d to update, %d to delete.\n", counts[ActionCreate], counts[ActionUpdate], counts[ActionDelete]))
+	return out.String()
+}
+
+// Apply applies the changes of the plan in order, recording an audit event for each change.
+// Apply stops at the first failing change.
+func (p Plan) Apply(store Store, actor string) error {
+	for _, c := range p.Changes {
+		if err := c.apply(store, actor); err != nil {
+			return fmt.Errorf("unable to %s %s %s: %s", c.Action, c.Kind, c.Name, err.Error())
+		}
+
+		store.RecordAuditEvent(types.AuditEvent{
+			Actor:        actor,
+			Action:       c.Action,
+			ResourceType: auditResourceTypes[c.Kind],
+			ResourceID:   c.resourceID,
+			ResourceName: c.Name,
+			Detail:       strings.Join(append([]string{"rbac apply"}, c.Details...), "; "),
+		})
+	}
+	return nil
+}
+
+var auditResourceTypes = map[string]string{
+	KindPermission: types.AuditResourcePermission,
+	KindGroup:      types.AuditResourceGroup,
+	KindAppConfig:  types.AuditResourceAppConfig,
+}
+
+func upsertPermission(permission types.Permission) func(Store, string) error {
+	return func(store Store, _ string) error {
+		_, err := store.UpsertPermission(permission)
+		return err
+	}
+}
+
+func deletePermission(permissionID uuid.UUID) func(Store, string) error {
+	return func(store Store, _ string) error {
+		return store.DeletePermission(permissionID)
+	}
+}
+
+func upsertGroup(group types.Group) func(Store, string) error {
+	return func(store Store, _ string) error {
+		_, err := store.UpsertGroup(group)
+		return err
+	}
+}
+
+func deleteGroup(groupID uuid.UUID) func(Store, string) error {
+	return func(store Store, _ string) error {
+		return store.DeleteGroup(groupID)
+	}
+}
+
+// updateAppConfig updates the dynamic app config as a new version authored by the actor applying the plan.
+// The update fails when the config was changed after the plan was made.
+func updateAppConfig(config types.DynamicAppConfig) func(Store, string) error {
+	return func(store Store, actor string) error {
+		config.UpdatedBy = actor
+		_, err := store.UpdateDynamicAppConfig(config)
+		return err
+	}
+}
+
+// groupDetails lists the permissions and members added to or removed from a group
+func groupDetails(existing, desired types.Group) []string {
+	existingPermissions := []string{}
+	for _, p := range existing.Permissions {
+		existingPermissions = append(existingPermissions, p.Name)
+	}
+	desiredPermissions := []string{}
+	for _, p := range desired.Permissions {
+		desiredPermissions = append(desiredPermissions, p.Name)
+	}
+	existingMembers := []string{}
+	for _, u := range existing.Users {
+		existingMembers = append(existingMembers, u.Name)
+	}
+	desiredMembers := []string{}
+	for _, u := range desired.Users {
+		desiredMembers = append(desiredMembers, u.Name)
+	}
+
+	details := []string{}
+	details = append(details, setDetails("permission", existingPermissions, desiredPermissions)...)
+	details = append(details, setDetails("member", existingMembers, desiredMembers)...)
+	return details
+}
+
+// samePermissions reports whether two groups hold the same permissions
+func samePermissions(a, b types.Group) bool {
+	names := func(g types.Group) []string {
+		n := []string{}
+		for _, p := range g.Permissions {
+			n = append(n, p.Name)
+		}
+		return n
+	}
+	return len(setDetails("permission", names(a), names(b))) == 0
+}
+
+// setDetails lists the items added to and removed from a set, in sorted order
+func setDetails(label string, existing, desired []string) []string {
+	existingSet := map[string]bool{}
+	for _, v := range existing {
+		existingSet[v] = true
+	}
+	desiredSet := map[string]bool{}
+	for _, v := range desired {
+		desiredSet[v] = true
+	}
+
+	details := []string{}
+	for _, v := range sortedKeys(desiredSet) {
+		if !existingSet[v] {
+			details = append(details, fmt.Sprintf("+ %s %s", label, v))
+		}
+	}
+	for _, v := range sortedKeys(existingSet) {
+		if !desiredSet[v] {
+			details = append(details, fmt.Sprintf("- %s %s", label, v))
+		}
+	}
+	return details
+}
+
+// appConfigDetails lists the dynamic app config values that differ, in sorted order
+func appConfigDetails(existing, desired types.DynamicConfigJSONB) ([]string, error) {
+	changes, err := types.DiffDynamicAppConfig(existing, desired)
+	if err != nil {
+		return nil, err
+	}
+
+	details := []string{}
+	for _, c := range changes {
+		before, _ := json.Marshal(c.From)
+		after, _ := json.Marshal(c.To)
+		details = append(details, fmt.Sprintf("%s: %s -> %s", c.Path, before, after))
+	}
+	return details, nil
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
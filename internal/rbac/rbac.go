@@ -0,0 +1,196 @@
+// Package rbac converts khub's access model (permissions, groups, group memberships and the dynamic app config)
+// to and from a declarative document, so that it can be reviewed in git and reconciled from CI.
+package rbac
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/sullivtr/k8s_platform/internal/providers"
+	"github.com/sullivtr/k8s_platform/internal/types"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// APIVersion is the api version of the rbac document
+	APIVersion = "khub/v1"
+	// Kind is the kind of the rbac document
+	Kind = "RBAC"
+)
+
+// Store is the subset of the storage provider used to export and reconcile the access model
+type Store interface {
+	GetPermissions() ([]types.Permission, error)
+	UpsertPermission(permission types.Permission) (types.Permission, error)
+	DeletePermission(permissionID uuid.UUID) error
+	GetGroups() ([]types.Group, error)
+	UpsertGroup(group types.Group) (types.Group, error)
+	DeleteGroup(groupID uuid.UUID) error
+	GetUsers() ([]types.User, error)
+	GetDynamicAppConfig() (types.DynamicAppConfig, error)
+	UpdateDynamicAppConfig(config types.DynamicAppConfig) (types.DynamicAppConfig, error)
+	RecordAuditEvent(event types.AuditEvent)
+}
+
+// Compile-time proof that the storage provider can be reconciled.
+var _ Store = (providers.IStorageProvider)(nil)
+
+// Document is the declarative representation of khub's access model.
+// Group members are referenced by username and group permissions by permission name.
+type Document struct {
+	APIVersion  string                    `json:"apiVersion"`
+	Kind        string                    `json:"kind"`
+	Permissions []PermissionSpec          `json:"permissions"`
+	Groups      []GroupSpec               `json:"groups"`
+	AppConfig   *types.DynamicConfigJSONB `json:"appConfig,omitempty"`
+}
+
+// PermissionSpec is the declarative representation of a permission
+type PermissionSpec struct {
+	Name   string `json:"name"`
+	AppTag string `json:"appTag"`
+}
+
+// GroupSpec is the declarative representation of a group, its permissions and its members
+type GroupSpec struct {
+	Name        string   `json:"name"`
+	Permissions []string `json:"permissions"`
+	Members     []string `json:"members"`
+}
+
+// Load reads an rbac document from a yaml file. Unknown fields are rejected.
+func Load(path string) (Document, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return Document{}, fmt.Errorf("unable to read rbac document: %s", err.Error())
+	}
+	return Parse(b)
+}
+
+// Parse parses and validates a yaml rbac document
+func Parse(b []byte) (Document, error) {
+	doc := Document{}
+	if err := yaml.UnmarshalStrict(b, &doc); err != nil {
+		return Document{}, fmt.Errorf("unable to parse rbac document: %s", err.Error())
+	}
+
+	if valid, errMsg := doc.IsValid(); !valid {
+		return Document{}, fmt.Errorf("invalid rbac document: %s", errMsg)
+	}
+	return doc, nil
+}
+
+// Marshal encodes an rbac document as yaml
+func Marshal(doc Document) ([]byte, error) {
+	return yaml.Marshal(doc)
+}
+
+// IsValid validates the document header, names and uniqueness of permissions and groups, and the app config
+func (d *Document) IsValid() (bool, string) {
+	errs := strings.Builder{}
+	if d.APIVersion != APIVersion || d.Kind != Kind {
+		errs.WriteString(fmt.Sprintf("apiVersion and kind must be %s and %s.\n", APIVersion, Kind))
+	}
+
+	permissionNames := map[string]bool{}
+	appTags := map[string]bool{}
+	for _, p := range d.Permissions {
+		permission := types.Permission{Name: p.Name, AppTag: p.AppTag}
+		if valid, errMsg := permission.IsValid(); !valid {
+			errs.WriteString(fmt.Sprintf("permission %q: %s", p.Name, errMsg))
+		}
+		if p.Name == "" || p.AppTag == "" {
+			errs.WriteString("permissions must have a name and an appTag.\n")
+		}
+		if permissionNames[p.Name] {
+			errs.WriteString(fmt.Sprintf("permission %q is declared more than once.\n", p.Name))
+		}
+		if appTags[p.AppTag] {
+			errs.WriteString(fmt.Sprintf("appTag %q is declared more than once.\n", p.AppTag))
+		}
+		permissionNames[p.Name] = true
+		appTags[p.AppTag] = true
+	}
+
+	groupNames := map[string]bool{}
+	for _, g := range d.Groups {
+		group := types.Group{Name: g.Name}
+		if valid, errMsg := group.IsValid(); !valid {
+			errs.WriteString(fmt.Sprintf("group %q: %s", g.Name, errMsg))
+		}
+		if g.Name == "" {
+			errs.WriteString("groups must have a name.\n")
+		}
+		if groupNames[g.Name] {
+			errs.WriteString(fmt.Sprintf("group %q is declared more than once.\n", g.Name))
+		}
+		groupNames[g.Name] = true
+	}
+
+	if d.AppConfig != nil {
+		if valid, errMsg := d.AppConfig.IsValid(); !valid {
+			errs.WriteString(fmt.Sprintf("appConfig: %s", errMsg))
+		}
+	}
+
+	errMsg := errs.String()
+	if len(errMsg) > 0 {
+		return false, errMsg
+	}
+	return true, ""
+}
+
+// Export reads the current access model from the store. Entries are sorted by name so that exports diff cleanly.
+func Export(store Store) (Document, error) {
+	permissions, err := store.GetPermissions()
+	if err != nil {
+		return Document{}, err
+	}
+
+	groups, err := store.GetGroups()
+	if err != nil {
+		return Document{}, err
+	}
+
+	dac, err := store.GetDynamicAppConfig()
+	if err != nil {
+		return Document{}, err
+	}
+
+	doc := Document{
+		APIVersion:  APIVersion,
+		Kind:        Kind,
+		Permissions: []PermissionSpec{},
+		Groups:      []GroupSpec{},
+		AppConfig:   &dac.Data,
+	}
+
+	for _, p := range permissions {
+		doc.Permissions = append(doc.Permissions, PermissionSpec{Name: p.Name, AppTag: p.AppTag})
+	}
+	sort.Slice(doc.Permissions, func(i, j int) bool { return doc.Permissions[i].Name < doc.Permissions[j].Name })
+
+	for _, g := range groups {
+		spec := GroupSpec{Name: g.Name, Permissions: []string{}, Members: []string{}}
+		for _, p := range g.Permissions {
+			spec.Permissions = append(spec.Permissions, p.Name)
+		}
+		for _, u := range g.Users {
+			spec.Members = append(spec.Members, u.Name)
+		}
+		sort.Strings(spec.Permissions)
+		sort.Strings(spec.Members)
+		doc.Groups = append(doc.Groups, spec)
+	}
+	sort.Slice(doc.Groups, func(i, j int) bool { return doc.Groups[i].Name < doc.Groups[j].Name })
+
+	return doc, nil
+}
+
+// ErrBuiltinAdmin is returned when a document attempts to change the app tag of the built-in admin permission, or the
+// permissions of the built-in admin group. The members of the admin group can be changed.
+var ErrBuiltinAdmin = errors.New("the built-in admin permission and the permissions of the built-in admin group cannot be changed")
@@ -0,0 +1,302 @@
+package rbac
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/sullivtr/k8s_platform/internal/types"
+)
+
+// fakeStore is an in-memory Store
+type fakeStore struct {
+	permissions []types.Permission
+	groups      []types.Group
+	users       []types.User
+	appConfig   types.DynamicAppConfig
+
+	upsertedPermissions []types.Permission
+	deletedPermissions  []uuid.UUID
+	upsertedGroups      []types.Group
+	deletedGroups       []uuid.UUID
+	updatedAppConfig    *types.DynamicAppConfig
+	auditEvents         []types.AuditEvent
+	failGroupUpserts    bool
+}
+
+func (s *fakeStore) GetPermissions() ([]types.Permission, error) { return s.permissions, nil }
+func (s *fakeStore) UpsertPermission(p types.Permission) (types.Permission, error) {
+	s.upsertedPermissions = append(s.upsertedPermissions, p)
+	return p, nil
+}
+func (s *fakeStore) DeletePermission(id uuid.UUID) error {
+	s.deletedPermissions = append(s.deletedPermissions, id)
+	return nil
+}
+func (s *fakeStore) GetGroups() ([]types.Group, error) { return s.groups, nil }
+func (s *fakeStore) UpsertGroup(g types.Group) (types.Group, error) {
+	if s.failGroupUpserts {
+		return types.Group{}, errors.New("boom")
+	}
+	s.upsertedGroups = append(s.upsertedGroups, g)
+	return g, nil
+}
+func (s *fakeStore) DeleteGroup(id uuid.UUID) error {
+	s.deletedGroups = append(s.deletedGroups, id)
+	return nil
+}
+func (s *fakeStore) GetUsers() ([]types.User, error) { return s.users, nil }
+func (s *fakeStore) GetDynamicAppConfig() (types.DynamicAppConfig, error) {
+	return s.appConfig, nil
+}
+func (s *fakeStore) UpdateDynamicAppConfig(c types.DynamicAppConfig) (types.DynamicAppConfig, error) {
+	s.updatedAppConfig = &c
+	return c, nil
+}
+func (s *fakeStore) RecordAuditEvent(e types.AuditEvent) { s.auditEvents = append(s.auditEvents, e) }
+
+func newFakeStore() *fakeStore {
+	adminID := uuid.MustParse(types.BuiltinAdminID)
+	checkoutID := uuid.New()
+	legacyID := uuid.New()
+	paymentsID := uuid.New()
+	oldID := uuid.New()
+	jdoeID := uuid.New()
+	asmithID := uuid.New()
+
+	allAdmin := types.Permission{ID: &adminID, Name: "AllAdmin", AppTag: "*"}
+	checkout := types.Permission{ID: &checkoutID, Name: "checkoutwrite", AppTag: "checkout_read"}
+	legacy := types.Permission{ID: &legacyID, Name: "legacywrite", AppTag: "legacy_write"}
+	jdoe := types.User{ID: &jdoeID, Name: "jdoe"}
+	asmith := types.User{ID: &asmithID, Name: "asmith"}
+
+	return &fakeStore{
+		permissions: []types.Permission{allAdmin, checkout, legacy},
+		groups: []types.Group{
+			{ID: &adminID, Name: "Admin", Permissions: []*types.Permission{&allAdmin}},
+			{ID: &paymentsID, Name: "payments", Permissions: []*types.Permission{&checkout, &legacy}, Users: []*types.User{&asmith}},
+			{ID: &oldID, Name: "old", Permissions: []*types.Permission{&legacy}},
+		},
+		users: []types.User{jdoe, asmith},
+		appConfig: types.DynamicAppConfig{ID: 1, Version: 3, Data: types.DynamicConfigJSONB{
+			DefaultReplicaScaleLimit: 100,
+			EnableK8sGlobalReadOnly:  true,
+			K8sClusterName:           "prod",
+		}},
+	}
+}
+
+const testDocument = `
+apiVersion: khub/v1
+kind: RBAC
+permissions:
+  - name: AllAdmin
+    appTag: "*"
+  - name: checkoutwrite
+    appTag: checkout_write
+  - name: searchread
+    appTag: search_read
+groups:
+  - name: Admin
+    permissions: [AllAdmin]
+  - name: payments
+    permissions: [checkoutwrite]
+    members: [jdoe]
+  - name: search
+    permissions: [searchread]
+    members: [jdoe, asmith]
+appConfig:
+  defaultReplicaScaleLimit: 100
+  enableK8sGlobalReadOnly: false
+  k8sClusterName: prod
+`
+
+func TestParseRejectsInvalidDocuments(t *testing.T) {
+	_, err := Parse([]byte("apiVersion: khub/v1\nkind: RBAC\nunknown: true\n"))
+	assert.ErrorContains(t, err, "unable to parse rbac document")
+
+	_, err = Parse([]byte("apiVersion: v2\nkind: RBAC\n"))
+	assert.ErrorContains(t, err, "apiVersion and kind must be khub/v1 and RBAC")
+
+	_, err = Parse([]byte("apiVersion: khub/v1\nkind: RBAC\ngroups:\n  - name: dup\n  - name: dup\n"))
+	assert.ErrorContains(t, err, `group "dup" is declared more than once`)
+
+	_, err = Parse([]byte("apiVersion: khub/v1\nkind: RBAC\npermissions:\n  - name: bad name\n    appTag: bad\n"))
+	assert.ErrorContains(t, err, "Permission Name is invalid")
+}
+
+func TestExport(t *testing.T) {
+	doc, err := Export(newFakeStore())
+	require.NoError(t, err)
+
+	assert.Equal(t, []PermissionSpec{
+		{Name: "AllAdmin", AppTag: "*"},
+		{Name: "checkoutwrite", AppTag: "checkout_read"},
+		{Name: "legacywrite", AppTag: "legacy_write"},
+	}, doc.Permissions)
+	assert.Equal(t, GroupSpec{Name: "payments", Permissions: []string{"checkoutwrite", "legacywrite"}, Members: []string{"asmith"}}, doc.Groups[2])
+	assert.Equal(t, "prod", doc.AppConfig.K8sClusterName)
+
+	// An export is a valid document that applies without changes
+	b, err := Marshal(doc)
+	require.NoError(t, err)
+	parsed, err := Parse(b)
+	require.NoError(t, err)
+	plan, err := NewPlan(newFakeStore(), parsed, true)
+	require.NoError(t, err)
+	assert.True(t, plan.Empty(), plan.String())
+}
+
+func TestNewPlan(t *testing.T) {
+	doc, err := Parse([]byte(testDocument))
+	require.NoError(t, err)
+
+	plan, err := NewPlan(newFakeStore(), doc, false)
+	require.NoError(t, err)
+
+	assert.Equal(t, `~ permission checkoutwrite
+    appTag: checkout_read -> checkout_write
++ permission searchread
+    appTag: search_read
+~ group payments
+    - permission legacywrite
+    + member jdoe
+    - member asmith
++ group search
+    + permission searchread
+    + member asmith
+    + member jdoe
+~ appconfig dynamic app config
+    enableK8sGlobalReadOnly: true -> false
+
+Plan: 2 to create, 3 to update, 0 to delete.
+`, plan.String())
+}
+
+func TestNewPlanPrune(t *testing.T) {
+	doc, err := Parse([]byte(testDocument))
+	require.NoError(t, err)
+
+	plan, err := NewPlan(newFakeStore(), doc, true)
+	require.NoError(t, err)
+
+	deletions := []string{}
+	for _, c := range plan.Changes {
+		if c.Action == ActionDelete {
+			deletions = append(deletions, c.Kind+" "+c.Name)
+		}
+	}
+	// Groups are deleted before permissions and the built-in admin entries are kept
+	assert.Equal(t, []string{"group old", "permission legacywrite"}, deletions)
+}
+
+func TestNewPlanErrors(t *testing.T) {
+	doc, err := Parse([]byte("apiVersion: khub/v1\nkind: RBAC\ngroups:\n  - name: search\n    members: [nobody]\n"))
+	require.NoError(t, err)
+	_, err = NewPlan(newFakeStore(), doc, false)
+	assert.ErrorContains(t, err, `group "search" references unknown user "nobody"`)
+
+	// Undeclared permissions cannot be referenced when they are about to be pruned
+	doc, err = Parse([]byte("apiVersion: khub/v1\nkind: RBAC\ngroups:\n  - name: search\n    permissions: [legacywrite]\n"))
+	require.NoError(t, err)
+	_, err = NewPlan(newFakeStore(), doc, false)
+	assert.NoError(t, err)
+	_, err = NewPlan(newFakeStore(), doc, true)
+	assert.ErrorContains(t, err, `group "search" references unknown permission "legacywrite"`)
+
+	doc, err = Parse([]byte("apiVersion: khub/v1\nkind: RBAC\npermissions:\n  - name: AllAdmin\n    appTag: nothing\n"))
+	require.NoError(t, err)
+	_, err = NewPlan(newFakeStore(), doc, false)
+	assert.ErrorIs(t, err, ErrBuiltinAdmin)
+
+	// The permissions of the built-in admin group cannot be changed, its members can
+	doc, err = Parse([]byte("apiVersion: khub/v1\nkind: RBAC\ngroups:\n  - name: Admin\n    permissions: [AllAdmin, legacywrite]\n"))
+	require.NoError(t, err)
+	_, err = NewPlan(newFakeStore(), doc, false)
+	assert.ErrorIs(t, err, ErrBuiltinAdmin)
+
+	doc, err = Parse([]byte("apiVersion: khub/v1\nkind: RBAC\ngroups:\n  - name: Admin\n    permissions: []\n"))
+	require.NoError(t, err)
+	_, err = NewPlan(newFakeStore(), doc, false)
+	assert.ErrorIs(t, err, ErrBuiltinAdmin)
+
+	doc, err = Parse([]byte("apiVersion: khub/v1\nkind: RBAC\ngroups:\n  - name: Admin\n    permissions: [AllAdmin]\n    members: [jdoe]\n"))
+	require.NoError(t, err)
+	plan, err := NewPlan(newFakeStore(), doc, false)
+	require.NoError(t, err)
+	require.Len(t, plan.Changes, 1)
+	assert.Equal(t, []string{"+ member jdoe"}, plan.Changes[0].Details)
+}
+
+func TestApply(t *testing.T) {
+	doc, err := Parse([]byte(testDocument))
+	require.NoError(t, err)
+
+	store := newFakeStore()
+	plan, err := NewPlan(store, doc, true)
+	require.NoError(t, err)
+	require.NoError(t, plan.Apply(store, "ci"))
+
+	assert.Len(t, store.upsertedPermissions, 2)
+	assert.Len(t, store.upsertedGroups, 2)
+	assert.Len(t, store.deletedGroups, 1)
+	assert.Len(t, store.deletedPermissions, 1)
+	assert.False(t, store.updatedAppConfig.Data.EnableK8sGlobalReadOnly)
+	assert.Equal(t, 3, store.updatedAppConfig.Version)
+	assert.Equal(t, "ci", store.updatedAppConfig.UpdatedBy)
+	assert.Len(t, store.auditEvents, len(plan.Changes))
+	assert.Equal(t, "ci", store.auditEvents[0].Actor)
+
+	// New groups reference the permissions created by the same plan
+	search := store.upsertedGroups[1]
+	assert.Equal(t, "search", search.Name)
+	assert.Equal(t, *store.upsertedPermissions[1].ID, *search.Permissions[0].ID)
+}
+
+func TestApplyStopsAtFirstError(t *testing.T) {
+	doc, err := Parse([]byte(testDocument))
+	require.NoError(t, err)
+
+	store := newFakeStore()
+	store.failGroupUpserts = true
+	plan, err := NewPlan(store, doc, true)
+	require.NoError(t, err)
+
+	err = plan.Apply(store, "ci")
+	assert.EqualError(t, err, "unable to update group payments: boom")
+	assert.Nil(t, store.updatedAppConfig)
+	assert.Empty(t, store.deletedGroups)
+}
+
+func TestAppConfigDetails(t *testing.T) {
+	existing := types.DynamicConfigJSONB{
+		ReplicaScaleLimits: map[string]int{"checkout": 10},
+		K8sPodExecPlugins: []types.K8sPodExecPlugin{
+			{Name: "dump", Command: "jstack 1"},
+			{Name: "restart", Command: "kill 1"},
+		},
+	}
+	desired := types.DynamicConfigJSONB{
+		ReplicaScaleLimits: map[string]int{"checkout": 20, "search": 5},
+		K8sPodExecPlugins: []types.K8sPodExecPlugin{
+			{Name: "restart", Command: "kill -9 1"},
+		},
+	}
+
+	details, err := appConfigDetails(existing, desired)
+	require.NoError(t, err)
+	// Maps are compared by key and plugins by name
+	assert.Equal(t, []string{
+		`k8sPodExecPlugins[dump]: {"command":"jstack 1","container":"","enabled":false,"labelFilter":"","name":"dump"} -> null`,
+		`k8sPodExecPlugins[restart].command: "kill 1" -> "kill -9 1"`,
+		`replicaScaleLimits.checkout: 10 -> 20`,
+		`replicaScaleLimits.search: null -> 5`,
+	}, details)
+}
+
+func TestParseRejectsInvalidAppConfig(t *testing.T) {
+	_, err := Parse([]byte("apiVersion: khub/v1\nkind: RBAC\nappConfig:\n  defaultReplicaScaleLimit: -1\n  k8sClusterName: prod\n"))
+	assert.ErrorContains(t, err, "appConfig: defaultReplicaScaleLimit must not be negative")
+}
@@ -0,0 +1,256 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/gorilla/sessions"
+	"github.com/labstack/echo-contrib/session"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/suite"
+	"github.com/sullivtr/k8s_platform/internal/config"
+	"github.com/sullivtr/k8s_platform/internal/devidp"
+	"github.com/sullivtr/k8s_platform/internal/handlers"
+	"github.com/sullivtr/k8s_platform/internal/modules"
+	"github.com/sullivtr/k8s_platform/internal/providers"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// AuthFlowSuite exercises Login -> AuthCodeCallback -> UserIdentity end to end against the dev idp
+type AuthFlowSuite struct {
+	suite.Suite
+	idp    *httptest.Server
+	khub   *httptest.Server
+	mock   sqlmock.Sqlmock
+	client *http.Client
+}
+
+func (s *AuthFlowSuite) SetupTest() {
+	var provider *devidp.Provider
+	s.idp = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		provider.ServeHTTP(w, r)
+	}))
+
+	var err error
+	users := append(devidp.DefaultUsers(), devidp.User{
+		Subject: "dev-unverified", Email: "jane.doe@khub.dev", Name: "Unverified Jane", Claims: map[string]any{"email_verified": false},
+	}, devidp.User{
+		// The email is verified, but the preferred username is set to the email of another user
+		Subject: "dev-impostor", Email: "mallory@khub.dev", PreferredUsername: "jane.doe@khub.dev", Name: "Mallory",
+	})
+	provider, err = devidp.New(devidp.Config{Issuer: s.idp.URL, Users: users})
+	s.Require().NoError(err)
+
+	db, mock, err := sqlmock.New()
+	s.Require().NoError(err)
+	s.mock = mock
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{Conn: db, PreferSimpleProtocol: true}), &gorm.Config{})
+	s.Require().NoError(err)
+
+	e := echo.New()
+	s.khub = httptest.NewServer(e)
+
+	c := &config.Config{
+		BaseURL:                     s.khub.URL + "/",
+		OIDCMetadataCacheTTLSeconds: 60,
+		OIDCProviders: []config.OIDCProvider{{
+			Name:        "dev",
+			Issuer:      provider.Issuer(),
+			ClientID:    provider.ClientID(),
+			RedirectURI: s.khub.URL + "/authorization-code/callback",
+		}},
+	}
+	prvds := &providers.ModuleProviders{
+		Config:          c,
+		StorageProvider: &providers.StorageProvider{Session: providers.StorageSession{SDK: modules.NewPGSDK(gormDB)}},
+		CacheProvider:   &providers.CacheProvider{Session: providers.CacheSession{SDK: modules.NewRedisStorageSDK("127.0.0.1:1")}},
+	}
+
+	e.Use(session.MiddlewareWithConfig(session.Config{Store: sessions.NewCookieStore([]byte("auth-flow-secret"))}))
+	e.Use(UserIdentity(c.OIDCClientID, c.OIDCIssuer, prvds.StorageProvider, authSkipper))
+	s.Require().NoError(handlers.RegisterRoutes(e, prvds))
+	e.GET("/api/test/whoami", func(ctx echo.Context) error {
+		return ctx.String(http.StatusOK, ctx.Get("username").(string)+" "+ctx.Get("email").(string))
+	})
+
+	jar, err := cookiejar.New(nil)
+	s.Require().NoError(err)
+	khubURL, _ := url.Parse(s.khub.URL)
+	s.client = &http.Client{
+		Jar: jar,
+		// Stop following redirects once the callback sends the browser back to the khub base url
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if req.URL.Host == khubURL.Host && req.URL.Path == "/" {
+				return http.ErrUseLastResponse
+			}
+			return nil
+		},
+	}
+}
+
+func (s *AuthFlowSuite) TearDownTest() {
+	s.khub.Close()
+	s.idp.Close()
+}
+
+func (s *AuthFlowSuite) expectNewUser(name, email, subject string) {
+	s.mock.ExpectQuery(`SELECT \* FROM "users" WHERE issuer = \$1 AND subject = \$2`).
+		WithArgs(s.idp.URL, subject, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	s.mock.ExpectQuery(`SELECT \* FROM "users" WHERE name = \$1`).
+		WithArgs(name, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	s.mock.ExpectBegin()
+	s.mock.ExpectQuery(`INSERT INTO "users"`).
+		WithArgs(name, email, false, sqlmock.AnyArg(), true, false, s.idp.URL, subject, false, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(uuid.New()))
+	s.mock.ExpectCommit()
+}
+
+func (s *AuthFlowSuite) get(path string) (int, string) {
+	resp, err := s.client.Get(s.khub.URL + path)
+	s.Require().NoError(err)
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, strings.TrimSpace(string(body))
+}
+
+func (s *AuthFlowSuite) TestLoginCallbackUserIdentity() {
+	s.expectNewUser("jdoe", "jane.doe@khub.dev", "dev-user")
+
+	status, body := s.get("/api/test/whoami")
+	s.Equal(http.StatusForbidden, status, body)
+
+	status, body = s.get("/login?login_hint=jane.doe@khub.dev")
+	s.Equal(http.StatusFound, status, body)
+
+	status, body = s.get("/api/test/whoami")
+	s.Equal(http.StatusOK, status)
+	s.Equal("jdoe jane.doe@khub.dev", body)
+	s.NoError(s.mock.ExpectationsWereMet())
+}
+
+func (s *AuthFlowSuite) TestLoginUnknownIDP() {
+	status, body := s.get("/login?idp=github")
+	s.Equal(http.StatusBadRequest, status)
+	s.Contains(body, "unknown identity provider: github")
+}
+
+func (s *AuthFlowSuite) TestLoginDeactivatedUser() {
+	s.mock.ExpectQuery(`SELECT \* FROM "users" WHERE issuer = \$1 AND subject = \$2`).
+		WithArgs(s.idp.URL, "dev-user", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "email", "issuer", "subject", "deactivated"}).
+			AddRow(uuid.New(), "jdoe", "jane.doe@khub.dev", s.idp.URL, "dev-user", true))
+	s.mock.ExpectQuery(`SELECT \* FROM "group_users"`).
+		WillReturnRows(sqlmock.NewRows([]string{"group_id", "user_id"}))
+
+	status, body := s.get("/login?login_hint=jane.doe@khub.dev")
+	s.Equal(http.StatusForbidden, status)
+	s.Contains(body, "user has been deactivated")
+
+	status, _ = s.get("/api/test/whoami")
+	s.Equal(http.StatusForbidden, status)
+	s.NoError(s.mock.ExpectationsWereMet())
+}
+
+func (s *AuthFlowSuite) TestLogoutEndsIDPSession() {
+	s.expectNewUser("admin", "admin@khub.dev", "dev-admin")
+
+	status, body := s.get("/login?login_hint=dev-admin")
+	s.Equal(http.StatusFound, status, body)
+
+	// The dev idp redirects back to the khub base url once its session is ended
+	status, _ = s.get("/logout")
+	s.Equal(http.StatusFound, status)
+
+	status, _ = s.get("/api/test/whoami")
+	s.Equal(http.StatusForbidden, status)
+}
+
+func (s *AuthFlowSuite) TestLoginLinksLegacyUser() {
+	legacyID := uuid.New()
+	s.mock.ExpectQuery(`SELECT \* FROM "users" WHERE issuer = \$1 AND subject = \$2`).
+		WithArgs(s.idp.URL, "dev-user", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	s.mock.ExpectQuery(`SELECT \* FROM "users" WHERE name = \$1`).
+		WithArgs("jdoe", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "email"}).AddRow(legacyID, "jdoe", "jane.doe@khub.dev"))
+	s.mock.ExpectBegin()
+	s.mock.ExpectExec(`UPDATE "users" SET .*"issuer"=\$\d+,"subject"=\$\d+`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	s.mock.ExpectCommit()
+
+	status, body := s.get("/login?login_hint=dev-user")
+	s.Equal(http.StatusFound, status, body)
+
+	status, body = s.get("/api/test/whoami")
+	s.Equal(http.StatusOK, status)
+	s.Equal("jdoe jane.doe@khub.dev", body)
+	s.NoError(s.mock.ExpectationsWereMet())
+}
+
+func (s *AuthFlowSuite) TestLoginDoesNotLinkUnverifiedEmail() {
+	s.mock.ExpectQuery(`SELECT \* FROM "users" WHERE issuer = \$1 AND subject = \$2`).
+		WithArgs(s.idp.URL, "dev-unverified", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	s.mock.ExpectQuery(`SELECT \* FROM "users" WHERE name = \$1`).
+		WithArgs("jdoe", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "email"}).AddRow(uuid.New(), "jdoe", "jane.doe@khub.dev"))
+	// The name suffixed with the idp is taken as well
+	s.mock.ExpectQuery(`SELECT \* FROM "users" WHERE name = \$1`).
+		WithArgs("jdoe-dev", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(uuid.New(), "jdoe-dev"))
+	s.mock.ExpectQuery(`SELECT \* FROM "users" WHERE name = \$1`).
+		WithArgs("jdoe-dev-2", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	s.mock.ExpectBegin()
+	s.mock.ExpectQuery(`INSERT INTO "users"`).
+		WithArgs("jdoe-dev-2", "jane.doe@khub.dev", false, sqlmock.AnyArg(), true, false, s.idp.URL, "dev-unverified", false, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(uuid.New()))
+	s.mock.ExpectCommit()
+
+	status, body := s.get("/login?login_hint=dev-unverified")
+	s.Equal(http.StatusFound, status, body)
+
+	status, body = s.get("/api/test/whoami")
+	s.Equal(http.StatusOK, status)
+	s.Equal("jdoe-dev-2 jane.doe@khub.dev", body)
+	s.NoError(s.mock.ExpectationsWereMet())
+}
+
+func (s *AuthFlowSuite) TestLoginDoesNotLinkByPreferredUsername() {
+	s.mock.ExpectQuery(`SELECT \* FROM "users" WHERE issuer = \$1 AND subject = \$2`).
+		WithArgs(s.idp.URL, "dev-impostor", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	s.mock.ExpectQuery(`SELECT \* FROM "users" WHERE name = \$1`).
+		WithArgs("jdoe", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "email"}).AddRow(uuid.New(), "jdoe", "jane.doe@khub.dev"))
+	s.mock.ExpectQuery(`SELECT \* FROM "users" WHERE name = \$1`).
+		WithArgs("jdoe-dev", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	s.mock.ExpectBegin()
+	s.mock.ExpectQuery(`INSERT INTO "users"`).
+		WithArgs("jdoe-dev", "jane.doe@khub.dev", false, sqlmock.AnyArg(), true, false, s.idp.URL, "dev-impostor", false, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(uuid.New()))
+	s.mock.ExpectCommit()
+
+	status, body := s.get("/login?login_hint=dev-impostor")
+	s.Equal(http.StatusFound, status, body)
+
+	status, body = s.get("/api/test/whoami")
+	s.Equal(http.StatusOK, status)
+	s.Equal("jdoe-dev jane.doe@khub.dev", body)
+	s.NoError(s.mock.ExpectationsWereMet())
+}
+
+func TestAuthFlowSuite(t *testing.T) {
+	suite.Run(t, new(AuthFlowSuite))
+}
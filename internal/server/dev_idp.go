@@ -0,0 +1,61 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+	"github.com/sullivtr/k8s_platform/internal/config"
+	"github.com/sullivtr/k8s_platform/internal/devidp"
+)
+
+// devIDPPath is the path the in-process dev identity provider is served from
+const devIDPPath = "/dev-idp"
+
+// mountDevIDP serves the in-process dev identity provider and makes it the default identity provider. The configured
+// identity providers, including the provider described by the legacy OIDC* settings, remain selectable.
+// It is a no-op in production.
+func mountDevIDP(e *echo.Echo, c *config.Config) error {
+	if !c.DevIDPEnabled {
+		return nil
+	}
+	if c.IsProduction() {
+		log.Warn().Msg("the dev idp is enabled but will not be served in production")
+		return nil
+	}
+
+	users := devidp.DefaultUsers()
+	if c.DevIDPUsersFile != "" {
+		var err error
+		if users, err = devidp.LoadUsers(c.DevIDPUsersFile); err != nil {
+			return err
+		}
+	}
+
+	issuer := c.DevIDPIssuer
+	if issuer == "" {
+		issuer = fmt.Sprintf("http://localhost:%d%s", c.ListenPort, devIDPPath)
+	}
+
+	provider, err := devidp.New(devidp.Config{Issuer: issuer, Users: users})
+	if err != nil {
+		return err
+	}
+	e.Any(devIDPPath+"/*", echo.WrapHandler(http.StripPrefix(devIDPPath, provider)))
+
+	// GetOIDCProviders only falls back on the legacy provider while OIDCProviders is empty, so it is copied over
+	configured := c.OIDCProviders
+	if len(configured) == 0 && c.OIDCIssuer != "" {
+		configured = c.GetOIDCProviders()
+	}
+	c.OIDCProviders = append([]config.OIDCProvider{{
+		Name:        "dev",
+		Issuer:      provider.Issuer(),
+		RedirectURI: c.OIDCRedirectURI,
+		ClientID:    provider.ClientID(),
+	}}, configured...)
+
+	log.Warn().Msgf("serving the dev idp at %s. Do not use this in a shared environment", provider.Issuer())
+	return nil
+}
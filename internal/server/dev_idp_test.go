@@ -0,0 +1,39 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/sullivtr/k8s_platform/internal/config"
+)
+
+func providerNames(c *config.Config) []string {
+	names := []string{}
+	for _, p := range c.GetOIDCProviders() {
+		names = append(names, p.Name)
+	}
+	return names
+}
+
+func TestMountDevIDPKeepsConfiguredProviders(t *testing.T) {
+	legacy := &config.Config{DevIDPEnabled: true, ListenPort: 8080, AuthIDP: "okta", OIDCIssuer: "https://corp.okta.com/", OIDCClientID: "corp"}
+	require.NoError(t, mountDevIDP(echo.New(), legacy))
+	assert.Equal(t, []string{"dev", "okta"}, providerNames(legacy))
+	okta, err := legacy.GetOIDCProvider("okta")
+	require.NoError(t, err)
+	assert.Equal(t, "corp", okta.ClientID)
+
+	multi := &config.Config{DevIDPEnabled: true, ListenPort: 8080, OIDCProviders: []config.OIDCProvider{
+		{Name: "employees", Issuer: "https://corp.okta.com/"},
+		{Name: "contractors", Issuer: "https://contractors.okta.com/"},
+	}}
+	require.NoError(t, mountDevIDP(echo.New(), multi))
+	assert.Equal(t, []string{"dev", "employees", "contractors"}, providerNames(multi))
+
+	// Without any identity provider configured, the dev idp is the only one
+	unconfigured := &config.Config{DevIDPEnabled: true, ListenPort: 8080}
+	require.NoError(t, mountDevIDP(echo.New(), unconfigured))
+	assert.Equal(t, []string{"dev"}, providerNames(unconfigured))
+}
@@ -16,6 +16,7 @@ import (
 	"github.com/sullivtr/k8s_platform/internal/config"
 	"github.com/sullivtr/k8s_platform/internal/handlers"
 	"github.com/sullivtr/k8s_platform/internal/providers"
+	"github.com/sullivtr/k8s_platform/internal/types"
 )
 
 func loggerConfig(production bool) middleware.LoggerConfig {
@@ -39,18 +40,23 @@ func gzipSkipper(ctx echo.Context) bool {
 
 func staticSkipper(ctx echo.Context) bool {
 	return strings.Contains(ctx.Request().URL.Path, "/api") ||
-		strings.Contains(ctx.Request().URL.Path, "/swagger")
+		strings.Contains(ctx.Request().URL.Path, "/swagger") ||
+		strings.HasPrefix(ctx.Request().URL.Path, devIDPPath)
 }
 
+// authSkipper skips the identity of websocket requests, except for followed exec jobs, which are only readable by the
+// user who started them
 func authSkipper(ctx echo.Context) bool {
-	return websocketSkipper(ctx) ||
+	return websocketSkipper(ctx) && !strings.HasPrefix(ctx.Request().URL.Path, handlers.ExecJobsPath) ||
+		strings.HasPrefix(ctx.Request().URL.Path, handlers.ReportFilesPath) ||
 		!strings.Contains(ctx.Request().URL.Path, "/api") &&
 			!strings.Contains(ctx.Request().URL.Path, "/swagger")
 }
 
 func userContextSkipper(ctx echo.Context) bool {
 	fmt.Printf("URL Path: %s\n", ctx.Request().URL.Path)
-	return ctx.Request().URL.Path == "/api/users/me" || ctx.Request().URL.Path == "/api/k8s/name" || (!strings.Contains(ctx.Request().URL.Path, "/api") &&
+	return ctx.Request().URL.Path == "/api/users/me" || ctx.Request().URL.Path == "/api/k8s/name" ||
+		strings.HasPrefix(ctx.Request().URL.Path, handlers.ReportFilesPath) || (!strings.Contains(ctx.Request().URL.Path, "/api") &&
 		!strings.Contains(ctx.Request().URL.Path, "/swagger"))
 }
 
@@ -78,18 +84,36 @@ func getMiddleware(c *config.Config, prvds *providers.ModuleProviders) []echo.Mi
 			Browse:  false,
 			HTML5:   true,
 		}),
-		UserIdentity(c.OIDCClientID, c.OIDCIssuer, authSkipper),
+		UserIdentity(c.OIDCClientID, c.OIDCIssuer, prvds.StorageProvider, authSkipper),
 		userAccessContextMiddleware(prvds, userContextSkipper),
 	}
 }
 
-// UserIdentity is a middleware that extracts the user's identity from the request's session
-func UserIdentity(cid, issuer string, skipper func(c echo.Context) bool) echo.MiddlewareFunc {
+// UserIdentity is a middleware that extracts the user's identity from the request's session.
+// Requests carrying an `Authorization: Bearer` khub api token are identified by the token owner instead.
+func UserIdentity(cid, issuer string, storageProvider *providers.StorageProvider, skipper func(c echo.Context) bool) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(ctx echo.Context) error {
 			if skipper(ctx) {
 				return next(ctx)
 			}
+
+			if rawToken, ok := getBearerAPIToken(ctx.Request()); ok {
+				user, token, err := storageProvider.AuthenticateAPIToken(rawToken)
+				if err != nil {
+					return ctx.JSON(http.StatusUnauthorized, fmt.Sprintf("unauthorized. %s", err.Error()))
+				}
+
+				if !token.HasScope(types.APITokenScopeWrite) && ctx.Request().Method != http.MethodGet {
+					return ctx.JSON(http.StatusForbidden, "forbidden. This api token does not have the write scope")
+				}
+
+				ctx.Set("username", user.Name)
+				ctx.Set("email", user.Email)
+				ctx.Set("apiToken", token)
+				return next(ctx)
+			}
+
 			sess, err := session.Get("khub-login-session-store", ctx)
 			if err != nil {
 				return ctx.JSON(http.StatusForbidden, fmt.Sprintf("forbidden. Unable to get auth info: %s", err.Error()))
@@ -100,21 +124,55 @@ func UserIdentity(cid, issuer string, skipper func(c echo.Context) bool) echo.Mi
 				return ctx.JSON(http.StatusForbidden, "forbidden. Unable to read user context details from request (unauthenticated)")
 			}
 
-			userIdentityParts := strings.Split(username.(string), "@")
-			// Check if username contains a '.' -- Fix for local-dev and staging
-			if strings.Contains(userIdentityParts[0], ".") {
-				subparts := strings.Split(userIdentityParts[0], ".")
-				userIdentityParts[0] = fmt.Sprintf("%c%s", subparts[0][0], subparts[1])
+			// Sessions created by the auth callback carry the name of the user resolved from the IDP's issuer and subject.
+			// Older sessions fall back to deriving the name from the email.
+			khubUsername, ok := sess.Values["khub_username"].(string)
+			if !ok || khubUsername == "" {
+				khubUsername = types.UsernameFromEmail(username.(string))
 			}
 
 			// These are set by the auth callback handler
-			ctx.Set("username", userIdentityParts[0])
+			ctx.Set("username", khubUsername)
 			ctx.Set("email", strings.ToLower(username.(string)))
+
 			return next(ctx)
 		}
 	}
 }
 
+// getBearerAPIToken extracts a khub api token from the request's Authorization header
+func getBearerAPIToken(r *http.Request) (string, bool) {
+	authHeader := r.Header.Get("Authorization")
+	if !strings.HasPrefix(authHeader, "Bearer ") {
+		return "", false
+	}
+
+	rawToken := strings.TrimSpace(strings.TrimPrefix(authHeader, "Bearer "))
+	if !strings.HasPrefix(rawToken, types.APITokenPrefix) {
+		return "", false
+	}
+	return rawToken, true
+}
+
+// restrictPermissionTagsToScopes downgrades permission tags to read access when an api token was not granted the write scope
+func restrictPermissionTagsToScopes(permissionTags []string, token types.APIToken) []string {
+	if token.HasScope(types.APITokenScopeWrite) {
+		return permissionTags
+	}
+
+	restricted := []string{}
+	for _, p := range permissionTags {
+		if p == "*" {
+			restricted = append(restricted, "global_read_only")
+		} else if strings.HasSuffix(p, "_write") {
+			restricted = append(restricted, strings.TrimSuffix(p, "_write")+"_read")
+		} else {
+			restricted = append(restricted, p)
+		}
+	}
+	return restricted
+}
+
 // getIDTokenWithNonce extracts a id_token token from the request's session along with the nonce value
 func getIDTokenWithNonce(sess *sessions.Session) (string, string, error) {
 	if sess.Values["id_token"] == nil || sess.Values["id_token"] == "" {
@@ -131,8 +189,8 @@ func getIDTokenWithNonce(sess *sessions.Session) (string, string, error) {
 func clearSession(ctx echo.Context, sess *sessions.Session) {
 	delete(sess.Values, "id_token")
 	delete(sess.Values, "access_token")
-	delete(sess.Values, "username")
-	delete(sess.Values, "email")
+	delete(sess.Values, "preferred_username")
+	delete(sess.Values, "khub_username")
 	sess.Options.MaxAge = -1
 	sess.Save(ctx.Request(), ctx.Response())
 }
@@ -151,6 +209,26 @@ func userAccessContextMiddleware(prvds *providers.ModuleProviders, skipper func(
 			}
 			ctx.Set("dynamicAppConfig", dac)
 
+			// Api token requests are not backed by a browser session, so their permissions are resolved per request.
+			if token, ok := ctx.Get("apiToken").(types.APIToken); ok {
+				user, _, err := handlers.GetUserContext(ctx, prvds.StorageProvider)
+				if err != nil || user.ID == nil {
+					return ctx.JSON(http.StatusForbidden, "forbidden. Unable to read user context details from request (unauthenticated)")
+				}
+
+				permissions, err := handlers.GetUserPermissions(ctx, prvds.StorageProvider, &user, dac.Data.EnableK8sGlobalReadOnly)
+				if err != nil {
+					return ctx.JSON(http.StatusUnauthorized, fmt.Sprintf("Unable to get user permissions: %s", err.Error()))
+				}
+
+				permissionTags := []string{}
+				for _, p := range permissions {
+					permissionTags = append(permissionTags, p.AppTag)
+				}
+				ctx.Set("permissions", restrictPermissionTagsToScopes(permissionTags, token))
+				return next(ctx)
+			}
+
 			sess, err := session.Get("user-permissions", ctx)
 			if err != nil {
 				return ctx.JSON(http.StatusInternalServerError, fmt.Sprintf("Unable to get auth session: %s", err.Error()))
@@ -163,6 +241,10 @@ func userAccessContextMiddleware(prvds *providers.ModuleProviders, skipper func(
 					return ctx.JSON(http.StatusForbidden, "forbidden. Unable to read user context details from request (unauthenticated)")
 				}
 
+				if user.Deactivated {
+					return ctx.JSON(http.StatusForbidden, "forbidden. This user has been deactivated")
+				}
+
 				permissions, err := handlers.GetUserPermissions(ctx, prvds.StorageProvider, &user, dac.Data.EnableK8sGlobalReadOnly)
 				if err != nil {
 					return ctx.JSON(http.StatusUnauthorized, fmt.Sprintf("Unable to get user permissions: %s", err.Error()))
@@ -16,6 +16,7 @@ import (
 	"github.com/lestrrat-go/jwx/v2/jws"
 	"github.com/lestrrat-go/jwx/v2/jwt"
 	"github.com/stretchr/testify/suite"
+	"github.com/sullivtr/k8s_platform/internal/types"
 )
 
 const (
@@ -105,3 +106,72 @@ func (suite *MiddlewareSuite) TestGetBearerToken() {
 		suite.Equal(c.expectation, trimmedT, fmt.Sprintf("Test case %d failed", i))
 	}
 }
+
+func (suite *MiddlewareSuite) TestGetBearerAPIToken() {
+	cases := []struct {
+		header   string
+		expected string
+		ok       bool
+	}{
+		{header: "Bearer khub_0123456789abcdef", expected: "khub_0123456789abcdef", ok: true},
+		{header: "Bearer eyJhbGciOiJSUzI1NiJ9.e30.sig", expected: "", ok: false},
+		{header: "Basic dXNlcjpwYXNz", expected: "", ok: false},
+		{header: "", expected: "", ok: false},
+	}
+
+	for i, c := range cases {
+		req := httptest.NewRequest(http.MethodGet, "http://fake-url-for-test.com/api/resource", nil)
+		if c.header != "" {
+			req.Header.Set("Authorization", c.header)
+		}
+
+		token, ok := getBearerAPIToken(req)
+		suite.Equal(c.ok, ok, fmt.Sprintf("Test case %d failed", i))
+		suite.Equal(c.expected, token, fmt.Sprintf("Test case %d failed", i))
+	}
+}
+
+func (suite *MiddlewareSuite) TestRestrictPermissionTagsToScopes() {
+	permissionTags := []string{"*", "app_write", "other_read", "global_read_only"}
+
+	readOnly := restrictPermissionTagsToScopes(permissionTags, types.APIToken{Scopes: []string{types.APITokenScopeRead}})
+	suite.Equal([]string{"global_read_only", "app_read", "other_read", "global_read_only"}, readOnly)
+
+	readWrite := restrictPermissionTagsToScopes(permissionTags, types.APIToken{Scopes: []string{types.APITokenScopeRead, types.APITokenScopeWrite}})
+	suite.Equal(permissionTags, readWrite)
+}
+
+func (suite *MiddlewareSuite) TestSignedReportDownloadsSkipAuth() {
+	e := echo.New()
+	cases := []struct {
+		path string
+		skip bool
+	}{
+		{path: "/api/reports/files/heap.hprof", skip: true},
+		{path: "/api/reports/download/heap.hprof", skip: false},
+		{path: "/api/reports", skip: false},
+	}
+
+	for _, c := range cases {
+		ctx := e.NewContext(httptest.NewRequest(http.MethodGet, c.path, nil), httptest.NewRecorder())
+		suite.Equal(c.skip, authSkipper(ctx), c.path)
+		suite.Equal(c.skip, userContextSkipper(ctx), c.path)
+	}
+}
+
+func (suite *MiddlewareSuite) TestExecJobWebsocketsAreIdentified() {
+	e := echo.New()
+	cases := []struct {
+		path string
+		skip bool
+	}{
+		{path: "/api/k8s/exec/jobs/job-1", skip: false},
+		{path: "/api/k8s/pods", skip: true},
+	}
+
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodGet, c.path, nil)
+		req.Header.Set("Upgrade", "websocket")
+		suite.Equal(c.skip, authSkipper(e.NewContext(req, httptest.NewRecorder())), c.path)
+	}
+}
@@ -37,8 +37,15 @@ func NewApp(c *config.Config) Server {
 
 	prvds.InitCacheProvider()
 	prvds.InitStorageProvider()
+	prvds.WatchDynamicAppConfig(context.Background())
 	prvds.InitK8sProvider()
+	prvds.InitMySQLTopoProvider()
 	prvds.InitAWSProvider()
+	prvds.RefreshReportStorage(context.Background())
+
+	if err := mountDevIDP(e.Echo, c); err != nil {
+		log.Fatal().Msgf("unable to start the dev idp: %v", err)
+	}
 
 	e.Use(getMiddleware(c, prvds)...)
 
@@ -63,6 +70,7 @@ func NewDataSink(c *config.Config) {
 	}
 	prvds.InitCacheProvider()
 	prvds.InitStorageProvider()
+	prvds.WatchDynamicAppConfig(context.Background())
 	prvds.InitK8sProvider()
 
 	log.Info().Msg("Starting data sink server")
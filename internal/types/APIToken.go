@@ -0,0 +1,96 @@
+package types
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	// APITokenPrefix is prepended to every token minted by khub so bearer tokens can be
+	// distinguished from IDP issued tokens.
+	APITokenPrefix = "khub_"
+
+	// APITokenScopeRead grants read access to resources the token owner can read.
+	APITokenScopeRead = "read"
+	// APITokenScopeWrite grants write access to resources the token owner can write.
+	APITokenScopeWrite = "write"
+)
+
+// APIToken represents a personal or service account token used for programmatic access to the khub api.
+// Only a sha256 hash of the token is persisted, the raw token is returned once at creation time.
+type APIToken struct {
+	ID         *uuid.UUID     `json:"id" gorm:"type:uuid;default:gen_random_uuid()"`
+	Name       string         `json:"name"`
+	UserID     uuid.UUID      `json:"userId" gorm:"type:uuid;index"`
+	TokenHash  string         `json:"-" gorm:"uniqueIndex"`
+	Hint       string         `json:"hint"`
+	Scopes     []string       `json:"scopes" gorm:"serializer:json"`
+	ExpiresAt  time.Time      `json:"expiresAt"`
+	LastUsed   *time.Time     `json:"lastUsed"`
+	CreatedAt  time.Time      `json:"createdAt"`
+	UpdatedAt  time.Time      `json:"updatedAt"`
+	DeletedAt  gorm.DeletedAt `json:"-" gorm:"index"`
+	PlainToken string         `json:"token,omitempty" gorm:"-"`
+}
+
+// APITokenRequest represents the request body used to mint a new api token
+type APITokenRequest struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays int      `json:"expiresInDays"`
+}
+
+func (t *APITokenRequest) IsValid() (bool, string) {
+	errors := strings.Builder{}
+	nameRegex, _ := regexp.Compile(`^[\w\.\-]+$`)
+	if !nameRegex.MatchString(t.Name) {
+		errors.WriteString(fmt.Sprintln("Token Name is invalid. Must be alphanumeric (can only contain the following special characters: - or .)"))
+	}
+
+	if len(t.Scopes) == 0 {
+		errors.WriteString(fmt.Sprintln("Token Scopes are invalid. At least one scope is required."))
+	}
+
+	for _, s := range t.Scopes {
+		if s != APITokenScopeRead && s != APITokenScopeWrite {
+			errors.WriteString(fmt.Sprintf("Token Scope, %s, is invalid. Must be one of: %s, %s\n", s, APITokenScopeRead, APITokenScopeWrite))
+		}
+	}
+
+	if t.ExpiresInDays < 1 || t.ExpiresInDays > 365 {
+		errors.WriteString(fmt.Sprintln("Token ExpiresInDays is invalid. Must be between 1 and 365."))
+	}
+
+	errMsg := errors.String()
+	if len(errMsg) > 0 {
+		return false, errMsg
+	}
+
+	return true, ""
+}
+
+// HasScope reports whether the token was granted the given scope
+func (t *APIToken) HasScope(scope string) bool {
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// IsExpired reports whether the token is past its expiry
+func (t *APIToken) IsExpired() bool {
+	return time.Now().After(t.ExpiresAt)
+}
+
+// ServiceAccountRequest represents the request body used to create a non-human service account
+type ServiceAccountRequest struct {
+	Name     string      `json:"name"`
+	GroupIDs []uuid.UUID `json:"groupIds"`
+}
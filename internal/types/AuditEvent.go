@@ -0,0 +1,48 @@
+package types
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Audit actions recorded for administrative operations
+const (
+	AuditActionCreate      = "create"
+	AuditActionUpdate      = "update"
+	AuditActionDelete      = "delete"
+	AuditActionRestore     = "restore"
+	AuditActionDeactivate  = "deactivate"
+	AuditActionActivate    = "activate"
+	AuditActionGrantAdmin  = "grant_admin"
+	AuditActionRevokeAdmin = "revoke_admin"
+	AuditActionRevoke      = "revoke"
+	AuditActionRollback    = "rollback"
+	AuditActionExecute     = "execute"
+)
+
+// Audit resource types
+const (
+	AuditResourceUser           = "user"
+	AuditResourceGroup          = "group"
+	AuditResourcePermission     = "permission"
+	AuditResourceAPIToken       = "api_token"
+	AuditResourceServiceAccount = "service_account"
+	AuditResourceUserSession    = "user_session"
+	AuditResourceAppConfig      = "app_config"
+	AuditResourceReport         = "report"
+	AuditResourceMySQLHost      = "mysql_host"
+	AuditResourceGraphNode      = "graph_node"
+)
+
+// AuditEvent represents an administrative operation performed on the khub application
+type AuditEvent struct {
+	ID           *uuid.UUID `json:"id" gorm:"type:uuid;default:gen_random_uuid()"`
+	Actor        string     `json:"actor" gorm:"index"`
+	Action       string     `json:"action"`
+	ResourceType string     `json:"resourceType" gorm:"index"`
+	ResourceID   string     `json:"resourceId" gorm:"index"`
+	ResourceName string     `json:"resourceName"`
+	Detail       string     `json:"detail"`
+	CreatedAt    time.Time  `json:"createdAt" gorm:"index"`
+}
@@ -0,0 +1,80 @@
+package types
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Sources a catalog host password can be read from
+const (
+	CredentialSourceSecret = "secret"
+	CredentialSourceFile   = "file"
+	CredentialSourceEnv    = "env"
+)
+
+// CredentialEnvVarPrefix is the prefix of the environment variables credentials may be read from, so they cannot read
+// the configuration of khub itself
+const CredentialEnvVarPrefix = "KHUB_CREDENTIAL_"
+
+var (
+	// envVarNameRegex is the allowed format of environment variable names
+	envVarNameRegex = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+	// secretKeyRegex is the allowed format of kubernetes secret data keys
+	secretKeyRegex = regexp.MustCompile(`^[-._a-zA-Z0-9]+$`)
+	// secretNameRegex is an RFC 1123 subdomain, the allowed format of kubernetes secret names
+	secretNameRegex = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`)
+)
+
+// CredentialRef references where the password of a MySQL or Postgres catalog host is read from. It is resolved each
+// time the topology is captured, so rotated passwords are picked up. Hosts without a source use the catalog password of
+// their engine, the MySQLCatalogDBPassword or PostgresCatalogDBPassword config.
+type CredentialRef struct {
+	// Source is one of secret, file or env
+	Source string `json:"source,omitempty"`
+	// SecretNamespace, SecretName and SecretKey locate the password in a kubernetes secret
+	SecretNamespace string `json:"secretNamespace,omitempty"`
+	SecretName      string `json:"secretName,omitempty"`
+	SecretKey       string `json:"secretKey,omitempty"`
+	// FilePath is the absolute path of a file holding the password, such as a mounted secret. It must be within the
+	// CredentialFilesDir config.
+	FilePath string `json:"filePath,omitempty"`
+	// EnvVar is the environment variable holding the password, which must start with CredentialEnvVarPrefix
+	EnvVar string `json:"envVar,omitempty"`
+}
+
+// IsValid validates that the fields required by the credential source are set
+func (c CredentialRef) IsValid() (bool, string) {
+	errors := strings.Builder{}
+	switch c.Source {
+	case "":
+	case CredentialSourceSecret:
+		if len(c.SecretNamespace) > maxK8sNameLength || !k8sNameRegex.MatchString(c.SecretNamespace) {
+			errors.WriteString(fmt.Sprintln("Credential secretNamespace is invalid. Must be a kubernetes namespace name."))
+		}
+		if len(c.SecretName) > 253 || !secretNameRegex.MatchString(c.SecretName) {
+			errors.WriteString(fmt.Sprintln("Credential secretName is invalid. Must be a kubernetes secret name."))
+		}
+		if len(c.SecretKey) > 253 || !secretKeyRegex.MatchString(c.SecretKey) {
+			errors.WriteString(fmt.Sprintln("Credential secretKey is invalid. Must be alphanumeric characters, '-', '_' or '.'."))
+		}
+	case CredentialSourceFile:
+		if !filepath.IsAbs(c.FilePath) || filepath.Clean(c.FilePath) != c.FilePath {
+			errors.WriteString(fmt.Sprintln("Credential filePath is invalid. Must be a clean absolute path."))
+		}
+	case CredentialSourceEnv:
+		if !envVarNameRegex.MatchString(c.EnvVar) || !strings.HasPrefix(c.EnvVar, CredentialEnvVarPrefix) {
+			errors.WriteString(fmt.Sprintf("Credential envVar is invalid. Must be an environment variable name starting with %s.\n", CredentialEnvVarPrefix))
+		}
+	default:
+		errors.WriteString(fmt.Sprintf("Credential source %s is invalid. Must be one of %s, %s or %s.\n",
+			c.Source, CredentialSourceSecret, CredentialSourceFile, CredentialSourceEnv))
+	}
+
+	errMsg := errors.String()
+	if len(errMsg) > 0 {
+		return false, errMsg
+	}
+	return true, ""
+}
@@ -0,0 +1,32 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateCredentialRef(t *testing.T) {
+	for name, ref := range map[string]CredentialRef{
+		"catalog password": {},
+		"secret":           {Source: CredentialSourceSecret, SecretNamespace: "databases", SecretName: "khub.monitor", SecretKey: "mysql_password"},
+		"file":             {Source: CredentialSourceFile, FilePath: "/mnt/secrets/db-core-007"},
+		"env":              {Source: CredentialSourceEnv, EnvVar: "KHUB_CREDENTIAL_CORE_PASSWORD"},
+	} {
+		ok, msg := ref.IsValid()
+		assert.True(t, ok, "%s: %s", name, msg)
+	}
+
+	for name, ref := range map[string]CredentialRef{
+		"secret without a key": {Source: CredentialSourceSecret, SecretNamespace: "databases", SecretName: "khub-monitor"},
+		"invalid namespace":    {Source: CredentialSourceSecret, SecretNamespace: "Databases", SecretName: "khub-monitor", SecretKey: "password"},
+		"relative file":        {Source: CredentialSourceFile, FilePath: "secrets/password"},
+		"unclean file":         {Source: CredentialSourceFile, FilePath: "/mnt/secrets/../password"},
+		"invalid env var":      {Source: CredentialSourceEnv, EnvVar: "1PASSWORD"},
+		"khub env var":         {Source: CredentialSourceEnv, EnvVar: "KHUB_DB_PASSWORD"},
+		"unknown source":       {Source: "vault"},
+	} {
+		ok, _ := ref.IsValid()
+		assert.False(t, ok, name)
+	}
+}
@@ -4,24 +4,41 @@ import (
 	"database/sql/driver"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
 )
 
 // DynamicConfigJSONB is a custom type for JSONB fields in the database
 type DynamicConfigJSONB struct {
-	DefaultReplicaScaleLimit int                `json:"defaultReplicaScaleLimit"`
-	ReplicaScaleLimits       map[string]int     `json:"replicaScaleLimits"`
-	EnableK8sGlobalReadOnly  bool               `json:"enableK8sGlobalReadOnly"`
-	K8sClusterName           string             `json:"k8sClusterName"`
-	K8sClusterNamespaces     []string           `json:"k8sClusterNamespaces"`
-	K8sPodExecPlugins        []K8sPodExecPlugin `json:"k8sPodExecPlugins"`
+	DefaultReplicaScaleLimit int                  `json:"defaultReplicaScaleLimit"`
+	ReplicaScaleLimits       map[string]int       `json:"replicaScaleLimits"`
+	EnableK8sGlobalReadOnly  bool                 `json:"enableK8sGlobalReadOnly"`
+	K8sClusterName           string               `json:"k8sClusterName"`
+	K8sClusterNamespaces     []string             `json:"k8sClusterNamespaces"`
+	K8sPodExecPlugins        []K8sPodExecPlugin   `json:"k8sPodExecPlugins"`
+	MySQLQueryPlugins        []MySQLQueryPlugin   `json:"mySQLQueryPlugins"`
+	MySQLDiscovery           MySQLDiscoveryConfig `json:"mySQLDiscovery"`
+	MySQLConsumerRules       []MySQLConsumerRule  `json:"mySQLConsumerRules"`
+	ReplicationAlerting      ReplAlertingConfig   `json:"replicationAlerting"`
 }
 
+// K8sPodExecPlugin is a command users with write access can run in a pod container.
+// The command may reference its parameters as {{name}}. See BuildCommand.
 type K8sPodExecPlugin struct {
-	Name        string `json:"name"`
-	Enabled     bool   `json:"enabled"`
-	Command     string `json:"command"`
-	Container   string `json:"container"`
-	LabelFilter string `json:"labelFilter"`
+	Name        string                `json:"name"`
+	Enabled     bool                  `json:"enabled"`
+	Command     string                `json:"command"`
+	Container   string                `json:"container"`
+	LabelFilter string                `json:"labelFilter"`
+	Parameters  []ExecPluginParameter `json:"parameters,omitempty"`
+	// TimeoutSeconds bounds the run time of the command. DefaultExecPluginTimeoutSeconds is used when it is 0.
+	TimeoutSeconds int `json:"timeoutSeconds,omitempty"`
+	// Async plugins run as a background job. Their output is streamed instead of returned when the command exits.
+	Async bool `json:"async,omitempty"`
+	// OutputFile is a file the command writes in the container. It is uploaded to the reports bucket once the command succeeds.
+	OutputFile *ExecPluginOutputFile `json:"outputFile,omitempty"`
 }
 
 // Value Marshal
@@ -38,7 +55,142 @@ func (jsonField *DynamicConfigJSONB) Scan(value interface{}) error {
 	return json.Unmarshal(data, &jsonField)
 }
 
+// DynamicAppConfig is the current dynamic app config. There is only ever one record (ID 1).
+// Version is incremented on every update, and updates must provide the version they are based on
+// so that concurrent edits are rejected instead of silently overwriting each other.
 type DynamicAppConfig struct {
-	ID   uint               `json:"id" gorm:"primaryKey"`
-	Data DynamicConfigJSONB `json:"data" gorm:"type:jsonb"`
+	ID        uint               `json:"id" gorm:"primaryKey"`
+	Version   int                `json:"version" gorm:"not null;default:1"`
+	Data      DynamicConfigJSONB `json:"data" gorm:"type:jsonb"`
+	UpdatedBy string             `json:"updatedBy"`
+	UpdatedAt time.Time          `json:"updatedAt"`
+}
+
+// DynamicAppConfigVersion is an immutable snapshot of the dynamic app config, stored on every update
+type DynamicAppConfigVersion struct {
+	Version    int                `json:"version" gorm:"primaryKey;autoIncrement:false"`
+	Data       DynamicConfigJSONB `json:"data" gorm:"type:jsonb"`
+	Author     string             `json:"author"`
+	RollbackOf int                `json:"rollbackOf,omitempty"`
+	CreatedAt  time.Time          `json:"createdAt"`
+}
+
+// DynamicAppConfigRollbackRequest represents the request body used to roll the dynamic app config back to a previous version
+type DynamicAppConfigRollbackRequest struct {
+	// Version is the version to roll back to
+	Version int `json:"version"`
+	// CurrentVersion is the version the rollback is based on
+	CurrentVersion int `json:"currentVersion"`
+}
+
+// DynamicAppConfigChange is a single difference between two dynamic app configs.
+// Path is the json path of the changed value. Plugins are keyed by name, e.g. k8sPodExecPlugins[restart].command
+type DynamicAppConfigChange struct {
+	Path string `json:"path"`
+	From any    `json:"from"`
+	To   any    `json:"to"`
+}
+
+// DiffDynamicAppConfig lists the differences between two dynamic app configs, sorted by path
+func DiffDynamicAppConfig(from, to DynamicConfigJSONB) ([]DynamicAppConfigChange, error) {
+	fromValue, err := toJSONValue(from)
+	if err != nil {
+		return nil, err
+	}
+	toValue, err := toJSONValue(to)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := []DynamicAppConfigChange{}
+	diffJSONValues("", fromValue, toValue, &changes)
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes, nil
+}
+
+func toJSONValue(v any) (any, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var value any
+	err = json.Unmarshal(b, &value)
+	return value, err
+}
+
+// diffJSONValues recursively compares decoded json values. Objects are compared by key,
+// and lists of named objects are compared by name. Any other value is compared as a whole.
+func diffJSONValues(path string, from, to any, changes *[]DynamicAppConfigChange) {
+	if reflect.DeepEqual(from, to) {
+		return
+	}
+
+	fromMap, fromIsMap := from.(map[string]any)
+	toMap, toIsMap := to.(map[string]any)
+	if fromIsMap && toIsMap {
+		keys := map[string]bool{}
+		for k := range fromMap {
+			keys[k] = true
+		}
+		for k := range toMap {
+			keys[k] = true
+		}
+		for k := range keys {
+			diffJSONValues(joinPath(path, k), fromMap[k], toMap[k], changes)
+		}
+		return
+	}
+
+	fromNamed, fromOK := namedObjects(from)
+	toNamed, toOK := namedObjects(to)
+	if fromOK && toOK {
+		names := map[string]bool{}
+		for k := range fromNamed {
+			names[k] = true
+		}
+		for k := range toNamed {
+			names[k] = true
+		}
+		for k := range names {
+			diffJSONValues(fmt.Sprintf("%s[%s]", path, k), fromNamed[k], toNamed[k], changes)
+		}
+		return
+	}
+
+	*changes = append(*changes, DynamicAppConfigChange{Path: path, From: from, To: to})
+}
+
+// namedObjects indexes a list of objects by their unique name. Empty and missing lists are treated as an empty index.
+func namedObjects(v any) (map[string]any, bool) {
+	if v == nil {
+		return map[string]any{}, true
+	}
+	list, ok := v.([]any)
+	if !ok {
+		return nil, false
+	}
+
+	named := map[string]any{}
+	for _, item := range list {
+		obj, ok := item.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		name, ok := obj["name"].(string)
+		if !ok || name == "" {
+			return nil, false
+		}
+		if _, dup := named[name]; dup {
+			return nil, false
+		}
+		named[name] = obj
+	}
+	return named, true
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
 }
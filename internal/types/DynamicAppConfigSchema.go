@@ -0,0 +1,412 @@
+package types
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Validation limits of the dynamic app config. They are shared by Validate and the published json schema.
+const (
+	// maxK8sNameLength is the maximum length of kubernetes names and label values
+	maxK8sNameLength = 63
+	// maxPluginCommandLength is the maximum length of a pod exec plugin command
+	maxPluginCommandLength = 1024
+)
+
+var (
+	// clusterNamePattern only allows characters that are safe to use in the sink's cache keys
+	clusterNamePattern = `^[a-zA-Z0-9]([a-zA-Z0-9.-]*[a-zA-Z0-9])?$`
+	// k8sNamePattern is an RFC 1123 label, used by namespaces and container names
+	k8sNamePattern = `^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	// pluginNamePattern is the allowed format of a pod exec plugin name
+	pluginNamePattern = `^[a-zA-Z0-9][a-zA-Z0-9_-]*$`
+	// pluginCommandPattern rejects shell metacharacters, other than {{name}} parameter references. Commands are split on
+	// spaces and executed without a shell, so metacharacters never work as intended and usually indicate an injection attempt.
+	pluginCommandPattern = "^(?:[^;&|`$<>(){}\\[\\]\\\\!*?~'\"\\n\\r\\t]|\\{\\{[a-zA-Z][a-zA-Z0-9_]*\\}\\})+$"
+	// parameterNamePattern is the allowed format of an exec plugin parameter name
+	parameterNamePattern = `^[a-zA-Z][a-zA-Z0-9_]*$`
+	// outputFilePathPattern is an absolute path, which may reference parameters as {{name}}
+	outputFilePathPattern = `^/(?:[a-zA-Z0-9._/-]|\{\{[a-zA-Z][a-zA-Z0-9_]*\}\})+$`
+	// reportTypePattern is the allowed format of the Type tag of uploaded reports
+	reportTypePattern = `^[a-zA-Z0-9][a-zA-Z0-9_-]*$`
+	// labelFilterPattern is a comma separated list of kubernetes label values
+	labelFilterPattern = `^[a-zA-Z0-9]([a-zA-Z0-9_.-]*[a-zA-Z0-9])?(,[a-zA-Z0-9]([a-zA-Z0-9_.-]*[a-zA-Z0-9])?)*$`
+
+	clusterNameRegex    = regexp.MustCompile(clusterNamePattern)
+	k8sNameRegex        = regexp.MustCompile(k8sNamePattern)
+	pluginNameRegex     = regexp.MustCompile(pluginNamePattern)
+	pluginCommandRegex  = regexp.MustCompile(pluginCommandPattern)
+	labelFilterRegex    = regexp.MustCompile(labelFilterPattern)
+	parameterNameRegex  = regexp.MustCompile(parameterNamePattern)
+	outputFilePathRegex = regexp.MustCompile(outputFilePathPattern)
+	reportTypeRegex     = regexp.MustCompile(reportTypePattern)
+)
+
+// ConfigFieldError is a validation error of a single dynamic app config field.
+// Field is the json path of the invalid value, e.g. k8sPodExecPlugins[2].command
+type ConfigFieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Validate validates the dynamic app config and returns an error for each invalid field
+func (c *DynamicConfigJSONB) Validate() []ConfigFieldError {
+	errs := []ConfigFieldError{}
+	add := func(field, format string, args ...any) {
+		errs = append(errs, ConfigFieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if c.DefaultReplicaScaleLimit < 0 {
+		add("defaultReplicaScaleLimit", "must not be negative")
+	}
+
+	for name, limit := range c.ReplicaScaleLimits {
+		if strings.TrimSpace(name) == "" {
+			add("replicaScaleLimits", "deployment names must not be empty")
+		}
+		if limit < 0 {
+			add(fmt.Sprintf("replicaScaleLimits.%s", name), "must not be negative")
+		}
+	}
+
+	switch {
+	case c.K8sClusterName == "":
+		add("k8sClusterName", "must not be empty")
+	case len(c.K8sClusterName) > maxK8sNameLength || !clusterNameRegex.MatchString(c.K8sClusterName):
+		add("k8sClusterName", "must be at most %d alphanumeric characters, '-' or '.', and start and end with an alphanumeric character", maxK8sNameLength)
+	}
+
+	namespaces := map[string]bool{}
+	for i, ns := range c.K8sClusterNamespaces {
+		field := fmt.Sprintf("k8sClusterNamespaces[%d]", i)
+		if len(ns) > maxK8sNameLength || !k8sNameRegex.MatchString(ns) {
+			add(field, "%q is not a valid namespace name", ns)
+		}
+		if namespaces[ns] {
+			add(field, "namespace %q is listed more than once", ns)
+		}
+		namespaces[ns] = true
+	}
+
+	plugins := map[string]bool{}
+	for i, p := range c.K8sPodExecPlugins {
+		field := fmt.Sprintf("k8sPodExecPlugins[%d]", i)
+		if !pluginNameRegex.MatchString(p.Name) {
+			add(field+".name", "must be alphanumeric, '-' or '_', and start with an alphanumeric character")
+		}
+		if plugins[p.Name] {
+			add(field+".name", "plugin %q is declared more than once", p.Name)
+		}
+		plugins[p.Name] = true
+
+		switch {
+		case strings.TrimSpace(p.Command) == "":
+			add(field+".command", "must not be empty")
+		case len(p.Command) > maxPluginCommandLength:
+			add(field+".command", "must be at most %d characters", maxPluginCommandLength)
+		case !pluginCommandRegex.MatchString(p.Command):
+			add(field+".command", "must not contain shell metacharacters. Commands are executed without a shell")
+		}
+
+		// An empty container runs the command in the default container of the pod
+		if p.Container != "" && (len(p.Container) > maxK8sNameLength || !k8sNameRegex.MatchString(p.Container)) {
+			add(field+".container", "%q is not a valid container name", p.Container)
+		}
+		if !labelFilterRegex.MatchString(p.LabelFilter) {
+			add(field+".labelFilter", "must be a comma separated list of label values")
+		}
+		errs = append(errs, p.validateParameters(field)...)
+	}
+
+	queryPlugins := map[string]bool{}
+	for i, p := range c.MySQLQueryPlugins {
+		field := fmt.Sprintf("mySQLQueryPlugins[%d]", i)
+		if queryPlugins[p.Name] {
+			add(field+".name", "plugin %q is declared more than once", p.Name)
+		}
+		queryPlugins[p.Name] = true
+		errs = append(errs, p.validate(field)...)
+	}
+
+	errs = append(errs, c.MySQLDiscovery.validate("mySQLDiscovery")...)
+	errs = append(errs, validateMySQLConsumerRules("mySQLConsumerRules", c.MySQLConsumerRules)...)
+	errs = append(errs, c.ReplicationAlerting.validate("replicationAlerting")...)
+
+	return errs
+}
+
+// IsValid validates the dynamic app config
+func (c *DynamicConfigJSONB) IsValid() (bool, string) {
+	errors := strings.Builder{}
+	for _, e := range c.Validate() {
+		errors.WriteString(fmt.Sprintf("%s %s.\n", e.Field, e.Message))
+	}
+
+	errMsg := errors.String()
+	if len(errMsg) > 0 {
+		return false, errMsg
+	}
+	return true, ""
+}
+
+// DynamicAppConfigSchema returns the json schema (draft 2020-12) of the dynamic app config data.
+// It mirrors the rules enforced by Validate, except for uniqueness of plugin names which json schema cannot express, and
+// the read only statement rules of query plugins.
+func DynamicAppConfigSchema() map[string]any {
+	scaleLimit := map[string]any{"type": "integer", "minimum": 0}
+	k8sName := map[string]any{"type": "string", "maxLength": maxK8sNameLength, "pattern": k8sNamePattern}
+
+	return map[string]any{
+		"$schema":              "https://json-schema.org/draft/2020-12/schema",
+		"$id":                  "/api/appconfig/schema",
+		"title":                "Dynamic App Config",
+		"type":                 "object",
+		"additionalProperties": false,
+		"required":             []string{"k8sClusterName"},
+		"properties": map[string]any{
+			"defaultReplicaScaleLimit": withDescription(scaleLimit, "The highest replica count deployments may be scaled to, unless overridden in replicaScaleLimits"),
+			"replicaScaleLimits": map[string]any{
+				"type":                 []string{"object", "null"},
+				"description":          "Per deployment overrides of the default replica scale limit",
+				"propertyNames":        map[string]any{"minLength": 1},
+				"additionalProperties": scaleLimit,
+			},
+			"enableK8sGlobalReadOnly": map[string]any{
+				"type":        "boolean",
+				"description": "Grants read access to all kubernetes resources to users without any group",
+			},
+			"k8sClusterName": map[string]any{
+				"type":        "string",
+				"description": "The name of the kubernetes cluster. It is used to key the cached cluster data",
+				"minLength":   1,
+				"maxLength":   maxK8sNameLength,
+				"pattern":     clusterNamePattern,
+			},
+			"k8sClusterNamespaces": map[string]any{
+				"type":        []string{"array", "null"},
+				"description": "The namespaces khub watches. All namespaces are watched when empty",
+				"uniqueItems": true,
+				"items":       k8sName,
+			},
+			"k8sPodExecPlugins": map[string]any{
+				"type":        []string{"array", "null"},
+				"description": "Commands users with write access can run in pod containers. Plugin names must be unique",
+				"items": map[string]any{
+					"type":                 "object",
+					"additionalProperties": false,
+					"required":             []string{"name", "command", "labelFilter"},
+					"properties": map[string]any{
+						"name":    map[string]any{"type": "string", "pattern": pluginNamePattern},
+						"enabled": map[string]any{"type": "boolean"},
+						"command": map[string]any{
+							"type":        "string",
+							"description": "The command to run. It is split on spaces and executed without a shell",
+							"maxLength":   maxPluginCommandLength,
+							"pattern":     pluginCommandPattern,
+						},
+						"container": map[string]any{
+							"type":        "string",
+							"description": "The name of the container the command runs in. The default container of the pod is used when empty",
+							"maxLength":   maxK8sNameLength,
+							"pattern":     "^$|" + k8sNamePattern,
+						},
+						"labelFilter": map[string]any{
+							"type":        "string",
+							"description": "A comma separated list of label values. The plugin is offered for pods with any of these label values",
+							"pattern":     labelFilterPattern,
+						},
+						"timeoutSeconds": map[string]any{
+							"type":        "integer",
+							"description": fmt.Sprintf("How long the command may run for. Defaults to %d seconds, and is at most %d seconds for plugins that are not async", DefaultExecPluginTimeoutSeconds, MaxSyncExecPluginTimeoutSeconds),
+							"minimum":     0,
+							"maximum":     MaxExecPluginTimeoutSeconds,
+						},
+						"async": map[string]any{
+							"type":        "boolean",
+							"description": "Run the command as a background job and stream its output",
+						},
+						"outputFile": map[string]any{
+							"type":                 []string{"object", "null"},
+							"description":          "A file written by the command, which is uploaded to the reports bucket once the command succeeds",
+							"additionalProperties": false,
+							"required":             []string{"path"},
+							"properties": map[string]any{
+								"path": map[string]any{
+									"type":        "string",
+									"description": "The absolute path of the file in the container. It may reference parameters as {{name}}",
+									"maxLength":   maxPluginCommandLength,
+									"pattern":     outputFilePathPattern,
+								},
+								"type": map[string]any{
+									"type":        "string",
+									"description": fmt.Sprintf("The Type tag of the uploaded report. Defaults to %s", DefaultExecPluginOutputType),
+									"maxLength":   maxK8sNameLength,
+									"pattern":     reportTypePattern,
+								},
+							},
+						},
+						"parameters": parametersSchema("Typed arguments referenced by the command as {{name}}. Parameter names must be unique"),
+					},
+				},
+			},
+			"mySQLQueryPlugins": map[string]any{
+				"type":        []string{"array", "null"},
+				"description": "Read only queries users holding the mysql_query or mysql_query_<name> permission tag can run against MySQL catalog hosts. Plugin names must be unique",
+				"items": map[string]any{
+					"type":                 "object",
+					"additionalProperties": false,
+					"required":             []string{"name", "query"},
+					"properties": map[string]any{
+						"name":        map[string]any{"type": "string", "pattern": pluginNamePattern},
+						"description": map[string]any{"type": "string"},
+						"query": map[string]any{
+							"type":        "string",
+							"description": "A single SELECT, SHOW, EXPLAIN, DESCRIBE or WITH statement. Parameters are referenced as {{name}}, unquoted, and bound as arguments",
+							"minLength":   1,
+							"maxLength":   maxMySQLQueryLength,
+						},
+						"hosts": map[string]any{
+							"type":        []string{"array", "null"},
+							"description": "The shortnames of the catalog hosts the query may run against. It may run against every host when empty",
+							"items":       map[string]any{"type": "string", "minLength": 1},
+						},
+						"maxRows": map[string]any{
+							"type":        "integer",
+							"description": fmt.Sprintf("The most rows returned. Defaults to %d", DefaultMySQLQueryPluginMaxRows),
+							"minimum":     0,
+							"maximum":     MaxMySQLQueryPluginMaxRows,
+						},
+						"timeoutSeconds": map[string]any{
+							"type":        "integer",
+							"description": fmt.Sprintf("How long the query may run for. Defaults to %d seconds", DefaultMySQLQueryPluginTimeoutSeconds),
+							"minimum":     0,
+							"maximum":     MaxMySQLQueryPluginTimeoutSeconds,
+						},
+						"parameters": parametersSchema("Typed arguments referenced by the query as {{name}}. Parameter names must be unique"),
+					},
+				},
+			},
+			"mySQLDiscovery": map[string]any{
+				"type":                 "object",
+				"description":          "The sources the data sink discovers MySQL hosts from. Discovered hosts are proposed to admins before they are added to the catalog",
+				"additionalProperties": false,
+				"properties": map[string]any{
+					"labels": map[string]any{
+						"type":                 []string{"object", "null"},
+						"description":          "The labels of the Services and StatefulSets running MySQL. Kubernetes discovery is disabled when empty",
+						"propertyNames":        map[string]any{"minLength": 1, "maxLength": 316},
+						"additionalProperties": map[string]any{"type": "string", "maxLength": maxK8sNameLength},
+					},
+					"rds": map[string]any{
+						"type":        "boolean",
+						"description": "Discover the MySQL and Aurora MySQL instances of the AWS account",
+					},
+					"username": map[string]any{
+						"type":        "string",
+						"description": "The username proposed when accepting a discovered host",
+					},
+				},
+			},
+			"mySQLConsumerRules": map[string]any{
+				"type":        []string{"array", "null"},
+				"description": "Classify the connections reading the binlog of MySQL catalog hosts. The first matching rule applies, consumers no rule matches are DMS tasks, unless they connect from a catalog host or their user is repl or rdsrepladmin. Rule names must be unique",
+				"items": map[string]any{
+					"type":                 "object",
+					"additionalProperties": false,
+					"required":             []string{"name", "type"},
+					"properties": map[string]any{
+						"name": map[string]any{"type": "string", "pattern": pluginNamePattern},
+						"user": map[string]any{"type": "string", "format": "regex", "description": "The regular expression the user of the connection must fully match. Any user matches when empty"},
+						"host": map[string]any{"type": "string", "format": "regex", "description": "The regular expression the client host of the connection must fully match. Any host matches when empty"},
+						"sources": map[string]any{
+							"type":        []string{"array", "null"},
+							"description": "The shortnames of the catalog hosts the rule applies to. It applies to every host when empty",
+							"items":       map[string]any{"type": "string", "minLength": 1},
+						},
+						"type": map[string]any{
+							"enum":        mySQLConsumerTypes,
+							"description": "replica consumers are shown through the replication channels of catalog hosts, dms and cdc consumers are shown as nodes, and ignore consumers are not shown",
+						},
+					},
+				},
+			},
+			"replicationAlerting": map[string]any{
+				"type":                 "object",
+				"description":          "The alerts evaluated after each capture of the replication topologies, and the webhooks they are sent to. Rule and receiver names must be unique",
+				"additionalProperties": false,
+				"properties": map[string]any{
+					"rules": map[string]any{
+						"type": []string{"array", "null"},
+						"items": map[string]any{
+							"type":                 "object",
+							"additionalProperties": false,
+							"required":             []string{"name", "kind"},
+							"properties": map[string]any{
+								"name":     map[string]any{"type": "string", "pattern": pluginNamePattern},
+								"kind":     map[string]any{"enum": []string{ReplAlertReplicationStopped, ReplAlertLag, ReplAlertSourceChanged, ReplAlertHostUnreachable}},
+								"topology": map[string]any{"enum": []string{"", ReplTopologyMySQL, ReplTopologyPostgres}, "description": "The topology the rule applies to. It applies to both when empty"},
+								"hosts": map[string]any{
+									"type":        []string{"array", "null"},
+									"description": "The shortnames of the hosts the rule applies to. It applies to every host when empty",
+									"items":       map[string]any{"type": "string", "minLength": 1},
+								},
+								"lagSeconds": map[string]any{"type": "integer", "minimum": 0, "description": "The seconds behind source lag rules fire above"},
+								"lagBytes":   map[string]any{"type": "integer", "minimum": 0, "description": "The bytes of WAL behind source lag rules fire above. Postgres only"},
+								"receivers": map[string]any{
+									"type":        []string{"array", "null"},
+									"description": "The names of the receivers notified. Every receiver is notified when empty",
+									"items":       map[string]any{"type": "string"},
+								},
+							},
+						},
+					},
+					"receivers": map[string]any{
+						"type": []string{"array", "null"},
+						"items": map[string]any{
+							"type":                 "object",
+							"additionalProperties": false,
+							"required":             []string{"name", "type", "url"},
+							"properties": map[string]any{
+								"name": map[string]any{"type": "string", "pattern": pluginNamePattern},
+								"type": map[string]any{"enum": []string{ReplAlertReceiverSlack, ReplAlertReceiverWebhook}, "description": "slack posts Slack compatible messages, webhook posts the alert as json"},
+								"url":  map[string]any{"type": "string", "format": "uri", "pattern": "^https?://"},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// parametersSchema is the json schema of typed plugin parameters
+func parametersSchema(description string) map[string]any {
+	return map[string]any{
+		"type":        []string{"array", "null"},
+		"description": description,
+		"items": map[string]any{
+			"type":                 "object",
+			"additionalProperties": false,
+			"required":             []string{"name", "type"},
+			"properties": map[string]any{
+				"name":        map[string]any{"type": "string", "pattern": parameterNamePattern},
+				"type":        map[string]any{"enum": []string{ExecPluginParameterInt, ExecPluginParameterEnum, ExecPluginParameterString}},
+				"description": map[string]any{"type": "string"},
+				"default":     map[string]any{"type": "string"},
+				"min":         map[string]any{"type": "integer", "description": "The minimum value of an int parameter"},
+				"max":         map[string]any{"type": "integer", "description": "The maximum value of an int parameter"},
+				"values":      map[string]any{"type": "array", "items": map[string]any{"type": "string"}, "description": "The allowed values of an enum parameter"},
+				"pattern":     map[string]any{"type": "string", "format": "regex", "description": "The regular expression string arguments must fully match"},
+			},
+		},
+	}
+}
+
+func withDescription(schema map[string]any, description string) map[string]any {
+	s := map[string]any{"description": description}
+	for k, v := range schema {
+		s[k] = v
+	}
+	return s
+}
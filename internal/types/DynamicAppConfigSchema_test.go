@@ -0,0 +1,91 @@
+package types
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func validDynamicConfig() DynamicConfigJSONB {
+	return DynamicConfigJSONB{
+		DefaultReplicaScaleLimit: 100,
+		ReplicaScaleLimits:       map[string]int{"checkout": 10},
+		K8sClusterName:           "prod-us-east-1",
+		K8sClusterNamespaces:     []string{"default", "payments"},
+		K8sPodExecPlugins: []K8sPodExecPlugin{
+			{Name: "thread-dump", Command: "jcmd 1 Thread.print", Container: "app", LabelFilter: "checkout,search"},
+		},
+	}
+}
+
+func TestDynamicConfigValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(c *DynamicConfigJSONB)
+		fields []string
+	}{
+		{name: "valid", modify: func(c *DynamicConfigJSONB) {}},
+		{name: "negative default scale limit", modify: func(c *DynamicConfigJSONB) { c.DefaultReplicaScaleLimit = -1 }, fields: []string{"defaultReplicaScaleLimit"}},
+		{name: "negative scale limit", modify: func(c *DynamicConfigJSONB) { c.ReplicaScaleLimits["checkout"] = -5 }, fields: []string{"replicaScaleLimits.checkout"}},
+		{name: "empty cluster name", modify: func(c *DynamicConfigJSONB) { c.K8sClusterName = "" }, fields: []string{"k8sClusterName"}},
+		{name: "cluster name with key separator", modify: func(c *DynamicConfigJSONB) { c.K8sClusterName = "prod:us" }, fields: []string{"k8sClusterName"}},
+		{name: "duplicate namespace", modify: func(c *DynamicConfigJSONB) { c.K8sClusterNamespaces = []string{"default", "default"} }, fields: []string{"k8sClusterNamespaces[1]"}},
+		{name: "invalid namespace", modify: func(c *DynamicConfigJSONB) { c.K8sClusterNamespaces = []string{"Payments"} }, fields: []string{"k8sClusterNamespaces[0]"}},
+		{
+			name: "duplicate plugin",
+			modify: func(c *DynamicConfigJSONB) {
+				c.K8sPodExecPlugins = append(c.K8sPodExecPlugins, c.K8sPodExecPlugins[0])
+			},
+			fields: []string{"k8sPodExecPlugins[1].name"},
+		},
+		{name: "shell metacharacters", modify: func(c *DynamicConfigJSONB) { c.K8sPodExecPlugins[0].Command = "ls; rm -rf /" }, fields: []string{"k8sPodExecPlugins[0].command"}},
+		{name: "command substitution", modify: func(c *DynamicConfigJSONB) { c.K8sPodExecPlugins[0].Command = "echo $(id)" }, fields: []string{"k8sPodExecPlugins[0].command"}},
+		{name: "default container", modify: func(c *DynamicConfigJSONB) { c.K8sPodExecPlugins[0].Container = "" }},
+		{
+			name: "invalid container and label filter",
+			modify: func(c *DynamicConfigJSONB) {
+				c.K8sPodExecPlugins[0].Container = "App"
+				c.K8sPodExecPlugins[0].LabelFilter = "checkout,"
+			},
+			fields: []string{"k8sPodExecPlugins[0].container", "k8sPodExecPlugins[0].labelFilter"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := validDynamicConfig()
+			tt.modify(&c)
+
+			fields := []string{}
+			for _, e := range c.Validate() {
+				fields = append(fields, e.Field)
+			}
+			if tt.fields == nil {
+				assert.Empty(t, fields)
+			} else {
+				assert.Equal(t, tt.fields, fields)
+			}
+		})
+	}
+}
+
+func TestDynamicAppConfigSchema(t *testing.T) {
+	b, err := json.Marshal(DynamicAppConfigSchema())
+	require.NoError(t, err)
+
+	// Every config field is described by the schema
+	schema := struct {
+		Properties map[string]any `json:"properties"`
+	}{}
+	require.NoError(t, json.Unmarshal(b, &schema))
+
+	fields := map[string]any{}
+	b, err = json.Marshal(validDynamicConfig())
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(b, &fields))
+	for k := range fields {
+		assert.Contains(t, schema.Properties, k)
+	}
+}
@@ -0,0 +1,304 @@
+package types
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Exec plugin parameter types
+const (
+	ExecPluginParameterInt    = "int"
+	ExecPluginParameterEnum   = "enum"
+	ExecPluginParameterString = "string"
+)
+
+const (
+	// DefaultExecPluginTimeoutSeconds is the timeout of exec plugins that do not configure one
+	DefaultExecPluginTimeoutSeconds = 10
+	// MaxExecPluginTimeoutSeconds is the highest timeout an exec plugin may configure
+	MaxExecPluginTimeoutSeconds = 3600
+	// MaxSyncExecPluginTimeoutSeconds is the highest timeout of plugins that are not async, which hold their request open
+	// until the command exits
+	MaxSyncExecPluginTimeoutSeconds = 60
+	// maxExecPluginArgumentLength is the maximum length of a string argument
+	maxExecPluginArgumentLength = 256
+	// DefaultExecPluginOutputType is the Type tag of exec plugin artifacts that do not configure one
+	DefaultExecPluginOutputType = "diagnostic"
+)
+
+// parameterPlaceholderRegex matches the {{name}} references of a plugin command
+var parameterPlaceholderRegex = regexp.MustCompile(`\{\{([a-zA-Z][a-zA-Z0-9_]*)\}\}`)
+
+// ExecPluginParameter is a typed argument of an exec plugin.
+// Int parameters may be bounded by Min and Max, enum parameters must be one of Values,
+// and string parameters must fully match Pattern.
+type ExecPluginParameter struct {
+	Name        string   `json:"name"`
+	Type        string   `json:"type"`
+	Description string   `json:"description,omitempty"`
+	Default     string   `json:"default,omitempty"`
+	Min         *int     `json:"min,omitempty"`
+	Max         *int     `json:"max,omitempty"`
+	Values      []string `json:"values,omitempty"`
+	Pattern     string   `json:"pattern,omitempty"`
+}
+
+// ExecPluginOutputFile is a file written by an exec plugin command, which khub copies out of the container and uploads
+// to the reports bucket. Path is absolute and may reference plugin parameters as {{name}}. Type is recorded as the Type tag
+// of the uploaded report.
+type ExecPluginOutputFile struct {
+	Path string `json:"path"`
+	Type string `json:"type,omitempty"`
+}
+
+// ReportType returns the Type tag of the uploaded output file
+func (f *ExecPluginOutputFile) ReportType() string {
+	if f.Type == "" {
+		return DefaultExecPluginOutputType
+	}
+	return f.Type
+}
+
+// ExecJob statuses
+const (
+	ExecJobRunning   = "running"
+	ExecJobSucceeded = "succeeded"
+	ExecJobFailed    = "failed"
+	ExecJobTimedOut  = "timed_out"
+)
+
+// ExecJob is an exec plugin running in the background.
+// Output is only populated when the job is fetched, it is stored separately while the job runs.
+type ExecJob struct {
+	ID         string     `json:"id"`
+	Plugin     string     `json:"plugin"`
+	Namespace  string     `json:"namespace"`
+	Pod        string     `json:"pod"`
+	Container  string     `json:"container"`
+	Command    []string   `json:"command"`
+	Status     string     `json:"status"`
+	Error      string     `json:"error,omitempty"`
+	Output     string     `json:"output,omitempty"`
+	StartedBy  string     `json:"startedBy"`
+	StartedAt  time.Time  `json:"startedAt"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+	// OutputFile is the resolved path of the plugin output file, uploaded with ArtifactTags once the command succeeds.
+	// Artifact is the name of the uploaded report.
+	OutputFile   string      `json:"outputFile,omitempty"`
+	ArtifactTags *ReportTags `json:"artifactTags,omitempty"`
+	Artifact     string      `json:"artifact,omitempty"`
+}
+
+// Done reports whether the job has finished
+func (j ExecJob) Done() bool {
+	return j.Status != ExecJobRunning
+}
+
+// Timeout returns the configured timeout of the plugin
+func (p *K8sPodExecPlugin) Timeout() time.Duration {
+	if p.TimeoutSeconds <= 0 {
+		return DefaultExecPluginTimeoutSeconds * time.Second
+	}
+	return time.Duration(p.TimeoutSeconds) * time.Second
+}
+
+// BuildCommand returns the argv of the plugin command with its parameters substituted.
+// The command is split on whitespace first, so an argument always stays within a single argv element
+// and is never interpreted by a shell. Missing arguments use the parameter default.
+func (p *K8sPodExecPlugin) BuildCommand(args map[string]string) ([]string, error) {
+	values, err := p.resolveArguments(args)
+	if err != nil {
+		return nil, err
+	}
+
+	argv := []string{}
+	for _, token := range strings.Fields(p.Command) {
+		argv = append(argv, substituteParameters(token, values))
+	}
+	return argv, nil
+}
+
+// BuildOutputPath returns the path of the plugin output file with its parameters substituted.
+// The resolved path must still be absolute and clean, so arguments cannot add '..' elements to it.
+func (p *K8sPodExecPlugin) BuildOutputPath(args map[string]string) (string, error) {
+	if p.OutputFile == nil {
+		return "", fmt.Errorf("plugin %s has no output file", p.Name)
+	}
+
+	values, err := p.resolveArguments(args)
+	if err != nil {
+		return "", err
+	}
+
+	filePath := substituteParameters(p.OutputFile.Path, values)
+	if !path.IsAbs(filePath) || path.Clean(filePath) != filePath || filePath == "/" {
+		return "", fmt.Errorf("the output file path of plugin %s resolves to an invalid path %s", p.Name, filePath)
+	}
+	return filePath, nil
+}
+
+// resolveArguments validates the arguments of the plugin parameters and fills in defaults
+func (p *K8sPodExecPlugin) resolveArguments(args map[string]string) (map[string]string, error) {
+	parameters := map[string]ExecPluginParameter{}
+	for _, param := range p.Parameters {
+		parameters[param.Name] = param
+	}
+
+	for name := range args {
+		if _, ok := parameters[name]; !ok {
+			return nil, fmt.Errorf("plugin %s has no parameter named %s", p.Name, name)
+		}
+	}
+
+	values := map[string]string{}
+	for _, param := range p.Parameters {
+		value, ok := args[param.Name]
+		if !ok || value == "" {
+			value = param.Default
+		}
+		if value == "" {
+			return nil, fmt.Errorf("parameter %s is required", param.Name)
+		}
+		if err := param.validateArgument(value); err != nil {
+			return nil, err
+		}
+		values[param.Name] = value
+	}
+	return values, nil
+}
+
+// substituteParameters replaces the {{name}} references of s with their values
+func substituteParameters(s string, values map[string]string) string {
+	return parameterPlaceholderRegex.ReplaceAllStringFunc(s, func(placeholder string) string {
+		return values[parameterPlaceholderRegex.FindStringSubmatch(placeholder)[1]]
+	})
+}
+
+// validateArgument checks an argument against the parameter type
+func (param *ExecPluginParameter) validateArgument(value string) error {
+	switch param.Type {
+	case ExecPluginParameterInt:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("parameter %s must be an integer", param.Name)
+		}
+		if param.Min != nil && n < *param.Min {
+			return fmt.Errorf("parameter %s must be at least %d", param.Name, *param.Min)
+		}
+		if param.Max != nil && n > *param.Max {
+			return fmt.Errorf("parameter %s must be at most %d", param.Name, *param.Max)
+		}
+	case ExecPluginParameterEnum:
+		if !slices.Contains(param.Values, value) {
+			return fmt.Errorf("parameter %s must be one of %s", param.Name, strings.Join(param.Values, ", "))
+		}
+	case ExecPluginParameterString:
+		if len(value) > maxExecPluginArgumentLength {
+			return fmt.Errorf("parameter %s must be at most %d characters", param.Name, maxExecPluginArgumentLength)
+		}
+		pattern, err := regexp.Compile(fmt.Sprintf("^(?:%s)$", param.Pattern))
+		if err != nil {
+			return fmt.Errorf("parameter %s has an invalid pattern", param.Name)
+		}
+		if !pattern.MatchString(value) {
+			return fmt.Errorf("parameter %s must match %s", param.Name, param.Pattern)
+		}
+	default:
+		return fmt.Errorf("parameter %s has an unknown type %s", param.Name, param.Type)
+	}
+	return nil
+}
+
+// validateParameters returns the validation errors of the plugin parameters and output file, keyed by the field they apply to
+func (p *K8sPodExecPlugin) validateParameters(field string) []ConfigFieldError {
+	errs := []ConfigFieldError{}
+	add := func(field, format string, args ...any) {
+		errs = append(errs, ConfigFieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if p.TimeoutSeconds < 0 || p.TimeoutSeconds > MaxExecPluginTimeoutSeconds {
+		add(field+".timeoutSeconds", "must be between 0 and %d", MaxExecPluginTimeoutSeconds)
+	} else if !p.Async && p.TimeoutSeconds > MaxSyncExecPluginTimeoutSeconds {
+		add(field+".timeoutSeconds", "must be at most %d for plugins that are not async", MaxSyncExecPluginTimeoutSeconds)
+	}
+
+	declared := map[string]bool{}
+	for i, param := range p.Parameters {
+		paramField := fmt.Sprintf("%s.parameters[%d]", field, i)
+		if !parameterNameRegex.MatchString(param.Name) {
+			add(paramField+".name", "must be alphanumeric or '_', and start with a letter")
+		}
+		if declared[param.Name] {
+			add(paramField+".name", "parameter %q is declared more than once", param.Name)
+		}
+		declared[param.Name] = true
+
+		switch param.Type {
+		case ExecPluginParameterInt:
+			if param.Min != nil && param.Max != nil && *param.Min > *param.Max {
+				add(paramField+".min", "must not be greater than max")
+			}
+		case ExecPluginParameterEnum:
+			if len(param.Values) == 0 {
+				add(paramField+".values", "enum parameters must list their values")
+			}
+			for _, v := range param.Values {
+				if v == "" || !pluginCommandRegex.MatchString(v) {
+					add(paramField+".values", "%q must not be empty or contain shell metacharacters", v)
+				}
+			}
+		case ExecPluginParameterString:
+			if param.Pattern == "" {
+				add(paramField+".pattern", "string parameters must declare a pattern")
+			} else if _, err := regexp.Compile(param.Pattern); err != nil {
+				add(paramField+".pattern", "is not a valid regular expression: %s", err.Error())
+			}
+		default:
+			add(paramField+".type", "must be one of %s, %s or %s", ExecPluginParameterInt, ExecPluginParameterEnum, ExecPluginParameterString)
+		}
+
+		if param.Default != "" && (param.Type == ExecPluginParameterInt || param.Type == ExecPluginParameterEnum || param.Type == ExecPluginParameterString) {
+			if err := param.validateArgument(param.Default); err != nil {
+				add(paramField+".default", "%s", err.Error())
+			}
+		}
+	}
+
+	used := map[string]bool{}
+	for _, m := range parameterPlaceholderRegex.FindAllStringSubmatch(p.Command, -1) {
+		used[m[1]] = true
+		if !declared[m[1]] {
+			add(field+".command", "references undeclared parameter %q", m[1])
+		}
+	}
+
+	if p.OutputFile != nil {
+		outputField := field + ".outputFile"
+		switch {
+		case len(p.OutputFile.Path) > maxPluginCommandLength || !outputFilePathRegex.MatchString(p.OutputFile.Path):
+			add(outputField+".path", "must be an absolute path of alphanumeric characters, '.', '-', '_' or '/'")
+		case slices.Contains(strings.Split(p.OutputFile.Path, "/"), ".."):
+			add(outputField+".path", "must not contain '..'")
+		}
+		for _, m := range parameterPlaceholderRegex.FindAllStringSubmatch(p.OutputFile.Path, -1) {
+			used[m[1]] = true
+			if !declared[m[1]] {
+				add(outputField+".path", "references undeclared parameter %q", m[1])
+			}
+		}
+		if p.OutputFile.Type != "" && (len(p.OutputFile.Type) > maxK8sNameLength || !reportTypeRegex.MatchString(p.OutputFile.Type)) {
+			add(outputField+".type", "must be at most %d alphanumeric characters, '-' or '_'", maxK8sNameLength)
+		}
+	}
+	for i, param := range p.Parameters {
+		if param.Name != "" && !used[param.Name] {
+			add(fmt.Sprintf("%s.parameters[%d].name", field, i), "parameter %q is not used by the command or output file", param.Name)
+		}
+	}
+	return errs
+}
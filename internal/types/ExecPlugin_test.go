@@ -0,0 +1,175 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func intPtr(i int) *int { return &i }
+
+func threadDumpPlugin() K8sPodExecPlugin {
+	return K8sPodExecPlugin{
+		Name:        "thread-dump",
+		Command:     "jcmd {{pid}} Thread.print -format={{format}}",
+		Container:   "app",
+		LabelFilter: "checkout",
+		Parameters: []ExecPluginParameter{
+			{Name: "pid", Type: ExecPluginParameterInt, Min: intPtr(1), Max: intPtr(65535), Default: "1"},
+			{Name: "format", Type: ExecPluginParameterEnum, Values: []string{"text", "json"}},
+		},
+	}
+}
+
+func TestBuildCommand(t *testing.T) {
+	plugin := threadDumpPlugin()
+
+	argv, err := plugin.BuildCommand(map[string]string{"pid": "42", "format": "json"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"jcmd", "42", "Thread.print", "-format=json"}, argv)
+
+	argv, err = plugin.BuildCommand(map[string]string{"format": "text"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"jcmd", "1", "Thread.print", "-format=text"}, argv)
+
+	tests := []struct {
+		name string
+		args map[string]string
+		err  string
+	}{
+		{name: "missing required", args: map[string]string{}, err: "parameter format is required"},
+		{name: "not an int", args: map[string]string{"pid": "1; rm -rf /", "format": "text"}, err: "parameter pid must be an integer"},
+		{name: "out of range", args: map[string]string{"pid": "0", "format": "text"}, err: "parameter pid must be at least 1"},
+		{name: "not an enum value", args: map[string]string{"format": "xml"}, err: "parameter format must be one of text, json"},
+		{name: "unknown parameter", args: map[string]string{"format": "text", "extra": "1"}, err: "plugin thread-dump has no parameter named extra"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := plugin.BuildCommand(tt.args)
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+}
+
+func TestBuildCommandKeepsStringArgumentsInOneElement(t *testing.T) {
+	plugin := K8sPodExecPlugin{
+		Name:    "flush",
+		Command: "cache-cli flush --prefix {{prefix}}",
+		Parameters: []ExecPluginParameter{
+			{Name: "prefix", Type: ExecPluginParameterString, Pattern: `[a-z: ]+`},
+		},
+	}
+
+	argv, err := plugin.BuildCommand(map[string]string{"prefix": "user: sessions"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"cache-cli", "flush", "--prefix", "user: sessions"}, argv)
+
+	// The pattern must match the whole argument
+	_, err = plugin.BuildCommand(map[string]string{"prefix": "user$(id)"})
+	assert.EqualError(t, err, "parameter prefix must match [a-z: ]+")
+}
+
+func TestValidateExecPluginParameters(t *testing.T) {
+	c := validDynamicConfig()
+	c.K8sPodExecPlugins = []K8sPodExecPlugin{threadDumpPlugin()}
+	assert.Empty(t, c.Validate())
+
+	// Long running plugins must run as jobs
+	plugin := threadDumpPlugin()
+	plugin.TimeoutSeconds = MaxSyncExecPluginTimeoutSeconds + 1
+	c.K8sPodExecPlugins = []K8sPodExecPlugin{plugin}
+	assert.Equal(t, "k8sPodExecPlugins[0].timeoutSeconds", c.Validate()[0].Field)
+	plugin.Async = true
+	c.K8sPodExecPlugins = []K8sPodExecPlugin{plugin}
+	assert.Empty(t, c.Validate())
+
+	plugin = threadDumpPlugin()
+	plugin.Command = "jcmd {{pid}} {{missing}}"
+	plugin.TimeoutSeconds = MaxExecPluginTimeoutSeconds + 1
+	plugin.Parameters = append(plugin.Parameters,
+		ExecPluginParameter{Name: "bad", Type: ExecPluginParameterString, Pattern: "("},
+		ExecPluginParameter{Name: "pid", Type: "float"},
+	)
+	c.K8sPodExecPlugins = []K8sPodExecPlugin{plugin}
+
+	fields := []string{}
+	for _, e := range c.Validate() {
+		fields = append(fields, e.Field)
+	}
+	assert.Equal(t, []string{
+		"k8sPodExecPlugins[0].timeoutSeconds",
+		"k8sPodExecPlugins[0].parameters[2].pattern",
+		"k8sPodExecPlugins[0].parameters[3].name",
+		"k8sPodExecPlugins[0].parameters[3].type",
+		"k8sPodExecPlugins[0].command",
+		"k8sPodExecPlugins[0].parameters[1].name",
+		"k8sPodExecPlugins[0].parameters[2].name",
+	}, fields)
+}
+
+func heapDumpPlugin() K8sPodExecPlugin {
+	return K8sPodExecPlugin{
+		Name:        "heap-dump",
+		Command:     "jcmd 1 GC.heap_dump /tmp/{{name}}.hprof",
+		Container:   "app",
+		LabelFilter: "checkout",
+		OutputFile:  &ExecPluginOutputFile{Path: "/tmp/{{name}}.hprof", Type: "heapdump"},
+		Parameters: []ExecPluginParameter{
+			{Name: "name", Type: ExecPluginParameterString, Pattern: `[a-z0-9./-]+`, Default: "heap"},
+		},
+	}
+}
+
+func TestBuildOutputPath(t *testing.T) {
+	plugin := heapDumpPlugin()
+
+	filePath, err := plugin.BuildOutputPath(map[string]string{"name": "checkout-1"})
+	require.NoError(t, err)
+	assert.Equal(t, "/tmp/checkout-1.hprof", filePath)
+
+	filePath, err = plugin.BuildOutputPath(nil)
+	require.NoError(t, err)
+	assert.Equal(t, "/tmp/heap.hprof", filePath)
+
+	_, err = plugin.BuildOutputPath(map[string]string{"name": "../etc/shadow"})
+	assert.EqualError(t, err, "the output file path of plugin heap-dump resolves to an invalid path /tmp/../etc/shadow.hprof")
+
+	plugin.OutputFile = nil
+	_, err = plugin.BuildOutputPath(nil)
+	assert.EqualError(t, err, "plugin heap-dump has no output file")
+}
+
+func TestValidateExecPluginOutputFile(t *testing.T) {
+	c := validDynamicConfig()
+	c.K8sPodExecPlugins = []K8sPodExecPlugin{heapDumpPlugin()}
+	assert.Empty(t, c.Validate())
+
+	// Parameters used only by the output file path are not reported as unused
+	plugin := heapDumpPlugin()
+	plugin.Command = "jcmd 1 GC.heap_dump /tmp/heap.hprof"
+	c.K8sPodExecPlugins = []K8sPodExecPlugin{plugin}
+	assert.Empty(t, c.Validate())
+
+	tests := []struct {
+		name       string
+		outputFile ExecPluginOutputFile
+		field      string
+	}{
+		{name: "relative path", outputFile: ExecPluginOutputFile{Path: "tmp/heap.hprof"}, field: "k8sPodExecPlugins[0].outputFile.path"},
+		{name: "parent directory", outputFile: ExecPluginOutputFile{Path: "/tmp/../etc/shadow"}, field: "k8sPodExecPlugins[0].outputFile.path"},
+		{name: "undeclared parameter", outputFile: ExecPluginOutputFile{Path: "/tmp/{{name}}-{{pid}}.hprof"}, field: "k8sPodExecPlugins[0].outputFile.path"},
+		{name: "invalid type", outputFile: ExecPluginOutputFile{Path: "/tmp/{{name}}.hprof", Type: "heap dump"}, field: "k8sPodExecPlugins[0].outputFile.type"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := heapDumpPlugin()
+			plugin.OutputFile = &tt.outputFile
+			c.K8sPodExecPlugins = []K8sPodExecPlugin{plugin}
+
+			errs := c.Validate()
+			require.Len(t, errs, 1)
+			assert.Equal(t, tt.field, errs[0].Field)
+		})
+	}
+}
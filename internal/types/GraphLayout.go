@@ -0,0 +1,358 @@
+package types
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+)
+
+// Graphs node positions are computed and overridden for
+const (
+	GraphMySQLReplication    = "mysql-replication"
+	GraphPostgresReplication = "postgres-replication"
+)
+
+// Spacing of the layered graph layout, in pixels. Layers are stacked top to bottom, and fit the nodes drawn by the
+// client.
+const (
+	GraphLayoutNodeSpacing  = 320
+	GraphLayoutLayerSpacing = 260
+)
+
+// graphLayoutSweeps is how many times node orders are swept to reduce edge crossings
+const graphLayoutSweeps = 8
+
+// GraphLink is a directed edge of a graph being laid out
+type GraphLink struct {
+	Source string
+	Target string
+}
+
+// GraphLayoutPosition is the top left corner of a node of a laid out graph
+type GraphLayoutPosition struct {
+	X int
+	Y int
+}
+
+// GraphNodePosition is a position an admin pinned a node of a graph at, which overrides the computed layout
+type GraphNodePosition struct {
+	Graph     string    `json:"graph" gorm:"primaryKey"`
+	NodeID    string    `json:"nodeId" gorm:"primaryKey"`
+	X         int       `json:"x"`
+	Y         int       `json:"y"`
+	UpdatedBy string    `json:"updatedBy"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// GraphNodePositionRequest represents the request body used to pin a node of a graph
+type GraphNodePositionRequest struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+// IsGraph reports whether positions can be pinned for a graph. App trees are not served yet, so their nodes cannot be
+// pinned.
+func IsGraph(graph string) bool {
+	return graph == GraphMySQLReplication || graph == GraphPostgresReplication
+}
+
+// LayoutLayeredGraph computes the position of each node of a directed graph with a layered (Sugiyama) layout:
+//  1. Cycles are broken by reversing the edges closing them. Edges in both directions are laid out once.
+//  2. Nodes are layered by their longest path from a root, so edges point down.
+//  3. Edges spanning several layers are split by virtual nodes, one per layer crossed.
+//  4. The order of the nodes of each layer is swept by the barycenter of their neighbors, to reduce edge crossings.
+//  5. Nodes are moved towards their neighbors, at least GraphLayoutNodeSpacing apart within a layer.
+//
+// The layout only depends on the node IDs and the links, not on their order, so a graph keeps its layout across
+// captures until it changes. Links to unknown nodes are ignored.
+func LayoutLayeredGraph(nodes []string, links []GraphLink) map[string]GraphLayoutPosition {
+	ids := slices.Clone(nodes)
+	slices.Sort(ids)
+	ids = slices.Compact(ids)
+	index := make(map[string]int, len(ids))
+	for i, id := range ids {
+		index[id] = i
+	}
+	n := len(ids)
+
+	sortedLinks := slices.Clone(links)
+	slices.SortFunc(sortedLinks, func(a, b GraphLink) int {
+		return cmp.Or(cmp.Compare(a.Source, b.Source), cmp.Compare(a.Target, b.Target))
+	})
+	succ := make([][]int, n)
+	linked := map[[2]int]bool{}
+	for _, l := range sortedLinks {
+		s, sok := index[l.Source]
+		t, tok := index[l.Target]
+		if !sok || !tok || s == t || linked[[2]int{s, t}] || linked[[2]int{t, s}] {
+			continue
+		}
+		linked[[2]int{s, t}] = true
+		succ[s] = append(succ[s], t)
+	}
+
+	dag := breakCycles(succ)
+	layer := longestPathLayers(dag)
+	up, down, layers := splitLongEdges(dag, layer)
+	orderLayers(layers, up, down)
+	x := placeLayers(layers, up, down)
+
+	minX := math.Inf(1)
+	for v := 0; v < n; v++ {
+		minX = math.Min(minX, x[v])
+	}
+	positions := make(map[string]GraphLayoutPosition, n)
+	for v, id := range ids {
+		positions[id] = GraphLayoutPosition{
+			X: int(math.Round((x[v] - minX) * GraphLayoutNodeSpacing)),
+			Y: layer[v] * GraphLayoutLayerSpacing,
+		}
+	}
+	return positions
+}
+
+// breakCycles returns the graph with the edges closing a cycle reversed. Nodes are visited from the roots first, so
+// the edges of acyclic graphs are kept.
+func breakCycles(succ [][]int) [][]int {
+	n := len(succ)
+	indegree := make([]int, n)
+	for _, targets := range succ {
+		for _, t := range targets {
+			indegree[t]++
+		}
+	}
+
+	const onStack, visited = 1, 2
+	state := make([]int, n)
+	dag := make([][]int, n)
+	var visit func(v int)
+	visit = func(v int) {
+		state[v] = onStack
+		for _, w := range succ[v] {
+			if state[w] == onStack {
+				dag[w] = append(dag[w], v)
+				continue
+			}
+			dag[v] = append(dag[v], w)
+			if state[w] == 0 {
+				visit(w)
+			}
+		}
+		state[v] = visited
+	}
+	for v := 0; v < n; v++ {
+		if indegree[v] == 0 && state[v] == 0 {
+			visit(v)
+		}
+	}
+	for v := 0; v < n; v++ {
+		if state[v] == 0 {
+			visit(v)
+		}
+	}
+	return dag
+}
+
+// longestPathLayers returns the layer of each node of an acyclic graph, the length of its longest path from a root
+func longestPathLayers(dag [][]int) []int {
+	n := len(dag)
+	indegree := make([]int, n)
+	for _, targets := range dag {
+		for _, t := range targets {
+			indegree[t]++
+		}
+	}
+	queue := []int{}
+	for v := 0; v < n; v++ {
+		if indegree[v] == 0 {
+			queue = append(queue, v)
+		}
+	}
+	layer := make([]int, n)
+	for len(queue) > 0 {
+		v := queue[0]
+		queue = queue[1:]
+		for _, w := range dag[v] {
+			layer[w] = max(layer[w], layer[v]+1)
+			if indegree[w]--; indegree[w] == 0 {
+				queue = append(queue, w)
+			}
+		}
+	}
+	return layer
+}
+
+// splitLongEdges adds a virtual node on each layer an edge crosses. It returns the neighbors of every node, virtual
+// nodes after the others, in the layer above and below, and the nodes of each layer.
+func splitLongEdges(dag [][]int, layer []int) ([][]int, [][]int, [][]int) {
+	n := len(dag)
+	vertexLayer := slices.Clone(layer)
+	up := make([][]int, n)
+	down := make([][]int, n)
+	for v := 0; v < n; v++ {
+		for _, w := range dag[v] {
+			prev := v
+			for l := layer[v] + 1; l < layer[w]; l++ {
+				virtual := len(vertexLayer)
+				vertexLayer = append(vertexLayer, l)
+				up = append(up, []int{prev})
+				down = append(down, nil)
+				down[prev] = append(down[prev], virtual)
+				prev = virtual
+			}
+			down[prev] = append(down[prev], w)
+			up[w] = append(up[w], prev)
+		}
+	}
+
+	layers := [][]int{}
+	for v, l := range vertexLayer {
+		for len(layers) <= l {
+			layers = append(layers, []int{})
+		}
+		layers[l] = append(layers[l], v)
+	}
+	return up, down, layers
+}
+
+// orderLayers sweeps the layers down and up, ordering the nodes of each layer by the mean order of their neighbors in
+// the layer swept from. Nodes without neighbors there keep their order.
+func orderLayers(layers, up, down [][]int) {
+	order := make([]float64, len(up))
+	for _, nodes := range layers {
+		for i, v := range nodes {
+			order[v] = float64(i)
+		}
+	}
+	sortLayer := func(nodes []int, neighbors [][]int) {
+		barycenter := make(map[int]float64, len(nodes))
+		for _, v := range nodes {
+			barycenter[v] = meanOr(neighbors[v], order, order[v])
+		}
+		slices.SortStableFunc(nodes, func(a, b int) int { return cmp.Compare(barycenter[a], barycenter[b]) })
+		for i, v := range nodes {
+			order[v] = float64(i)
+		}
+	}
+
+	for sweep := 0; sweep < graphLayoutSweeps; sweep++ {
+		if sweep%2 == 0 {
+			for l := 1; l < len(layers); l++ {
+				sortLayer(layers[l], up)
+			}
+		} else {
+			for l := len(layers) - 2; l >= 0; l-- {
+				sortLayer(layers[l], down)
+			}
+		}
+	}
+}
+
+// placeLayers returns the horizontal position of every node, in units of GraphLayoutNodeSpacing. Each layer is moved
+// towards the mean position of the neighbors of its nodes, down, up and down again, keeping the nodes of a layer in
+// order and at least a unit apart.
+func placeLayers(layers, up, down [][]int) []float64 {
+	x := make([]float64, len(up))
+	for _, nodes := range layers {
+		for i, v := range nodes {
+			x[v] = float64(i)
+		}
+	}
+	placeLayer := func(nodes []int, neighbors [][]int) {
+		desired := make([]float64, len(nodes))
+		for i, v := range nodes {
+			desired[i] = meanOr(neighbors[v], x, x[v])
+		}
+		// The mean of the placements packed from the left and from the right keeps nodes a unit apart
+		left := make([]float64, len(nodes))
+		right := make([]float64, len(nodes))
+		for i := range nodes {
+			left[i] = desired[i]
+			if i > 0 {
+				left[i] = math.Max(desired[i], left[i-1]+1)
+			}
+		}
+		for i := len(nodes) - 1; i >= 0; i-- {
+			right[i] = desired[i]
+			if i < len(nodes)-1 {
+				right[i] = math.Min(desired[i], right[i+1]-1)
+			}
+		}
+		for i, v := range nodes {
+			x[v] = (left[i] + right[i]) / 2
+		}
+	}
+
+	for pass := 0; pass < 3; pass++ {
+		if pass%2 == 0 {
+			for l := 1; l < len(layers); l++ {
+				placeLayer(layers[l], up)
+			}
+		} else {
+			for l := len(layers) - 2; l >= 0; l-- {
+				placeLayer(layers[l], down)
+			}
+		}
+	}
+	return x
+}
+
+// meanOr returns the mean value of nodes, or fallback when there are none
+func meanOr(nodes []int, values []float64, fallback float64) float64 {
+	if len(nodes) == 0 {
+		return fallback
+	}
+	sum := 0.0
+	for _, v := range nodes {
+		sum += values[v]
+	}
+	return sum / float64(len(nodes))
+}
+
+// LayoutReplTopoTree positions the nodes of a replication graph with a layered layout, sources above their replicas
+func LayoutReplTopoTree(nodes []ReplTopoTreeNode, edges []ReplTopoTreeEdge) {
+	ids := make([]string, len(nodes))
+	for i, node := range nodes {
+		ids[i] = node.ID
+	}
+	links := make([]GraphLink, len(edges))
+	for i, edge := range edges {
+		links[i] = GraphLink{Source: edge.Source, Target: edge.Target}
+	}
+	positions := LayoutLayeredGraph(ids, links)
+	for i := range nodes {
+		p := positions[nodes[i].ID]
+		nodes[i].Position = ReplTopoTreeNodePosition{X: p.X, Y: p.Y}
+	}
+}
+
+// PinReplTopoTreeNodes moves the nodes of a replication graph that were pinned by an admin
+func PinReplTopoTreeNodes(nodes []ReplTopoTreeNode, pinned []GraphNodePosition) {
+	byNode := pinnedByNode(pinned)
+	for i := range nodes {
+		if p, ok := byNode[nodes[i].ID]; ok {
+			nodes[i].Position = ReplTopoTreeNodePosition{X: p.X, Y: p.Y}
+		}
+	}
+}
+
+func pinnedByNode(pinned []GraphNodePosition) map[string]GraphNodePosition {
+	byNode := make(map[string]GraphNodePosition, len(pinned))
+	for _, p := range pinned {
+		byNode[p.NodeID] = p
+	}
+	return byNode
+}
+
+// IsValid validates a pinned position
+func (p *GraphNodePosition) IsValid() (bool, string) {
+	if !IsGraph(p.Graph) {
+		return false, fmt.Sprintf("Graph is invalid. Must be one of %s or %s", GraphMySQLReplication, GraphPostgresReplication)
+	}
+	if p.NodeID == "" {
+		return false, "NodeID is invalid. Must not be empty"
+	}
+	return true, ""
+}
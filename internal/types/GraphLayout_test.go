@@ -0,0 +1,120 @@
+package types
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLayoutLayeredGraph(t *testing.T) {
+	nodes := []string{"replica-2", "primary", "replica-1", "relay", "relay-replica", "dms"}
+	links := []GraphLink{
+		{Source: "primary", Target: "replica-1"},
+		{Source: "primary", Target: "replica-2"},
+		{Source: "primary", Target: "relay"},
+		{Source: "relay", Target: "relay-replica"},
+		{Source: "primary", Target: "relay-replica"},
+		{Source: "relay", Target: "dms"},
+		{Source: "primary", Target: "unknown"},
+	}
+
+	positions := LayoutLayeredGraph(nodes, links)
+	require.Len(t, positions, 6)
+	assert.Equal(t, 0, positions["primary"].Y)
+	assert.Equal(t, GraphLayoutLayerSpacing, positions["relay"].Y)
+	assert.Equal(t, GraphLayoutLayerSpacing, positions["replica-1"].Y)
+	assert.Equal(t, 2*GraphLayoutLayerSpacing, positions["relay-replica"].Y)
+	assert.Equal(t, 2*GraphLayoutLayerSpacing, positions["dms"].Y)
+
+	// Nodes of a layer do not overlap, and the layout starts at the left edge
+	minX := positions["primary"].X
+	for _, a := range nodes {
+		minX = min(minX, positions[a].X)
+		for _, b := range nodes {
+			if a != b && positions[a].Y == positions[b].Y {
+				assert.GreaterOrEqual(t, abs(positions[a].X-positions[b].X), GraphLayoutNodeSpacing-1, "%s and %s overlap", a, b)
+			}
+		}
+	}
+	assert.Equal(t, 0, minX)
+
+	// The layout does not depend on the order of the nodes and links
+	reversed := []GraphLink{}
+	for i := len(links) - 1; i >= 0; i-- {
+		reversed = append(reversed, links[i])
+	}
+	assert.Equal(t, positions, LayoutLayeredGraph([]string{"dms", "relay-replica", "relay", "replica-1", "primary", "replica-2"}, reversed))
+}
+
+func TestLayoutLayeredGraphCycles(t *testing.T) {
+	// Circular replication and bidirectional links are laid out without looping
+	positions := LayoutLayeredGraph([]string{"a", "b", "c", "d"}, []GraphLink{
+		{Source: "a", Target: "b"}, {Source: "b", Target: "c"}, {Source: "c", Target: "a"},
+		{Source: "c", Target: "d"}, {Source: "d", Target: "c"},
+	})
+	require.Len(t, positions, 4)
+	layers := map[int]int{}
+	for _, p := range positions {
+		layers[p.Y]++
+	}
+	assert.Len(t, layers, 4)
+	assert.Less(t, positions["a"].Y, positions["b"].Y)
+}
+
+func TestLayoutLayeredGraphLarge(t *testing.T) {
+	nodes := []string{"primary"}
+	links := []GraphLink{}
+	for i := 0; i < 30; i++ {
+		relay := fmt.Sprintf("relay-%02d", i)
+		nodes = append(nodes, relay)
+		links = append(links, GraphLink{Source: "primary", Target: relay})
+		for j := 0; j < 10; j++ {
+			replica := fmt.Sprintf("%s-replica-%02d", relay, j)
+			nodes = append(nodes, replica)
+			links = append(links, GraphLink{Source: relay, Target: replica})
+		}
+	}
+
+	positions := LayoutLayeredGraph(nodes, links)
+	seen := map[GraphLayoutPosition]string{}
+	for id, p := range positions {
+		other, ok := seen[p]
+		assert.False(t, ok, "%s and %s share a position", id, other)
+		seen[p] = id
+	}
+	// The replicas of a relay are grouped under it
+	assert.Less(t, positions["relay-00-replica-09"].X, positions["relay-01-replica-00"].X)
+}
+
+func TestPinReplTopoTreeNodes(t *testing.T) {
+	nodes := []ReplTopoTreeNode{{ID: "db-core-007"}, {ID: "db-core-008"}}
+	edges := []ReplTopoTreeEdge{{ID: "db-core-007-db-core-008", Source: "db-core-007", Target: "db-core-008"}}
+	LayoutReplTopoTree(nodes, edges)
+	assert.Equal(t, ReplTopoTreeNodePosition{X: 0, Y: GraphLayoutLayerSpacing}, nodes[1].Position)
+
+	PinReplTopoTreeNodes(nodes, []GraphNodePosition{{Graph: GraphMySQLReplication, NodeID: "db-core-008", X: 40, Y: 900}})
+	assert.Equal(t, ReplTopoTreeNodePosition{X: 0, Y: 0}, nodes[0].Position)
+	assert.Equal(t, ReplTopoTreeNodePosition{X: 40, Y: 900}, nodes[1].Position)
+}
+
+func TestGraphNodePositionIsValid(t *testing.T) {
+	ok, _ := (&GraphNodePosition{Graph: GraphPostgresReplication, NodeID: "pg-orders-1"}).IsValid()
+	assert.True(t, ok)
+
+	// App trees are not served yet, so their nodes cannot be pinned
+	ok, msg := (&GraphNodePosition{Graph: "app-tree", NodeID: "deployment/checkout"}).IsValid()
+	assert.False(t, ok)
+	assert.Contains(t, msg, "Graph is invalid")
+
+	ok, _ = (&GraphNodePosition{Graph: GraphMySQLReplication}).IsValid()
+	assert.False(t, ok)
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
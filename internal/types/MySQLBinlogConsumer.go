@@ -0,0 +1,156 @@
+package types
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Types of binlog consumers
+const (
+	// MySQLConsumerReplica consumers are MySQL replicas. They are shown through the replication channels of the catalog
+	// hosts, so they are not captured as consumers.
+	MySQLConsumerReplica = "replica"
+	MySQLConsumerDMS     = "dms"
+	MySQLConsumerCDC     = "cdc"
+	MySQLConsumerIgnore  = "ignore"
+)
+
+// DefaultMySQLConsumerRules classify the binlog consumers no configured rule matches, and whose client host is not a
+// catalog host. The repl and rdsrepladmin users are replicas, and any other consumer is a DMS task.
+var DefaultMySQLConsumerRules = []MySQLConsumerRule{
+	{Name: "default-replica", User: "repl|rdsrepladmin", Type: MySQLConsumerReplica},
+	{Name: "default-dms", Type: MySQLConsumerDMS},
+}
+
+// MySQLConsumerRule classifies the connections reading the binlog of the catalog hosts. The first matching rule
+// classifies a consumer.
+type MySQLConsumerRule struct {
+	Name string `json:"name"`
+	// User and Host are regular expressions fully matching the user and the client host of the connection. An empty
+	// expression matches any user or host.
+	User string `json:"user,omitempty"`
+	Host string `json:"host,omitempty"`
+	// Sources lists the shortnames of the catalog hosts the rule applies to. It applies to every host when empty.
+	Sources []string `json:"sources,omitempty"`
+	// Type is one of replica, dms, cdc or ignore
+	Type string `json:"type"`
+}
+
+// MySQLBinlogConsumer is a connection reading the binlog of a host
+type MySQLBinlogConsumer struct {
+	User string
+	// Host is the client host of the connection, without its port
+	Host string
+	// CatalogHost reports whether Host resolves to a catalog host. It is set by the capture, not by the probe.
+	CatalogHost bool
+}
+
+// MySQLConsumerClassifier classifies binlog consumers by consumer rules whose expressions are compiled once. The zero
+// value only applies the default classification.
+type MySQLConsumerClassifier struct {
+	rules []compiledMySQLConsumerRule
+}
+
+// compiledMySQLConsumerRule is a consumer rule with its compiled expressions. Nil expressions match any value.
+type compiledMySQLConsumerRule struct {
+	MySQLConsumerRule
+	user, host *regexp.Regexp
+	invalid    bool
+}
+
+// NewMySQLConsumerClassifier compiles the consumer rules. Rules with an invalid expression match no consumer.
+func NewMySQLConsumerClassifier(rules []MySQLConsumerRule) *MySQLConsumerClassifier {
+	return &MySQLConsumerClassifier{rules: compileMySQLConsumerRules(rules)}
+}
+
+// defaultMySQLConsumerRules are the compiled DefaultMySQLConsumerRules
+var defaultMySQLConsumerRules = compileMySQLConsumerRules(DefaultMySQLConsumerRules)
+
+// Classify returns the type of a consumer of a catalog host, from the first matching rule. Consumers no rule matches
+// are replicas when their client host is a catalog host, and are otherwise classified by the DefaultMySQLConsumerRules.
+func (c *MySQLConsumerClassifier) Classify(source string, consumer MySQLBinlogConsumer) string {
+	if c != nil {
+		if t, ok := classifyMySQLConsumer(c.rules, source, consumer); ok {
+			return t
+		}
+	}
+	if consumer.CatalogHost {
+		return MySQLConsumerReplica
+	}
+	if t, ok := classifyMySQLConsumer(defaultMySQLConsumerRules, source, consumer); ok {
+		return t
+	}
+	return MySQLConsumerDMS
+}
+
+// classifyMySQLConsumer returns the type of the first rule matching a consumer, if any
+func classifyMySQLConsumer(rules []compiledMySQLConsumerRule, source string, consumer MySQLBinlogConsumer) (string, bool) {
+	for _, r := range rules {
+		if r.matches(source, consumer) {
+			return r.Type, true
+		}
+	}
+	return "", false
+}
+
+// matches reports whether the rule classifies a consumer of a catalog host
+func (r compiledMySQLConsumerRule) matches(source string, consumer MySQLBinlogConsumer) bool {
+	if r.invalid || len(r.Sources) > 0 && !slices.Contains(r.Sources, source) {
+		return false
+	}
+	return (r.user == nil || r.user.MatchString(consumer.User)) && (r.host == nil || r.host.MatchString(consumer.Host))
+}
+
+// compileMySQLConsumerRules compiles the expressions of the rules, anchored to match whole values
+func compileMySQLConsumerRules(rules []MySQLConsumerRule) []compiledMySQLConsumerRule {
+	compiled := make([]compiledMySQLConsumerRule, 0, len(rules))
+	for _, r := range rules {
+		c := compiledMySQLConsumerRule{MySQLConsumerRule: r}
+		var userErr, hostErr error
+		if r.User != "" {
+			c.user, userErr = regexp.Compile("^(?:" + r.User + ")$")
+		}
+		if r.Host != "" {
+			c.host, hostErr = regexp.Compile("^(?:" + r.Host + ")$")
+		}
+		c.invalid = userErr != nil || hostErr != nil
+		compiled = append(compiled, c)
+	}
+	return compiled
+}
+
+// validateMySQLConsumerRules returns the validation errors of the consumer rules, keyed by the field they apply to
+func validateMySQLConsumerRules(field string, rules []MySQLConsumerRule) []ConfigFieldError {
+	errs := []ConfigFieldError{}
+	add := func(field, format string, args ...any) {
+		errs = append(errs, ConfigFieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	names := map[string]bool{}
+	for i, r := range rules {
+		field := fmt.Sprintf("%s[%d]", field, i)
+		if !pluginNameRegex.MatchString(r.Name) {
+			add(field+".name", "must be alphanumeric, '-' or '_', and start with an alphanumeric character")
+		}
+		if names[r.Name] {
+			add(field+".name", "rule %q is declared more than once", r.Name)
+		}
+		names[r.Name] = true
+
+		if _, err := regexp.Compile(r.User); err != nil {
+			add(field+".user", "is not a valid regular expression: %v", err)
+		}
+		if _, err := regexp.Compile(r.Host); err != nil {
+			add(field+".host", "is not a valid regular expression: %v", err)
+		}
+		if !slices.Contains(mySQLConsumerTypes, r.Type) {
+			add(field+".type", "must be one of %s", strings.Join(mySQLConsumerTypes, ", "))
+		}
+	}
+	return errs
+}
+
+// mySQLConsumerTypes are the types rules may classify consumers as
+var mySQLConsumerTypes = []string{MySQLConsumerReplica, MySQLConsumerDMS, MySQLConsumerCDC, MySQLConsumerIgnore}
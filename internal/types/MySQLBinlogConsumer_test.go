@@ -0,0 +1,48 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMySQLConsumerClassifier(t *testing.T) {
+	classifier := NewMySQLConsumerClassifier([]MySQLConsumerRule{
+		{Name: "debezium", User: "debezium|cdc_.*", Type: MySQLConsumerCDC},
+		{Name: "fivetran", User: "fivetran", Host: `35\.\d+\.\d+\.\d+`, Type: MySQLConsumerCDC},
+		{Name: "orders-backup", User: "backup", Sources: []string{"db-orders-001"}, Type: MySQLConsumerIgnore},
+		{Name: "broken", User: "(", Type: MySQLConsumerIgnore},
+		{Name: "catalog-cdc", User: "maxwell", Type: MySQLConsumerCDC},
+	})
+
+	assert.Equal(t, MySQLConsumerCDC, classifier.Classify("db-core-007", MySQLBinlogConsumer{User: "cdc_orders", Host: "10.0.0.1"}))
+	assert.Equal(t, MySQLConsumerCDC, classifier.Classify("db-core-007", MySQLBinlogConsumer{User: "fivetran", Host: "35.1.2.3"}))
+	assert.Equal(t, MySQLConsumerDMS, classifier.Classify("db-core-007", MySQLBinlogConsumer{User: "fivetran", Host: "10.0.0.1"}))
+	assert.Equal(t, MySQLConsumerIgnore, classifier.Classify("db-orders-001", MySQLBinlogConsumer{User: "backup"}))
+	assert.Equal(t, MySQLConsumerDMS, classifier.Classify("db-core-007", MySQLBinlogConsumer{User: "backup"}))
+	assert.Equal(t, MySQLConsumerReplica, classifier.Classify("db-core-007", MySQLBinlogConsumer{User: "repl", Host: "10.0.0.2"}))
+	assert.Equal(t, MySQLConsumerReplica, classifier.Classify("db-core-007", MySQLBinlogConsumer{User: "rdsrepladmin", Host: "10.0.0.2"}))
+	// Patterns match the whole user
+	assert.Equal(t, MySQLConsumerDMS, classifier.Classify("db-core-007", MySQLBinlogConsumer{User: "debezium2"}))
+
+	// Consumers connecting from a catalog host are replicas, unless a configured rule matches them
+	assert.Equal(t, MySQLConsumerReplica, classifier.Classify("db-core-007", MySQLBinlogConsumer{User: "gtid_repl", Host: "10.0.0.3", CatalogHost: true}))
+	assert.Equal(t, MySQLConsumerCDC, classifier.Classify("db-core-007", MySQLBinlogConsumer{User: "maxwell", Host: "10.0.0.3", CatalogHost: true}))
+
+	// Without rules, only the default classification applies
+	var defaults *MySQLConsumerClassifier
+	assert.Equal(t, MySQLConsumerDMS, defaults.Classify("db-core-007", MySQLBinlogConsumer{User: "debezium"}))
+	assert.Equal(t, MySQLConsumerReplica, defaults.Classify("db-core-007", MySQLBinlogConsumer{User: "repl"}))
+}
+
+func TestValidateMySQLConsumerRules(t *testing.T) {
+	rules := []MySQLConsumerRule{
+		{Name: "debezium", User: "debezium", Type: MySQLConsumerCDC},
+		{Name: "debezium", User: "(", Host: "[", Type: "kafka"},
+	}
+	fields := []string{}
+	for _, e := range validateMySQLConsumerRules("mySQLConsumerRules", rules) {
+		fields = append(fields, e.Field)
+	}
+	assert.Equal(t, []string{"mySQLConsumerRules[1].name", "mySQLConsumerRules[1].user", "mySQLConsumerRules[1].host", "mySQLConsumerRules[1].type"}, fields)
+}
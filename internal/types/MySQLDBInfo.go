@@ -8,19 +8,23 @@ import (
 	"gorm.io/gorm"
 )
 
-// MySQLDBInfo represents a MySQL database within the topology
+// MySQLDBInfo represents a MySQL database of the catalog. Its replication status is captured into a ReplDBInfo.
 type MySQLDBInfo struct {
-	Host               string         `json:"host" gorm:"primaryKey"`
-	Shortname          string         `json:"shortName"`
-	Username           string         `json:"username"`
-	Port               int            `json:"port"`
-	IsPrimary          bool           `json:"isPrimary"`
-	Replicas           []string       `json:"replicas" gorm:"-"`
-	Source             string         `json:"source" gorm:"-"`
-	ReplicationRunning bool           `json:"replication_running" gorm:"-"`
-	CreatedAt          time.Time      `json:"createdAt"`
-	UpdatedAt          time.Time      `json:"updatedAt"`
-	DeletedAt          gorm.DeletedAt `gorm:"index"`
+	Host       string        `json:"host" gorm:"primaryKey"`
+	Shortname  string        `json:"shortName"`
+	Username   string        `json:"username"`
+	Port       int           `json:"port"`
+	IsPrimary  bool          `json:"isPrimary"`
+	Credential CredentialRef `json:"credential" gorm:"embedded;embeddedPrefix:credential_"`
+	// Origin is where the host was added from, manual or a discovery source. OriginRef identifies the discovered
+	// resource, such as service/<namespace>/<name> or the ARN of an RDS instance.
+	Origin    string `json:"origin" gorm:"not null;default:manual"`
+	OriginRef string `json:"originRef,omitempty"`
+	// LastSeenAt is when a discovery source last found the host. It is nil for hosts no source has found.
+	LastSeenAt *time.Time     `json:"lastSeenAt"`
+	CreatedAt  time.Time      `json:"createdAt"`
+	UpdatedAt  time.Time      `json:"updatedAt"`
+	DeletedAt  gorm.DeletedAt `gorm:"index"`
 }
 
 func (d *MySQLDBInfo) IsValid() (bool, string) {
@@ -37,6 +41,10 @@ func (d *MySQLDBInfo) IsValid() (bool, string) {
 		errors.WriteString(fmt.Sprintln("Username is invalid. Must not be empty"))
 	}
 
+	if valid, errMsg := d.Credential.IsValid(); !valid {
+		errors.WriteString(errMsg)
+	}
+
 	errMsg := errors.String()
 	if len(errMsg) > 0 {
 		return false, errMsg
@@ -45,41 +53,16 @@ func (d *MySQLDBInfo) IsValid() (bool, string) {
 	return true, ""
 }
 
-// SetSource sets the replication source of the database
-func (db *MySQLDBInfo) SetSource(host string) {
-	db.Source = host
-}
-
-// SetReplicationRunning sets the replication status of the database to running
-func (db *MySQLDBInfo) SetReplicationRunning(running bool) {
-	db.ReplicationRunning = running
-}
-
-// AddReplica adds a replica to the database
-func (db *MySQLDBInfo) AddReplica(replica string) {
-	db.Replicas = append(db.Replicas, replica)
-}
-
-type ReplTopoTreeNode struct {
-	ID       string                   `json:"id"`
-	Data     MySQLDBInfo              `json:"data"`
-	Position ReplTopoTreeNodePosition `json:"position"`
-}
-
-type ReplTopoTreeNodePosition struct {
-	X int `json:"x"`
-	Y int `json:"y"`
-}
-
-type ReplTopoTreeEdge struct {
-	ID       string `json:"id"`
-	Source   string `json:"source"`
-	Target   string `json:"target"`
-	EdgeType string `json:"edgeType"`
-	Animated bool   `json:"animated"`
-}
-
-type ReplTopoTree struct {
-	Nodes []ReplTopoTreeNode `json:"nodes"`
-	Edges []ReplTopoTreeEdge `json:"edges"`
+// TopologyNode returns the node of the database in the replication topology graph, without its replication status
+func (d MySQLDBInfo) TopologyNode() *ReplDBInfo {
+	return &ReplDBInfo{
+		Host:       d.Host,
+		Shortname:  d.Shortname,
+		Username:   d.Username,
+		Port:       d.Port,
+		IsPrimary:  d.IsPrimary,
+		Credential: d.Credential,
+		CreatedAt:  d.CreatedAt,
+		UpdatedAt:  d.UpdatedAt,
+	}
 }
@@ -0,0 +1,14 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMySQLDBInfoIsValid(t *testing.T) {
+	db := MySQLDBInfo{Host: "db-1.example.com", Shortname: "db-1", Username: "khub", Credential: CredentialRef{Source: CredentialSourceEnv}}
+	ok, msg := db.IsValid()
+	assert.False(t, ok)
+	assert.Contains(t, msg, "envVar is invalid")
+}
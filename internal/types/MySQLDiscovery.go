@@ -0,0 +1,145 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// Origins of MySQL catalog hosts
+const (
+	MySQLOriginManual     = "manual"
+	MySQLOriginKubernetes = "kubernetes"
+	MySQLOriginRDS        = "rds"
+)
+
+// Statuses of MySQL discovery proposals. Accepted proposals are moved to the catalog.
+const (
+	MySQLProposalPending = "pending"
+	MySQLProposalIgnored = "ignored"
+)
+
+// DefaultMySQLDiscoveryPort is the port of discovered hosts that do not expose a port named mysql
+const DefaultMySQLDiscoveryPort = 3306
+
+// MySQLDiscoveryConfig configures the sources the data sink discovers MySQL hosts from
+type MySQLDiscoveryConfig struct {
+	// Labels selects the Services and StatefulSets running MySQL. Kubernetes discovery is disabled when it is empty.
+	Labels map[string]string `json:"labels,omitempty"`
+	// RDS discovers the MySQL and Aurora MySQL instances of the AWS account
+	RDS bool `json:"rds,omitempty"`
+	// Username is the username proposed when accepting a discovered host
+	Username string `json:"username,omitempty"`
+}
+
+// Enabled reports whether any discovery source is configured
+func (c MySQLDiscoveryConfig) Enabled() bool {
+	return len(c.Labels) > 0 || c.RDS
+}
+
+// Matches reports whether a resource carries every configured label
+func (c MySQLDiscoveryConfig) Matches(labels map[string]string) bool {
+	if len(c.Labels) == 0 {
+		return false
+	}
+	for k, v := range c.Labels {
+		if value, ok := labels[k]; !ok || value != v {
+			return false
+		}
+	}
+	return true
+}
+
+// validate returns the validation errors of the discovery config, keyed by the field they apply to
+func (c MySQLDiscoveryConfig) validate(field string) []ConfigFieldError {
+	errs := []ConfigFieldError{}
+	for k, v := range c.Labels {
+		if msgs := validation.IsQualifiedName(k); len(msgs) > 0 {
+			errs = append(errs, ConfigFieldError{Field: field + ".labels", Message: fmt.Sprintf("%q is not a valid label key: %s", k, strings.Join(msgs, ", "))})
+		}
+		if msgs := validation.IsValidLabelValue(v); len(msgs) > 0 {
+			errs = append(errs, ConfigFieldError{Field: fmt.Sprintf("%s.labels.%s", field, k), Message: fmt.Sprintf("%q is not a valid label value: %s", v, strings.Join(msgs, ", "))})
+		}
+	}
+	return errs
+}
+
+// MySQLDiscoveryProposal is a MySQL host found by a discovery source which is not in the catalog yet.
+// Admins accept proposals into the catalog, or ignore them so they are no longer offered.
+type MySQLDiscoveryProposal struct {
+	Host      string `json:"host" gorm:"primaryKey"`
+	Shortname string `json:"shortName"`
+	Port      int    `json:"port"`
+	Origin    string `json:"origin"`
+	OriginRef string `json:"originRef"`
+	Status    string `json:"status" gorm:"not null;default:pending;index"`
+	// CreatedAt is when the host was first found, LastSeenAt when it was last found
+	CreatedAt  time.Time `json:"createdAt"`
+	LastSeenAt time.Time `json:"lastSeenAt"`
+}
+
+// MySQLDiscoveryAcceptRequest represents the request body used to accept a discovery proposal into the catalog.
+// Shortname and Port default to the proposal, and Username to the discovery config.
+type MySQLDiscoveryAcceptRequest struct {
+	Shortname  string        `json:"shortName"`
+	Username   string        `json:"username"`
+	Port       int           `json:"port"`
+	IsPrimary  bool          `json:"isPrimary"`
+	Credential CredentialRef `json:"credential"`
+}
+
+// CatalogEntry returns the catalog entry of an accepted proposal
+func (p MySQLDiscoveryProposal) CatalogEntry(request MySQLDiscoveryAcceptRequest, defaultUsername string) MySQLDBInfo {
+	lastSeenAt := p.LastSeenAt
+	db := MySQLDBInfo{
+		Host:       p.Host,
+		Shortname:  p.Shortname,
+		Username:   defaultUsername,
+		Port:       p.Port,
+		IsPrimary:  request.IsPrimary,
+		Credential: request.Credential,
+		Origin:     p.Origin,
+		OriginRef:  p.OriginRef,
+		LastSeenAt: &lastSeenAt,
+	}
+	if request.Shortname != "" {
+		db.Shortname = request.Shortname
+	}
+	if request.Username != "" {
+		db.Username = request.Username
+	}
+	if request.Port > 0 {
+		db.Port = request.Port
+	}
+	return db
+}
+
+// ReconcileMySQLDiscovery splits the hosts found by discovery sources at the given time into the catalog hosts that were
+// seen, and proposals for the hosts that are not in the catalog. Hosts found more than once are proposed once, from the
+// first source that found them.
+func ReconcileMySQLDiscovery(catalog []*MySQLDBInfo, discovered []MySQLDiscoveryProposal, at time.Time) ([]string, []MySQLDiscoveryProposal) {
+	inCatalog := map[string]bool{}
+	for _, db := range catalog {
+		inCatalog[db.Host] = true
+	}
+
+	seen := []string{}
+	proposals := []MySQLDiscoveryProposal{}
+	found := map[string]bool{}
+	for _, d := range discovered {
+		if d.Host == "" || found[d.Host] {
+			continue
+		}
+		found[d.Host] = true
+		if inCatalog[d.Host] {
+			seen = append(seen, d.Host)
+			continue
+		}
+		d.Status = MySQLProposalPending
+		d.LastSeenAt = at
+		proposals = append(proposals, d)
+	}
+	return seen, proposals
+}
@@ -0,0 +1,61 @@
+package types
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReconcileMySQLDiscovery(t *testing.T) {
+	seenAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	catalog := []*MySQLDBInfo{{Host: "orders-0.orders.databases.svc", Shortname: "orders-0"}}
+	discovered := []MySQLDiscoveryProposal{
+		{Host: "orders-0.orders.databases.svc", Shortname: "orders-0", Origin: MySQLOriginKubernetes},
+		{Host: "orders-1.orders.databases.svc", Shortname: "orders-1", Origin: MySQLOriginKubernetes},
+		{Host: "payments.rds.amazonaws.com", Shortname: "payments", Origin: MySQLOriginRDS},
+		{Host: "payments.rds.amazonaws.com", Shortname: "payments-svc", Origin: MySQLOriginKubernetes},
+	}
+
+	seen, proposals := ReconcileMySQLDiscovery(catalog, discovered, seenAt)
+	assert.Equal(t, []string{"orders-0.orders.databases.svc"}, seen)
+	require.Len(t, proposals, 2)
+	assert.Equal(t, "orders-1", proposals[0].Shortname)
+	assert.Equal(t, MySQLProposalPending, proposals[0].Status)
+	assert.Equal(t, seenAt, proposals[0].LastSeenAt)
+	assert.Equal(t, MySQLOriginRDS, proposals[1].Origin)
+}
+
+func TestMySQLDiscoveryCatalogEntry(t *testing.T) {
+	seenAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	proposal := MySQLDiscoveryProposal{
+		Host: "payments.rds.amazonaws.com", Shortname: "payments", Port: 3306,
+		Origin: MySQLOriginRDS, OriginRef: "arn:aws:rds:us-east-1:123456789012:db:payments", LastSeenAt: seenAt,
+	}
+
+	db := proposal.CatalogEntry(MySQLDiscoveryAcceptRequest{}, "khub")
+	assert.Equal(t, "payments", db.Shortname)
+	assert.Equal(t, "khub", db.Username)
+	assert.Equal(t, 3306, db.Port)
+	assert.Equal(t, MySQLOriginRDS, db.Origin)
+	assert.Equal(t, seenAt, *db.LastSeenAt)
+
+	db = proposal.CatalogEntry(MySQLDiscoveryAcceptRequest{Shortname: "payments-primary", Username: "monitor", IsPrimary: true}, "khub")
+	assert.Equal(t, "payments-primary", db.Shortname)
+	assert.Equal(t, "monitor", db.Username)
+	assert.True(t, db.IsPrimary)
+}
+
+func TestMySQLDiscoveryConfigValidate(t *testing.T) {
+	cfg := MySQLDiscoveryConfig{Labels: map[string]string{"app.kubernetes.io/name": "mysql"}}
+	assert.Empty(t, cfg.validate("mySQLDiscovery"))
+	assert.True(t, cfg.Matches(map[string]string{"app.kubernetes.io/name": "mysql", "team": "core"}))
+	assert.False(t, cfg.Matches(map[string]string{"app.kubernetes.io/name": "redis"}))
+
+	cfg.Labels = map[string]string{"not a key": "mysql", "team": "core team"}
+	errs := cfg.validate("mySQLDiscovery")
+	require.Len(t, errs, 2)
+	fields := []string{errs[0].Field, errs[1].Field}
+	assert.ElementsMatch(t, []string{"mySQLDiscovery.labels", "mySQLDiscovery.labels.team"}, fields)
+}
@@ -0,0 +1,161 @@
+package types
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultMySQLQueryPluginMaxRows is the row limit of query plugins that do not configure one
+	DefaultMySQLQueryPluginMaxRows = 100
+	// MaxMySQLQueryPluginMaxRows is the highest row limit a query plugin may configure
+	MaxMySQLQueryPluginMaxRows = 10000
+	// DefaultMySQLQueryPluginTimeoutSeconds is the timeout of query plugins that do not configure one
+	DefaultMySQLQueryPluginTimeoutSeconds = 10
+	// MaxMySQLQueryPluginTimeoutSeconds is the highest timeout a query plugin may configure
+	MaxMySQLQueryPluginTimeoutSeconds = 300
+	// maxMySQLQueryLength is the maximum length of a query plugin query
+	maxMySQLQueryLength = 4096
+	// MySQLQueryPermissionTag grants running every query plugin. mysql_query_<name> grants a single plugin.
+	MySQLQueryPermissionTag = "mysql_query"
+)
+
+var (
+	// readOnlyQueryRegex matches the statements query plugins may run
+	readOnlyQueryRegex = regexp.MustCompile(`(?is)^\s*(SELECT|SHOW|EXPLAIN|DESCRIBE|DESC|WITH)\s`)
+	// lockingQueryRegex matches clauses that write files or take row locks, which read only transactions still allow
+	lockingQueryRegex = regexp.MustCompile(`(?i)\bINTO\s+(OUTFILE|DUMPFILE)\b|\bFOR\s+(UPDATE|SHARE)\b|\bLOCK\s+IN\s+SHARE\s+MODE\b|\bGET_LOCK\s*\(`)
+	// quotedParameterRegex matches parameters referenced within quotes, where they would not be bound
+	quotedParameterRegex = regexp.MustCompile("['\"`]\\{\\{[a-zA-Z][a-zA-Z0-9_]*\\}\\}")
+)
+
+// MySQLQueryPlugin is a named read only query users can run against the hosts of the MySQL catalog.
+// The query may reference its parameters as {{name}}, which are bound as arguments of the statement. See BuildQuery.
+type MySQLQueryPlugin struct {
+	Name        string                `json:"name"`
+	Description string                `json:"description,omitempty"`
+	Query       string                `json:"query"`
+	Parameters  []ExecPluginParameter `json:"parameters,omitempty"`
+	// Hosts lists the shortnames of the catalog hosts the query may run against. It may run against every host when empty.
+	Hosts []string `json:"hosts,omitempty"`
+	// MaxRows bounds the rows returned. DefaultMySQLQueryPluginMaxRows is used when it is 0.
+	MaxRows int `json:"maxRows,omitempty"`
+	// TimeoutSeconds bounds the run time of the query. DefaultMySQLQueryPluginTimeoutSeconds is used when it is 0.
+	TimeoutSeconds int `json:"timeoutSeconds,omitempty"`
+}
+
+// MySQLQueryRequest represents the request body used to run a query plugin
+type MySQLQueryRequest struct {
+	Args map[string]string `json:"args"`
+}
+
+// MySQLQueryResult is the table returned by a query plugin. NULL values are nil.
+// Truncated is set when the query returned more than the plugin row limit.
+type MySQLQueryResult struct {
+	Plugin     string      `json:"plugin"`
+	Host       string      `json:"host"`
+	Columns    []string    `json:"columns"`
+	Rows       [][]*string `json:"rows"`
+	Truncated  bool        `json:"truncated"`
+	DurationMs int64       `json:"durationMs"`
+}
+
+// Timeout returns the configured timeout of the plugin
+func (p *MySQLQueryPlugin) Timeout() time.Duration {
+	if p.TimeoutSeconds <= 0 {
+		return DefaultMySQLQueryPluginTimeoutSeconds * time.Second
+	}
+	return time.Duration(p.TimeoutSeconds) * time.Second
+}
+
+// RowLimit returns the configured row limit of the plugin
+func (p *MySQLQueryPlugin) RowLimit() int {
+	if p.MaxRows <= 0 {
+		return DefaultMySQLQueryPluginMaxRows
+	}
+	return p.MaxRows
+}
+
+// Permitted reports whether a user holding the permission tags may run the plugin
+func (p *MySQLQueryPlugin) Permitted(permissions []string) bool {
+	for _, t := range permissions {
+		if t == "*" || t == MySQLQueryPermissionTag || t == fmt.Sprintf("%s_%s", MySQLQueryPermissionTag, strings.ToLower(p.Name)) {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsHost reports whether the plugin may run against a catalog host
+func (p *MySQLQueryPlugin) AllowsHost(shortname string) bool {
+	return len(p.Hosts) == 0 || slices.Contains(p.Hosts, shortname)
+}
+
+// BuildQuery returns the query of the plugin with its {{name}} references replaced by placeholders, and the arguments
+// bound to them in order. Arguments are validated against their parameter, and missing arguments use the default.
+func (p *MySQLQueryPlugin) BuildQuery(args map[string]string) (string, []any, error) {
+	plugin := K8sPodExecPlugin{Name: p.Name, Parameters: p.Parameters}
+	values, err := plugin.resolveArguments(args)
+	if err != nil {
+		return "", nil, err
+	}
+
+	bound := []any{}
+	query := parameterPlaceholderRegex.ReplaceAllStringFunc(p.Query, func(placeholder string) string {
+		bound = append(bound, values[parameterPlaceholderRegex.FindStringSubmatch(placeholder)[1]])
+		return "?"
+	})
+	return query, bound, nil
+}
+
+// validate returns the validation errors of the plugin, keyed by the field they apply to
+func (p *MySQLQueryPlugin) validate(field string) []ConfigFieldError {
+	errs := []ConfigFieldError{}
+	add := func(field, format string, args ...any) {
+		errs = append(errs, ConfigFieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if !pluginNameRegex.MatchString(p.Name) {
+		add(field+".name", "must be alphanumeric, '-' or '_', and start with an alphanumeric character")
+	}
+
+	query := strings.TrimRight(strings.TrimSpace(p.Query), ";")
+	switch {
+	case query == "":
+		add(field+".query", "must not be empty")
+	case len(p.Query) > maxMySQLQueryLength:
+		add(field+".query", "must be at most %d characters", maxMySQLQueryLength)
+	case !readOnlyQueryRegex.MatchString(query + " "):
+		add(field+".query", "must be a SELECT, SHOW, EXPLAIN, DESCRIBE or WITH statement")
+	case strings.Contains(query, ";"):
+		add(field+".query", "must be a single statement")
+	case lockingQueryRegex.MatchString(query):
+		add(field+".query", "must not write files or take locks")
+	case quotedParameterRegex.MatchString(query):
+		add(field+".query", "parameters are bound as arguments and must not be quoted")
+	}
+
+	if p.MaxRows < 0 || p.MaxRows > MaxMySQLQueryPluginMaxRows {
+		add(field+".maxRows", "must be between 0 and %d", MaxMySQLQueryPluginMaxRows)
+	}
+	if p.TimeoutSeconds < 0 || p.TimeoutSeconds > MaxMySQLQueryPluginTimeoutSeconds {
+		add(field+".timeoutSeconds", "must be between 0 and %d", MaxMySQLQueryPluginTimeoutSeconds)
+	}
+	for i, host := range p.Hosts {
+		if strings.TrimSpace(host) == "" {
+			add(fmt.Sprintf("%s.hosts[%d]", field, i), "must not be empty")
+		}
+	}
+
+	// The parameters follow the rules of exec plugin parameters, their references are checked against the query
+	plugin := K8sPodExecPlugin{Name: p.Name, Command: p.Query, Parameters: p.Parameters}
+	for _, e := range plugin.validateParameters(field) {
+		e.Field = strings.Replace(e.Field, field+".command", field+".query", 1)
+		e.Message = strings.Replace(e.Message, "command or output file", "query", 1)
+		errs = append(errs, e)
+	}
+	return errs
+}
@@ -0,0 +1,92 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func longQueriesPlugin() MySQLQueryPlugin {
+	return MySQLQueryPlugin{
+		Name:  "long-queries",
+		Query: "SELECT ID, USER, TIME, INFO FROM information_schema.processlist WHERE TIME > {{seconds}} AND USER = {{user}} LIMIT 50",
+		Parameters: []ExecPluginParameter{
+			{Name: "seconds", Type: ExecPluginParameterInt, Min: intPtr(0), Default: "10"},
+			{Name: "user", Type: ExecPluginParameterString, Pattern: `[a-z_]+`},
+		},
+	}
+}
+
+func TestBuildQuery(t *testing.T) {
+	plugin := longQueriesPlugin()
+
+	query, args, err := plugin.BuildQuery(map[string]string{"user": "app"})
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT ID, USER, TIME, INFO FROM information_schema.processlist WHERE TIME > ? AND USER = ? LIMIT 50", query)
+	assert.Equal(t, []any{"10", "app"}, args)
+
+	_, _, err = plugin.BuildQuery(map[string]string{"user": "app' OR 1=1 --"})
+	assert.EqualError(t, err, "parameter user must match [a-z_]+")
+}
+
+func TestMySQLQueryPluginAccess(t *testing.T) {
+	plugin := longQueriesPlugin()
+	assert.True(t, plugin.Permitted([]string{"mysql_query"}))
+	assert.True(t, plugin.Permitted([]string{"mysql_query_long-queries"}))
+	assert.True(t, plugin.Permitted([]string{"*"}))
+	assert.False(t, plugin.Permitted([]string{"mysql_query_innodb-status", "global_read_only"}))
+
+	assert.True(t, plugin.AllowsHost("db-core-001"))
+	plugin.Hosts = []string{"db-core-002"}
+	assert.False(t, plugin.AllowsHost("db-core-001"))
+}
+
+func TestMySQLQueryPluginValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(p *MySQLQueryPlugin)
+		fields []string
+	}{
+		{name: "valid", modify: func(p *MySQLQueryPlugin) {}},
+		{name: "show", modify: func(p *MySQLQueryPlugin) { p.Query, p.Parameters = "SHOW ENGINE INNODB STATUS;", nil }},
+		{name: "write", modify: func(p *MySQLQueryPlugin) { p.Query, p.Parameters = "DELETE FROM users", nil }, fields: []string{"mySQLQueryPlugins[0].query"}},
+		{name: "stacked", modify: func(p *MySQLQueryPlugin) { p.Query, p.Parameters = "SELECT 1; DROP TABLE users", nil }, fields: []string{"mySQLQueryPlugins[0].query"}},
+		{name: "outfile", modify: func(p *MySQLQueryPlugin) { p.Query, p.Parameters = "SELECT * FROM users INTO OUTFILE '/tmp/u'", nil }, fields: []string{"mySQLQueryPlugins[0].query"}},
+		{name: "locking", modify: func(p *MySQLQueryPlugin) { p.Query, p.Parameters = "SELECT * FROM users FOR UPDATE", nil }, fields: []string{"mySQLQueryPlugins[0].query"}},
+		{
+			name:   "quoted parameter",
+			modify: func(p *MySQLQueryPlugin) { p.Query = "SELECT * FROM t WHERE a > {{seconds}} AND b = '{{user}}'" },
+			fields: []string{"mySQLQueryPlugins[0].query"},
+		},
+		{
+			name:   "undeclared parameter",
+			modify: func(p *MySQLQueryPlugin) { p.Query += " OFFSET {{offset}}" },
+			fields: []string{"mySQLQueryPlugins[0].query"},
+		},
+		{
+			name:   "limits",
+			modify: func(p *MySQLQueryPlugin) { p.MaxRows, p.TimeoutSeconds = MaxMySQLQueryPluginMaxRows+1, -1 },
+			fields: []string{"mySQLQueryPlugins[0].maxRows", "mySQLQueryPlugins[0].timeoutSeconds"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := validDynamicConfig()
+			plugin := longQueriesPlugin()
+			tt.modify(&plugin)
+			c.MySQLQueryPlugins = []MySQLQueryPlugin{plugin}
+
+			fields := []string{}
+			for _, e := range c.Validate() {
+				fields = append(fields, e.Field)
+			}
+			if tt.fields == nil {
+				assert.Empty(t, fields)
+			} else {
+				assert.Equal(t, tt.fields, fields)
+			}
+		})
+	}
+}
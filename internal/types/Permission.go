@@ -10,6 +10,10 @@ import (
 	"gorm.io/gorm"
 )
 
+// BuiltinAdminID is the ID of the built-in AllAdmin permission and Admin group created by the initial migration.
+// They cannot be deleted.
+const BuiltinAdminID = "1b434611-5fe8-4ed0-b0b4-1307f9945b34"
+
 // Permission represents a permission on the khub application.
 // The permission is used to indicate which app's can be accessed by a group.
 type Permission struct {
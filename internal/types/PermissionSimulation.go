@@ -0,0 +1,43 @@
+package types
+
+// PermissionGrant describes a permission tag held by a user and where it came from.
+// Group is empty for permissions that are not granted through a group (admin access and global read only).
+type PermissionGrant struct {
+	AppTag     string `json:"appTag"`
+	Permission string `json:"permission"`
+	Group      string `json:"group"`
+}
+
+// SimulatedResourceAccess describes the access a user has to a single k8s resource and the grants that allow it
+type SimulatedResourceAccess struct {
+	Namespace string            `json:"namespace"`
+	Name      string            `json:"name"`
+	Write     bool              `json:"write"`
+	GrantedBy []PermissionGrant `json:"grantedBy"`
+}
+
+// PermissionSimulation is the result of evaluating the authorization path of a user against a cached k8s resource type
+type PermissionSimulation struct {
+	User           string                    `json:"user"`
+	IsAdmin        bool                      `json:"isAdmin"`
+	Deactivated    bool                      `json:"deactivated"`
+	GlobalReadOnly bool                      `json:"globalReadOnly"`
+	Resource       string                    `json:"resource"`
+	Grants         []PermissionGrant         `json:"grants"`
+	Resources      []SimulatedResourceAccess `json:"resources"`
+}
+
+// ResourceWriter is a user that can write a k8s resource and the grants that allow it
+type ResourceWriter struct {
+	User      string            `json:"user"`
+	Email     string            `json:"email"`
+	GrantedBy []PermissionGrant `json:"grantedBy"`
+}
+
+// ResourceWriters is the result of the inverse permission query: every user that can write a k8s resource
+type ResourceWriters struct {
+	Resource  string           `json:"resource"`
+	Namespace string           `json:"namespace"`
+	Name      string           `json:"name"`
+	Writers   []ResourceWriter `json:"writers"`
+}
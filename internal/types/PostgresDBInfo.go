@@ -0,0 +1,83 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Defaults of Postgres catalog hosts
+const (
+	DefaultPostgresDatabase = "postgres"
+	DefaultPostgresPort     = 5432
+)
+
+// PostgresDBInfo represents a Postgres database of the catalog. Its replication status is captured into a ReplDBInfo,
+// streaming replicas and logical subscriptions are both replication channels.
+type PostgresDBInfo struct {
+	Host      string `json:"host" gorm:"primaryKey"`
+	Shortname string `json:"shortName"`
+	Username  string `json:"username"`
+	Port      int    `json:"port"`
+	// Database is the database khub connects to. Subscriptions are only listed for the database they are created in.
+	Database   string         `json:"database" gorm:"not null;default:postgres"`
+	IsPrimary  bool           `json:"isPrimary"`
+	Credential CredentialRef  `json:"credential" gorm:"embedded;embeddedPrefix:credential_"`
+	CreatedAt  time.Time      `json:"createdAt"`
+	UpdatedAt  time.Time      `json:"updatedAt"`
+	DeletedAt  gorm.DeletedAt `gorm:"index"`
+}
+
+func (d *PostgresDBInfo) IsValid() (bool, string) {
+	errors := strings.Builder{}
+	if d.Host == "" {
+		errors.WriteString(fmt.Sprintln("Host is invalid. Must not be empty."))
+	}
+
+	if d.Shortname == "" {
+		errors.WriteString(fmt.Sprintln("Shortname is invalid. Must not be empty"))
+	}
+
+	if d.Username == "" {
+		errors.WriteString(fmt.Sprintln("Username is invalid. Must not be empty"))
+	}
+
+	if d.Port < 1 || d.Port > 65535 {
+		errors.WriteString(fmt.Sprintln("Port is invalid. Must be between 1 and 65535"))
+	}
+
+	if valid, errMsg := d.Credential.IsValid(); !valid {
+		errors.WriteString(errMsg)
+	}
+
+	errMsg := errors.String()
+	if len(errMsg) > 0 {
+		return false, errMsg
+	}
+
+	return true, ""
+}
+
+// DatabaseName returns the database khub connects to
+func (d PostgresDBInfo) DatabaseName() string {
+	if d.Database == "" {
+		return DefaultPostgresDatabase
+	}
+	return d.Database
+}
+
+// TopologyNode returns the node of the database in the replication topology graph, without its replication status
+func (d PostgresDBInfo) TopologyNode() *ReplDBInfo {
+	return &ReplDBInfo{
+		Host:       d.Host,
+		Shortname:  d.Shortname,
+		Username:   d.Username,
+		Port:       d.Port,
+		IsPrimary:  d.IsPrimary,
+		Credential: d.Credential,
+		CreatedAt:  d.CreatedAt,
+		UpdatedAt:  d.UpdatedAt,
+	}
+}
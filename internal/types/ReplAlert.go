@@ -0,0 +1,370 @@
+package types
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Kinds of replication alert rules
+const (
+	ReplAlertReplicationStopped = "replication_stopped"
+	ReplAlertLag                = "lag"
+	ReplAlertSourceChanged      = "source_changed"
+	ReplAlertHostUnreachable    = "host_unreachable"
+)
+
+// Types of replication alert receivers
+const (
+	// ReplAlertReceiverSlack posts Slack compatible messages, such as to a Slack incoming webhook
+	ReplAlertReceiverSlack = "slack"
+	// ReplAlertReceiverWebhook posts the alert as json
+	ReplAlertReceiverWebhook = "webhook"
+)
+
+// Statuses of replication alerts
+const (
+	ReplAlertFiring   = "firing"
+	ReplAlertResolved = "resolved"
+)
+
+// Replication topologies alerts are evaluated for
+const (
+	ReplTopologyMySQL    = "mysql"
+	ReplTopologyPostgres = "postgres"
+)
+
+// ReplAlertingConfig configures the alerts evaluated after each capture of the replication topologies, and where they
+// are sent
+type ReplAlertingConfig struct {
+	Rules     []ReplAlertRule     `json:"rules,omitempty"`
+	Receivers []ReplAlertReceiver `json:"receivers,omitempty"`
+}
+
+// ReplAlertRule alerts on the hosts of a replication topology. Each host fires a single alert per rule, which resolves
+// once the host no longer matches the rule. Source changes are events, they are notified once and never resolve.
+type ReplAlertRule struct {
+	Name string `json:"name"`
+	Kind string `json:"kind"`
+	// Topology is mysql or postgres. The rule applies to both topologies when it is empty.
+	Topology string `json:"topology,omitempty"`
+	// Hosts lists the shortnames of the hosts the rule applies to. It applies to every host when empty.
+	Hosts []string `json:"hosts,omitempty"`
+	// LagSeconds and LagBytes are the thresholds of lag rules. Hosts lagging more than either threshold fire, a threshold
+	// of 0 is not checked. LagBytes only applies to Postgres hosts.
+	LagSeconds int64 `json:"lagSeconds,omitempty"`
+	LagBytes   int64 `json:"lagBytes,omitempty"`
+	// Receivers lists the names of the receivers notified of the alerts of the rule. Every receiver is notified when empty.
+	Receivers []string `json:"receivers,omitempty"`
+}
+
+// ReplAlertReceiver is a webhook notified of replication alerts
+type ReplAlertReceiver struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
+// ReplAlert is an alert of a replication alert rule for a host. Its Fingerprint identifies it across captures, so an
+// alert is only notified when it starts firing and when it resolves.
+type ReplAlert struct {
+	Fingerprint string     `json:"fingerprint"`
+	Status      string     `json:"status"`
+	Rule        string     `json:"rule"`
+	Kind        string     `json:"kind"`
+	Topology    string     `json:"topology"`
+	Host        string     `json:"host"`
+	Summary     string     `json:"summary"`
+	StartsAt    time.Time  `json:"startsAt"`
+	EndsAt      *time.Time `json:"endsAt,omitempty"`
+}
+
+// ReplAlertState is what the alerting of a topology remembers between captures: the active alerts, by fingerprint, the
+// source of each reachable host, and the notifications that did not reach every receiver.
+type ReplAlertState struct {
+	Active  map[string]ReplAlert `json:"active"`
+	Sources map[string]string    `json:"sources"`
+	Retries []ReplAlertDelivery  `json:"retries,omitempty"`
+}
+
+// ReplAlertDelivery is the notification of an alert to the named receivers
+type ReplAlertDelivery struct {
+	Alert     ReplAlert `json:"alert"`
+	Receivers []string  `json:"receivers"`
+}
+
+// AppliesTo reports whether the rule applies to a host of a topology
+func (r ReplAlertRule) AppliesTo(topology, host string) bool {
+	return (r.Topology == "" || r.Topology == topology) && (len(r.Hosts) == 0 || slices.Contains(r.Hosts, host))
+}
+
+// Notifies reports whether a receiver is notified of the alerts of the rule
+func (r ReplAlertRule) Notifies(receiver string) bool {
+	return len(r.Receivers) == 0 || slices.Contains(r.Receivers, receiver)
+}
+
+// EvaluateReplAlertRules returns the alerts firing for a capture of a topology. Sources are the sources of the hosts at
+// the previous capture, a host without a previous source never fires source changes. Unreachable hosts only fire
+// host_unreachable rules, as their replication status is unknown.
+func EvaluateReplAlertRules(topology string, rules []ReplAlertRule, nodes []ReplTopoTreeNode, sources map[string]string, now time.Time) []ReplAlert {
+	alerts := []ReplAlert{}
+	for _, rule := range rules {
+		for _, node := range nodes {
+			if !rule.AppliesTo(topology, node.ID) {
+				continue
+			}
+			summary, firing := rule.evaluate(node, sources)
+			if !firing {
+				continue
+			}
+			alert := ReplAlert{
+				Fingerprint: fmt.Sprintf("%s/%s/%s", topology, rule.Name, node.ID),
+				Status:      ReplAlertFiring,
+				Rule:        rule.Name,
+				Kind:        rule.Kind,
+				Topology:    topology,
+				Host:        node.ID,
+				Summary:     summary,
+				StartsAt:    now,
+			}
+			if rule.Kind == ReplAlertSourceChanged {
+				alert.Fingerprint = fmt.Sprintf("%s/%s", alert.Fingerprint, node.Data.Source)
+			}
+			alerts = append(alerts, alert)
+		}
+	}
+	return alerts
+}
+
+// evaluate returns the summary of the alert of a host, and whether the host fires the rule
+func (r ReplAlertRule) evaluate(node ReplTopoTreeNode, sources map[string]string) (string, bool) {
+	db := node.Data
+	if r.Kind == ReplAlertHostUnreachable {
+		return fmt.Sprintf("%s is unreachable: %s", node.ID, db.ProbeError), db.ProbeError != ""
+	}
+	if db.ProbeError != "" {
+		return "", false
+	}
+
+	switch r.Kind {
+	case ReplAlertReplicationStopped:
+		if len(db.Channels) == 0 || db.ReplicationRunning {
+			return "", false
+		}
+		summary := fmt.Sprintf("replication of %s from %s stopped", node.ID, orNone(db.Source))
+		var lastError *ReplChannelError
+		for _, c := range db.Channels {
+			if err := c.LastError(); !c.Running() && err != nil && (lastError == nil || err.Timestamp.After(lastError.Timestamp)) {
+				lastError = err
+			}
+		}
+		if lastError != nil {
+			summary = fmt.Sprintf("%s: error %d %s", summary, lastError.Number, lastError.Message)
+		}
+		return summary, true
+	case ReplAlertLag:
+		lags := []string{}
+		if r.LagSeconds > 0 && db.SecondsBehindSource != nil && *db.SecondsBehindSource > r.LagSeconds {
+			lags = append(lags, fmt.Sprintf("%ds", *db.SecondsBehindSource))
+		}
+		if r.LagBytes > 0 && db.BytesBehindSource != nil && *db.BytesBehindSource > r.LagBytes {
+			lags = append(lags, fmt.Sprintf("%d bytes", *db.BytesBehindSource))
+		}
+		if len(lags) == 0 {
+			return "", false
+		}
+		return fmt.Sprintf("%s is %s behind %s", node.ID, strings.Join(lags, " and "), orNone(db.Source)), true
+	case ReplAlertSourceChanged:
+		previous, ok := sources[node.ID]
+		if !ok || previous == db.Source {
+			return "", false
+		}
+		return fmt.Sprintf("the source of %s changed from %s to %s", node.ID, orNone(previous), orNone(db.Source)), true
+	}
+	return "", false
+}
+
+// Reconcile records the alerts firing at a capture and the sources of its reachable hosts. It returns the alerts to
+// notify: the alerts that started firing, source changes, and the active alerts that no longer fire, as resolved.
+// The replication of unreachable hosts is unknown, so their active alerts stay active until they are reachable again.
+func (s *ReplAlertState) Reconcile(firing []ReplAlert, nodes []ReplTopoTreeNode, now time.Time) []ReplAlert {
+	if s.Active == nil {
+		s.Active = map[string]ReplAlert{}
+	}
+	if s.Sources == nil {
+		s.Sources = map[string]string{}
+	}
+
+	notify := []ReplAlert{}
+	firingByFingerprint := map[string]bool{}
+	for _, alert := range firing {
+		firingByFingerprint[alert.Fingerprint] = true
+		if alert.Kind == ReplAlertSourceChanged {
+			notify = append(notify, alert)
+			continue
+		}
+		if _, ok := s.Active[alert.Fingerprint]; !ok {
+			s.Active[alert.Fingerprint] = alert
+			notify = append(notify, alert)
+		}
+	}
+	unreachable := map[string]bool{}
+	for _, node := range nodes {
+		if node.Data.ProbeError != "" {
+			unreachable[node.ID] = true
+		}
+	}
+	for fingerprint, alert := range s.Active {
+		if firingByFingerprint[fingerprint] || (alert.Kind != ReplAlertHostUnreachable && unreachable[alert.Host]) {
+			continue
+		}
+		delete(s.Active, fingerprint)
+		endsAt := now
+		alert.Status = ReplAlertResolved
+		alert.EndsAt = &endsAt
+		notify = append(notify, alert)
+	}
+	slices.SortFunc(notify, func(a, b ReplAlert) int { return strings.Compare(a.Fingerprint, b.Fingerprint) })
+
+	for _, node := range nodes {
+		if node.Data.ProbeError == "" {
+			s.Sources[node.ID] = node.Data.Source
+		}
+	}
+	return notify
+}
+
+// Deliveries returns the notifications to send after a capture, and takes the retries out of the state. The alerts to
+// notify are delivered to the receivers of their rule, and retries to the receivers that did not get them. Retries of
+// an alert that is notified again, of removed rules and of removed receivers are dropped.
+func (s *ReplAlertState) Deliveries(notify []ReplAlert, cfg ReplAlertingConfig) []ReplAlertDelivery {
+	rules := map[string]ReplAlertRule{}
+	for _, r := range cfg.Rules {
+		rules[r.Name] = r
+	}
+	receivers := map[string]bool{}
+	for _, r := range cfg.Receivers {
+		receivers[r.Name] = true
+	}
+	notified := map[string]bool{}
+	for _, alert := range notify {
+		notified[alert.Fingerprint] = true
+	}
+
+	deliveries := []ReplAlertDelivery{}
+	for _, retry := range s.Retries {
+		rule, ok := rules[retry.Alert.Rule]
+		if !ok || notified[retry.Alert.Fingerprint] {
+			continue
+		}
+		names := []string{}
+		for _, name := range retry.Receivers {
+			if receivers[name] && rule.Notifies(name) {
+				names = append(names, name)
+			}
+		}
+		if len(names) > 0 {
+			deliveries = append(deliveries, ReplAlertDelivery{Alert: retry.Alert, Receivers: names})
+		}
+	}
+	s.Retries = nil
+
+	for _, alert := range notify {
+		rule, ok := rules[alert.Rule]
+		if !ok {
+			continue
+		}
+		names := []string{}
+		for _, r := range cfg.Receivers {
+			if rule.Notifies(r.Name) {
+				names = append(names, r.Name)
+			}
+		}
+		deliveries = append(deliveries, ReplAlertDelivery{Alert: alert, Receivers: names})
+	}
+	return deliveries
+}
+
+// Undelivered records the receivers a notification could not be delivered to, so it is sent to them again after the
+// next capture
+func (s *ReplAlertState) Undelivered(alert ReplAlert, receivers []string) {
+	s.Retries = append(s.Retries, ReplAlertDelivery{Alert: alert, Receivers: receivers})
+}
+
+// Redacted returns the alerting config without the urls of its receivers, which hold the credentials of their webhooks
+func (c ReplAlertingConfig) Redacted() ReplAlertingConfig {
+	receivers := make([]ReplAlertReceiver, len(c.Receivers))
+	for i, r := range c.Receivers {
+		r.URL = ""
+		receivers[i] = r
+	}
+	if c.Receivers == nil {
+		receivers = nil
+	}
+	return ReplAlertingConfig{Rules: c.Rules, Receivers: receivers}
+}
+
+// validate returns the validation errors of the alerting config, keyed by the field they apply to
+func (c ReplAlertingConfig) validate(field string) []ConfigFieldError {
+	errs := []ConfigFieldError{}
+	add := func(field, format string, args ...any) {
+		errs = append(errs, ConfigFieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	receivers := map[string]bool{}
+	for i, r := range c.Receivers {
+		field := fmt.Sprintf("%s.receivers[%d]", field, i)
+		if !pluginNameRegex.MatchString(r.Name) {
+			add(field+".name", "must be alphanumeric, '-' or '_', and start with an alphanumeric character")
+		}
+		if receivers[r.Name] {
+			add(field+".name", "receiver %q is declared more than once", r.Name)
+		}
+		receivers[r.Name] = true
+		if r.Type != ReplAlertReceiverSlack && r.Type != ReplAlertReceiverWebhook {
+			add(field+".type", "must be one of %s or %s", ReplAlertReceiverSlack, ReplAlertReceiverWebhook)
+		}
+		if u, err := url.Parse(r.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			add(field+".url", "must be an http or https url")
+		}
+	}
+
+	rules := map[string]bool{}
+	for i, r := range c.Rules {
+		field := fmt.Sprintf("%s.rules[%d]", field, i)
+		if !pluginNameRegex.MatchString(r.Name) {
+			add(field+".name", "must be alphanumeric, '-' or '_', and start with an alphanumeric character")
+		}
+		if rules[r.Name] {
+			add(field+".name", "rule %q is declared more than once", r.Name)
+		}
+		rules[r.Name] = true
+
+		switch r.Kind {
+		case ReplAlertReplicationStopped, ReplAlertSourceChanged, ReplAlertHostUnreachable:
+		case ReplAlertLag:
+			if r.LagSeconds <= 0 && r.LagBytes <= 0 {
+				add(field, "lag rules must set lagSeconds or lagBytes")
+			}
+		default:
+			add(field+".kind", "must be one of %s", strings.Join([]string{ReplAlertReplicationStopped, ReplAlertLag, ReplAlertSourceChanged, ReplAlertHostUnreachable}, ", "))
+		}
+		if r.Topology != "" && r.Topology != ReplTopologyMySQL && r.Topology != ReplTopologyPostgres {
+			add(field+".topology", "must be %s or %s", ReplTopologyMySQL, ReplTopologyPostgres)
+		}
+		if r.LagSeconds < 0 {
+			add(field+".lagSeconds", "must not be negative")
+		}
+		if r.LagBytes < 0 {
+			add(field+".lagBytes", "must not be negative")
+		}
+		for _, name := range r.Receivers {
+			if !receivers[name] {
+				add(field+".receivers", "receiver %q is not declared", name)
+			}
+		}
+	}
+	return errs
+}
@@ -0,0 +1,180 @@
+package types
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func replAlertNodes() []ReplTopoTreeNode {
+	lag := int64(600)
+	return []ReplTopoTreeNode{
+		{ID: "db-core-007", Data: ReplDBInfo{Shortname: "db-core-007"}},
+		{ID: "db-core-008", Data: ReplDBInfo{
+			Shortname: "db-core-008", Source: "db-core-007",
+			Channels: []ReplChannel{{Name: "", Source: "db-core-007", IOState: "ON", SQLState: "OFF",
+				LastSQLError: &ReplChannelError{Number: 1062, Message: "Duplicate entry", Timestamp: time.Date(2024, 5, 1, 11, 0, 0, 0, time.UTC)}}},
+		}},
+		{ID: "db-core-009", Data: ReplDBInfo{
+			Shortname: "db-core-009", Source: "db-core-008", ReplicationRunning: true, SecondsBehindSource: &lag,
+			Channels: []ReplChannel{{Name: "", Source: "db-core-008", IOState: "ON", SQLState: "ON", SecondsBehindSource: &lag}},
+		}},
+		{ID: "db-core-010", Data: ReplDBInfo{Shortname: "db-core-010", ProbeError: "unable to connect: i/o timeout"}},
+	}
+}
+
+func TestEvaluateReplAlertRules(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	rules := []ReplAlertRule{
+		{Name: "stopped", Kind: ReplAlertReplicationStopped},
+		{Name: "lag", Kind: ReplAlertLag, LagSeconds: 300},
+		{Name: "unreachable", Kind: ReplAlertHostUnreachable},
+		{Name: "source", Kind: ReplAlertSourceChanged},
+		{Name: "postgres-lag", Kind: ReplAlertLag, Topology: ReplTopologyPostgres, LagSeconds: 1},
+		{Name: "core-007-unreachable", Kind: ReplAlertHostUnreachable, Hosts: []string{"db-core-007"}},
+	}
+	sources := map[string]string{"db-core-007": "", "db-core-008": "db-core-007", "db-core-009": "db-core-007", "db-core-010": "db-core-007"}
+
+	alerts := EvaluateReplAlertRules(ReplTopologyMySQL, rules, replAlertNodes(), sources, now)
+	require.Len(t, alerts, 4)
+	assert.Equal(t, "mysql/stopped/db-core-008", alerts[0].Fingerprint)
+	assert.Equal(t, "replication of db-core-008 from db-core-007 stopped: error 1062 Duplicate entry", alerts[0].Summary)
+	assert.Equal(t, "db-core-009 is 600s behind db-core-008", alerts[1].Summary)
+	assert.Equal(t, "db-core-010 is unreachable: unable to connect: i/o timeout", alerts[2].Summary)
+	assert.Equal(t, "mysql/source/db-core-009/db-core-008", alerts[3].Fingerprint)
+	assert.Equal(t, "the source of db-core-009 changed from db-core-007 to db-core-008", alerts[3].Summary)
+	assert.Equal(t, now, alerts[3].StartsAt)
+}
+
+func TestReplAlertStateReconcile(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	rules := []ReplAlertRule{{Name: "stopped", Kind: ReplAlertReplicationStopped}, {Name: "source", Kind: ReplAlertSourceChanged}}
+	nodes := replAlertNodes()
+	state := ReplAlertState{}
+
+	// The first capture notifies stopped replication, and only records sources
+	notify := state.Reconcile(EvaluateReplAlertRules(ReplTopologyMySQL, rules, nodes, state.Sources, start), nodes, start)
+	require.Len(t, notify, 1)
+	assert.Equal(t, ReplAlertFiring, notify[0].Status)
+	assert.Equal(t, map[string]string{"db-core-007": "", "db-core-008": "db-core-007", "db-core-009": "db-core-008"}, state.Sources)
+
+	// Alerts still firing are not notified again
+	next := start.Add(5 * time.Minute)
+	assert.Empty(t, state.Reconcile(EvaluateReplAlertRules(ReplTopologyMySQL, rules, nodes, state.Sources, next), nodes, next))
+
+	// Replication recovers on a new source
+	nodes[1].Data.ReplicationRunning = true
+	nodes[1].Data.Source = "db-core-009"
+	last := next.Add(5 * time.Minute)
+	notify = state.Reconcile(EvaluateReplAlertRules(ReplTopologyMySQL, rules, nodes, state.Sources, last), nodes, last)
+	require.Len(t, notify, 2)
+	assert.Equal(t, ReplAlertSourceChanged, notify[0].Kind)
+	assert.Equal(t, ReplAlertResolved, notify[1].Status)
+	assert.Equal(t, start, notify[1].StartsAt)
+	assert.Equal(t, last, *notify[1].EndsAt)
+	assert.Empty(t, state.Active)
+
+	assert.Empty(t, state.Retries)
+}
+
+func TestReplAlertStateDeliveries(t *testing.T) {
+	cfg := ReplAlertingConfig{
+		Rules: []ReplAlertRule{
+			{Name: "stopped", Kind: ReplAlertReplicationStopped},
+			{Name: "lag", Kind: ReplAlertLag, Receivers: []string{"dba"}},
+		},
+		Receivers: []ReplAlertReceiver{{Name: "dba"}, {Name: "oncall"}},
+	}
+	stopped := ReplAlert{Fingerprint: "mysql/stopped/db-core-008", Status: ReplAlertFiring, Rule: "stopped"}
+	lag := ReplAlert{Fingerprint: "mysql/lag/db-core-009", Status: ReplAlertFiring, Rule: "lag"}
+	state := ReplAlertState{}
+
+	deliveries := state.Deliveries([]ReplAlert{stopped, lag}, cfg)
+	assert.Equal(t, []ReplAlertDelivery{
+		{Alert: stopped, Receivers: []string{"dba", "oncall"}},
+		{Alert: lag, Receivers: []string{"dba"}},
+	}, deliveries)
+
+	// A notification that failed for one receiver is only sent to it again
+	state.Undelivered(stopped, []string{"oncall"})
+	deliveries = state.Deliveries([]ReplAlert{}, cfg)
+	assert.Equal(t, []ReplAlertDelivery{{Alert: stopped, Receivers: []string{"oncall"}}}, deliveries)
+	assert.Empty(t, state.Retries)
+
+	// Retries are dropped once the alert is notified again, or its receiver is removed
+	state.Undelivered(stopped, []string{"oncall"})
+	resolved := stopped
+	resolved.Status = ReplAlertResolved
+	deliveries = state.Deliveries([]ReplAlert{resolved}, cfg)
+	assert.Equal(t, []ReplAlertDelivery{{Alert: resolved, Receivers: []string{"dba", "oncall"}}}, deliveries)
+
+	state.Undelivered(stopped, []string{"oncall"})
+	cfg.Receivers = cfg.Receivers[:1]
+	assert.Empty(t, state.Deliveries([]ReplAlert{}, cfg))
+}
+
+func TestReplAlertStateReconcileUnreachable(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	rules := []ReplAlertRule{
+		{Name: "stopped", Kind: ReplAlertReplicationStopped},
+		{Name: "lag", Kind: ReplAlertLag, LagSeconds: 300},
+		{Name: "unreachable", Kind: ReplAlertHostUnreachable},
+	}
+	nodes := replAlertNodes()
+	state := ReplAlertState{}
+	require.Len(t, state.Reconcile(EvaluateReplAlertRules(ReplTopologyMySQL, rules, nodes, state.Sources, start), nodes, start), 3)
+
+	// Hosts that become unreachable only fire host_unreachable, their other alerts are neither resolved nor notified
+	nodes[1].Data.ProbeError = "unable to connect: i/o timeout"
+	nodes[2].Data.ProbeError = "unable to connect: i/o timeout"
+	next := start.Add(5 * time.Minute)
+	notify := state.Reconcile(EvaluateReplAlertRules(ReplTopologyMySQL, rules, nodes, state.Sources, next), nodes, next)
+	require.Len(t, notify, 2)
+	assert.Equal(t, "mysql/unreachable/db-core-008", notify[0].Fingerprint)
+	assert.Equal(t, "mysql/unreachable/db-core-009", notify[1].Fingerprint)
+	assert.Contains(t, state.Active, "mysql/stopped/db-core-008")
+	assert.Contains(t, state.Active, "mysql/lag/db-core-009")
+
+	// Once reachable again, the alerts resolve if the host recovered meanwhile
+	nodes = replAlertNodes()
+	nodes[1].Data.ReplicationRunning = true
+	last := next.Add(5 * time.Minute)
+	notify = state.Reconcile(EvaluateReplAlertRules(ReplTopologyMySQL, rules, nodes, state.Sources, last), nodes, last)
+	require.Len(t, notify, 3)
+	assert.Equal(t, "mysql/stopped/db-core-008", notify[0].Fingerprint)
+	assert.Equal(t, ReplAlertResolved, notify[0].Status)
+	assert.Equal(t, "mysql/unreachable/db-core-008", notify[1].Fingerprint)
+	assert.Equal(t, "mysql/unreachable/db-core-009", notify[2].Fingerprint)
+	assert.Contains(t, state.Active, "mysql/lag/db-core-009")
+}
+
+func TestReplAlertingConfigRedacted(t *testing.T) {
+	cfg := ReplAlertingConfig{Receivers: []ReplAlertReceiver{{Name: "dba", Type: ReplAlertReceiverSlack, URL: "https://hooks.slack.com/services/T000/B000/XXXX"}}}
+	assert.Equal(t, []ReplAlertReceiver{{Name: "dba", Type: ReplAlertReceiverSlack}}, cfg.Redacted().Receivers)
+	assert.Equal(t, "https://hooks.slack.com/services/T000/B000/XXXX", cfg.Receivers[0].URL)
+}
+
+func TestReplAlertingConfigValidate(t *testing.T) {
+	cfg := ReplAlertingConfig{
+		Receivers: []ReplAlertReceiver{{Name: "dba", Type: ReplAlertReceiverSlack, URL: "https://hooks.slack.com/services/T000/B000/XXXX"}},
+		Rules:     []ReplAlertRule{{Name: "lag", Kind: ReplAlertLag, LagSeconds: 300, Receivers: []string{"dba"}}},
+	}
+	assert.Empty(t, cfg.validate("replicationAlerting"))
+
+	cfg.Receivers = append(cfg.Receivers, ReplAlertReceiver{Name: "dba", Type: "email", URL: "mailto:dba@example.com"})
+	cfg.Rules = append(cfg.Rules,
+		ReplAlertRule{Name: "slow", Kind: ReplAlertLag, Topology: "oracle", Receivers: []string{"pager"}},
+		ReplAlertRule{Name: "stopped", Kind: "stopped"},
+	)
+	fields := []string{}
+	for _, e := range cfg.validate("replicationAlerting") {
+		fields = append(fields, e.Field)
+	}
+	assert.Equal(t, []string{
+		"replicationAlerting.receivers[1].name", "replicationAlerting.receivers[1].type", "replicationAlerting.receivers[1].url",
+		"replicationAlerting.rules[1]", "replicationAlerting.rules[1].topology", "replicationAlerting.rules[1].receivers",
+		"replicationAlerting.rules[2].kind",
+	}, fields)
+}
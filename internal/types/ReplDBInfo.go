@@ -0,0 +1,209 @@
+package types
+
+import (
+	"time"
+)
+
+// ReplDBInfo is a node of the replication topology graph: a MySQL or Postgres catalog host, or a binlog consumer, with
+// the replication status read from it when the topology is captured.
+type ReplDBInfo struct {
+	Host               string        `json:"host"`
+	Shortname          string        `json:"shortName"`
+	Username           string        `json:"username"`
+	Port               int           `json:"port"`
+	IsPrimary          bool          `json:"isPrimary"`
+	Credential         CredentialRef `json:"credential"`
+	Replicas           []string      `json:"replicas"`
+	Source             string        `json:"source"`
+	ReplicationRunning bool          `json:"replication_running"`
+	// Channels, ExecutedGTIDSet and SecondsBehindSource are read from the host when the topology is captured.
+	// SecondsBehindSource is the highest lag of its channels, nil when it is unknown.
+	Channels            []ReplChannel `json:"channels"`
+	ExecutedGTIDSet     string        `json:"executedGtidSet"`
+	SecondsBehindSource *int64        `json:"secondsBehindSource"`
+	// BytesBehindSource is the highest lag of its channels in bytes of WAL, which is only known for Postgres hosts
+	BytesBehindSource *int64 `json:"bytesBehindSource,omitempty"`
+	// ProbeError is why the replication status of the host could not be read during the capture
+	ProbeError string `json:"probeError,omitempty"`
+	// ConsumerType is the type of binlog consumers captured as nodes, such as dms or cdc. It is empty for catalog hosts.
+	ConsumerType string    `json:"consumerType,omitempty"`
+	CreatedAt    time.Time `json:"createdAt"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+}
+
+// SetSource sets the replication source of the database
+func (db *ReplDBInfo) SetSource(host string) {
+	db.Source = host
+}
+
+// SetReplicationRunning sets the replication status of the database to running
+func (db *ReplDBInfo) SetReplicationRunning(running bool) {
+	db.ReplicationRunning = running
+}
+
+// SetReplicationChannels records the replication channels of the database. The source of the database is the source of
+// its first channel, and replication is running when every channel is running.
+func (db *ReplDBInfo) SetReplicationChannels(channels []ReplChannel) {
+	db.Channels = channels
+	db.SecondsBehindSource = nil
+	db.BytesBehindSource = nil
+	running := len(channels) > 0
+	for _, c := range channels {
+		if db.Source == "" && c.Source != "" {
+			db.SetSource(c.Source)
+		}
+		running = running && c.Running()
+		db.SecondsBehindSource = maxLag(db.SecondsBehindSource, c.SecondsBehindSource)
+		db.BytesBehindSource = maxLag(db.BytesBehindSource, c.BytesBehindSource)
+	}
+	db.SetReplicationRunning(running)
+}
+
+// ChannelFrom returns the replication channel the database replicates from the source through, if any
+func (db *ReplDBInfo) ChannelFrom(source string) (ReplChannel, bool) {
+	for _, c := range db.Channels {
+		if c.Source == source {
+			return c, true
+		}
+	}
+	return ReplChannel{}, false
+}
+
+// AddReplica adds a replica to the database
+func (db *ReplDBInfo) AddReplica(replica string) {
+	db.Replicas = append(db.Replicas, replica)
+}
+
+// Replication health of topology nodes and edges
+const (
+	ReplicationHealthy   = "healthy"
+	ReplicationUnhealthy = "unhealthy"
+	ReplicationUnknown   = "unknown"
+)
+
+// Kinds of Postgres replication channels. MySQL channels have no kind.
+const (
+	ReplicationKindStreaming = "streaming"
+	ReplicationKindLogical   = "logical"
+)
+
+// ReplChannel is the status of a replication channel of a replica. MySQL channels are read from performance_schema,
+// Postgres streaming replication and logical subscriptions are described as channels too.
+type ReplChannel struct {
+	Name string `json:"name"`
+	// Kind is streaming or logical for Postgres channels
+	Kind string `json:"kind,omitempty"`
+	// SourceHost is the host the channel connects to, Source is the catalog shortname it resolves to
+	SourceHost string `json:"sourceHost"`
+	Source     string `json:"source"`
+	// IOState and SQLState are the service states of the receiver and applier threads: ON, OFF or CONNECTING
+	IOState  string `json:"ioState"`
+	SQLState string `json:"sqlState"`
+	// SecondsBehindSource is how long ago the transaction being applied was committed on the source. It is nil when
+	// either thread is not running, like Seconds_Behind_Source.
+	SecondsBehindSource *int64 `json:"secondsBehindSource"`
+	// BytesBehindSource is how much WAL the Postgres source wrote that the replica did not apply yet
+	BytesBehindSource *int64            `json:"bytesBehindSource,omitempty"`
+	ReceivedGTIDSet   string            `json:"receivedGtidSet"`
+	LastIOError       *ReplChannelError `json:"lastIOError,omitempty"`
+	LastSQLError      *ReplChannelError `json:"lastSQLError,omitempty"`
+}
+
+// ReplChannelError is the last error of a replication thread
+type ReplChannelError struct {
+	Number    int       `json:"number"`
+	Message   string    `json:"message"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Running reports whether both threads of the channel are running
+func (c ReplChannel) Running() bool {
+	return c.IOState == "ON" && c.SQLState == "ON"
+}
+
+// Healthy reports whether the channel is running without errors
+func (c ReplChannel) Healthy() bool {
+	return c.Running() && c.LastIOError == nil && c.LastSQLError == nil
+}
+
+// LastError returns the most recent error of the channel threads
+func (c ReplChannel) LastError() *ReplChannelError {
+	if c.LastIOError == nil || (c.LastSQLError != nil && c.LastSQLError.Timestamp.After(c.LastIOError.Timestamp)) {
+		return c.LastSQLError
+	}
+	return c.LastIOError
+}
+
+// ReplicationHealth summarizes the health of replication channels. It is unhealthy when any channel is unhealthy, and
+// unknown when there are no channels.
+func ReplicationHealth(channels ...ReplChannel) string {
+	if len(channels) == 0 {
+		return ReplicationUnknown
+	}
+	for _, c := range channels {
+		if !c.Healthy() {
+			return ReplicationUnhealthy
+		}
+	}
+	return ReplicationHealthy
+}
+
+type ReplTopoTreeNode struct {
+	ID       string                   `json:"id"`
+	Data     ReplDBInfo               `json:"data"`
+	Health   string                   `json:"health"`
+	Position ReplTopoTreeNodePosition `json:"position"`
+}
+
+type ReplTopoTreeNodePosition struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+// ReplTopoTreeEdge is a replication link. Health, SecondsBehindSource, BytesBehindSource and LastError describe the
+// channels of the link, both directions of bidirectional links.
+type ReplTopoTreeEdge struct {
+	ID                  string            `json:"id"`
+	Source              string            `json:"source"`
+	Target              string            `json:"target"`
+	EdgeType            string            `json:"edgeType"`
+	Animated            bool              `json:"animated"`
+	Channels            []string          `json:"channels"`
+	Health              string            `json:"health"`
+	SecondsBehindSource *int64            `json:"secondsBehindSource"`
+	LastError           *ReplChannelError `json:"lastError,omitempty"`
+	// Kind is the kind of the Postgres channels of the link, streaming or logical
+	Kind              string `json:"kind,omitempty"`
+	BytesBehindSource *int64 `json:"bytesBehindSource,omitempty"`
+}
+
+// SetChannels describes the edge with the replication channels of its link
+func (e *ReplTopoTreeEdge) SetChannels(channels ...ReplChannel) {
+	e.Channels = []string{}
+	e.Health = ReplicationHealth(channels...)
+	for _, c := range channels {
+		e.Channels = append(e.Channels, c.Name)
+		e.SecondsBehindSource = maxLag(e.SecondsBehindSource, c.SecondsBehindSource)
+		e.BytesBehindSource = maxLag(e.BytesBehindSource, c.BytesBehindSource)
+		if c.Kind != "" {
+			e.Kind = c.Kind
+		}
+		if err := c.LastError(); err != nil && (e.LastError == nil || err.Timestamp.After(e.LastError.Timestamp)) {
+			e.LastError = err
+		}
+	}
+}
+
+// maxLag returns a copy of the highest of two lags, nil when both are unknown
+func maxLag(current, lag *int64) *int64 {
+	if lag == nil || (current != nil && *current >= *lag) {
+		return current
+	}
+	max := *lag
+	return &max
+}
+
+type ReplTopoTree struct {
+	Nodes []ReplTopoTreeNode `json:"nodes"`
+	Edges []ReplTopoTreeEdge `json:"edges"`
+}
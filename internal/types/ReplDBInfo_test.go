@@ -0,0 +1,40 @@
+package types
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReplicationEdgeChannels(t *testing.T) {
+	lag := int64(42)
+	erroredAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	healthy := ReplChannel{Name: "", Source: "db-1", IOState: "ON", SQLState: "ON", SecondsBehindSource: &lag}
+	broken := ReplChannel{Name: "reverse", Source: "db-2", IOState: "ON", SQLState: "OFF",
+		LastSQLError: &ReplChannelError{Number: 1062, Message: "duplicate entry", Timestamp: erroredAt}}
+
+	edge := ReplTopoTreeEdge{}
+	edge.SetChannels(healthy)
+	assert.Equal(t, ReplicationHealthy, edge.Health)
+	assert.Equal(t, int64(42), *edge.SecondsBehindSource)
+
+	edge = ReplTopoTreeEdge{}
+	edge.SetChannels(healthy, broken)
+	assert.Equal(t, ReplicationUnhealthy, edge.Health)
+	assert.Equal(t, []string{"", "reverse"}, edge.Channels)
+	assert.Equal(t, 1062, edge.LastError.Number)
+
+	edge = ReplTopoTreeEdge{}
+	edge.SetChannels()
+	assert.Equal(t, ReplicationUnknown, edge.Health)
+
+	db := ReplDBInfo{}
+	db.SetReplicationChannels([]ReplChannel{healthy, broken})
+	assert.Equal(t, "db-1", db.Source)
+	assert.False(t, db.ReplicationRunning)
+	assert.Equal(t, int64(42), *db.SecondsBehindSource)
+	channel, ok := db.ChannelFrom("db-2")
+	assert.True(t, ok)
+	assert.Equal(t, "reverse", channel.Name)
+}
@@ -0,0 +1,113 @@
+package types
+
+import (
+	"fmt"
+	"time"
+)
+
+// Kinds of changes between two captures of the replication topology
+const (
+	ReplTopoChangeNodeAdded     = "node_added"
+	ReplTopoChangeNodeRemoved   = "node_removed"
+	ReplTopoChangeSourceChanged = "source_changed"
+	ReplTopoChangeLinkAdded     = "link_added"
+	ReplTopoChangeLinkRemoved   = "link_removed"
+	ReplTopoChangeLinkBroken    = "link_broken"
+	ReplTopoChangeLinkRecovered = "link_recovered"
+)
+
+// ReplTopoSnapshot is a capture of the replication topology, stored so the topology can be shown at a past time.
+// Changes lists what changed since the previous snapshot.
+type ReplTopoSnapshot struct {
+	ID         uint               `json:"id" gorm:"primaryKey"`
+	CapturedAt time.Time          `json:"capturedAt" gorm:"index"`
+	Nodes      []ReplTopoTreeNode `json:"nodes" gorm:"type:jsonb;serializer:json"`
+	Edges      []ReplTopoTreeEdge `json:"edges" gorm:"type:jsonb;serializer:json"`
+	Changes    []ReplTopoChange   `json:"changes" gorm:"type:jsonb;serializer:json"`
+}
+
+// ReplTopoChange is a change of a node or an edge of the replication topology
+type ReplTopoChange struct {
+	Kind   string `json:"kind"`
+	Node   string `json:"node,omitempty"`
+	Edge   string `json:"edge,omitempty"`
+	From   string `json:"from,omitempty"`
+	To     string `json:"to,omitempty"`
+	Detail string `json:"detail"`
+}
+
+// ChangesSince returns the changes of the snapshot since the previous one. The first snapshot has no previous snapshot
+// to compare to, so it has no changes, rather than reporting every node as added.
+func (s ReplTopoSnapshot) ChangesSince(previous *ReplTopoSnapshot) []ReplTopoChange {
+	if previous == nil {
+		return []ReplTopoChange{}
+	}
+	return DiffReplTopology(previous.Nodes, previous.Edges, s.Nodes, s.Edges)
+}
+
+// DiffReplTopology returns the changes from the previous capture of the topology to the next one
+func DiffReplTopology(prevNodes []ReplTopoTreeNode, prevEdges []ReplTopoTreeEdge, nextNodes []ReplTopoTreeNode, nextEdges []ReplTopoTreeEdge) []ReplTopoChange {
+	changes := []ReplTopoChange{}
+
+	prevNodeByID := map[string]ReplTopoTreeNode{}
+	for _, n := range prevNodes {
+		prevNodeByID[n.ID] = n
+	}
+	nextNodeByID := map[string]ReplTopoTreeNode{}
+	for _, n := range nextNodes {
+		nextNodeByID[n.ID] = n
+
+		prev, ok := prevNodeByID[n.ID]
+		switch {
+		case !ok:
+			changes = append(changes, ReplTopoChange{Kind: ReplTopoChangeNodeAdded, Node: n.ID, Detail: fmt.Sprintf("%s was added", n.ID)})
+		case prev.Data.Source != n.Data.Source:
+			changes = append(changes, ReplTopoChange{
+				Kind: ReplTopoChangeSourceChanged, Node: n.ID, From: prev.Data.Source, To: n.Data.Source,
+				Detail: fmt.Sprintf("the source of %s changed from %s to %s", n.ID, orNone(prev.Data.Source), orNone(n.Data.Source)),
+			})
+		}
+	}
+	for _, n := range prevNodes {
+		if _, ok := nextNodeByID[n.ID]; !ok {
+			changes = append(changes, ReplTopoChange{Kind: ReplTopoChangeNodeRemoved, Node: n.ID, Detail: fmt.Sprintf("%s was removed", n.ID)})
+		}
+	}
+
+	prevEdgeByID := map[string]ReplTopoTreeEdge{}
+	for _, e := range prevEdges {
+		prevEdgeByID[e.ID] = e
+	}
+	nextEdgeByID := map[string]ReplTopoTreeEdge{}
+	for _, e := range nextEdges {
+		nextEdgeByID[e.ID] = e
+
+		prev, ok := prevEdgeByID[e.ID]
+		switch {
+		case !ok:
+			changes = append(changes, ReplTopoChange{Kind: ReplTopoChangeLinkAdded, Edge: e.ID, From: e.Source, To: e.Target, Detail: fmt.Sprintf("%s now replicates to %s", e.Source, e.Target)})
+		case prev.Health != ReplicationUnhealthy && e.Health == ReplicationUnhealthy:
+			detail := fmt.Sprintf("replication from %s to %s broke", e.Source, e.Target)
+			if e.LastError != nil {
+				detail = fmt.Sprintf("%s: error %d %s", detail, e.LastError.Number, e.LastError.Message)
+			}
+			changes = append(changes, ReplTopoChange{Kind: ReplTopoChangeLinkBroken, Edge: e.ID, From: e.Source, To: e.Target, Detail: detail})
+		case prev.Health == ReplicationUnhealthy && e.Health == ReplicationHealthy:
+			changes = append(changes, ReplTopoChange{Kind: ReplTopoChangeLinkRecovered, Edge: e.ID, From: e.Source, To: e.Target, Detail: fmt.Sprintf("replication from %s to %s recovered", e.Source, e.Target)})
+		}
+	}
+	for _, e := range prevEdges {
+		if _, ok := nextEdgeByID[e.ID]; !ok {
+			changes = append(changes, ReplTopoChange{Kind: ReplTopoChangeLinkRemoved, Edge: e.ID, From: e.Source, To: e.Target, Detail: fmt.Sprintf("%s no longer replicates to %s", e.Source, e.Target)})
+		}
+	}
+
+	return changes
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "none"
+	}
+	return s
+}
@@ -0,0 +1,64 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffReplTopology(t *testing.T) {
+	prevNodes := []ReplTopoTreeNode{
+		{ID: "db-1"},
+		{ID: "db-2", Data: ReplDBInfo{Source: "db-1"}},
+		{ID: "db-3", Data: ReplDBInfo{Source: "db-1"}},
+	}
+	prevEdges := []ReplTopoTreeEdge{
+		{ID: "db-1-db-2", Source: "db-1", Target: "db-2", Health: ReplicationHealthy},
+		{ID: "db-1-db-3", Source: "db-1", Target: "db-3", Health: ReplicationUnhealthy},
+	}
+	nextNodes := []ReplTopoTreeNode{
+		{ID: "db-1"},
+		{ID: "db-2", Data: ReplDBInfo{Source: "db-4"}},
+		{ID: "db-4", Data: ReplDBInfo{Source: "db-1"}},
+	}
+	nextEdges := []ReplTopoTreeEdge{
+		{ID: "db-4-db-2", Source: "db-4", Target: "db-2", Health: ReplicationUnhealthy, LastError: &ReplChannelError{Number: 1236, Message: "binlog purged"}},
+		{ID: "db-1-db-4", Source: "db-1", Target: "db-4", Health: ReplicationHealthy},
+	}
+
+	changes := DiffReplTopology(prevNodes, prevEdges, nextNodes, nextEdges)
+
+	kinds := map[string][]string{}
+	for _, c := range changes {
+		kinds[c.Kind] = append(kinds[c.Kind], c.Node+c.Edge)
+	}
+	assert.Equal(t, []string{"db-4"}, kinds[ReplTopoChangeNodeAdded])
+	assert.Equal(t, []string{"db-3"}, kinds[ReplTopoChangeNodeRemoved])
+	assert.Equal(t, []string{"db-2"}, kinds[ReplTopoChangeSourceChanged])
+	assert.Equal(t, []string{"db-4-db-2", "db-1-db-4"}, kinds[ReplTopoChangeLinkAdded])
+	assert.Equal(t, []string{"db-1-db-2", "db-1-db-3"}, kinds[ReplTopoChangeLinkRemoved])
+	assert.Contains(t, changes, ReplTopoChange{
+		Kind: ReplTopoChangeSourceChanged, Node: "db-2", From: "db-1", To: "db-4",
+		Detail: "the source of db-2 changed from db-1 to db-4",
+	})
+}
+
+func TestDiffReplTopologyLinkHealth(t *testing.T) {
+	nodes := []ReplTopoTreeNode{{ID: "db-1"}, {ID: "db-2", Data: ReplDBInfo{Source: "db-1"}}}
+	healthy := []ReplTopoTreeEdge{{ID: "db-1-db-2", Source: "db-1", Target: "db-2", Health: ReplicationHealthy}}
+	broken := []ReplTopoTreeEdge{{ID: "db-1-db-2", Source: "db-1", Target: "db-2", Health: ReplicationUnhealthy,
+		LastError: &ReplChannelError{Number: 1236, Message: "binlog purged"}}}
+
+	assert.Equal(t, []ReplTopoChange{{
+		Kind: ReplTopoChangeLinkBroken, Edge: "db-1-db-2", From: "db-1", To: "db-2",
+		Detail: "replication from db-1 to db-2 broke: error 1236 binlog purged",
+	}}, DiffReplTopology(nodes, healthy, nodes, broken))
+	assert.Equal(t, ReplTopoChangeLinkRecovered, DiffReplTopology(nodes, broken, nodes, healthy)[0].Kind)
+	assert.Empty(t, DiffReplTopology(nodes, healthy, nodes, healthy))
+
+	// The first capture has no previous capture to diff against, later ones do
+	next := ReplTopoSnapshot{Nodes: nodes, Edges: broken}
+	assert.Empty(t, next.ChangesSince(nil))
+	assert.NotNil(t, next.ChangesSince(nil))
+	assert.Equal(t, ReplTopoChangeLinkBroken, next.ChangesSince(&ReplTopoSnapshot{Nodes: nodes, Edges: healthy})[0].Kind)
+}
@@ -0,0 +1,103 @@
+package types
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// maxReportReasonLength is the maximum length of the Reason tag of an uploaded report
+const maxReportReasonLength = 256
+
+var (
+	// reportNameRegex is the allowed format of uploaded report names. Names are a single path segment of the download route.
+	reportNameRegex = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]{0,254}$`)
+	// reportReasonRegex only allows the characters s3 accepts in tag values
+	reportReasonRegex = regexp.MustCompile(`^[a-zA-Z0-9 +\-=._:/@]+$`)
+)
+
+// ReportAccess is the identity reports are listed for. Reports are visible to the user that created them, and to users
+// holding the read or write permission tag of the report type.
+type ReportAccess struct {
+	Email       string
+	Permissions []string
+}
+
+// ReportPermissionTags returns the permission tags granting read and write access to reports of a type.
+// Write access allows uploading reports of the type, and implies read access.
+func ReportPermissionTags(reportType string) (string, string) {
+	t := strings.ToLower(reportType)
+	return fmt.Sprintf("reports_%s_read", t), fmt.Sprintf("reports_%s_write", t)
+}
+
+// CanRead reports whether the report is visible to the user
+func (a ReportAccess) CanRead(r Report) bool {
+	if r.OwnedBy(a.Email) {
+		return true
+	}
+	read, write := ReportPermissionTags(r.Type)
+	for _, p := range a.Permissions {
+		if p == "*" || p == "global_read_only" || p == read || p == write {
+			return true
+		}
+	}
+	return false
+}
+
+// CanUpload reports whether the user may upload reports of a type
+func (a ReportAccess) CanUpload(reportType string) bool {
+	_, write := ReportPermissionTags(reportType)
+	for _, p := range a.Permissions {
+		if p == "*" || p == write {
+			return true
+		}
+	}
+	return false
+}
+
+// OwnedBy reports whether the report was created by the user with the given email.
+// The User tag of older reports drops the @gmail.com domain.
+func (r Report) OwnedBy(email string) bool {
+	if email == "" || r.User == "" {
+		return false
+	}
+	return strings.EqualFold(r.User, email) || strings.EqualFold(r.User, strings.ReplaceAll(email, "@gmail.com", ""))
+}
+
+// ReportUploadRequest requests a link to upload a report. Reason and Type are required and recorded as tags of the report.
+type ReportUploadRequest struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+	Type   string `json:"type"`
+}
+
+// IsValid validates the report upload request
+func (r *ReportUploadRequest) IsValid() (bool, string) {
+	errors := strings.Builder{}
+	if !reportNameRegex.MatchString(r.Name) {
+		errors.WriteString(fmt.Sprintln("Report name is invalid. Must be at most 255 alphanumeric characters, '.', '-' or '_', and start with an alphanumeric character."))
+	}
+	if strings.TrimSpace(r.Reason) == "" || len(r.Reason) > maxReportReasonLength || !reportReasonRegex.MatchString(r.Reason) {
+		errors.WriteString(fmt.Sprintf("Report reason is required. Must be at most %d letters, numbers, spaces or + - = . _ : / @.\n", maxReportReasonLength))
+	}
+	if len(r.Type) > maxK8sNameLength || !reportTypeRegex.MatchString(r.Type) {
+		errors.WriteString(fmt.Sprintf("Report type is required. Must be at most %d alphanumeric characters, '-' or '_'.\n", maxK8sNameLength))
+	}
+
+	errMsg := errors.String()
+	if len(errMsg) > 0 {
+		return false, errMsg
+	}
+	return true, ""
+}
+
+// ReportUpload is a link to upload a report. The report must be sent as the body of a Method request to URL,
+// with every header of Headers, before ExpiresAt.
+type ReportUpload struct {
+	Name      string            `json:"name"`
+	URL       string            `json:"url"`
+	Method    string            `json:"method"`
+	Headers   map[string]string `json:"headers"`
+	ExpiresAt time.Time         `json:"expiresAt"`
+}
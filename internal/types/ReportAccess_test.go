@@ -0,0 +1,44 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReportAccess(t *testing.T) {
+	heapdump := Report{Name: "a.hprof", User: "jane.doe", Type: "HeapDump"}
+
+	assert.True(t, ReportAccess{Email: "jane.doe@gmail.com"}.CanRead(heapdump))
+	assert.False(t, ReportAccess{Email: "john@gmail.com"}.CanRead(heapdump))
+	assert.True(t, ReportAccess{Email: "john@gmail.com", Permissions: []string{"reports_heapdump_read"}}.CanRead(heapdump))
+	assert.True(t, ReportAccess{Email: "john@gmail.com", Permissions: []string{"reports_heapdump_write"}}.CanRead(heapdump))
+	assert.True(t, ReportAccess{Email: "john@gmail.com", Permissions: []string{"global_read_only"}}.CanRead(heapdump))
+	assert.False(t, ReportAccess{Email: "john@gmail.com", Permissions: []string{"reports_diagnostic_read"}}.CanRead(heapdump))
+
+	assert.True(t, ReportAccess{Permissions: []string{"*"}}.CanUpload("heapdump"))
+	assert.True(t, ReportAccess{Permissions: []string{"reports_heapdump_write"}}.CanUpload("heapdump"))
+	assert.False(t, ReportAccess{Permissions: []string{"reports_heapdump_read", "global_read_only"}}.CanUpload("heapdump"))
+
+	assert.False(t, Report{User: ""}.OwnedBy(""))
+	assert.True(t, Report{User: "ops@example.com"}.OwnedBy("ops@example.com"))
+}
+
+func TestValidateReportUploadRequest(t *testing.T) {
+	valid := ReportUploadRequest{Name: "checkout-heap.hprof", Reason: "INC-42: latency spike", Type: "heapdump"}
+	ok, msg := valid.IsValid()
+	assert.True(t, ok, msg)
+
+	for name, req := range map[string]ReportUploadRequest{
+		"name with a path":  {Name: "../heap.hprof", Reason: "oom", Type: "heapdump"},
+		"hidden name":       {Name: ".heap.hprof", Reason: "oom", Type: "heapdump"},
+		"missing reason":    {Name: "heap.hprof", Reason: " ", Type: "heapdump"},
+		"invalid reason":    {Name: "heap.hprof", Reason: "oom; rm -rf", Type: "heapdump"},
+		"missing type":      {Name: "heap.hprof", Reason: "oom"},
+		"type with a space": {Name: "heap.hprof", Reason: "oom", Type: "heap dump"},
+	} {
+		ok, msg := req.IsValid()
+		assert.False(t, ok, name)
+		assert.NotEmpty(t, msg, name)
+	}
+}
@@ -1,8 +1,18 @@
 package types
 
-import "time"
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
 
-// Report represents the dumpfile s3 object metadata
+// Report represents the dumpfile s3 object metadata.
+// Expires is empty when the report storage does not expire the report, or when its expiry is unknown.
 type Report struct {
 	Name         string    `json:"name"`
 	LastModified time.Time `json:"lastModified"`
@@ -15,3 +25,141 @@ type Report struct {
 	Reason       string    `json:"reason"`
 	Type         string    `json:"type"`
 }
+
+// ReportTags are the s3 object tags describing who created a report, why, and what kind of report it is
+type ReportTags struct {
+	User   string `json:"user"`
+	Reason string `json:"reason"`
+	Type   string `json:"type"`
+}
+
+const (
+	// DefaultReportPageSize is the number of reports returned per page when no limit is requested
+	DefaultReportPageSize = 100
+	// MaxReportPageSize is the highest number of reports that can be requested per page
+	MaxReportPageSize = 1000
+)
+
+// ErrInvalidReportCursor is returned when a report cursor was not returned by a previous page
+var ErrInvalidReportCursor = errors.New("invalid report cursor")
+
+// ReportFilter selects a page of reports. User and Type must match exactly, Reason matches substrings, all case-insensitively.
+// Since is inclusive and Until exclusive. Cursor is the NextCursor of the previous page.
+// When Access is set, only the reports visible to it are listed.
+type ReportFilter struct {
+	Access *ReportAccess
+	User   string
+	Type   string
+	Reason string
+	Since  *time.Time
+	Until  *time.Time
+	Cursor string
+	Limit  int
+}
+
+// ReportPage is a page of reports, newest first. NextCursor is empty on the last page.
+type ReportPage struct {
+	Reports    []Report `json:"reports"`
+	NextCursor string   `json:"nextCursor,omitempty"`
+}
+
+// Matches reports whether a report matches the filter, ignoring the cursor and limit
+func (f ReportFilter) Matches(r Report) bool {
+	if f.User != "" && !strings.EqualFold(f.User, r.User) && !strings.EqualFold(strings.ReplaceAll(f.User, "@gmail.com", ""), r.User) {
+		return false
+	}
+	if f.Type != "" && !strings.EqualFold(f.Type, r.Type) {
+		return false
+	}
+	if f.Reason != "" && !strings.Contains(strings.ToLower(r.Reason), strings.ToLower(f.Reason)) {
+		return false
+	}
+	if f.Since != nil && r.LastModified.Before(*f.Since) {
+		return false
+	}
+	if f.Until != nil && !r.LastModified.Before(*f.Until) {
+		return false
+	}
+	if f.Access != nil && !f.Access.CanRead(r) {
+		return false
+	}
+	return true
+}
+
+// PaginateReports filters the reports and returns the page following the filter cursor.
+// Reports are ordered newest first, then by name, and the cursor encodes the position of the last report of a page,
+// so pages stay consistent while new reports are uploaded.
+func PaginateReports(reports []Report, filter ReportFilter) (ReportPage, error) {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = DefaultReportPageSize
+	}
+	if limit > MaxReportPageSize {
+		limit = MaxReportPageSize
+	}
+
+	var after *reportCursor
+	if filter.Cursor != "" {
+		cursor, err := decodeReportCursor(filter.Cursor)
+		if err != nil {
+			return ReportPage{}, err
+		}
+		after = &cursor
+	}
+
+	sorted := slices.Clone(reports)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return reportCursorOf(sorted[i]).before(reportCursorOf(sorted[j]))
+	})
+
+	page := ReportPage{Reports: []Report{}}
+	for _, r := range sorted {
+		if !filter.Matches(r) || (after != nil && !after.before(reportCursorOf(r))) {
+			continue
+		}
+		if len(page.Reports) == limit {
+			page.NextCursor = reportCursorOf(page.Reports[limit-1]).encode()
+			break
+		}
+		page.Reports = append(page.Reports, r)
+	}
+	return page, nil
+}
+
+// reportCursor is the position of a report in the listing order
+type reportCursor struct {
+	lastModified int64
+	name         string
+}
+
+func reportCursorOf(r Report) reportCursor {
+	return reportCursor{lastModified: r.LastModified.UnixNano(), name: r.Name}
+}
+
+// before reports whether c is listed before other
+func (c reportCursor) before(other reportCursor) bool {
+	if c.lastModified != other.lastModified {
+		return c.lastModified > other.lastModified
+	}
+	return c.name < other.name
+}
+
+func (c reportCursor) encode() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%d:%s", c.lastModified, c.name)))
+}
+
+func decodeReportCursor(cursor string) (reportCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return reportCursor{}, ErrInvalidReportCursor
+	}
+	lastModified, name, ok := strings.Cut(string(data), ":")
+	if !ok {
+		return reportCursor{}, ErrInvalidReportCursor
+	}
+	nanos, err := strconv.ParseInt(lastModified, 10, 64)
+	if err != nil {
+		return reportCursor{}, ErrInvalidReportCursor
+	}
+	return reportCursor{lastModified: nanos, name: name}, nil
+}
@@ -0,0 +1,69 @@
+package types
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testReports() []Report {
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	return []Report{
+		{Name: "a.hprof", LastModified: base, User: "jane.doe", Type: "heapdump", Reason: "Latency spike"},
+		{Name: "b.hprof", LastModified: base, User: "john", Type: "heapdump", Reason: "oom"},
+		{Name: "c.log", LastModified: base.Add(time.Hour), User: "jane.doe", Type: "diagnostic", Reason: "latency"},
+		{Name: "d.log", LastModified: base.Add(-time.Hour), User: "jane.doe", Type: "diagnostic", Reason: "unspecified"},
+	}
+}
+
+func reportNames(page ReportPage) []string {
+	names := []string{}
+	for _, r := range page.Reports {
+		names = append(names, r.Name)
+	}
+	return names
+}
+
+func TestPaginateReports(t *testing.T) {
+	reports := testReports()
+
+	page, err := PaginateReports(reports, ReportFilter{Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"c.log", "a.hprof"}, reportNames(page))
+	require.NotEmpty(t, page.NextCursor)
+
+	// Reports uploaded after the first page do not shift the following pages
+	reports = append(reports, Report{Name: "e.log", LastModified: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)})
+	page, err = PaginateReports(reports, ReportFilter{Limit: 2, Cursor: page.NextCursor})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b.hprof", "d.log"}, reportNames(page))
+	assert.Empty(t, page.NextCursor)
+
+	_, err = PaginateReports(reports, ReportFilter{Cursor: "not-a-cursor"})
+	assert.ErrorIs(t, err, ErrInvalidReportCursor)
+}
+
+func TestFilterReports(t *testing.T) {
+	since := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	until := since.Add(time.Hour)
+
+	tests := []struct {
+		name     string
+		filter   ReportFilter
+		expected []string
+	}{
+		{name: "user", filter: ReportFilter{User: "jane.doe@gmail.com"}, expected: []string{"c.log", "a.hprof", "d.log"}},
+		{name: "type", filter: ReportFilter{Type: "HEAPDUMP"}, expected: []string{"a.hprof", "b.hprof"}},
+		{name: "reason", filter: ReportFilter{Reason: "latency"}, expected: []string{"c.log", "a.hprof"}},
+		{name: "date range", filter: ReportFilter{Since: &since, Until: &until}, expected: []string{"a.hprof", "b.hprof"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := PaginateReports(testReports(), tt.filter)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, reportNames(page))
+		})
+	}
+}
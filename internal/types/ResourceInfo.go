@@ -6,4 +6,8 @@ type ResourceInfo struct {
 	Namespace string            `json:"namespace"`
 	Labels    map[string]string `json:"labels"`
 	Plugin    K8sPodExecPlugin  `json:"plugin"`
+	// PluginArgs are the arguments of the plugin parameters, keyed by parameter name
+	PluginArgs map[string]string `json:"pluginArgs,omitempty"`
+	// Reason is recorded on the artifact of plugins with an output file
+	Reason string `json:"reason,omitempty"`
 }
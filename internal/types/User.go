@@ -11,17 +11,70 @@ import (
 )
 
 // User represents a user on the khub application.
+// Service accounts are non-human users that can only authenticate with api tokens.
+// Users that sign in through an identity provider are keyed by the provider's issuer and subject.
+// Deactivated users are kept for auditing but can no longer sign in or use their api tokens.
 type User struct {
-	ID        *uuid.UUID     `json:"id" gorm:"type:uuid;default:gen_random_uuid()"`
-	Name      string         `json:"name" gorm:"uniqueIndex"`
-	Email     string         `json:"email"`
-	IsAdmin   bool           `json:"isAdmin"`
-	LastUsed  time.Time      `json:"lastUsed"`
-	DarkMode  bool           `json:"darkMode"`
-	Groups    []*Group       `json:"groups" gorm:"many2many:group_users;"`
-	CreatedAt time.Time      `json:"createdAt"`
-	UpdatedAt time.Time      `json:"updatedAt"`
-	DeletedAt gorm.DeletedAt `gorm:"index"`
+	ID               *uuid.UUID     `json:"id" gorm:"type:uuid;default:gen_random_uuid()"`
+	Name             string         `json:"name" gorm:"uniqueIndex"`
+	Email            string         `json:"email"`
+	IsAdmin          bool           `json:"isAdmin"`
+	LastUsed         time.Time      `json:"lastUsed"`
+	DarkMode         bool           `json:"darkMode"`
+	IsServiceAccount bool           `json:"isServiceAccount"`
+	Issuer           string         `json:"issuer" gorm:"index:idx_users_identity,unique,where:subject <> ''"`
+	Subject          string         `json:"subject" gorm:"index:idx_users_identity,unique,where:subject <> ''"`
+	Deactivated      bool           `json:"deactivated"`
+	Groups           []*Group       `json:"groups" gorm:"many2many:group_users;"`
+	CreatedAt        time.Time      `json:"createdAt"`
+	UpdatedAt        time.Time      `json:"updatedAt"`
+	DeletedAt        gorm.DeletedAt `gorm:"index"`
+}
+
+// UserAdminRequest represents the request body used to grant or revoke admin access
+type UserAdminRequest struct {
+	IsAdmin bool `json:"isAdmin"`
+}
+
+// ServiceAccountEmailDomain is the synthetic email domain given to service accounts
+const ServiceAccountEmailDomain = "serviceaccount.khub"
+
+// NewServiceAccount returns a service account user with the given name
+func NewServiceAccount(name string) User {
+	return User{
+		Name:             name,
+		Email:            fmt.Sprintf("%s@%s", strings.ToLower(name), ServiceAccountEmailDomain),
+		IsServiceAccount: true,
+		LastUsed:         time.Now(),
+	}
+}
+
+// UserIdentity represents the identity of a user as asserted by an OIDC identity provider
+type UserIdentity struct {
+	IDP     string
+	Issuer  string
+	Subject string
+	Email   string
+	// VerifiedEmail is the email claim of the identity, when the identity provider asserted that the user owns it.
+	// It is empty otherwise. Email may be a preferred username, which identity providers do not verify.
+	VerifiedEmail string
+	// DefaultIDP is whether the identity was asserted by the default identity provider, which users created before
+	// identities were recorded signed in with
+	DefaultIDP bool
+}
+
+// UsernameFromEmail derives the khub username from a user's email, e.g. jane.doe@example.com -> jdoe
+func UsernameFromEmail(email string) string {
+	userIdentityParts := strings.Split(email, "@")
+
+	// Check if username contains a '.' -- Fix for local-dev and staging
+	if strings.Contains(userIdentityParts[0], ".") {
+		subparts := strings.Split(userIdentityParts[0], ".")
+		if len(subparts[0]) > 0 {
+			userIdentityParts[0] = fmt.Sprintf("%c%s", subparts[0][0], subparts[1])
+		}
+	}
+	return userIdentityParts[0]
 }
 
 // UserAccessDetails represents the access a user has, including their groups and permissions
@@ -0,0 +1,15 @@
+package types
+
+import "time"
+
+// UserSession represents an active browser login session held in the redis session store.
+// The ID is a hash of the session key so that listing sessions never exposes a usable session cookie.
+type UserSession struct {
+	ID         string    `json:"id"`
+	Email      string    `json:"email"`
+	IPAddress  string    `json:"ipAddress"`
+	UserAgent  string    `json:"userAgent"`
+	CreatedAt  time.Time `json:"createdAt"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+	SessionKey string    `json:"-"`
+}